@@ -0,0 +1,205 @@
+// Package cash checks the cash ledger generated from imported transactions
+// against the balances brokers report.
+package cash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// toleranceMicros absorbs rounding below a cent.
+const toleranceMicros = 10_000
+
+// ErrNoCheckpoints is returned when an account has no reported cash balances
+// to reconcile against.
+var ErrNoCheckpoints = errors.New("no cash checkpoints")
+
+// CheckpointLine compares a reported cash balance with the ledger balance at
+// the close of its date. Differences are reported minus ledger.
+type CheckpointLine struct {
+	Date           string
+	Source         string
+	Description    string
+	ReportedMicros int64
+	LedgerMicros   int64
+	DiffMicros     int64
+	ChangeMicros   int64 // difference added since the previous checkpoint
+	Matched        bool
+}
+
+// LedgerEntry is a cash transaction with the running balance after it.
+type LedgerEntry struct {
+	Date          string
+	CashType      string
+	Symbol        string
+	Description   string
+	AmountMicros  int64
+	BalanceMicros int64
+}
+
+// Candidate is a row an import skipped during the drift period. Explains is
+// set when its amount alone accounts for the drift.
+type Candidate struct {
+	db.ImportDiagnostic
+	Explains bool
+}
+
+// Drift is where the ledger first moves away from the reported balances:
+// after StartDate, the previous checkpoint (empty for the first one), through
+// EndDate, the first checkpoint that doesn't match.
+type Drift struct {
+	StartDate  string
+	EndDate    string
+	DiffMicros int64 // difference added in the period
+	Entries    []LedgerEntry
+	Candidates []Candidate
+}
+
+type Reconciliation struct {
+	AccountID string
+	Lines     []CheckpointLine
+	Drift     *Drift // nil when every checkpoint matches
+}
+
+// Unmatched returns the number of checkpoints the ledger doesn't match.
+func (r *Reconciliation) Unmatched() int {
+	var n int
+	for _, line := range r.Lines {
+		if !line.Matched {
+			n++
+		}
+	}
+	return n
+}
+
+type Reconciler struct {
+	queries *db.Queries
+}
+
+func NewReconciler(queries *db.Queries) *Reconciler {
+	return &Reconciler{queries: queries}
+}
+
+// Reconcile compares the account's cash ledger with its checkpoints and, when
+// they drift apart, lists the rows imports skipped in the period it started.
+func (r *Reconciler) Reconcile(ctx context.Context, accountID string) (*Reconciliation, error) {
+	checkpoints, err := r.queries.ListCashCheckpoints(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cash checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		return nil, ErrNoCheckpoints
+	}
+
+	ledger, err := r.queries.ListCashTransactions(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cash transactions: %w", err)
+	}
+
+	rec := CompareCheckpoints(accountID, ledger, checkpoints)
+	if rec.Drift == nil {
+		return rec, nil
+	}
+
+	diagnostics, err := r.queries.ListImportDiagnostics(ctx, db.ListImportDiagnosticsParams{
+		AccountID: accountID,
+		StartDate: rec.Drift.StartDate,
+		EndDate:   rec.Drift.EndDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import diagnostics: %w", err)
+	}
+	rec.Drift.Candidates = MatchCandidates(rec.Drift.DiffMicros, diagnostics)
+
+	return rec, nil
+}
+
+// CompareCheckpoints runs the ledger forward through each checkpoint and finds
+// the first period where the difference grows. Ledger rows may be in any order.
+func CompareCheckpoints(accountID string, ledger []db.ListCashTransactionsRow, checkpoints []db.CashCheckpoint) *Reconciliation {
+	rows := append([]db.ListCashTransactionsRow(nil), ledger...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].TransactionDate != rows[j].TransactionDate {
+			return rows[i].TransactionDate < rows[j].TransactionDate
+		}
+		return rows[i].CreatedAt < rows[j].CreatedAt
+	})
+	points := append([]db.CashCheckpoint(nil), checkpoints...)
+	sort.Slice(points, func(i, j int) bool { return points[i].CheckpointDate < points[j].CheckpointDate })
+
+	rec := &Reconciliation{AccountID: accountID}
+
+	var (
+		balance  int64
+		prevDiff int64
+		prevDate string
+		next     int
+	)
+	for _, cp := range points {
+		var entries []LedgerEntry
+		for ; next < len(rows) && rows[next].TransactionDate <= cp.CheckpointDate; next++ {
+			row := rows[next]
+			balance += row.AmountMicros
+			entries = append(entries, LedgerEntry{
+				Date:          row.TransactionDate,
+				CashType:      row.CashType,
+				Symbol:        row.Symbol.String,
+				Description:   row.Description.String,
+				AmountMicros:  row.AmountMicros,
+				BalanceMicros: balance,
+			})
+		}
+
+		diff := cp.BalanceMicros - balance
+		line := CheckpointLine{
+			Date:           cp.CheckpointDate,
+			Source:         cp.Source,
+			Description:    cp.Description.String,
+			ReportedMicros: cp.BalanceMicros,
+			LedgerMicros:   balance,
+			DiffMicros:     diff,
+			ChangeMicros:   diff - prevDiff,
+			Matched:        abs(diff) < toleranceMicros,
+		}
+		rec.Lines = append(rec.Lines, line)
+
+		if rec.Drift == nil && abs(line.ChangeMicros) >= toleranceMicros {
+			rec.Drift = &Drift{
+				StartDate:  prevDate,
+				EndDate:    cp.CheckpointDate,
+				DiffMicros: line.ChangeMicros,
+				Entries:    entries,
+			}
+		}
+		prevDiff = diff
+		prevDate = cp.CheckpointDate
+	}
+
+	return rec
+}
+
+// MatchCandidates flags the skipped rows whose amount alone explains a drift.
+func MatchCandidates(driftMicros int64, diagnostics []db.ImportDiagnostic) []Candidate {
+	var candidates []Candidate
+	for _, d := range diagnostics {
+		if d.AmountMicros == 0 {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			ImportDiagnostic: d,
+			Explains:         abs(d.AmountMicros-driftMicros) < toleranceMicros,
+		})
+	}
+	return candidates
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
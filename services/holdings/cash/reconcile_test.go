@@ -0,0 +1,103 @@
+package cash_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/cash"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+func TestCompareCheckpoints(t *testing.T) {
+	entry := func(date, cashType string, amount int64) db.ListCashTransactionsRow {
+		return db.ListCashTransactionsRow{TransactionDate: date, CashType: cashType, AmountMicros: amount}
+	}
+	checkpoint := func(date string, balance int64) db.CashCheckpoint {
+		return db.CashCheckpoint{CheckpointDate: date, BalanceMicros: balance, Source: "statement"}
+	}
+
+	// Newest first, as ListCashTransactions returns them.
+	ledger := []db.ListCashTransactionsRow{
+		entry("2024-09-15", "dividend", 40_000_000),
+		entry("2024-05-10", "dividend", 100_000_000),
+		entry("2024-04-01", "purchase", -2_000_000_000),
+		entry("2024-02-01", "deposit", 5_000_000_000),
+		entry("2024-01-01", "opening", 1_000_000_000),
+	}
+	checkpoints := []db.CashCheckpoint{
+		checkpoint("2024-09-30", 3_125_000_000),
+		checkpoint("2024-03-31", 6_000_000_000),
+		checkpoint("2024-06-30", 4_085_000_000), // $15 foreign tax withheld in May
+	}
+
+	rec := cash.CompareCheckpoints("acct", ledger, checkpoints)
+
+	want := []struct {
+		date    string
+		ledger  int64
+		diff    int64
+		change  int64
+		matched bool
+	}{
+		{"2024-03-31", 6_000_000_000, 0, 0, true},
+		{"2024-06-30", 4_100_000_000, -15_000_000, -15_000_000, false},
+		{"2024-09-30", 4_140_000_000, -1_015_000_000, -1_000_000_000, false},
+	}
+	if len(rec.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(rec.Lines), len(want), rec.Lines)
+	}
+	for i, w := range want {
+		l := rec.Lines[i]
+		if l.Date != w.date || l.LedgerMicros != w.ledger || l.DiffMicros != w.diff || l.ChangeMicros != w.change || l.Matched != w.matched {
+			t.Errorf("line %d: got %+v, want %+v", i, l, w)
+		}
+	}
+	if rec.Unmatched() != 2 {
+		t.Errorf("expected 2 unmatched checkpoints, got %d", rec.Unmatched())
+	}
+
+	drift := rec.Drift
+	if drift == nil {
+		t.Fatal("expected a drift")
+	}
+	if drift.StartDate != "2024-03-31" || drift.EndDate != "2024-06-30" || drift.DiffMicros != -15_000_000 {
+		t.Errorf("unexpected drift period: %+v", drift)
+	}
+	if len(drift.Entries) != 2 || drift.Entries[1].BalanceMicros != 4_100_000_000 {
+		t.Errorf("unexpected drift ledger: %+v", drift.Entries)
+	}
+
+	diagnostic := func(date, activity string, amount int64) db.ImportDiagnostic {
+		return db.ImportDiagnostic{
+			ActivityDate: date, Activity: activity, AmountMicros: amount, Reason: "unmapped",
+			Description: sql.NullString{String: activity, Valid: true},
+		}
+	}
+	candidates := cash.MatchCandidates(drift.DiffMicros, []db.ImportDiagnostic{
+		diagnostic("2024-05-10", "Foreign Tax Withheld", -15_000_000),
+		diagnostic("2024-05-31", "Sweep", -85_000_000),
+		diagnostic("2024-06-01", "Memo", 0),
+	})
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %d: %+v", len(candidates), candidates)
+	}
+	if !candidates[0].Explains || candidates[1].Explains {
+		t.Errorf("only the foreign tax should explain the drift: %+v", candidates)
+	}
+}
+
+func TestCompareCheckpointsMatched(t *testing.T) {
+	ledger := []db.ListCashTransactionsRow{
+		{TransactionDate: "2024-01-01", CashType: "opening", AmountMicros: 1_000_000_000},
+		{TransactionDate: "2024-02-01", CashType: "interest", AmountMicros: 1_234_567},
+	}
+	checkpoints := []db.CashCheckpoint{
+		{CheckpointDate: "2024-01-31", BalanceMicros: 1_000_000_000},
+		{CheckpointDate: "2024-02-29", BalanceMicros: 1_001_230_000}, // statement rounds to the cent
+	}
+
+	rec := cash.CompareCheckpoints("acct", ledger, checkpoints)
+	if rec.Drift != nil || rec.Unmatched() != 0 {
+		t.Errorf("expected every checkpoint to match: %+v", rec.Lines)
+	}
+}
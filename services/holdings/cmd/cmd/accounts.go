@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -8,6 +9,7 @@ import (
 	"github.com/levisegal/monay/services/holdings/config"
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/performance"
 )
 
 func accountsCommand() *cobra.Command {
@@ -19,6 +21,8 @@ func accountsCommand() *cobra.Command {
 	cmd.AddCommand(accountsListCommand())
 	cmd.AddCommand(accountsRenameCommand())
 	cmd.AddCommand(accountsDeleteCommand())
+	cmd.AddCommand(accountsMarginCommand())
+	cmd.AddCommand(accountsBenchmarkCommand())
 
 	return cmd
 }
@@ -152,3 +156,114 @@ func accountsRenameCommand() *cobra.Command {
 	return cmd
 }
 
+func accountsMarginCommand() *cobra.Command {
+	var name string
+	var enabled bool
+
+	cmd := &cobra.Command{
+		Use:   "margin",
+		Short: "Mark an account as allowing margin, so sells beyond holdings open short lots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, name)
+			if err != nil {
+				return fmt.Errorf("account %q not found: %w", name, err)
+			}
+
+			err = queries.SetAccountMargin(ctx, db.SetAccountMarginParams{
+				ID:     account.ID,
+				Margin: enabled,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update account: %w", err)
+			}
+
+			if enabled {
+				fmt.Printf("Margin enabled for %q; run lots process to replay its sells\n", name)
+			} else {
+				fmt.Printf("Margin disabled for %q; run lots process to replay its sells\n", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Account name")
+	cmd.Flags().BoolVar(&enabled, "enabled", true, "Allow margin (--enabled=false to turn off)")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func accountsBenchmarkCommand() *cobra.Command {
+	var name, benchmark string
+
+	cmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Set the benchmark an account's performance is compared with",
+		Long: `Set the benchmark (see "benchmarks list") an account's performance is
+compared with, or clear it with --benchmark "". Accounts without one are
+compared with MUNI when bonds are most of their value and TOTAL otherwise.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, name)
+			if err != nil {
+				return fmt.Errorf("account %q not found: %w", name, err)
+			}
+			if benchmark != "" {
+				if _, err := performance.NewCalculator(queries).Benchmark(ctx, benchmark); err != nil {
+					return err
+				}
+			}
+
+			err = queries.SetAccountBenchmark(ctx, db.SetAccountBenchmarkParams{
+				ID:        account.ID,
+				Benchmark: sql.NullString{String: benchmark, Valid: benchmark != ""},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update account: %w", err)
+			}
+
+			if benchmark == "" {
+				fmt.Printf("Cleared the benchmark for %q\n", name)
+			} else {
+				fmt.Printf("Benchmark for %q set to %s\n", name, benchmark)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Account name")
+	cmd.Flags().StringVar(&benchmark, "benchmark", "", "Benchmark name")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("benchmark")
+
+	return cmd
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/performance"
+)
+
+func benchmarksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "benchmarks",
+		Short: "Manage performance benchmarks",
+	}
+
+	cmd.AddCommand(listBenchmarksCommand())
+	cmd.AddCommand(setBenchmarkCommand())
+	cmd.AddCommand(deleteBenchmarkCommand())
+
+	return cmd
+}
+
+func listBenchmarksCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List built-in and custom benchmarks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			benchmarks, err := performance.NewCalculator(db.New(conn)).Benchmarks(ctx)
+			if err != nil {
+				return err
+			}
+
+			tbl := table.New("Name", "Components", "Rebalance", "Description")
+			tbl.WithWriter(os.Stdout)
+			for _, b := range benchmarks {
+				var components []string
+				for _, c := range b.Components {
+					components = append(components, fmt.Sprintf("%s %.0f%% %s", c.Symbol, c.Weight*100, c.SecurityType))
+				}
+				tbl.AddRow(b.Name, strings.Join(components, ", "), b.Rebalance, b.Description)
+			}
+			tbl.Print()
+			return nil
+		},
+	}
+}
+
+func setBenchmarkCommand() *cobra.Command {
+	var (
+		name        string
+		description string
+		rebalance   string
+		components  []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Create or replace a custom benchmark",
+		Long: `Store a benchmark of fixed-weight components, each SYMBOL=WEIGHT with an
+optional :TYPE attribution segment (default equity), e.g.
+
+  benchmarks set --name 70/30 --component VTI=0.7 --component MUB=0.3:bond
+
+A stored benchmark replaces a built-in one of the same name. Run "prices sync"
+afterwards to fetch closes for new symbols.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if rebalance != performance.RebalanceMonthly && rebalance != performance.RebalanceNone {
+				return fmt.Errorf("invalid --rebalance %q: want monthly or none", rebalance)
+			}
+			var parsed []performance.Component
+			for _, c := range components {
+				component, err := parseBenchmarkComponent(c)
+				if err != nil {
+					return err
+				}
+				parsed = append(parsed, component)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			err = queries.UpsertBenchmark(ctx, db.UpsertBenchmarkParams{
+				Name:        name,
+				Description: sql.NullString{String: description, Valid: description != ""},
+				Rebalance:   rebalance,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save benchmark: %w", err)
+			}
+			if err := queries.DeleteBenchmarkComponents(ctx, name); err != nil {
+				return fmt.Errorf("failed to delete benchmark components: %w", err)
+			}
+			for _, c := range parsed {
+				err := queries.CreateBenchmarkComponent(ctx, db.CreateBenchmarkComponentParams{
+					BenchmarkName: name,
+					Symbol:        c.Symbol,
+					Weight:        c.Weight,
+					SecurityType:  c.SecurityType,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to save benchmark component %s: %w", c.Symbol, err)
+				}
+			}
+
+			fmt.Printf("Saved benchmark %q with %d components\n", name, len(parsed))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Benchmark name")
+	cmd.Flags().StringVar(&description, "description", "", "Description")
+	cmd.Flags().StringVar(&rebalance, "rebalance", performance.RebalanceMonthly, "monthly or none (buy and hold)")
+	cmd.Flags().StringArrayVar(&components, "component", nil, "SYMBOL=WEIGHT[:TYPE] - can be repeated")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("component")
+
+	return cmd
+}
+
+func deleteBenchmarkCommand() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a custom benchmark, restoring any built-in one of the same name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			if err := queries.DeleteBenchmarkComponents(ctx, name); err != nil {
+				return fmt.Errorf("failed to delete benchmark components: %w", err)
+			}
+			if err := queries.DeleteBenchmark(ctx, name); err != nil {
+				return fmt.Errorf("failed to delete benchmark: %w", err)
+			}
+
+			fmt.Printf("Deleted benchmark %q\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Benchmark name")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+// parseBenchmarkComponent parses SYMBOL=WEIGHT[:TYPE].
+func parseBenchmarkComponent(s string) (performance.Component, error) {
+	symbol, rest, ok := strings.Cut(s, "=")
+	if !ok || symbol == "" {
+		return performance.Component{}, fmt.Errorf("invalid --component %q: want SYMBOL=WEIGHT[:TYPE]", s)
+	}
+	weightText, securityType, _ := strings.Cut(rest, ":")
+	weight, err := strconv.ParseFloat(weightText, 64)
+	if err != nil || weight <= 0 {
+		return performance.Component{}, fmt.Errorf("invalid --component %q: weight must be a positive number", s)
+	}
+	if securityType == "" {
+		securityType = "equity"
+	}
+	return performance.Component{Symbol: strings.ToUpper(symbol), Weight: weight, SecurityType: securityType}, nil
+}
@@ -3,15 +3,19 @@ package cmd
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
+	"github.com/rodaine/table"
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 
+	"github.com/levisegal/monay/services/holdings/cash"
 	"github.com/levisegal/monay/services/holdings/config"
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
@@ -27,6 +31,8 @@ func cashCommand() *cobra.Command {
 	cmd.AddCommand(cashBalanceCommand())
 	cmd.AddCommand(cashLedgerCommand())
 	cmd.AddCommand(cashGenerateCommand())
+	cmd.AddCommand(cashCheckpointCommand())
+	cmd.AddCommand(cashReconcileCommand())
 
 	return cmd
 }
@@ -151,6 +157,238 @@ func cashGenerateCommand() *cobra.Command {
 	return cmd
 }
 
+func cashCheckpointCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checkpoint",
+		Short: "Record cash balances reported on statements",
+	}
+
+	cmd.AddCommand(cashCheckpointSetCommand())
+	cmd.AddCommand(cashCheckpointListCommand())
+	cmd.AddCommand(cashCheckpointDeleteCommand())
+
+	return cmd
+}
+
+func cashCheckpointSetCommand() *cobra.Command {
+	var (
+		accountName string
+		dateStr     string
+		balance     string
+		source      string
+		note        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Record the cash balance a statement reports on a date",
+		Long: `Record the cash balance, including the sweep or money market fund, that a
+statement reports at the close of a date. LPL positions files record their own
+checkpoints on import; use this for PDF statements and the other brokers.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			date, err := time.Parse("2006-01-02", dateStr)
+			if err != nil {
+				return fmt.Errorf("invalid date format, use YYYY-MM-DD: %w", err)
+			}
+
+			balanceDec, err := decimal.NewFromString(balance)
+			if err != nil {
+				return fmt.Errorf("invalid balance: %w", err)
+			}
+			balanceMicros := balanceDec.Mul(decimal.NewFromInt(1_000_000)).IntPart()
+
+			switch source {
+			case "manual", "statement", "positions":
+			default:
+				return fmt.Errorf("invalid source %q: use manual, statement or positions", source)
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			err = queries.UpsertCashCheckpoint(ctx, db.UpsertCashCheckpointParams{
+				AccountID:      account.ID,
+				CheckpointDate: date.Format("2006-01-02"),
+				BalanceMicros:  balanceMicros,
+				Source:         source,
+				Description:    sql.NullString{String: note, Valid: note != ""},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save cash checkpoint: %w", err)
+			}
+
+			slog.Info("saved cash checkpoint",
+				"account", accountName,
+				"date", date.Format("2006-01-02"),
+				"balance", formatMicros(balanceMicros),
+			)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&dateStr, "date", "", "Statement date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&balance, "balance", "", "Reported cash balance (e.g., 5000.00)")
+	cmd.Flags().StringVar(&source, "source", "statement", "Where the balance came from: manual, statement or positions")
+	cmd.Flags().StringVar(&note, "note", "", "Note, e.g. the statement file")
+
+	cmd.MarkFlagRequired("account-name")
+	cmd.MarkFlagRequired("date")
+	cmd.MarkFlagRequired("balance")
+
+	return cmd
+}
+
+func cashCheckpointListCommand() *cobra.Command {
+	var accountName string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded cash balances for an account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			checkpoints, err := queries.ListCashCheckpoints(ctx, account.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list cash checkpoints: %w", err)
+			}
+			if len(checkpoints) == 0 {
+				fmt.Printf("No cash checkpoints for %s\n", accountName)
+				return nil
+			}
+
+			tbl := table.New("Date", "Balance", "Source", "Note")
+			tbl.WithWriter(os.Stdout)
+			for _, cp := range checkpoints {
+				tbl.AddRow(cp.CheckpointDate, formatMicros(cp.BalanceMicros), cp.Source, cp.Description.String)
+			}
+			tbl.Print()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
+func cashCheckpointDeleteCommand() *cobra.Command {
+	var (
+		accountName string
+		dateStr     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete the cash balance recorded on a date",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			err = queries.DeleteCashCheckpoint(ctx, db.DeleteCashCheckpointParams{
+				AccountID:      account.ID,
+				CheckpointDate: dateStr,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete cash checkpoint: %w", err)
+			}
+
+			slog.Info("deleted cash checkpoint", "account", accountName, "date", dateStr)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&dateStr, "date", "", "Checkpoint date (YYYY-MM-DD)")
+
+	cmd.MarkFlagRequired("account-name")
+	cmd.MarkFlagRequired("date")
+
+	return cmd
+}
+
+func cashReconcileCommand() *cobra.Command {
+	var accountName string
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare the cash ledger with recorded statement balances",
+		Long: `Run the cash ledger forward through each checkpoint recorded with 'cash
+checkpoint set' or imported from a positions file, find the period where the
+ledger first drifts from the reported balance, and list rows the importer
+skipped in that period (sweeps, reinvestment cash legs, foreign tax and
+activity with no transaction type) that could account for it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			return reconcileCash(ctx, cfg, accountName)
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
 func setCashOpening(ctx context.Context, cfg *config.Config, accountName string, date time.Time, balanceMicros int64) error {
 	conn, err := database.Open(ctx, cfg.DBPath)
 	if err != nil {
@@ -351,6 +589,7 @@ func generateCashTransactions(ctx context.Context, cfg *config.Config, accountNa
 			AmountMicros:    amountMicros,
 			SecurityID:      txn.SecurityID,
 			Description:     txn.Description,
+			DividendType:    txn.DividendType,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create cash transaction: %w", err)
@@ -367,11 +606,121 @@ func generateCashTransactions(ctx context.Context, cfg *config.Config, accountNa
 	return nil
 }
 
+func reconcileCash(ctx context.Context, cfg *config.Config, accountName string) error {
+	conn, err := database.Open(ctx, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	queries := db.New(conn)
+
+	account, err := queries.GetAccountByName(ctx, accountName)
+	if err != nil {
+		return fmt.Errorf("account not found: %s", accountName)
+	}
+
+	rec, err := cash.NewReconciler(queries).Reconcile(ctx, account.ID)
+	if errors.Is(err, cash.ErrNoCheckpoints) {
+		fmt.Printf("No cash checkpoints for %s; record one with 'cash checkpoint set'\n", accountName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== %s: Cash Ledger vs Statements ===\n\n", account.Name)
+
+	tbl := table.New("Date", "Source", "Statement", "Ledger", "Difference", "Change", "")
+	tbl.WithWriter(os.Stdout)
+	for _, l := range rec.Lines {
+		status := "ok"
+		if !l.Matched {
+			status = "drift"
+		}
+		tbl.AddRow(
+			l.Date,
+			l.Source,
+			formatMicros(l.ReportedMicros),
+			formatMicros(l.LedgerMicros),
+			formatMicros(l.DiffMicros),
+			formatMicros(l.ChangeMicros),
+			status,
+		)
+	}
+	tbl.Print()
+
+	drift := rec.Drift
+	if drift == nil {
+		fmt.Printf("\nSummary: %d checkpoints, ledger matches every one\n", len(rec.Lines))
+		return nil
+	}
+
+	if drift.StartDate == "" {
+		fmt.Printf("\nDrift starts before the first checkpoint, %s: %s (statement minus ledger)\n", drift.EndDate, formatMicros(drift.DiffMicros))
+		fmt.Printf("Check the opening balance ('cash set') as well as the rows below.\n")
+	} else {
+		fmt.Printf("\nDrift starts between %s and %s: %s (statement minus ledger)\n", drift.StartDate, drift.EndDate, formatMicros(drift.DiffMicros))
+	}
+
+	// The first period runs from the start of the ledger, too long to be useful.
+	if drift.StartDate != "" && len(drift.Entries) > 0 {
+		fmt.Printf("\nLedger in the period:\n")
+		entries := table.New("Date", "Type", "Amount", "Balance", "Description")
+		entries.WithWriter(os.Stdout)
+		for _, e := range drift.Entries {
+			desc := e.Description
+			if e.Symbol != "" {
+				desc = e.Symbol + ": " + desc
+			}
+			if len(desc) > 40 {
+				desc = desc[:37] + "..."
+			}
+			entries.AddRow(e.Date, e.CashType, formatMicros(e.AmountMicros), formatMicros(e.BalanceMicros), desc)
+		}
+		entries.Print()
+	}
+
+	if len(drift.Candidates) == 0 {
+		fmt.Printf("\nNo skipped import rows in the period; check for missing activity or the opening balance.\n")
+	} else {
+		fmt.Printf("\nCandidate unmapped rows (* explains the drift on its own):\n")
+		candidates := table.New("", "Date", "Activity", "Amount", "Reason", "Source", "Description")
+		candidates.WithWriter(os.Stdout)
+		var total int64
+		for _, c := range drift.Candidates {
+			mark := ""
+			if c.Explains {
+				mark = "*"
+			}
+			desc := c.Description.String
+			if len(desc) > 40 {
+				desc = desc[:37] + "..."
+			}
+			candidates.AddRow(
+				mark,
+				c.ActivityDate,
+				c.Activity,
+				formatMicros(c.AmountMicros),
+				c.Reason,
+				fmt.Sprintf("%s:%d", c.SourceFile, c.LineNumber),
+				desc,
+			)
+			total += c.AmountMicros
+		}
+		candidates.Print()
+		fmt.Printf("Candidates total: %s\n", formatMicros(total))
+	}
+
+	fmt.Printf("\nSummary: %d checkpoints, %d off\n", len(rec.Lines), rec.Unmatched())
+	return nil
+}
+
 func mapTransactionTypeToCashType(txnType string) (cashType string, hasCashImpact bool) {
 	switch txnType {
-	case "buy":
+	case "buy", "buy_to_cover":
 		return "purchase", true
-	case "sell":
+	case "sell", "sell_short", "redemption":
 		return "proceeds", true
 	case "dividend":
 		return "dividend", true
@@ -379,6 +728,8 @@ func mapTransactionTypeToCashType(txnType string) (cashType string, hasCashImpac
 		return "interest", true
 	case "cap_gain":
 		return "cap_gain", true
+	case "return_of_capital":
+		return "return_of_capital", true
 	case "fee":
 		return "fee", true
 	case "transfer_in":
@@ -407,7 +758,7 @@ func normalizeCashAmount(cashType string, amountMicros int64) int64 {
 			return -amountMicros
 		}
 		return amountMicros
-	case "proceeds", "dividend", "interest", "cap_gain", "transfer_in":
+	case "proceeds", "dividend", "interest", "cap_gain", "return_of_capital", "transfer_in":
 		if amountMicros < 0 {
 			return -amountMicros
 		}
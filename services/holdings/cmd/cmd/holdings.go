@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
@@ -14,6 +17,7 @@ import (
 	"github.com/levisegal/monay/services/holdings/config"
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
 )
 
 func holdingsCommand() *cobra.Command {
@@ -24,6 +28,8 @@ func holdingsCommand() *cobra.Command {
 
 	cmd.AddCommand(listHoldingsCommand())
 	cmd.AddCommand(positionsCommand())
+	cmd.AddCommand(optionsCommand())
+	cmd.AddCommand(reconcileHoldingsCommand())
 
 	return cmd
 }
@@ -32,10 +38,23 @@ func listHoldingsCommand() *cobra.Command {
 	var accountName string
 	var all bool
 	var sortBy string
+	var withPrices bool
+	var showLots bool
+	var asOf string
 
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List current holdings with cost basis",
+		Long: `List current holdings with cost basis.
+
+With --prices, each lot is marked to its security's latest stored close (see
+'prices import') and the unrealized gain is split short and long-term by lot
+age. --lots also lists every lot.
+
+With --as-of, lots are rebuilt as they stood at the close of that date from
+the transactions, sales and transfers out dated on or before it, and cash is
+the balance on that date; with --prices they're marked to the last close on
+or before it.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -44,6 +63,14 @@ func listHoldingsCommand() *cobra.Command {
 				return err
 			}
 
+			var date time.Time
+			if asOf != "" {
+				date, err = time.Parse("2006-01-02", asOf)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of date: %w", err)
+				}
+			}
+
 			conn, err := database.Open(ctx, cfg.DBPath)
 			if err != nil {
 				return err
@@ -52,6 +79,12 @@ func listHoldingsCommand() *cobra.Command {
 
 			queries := db.New(conn)
 
+			if all && withPrices {
+				return listHoldingValues(ctx, queries, "", "All Holdings", showLots, date)
+			}
+			if all && asOf != "" {
+				return listHoldingsAsOf(ctx, queries, "", "All Holdings", date)
+			}
 			if all {
 				return listAllHoldings(queries, ctx, sortBy)
 			}
@@ -65,6 +98,13 @@ func listHoldingsCommand() *cobra.Command {
 				return fmt.Errorf("account not found: %s", accountName)
 			}
 
+			if withPrices {
+				return listHoldingValues(ctx, queries, account.ID, account.Name, showLots, date)
+			}
+			if asOf != "" {
+				return listHoldingsAsOf(ctx, queries, account.ID, account.Name, date)
+			}
+
 			holdings, err := queries.ListHoldingsByAccount(ctx, account.ID)
 			if err != nil {
 				return fmt.Errorf("failed to list holdings: %w", err)
@@ -105,10 +145,172 @@ func listHoldingsCommand() *cobra.Command {
 	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
 	cmd.Flags().BoolVar(&all, "all", false, "Show holdings across all accounts")
 	cmd.Flags().StringVar(&sortBy, "sort", "cost", "Sort by: cost, symbol, account")
+	cmd.Flags().BoolVar(&withPrices, "prices", false, "Show market value and unrealized gains from stored prices")
+	cmd.Flags().BoolVar(&showLots, "lots", false, "With --prices, also list each lot")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Show holdings at the close of this date (YYYY-MM-DD)")
 
 	return cmd
 }
 
+// listHoldingValues prints holdings marked to the latest stored prices, for
+// one account or, with an empty accountID, all of them. A non-zero asOf
+// shows the holdings and prices at the close of that date instead.
+func listHoldingValues(ctx context.Context, queries *db.Queries, accountID, title string, showLots bool, asOf time.Time) error {
+	date := asOf
+	if date.IsZero() {
+		date = time.Now()
+	}
+	values, err := taxlots.NewValuer(queries).Value(ctx, accountID, date)
+	if err != nil {
+		return err
+	}
+	holdings := taxlots.SummarizeHoldings(values)
+
+	cash, err := cashBalance(ctx, queries, accountID, asOf)
+	if err != nil {
+		return err
+	}
+
+	if asOf.IsZero() {
+		fmt.Printf("\n=== %s: Unrealized Gains ===\n\n", title)
+	} else {
+		fmt.Printf("\n=== %s: Unrealized Gains as of %s ===\n\n", title, asOf.Format("2006-01-02"))
+	}
+
+	tbl := table.New("Account", "Symbol", "Quantity", "Price", "As Of", "Market Value", "Cost Basis", "Short-Term", "Long-Term", "Total")
+	tbl.WithWriter(os.Stdout)
+
+	var totalCost, totalValue, totalST, totalLT int64
+	var unpriced []string
+	for _, h := range holdings {
+		totalCost += h.CostBasisMicros
+		if !h.Priced {
+			unpriced = append(unpriced, h.Symbol)
+			tbl.AddRow(h.AccountName, h.Symbol, formatQty(float64(h.QuantityMicros)/1_000_000), "-", "-", "-",
+				formatMicros(h.CostBasisMicros), "-", "-", "-")
+			continue
+		}
+		totalValue += h.MarketValueMicros
+		totalST += h.ShortTermGainMicros
+		totalLT += h.LongTermGainMicros
+		tbl.AddRow(
+			h.AccountName,
+			h.Symbol,
+			formatQty(float64(h.QuantityMicros)/1_000_000),
+			formatMicros(h.PriceMicros),
+			h.PriceDate,
+			formatMicros(h.MarketValueMicros),
+			formatMicros(h.CostBasisMicros),
+			formatMicros(h.ShortTermGainMicros),
+			formatMicros(h.LongTermGainMicros),
+			formatMicros(h.GainMicros()),
+		)
+	}
+	tbl.Print()
+
+	if showLots {
+		fmt.Printf("\n=== Lots ===\n\n")
+		lotTbl := table.New("Account", "Symbol", "Acquired", "Quantity", "Market Value", "Cost Basis", "Gain", "Term")
+		lotTbl.WithWriter(os.Stdout)
+		for _, v := range values {
+			qty := float64(v.Lot.RemainingMicros) / 1_000_000
+			if v.Lot.PositionSide == string(taxlots.PositionShort) {
+				qty = -qty
+			}
+			marketValue, gain, term := "-", "-", "-"
+			if v.Priced {
+				marketValue = formatMicros(v.MarketValueMicros)
+				gain = formatMicros(v.GainMicros)
+				term = string(v.HoldingPeriod)
+			}
+			lotTbl.AddRow(v.Lot.AccountName, v.Lot.Symbol, v.Lot.AcquiredDate, formatQty(qty), marketValue,
+				formatMicros(v.CostBasisMicros), gain, term)
+		}
+		lotTbl.Print()
+	}
+
+	fmt.Printf("\nMarket value:         %s\n", formatMicros(totalValue))
+	fmt.Printf("Cost basis:           %s\n", formatMicros(totalCost))
+	fmt.Printf("Unrealized gain:      %s (short-term %s, long-term %s)\n", formatMicros(totalST+totalLT), formatMicros(totalST), formatMicros(totalLT))
+	fmt.Printf("Cash:                 %s\n", formatMicros(cash))
+	fmt.Printf("TOTAL (value + cash): %s\n", formatMicros(totalValue+cash))
+	if len(unpriced) > 0 {
+		fmt.Printf("\nNo price for: %s (not in market value or gains)\n", strings.Join(unpriced, ", "))
+	}
+
+	return nil
+}
+
+// listHoldingsAsOf prints the quantity and cost basis of each holding at the
+// close of asOf, for one account or, with an empty accountID, all of them.
+func listHoldingsAsOf(ctx context.Context, queries *db.Queries, accountID, title string, asOf time.Time) error {
+	lots, err := taxlots.NewValuer(queries).OpenLots(ctx, accountID, asOf)
+	if err != nil {
+		return err
+	}
+	values := taxlots.ValueLots(lots, nil, nil, nil, asOf)
+
+	cash, err := cashBalance(ctx, queries, accountID, asOf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== %s: Holdings as of %s ===\n\n", title, asOf.Format("2006-01-02"))
+
+	tbl := table.New("Account", "Symbol", "Quantity", "Cost Basis", "Acquired")
+	tbl.WithWriter(os.Stdout)
+
+	var totalCostBasis int64
+	for _, h := range taxlots.SummarizeHoldings(values) {
+		totalCostBasis += h.CostBasisMicros
+		tbl.AddRow(h.AccountName, h.Symbol, formatQty(float64(h.QuantityMicros)/1_000_000), formatMicros(h.CostBasisMicros), h.EarliestAcquired)
+	}
+
+	tbl.Print()
+
+	fmt.Printf("\nPositions (cost basis): %s\n", formatMicros(totalCostBasis))
+	fmt.Printf("Cash:                   %s\n", formatMicros(cash))
+	fmt.Printf("TOTAL (cost + cash):    %s\n", formatMicros(totalCostBasis+cash))
+
+	return nil
+}
+
+// cashBalance sums the cash of accountID, or of every account when it is
+// empty, at the close of asOf, or now when asOf is zero.
+func cashBalance(ctx context.Context, queries *db.Queries, accountID string, asOf time.Time) (int64, error) {
+	var accountIDs []string
+	if accountID != "" {
+		accountIDs = []string{accountID}
+	} else {
+		accounts, err := queries.ListAccounts(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		for _, a := range accounts {
+			accountIDs = append(accountIDs, a.ID)
+		}
+	}
+
+	var cash int64
+	for _, id := range accountIDs {
+		var cashVal interface{}
+		if asOf.IsZero() {
+			cashVal, _ = queries.GetCashBalance(ctx, id)
+		} else {
+			v, err := queries.GetCashBalanceAsOfDate(ctx, db.GetCashBalanceAsOfDateParams{
+				AccountID: id,
+				AsOfDate:  asOf.Format("2006-01-02"),
+			})
+			if err != nil {
+				return 0, fmt.Errorf("failed to get cash balance: %w", err)
+			}
+			cashVal = v
+		}
+		cash += toInt64Val(cashVal)
+	}
+	return cash, nil
+}
+
 func listAllHoldings(queries *db.Queries, ctx context.Context, sortBy string) error {
 	holdings, err := queries.ListAllHoldings(ctx)
 	if err != nil {
@@ -229,6 +431,186 @@ func positionsCommand() *cobra.Command {
 	return cmd
 }
 
+func optionsCommand() *cobra.Command {
+	var accountName string
+
+	cmd := &cobra.Command{
+		Use:   "options",
+		Short: "List open option positions next to the underlying shares held",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			lots, err := queries.ListOpenLots(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list open lots: %w", err)
+			}
+			options, err := queries.ListOptions(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list options: %w", err)
+			}
+
+			fmt.Printf("\n=== Option Positions ===\n\n")
+
+			tbl := table.New("Account", "Underlying", "Type", "Strike", "Expires", "Contracts", "Premium", "Shares Held")
+			tbl.WithWriter(os.Stdout)
+
+			var count int
+			for _, p := range taxlots.OptionPositions(lots, options) {
+				if accountID != "" && p.AccountID != accountID {
+					continue
+				}
+				count++
+				tbl.AddRow(
+					p.AccountName,
+					p.Underlying,
+					p.OptionType,
+					formatCurrency(float64(p.StrikeMicros)/1_000_000),
+					p.ExpirationDate,
+					formatQty(float64(p.ContractsMicros)/1_000_000),
+					formatCurrency(float64(p.PremiumMicros)/1_000_000),
+					formatQty(float64(p.UnderlyingMicros)/1_000_000),
+				)
+			}
+
+			if count == 0 {
+				fmt.Println("No open option positions.")
+				return nil
+			}
+			tbl.Print()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+
+	return cmd
+}
+
+func reconcileHoldingsCommand() *cobra.Command {
+	var (
+		accountName string
+		asOf        string
+		all         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare lot-derived holdings with the broker's positions",
+		Long: `Compare the broker's positions snapshot (imported with the account) on or
+before --as-of with the lots open at the close of the snapshot date, per
+symbol, and suggest likely causes for mismatches: a missing opening balance
+(see 'lots check'), a split not applied to lots, dividend reinvestments not
+imported, or a sale or transfer out not imported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			date := time.Now()
+			if asOf != "" {
+				date, err = time.Parse("2006-01-02", asOf)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of date: %w", err)
+				}
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			rec, err := taxlots.NewAnalyzer(queries).ReconcilePositions(ctx, account.ID, date)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n=== %s: Holdings vs Broker Positions on %s ===\n\n", account.Name, rec.AsOfDate)
+
+			lines := rec.Unmatched()
+			if all {
+				lines = rec.Lines
+			}
+
+			tbl := table.New("Symbol", "Status", "Broker Qty", "Our Qty", "Qty Diff", "Broker Basis", "Our Basis", "Basis Diff")
+			tbl.WithWriter(os.Stdout)
+			for _, l := range lines {
+				brokerBasis, basisDiff := "-", "-"
+				if l.BrokerCostBasisValid || l.Status == taxlots.PositionNotReported {
+					brokerBasis = formatMicros(l.BrokerCostBasisMicros)
+					basisDiff = formatMicros(l.CostBasisDiffMicros)
+				}
+				tbl.AddRow(
+					l.Symbol,
+					string(l.Status),
+					formatQty(float64(l.BrokerQuantityMicros)/1_000_000),
+					formatQty(float64(l.LotQuantityMicros)/1_000_000),
+					formatQty(float64(l.QuantityDiffMicros)/1_000_000),
+					brokerBasis,
+					formatMicros(l.LotCostBasisMicros),
+					basisDiff,
+				)
+			}
+			tbl.Print()
+
+			var unmatched int
+			for _, l := range rec.Unmatched() {
+				unmatched++
+				if len(l.Causes) == 0 {
+					continue
+				}
+				fmt.Printf("\n%s:\n", l.Symbol)
+				for _, c := range l.Causes {
+					fmt.Printf("  - %s: %s\n", c.Kind, c.Detail)
+				}
+			}
+
+			fmt.Printf("\nSummary: %d positions, %d need attention\n", len(rec.Lines), unmatched)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Use the latest broker snapshot on or before this date (YYYY-MM-DD, default latest)")
+	cmd.Flags().BoolVar(&all, "all", false, "Also list matched positions")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
 func formatCurrency(amount float64) string {
 	p := message.NewPrinter(language.English)
 	return p.Sprintf("$%.2f", amount)
@@ -241,6 +623,8 @@ func formatQty(qty float64) string {
 
 func nullFloat64ToFloat(nf interface{}) float64 {
 	switch v := nf.(type) {
+	case sql.NullFloat64:
+		return v.Float64
 	case float64:
 		return v
 	case int64:
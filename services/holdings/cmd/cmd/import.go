@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
 	"github.com/levisegal/monay/services/holdings/importer"
+	"github.com/levisegal/monay/services/holdings/taxlots"
 )
 
 func importCommand() *cobra.Command {
@@ -42,7 +46,7 @@ func importCommand() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&broker, "broker", "", "Broker name (etrade, schwab, fidelity, vanguard, lpl)")
+	cmd.Flags().StringVar(&broker, "broker", "", "Broker name (etrade, etrade_stockplan, schwab, fidelity, vanguard, lpl)")
 	cmd.Flags().StringArrayVar(&files, "file", nil, "Path to CSV file(s) - can be repeated")
 	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name for imported data")
 
@@ -73,6 +77,7 @@ func runImport(ctx context.Context, cfg *config.Config, brokerName, filePath, ac
 	slog.Info("parsed CSV",
 		"transactions", len(result.Transactions),
 		"positions", len(result.Positions),
+		"skipped", len(result.Diagnostics),
 	)
 
 	conn, err := database.Open(ctx, cfg.DBPath)
@@ -101,18 +106,47 @@ func runImport(ctx context.Context, cfg *config.Config, brokerName, filePath, ac
 		var securityID sql.NullString
 
 		if txn.Symbol != "" {
+			var securityType sql.NullString
+			switch {
+			case txn.Option != nil:
+				securityType = sql.NullString{String: "option", Valid: true}
+			case txn.Bond != nil:
+				securityType = sql.NullString{String: "bond", Valid: true}
+			}
+
 			sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
-				ID:     database.NewID(database.PrefixSecurity),
-				Symbol: txn.Symbol,
-				Name:   sql.NullString{String: txn.SecurityName, Valid: txn.SecurityName != ""},
+				ID:           database.NewID(database.PrefixSecurity),
+				Symbol:       txn.Symbol,
+				Name:         sql.NullString{String: txn.SecurityName, Valid: txn.SecurityName != ""},
+				SecurityType: securityType,
 			})
 			if err != nil {
 				return fmt.Errorf("failed to upsert security %s: %w", txn.Symbol, err)
 			}
 			securityID = sql.NullString{String: sec.ID, Valid: true}
+
+			if txn.Option != nil {
+				err := queries.UpsertOption(ctx, db.UpsertOptionParams{
+					SecurityID:       sec.ID,
+					UnderlyingSymbol: txn.Option.Underlying,
+					ExpirationDate:   txn.Option.Expiration.Format("2006-01-02"),
+					OptionType:       string(txn.Option.Type),
+					StrikeMicros:     txn.Option.StrikeMicros,
+					Multiplier:       txn.Option.Multiplier,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to upsert option %s: %w", txn.Symbol, err)
+				}
+			}
+
+			if txn.Bond != nil {
+				if err := queries.UpsertBond(ctx, bondParams(sec.ID, txn.Bond)); err != nil {
+					return fmt.Errorf("failed to upsert bond %s: %w", txn.Symbol, err)
+				}
+			}
 		}
 
-		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		params := db.CreateTransactionParams{
 			ID:              database.NewID(database.PrefixTransaction),
 			AccountID:       account.ID,
 			SecurityID:      securityID,
@@ -122,11 +156,19 @@ func runImport(ctx context.Context, cfg *config.Config, brokerName, filePath, ac
 			PriceMicros:     sql.NullInt64{Int64: txn.PriceMicros, Valid: true},
 			AmountMicros:    txn.AmountMicros,
 			FeesMicros:      sql.NullInt64{Int64: txn.FeesMicros, Valid: true},
+			FeesInAmount:    importer.AmountIncludesFees(importer.Broker(brokerName)),
 			Description:     sql.NullString{String: txn.Description, Valid: txn.Description != ""},
-		})
-		if err != nil {
+			DividendType:    sql.NullString{String: string(txn.DividendType), Valid: txn.DividendType != ""},
+		}
+		if err := queries.CreateTransaction(ctx, params); err != nil {
 			return fmt.Errorf("failed to create transaction: %w", err)
 		}
+
+		if txn.Compensation != nil {
+			if err := upsertCompensation(ctx, queries, params, txn.Compensation); err != nil {
+				return err
+			}
+		}
 	}
 
 	for _, pos := range result.Positions {
@@ -153,11 +195,115 @@ func runImport(ctx context.Context, cfg *config.Config, brokerName, filePath, ac
 		}
 	}
 
+	sourceFile := filepath.Base(filePath)
+	if err := recordCashBalances(ctx, queries, account.ID, sourceFile, result.CashBalances); err != nil {
+		return err
+	}
+
+	for _, d := range result.Diagnostics {
+		date := d.Date.Format("2006-01-02")
+		err := queries.CreateImportDiagnostic(ctx, db.CreateImportDiagnosticParams{
+			ID:           database.DerivedID(database.PrefixDiagnostic, account.ID, sourceFile, strconv.Itoa(d.Line)),
+			AccountID:    account.ID,
+			SourceFile:   sourceFile,
+			LineNumber:   int64(d.Line),
+			ActivityDate: date,
+			Activity:     d.Activity,
+			Symbol:       sql.NullString{String: d.Symbol, Valid: d.Symbol != ""},
+			AmountMicros: d.AmountMicros,
+			Description:  sql.NullString{String: d.Description, Valid: d.Description != ""},
+			Reason:       string(d.Reason),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record import diagnostic: %w", err)
+		}
+	}
+
 	slog.Info("import complete",
 		"account", accountName,
 		"transactions", len(result.Transactions),
 		"positions", len(result.Positions),
+		"cash_balances", len(result.CashBalances),
+		"skipped", len(result.Diagnostics),
 	)
 
 	return nil
 }
+
+// recordCashBalances saves the cash a positions file reports as checkpoints for
+// 'cash reconcile', adding up the cash rows on each date.
+func recordCashBalances(ctx context.Context, queries *db.Queries, accountID, sourceFile string, balances []importer.CashBalance) error {
+	totals := make(map[string]int64)
+	var dates []string
+	for _, b := range balances {
+		date := b.AsOfDate.Format("2006-01-02")
+		if _, ok := totals[date]; !ok {
+			dates = append(dates, date)
+		}
+		totals[date] += b.BalanceMicros
+	}
+
+	for _, date := range dates {
+		err := queries.UpsertCashCheckpoint(ctx, db.UpsertCashCheckpointParams{
+			AccountID:      accountID,
+			CheckpointDate: date,
+			BalanceMicros:  totals[date],
+			Source:         "positions",
+			Description:    sql.NullString{String: sourceFile, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save cash checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// upsertCompensation records the plan details of an RSU vest or ESPP purchase.
+// The transaction is looked up by its unique key, since a re-import keeps the
+// ID from the first one.
+func upsertCompensation(ctx context.Context, queries *db.Queries, txn db.CreateTransactionParams, comp *importer.Compensation) error {
+	id, err := queries.GetTransactionIDByKey(ctx, db.GetTransactionIDByKeyParams{
+		AccountID:       txn.AccountID,
+		SecurityID:      txn.SecurityID,
+		TransactionType: txn.TransactionType,
+		TransactionDate: txn.TransactionDate,
+		QuantityMicros:  txn.QuantityMicros,
+		AmountMicros:    txn.AmountMicros,
+		Description:     txn.Description,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find transaction: %w", err)
+	}
+
+	err = queries.UpsertAcquisition(ctx, db.UpsertAcquisitionParams{
+		TransactionID:        id,
+		AcquisitionKind:      string(taxlots.AcquisitionCompensation),
+		FmvMicros:            sql.NullInt64{Int64: comp.FMVMicros, Valid: true},
+		PlanType:             sql.NullString{String: string(comp.PlanType), Valid: true},
+		SharesWithheldMicros: sql.NullInt64{Int64: comp.SharesWithheldMicros, Valid: comp.SharesWithheldMicros != 0},
+		GrantDate:            sql.NullString{String: comp.GrantDate.Format("2006-01-02"), Valid: !comp.GrantDate.IsZero()},
+		GrantFmvMicros:       sql.NullInt64{Int64: comp.GrantFMVMicros, Valid: comp.GrantFMVMicros != 0},
+		DiscountRate:         sql.NullFloat64{Float64: comp.DiscountRate, Valid: comp.PlanType == importer.PlanTypeESPP},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record compensation: %w", err)
+	}
+	return nil
+}
+
+func bondParams(securityID string, b *importer.BondTerms) db.UpsertBondParams {
+	date := func(t time.Time) sql.NullString {
+		return sql.NullString{String: t.Format("2006-01-02"), Valid: !t.IsZero()}
+	}
+	return db.UpsertBondParams{
+		SecurityID:      securityID,
+		CouponRate:      b.CouponRate,
+		MaturityDate:    b.Maturity.Format("2006-01-02"),
+		DatedDate:       date(b.DatedDate),
+		FirstCouponDate: date(b.FirstCouponDate),
+		CallDate:        date(b.CallDate),
+		CallPriceMicros: sql.NullInt64{Int64: b.CallPriceMicros, Valid: !b.CallDate.IsZero()},
+		ParMicros:       b.ParMicros,
+		PaymentsPerYear: b.CouponsPerYear,
+	}
+}
@@ -0,0 +1,488 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+func incomeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "income",
+		Short: "Income reports from cash transactions",
+		Long: `Roll up dividends, interest, capital gain distributions, fees and
+distributions to linked accounts from the cash ledger ("cash generate").
+
+Dividends keep the type the broker exported (qualified, ordinary, section
+199A, exempt-interest, return of capital). Untyped and foreign dividends are
+estimated: dividends from bond, money market and real estate funds are
+ordinary and from muni funds exempt-interest; bond coupons are tax-exempt
+when the issuer is named like a state or local government, authority or
+district, and taxable otherwise. Interest not paid by a bond is sweep
+interest.`,
+	}
+
+	cmd.AddCommand(incomeSummaryCommand())
+	cmd.AddCommand(incomeBySecurityCommand())
+	cmd.AddCommand(incomeProjectCommand())
+	cmd.AddCommand(income1099DIVCommand())
+
+	return cmd
+}
+
+func incomeSummaryCommand() *cobra.Command {
+	var (
+		accountName string
+		year        int
+		by          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "summary",
+		Short: "Show income, fees and distributions for a year, with YTD rollups",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			grouping := income.Grouping(by)
+			switch grouping {
+			case income.GroupByMonth, income.GroupByQuarter, income.GroupByYear:
+			default:
+				return fmt.Errorf("invalid --by %q: use month, quarter or year", by)
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			title := "All Accounts"
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				title, accountID = account.Name, account.ID
+			}
+
+			start, end := income.YearRange(year, time.Now())
+			rows, err := income.NewReporter(queries).Activity(ctx, accountID, start, end)
+			if err != nil {
+				return err
+			}
+			summary := income.Summarize(rows, start, end, grouping)
+
+			var cashMicros int64
+			if accountID != "" {
+				balance, err := queries.GetCashBalance(ctx, accountID)
+				if err != nil {
+					return fmt.Errorf("failed to get cash balance: %w", err)
+				}
+				cashMicros = toInt64(balance)
+			} else {
+				accounts, err := queries.ListAccounts(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to list accounts: %w", err)
+				}
+				for _, a := range accounts {
+					balance, err := queries.GetCashBalance(ctx, a.ID)
+					if err != nil {
+						return fmt.Errorf("failed to get cash balance: %w", err)
+					}
+					cashMicros += toInt64(balance)
+				}
+			}
+
+			label := fmt.Sprintf("%d", year)
+			if year == 0 {
+				label = "All Years"
+			} else if end.Year() == year && end.Before(time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)) {
+				label = fmt.Sprintf("%d YTD through %s", year, end.Format("2006-01-02"))
+			}
+			printIncomeSummary(title, label, summary.Totals, cashMicros)
+
+			if len(summary.Periods) > 0 {
+				fmt.Printf("\nBy %s:\n", grouping)
+				tbl := table.New("Period", "Dividends", "Interest", "Cap Gains", "Fees", "Net", "YTD Income", "YTD Net")
+				tbl.WithWriter(os.Stdout)
+				for _, p := range summary.Periods {
+					tbl.AddRow(
+						p.Label,
+						formatMicros(p.Totals.DividendsMicros()),
+						formatMicros(p.Totals.InterestMicros()),
+						formatMicros(p.Totals.CapitalGainsMicros),
+						formatMicros(p.Totals.FeesMicros),
+						formatMicros(p.Totals.NetMicros()),
+						formatMicros(p.YTD.IncomeMicros()),
+						formatMicros(p.YTD.NetMicros()),
+					)
+				}
+				tbl.Print()
+			}
+
+			if accountID == "" && len(summary.Accounts) > 0 {
+				fmt.Printf("\nBy account:\n")
+				tbl := table.New("Account", "Dividends", "Interest", "Tax-Exempt", "Cap Gains", "Fees", "Net", "Distributions")
+				tbl.WithWriter(os.Stdout)
+				for _, a := range summary.Accounts {
+					tbl.AddRow(
+						a.AccountName,
+						formatMicros(a.Totals.DividendsMicros()),
+						formatMicros(a.Totals.InterestMicros()),
+						formatMicros(a.Totals.TaxExemptMicros()),
+						formatMicros(a.Totals.CapitalGainsMicros),
+						formatMicros(a.Totals.FeesMicros),
+						formatMicros(a.Totals.NetMicros()),
+						formatMicros(a.Totals.DistributionsMicros),
+					)
+				}
+				tbl.Print()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+	cmd.Flags().IntVar(&year, "year", time.Now().Year(), "Calendar year, 0 for all years")
+	cmd.Flags().StringVar(&by, "by", "month", "Period rollup: month, quarter or year")
+
+	return cmd
+}
+
+func incomeBySecurityCommand() *cobra.Command {
+	var (
+		accountName string
+		year        int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "by-security",
+		Short: "Show income paid by each security",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			title := "All Accounts"
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				title, accountID = account.Name, account.ID
+			}
+
+			start, end := income.YearRange(year, time.Now())
+			rows, err := income.NewReporter(queries).Activity(ctx, accountID, start, end)
+			if err != nil {
+				return err
+			}
+			lines := income.BySecurity(rows)
+			if len(lines) == 0 {
+				fmt.Printf("No income found for %s\n", title)
+				return nil
+			}
+
+			label := "All Years"
+			if year != 0 {
+				label = fmt.Sprintf("%d", year)
+			}
+			fmt.Printf("\n=== %s: Income by Security (%s) ===\n\n", title, label)
+
+			tbl := table.New("Symbol", "Name", "Type", "Payments", "Amount", "Last Paid")
+			tbl.WithWriter(os.Stdout)
+			var total int64
+			for _, l := range lines {
+				name := strings.Join(strings.Fields(l.Name), " ")
+				if len(name) > 32 {
+					name = name[:29] + "..."
+				}
+				symbol := l.Symbol
+				if symbol == "" {
+					symbol = "(cash)"
+				}
+				tbl.AddRow(symbol, name, incomeCategoryLabel(l.Category), l.Payments, formatMicros(l.AmountMicros), l.LastPaid)
+				if l.Category != income.CategoryReturnOfCapital {
+					total += l.AmountMicros
+				}
+			}
+			tbl.Print()
+			fmt.Printf("\nTotal Income: %s\n", formatMicros(total))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+	cmd.Flags().IntVar(&year, "year", 0, "Calendar year (default: all years)")
+
+	return cmd
+}
+
+func incomeProjectCommand() *cobra.Command {
+	var (
+		accountName string
+		payments    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "project",
+		Short: "Project the next 12 months of income from current holdings",
+		Long: `Project income over the twelve months starting this month from the lots
+open today. Bonds pay their coupon schedule and their face value at maturity.
+Money market funds and sweep cash pay the average of their last three months
+each month end. Other holdings repeat their last payment per share, at the
+frequency of the last thirteen months of payments, on today's quantity.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			title := "All Accounts"
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				title, accountID = account.Name, account.ID
+			}
+
+			projection, err := income.NewProjector(queries).Project(ctx, accountID, time.Now())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n=== %s: Projected Income (%s to %s) ===\n\n", title, projection.ReferenceDate, projection.EndDate)
+
+			tbl := table.New("Month", "Dividends", "Interest", "MMF", "Total", "Maturities")
+			tbl.WithWriter(os.Stdout)
+			for _, m := range projection.Months {
+				tbl.AddRow(
+					m.Label,
+					formatMicros(m.DividendMicros),
+					formatMicros(m.InterestMicros),
+					formatMicros(m.MoneyMarketMicros),
+					formatMicros(m.IncomeMicros()),
+					formatMicros(m.MaturityMicros),
+				)
+			}
+			tbl.Print()
+
+			totals := projection.Totals()
+			fmt.Printf("\nProjected Income: %s\n", formatMicros(totals.IncomeMicros()))
+			if totals.MaturityMicros != 0 {
+				fmt.Printf("Maturing Principal: %s\n", formatMicros(totals.MaturityMicros))
+			}
+
+			if payments && len(projection.Payments)+len(projection.Maturities) > 0 {
+				fmt.Printf("\nPayments:\n")
+				tbl := table.New("Date", "Symbol", "Type", "Frequency", "Per Share", "Amount")
+				tbl.WithWriter(os.Stdout)
+				all := append(append([]income.ProjectedPayment{}, projection.Payments...), projection.Maturities...)
+				sort.SliceStable(all, func(i, j int) bool { return all[i].PaymentDate < all[j].PaymentDate })
+				for _, p := range all {
+					tbl.AddRow(
+						p.PaymentDate,
+						p.Symbol,
+						string(p.Type),
+						string(p.Frequency),
+						fmt.Sprintf("%.4f", float64(p.PerShareMicros)/1_000_000),
+						formatMicros(p.AmountMicros),
+					)
+				}
+				tbl.Print()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+	cmd.Flags().BoolVar(&payments, "payments", false, "List each projected payment")
+
+	return cmd
+}
+
+func income1099DIVCommand() *cobra.Command {
+	var (
+		accountName string
+		year        int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "1099-div",
+		Short: "Estimate 1099-DIV boxes for a year, to check against the broker's form",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if year <= 0 {
+				return fmt.Errorf("--year is required")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			forms, err := income.NewReporter(queries).Form1099DIV(ctx, accountID, year)
+			if err != nil {
+				return err
+			}
+			if len(forms) == 0 {
+				fmt.Printf("No dividends found for %d\n", year)
+				return nil
+			}
+
+			for _, f := range forms {
+				fmt.Printf("\n=== %s: Estimated 1099-DIV (%d) ===\n\n", f.AccountName, f.Year)
+
+				tbl := table.New("Box", "Description", "Amount")
+				tbl.WithWriter(os.Stdout)
+				tbl.AddRow("1a", "Total ordinary dividends", formatMicros(f.OrdinaryDividendsMicros))
+				tbl.AddRow("1b", "Qualified dividends", formatMicros(f.QualifiedDividendsMicros))
+				tbl.AddRow("2a", "Total capital gain distributions", formatMicros(f.CapitalGainsMicros))
+				tbl.AddRow("3", "Nondividend distributions", formatMicros(f.NondividendMicros))
+				tbl.AddRow("5", "Section 199A dividends", formatMicros(f.Section199AMicros))
+				tbl.AddRow("7", "Foreign tax paid", formatMicros(f.ForeignTaxMicros))
+				tbl.AddRow("12", "Exempt-interest dividends", formatMicros(f.ExemptInterestMicros))
+				tbl.Print()
+
+				if f.EstimatedMicros != 0 {
+					fmt.Printf("\n%s of dividends had no type from the broker and were classified by security name.\n",
+						formatMicros(f.EstimatedMicros))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+	cmd.Flags().IntVar(&year, "year", time.Now().Year()-1, "Tax year")
+
+	return cmd
+}
+
+func printIncomeSummary(title, label string, t income.Totals, cashMicros int64) {
+	rule := "  " + strings.Repeat("─", 33)
+	line := func(name string, micros int64) {
+		fmt.Printf("  %-20s %12s\n", name, formatMicros(micros))
+	}
+	nonzero := func(name string, micros int64) {
+		if micros != 0 {
+			line(name, micros)
+		}
+	}
+
+	fmt.Printf("\n=== %s: Cash Activity (%s) ===\n\n", title, label)
+
+	fmt.Println("INCOME:")
+	nonzero("Qualified dividends", t.QualifiedDividendsMicros)
+	nonzero("Ordinary dividends", t.OrdinaryDividendsMicros)
+	nonzero("Exempt dividends", t.ExemptDividendsMicros)
+	nonzero("Cap gain distrib.", t.CapitalGainsMicros)
+	nonzero("Interest (bonds)", t.BondInterestMicros)
+	nonzero("Interest (munis)", t.MuniInterestMicros)
+	nonzero("Interest (sweep)", t.SweepInterestMicros)
+	fmt.Println(rule)
+	line("Total Income", t.IncomeMicros())
+	if exempt := t.TaxExemptMicros(); exempt != 0 {
+		line("  of which exempt", exempt)
+	}
+
+	fmt.Println("\nEXPENSES:")
+	line("Advisory Fees", t.FeesMicros)
+	fmt.Println(rule)
+	line("Net Income", t.NetMicros())
+
+	if t.ReturnOfCapitalMicros != 0 {
+		fmt.Println("\nRETURN OF CAPITAL (reduces basis):")
+		line("Return of capital", t.ReturnOfCapitalMicros)
+	}
+
+	fmt.Println("\nDISTRIBUTIONS:")
+	line("To linked account", t.DistributionsMicros)
+
+	fmt.Printf("\n%-22s %12s\n", "CURRENT CASH:", formatMicros(cashMicros))
+}
+
+func incomeCategoryLabel(c income.Category) string {
+	switch c {
+	case income.CategoryQualifiedDividend:
+		return "qualified dividend"
+	case income.CategoryOrdinaryDividend:
+		return "ordinary dividend"
+	case income.CategoryExemptDividend:
+		return "exempt dividend"
+	case income.CategoryCapitalGain:
+		return "cap gain"
+	case income.CategoryBondInterest:
+		return "bond interest"
+	case income.CategoryMuniInterest:
+		return "muni interest"
+	case income.CategorySweepInterest:
+		return "sweep interest"
+	case income.CategoryReturnOfCapital:
+		return "return of capital"
+	default:
+		return string(c)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rodaine/table"
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 
@@ -29,6 +31,10 @@ func lotsCommand() *cobra.Command {
 	cmd.AddCommand(createLotsCommand())
 	cmd.AddCommand(checkLotsCommand())
 	cmd.AddCommand(clearLotsCommand())
+	cmd.AddCommand(auditLotsCommand())
+	cmd.AddCommand(listLotsCommand())
+	cmd.AddCommand(adjustLotCommand())
+	cmd.AddCommand(listAdjustmentsCommand())
 
 	return cmd
 }
@@ -64,10 +70,18 @@ func clearLotsCommand() *cobra.Command {
 				return fmt.Errorf("failed to delete lots: %w", err)
 			}
 
+			if err := queries.DeleteLotJournalByAccount(ctx, account.ID); err != nil {
+				return fmt.Errorf("failed to delete lot journal: %w", err)
+			}
+
 			if err := queries.DeleteTransactionsByAccount(ctx, account.ID); err != nil {
 				return fmt.Errorf("failed to delete transactions: %w", err)
 			}
 
+			if err := queries.DeleteImportDiagnosticsByAccount(ctx, account.ID); err != nil {
+				return fmt.Errorf("failed to delete import diagnostics: %w", err)
+			}
+
 			slog.Info("cleared account data", "account", account.Name)
 			return nil
 		},
@@ -79,16 +93,229 @@ func clearLotsCommand() *cobra.Command {
 	return cmd
 }
 
+func auditLotsCommand() *cobra.Command {
+	var accountName string
+	var year int
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show gross proceeds, fees and net proceeds for each lot disposition",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			dispositions, err := queries.ListDispositionsByAccount(ctx, account.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list dispositions: %w", err)
+			}
+
+			fmt.Printf("\n=== %s: Lot Dispositions ===\n\n", account.Name)
+
+			tbl := table.New("Sold", "Symbol", "Acquired", "Quantity", "Gross", "Fees", "Net", "Basis", "Gain", "Term")
+			tbl.WithWriter(os.Stdout)
+
+			var totalGross, totalFees, totalNet, totalBasis, totalGain int64
+			for _, d := range dispositions {
+				if year > 0 && !strings.HasPrefix(d.DisposedDate, fmt.Sprintf("%d-", year)) {
+					continue
+				}
+
+				gross := d.ProceedsMicros + d.FeesMicros
+				tbl.AddRow(
+					d.DisposedDate,
+					d.Symbol,
+					d.AcquiredDate,
+					formatQty(float64(d.QuantityMicros)/1_000_000),
+					formatMicros(gross),
+					formatMicros(d.FeesMicros),
+					formatMicros(d.ProceedsMicros),
+					formatMicros(d.CostBasisMicros),
+					formatMicros(d.RealizedGainMicros),
+					d.HoldingPeriod,
+				)
+
+				totalGross += gross
+				totalFees += d.FeesMicros
+				totalNet += d.ProceedsMicros
+				totalBasis += d.CostBasisMicros
+				totalGain += d.RealizedGainMicros
+			}
+
+			tbl.Print()
+
+			fmt.Printf("\nGross proceeds: %s\n", formatMicros(totalGross))
+			fmt.Printf("Fees:           %s\n", formatMicros(totalFees))
+			fmt.Printf("Net proceeds:   %s\n", formatMicros(totalNet))
+			fmt.Printf("Cost basis:     %s\n", formatMicros(totalBasis))
+			fmt.Printf("Realized gain:  %s\n", formatMicros(totalGain))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().IntVar(&year, "year", 0, "Filter by year sold (optional)")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
+func listLotsCommand() *cobra.Command {
+	var accountName string
+	var symbol string
+	var showIDs bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List open lots with the date each becomes long-term",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			lots, err := queries.ListLotsByAccount(ctx, account.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list lots: %w", err)
+			}
+
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+
+			fmt.Printf("\n=== %s: Open Lots ===\n\n", account.Name)
+
+			columns := []interface{}{"Symbol", "Acquired", "Kind", "Remaining", "Cost Basis", "Long-Term On", "Status"}
+			if showIDs {
+				columns = append(columns, "Lot ID")
+			}
+			tbl := table.New(columns...)
+			tbl.WithWriter(os.Stdout)
+
+			var shortTermBasis, longTermBasis int64
+			for _, lot := range lots {
+				if lot.RemainingMicros <= 0 {
+					continue
+				}
+				if symbol != "" && !strings.EqualFold(lot.Symbol, symbol) {
+					continue
+				}
+
+				if _, err := time.Parse("2006-01-02", lot.AcquiredDate); err != nil {
+					return fmt.Errorf("invalid acquired date on lot %s: %w", lot.ID, err)
+				}
+				longTerm := taxlots.LongTermDateFor(taxlots.AcquisitionKind(lot.AcquisitionKind), lot.AcquiredDate, lot.DonorAcquiredDate.String)
+
+				costBasis := taxlots.ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros)
+
+				if lot.PositionSide == string(taxlots.PositionShort) {
+					// Owed back rather than held; the basis column is what
+					// the short sale brought in.
+					row := []interface{}{
+						lot.Symbol,
+						lot.AcquiredDate,
+						lot.AcquisitionKind,
+						formatQty(-float64(lot.RemainingMicros) / 1_000_000),
+						formatMicros(-costBasis),
+						"-",
+						"short",
+					}
+					if showIDs {
+						row = append(row, lot.ID)
+					}
+					tbl.AddRow(row...)
+					continue
+				}
+
+				status := "long-term"
+				if today.Before(longTerm) {
+					days := int(longTerm.Sub(today).Hours() / 24)
+					status = fmt.Sprintf("short-term (%d days)", days)
+					shortTermBasis += costBasis
+				} else {
+					longTermBasis += costBasis
+				}
+
+				row := []interface{}{
+					lot.Symbol,
+					lot.AcquiredDate,
+					lot.AcquisitionKind,
+					formatQty(float64(lot.RemainingMicros) / 1_000_000),
+					formatMicros(costBasis),
+					longTerm.Format("2006-01-02"),
+					status,
+				}
+				if showIDs {
+					row = append(row, lot.ID)
+				}
+				tbl.AddRow(row...)
+			}
+
+			tbl.Print()
+
+			fmt.Printf("\nShort-term basis: %s\n", formatMicros(shortTermBasis))
+			fmt.Printf("Long-term basis:  %s\n", formatMicros(longTermBasis))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Filter by symbol (optional)")
+	cmd.Flags().BoolVar(&showIDs, "ids", false, "Show lot IDs (for lots adjust)")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
 func processLotsCommand() *cobra.Command {
 	var accountName string
+	var full bool
 
 	cmd := &cobra.Command{
 		Use:   "process",
 		Short: "Process tax lots from transactions (FIFO matching)",
 		Long: `Process transactions to create tax lots and match sells to buys using FIFO.
 
-This should be run after importing all transaction history for an account.
-It will clear existing lots and recompute from scratch.`,
+This should be run after importing transaction history for an account. Only
+securities whose transactions changed since the last run are replayed, from
+the earliest changed date; --full replays the whole history. Lot IDs are
+derived from the transactions that create them, so they stay the same across
+runs. Same-day transactions replay buys before sells.
+
+Shares transferred between our accounts are matched by security, quantity and
+date. Matched transfers move the original lots, with their acquisition dates
+and basis, into the receiving account; both accounts are rebuilt together.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -113,22 +340,54 @@ It will clear existing lots and recompute from scratch.`,
 			slog.Info("processing lots", "account", account.Name, "account_id", account.ID)
 
 			processor := taxlots.NewProcessor(queries)
-			if err := processor.ProcessTransactions(ctx, account.ID); err != nil {
+			process := processor.ProcessTransactions
+			if full {
+				process = processor.RebuildTransactions
+			}
+			result, err := process(ctx, account.ID)
+			if err != nil {
 				return fmt.Errorf("failed to process tax lots: %w", err)
 			}
 
-			slog.Info("lot processing complete", "account", account.Name)
+			slog.Info("lot processing complete",
+				"account", account.Name,
+				"accounts_processed", len(result.AccountIDs),
+				"securities_replayed", len(result.Replays),
+				"transfers_matched", len(result.Transfers),
+			)
+
+			printUnmatchedTransfers(result.UnmatchedTransfers)
 
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name to process")
+	cmd.Flags().BoolVar(&full, "full", false, "Replay all history instead of only what changed")
 	cmd.MarkFlagRequired("account-name")
 
 	return cmd
 }
 
+func printUnmatchedTransfers(unmatched []taxlots.UnmatchedTransfer) {
+	if len(unmatched) == 0 {
+		return
+	}
+
+	fmt.Println("\nUNMATCHED TRANSFERS (no counterpart in another account):")
+	fmt.Printf("  %-12s %-18s %-20s %-10s %15s\n", "Date", "Type", "Account", "Symbol", "Quantity")
+	for _, u := range unmatched {
+		fmt.Printf("  %-12s %-18s %-20s %-10s %15.2f\n",
+			u.TransactionDate,
+			u.TransactionType,
+			u.AccountName,
+			u.Symbol,
+			float64(u.QuantityMicros)/1_000_000,
+		)
+	}
+	fmt.Println("\nTransfers in keep the amount reported by the broker; transfers out relieve lots without a gain.")
+}
+
 func createLotsCommand() *cobra.Command {
 	var accountName string
 
@@ -170,30 +429,8 @@ This will prompt you for symbol, quantity, cost basis, and acquisition date for
 					break
 				}
 
-				sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
-					ID:     database.NewID(database.PrefixSecurity),
-					Symbol: lot.symbol,
-					Name:   sql.NullString{},
-				})
-				if err != nil {
-					return fmt.Errorf("failed to upsert security: %w", err)
-				}
-
-				txnID := database.NewID(database.PrefixTransaction)
-				err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
-					ID:              txnID,
-					AccountID:       account.ID,
-					SecurityID:      sql.NullString{String: sec.ID, Valid: true},
-					TransactionType: string(importer.TransactionTypeOpeningBalance),
-					TransactionDate: lot.acquiredDate.Format("2006-01-02"),
-					QuantityMicros:  sql.NullInt64{Int64: lot.quantityMicros, Valid: true},
-					PriceMicros:     sql.NullInt64{Int64: lot.costBasisMicros / lot.quantityMicros, Valid: true},
-					AmountMicros:    lot.costBasisMicros,
-					FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
-					Description:     sql.NullString{String: "Opening balance - manual entry", Valid: true},
-				})
-				if err != nil {
-					return fmt.Errorf("failed to create transaction: %w", err)
+				if err := createOpeningBalance(ctx, queries, account.ID, lot); err != nil {
+					return err
 				}
 
 				slog.Info("created opening balance",
@@ -201,6 +438,7 @@ This will prompt you for symbol, quantity, cost basis, and acquisition date for
 					"quantity", float64(lot.quantityMicros)/1_000_000,
 					"cost_basis", float64(lot.costBasisMicros)/1_000_000,
 					"acquired", lot.acquiredDate.Format("2006-01-02"),
+					"kind", lot.kind,
 				)
 
 				fmt.Print("\nAdd another lot? (y/n): ")
@@ -226,10 +464,59 @@ This will prompt you for symbol, quantity, cost basis, and acquisition date for
 }
 
 type lotInput struct {
-	symbol          string
-	quantityMicros  int64
-	costBasisMicros int64
-	acquiredDate    time.Time
+	symbol            string
+	quantityMicros    int64
+	costBasisMicros   int64
+	acquiredDate      time.Time
+	kind              taxlots.AcquisitionKind
+	fmvMicros         sql.NullInt64
+	donorAcquiredDate sql.NullString
+}
+
+// createOpeningBalance records an entered lot as an opening_balance
+// transaction, plus how it was acquired when it wasn't a purchase.
+func createOpeningBalance(ctx context.Context, queries *db.Queries, accountID string, lot *lotInput) error {
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: lot.symbol,
+		Name:   sql.NullString{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert security: %w", err)
+	}
+
+	txnID := database.NewID(database.PrefixTransaction)
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              txnID,
+		AccountID:       accountID,
+		SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		TransactionType: string(importer.TransactionTypeOpeningBalance),
+		TransactionDate: lot.acquiredDate.Format("2006-01-02"),
+		QuantityMicros:  sql.NullInt64{Int64: lot.quantityMicros, Valid: true},
+		PriceMicros:     sql.NullInt64{Int64: lot.costBasisMicros / lot.quantityMicros, Valid: true},
+		AmountMicros:    lot.costBasisMicros,
+		FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
+		FeesInAmount:    true,
+		Description:     sql.NullString{String: "Opening balance - manual entry", Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if lot.kind == "" || lot.kind == taxlots.AcquisitionPurchase {
+		return nil
+	}
+
+	err = queries.UpsertAcquisition(ctx, db.UpsertAcquisitionParams{
+		TransactionID:     txnID,
+		AcquisitionKind:   string(lot.kind),
+		FmvMicros:         lot.fmvMicros,
+		DonorAcquiredDate: lot.donorAcquiredDate,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record acquisition: %w", err)
+	}
+	return nil
 }
 
 func checkLotsCommand() *cobra.Command {
@@ -340,29 +627,8 @@ Use --fix to interactively add opening balances for positions needing review.`,
 					continue
 				}
 
-				sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
-					ID:     database.NewID(database.PrefixSecurity),
-					Symbol: lot.symbol,
-					Name:   sql.NullString{},
-				})
-				if err != nil {
-					return fmt.Errorf("failed to upsert security: %w", err)
-				}
-
-				err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
-					ID:              database.NewID(database.PrefixTransaction),
-					AccountID:       account.ID,
-					SecurityID:      sql.NullString{String: sec.ID, Valid: true},
-					TransactionType: string(importer.TransactionTypeOpeningBalance),
-					TransactionDate: lot.acquiredDate.Format("2006-01-02"),
-					QuantityMicros:  sql.NullInt64{Int64: lot.quantityMicros, Valid: true},
-					PriceMicros:     sql.NullInt64{Int64: lot.costBasisMicros / lot.quantityMicros, Valid: true},
-					AmountMicros:    lot.costBasisMicros,
-					FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
-					Description:     sql.NullString{String: "Opening balance - manual entry", Valid: true},
-				})
-				if err != nil {
-					return fmt.Errorf("failed to create transaction: %w", err)
+				if err := createOpeningBalance(ctx, queries, account.ID, lot); err != nil {
+					return err
 				}
 
 				fmt.Printf("Created opening balance for %s.\n", lot.symbol)
@@ -397,7 +663,13 @@ func promptForLotWithSymbol(scanner *bufio.Scanner, symbol string, suggestedQtyM
 		quantityMicros = qtyDec.Mul(decimal.NewFromInt(1_000_000)).IntPart()
 	}
 
-	fmt.Print("Total cost basis ($): ")
+	kind, err := promptForKind(scanner)
+	if err != nil {
+		return nil, err
+	}
+	costPrompt, datePrompt := basisPrompts(kind)
+
+	fmt.Print(costPrompt)
 	if !scanner.Scan() {
 		return nil, scanner.Err()
 	}
@@ -411,7 +683,7 @@ func promptForLotWithSymbol(scanner *bufio.Scanner, symbol string, suggestedQtyM
 	}
 	costBasisMicros := costDec.Mul(decimal.NewFromInt(1_000_000)).IntPart()
 
-	fmt.Print("Acquisition date (YYYY-MM-DD): ")
+	fmt.Print(datePrompt)
 	if !scanner.Scan() {
 		return nil, scanner.Err()
 	}
@@ -424,12 +696,17 @@ func promptForLotWithSymbol(scanner *bufio.Scanner, symbol string, suggestedQtyM
 		return nil, fmt.Errorf("invalid date (use YYYY-MM-DD): %w", err)
 	}
 
-	return &lotInput{
+	lot := &lotInput{
 		symbol:          symbol,
 		quantityMicros:  quantityMicros,
 		costBasisMicros: costBasisMicros,
 		acquiredDate:    acquiredDate,
-	}, nil
+		kind:            kind,
+	}
+	if err := promptForAcquisition(scanner, lot); err != nil {
+		return nil, err
+	}
+	return lot, nil
 }
 
 func promptForLot(scanner *bufio.Scanner) (*lotInput, error) {
@@ -452,7 +729,13 @@ func promptForLot(scanner *bufio.Scanner) (*lotInput, error) {
 	}
 	quantityMicros := qtyDec.Mul(decimal.NewFromInt(1_000_000)).IntPart()
 
-	fmt.Print("Total cost basis ($): ")
+	kind, err := promptForKind(scanner)
+	if err != nil {
+		return nil, err
+	}
+	costPrompt, datePrompt := basisPrompts(kind)
+
+	fmt.Print(costPrompt)
 	if !scanner.Scan() {
 		return nil, scanner.Err()
 	}
@@ -462,7 +745,7 @@ func promptForLot(scanner *bufio.Scanner) (*lotInput, error) {
 	}
 	costBasisMicros := costDec.Mul(decimal.NewFromInt(1_000_000)).IntPart()
 
-	fmt.Print("Acquisition date (YYYY-MM-DD): ")
+	fmt.Print(datePrompt)
 	if !scanner.Scan() {
 		return nil, scanner.Err()
 	}
@@ -472,10 +755,310 @@ func promptForLot(scanner *bufio.Scanner) (*lotInput, error) {
 		return nil, fmt.Errorf("invalid date (use YYYY-MM-DD): %w", err)
 	}
 
-	return &lotInput{
+	lot := &lotInput{
 		symbol:          symbol,
 		quantityMicros:  quantityMicros,
 		costBasisMicros: costBasisMicros,
 		acquiredDate:    acquiredDate,
-	}, nil
+		kind:            kind,
+	}
+	if err := promptForAcquisition(scanner, lot); err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+func promptForKind(scanner *bufio.Scanner) (taxlots.AcquisitionKind, error) {
+	fmt.Print("Acquired by (purchase, gift, inheritance, transfer, compensation) [purchase]: ")
+	if !scanner.Scan() {
+		return "", scanner.Err()
+	}
+	kindStr := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	if kindStr == "" {
+		return taxlots.AcquisitionPurchase, nil
+	}
+	return taxlots.ParseAcquisitionKind(kindStr)
+}
+
+// basisPrompts returns the cost basis and date prompts for how a lot was
+// acquired. Gifts keep the donor's basis; inherited and compensation lots
+// start at market value.
+func basisPrompts(kind taxlots.AcquisitionKind) (string, string) {
+	switch kind {
+	case taxlots.AcquisitionGift:
+		return "Donor's cost basis ($): ", "Date of gift (YYYY-MM-DD): "
+	case taxlots.AcquisitionInheritance:
+		return "Fair market value at date of death ($): ", "Date of death (YYYY-MM-DD): "
+	case taxlots.AcquisitionCompensation:
+		return "Fair market value when vested or exercised ($): ", "Vest or exercise date (YYYY-MM-DD): "
+	default:
+		return "Total cost basis ($): ", "Acquisition date (YYYY-MM-DD): "
+	}
+}
+
+// promptForAcquisition fills in what the basis rules need beyond cost and
+// date. For a gift that's when the donor acquired the shares, which carries
+// over to the holding period, and what they were worth when given, which
+// limits a loss when it is below the donor's basis. Both can be left blank.
+func promptForAcquisition(scanner *bufio.Scanner, lot *lotInput) error {
+	if lot.kind == taxlots.AcquisitionInheritance {
+		lot.fmvMicros = sql.NullInt64{Int64: lot.costBasisMicros, Valid: true}
+		fmt.Println("Inherited lots are long-term regardless of holding period.")
+		return nil
+	}
+	if lot.kind != taxlots.AcquisitionGift {
+		return nil
+	}
+
+	fmt.Print("Donor's acquisition date (YYYY-MM-DD, blank if unknown): ")
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	if dateStr := strings.TrimSpace(scanner.Text()); dateStr != "" {
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			return fmt.Errorf("invalid date (use YYYY-MM-DD): %w", err)
+		}
+		lot.donorAcquiredDate = sql.NullString{String: dateStr, Valid: true}
+	}
+
+	fmt.Print("Fair market value when given ($, blank if above the donor's basis): ")
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	if fmvStr := strings.TrimSpace(scanner.Text()); fmvStr != "" {
+		fmvDec, err := decimal.NewFromString(fmvStr)
+		if err != nil {
+			return fmt.Errorf("invalid fair market value: %w", err)
+		}
+		lot.fmvMicros = sql.NullInt64{Int64: fmvDec.Mul(decimal.NewFromInt(1_000_000)).IntPart(), Valid: true}
+	}
+
+	return nil
+}
+
+func adjustLotCommand() *cobra.Command {
+	var (
+		lotID           string
+		correction      string
+		override        string
+		returnOfCapital string
+		accountName     string
+		symbol          string
+		dateStr         string
+		reason          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "adjust",
+		Short: "Adjust a lot's cost basis",
+		Long: `Record a change to cost basis. Adjustments are kept as an audit trail and
+applied every time lots are processed.
+
+  --lot-id ID --correction AMOUNT       add AMOUNT (may be negative) to the lot's remaining basis
+  --lot-id ID --override AMOUNT         set the lot's remaining basis to AMOUNT
+  --account-name X --symbol S --return-of-capital AMOUNT --date D
+                                        record a return of capital distribution; it reduces
+                                        the basis of every open lot by shares held, and any
+                                        excess over basis is a capital gain
+
+Corrections and overrides take effect on the date the lot was created unless
+--date is given. Find lot IDs with 'lots list --ids'. A reason is required.
+Run 'lots process' afterwards.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			if reason == "" {
+				return fmt.Errorf("--reason is required")
+			}
+
+			if returnOfCapital != "" {
+				return recordReturnOfCapital(cmd, queries, accountName, symbol, dateStr, returnOfCapital, reason)
+			}
+
+			params := taxlots.AdjustLotParams{
+				LotID:         lotID,
+				EffectiveDate: dateStr,
+				Reason:        reason,
+			}
+			switch {
+			case correction != "" && override == "":
+				params.Type = taxlots.AdjustmentBasisCorrection
+				params.AmountMicros, err = parseMicros(correction)
+			case override != "" && correction == "":
+				params.Type = taxlots.AdjustmentBasisOverride
+				params.AmountMicros, err = parseMicros(override)
+			default:
+				return fmt.Errorf("give exactly one of --correction, --override or --return-of-capital")
+			}
+			if err != nil {
+				return fmt.Errorf("invalid amount: %w", err)
+			}
+			if lotID == "" {
+				return fmt.Errorf("--lot-id is required")
+			}
+
+			adj, err := taxlots.NewProcessor(queries).AdjustLot(ctx, params)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Recorded %s of %s on %s effective %s.\n",
+				adj.AdjustmentType,
+				formatMicros(adj.AmountMicros),
+				adj.LotID,
+				adj.EffectiveDate,
+			)
+			fmt.Println("Run 'lots process' to apply it.")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&lotID, "lot-id", "", "Lot to adjust")
+	cmd.Flags().StringVar(&correction, "correction", "", "Amount to add to remaining basis (negative to reduce)")
+	cmd.Flags().StringVar(&override, "override", "", "New remaining basis")
+	cmd.Flags().StringVar(&returnOfCapital, "return-of-capital", "", "Return of capital distribution amount")
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account (return of capital)")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Symbol (return of capital)")
+	cmd.Flags().StringVar(&dateStr, "date", "", "Effective date YYYY-MM-DD")
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the basis changed")
+
+	return cmd
+}
+
+// recordReturnOfCapital enters a return of capital the broker export didn't
+// include as a transaction, so it moves cash as well as basis.
+func recordReturnOfCapital(cmd *cobra.Command, queries *db.Queries, accountName, symbol, dateStr, amountStr, reason string) error {
+	ctx := cmd.Context()
+
+	if accountName == "" || symbol == "" || dateStr == "" {
+		return fmt.Errorf("--return-of-capital needs --account-name, --symbol and --date")
+	}
+	if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+		return fmt.Errorf("invalid --date: %w", err)
+	}
+
+	account, err := queries.GetAccountByName(ctx, accountName)
+	if err != nil {
+		return fmt.Errorf("account not found: %s", accountName)
+	}
+
+	sec, err := queries.GetSecurityBySymbol(ctx, strings.ToUpper(symbol))
+	if err != nil {
+		return fmt.Errorf("security not found: %s", symbol)
+	}
+
+	amount, err := parseMicros(amountStr)
+	if err != nil || amount <= 0 {
+		return fmt.Errorf("invalid --return-of-capital amount: %s", amountStr)
+	}
+
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       account.ID,
+		SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		TransactionType: string(importer.TransactionTypeReturnOfCapital),
+		TransactionDate: dateStr,
+		QuantityMicros:  sql.NullInt64{Int64: 0, Valid: true},
+		PriceMicros:     sql.NullInt64{Int64: 0, Valid: true},
+		AmountMicros:    amount,
+		FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
+		FeesInAmount:    true,
+		Description:     sql.NullString{String: reason, Valid: true},
+		DividendType:    sql.NullString{String: string(importer.DividendTypeReturnOfCapital), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	fmt.Printf("Recorded return of capital of %s on %s %s.\n", formatMicros(amount), sec.Symbol, dateStr)
+	fmt.Printf("Run 'lots process --account-name %s' to apply it.\n", account.Name)
+	return nil
+}
+
+func listAdjustmentsCommand() *cobra.Command {
+	var accountName string
+	var symbol string
+
+	cmd := &cobra.Command{
+		Use:   "adjustments",
+		Short: "List cost basis adjustments for an account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			adjustments, err := queries.ListLotAdjustmentsByAccount(ctx, account.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list lot adjustments: %w", err)
+			}
+
+			fmt.Printf("\n=== %s: Cost Basis Adjustments ===\n\n", account.Name)
+
+			tbl := table.New("Date", "Symbol", "Type", "Lot ID", "Amount", "Basis Before", "Basis After", "Gain", "Reason", "Entered")
+			tbl.WithWriter(os.Stdout)
+
+			for _, a := range adjustments {
+				if symbol != "" && !strings.EqualFold(a.Symbol, symbol) {
+					continue
+				}
+
+				before, after := "not applied", ""
+				if a.BasisBeforeMicros != 0 || a.BasisAfterMicros != 0 {
+					before, after = formatMicros(a.BasisBeforeMicros), formatMicros(a.BasisAfterMicros)
+				}
+				tbl.AddRow(
+					a.EffectiveDate,
+					a.Symbol,
+					a.AdjustmentType,
+					a.LotID,
+					formatMicros(a.AmountMicros),
+					before,
+					after,
+					formatMicros(a.GainMicros),
+					a.Reason,
+					a.CreatedAt,
+				)
+			}
+
+			tbl.Print()
+			fmt.Println("\nBasis before and after are for the whole lot, as acquired.")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Filter by symbol (optional)")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
 }
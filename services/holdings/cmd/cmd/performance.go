@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/performance"
+	"github.com/levisegal/monay/services/holdings/portfolio"
+)
+
+func performanceCommand() *cobra.Command {
+	var (
+		accountName string
+		asOf        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "performance",
+		Short: "Show time-weighted and money-weighted returns",
+		Long: `Show MTD, QTD, YTD, 1Y, 3Y and since-inception returns from the stored daily
+valuations ("portfolio rebuild"), for one account or for each account and the
+household.
+
+TWR is the time-weighted return with deposits, withdrawals and shares moved
+in or out taken out, compounded daily; it is annualized for periods longer
+than a year. XIRR is the annual money-weighted return of the starting value,
+the flows and the ending value. Periods reaching back before an account's
+first valuation are left out.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			end := time.Now()
+			if asOf != "" {
+				end, err = time.Parse("2006-01-02", asOf)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of date: %w", err)
+				}
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+			calc := performance.NewCalculator(queries)
+
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				returns, err := calc.Returns(ctx, account.ID, end)
+				if err != nil {
+					return err
+				}
+				printReturns(accountName, returns)
+				return nil
+			}
+
+			accounts, err := queries.ListAccounts(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list accounts: %w", err)
+			}
+			for _, account := range accounts {
+				returns, err := calc.Returns(ctx, account.ID, end)
+				if err != nil {
+					return err
+				}
+				if len(returns) > 0 {
+					printReturns(account.Name, returns)
+				}
+			}
+			returns, err := calc.Returns(ctx, "", end)
+			if err != nil {
+				return err
+			}
+			printReturns("Household", returns)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: each account and the household)")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Last valuation date to measure to (YYYY-MM-DD, default latest)")
+
+	cmd.AddCommand(comparePerformanceCommand())
+	cmd.AddCommand(attributionCommand())
+
+	return cmd
+}
+
+func comparePerformanceCommand() *cobra.Command {
+	var (
+		accountName   string
+		benchmarkName string
+		timeRange     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare an account's returns with its benchmark",
+		Long: `Show an account's time-weighted returns beside its benchmark's (see
+"accounts benchmark") over each period, and with --range the cumulative
+returns of both on each day. Benchmarks are valued from stored closes with
+dividends reinvested; "prices sync" fetches them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			end := time.Now()
+			var start time.Time
+			if timeRange != "" {
+				start, err = portfolio.RangeStart(timeRange, end)
+				if err != nil {
+					return err
+				}
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+			calc := performance.NewCalculator(queries)
+
+			account, benchmark, err := accountBenchmark(ctx, queries, calc, accountName, benchmarkName, end)
+			if err != nil {
+				return err
+			}
+
+			comparisons, err := calc.Compare(ctx, account.ID, benchmark, end)
+			if err != nil {
+				return err
+			}
+			if len(comparisons) == 0 {
+				fmt.Println("No valuations; run 'portfolio rebuild'")
+				return nil
+			}
+
+			fmt.Printf("\n=== %s vs %s: Returns through %s ===\n\n", accountName, benchmark.Name, comparisons[0].End.Format("2006-01-02"))
+
+			tbl := table.New("Period", "Start", "TWR", benchmark.Name, "Excess")
+			tbl.WithWriter(os.Stdout)
+			for _, c := range comparisons {
+				benchmarkReturn, excess := "-", "-"
+				if c.BenchmarkValid {
+					benchmarkReturn = fmt.Sprintf("%.2f%%", c.BenchmarkReturn*100)
+					excess = fmt.Sprintf("%+.2f%%", c.Excess()*100)
+				}
+				tbl.AddRow(string(c.Period), c.Start.Format("2006-01-02"), fmt.Sprintf("%.2f%%", c.TWR*100), benchmarkReturn, excess)
+			}
+			tbl.Print()
+
+			if timeRange == "" {
+				return nil
+			}
+			series, err := calc.Series(ctx, account.ID, benchmark, start, end)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n=== Cumulative Returns (%s) ===\n\n", timeRange)
+
+			tbl = table.New("Date", "Account", benchmark.Name)
+			tbl.WithWriter(os.Stdout)
+			for _, p := range series {
+				benchmarkReturn := "-"
+				if p.BenchmarkValid {
+					benchmarkReturn = fmt.Sprintf("%.2f%%", p.BenchmarkReturn*100)
+				}
+				tbl.AddRow(p.Date.Format("2006-01-02"), fmt.Sprintf("%.2f%%", p.Return*100), benchmarkReturn)
+			}
+			tbl.Print()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&benchmarkName, "benchmark", "", "Benchmark name (default: the account's)")
+	cmd.Flags().StringVar(&timeRange, "range", "", "Also show daily cumulative returns over 1d, 1w, 1m, 3m, 6m, ytd, 1y, 3y, 5y or all")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
+func attributionCommand() *cobra.Command {
+	var (
+		accountName   string
+		benchmarkName string
+		period        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "attribution",
+		Short: "Attribute an account's return against its benchmark by security type",
+		Long: `Split the difference between an account's return and its benchmark's over a
+period into allocation (holding more or less of a security type than the
+benchmark) and selection (doing better or worse within a type), by security
+type. Segment returns are modified Dietz over the securities, with income
+paid on them included; cash is left out.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			p := performance.Period(strings.ToLower(period))
+			if !slices.Contains(performance.Periods, p) {
+				return fmt.Errorf("invalid --period %q: want mtd, qtd, ytd, 1y, 3y or inception", period)
+			}
+			end := time.Now()
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+			calc := performance.NewCalculator(queries)
+
+			account, benchmark, err := accountBenchmark(ctx, queries, calc, accountName, benchmarkName, end)
+			if err != nil {
+				return err
+			}
+
+			a, err := calc.Attribution(ctx, account.ID, benchmark, p, end)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n=== %s vs %s: Attribution %s to %s ===\n\n", accountName, benchmark.Name,
+				a.Start.Format("2006-01-02"), a.End.Format("2006-01-02"))
+
+			percent := func(f float64) string { return fmt.Sprintf("%.2f%%", f*100) }
+			tbl := table.New("Type", "Start Value", "Net Flows", "End Value", "Weight", "Return", "Bench Weight", "Bench Return", "Allocation", "Selection")
+			tbl.WithWriter(os.Stdout)
+			for _, s := range a.Segments {
+				tbl.AddRow(
+					s.SecurityType,
+					formatMicros(s.StartValueMicros),
+					formatMicros(s.NetFlowMicros),
+					formatMicros(s.EndValueMicros),
+					percent(s.Weight),
+					percent(s.Return),
+					percent(s.BenchmarkWeight),
+					percent(s.BenchmarkReturn),
+					percent(s.Allocation),
+					percent(s.Selection),
+				)
+			}
+			tbl.Print()
+
+			fmt.Printf("\nReturn:                 %s\n", percent(a.Return))
+			fmt.Printf("Benchmark return:       %s\n", percent(a.BenchmarkReturn))
+			fmt.Printf("Allocation effect:      %s\n", percent(a.Allocation))
+			fmt.Printf("Selection effect:       %s\n", percent(a.Selection))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&benchmarkName, "benchmark", "", "Benchmark name (default: the account's)")
+	cmd.Flags().StringVar(&period, "period", "ytd", "mtd, qtd, ytd, 1y, 3y or inception")
+	cmd.MarkFlagRequired("account-name")
+
+	return cmd
+}
+
+// accountBenchmark looks up an account by name and the benchmark to compare
+// it with: benchmarkName if given, else the account's.
+func accountBenchmark(ctx context.Context, queries *db.Queries, calc *performance.Calculator, accountName, benchmarkName string, end time.Time) (db.Account, performance.Benchmark, error) {
+	account, err := queries.GetAccountByName(ctx, accountName)
+	if err != nil {
+		return db.Account{}, performance.Benchmark{}, fmt.Errorf("account not found: %s", accountName)
+	}
+	var benchmark performance.Benchmark
+	if benchmarkName != "" {
+		benchmark, err = calc.Benchmark(ctx, benchmarkName)
+	} else {
+		benchmark, err = calc.AccountBenchmark(ctx, account, end)
+	}
+	if err != nil {
+		return db.Account{}, performance.Benchmark{}, err
+	}
+	return account, benchmark, nil
+}
+
+func printReturns(title string, returns []performance.Return) {
+	if len(returns) == 0 {
+		fmt.Printf("\n%s: no valuations; run 'portfolio rebuild'\n", title)
+		return
+	}
+
+	fmt.Printf("\n=== %s: Returns through %s ===\n\n", title, returns[0].End.Format("2006-01-02"))
+
+	tbl := table.New("Period", "Start", "Start Value", "Net Flows", "End Value", "Gain", "TWR", "Annualized", "XIRR")
+	tbl.WithWriter(os.Stdout)
+	for _, r := range returns {
+		xirr := "-"
+		if r.XIRRValid {
+			xirr = fmt.Sprintf("%.2f%%", r.XIRR*100)
+		}
+		tbl.AddRow(
+			string(r.Period),
+			r.Start.Format("2006-01-02"),
+			formatMicros(r.StartValueMicros),
+			formatMicros(r.NetFlowMicros),
+			formatMicros(r.EndValueMicros),
+			formatMicros(r.GainMicros),
+			fmt.Sprintf("%.2f%%", r.TWR*100),
+			fmt.Sprintf("%.2f%%", r.AnnualizedTWR*100),
+			xirr,
+		)
+	}
+	tbl.Print()
+}
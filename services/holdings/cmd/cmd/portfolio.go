@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/portfolio"
+)
+
+func portfolioCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "portfolio",
+		Short: "Portfolio value over time",
+	}
+
+	cmd.AddCommand(rebuildValuationsCommand())
+	cmd.AddCommand(portfolioHistoryCommand())
+
+	return cmd
+}
+
+func rebuildValuationsCommand() *cobra.Command {
+	var toDate string
+
+	cmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Recompute daily account valuations",
+		Long: `Replay every account's lots and cash into daily holdings and value them at
+each weekday's close from stored prices, replacing the stored valuations.
+"prices sync" rebuilds them too; run this after importing transactions or
+prices by hand.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			to := time.Now()
+			if toDate != "" {
+				to, err = time.Parse("2006-01-02", toDate)
+				if err != nil {
+					return fmt.Errorf("invalid --to date: %w", err)
+				}
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			count, err := portfolio.NewValuer(db.New(conn)).Rebuild(ctx, to)
+			if err != nil {
+				return err
+			}
+			slog.Info("rebuilt valuations", "valuations", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&toDate, "to", "", "Last date to value (YYYY-MM-DD, default today)")
+
+	return cmd
+}
+
+func portfolioHistoryCommand() *cobra.Command {
+	var (
+		accountName string
+		timeRange   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show daily market value",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			end := time.Now()
+			start, err := portfolio.RangeStart(timeRange, end)
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			title := "Household"
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+				title = accountName
+			}
+
+			points, err := portfolio.NewValuer(queries).History(ctx, accountID, start, end)
+			if err != nil {
+				return err
+			}
+			if len(points) == 0 {
+				fmt.Println("No valuations; run 'portfolio rebuild'")
+				return nil
+			}
+
+			fmt.Printf("\n=== %s: Market Value (%s) ===\n\n", title, timeRange)
+
+			tbl := table.New("Date", "Market Value", "Securities", "Cash", "Net Flow", "Day Change", "Day %", "Unpriced")
+			tbl.WithWriter(os.Stdout)
+			for _, p := range points {
+				unpriced := ""
+				if p.UnpricedCount > 0 {
+					unpriced = fmt.Sprintf("%d", p.UnpricedCount)
+				}
+				tbl.AddRow(
+					p.Date.Format("2006-01-02"),
+					formatMicros(p.MarketValueMicros),
+					formatMicros(p.SecuritiesValueMicros),
+					formatMicros(p.CashMicros),
+					formatMicros(p.NetFlowMicros),
+					formatMicros(p.DayChangeMicros),
+					fmt.Sprintf("%.2f%%", p.DayChangePercent),
+					unpriced,
+				)
+			}
+			tbl.Print()
+
+			first, last := points[0], points[len(points)-1]
+			fmt.Printf("\nChange:                 %s\n", formatMicros(last.MarketValueMicros-first.MarketValueMicros))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name (default: all accounts)")
+	cmd.Flags().StringVar(&timeRange, "range", "1y", "1d, 1w, 1m, 3m, 6m, ytd, 1y, 3y, 5y or all")
+
+	return cmd
+}
@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/importer"
+	"github.com/levisegal/monay/services/holdings/marketdata"
+	"github.com/levisegal/monay/services/holdings/performance"
+	"github.com/levisegal/monay/services/holdings/portfolio"
+)
+
+func pricesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prices",
+		Short: "Manage security prices",
+	}
+
+	cmd.AddCommand(importPricesCommand())
+	cmd.AddCommand(syncPricesCommand())
+	cmd.AddCommand(stubPricesCommand())
+
+	return cmd
+}
+
+func importPricesCommand() *cobra.Command {
+	var (
+		files  []string
+		symbol string
+		source string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import daily closing prices from CSV files",
+		Long: `Import daily closes from CSV files with a header row naming a date and a
+close (or price) column. Files with a symbol column can hold any number of
+securities; one-security downloads without one need --symbol. Bond prices are
+per 100 of face value. Re-importing a day replaces its close.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			for _, file := range files {
+				if err := runImportPrices(ctx, queries, file, symbol, source); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&files, "file", nil, "Path to CSV file(s) - can be repeated")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Symbol for files without a symbol column")
+	cmd.Flags().StringVar(&source, "source", "csv", "Source recorded with each price")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runImportPrices(ctx context.Context, queries *db.Queries, filePath, symbol, source string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	prices, err := importer.ParsePrices(f, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	securityIDs := make(map[string]string)
+	for _, p := range prices {
+		securityID, ok := securityIDs[p.Symbol]
+		if !ok {
+			sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+				ID:     database.NewID(database.PrefixSecurity),
+				Symbol: p.Symbol,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to upsert security %s: %w", p.Symbol, err)
+			}
+			securityID = sec.ID
+			securityIDs[p.Symbol] = securityID
+		}
+
+		err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+			SecurityID:  securityID,
+			PriceDate:   p.Date.Format("2006-01-02"),
+			CloseMicros: p.CloseMicros,
+			Source:      source,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save price for %s: %w", p.Symbol, err)
+		}
+	}
+
+	slog.Info("imported prices",
+		"file", filePath,
+		"prices", len(prices),
+		"securities", len(securityIDs),
+	)
+
+	return nil
+}
+
+func syncPricesCommand() *cobra.Command {
+	var (
+		dir     string
+		baseURL string
+		symbols []string
+		toDate  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Backfill prices, splits and dividends from a market data provider",
+		Long: `Fetch daily closes, splits and dividends for every security ever held, from
+its first transaction date, and for benchmark components from the first
+transaction date of all, or for --symbol only. Later syncs pick up from the
+last stored close. Weekdays without a close (market holidays) are filled with
+the previous close. Daily valuations are rebuilt afterwards.
+
+The provider is a directory of CSVs (--dir, or MONAY_HOLDINGS_MARKET_DATA_PATH)
+with SYMBOL.csv closes and optional SYMBOL.splits.csv and SYMBOL.dividends.csv,
+a single CSV of closes with a symbol column, or a market data server (--url, or
+MONAY_HOLDINGS_MARKET_DATA_URL) such as "prices stub".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			to := time.Now()
+			if toDate != "" {
+				to, err = time.Parse("2006-01-02", toDate)
+				if err != nil {
+					return fmt.Errorf("invalid --to date: %w", err)
+				}
+			}
+
+			if dir == "" && baseURL == "" {
+				dir, baseURL = cfg.MarketDataPath, cfg.MarketDataURL
+			}
+			provider, err := newMarketDataProvider(dir, baseURL)
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			syncer := marketdata.NewSyncer(queries, provider)
+			results, err := syncer.Sync(ctx, symbols, to)
+			if err != nil {
+				return err
+			}
+
+			extra, from, err := benchmarkSymbols(ctx, queries, symbols)
+			if err != nil {
+				return err
+			}
+			if len(extra) > 0 {
+				benchmarkResults, err := syncer.SyncSymbols(ctx, extra, from, to)
+				if err != nil {
+					return err
+				}
+				results = append(results, benchmarkResults...)
+			}
+
+			var missing []string
+			for _, r := range results {
+				if r.NotFound {
+					missing = append(missing, r.Symbol)
+					continue
+				}
+				slog.Info("synced prices",
+					"symbol", r.Symbol,
+					"from", r.From.Format("2006-01-02"),
+					"bars", r.Bars,
+					"filled", r.Filled,
+					"splits", r.Splits,
+					"dividends", r.Dividends,
+				)
+			}
+			if len(missing) > 0 {
+				slog.Warn("no market data", "provider", provider.Name(), "symbols", missing)
+			}
+
+			count, err := portfolio.NewValuer(queries).Rebuild(ctx, to)
+			if err != nil {
+				return err
+			}
+			slog.Info("rebuilt valuations", "valuations", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory (or CSV file) of daily closes")
+	cmd.Flags().StringVar(&baseURL, "url", "", "Market data server URL")
+	cmd.Flags().StringArrayVar(&symbols, "symbol", nil, "Only sync this symbol - can be repeated")
+	cmd.Flags().StringVar(&toDate, "to", "", "Last date to sync (YYYY-MM-DD, default today)")
+
+	return cmd
+}
+
+// benchmarkSymbols returns the benchmark components that aren't held, only
+// those in symbols if any are given, and the first transaction date of all
+// holdings to sync them from.
+func benchmarkSymbols(ctx context.Context, queries *db.Queries, symbols []string) ([]string, time.Time, error) {
+	held, err := queries.ListHeldSecurities(ctx)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to list held securities: %w", err)
+	}
+	if len(held) == 0 {
+		return nil, time.Time{}, nil
+	}
+
+	first := held[0].FirstTransactionDate
+	heldSymbols := make(map[string]bool)
+	for _, sec := range held {
+		heldSymbols[sec.Symbol] = true
+		if sec.FirstTransactionDate < first {
+			first = sec.FirstTransactionDate
+		}
+	}
+	from, err := time.Parse("2006-01-02", first)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid first transaction date: %w", err)
+	}
+
+	benchmarks, err := performance.NewCalculator(queries).Benchmarks(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	only := make(map[string]bool)
+	for _, symbol := range symbols {
+		only[symbol] = true
+	}
+	var extra []string
+	for _, symbol := range performance.Symbols(benchmarks) {
+		if heldSymbols[symbol] || (len(only) > 0 && !only[symbol]) {
+			continue
+		}
+		extra = append(extra, symbol)
+	}
+	return extra, from, nil
+}
+
+func stubPricesCommand() *cobra.Command {
+	var (
+		dir    string
+		listen string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stub",
+		Short: "Serve a directory of price CSVs as a market data server",
+		Long: `Serve --dir over the HTTP API "prices sync --url" reads, for testing the HTTP
+provider locally without a market data subscription.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			provider, err := marketdata.NewCSVProvider(dir)
+			if err != nil {
+				return err
+			}
+
+			server := &http.Server{Addr: listen, Handler: marketdata.NewStubHandler(provider)}
+			go func() {
+				<-ctx.Done()
+				server.Shutdown(context.Background())
+			}()
+
+			slog.Info("market data stub is running", "listen_addr", listen, "dir", dir)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory (or CSV file) of daily closes")
+	cmd.Flags().StringVar(&listen, "listen", ":8899", "Address to listen on")
+	cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
+
+func newMarketDataProvider(dir, baseURL string) (marketdata.Provider, error) {
+	switch {
+	case baseURL != "":
+		return marketdata.NewHTTPProvider(baseURL), nil
+	case dir != "":
+		return marketdata.NewCSVProvider(dir)
+	default:
+		return nil, fmt.Errorf("no market data provider: pass --dir or --url")
+	}
+}
@@ -24,6 +24,13 @@ func Command() *cobra.Command {
 	command.AddCommand(holdingsCommand())
 	command.AddCommand(accountsCommand())
 	command.AddCommand(cashCommand())
+	command.AddCommand(incomeCommand())
+	command.AddCommand(taxCommand())
+	command.AddCommand(simulateCommand())
+	command.AddCommand(pricesCommand())
+	command.AddCommand(portfolioCommand())
+	command.AddCommand(performanceCommand())
+	command.AddCommand(benchmarksCommand())
 
 	return command
 }
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func simulateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "What-if simulations against current lots",
+	}
+
+	cmd.AddCommand(simulateSellCommand())
+
+	return cmd
+}
+
+func simulateSellCommand() *cobra.Command {
+	var (
+		accountName string
+		symbol      string
+		qty         string
+		price       string
+		fees        string
+		method      string
+		dateStr     string
+		rates       taxlots.TaxRates
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sell",
+		Short: "Simulate a sale and its tax impact",
+		Long: `Match a hypothetical sale against open lots without recording anything, and
+show which lots it relieves, the realized short and long-term gain, the
+estimated federal and state tax, and how this year's realized totals change.
+
+Methods:
+  fifo  oldest lots first (default, matches 'lots process')
+  lifo  newest lots first
+  hifo  highest cost per share first
+
+Without --account-name, open lots from every account holding the symbol are pooled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			lotMethod, err := taxlots.ParseMethod(method)
+			if err != nil {
+				return err
+			}
+
+			date := time.Now()
+			if dateStr != "" {
+				date, err = time.Parse("2006-01-02", dateStr)
+				if err != nil {
+					return fmt.Errorf("invalid --date: %w", err)
+				}
+			}
+
+			qtyMicros, err := parseMicros(qty)
+			if err != nil {
+				return fmt.Errorf("invalid --qty: %w", err)
+			}
+			priceMicros, err := parseMicros(price)
+			if err != nil {
+				return fmt.Errorf("invalid --price: %w", err)
+			}
+			feesMicros, err := parseMicros(fees)
+			if err != nil {
+				return fmt.Errorf("invalid --fees: %w", err)
+			}
+
+			resolveTaxRates(cmd, cfg, &rates)
+
+			result, err := taxlots.NewSimulator(queries).SimulateSell(ctx, taxlots.SimulateSellParams{
+				AccountID:      accountID,
+				Symbol:         symbol,
+				QuantityMicros: qtyMicros,
+				PriceMicros:    priceMicros,
+				FeesMicros:     feesMicros,
+				Method:         lotMethod,
+				Date:           date,
+				Rates:          rates,
+			})
+			if err != nil {
+				return err
+			}
+
+			printSimulatedSell(result, rates)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account to sell from (default: all accounts)")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Symbol to sell")
+	cmd.Flags().StringVar(&qty, "qty", "", "Quantity to sell")
+	cmd.Flags().StringVar(&price, "price", "", "Sale price per share (per 100 of face value for bonds; options are multiplied by the contract size)")
+	cmd.Flags().StringVar(&fees, "fees", "0", "Commissions and fees")
+	cmd.Flags().StringVar(&method, "method", "fifo", "Lot selection: fifo, lifo, hifo")
+	cmd.Flags().StringVar(&dateStr, "date", "", "Sale date YYYY-MM-DD (default today)")
+	addTaxRateFlags(cmd, &rates)
+	cmd.MarkFlagRequired("symbol")
+	cmd.MarkFlagRequired("qty")
+	cmd.MarkFlagRequired("price")
+
+	return cmd
+}
+
+func printSimulatedSell(result *taxlots.SimulateSellResult, rates taxlots.TaxRates) {
+	fmt.Printf("\n=== Simulated Sale: %s %s @ %s (%s, %s) ===\n\n",
+		formatQty(float64(result.QuantityMicros)/1_000_000),
+		result.Symbol,
+		formatMicros(result.PriceMicros),
+		result.Method,
+		result.Date.Format("2006-01-02"),
+	)
+
+	tbl := table.New("Account", "Acquired", "Quantity", "Basis", "Proceeds", "Gain", "Term", "Left in Lot")
+	for _, r := range result.Reliefs {
+		tbl.AddRow(
+			r.AccountName,
+			r.Lot.AcquiredDate,
+			formatQty(float64(r.QuantityMicros)/1_000_000),
+			formatMicros(r.CostBasisMicros),
+			formatMicros(r.ProceedsMicros),
+			formatMicros(r.GainMicros),
+			r.HoldingPeriod,
+			formatQty(float64(r.RemainingMicros)/1_000_000),
+		)
+	}
+	tbl.Print()
+
+	if result.UnmatchedMicros > 0 {
+		fmt.Printf("\nWarning: only %s shares available; %s not covered by open lots and left out of proceeds\n",
+			formatQty(float64(result.QuantityMicros-result.UnmatchedMicros)/1_000_000),
+			formatQty(float64(result.UnmatchedMicros)/1_000_000),
+		)
+	}
+
+	fmt.Printf("\nProceeds (net of %s fees): %s\n", formatMicros(result.FeesMicros), formatMicros(result.ProceedsMicros))
+	fmt.Printf("Short-term gain: %s\n", formatMicros(result.Gain.ShortTermMicros))
+	fmt.Printf("Long-term gain:  %s\n", formatMicros(result.Gain.LongTermMicros))
+	fmt.Printf("Total gain:      %s\n", formatMicros(result.Gain.TotalMicros))
+
+	fmt.Printf("\nEst. federal tax (%.0f%% short / %.0f%% long): %s\n", rates.ShortTerm*100, rates.LongTerm*100, formatMicros(result.FederalTaxMicros))
+	fmt.Printf("Est. state tax (%.0f%%): %s\n", rates.State*100, formatMicros(result.StateTaxMicros))
+	fmt.Printf("Est. total tax: %s\n", formatMicros(result.TotalTaxMicros))
+
+	fmt.Printf("\n=== %d Realized Gains (all accounts) ===\n\n", result.Date.Year())
+	ytd := table.New("", "Before", "After", "Change")
+	ytd.AddRow("Short-term", formatMicros(result.YTDBefore.ShortTermMicros), formatMicros(result.YTDAfter.ShortTermMicros), formatMicros(result.Gain.ShortTermMicros))
+	ytd.AddRow("Long-term", formatMicros(result.YTDBefore.LongTermMicros), formatMicros(result.YTDAfter.LongTermMicros), formatMicros(result.Gain.LongTermMicros))
+	ytd.AddRow("Total", formatMicros(result.YTDBefore.TotalMicros), formatMicros(result.YTDAfter.TotalMicros), formatMicros(result.Gain.TotalMicros))
+	ytd.Print()
+}
+
+func parseMicros(s string) (int64, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	return d.Mul(decimal.NewFromInt(1_000_000)).IntPart(), nil
+}
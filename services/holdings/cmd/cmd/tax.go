@@ -0,0 +1,821 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/importer"
+	"github.com/levisegal/monay/services/holdings/tax"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func taxCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tax",
+		Short: "Tax reporting commands",
+	}
+
+	cmd.AddCommand(form8949Command())
+	cmd.AddCommand(reconcileTaxCommand())
+	cmd.AddCommand(harvestCommand())
+	cmd.AddCommand(compensationCommand())
+	cmd.AddCommand(bondPremiumCommand())
+
+	return cmd
+}
+
+func form8949Command() *cobra.Command {
+	var (
+		year        int
+		accountName string
+		format      string
+		output      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "8949",
+		Short: "Build Form 8949 and Schedule D totals from lot dispositions",
+		Long: `Group the year's lot dispositions into Form 8949 boxes and total them for Schedule D.
+
+Boxes:
+  A / D  short / long-term, basis reported to the IRS (covered lots)
+  B / E  short / long-term, basis not reported (lots acquired before the
+         covered-security dates: 2011 stock and ETFs, 2012 mutual funds,
+         2014 bonds)
+  C / F  short / long-term, not on a 1099-B (return of capital beyond basis)
+
+Adjustments, columns (f) and (g):
+  B      RSU and ESPP sales in boxes A and D: column (e) is the 1099-B basis,
+         without the compensation income, and (g) subtracts that income
+  W      loss disallowed by a purchase of the same security, in any account,
+         within 30 days of the sale
+
+Formats:
+  table  8949 lines by box and the Schedule D summary (default)
+  csv    one row per 8949 line
+  txf    Tax Exchange Format v042 for tax software import`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			form, err := tax.NewReporter(queries).Form8949(ctx, year, accountID)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			switch format {
+			case "csv":
+				return form.WriteCSV(w)
+			case "txf":
+				return form.WriteTXF(w, time.Now())
+			case "table":
+				printForm8949(w, form)
+				return nil
+			default:
+				return fmt.Errorf("unsupported format: %s (use table, csv or txf)", format)
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&year, "year", 0, "Tax year")
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Limit to one account (optional)")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, csv, txf")
+	cmd.Flags().StringVar(&output, "output", "", "Write to file instead of stdout")
+	cmd.MarkFlagRequired("year")
+
+	return cmd
+}
+
+func printForm8949(w io.Writer, form *tax.Form8949) {
+	for _, box := range tax.Boxes {
+		var lines []tax.Form8949Line
+		for _, line := range form.Lines {
+			if line.Box == box {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n=== Form 8949 (%d) Box %s ===\n\n", form.Year, box)
+
+		tbl := table.New("Description", "Acquired", "Sold", "Proceeds", "Basis", "Code", "Adjustment", "Gain")
+		tbl.WithWriter(w)
+		for _, line := range lines {
+			adjustment := ""
+			if line.AdjustmentMicros != 0 {
+				adjustment = formatMicros(line.AdjustmentMicros)
+			}
+			tbl.AddRow(
+				line.Description,
+				line.DateAcquired,
+				line.DateSold,
+				formatMicros(line.ProceedsMicros),
+				formatMicros(line.CostBasisMicros),
+				line.AdjustmentCode,
+				adjustment,
+				formatMicros(line.GainMicros),
+			)
+		}
+		tbl.Print()
+	}
+
+	fmt.Fprintf(w, "\n=== Schedule D (%d) ===\n\n", form.Year)
+
+	tbl := table.New("Line", "Box", "Lines", "Proceeds", "Basis", "Adjustments", "Gain")
+	tbl.WithWriter(w)
+	for _, t := range form.ScheduleD.Boxes {
+		if t.Lines == 0 {
+			continue
+		}
+		tbl.AddRow(
+			t.Box.ScheduleDLine(),
+			string(t.Box),
+			t.Lines,
+			formatMicros(t.ProceedsMicros),
+			formatMicros(t.CostBasisMicros),
+			formatMicros(t.AdjustmentMicros),
+			formatMicros(t.GainMicros),
+		)
+	}
+	tbl.Print()
+
+	fmt.Fprintf(w, "\nLine 7  Net short-term: %s\n", formatMicros(form.ScheduleD.ShortTermGainMicros))
+	fmt.Fprintf(w, "Line 15 Net long-term:  %s\n", formatMicros(form.ScheduleD.LongTermGainMicros))
+	fmt.Fprintf(w, "Line 16 Total:          %s\n", formatMicros(form.ScheduleD.TotalGainMicros))
+}
+
+func compensationCommand() *cobra.Command {
+	var (
+		year        int
+		accountName string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compensation",
+		Short: "Show the 1099-B basis adjustments for RSU and ESPP sales",
+		Long: `List the year's sales of RSU and ESPP shares with the ordinary income their
+basis includes. Brokers usually leave that income out of the 1099-B basis
+(zero for RSUs, the purchase price for ESPP) because it was already taxed on
+the W-2. The 8949 reports covered sales with code B and the adjustment in
+column (g).
+
+ESPP sales are qualifying when sold more than two years after the offering
+date and more than one year after the purchase; the income is then the lesser
+of the gain and the discount on the offering date price. Otherwise the income
+is the discount from the purchase date price.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			report, err := tax.NewReporter(queries).Compensation(ctx, year, accountID)
+			if err != nil {
+				return err
+			}
+
+			printCompensation(report)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&year, "year", 0, "Tax year")
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Limit to one account (optional)")
+	cmd.MarkFlagRequired("year")
+
+	return cmd
+}
+
+func printCompensation(report *tax.CompensationReport) {
+	if len(report.Lines) == 0 {
+		fmt.Printf("No RSU or ESPP sales in %d\n", report.Year)
+		return
+	}
+
+	fmt.Printf("\n=== Compensation Income Adjustments (%d) ===\n\n", report.Year)
+
+	tbl := table.New("Symbol", "Plan", "Acquired", "Sold", "Quantity", "Proceeds", "1099-B Basis", "Adjustment", "Basis", "Gain", "ESPP")
+	for _, line := range report.Lines {
+		tbl.AddRow(
+			line.Symbol,
+			strings.ToUpper(line.PlanType),
+			line.DateAcquired,
+			line.DateSold,
+			formatQty(float64(line.QuantityMicros)/1_000_000),
+			formatMicros(line.ProceedsMicros),
+			formatMicros(line.BrokerBasisMicros),
+			formatMicros(line.AdjustmentMicros),
+			formatMicros(line.CostBasisMicros),
+			formatMicros(line.GainMicros),
+			line.EsppDisposition,
+		)
+	}
+	tbl.Print()
+
+	fmt.Printf("\nTotal adjustment (code B, column g): %s\n", formatMicros(report.TotalAdjustmentMicros))
+}
+
+func bondPremiumCommand() *cobra.Command {
+	var (
+		year        int
+		accountName string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bond-premium",
+		Short: "Show bond premium amortized during a year",
+		Long: `List the premium amortized on each bond lot during the year, by constant
+yield to maturity or to the call date when that yields less. Amortization
+comes off the lot's basis, so a bond held to redemption has no gain or loss.
+
+For tax-exempt bonds the amortization reduces the tax-exempt interest reported
+(1099-INT box 13); for taxable bonds it offsets interest income (box 11).
+Compare with the broker's figures.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			report, err := tax.NewReporter(queries).BondPremium(ctx, year, accountID)
+			if err != nil {
+				return err
+			}
+
+			printBondPremium(report)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&year, "year", 0, "Tax year")
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Limit to one account (optional)")
+	cmd.MarkFlagRequired("year")
+
+	return cmd
+}
+
+func printBondPremium(report *tax.BondPremiumReport) {
+	if len(report.Lines) == 0 {
+		fmt.Printf("No bond premium amortized in %d\n", report.Year)
+		return
+	}
+
+	fmt.Printf("\n=== Bond Premium Amortization (%d) ===\n\n", report.Year)
+
+	tbl := table.New("Symbol", "Acquired", "Quantity", "Basis", "Amortized")
+	for _, line := range report.Lines {
+		tbl.AddRow(
+			line.Symbol,
+			line.DateAcquired,
+			formatQty(float64(line.QuantityMicros)/1_000_000),
+			formatMicros(line.CostBasisMicros),
+			formatMicros(line.AmortizationMicros),
+		)
+	}
+	tbl.Print()
+
+	fmt.Printf("\nTotal amortized: %s\n", formatMicros(report.TotalAmortizationMicros))
+}
+
+func reconcileTaxCommand() *cobra.Command {
+	var (
+		year        int
+		accountName string
+		broker      string
+		file        string
+		showAll     bool
+		adoptBasis  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare computed dispositions with a broker's 1099-B",
+		Long: `Match each sale on a broker's 1099-B (CSV or TXF download) to the account's
+lot dispositions by security, sale date and quantity, and list differences in
+proceeds, basis, wash-sale adjustments and term.
+
+Statuses:
+  matched      same quantity, amounts within $1 and same term
+  mismatch     matched, but proceeds, basis, term differ or a wash sale was reported
+  missing      broker reported shares we have no lots for
+  not_on_1099  we have a disposition the broker didn't report
+
+With --adopt-basis, each missing sale that has an acquired date is recorded as
+an opening balance using the broker's basis. Run 'lots process' afterwards.
+
+Supported brokers: etrade, merrill, lpl`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			account, err := queries.GetAccountByName(ctx, accountName)
+			if err != nil {
+				return fmt.Errorf("account not found: %s", accountName)
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("failed to open file: %w", err)
+			}
+			defer f.Close()
+
+			result, err := importer.Parse1099B(ctx, importer.Broker(broker), f)
+			if err != nil {
+				return fmt.Errorf("failed to parse 1099-B: %w", err)
+			}
+
+			rec, err := tax.NewReporter(queries).Reconcile(ctx, year, account.ID, result.Sales)
+			if err != nil {
+				return err
+			}
+
+			lines := rec.Unmatched()
+			if showAll {
+				lines = rec.Lines
+			}
+			printReconciliation(rec, lines)
+
+			if adoptBasis {
+				return adoptBrokerBasis(ctx, queries, account.ID, rec)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&year, "year", 0, "Tax year")
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Account name")
+	cmd.Flags().StringVar(&broker, "broker", "", "Broker that issued the 1099-B (etrade, merrill, lpl)")
+	cmd.Flags().StringVar(&file, "file", "", "Path to the 1099-B CSV or TXF download")
+	cmd.Flags().BoolVar(&showAll, "all", false, "Show matched sales too")
+	cmd.Flags().BoolVar(&adoptBasis, "adopt-basis", false, "Create opening balances from the broker's basis for missing lots")
+	cmd.MarkFlagRequired("year")
+	cmd.MarkFlagRequired("account-name")
+	cmd.MarkFlagRequired("broker")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func printReconciliation(rec *tax.Reconciliation, lines []tax.ReconcileLine) {
+	counts := make(map[tax.ReconcileStatus]int)
+	for _, line := range rec.Lines {
+		counts[line.Status]++
+	}
+
+	fmt.Printf("\n=== 1099-B Reconciliation (%d) ===\n\n", rec.Year)
+	fmt.Printf("Matched: %d  Mismatch: %d  Missing: %d  Not on 1099-B: %d\n\n",
+		counts[tax.ReconcileMatched],
+		counts[tax.ReconcileMismatch],
+		counts[tax.ReconcileMissing],
+		counts[tax.ReconcileBrokerless],
+	)
+
+	if len(lines) == 0 {
+		fmt.Println("Every 1099-B sale matches a disposition.")
+		return
+	}
+
+	tbl := table.New("Status", "Sold", "Symbol", "Acquired", "Broker Qty", "Our Qty", "Proceeds Diff", "Basis Diff", "Wash Sale", "Term (Broker/Ours)")
+	for _, line := range lines {
+		acquired := line.DateAcquired
+		if acquired == "" && line.Sale != nil {
+			acquired = "VARIOUS"
+		}
+		washSale := ""
+		if line.WashSaleMicros != 0 {
+			washSale = formatMicros(line.WashSaleMicros)
+		}
+		term := fmt.Sprintf("%s/%s", termLabel(line.BrokerTerm), termLabel(line.OurTerm))
+		if line.TermMismatch {
+			term += " *"
+		}
+		tbl.AddRow(
+			line.Status,
+			line.DateSold,
+			line.Symbol,
+			acquired,
+			formatQty(float64(line.BrokerQuantityMicros)/1_000_000),
+			formatQty(float64(line.MatchedQuantityMicros)/1_000_000),
+			formatMicros(line.ProceedsDiffMicros),
+			formatMicros(line.BasisDiffMicros),
+			washSale,
+			term,
+		)
+	}
+	tbl.Print()
+	fmt.Println("\nDiffs are broker minus ours.")
+}
+
+func termLabel(term string) string {
+	switch term {
+	case "short_term":
+		return "short"
+	case "long_term":
+		return "long"
+	case "":
+		return "-"
+	default:
+		return term
+	}
+}
+
+func adoptBrokerBasis(ctx context.Context, queries *db.Queries, accountID string, rec *tax.Reconciliation) error {
+	created := 0
+	for _, line := range rec.Lines {
+		if line.Status != tax.ReconcileMissing {
+			continue
+		}
+		if line.DateAcquired == "" {
+			fmt.Printf("Skipping %s sold %s: broker reported acquired date as VARIOUS\n", line.Symbol, line.DateSold)
+			continue
+		}
+
+		sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+			ID:     database.NewID(database.PrefixSecurity),
+			Symbol: line.Symbol,
+			Name:   sql.NullString{},
+			Cusip:  sql.NullString{String: line.Sale.CUSIP, Valid: line.Sale.CUSIP != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upsert security: %w", err)
+		}
+
+		basis := taxlots.ProRata(line.Sale.CostBasisMicros, line.Sale.QuantityMicros, line.MissingQuantityMicros)
+		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+			ID:              database.NewID(database.PrefixTransaction),
+			AccountID:       accountID,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+			TransactionType: string(importer.TransactionTypeOpeningBalance),
+			TransactionDate: line.DateAcquired,
+			QuantityMicros:  sql.NullInt64{Int64: line.MissingQuantityMicros, Valid: true},
+			PriceMicros:     sql.NullInt64{Int64: taxlots.ProRata(basis, line.MissingQuantityMicros, 1_000_000), Valid: true},
+			AmountMicros:    basis,
+			FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
+			FeesInAmount:    true,
+			Description:     sql.NullString{String: fmt.Sprintf("Opening balance - 1099-B basis (sold %s)", line.DateSold), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		slog.Info("adopted broker basis",
+			"symbol", line.Symbol,
+			"quantity", float64(line.MissingQuantityMicros)/1_000_000,
+			"cost_basis", float64(basis)/1_000_000,
+			"acquired", line.DateAcquired,
+		)
+		created++
+	}
+
+	fmt.Printf("\nCreated %d opening balances from 1099-B basis.", created)
+	if created > 0 {
+		fmt.Print(" Run 'lots process' to rebuild tax lots.")
+	}
+	fmt.Println()
+	return nil
+}
+
+func harvestCommand() *cobra.Command {
+	var (
+		accountName string
+		minLoss     float64
+		pricesFile  string
+		priceFlags  []string
+		asOfStr     string
+		rates       taxlots.TaxRates
+		pairsFile   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "harvest",
+		Short: "Find open lots to sell for tax losses",
+		Long: `List open lots trading below their cost basis, split into short-term and
+long-term, with the tax a sale would save at your marginal rates.
+
+Prices come from --price SYMBOL=PRICE flags, a --prices CSV (symbol,price), the
+latest close from "prices import" and, for anything else, the latest imported
+position snapshot. With --as-of, lots are those open at the close of that date
+and closes and snapshots are the latest on or before it.
+
+A lot is flagged as a wash sale risk when the same security was bought in any
+account in the last 30 days, including dividend reinvestments. DRIP means the
+security reinvested a dividend recently; turn reinvestment off before selling.
+
+Rates default to MONAY_HOLDINGS_TAX_SHORT_TERM_RATE, _LONG_TERM_RATE and
+_STATE_RATE. Replacement suggestions come from MONAY_HOLDINGS_HARVEST_PAIRS_PATH
+(or --pairs), a CSV of "SYMBOL,REPLACEMENT,..." lines, or built-in index fund pairs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			conn, err := database.Open(ctx, cfg.DBPath)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			queries := db.New(conn)
+
+			var accountID string
+			if accountName != "" {
+				account, err := queries.GetAccountByName(ctx, accountName)
+				if err != nil {
+					return fmt.Errorf("account not found: %s", accountName)
+				}
+				accountID = account.ID
+			}
+
+			asOf := time.Now()
+			if asOfStr != "" {
+				asOf, err = time.Parse("2006-01-02", asOfStr)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of date: %w", err)
+				}
+			}
+
+			prices, err := loadHarvestPrices(ctx, queries, asOf, pricesFile, priceFlags)
+			if err != nil {
+				return err
+			}
+
+			if pairsFile == "" {
+				pairsFile = cfg.HarvestPairsPath
+			}
+			pairs := tax.DefaultReplacementPairs
+			if pairsFile != "" {
+				f, err := os.Open(pairsFile)
+				if err != nil {
+					return fmt.Errorf("failed to open pairs file: %w", err)
+				}
+				pairs, err = tax.ParseReplacementPairs(f)
+				f.Close()
+				if err != nil {
+					return err
+				}
+			}
+
+			resolveTaxRates(cmd, cfg, &rates)
+
+			report, err := tax.NewReporter(queries).Harvest(ctx, tax.HarvestOptions{
+				AsOf:          asOf,
+				AccountID:     accountID,
+				MinLossMicros: int64(minLoss * 1_000_000),
+				PricesMicros:  prices,
+				ShortTermRate: rates.ShortTerm,
+				LongTermRate:  rates.LongTerm,
+				StateRate:     rates.State,
+				Pairs:         pairs,
+			})
+			if err != nil {
+				return err
+			}
+
+			printHarvestCandidates("Short-Term Losses", report.ShortTerm, rates.ShortTerm+rates.State)
+			printHarvestCandidates("Long-Term Losses", report.LongTerm, rates.LongTerm+rates.State)
+
+			fmt.Printf("\nShort-term losses: %s\n", formatMicros(-report.ShortTermLossMicros))
+			fmt.Printf("Long-term losses:  %s\n", formatMicros(-report.LongTermLossMicros))
+			fmt.Printf("Est. tax saved:    %s\n", formatMicros(report.TaxSavedMicros))
+
+			if len(report.MissingPrices) > 0 {
+				fmt.Printf("\nNo price for: %s\n", strings.Join(report.MissingPrices, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountName, "account-name", "", "Limit to one account (optional)")
+	cmd.Flags().Float64Var(&minLoss, "min-loss", 100, "Minimum unrealized loss per lot in dollars")
+	cmd.Flags().StringVar(&pricesFile, "prices", "", "CSV of symbol,price")
+	cmd.Flags().StringArrayVar(&priceFlags, "price", nil, "Price override as SYMBOL=PRICE (repeatable)")
+	cmd.Flags().StringVar(&asOfStr, "as-of", "", "Evaluate as of date YYYY-MM-DD (default today)")
+	cmd.Flags().StringVar(&pairsFile, "pairs", "", "CSV of replacement pairs")
+	addTaxRateFlags(cmd, &rates)
+
+	return cmd
+}
+
+// addTaxRateFlags registers rate overrides; resolveTaxRates fills in the
+// configured rates for any flag not given.
+func addTaxRateFlags(cmd *cobra.Command, rates *taxlots.TaxRates) {
+	cmd.Flags().Float64Var(&rates.ShortTerm, "short-term-rate", 0, "Federal short-term marginal rate, e.g. 0.32")
+	cmd.Flags().Float64Var(&rates.LongTerm, "long-term-rate", 0, "Federal long-term rate, e.g. 0.15")
+	cmd.Flags().Float64Var(&rates.State, "state-rate", 0, "State marginal rate, e.g. 0.05")
+}
+
+func resolveTaxRates(cmd *cobra.Command, cfg *config.Config, rates *taxlots.TaxRates) {
+	if !cmd.Flags().Changed("short-term-rate") {
+		rates.ShortTerm = cfg.TaxShortTermRate
+	}
+	if !cmd.Flags().Changed("long-term-rate") {
+		rates.LongTerm = cfg.TaxLongTermRate
+	}
+	if !cmd.Flags().Changed("state-rate") {
+		rates.State = cfg.TaxStateRate
+	}
+}
+
+func printHarvestCandidates(title string, candidates []tax.HarvestCandidate, rate float64) {
+	fmt.Printf("\n=== %s (%.0f%%) ===\n\n", title, rate*100)
+	if len(candidates) == 0 {
+		fmt.Println("None.")
+		return
+	}
+
+	tbl := table.New("Account", "Symbol", "Acquired", "Quantity", "Basis", "Value", "Loss", "Tax Saved", "Wash Sale", "Replace With")
+	for _, c := range candidates {
+		wash := ""
+		if len(c.WashSaleBuys) > 0 {
+			last := c.WashSaleBuys[len(c.WashSaleBuys)-1]
+			wash = fmt.Sprintf("%s sh bought %s", formatQty(float64(c.WashSaleQuantityMicros)/1_000_000), last.Date)
+			if last.DRIP {
+				wash += " (DRIP)"
+			}
+		} else if c.DRIPEnrolled {
+			wash = "DRIP"
+		}
+		tbl.AddRow(
+			c.AccountName,
+			c.Symbol,
+			c.AcquiredDate,
+			formatQty(float64(c.QuantityMicros)/1_000_000),
+			formatMicros(c.CostBasisMicros),
+			formatMicros(c.MarketValueMicros),
+			formatMicros(-c.LossMicros),
+			formatMicros(c.TaxSavedMicros),
+			wash,
+			strings.Join(c.Replacements, ", "),
+		)
+	}
+	tbl.Print()
+}
+
+// loadHarvestPrices merges prices from the latest position snapshots and
+// stored closes on or before date, a symbol,price CSV and SYMBOL=PRICE flags,
+// later sources winning.
+func loadHarvestPrices(ctx context.Context, queries *db.Queries, date time.Time, pricesFile string, priceFlags []string) (map[string]int64, error) {
+	prices := make(map[string]int64)
+	day := date.Format("2006-01-02")
+
+	positions, err := queries.ListAllPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %w", err)
+	}
+	asOf := make(map[string]string)
+	for _, p := range positions {
+		if !p.MarketValueMicros.Valid || p.QuantityMicros == 0 || p.AsOfDate > day || p.AsOfDate < asOf[p.Symbol] {
+			continue
+		}
+		prices[p.Symbol] = taxlots.ProRata(p.MarketValueMicros.Int64, p.QuantityMicros, 1_000_000)
+		asOf[p.Symbol] = p.AsOfDate
+	}
+
+	closes, err := queries.ListLatestPrices(ctx, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	for _, c := range closes {
+		if c.PriceDate >= asOf[c.Symbol] {
+			prices[c.Symbol] = c.CloseMicros
+		}
+	}
+
+	if pricesFile != "" {
+		f, err := os.Open(pricesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open prices file: %w", err)
+		}
+		defer f.Close()
+
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prices file: %w", err)
+		}
+		for _, record := range records {
+			if len(record) < 2 {
+				continue
+			}
+			price, err := decimal.NewFromString(strings.TrimPrefix(strings.TrimSpace(record[1]), "$"))
+			if err != nil {
+				// header row
+				continue
+			}
+			prices[strings.ToUpper(strings.TrimSpace(record[0]))] = price.Mul(decimal.NewFromInt(1_000_000)).IntPart()
+		}
+	}
+
+	for _, flag := range priceFlags {
+		symbol, value, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --price %q, want SYMBOL=PRICE", flag)
+		}
+		price, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --price %q: %w", flag, err)
+		}
+		prices[strings.ToUpper(symbol)] = price.Mul(decimal.NewFromInt(1_000_000)).IntPart()
+	}
+
+	return prices, nil
+}
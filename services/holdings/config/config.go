@@ -42,6 +42,9 @@ func defaultConfig() *Config {
 		ListenAddr:   ":8888",
 		LoggingLevel: "info",
 		DBPath:       "./holdings.db",
+
+		TaxShortTermRate: 0.32,
+		TaxLongTermRate:  0.15,
 	}
 }
 
@@ -49,4 +52,19 @@ type Config struct {
 	ListenAddr   string `env:"LISTEN_ADDR"`
 	LoggingLevel string `env:"LOGGING_LEVEL"`
 	DBPath       string `env:"DB_PATH"`
+
+	// Marginal rates used for tax estimates (0.32 = 32%).
+	TaxShortTermRate float64 `env:"TAX_SHORT_TERM_RATE"`
+	TaxLongTermRate  float64 `env:"TAX_LONG_TERM_RATE"`
+	TaxStateRate     float64 `env:"TAX_STATE_RATE"`
+
+	// CSV of replacement securities for tax-loss harvesting, one line per
+	// symbol: "VTI,ITOT,SCHB". Built-in pairs are used when unset.
+	HarvestPairsPath string `env:"HARVEST_PAIRS_PATH"`
+
+	// Market data for "prices sync": a directory or CSV of closes, or the URL
+	// of a server speaking the marketdata HTTP API. The URL wins when both
+	// are set.
+	MarketDataPath string `env:"MARKET_DATA_PATH"`
+	MarketDataURL  string `env:"MARKET_DATA_URL"`
 }
@@ -28,6 +28,11 @@ func Open(ctx context.Context, dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
+	if err := migrateColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate columns: %w", err)
+	}
+
 	if _, err := db.ExecContext(ctx, schema); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create schema: %w", err)
@@ -293,6 +293,42 @@ func TestTransactionsAndLots(t *testing.T) {
 			t.Errorf("expected 1 lot, got %d", len(lots))
 		}
 	})
+
+	t.Run("re-import fills in dividend type", func(t *testing.T) {
+		for _, dividendType := range []string{"", "qualified", ""} {
+			err := queries.CreateTransaction(ctx, db.CreateTransactionParams{
+				ID:              database.NewID(database.PrefixTransaction),
+				AccountID:       acct.ID,
+				SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+				TransactionType: "dividend",
+				TransactionDate: "2024-03-14",
+				QuantityMicros:  sql.NullInt64{Int64: 0, Valid: true},
+				AmountMicros:    7_500_000,
+				Description:     sql.NullString{String: "MICROSOFT CORP", Valid: true},
+				DividendType:    sql.NullString{String: dividendType, Valid: dividendType != ""},
+			})
+			if err != nil {
+				t.Fatalf("failed to create transaction: %v", err)
+			}
+		}
+
+		txns, err := queries.ListTransactionsByAccount(ctx, acct.ID)
+		if err != nil {
+			t.Fatalf("failed to list transactions: %v", err)
+		}
+		var dividends []db.ListTransactionsByAccountRow
+		for _, txn := range txns {
+			if txn.TransactionType == "dividend" {
+				dividends = append(dividends, txn)
+			}
+		}
+		if len(dividends) != 1 {
+			t.Fatalf("expected 1 dividend, got %d", len(dividends))
+		}
+		if dividends[0].DividendType.String != "qualified" {
+			t.Errorf("expected dividend type 'qualified', got %q", dividends[0].DividendType.String)
+		}
+	})
 }
 
 func TestCashTransactions(t *testing.T) {
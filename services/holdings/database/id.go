@@ -1,6 +1,11 @@
 package database
 
-import "github.com/segmentio/ksuid"
+import (
+	"crypto/sha256"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
 
 type IDPrefix string
 
@@ -11,9 +16,20 @@ const (
 	PrefixTransaction    IDPrefix = "txn"
 	PrefixLot            IDPrefix = "lot"
 	PrefixLotDisposition IDPrefix = "disp"
+	PrefixLotTransfer    IDPrefix = "xfer"
+	PrefixLotAdjustment  IDPrefix = "adj"
 	PrefixCashTxn        IDPrefix = "cash"
+	PrefixDiagnostic     IDPrefix = "diag"
 )
 
 func NewID(prefix IDPrefix) string {
 	return string(prefix) + "_" + ksuid.New().String()
 }
+
+// DerivedID returns an ID determined entirely by parts, formatted like NewID.
+// Rows rebuilt from the same source rows get the same ID every time.
+func DerivedID(prefix IDPrefix, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	id, _ := ksuid.FromBytes(sum[:len(ksuid.Nil)])
+	return string(prefix) + "_" + id.String()
+}
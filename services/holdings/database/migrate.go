@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations lists columns added to tables after they first shipped.
+// schema.sql only creates missing tables, so databases created before a column
+// existed get it added here. Runs before the schema so indexes on new columns
+// can be created.
+var columnMigrations = []columnMigration{
+	{table: "lots", column: "source_lot_id", definition: "text references lots (id) on delete set null"},
+	{table: "transactions", column: "fees_in_amount", definition: "boolean not null default 1"},
+	{table: "lot_dispositions", column: "fees_micros", definition: "integer not null default 0"},
+	{table: "lots", column: "acquisition_kind", definition: "text not null default 'purchase'"},
+	{table: "lots", column: "fmv_micros", definition: "integer"},
+	{table: "lots", column: "donor_acquired_date", definition: "text"},
+	{table: "acquisitions", column: "plan_type", definition: "text"},
+	{table: "acquisitions", column: "shares_withheld_micros", definition: "integer"},
+	{table: "acquisitions", column: "grant_date", definition: "text"},
+	{table: "acquisitions", column: "grant_fmv_micros", definition: "integer"},
+	{table: "acquisitions", column: "discount_rate", definition: "real"},
+	{table: "lots", column: "plan_type", definition: "text"},
+	{table: "lots", column: "grant_date", definition: "text"},
+	{table: "lots", column: "grant_fmv_micros", definition: "integer"},
+	{table: "lots", column: "discount_rate", definition: "real"},
+	{table: "lot_dispositions", column: "compensation_income_micros", definition: "integer not null default 0"},
+	{table: "lot_dispositions", column: "espp_disposition", definition: "text"},
+	{table: "lots", column: "position_side", definition: "text not null default 'long'"},
+	{table: "accounts", column: "margin", definition: "boolean not null default 0"},
+	{table: "prices", column: "filled", definition: "boolean not null default 0"},
+	{table: "valuations", column: "net_flow_micros", definition: "integer not null default 0"},
+	{table: "accounts", column: "benchmark", definition: "text"},
+	{table: "transactions", column: "dividend_type", definition: "text"},
+	{table: "cash_transactions", column: "dividend_type", definition: "text"},
+}
+
+func migrateColumns(ctx context.Context, db *sql.DB) error {
+	for _, m := range columnMigrations {
+		var tables int
+		err := db.QueryRowContext(ctx,
+			"select count(*) from sqlite_master where type = 'table' and name = ?", m.table,
+		).Scan(&tables)
+		if err != nil {
+			return err
+		}
+		if tables == 0 {
+			continue
+		}
+
+		var columns int
+		err = db.QueryRowContext(ctx,
+			"select count(*) from pragma_table_info(?) where name = ?", m.table, m.column,
+		).Scan(&columns)
+		if err != nil {
+			return err
+		}
+		if columns > 0 {
+			continue
+		}
+
+		stmt := fmt.Sprintf("alter table %s add column %s %s", m.table, m.column, m.definition)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
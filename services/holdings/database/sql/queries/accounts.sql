@@ -52,3 +52,17 @@ returning *;
 -- name: DeleteAccount :exec
 delete from accounts
 where id = @id;
+
+-- name: SetAccountMargin :exec
+update accounts
+set
+    margin = @margin,
+    updated_at = datetime('now')
+where id = @id;
+
+-- name: SetAccountBenchmark :exec
+update accounts
+set
+    benchmark = @benchmark,
+    updated_at = datetime('now')
+where id = @id;
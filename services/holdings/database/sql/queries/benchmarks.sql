@@ -0,0 +1,44 @@
+-- name: ListBenchmarks :many
+select *
+from benchmarks
+order by name;
+
+-- name: ListBenchmarkComponents :many
+select *
+from benchmark_components
+order by benchmark_name, weight desc, symbol;
+
+-- name: UpsertBenchmark :exec
+insert into benchmarks (
+    name,
+    description,
+    rebalance
+) values (
+    @name,
+    @description,
+    @rebalance
+)
+on conflict (name) do update set
+    description = excluded.description,
+    rebalance = excluded.rebalance;
+
+-- name: CreateBenchmarkComponent :exec
+insert into benchmark_components (
+    benchmark_name,
+    symbol,
+    weight,
+    security_type
+) values (
+    @benchmark_name,
+    @symbol,
+    @weight,
+    @security_type
+);
+
+-- name: DeleteBenchmarkComponents :exec
+delete from benchmark_components
+where benchmark_name = @benchmark_name;
+
+-- name: DeleteBenchmark :exec
+delete from benchmarks
+where name = @name;
@@ -7,7 +7,8 @@ insert into cash_transactions (
     cash_type,
     amount_micros,
     security_id,
-    description
+    description,
+    dividend_type
 ) values (
     @id,
     @account_id,
@@ -16,7 +17,8 @@ insert into cash_transactions (
     @cash_type,
     @amount_micros,
     @security_id,
-    @description
+    @description,
+    @dividend_type
 )
 on conflict do nothing;
 
@@ -76,3 +78,82 @@ delete from cash_transactions
 where
     account_id = @account_id
     and cash_type != 'opening';
+
+-- name: ListDailyCashChanges :many
+select
+    account_id,
+    transaction_date,
+    cash_type,
+    cast(sum(amount_micros) as integer) as amount_micros
+from cash_transactions
+group by account_id, transaction_date, cash_type
+order by transaction_date;
+
+-- name: ListSecurityIncome :many
+select
+    cast(security_id as text) as security_id,
+    transaction_date,
+    cash_type,
+    amount_micros
+from cash_transactions
+where
+    account_id = @account_id
+    and security_id is not null
+    and cash_type in ('dividend', 'interest', 'cap_gain', 'return_of_capital')
+    and transaction_date > @start_date
+    and transaction_date <= @end_date
+order by transaction_date;
+
+-- name: UpsertCashCheckpoint :exec
+insert into cash_checkpoints (
+    account_id,
+    checkpoint_date,
+    balance_micros,
+    source,
+    description
+) values (
+    @account_id,
+    @checkpoint_date,
+    @balance_micros,
+    @source,
+    @description
+)
+on conflict (account_id, checkpoint_date) do update set
+    balance_micros = excluded.balance_micros,
+    source = excluded.source,
+    description = excluded.description;
+
+-- name: ListCashCheckpoints :many
+select *
+from cash_checkpoints
+where account_id = @account_id
+order by checkpoint_date;
+
+-- name: DeleteCashCheckpoint :exec
+delete from cash_checkpoints
+where
+    account_id = @account_id
+    and checkpoint_date = @checkpoint_date;
+
+-- name: ListIncomeActivity :many
+select
+    ct.account_id,
+    a.name as account_name,
+    ct.transaction_date,
+    ct.cash_type,
+    ct.amount_micros,
+    ct.security_id,
+    s.symbol,
+    s.name as security_name,
+    b.coupon_rate,
+    ct.description,
+    ct.dividend_type
+from cash_transactions ct
+join accounts a on a.id = ct.account_id
+left join securities s on s.id = ct.security_id
+left join bonds b on b.security_id = ct.security_id
+where
+    ct.cash_type in ('dividend', 'interest', 'cap_gain', 'return_of_capital', 'fee', 'transfer_out', 'withdrawal')
+    and ct.transaction_date >= @start_date
+    and ct.transaction_date <= @end_date
+order by ct.transaction_date, a.name;
@@ -0,0 +1,38 @@
+-- name: CreateImportDiagnostic :exec
+insert into import_diagnostics (
+    id,
+    account_id,
+    source_file,
+    line_number,
+    activity_date,
+    activity,
+    symbol,
+    amount_micros,
+    description,
+    reason
+) values (
+    @id,
+    @account_id,
+    @source_file,
+    @line_number,
+    @activity_date,
+    @activity,
+    @symbol,
+    @amount_micros,
+    @description,
+    @reason
+)
+on conflict do nothing;
+
+-- name: ListImportDiagnostics :many
+select *
+from import_diagnostics
+where
+    account_id = @account_id
+    and activity_date > @start_date
+    and activity_date <= @end_date
+order by activity_date, source_file, line_number;
+
+-- name: DeleteImportDiagnosticsByAccount :exec
+delete from import_diagnostics
+where account_id = @account_id;
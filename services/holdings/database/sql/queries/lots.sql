@@ -15,7 +15,16 @@ insert into lots (
     acquired_date,
     quantity_micros,
     remaining_micros,
-    cost_basis_micros
+    cost_basis_micros,
+    source_lot_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate,
+    position_side
 ) values (
     @id,
     @account_id,
@@ -24,7 +33,16 @@ insert into lots (
     @acquired_date,
     @quantity_micros,
     @remaining_micros,
-    @cost_basis_micros
+    @cost_basis_micros,
+    @source_lot_id,
+    @acquisition_kind,
+    @fmv_micros,
+    @donor_acquired_date,
+    @plan_type,
+    @grant_date,
+    @grant_fmv_micros,
+    @discount_rate,
+    @position_side
 )
 returning *;
 
@@ -40,7 +58,7 @@ where
     account_id = @account_id
     and security_id = @security_id
     and remaining_micros > 0
-order by acquired_date asc;
+order by acquired_date asc, id asc;
 
 -- name: ListLotsByAccount :many
 select
@@ -52,11 +70,122 @@ join securities s on s.id = l.security_id
 where l.account_id = @account_id
 order by l.acquired_date asc;
 
+-- name: ListOpenLots :many
+select
+    l.*,
+    s.symbol,
+    s.name as security_name,
+    s.security_type,
+    a.name as account_name
+from lots l
+join securities s on s.id = l.security_id
+join accounts a on a.id = l.account_id
+where l.remaining_micros > 0
+order by s.symbol asc, l.acquired_date asc;
+
+-- name: ListOpenLotsAsOf :many
+select
+    id,
+    account_id,
+    security_id,
+    transaction_id,
+    acquired_date,
+    quantity_micros,
+    remaining_micros,
+    cost_basis_micros,
+    source_lot_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate,
+    position_side,
+    created_at,
+    symbol,
+    security_name,
+    security_type,
+    account_name
+from (
+    select
+        l.id,
+        l.account_id,
+        l.security_id,
+        l.transaction_id,
+        l.acquired_date,
+        l.quantity_micros,
+        cast(
+            l.quantity_micros
+            - coalesce((select sum(d.quantity_micros) from lot_dispositions d where d.lot_id = l.id and d.disposed_date <= @as_of_date), 0)
+            - coalesce((select sum(x.quantity_micros) from lot_transfers x where x.lot_id = l.id and x.transfer_date <= @as_of_date), 0)
+            as integer
+        ) as remaining_micros,
+        coalesce(
+            (
+                select a.basis_before_micros
+                from lot_adjustments a
+                where a.lot_id = l.id and a.effective_date > @as_of_date
+                order by a.effective_date asc, a.created_at asc
+                limit 1
+            ),
+            l.cost_basis_micros
+        ) as cost_basis_micros,
+        l.source_lot_id,
+        l.acquisition_kind,
+        l.fmv_micros,
+        l.donor_acquired_date,
+        l.plan_type,
+        l.grant_date,
+        l.grant_fmv_micros,
+        l.discount_rate,
+        l.position_side,
+        l.created_at,
+        s.symbol,
+        s.name as security_name,
+        s.security_type,
+        a.name as account_name
+    from lots l
+    join transactions t on t.id = l.transaction_id
+    join securities s on s.id = l.security_id
+    join accounts a on a.id = l.account_id
+    where t.transaction_date <= @as_of_date
+)
+where remaining_micros > 0
+order by symbol asc, acquired_date asc;
+
 -- name: UpdateLotRemaining :exec
 update lots
 set remaining_micros = @remaining_micros
 where id = @id;
 
+-- name: RecomputeLotRemaining :exec
+update lots
+set remaining_micros = quantity_micros
+    - coalesce((select sum(d.quantity_micros) from lot_dispositions d where d.lot_id = lots.id), 0)
+    - coalesce((select sum(t.quantity_micros) from lot_transfers t where t.lot_id = lots.id), 0)
+where
+    account_id = @account_id
+    and security_id = @security_id;
+
+-- name: CreateLotTransfer :one
+insert into lot_transfers (
+    id,
+    lot_id,
+    transaction_id,
+    transfer_date,
+    quantity_micros,
+    destination_lot_id
+) values (
+    @id,
+    @lot_id,
+    @transaction_id,
+    @transfer_date,
+    @quantity_micros,
+    @destination_lot_id
+)
+returning *;
+
 -- name: CreateLotDisposition :one
 insert into lot_dispositions (
     id,
@@ -66,8 +195,11 @@ insert into lot_dispositions (
     quantity_micros,
     cost_basis_micros,
     proceeds_micros,
+    fees_micros,
     realized_gain_micros,
-    holding_period
+    holding_period,
+    compensation_income_micros,
+    espp_disposition
 ) values (
     @id,
     @lot_id,
@@ -76,8 +208,11 @@ insert into lot_dispositions (
     @quantity_micros,
     @cost_basis_micros,
     @proceeds_micros,
+    @fees_micros,
     @realized_gain_micros,
-    @holding_period
+    @holding_period,
+    @compensation_income_micros,
+    @espp_disposition
 )
 returning *;
 
@@ -90,13 +225,29 @@ from lot_dispositions d
 join lots l on l.id = d.lot_id
 where d.sell_transaction_id = @sell_transaction_id;
 
+-- name: ListDispositionsByAccount :many
+select
+    d.*,
+    l.acquired_date,
+    l.security_id,
+    s.symbol
+from lot_dispositions d
+join lots l on l.id = d.lot_id
+join securities s on s.id = l.security_id
+where l.account_id = @account_id
+order by d.disposed_date asc, s.symbol asc;
+
 -- name: ListDispositionsByYear :many
 select
     d.*,
     l.acquired_date,
     l.security_id,
+    l.account_id,
+    l.plan_type,
+    l.transaction_id as lot_transaction_id,
     s.symbol,
-    s.name as security_name
+    s.name as security_name,
+    s.security_type
 from lot_dispositions d
 join lots l on l.id = d.lot_id
 join securities s on s.id = l.security_id
@@ -130,10 +281,11 @@ group by s.symbol, s.id;
 select
     s.symbol,
     s.name as security_name,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -149,10 +301,11 @@ select
     a.name as account_name,
     s.symbol,
     s.name as security_name,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -168,10 +321,11 @@ select
     s.symbol,
     s.name as security_name,
     count(distinct a.id) as account_count,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -181,3 +335,202 @@ join accounts a on a.id = l.account_id
 where l.remaining_micros > 0
 group by s.symbol, s.name
 order by cost_basis_micros desc;
+
+-- name: ListLotJournalByAccount :many
+select *
+from lot_journal
+where account_id = @account_id
+order by security_id asc, sort_key asc;
+
+-- name: CreateLotJournalEntry :exec
+insert into lot_journal (
+    account_id,
+    security_id,
+    sort_key,
+    transaction_id,
+    digest
+) values (
+    @account_id,
+    @security_id,
+    @sort_key,
+    @transaction_id,
+    @digest
+);
+
+-- name: DeleteLotJournalByAccount :exec
+delete from lot_journal
+where account_id = @account_id;
+
+-- name: DeleteLotJournalFrom :exec
+delete from lot_journal
+where
+    account_id = @account_id
+    and security_id = @security_id
+    and sort_key >= @sort_key;
+
+-- name: DeleteLotDispositionsFrom :exec
+delete from lot_dispositions
+where
+    lot_id in (select id from lots where account_id = @account_id and security_id = @security_id)
+    and sell_transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+    );
+
+-- name: DeleteLotTransfersFrom :exec
+delete from lot_transfers
+where
+    lot_id in (select id from lots where account_id = @account_id and security_id = @security_id)
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+    );
+
+-- name: DeleteLotsFrom :exec
+delete from lots
+where
+    account_id = @account_id
+    and security_id = @security_id
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+    );
+
+-- name: CreateLotAdjustment :one
+insert into lot_adjustments (
+    id,
+    account_id,
+    security_id,
+    lot_id,
+    transaction_id,
+    adjustment_type,
+    effective_date,
+    amount_micros,
+    basis_before_micros,
+    basis_after_micros,
+    gain_micros,
+    reason
+) values (
+    @id,
+    @account_id,
+    @security_id,
+    @lot_id,
+    @transaction_id,
+    @adjustment_type,
+    @effective_date,
+    @amount_micros,
+    @basis_before_micros,
+    @basis_after_micros,
+    @gain_micros,
+    @reason
+)
+returning *;
+
+-- name: ListLotAdjustmentsByAccount :many
+select
+    a.*,
+    s.symbol
+from lot_adjustments a
+join securities s on s.id = a.security_id
+where a.account_id = @account_id
+order by a.effective_date asc, a.created_at asc;
+
+-- name: ListEnteredLotAdjustmentsByAccount :many
+select *
+from lot_adjustments
+where
+    account_id = @account_id
+    and transaction_id is null
+order by effective_date asc, id asc;
+
+-- name: UpdateLotAdjustmentApplied :exec
+update lot_adjustments
+set
+    basis_before_micros = @basis_before_micros,
+    basis_after_micros = @basis_after_micros
+where id = @id;
+
+-- name: UpdateLotBasis :exec
+update lots
+set cost_basis_micros = @cost_basis_micros
+where id = @id;
+
+-- name: RestoreLotBasisFrom :exec
+update lots
+set cost_basis_micros = cost_basis_micros - coalesce((
+    select sum(a.basis_after_micros - a.basis_before_micros)
+    from lot_adjustments a
+    where
+        a.lot_id = lots.id
+        and coalesce(a.transaction_id, a.id) not in (
+            select transaction_id
+            from lot_journal
+            where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+        )
+), 0)
+where
+    account_id = @account_id
+    and security_id = @security_id;
+
+-- name: DeleteLotAdjustmentsFrom :exec
+delete from lot_adjustments
+where
+    account_id = @account_id
+    and security_id = @security_id
+    and transaction_id is not null
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+    );
+
+-- name: ResetLotAdjustmentsFrom :exec
+update lot_adjustments
+set
+    basis_before_micros = 0,
+    basis_after_micros = 0
+where
+    account_id = @account_id
+    and security_id = @security_id
+    and transaction_id is null
+    and id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = @account_id and security_id = @security_id and sort_key < @sort_key
+    );
+
+
+-- name: ListLotQuantityChanges :many
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    t.transaction_date as change_date,
+    t.transaction_type as change_type,
+    l.quantity_micros
+from lots l
+join transactions t on t.id = l.transaction_id
+union all
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    d.disposed_date as change_date,
+    'disposition' as change_type,
+    -d.quantity_micros as quantity_micros
+from lot_dispositions d
+join lots l on l.id = d.lot_id
+union all
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    x.transfer_date as change_date,
+    'transfer_out' as change_type,
+    -x.quantity_micros as quantity_micros
+from lot_transfers x
+join lots l on l.id = x.lot_id
+order by change_date;
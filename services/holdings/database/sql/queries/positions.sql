@@ -3,6 +3,13 @@ select *
 from positions
 where id = @id;
 
+-- name: GetLatestPositionDate :one
+select as_of_date
+from positions
+where account_id = @account_id and as_of_date <= @as_of_date
+order by as_of_date desc
+limit 1;
+
 -- name: ListAllPositions :many
 select
     p.*,
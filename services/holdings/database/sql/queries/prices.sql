@@ -0,0 +1,72 @@
+-- name: UpsertPrice :exec
+insert into prices (
+    security_id,
+    price_date,
+    close_micros,
+    source,
+    filled
+) values (
+    @security_id,
+    @price_date,
+    @close_micros,
+    @source,
+    @filled
+)
+on conflict (security_id, price_date) do update set
+    close_micros = excluded.close_micros,
+    source = excluded.source,
+    filled = excluded.filled
+where excluded.filled = 0 or prices.filled = 1;
+
+-- name: GetLastPrice :one
+select *
+from prices
+where security_id = @security_id and filled = 0
+order by price_date desc
+limit 1;
+
+-- name: ListLatestPrices :many
+select
+    p.*,
+    s.symbol
+from prices p
+join securities s on s.id = p.security_id
+where p.price_date = (
+    select max(p2.price_date)
+    from prices p2
+    where p2.security_id = p.security_id and p2.price_date <= @as_of_date
+)
+order by s.symbol;
+
+-- name: UpsertCorporateAction :exec
+insert into corporate_actions (
+    security_id,
+    action_date,
+    action_type,
+    ratio,
+    amount_micros,
+    source
+) values (
+    @security_id,
+    @action_date,
+    @action_type,
+    @ratio,
+    @amount_micros,
+    @source
+)
+on conflict (security_id, action_date, action_type) do update set
+    ratio = excluded.ratio,
+    amount_micros = excluded.amount_micros,
+    source = excluded.source;
+
+-- name: ListCorporateActions :many
+select *
+from corporate_actions
+where security_id = @security_id
+order by action_date, action_type;
+
+-- name: ListPricesThrough :many
+select *
+from prices
+where price_date <= @to_date
+order by security_id, price_date;
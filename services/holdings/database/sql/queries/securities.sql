@@ -8,11 +8,84 @@ select *
 from securities
 where symbol = @symbol;
 
+-- name: ListBonds :many
+select
+    b.*,
+    s.symbol
+from bonds b
+join securities s on s.id = b.security_id
+order by s.symbol asc;
+
+-- name: ListOptions :many
+select
+    o.*,
+    s.symbol,
+    u.id as underlying_security_id
+from options o
+join securities s on s.id = o.security_id
+left join securities u on u.symbol = o.underlying_symbol
+order by s.symbol asc;
+
 -- name: ListSecurities :many
 select *
 from securities
 order by symbol;
 
+-- name: UpsertBond :exec
+insert into bonds (
+    security_id,
+    coupon_rate,
+    maturity_date,
+    dated_date,
+    first_coupon_date,
+    call_date,
+    call_price_micros,
+    par_micros,
+    payments_per_year
+) values (
+    @security_id,
+    @coupon_rate,
+    @maturity_date,
+    @dated_date,
+    @first_coupon_date,
+    @call_date,
+    @call_price_micros,
+    @par_micros,
+    @payments_per_year
+)
+on conflict (security_id) do update set
+    coupon_rate = excluded.coupon_rate,
+    maturity_date = excluded.maturity_date,
+    dated_date = excluded.dated_date,
+    first_coupon_date = excluded.first_coupon_date,
+    call_date = excluded.call_date,
+    call_price_micros = excluded.call_price_micros,
+    par_micros = excluded.par_micros,
+    payments_per_year = excluded.payments_per_year;
+
+-- name: UpsertOption :exec
+insert into options (
+    security_id,
+    underlying_symbol,
+    expiration_date,
+    option_type,
+    strike_micros,
+    multiplier
+) values (
+    @security_id,
+    @underlying_symbol,
+    @expiration_date,
+    @option_type,
+    @strike_micros,
+    @multiplier
+)
+on conflict (security_id) do update set
+    underlying_symbol = excluded.underlying_symbol,
+    expiration_date = excluded.expiration_date,
+    option_type = excluded.option_type,
+    strike_micros = excluded.strike_micros,
+    multiplier = excluded.multiplier;
+
 -- name: UpsertSecurity :one
 insert into securities (
     id,
@@ -33,3 +106,12 @@ on conflict (symbol) do update set
     cusip = coalesce(excluded.cusip, securities.cusip),
     updated_at = datetime('now')
 returning *;
+
+-- name: ListHeldSecurities :many
+select
+    s.*,
+    cast(min(t.transaction_date) as text) as first_transaction_date
+from securities s
+join transactions t on t.security_id = s.id
+group by s.id
+order by s.symbol;
@@ -3,13 +3,34 @@ select *
 from transactions
 where id = @id;
 
+-- name: GetTransactionIDByKey :one
+select id
+from transactions
+where account_id = @account_id
+  and security_id is @security_id
+  and transaction_type = @transaction_type
+  and transaction_date = @transaction_date
+  and quantity_micros is @quantity_micros
+  and amount_micros = @amount_micros
+  and description is @description;
+
 -- name: ListTransactionsByAccount :many
 select
     t.*,
     s.symbol,
-    s.name as security_name
+    s.name as security_name,
+    aq.acquisition_kind,
+    aq.fmv_micros,
+    aq.donor_acquired_date,
+    aq.plan_type,
+    aq.grant_date,
+    aq.grant_fmv_micros,
+    aq.discount_rate,
+    a.margin as account_margin
 from transactions t
+join accounts a on a.id = t.account_id
 left join securities s on s.id = t.security_id
+left join acquisitions aq on aq.transaction_id = t.id
 where t.account_id = @account_id
 order by t.transaction_date desc, t.created_at desc;
 
@@ -37,7 +58,9 @@ insert into transactions (
     price_micros,
     amount_micros,
     fees_micros,
-    description
+    fees_in_amount,
+    description,
+    dividend_type
 ) values (
     @id,
     @account_id,
@@ -48,9 +71,12 @@ insert into transactions (
     @price_micros,
     @amount_micros,
     @fees_micros,
-    @description
+    @fees_in_amount,
+    @description,
+    @dividend_type
 )
-on conflict do nothing;
+on conflict (account_id, security_id, transaction_type, transaction_date, quantity_micros, amount_micros, description) do update set
+    dividend_type = coalesce(excluded.dividend_type, transactions.dividend_type);
 
 -- name: DeleteTransaction :exec
 delete from transactions
@@ -59,3 +85,61 @@ where id = @id;
 -- name: DeleteTransactionsByAccount :exec
 delete from transactions
 where account_id = @account_id;
+
+-- name: ListPurchasesSince :many
+select
+    t.*,
+    s.symbol,
+    a.name as account_name
+from transactions t
+join securities s on s.id = t.security_id
+join accounts a on a.id = t.account_id
+where
+    t.transaction_type in ('buy', 'rsu_vest', 'espp_purchase')
+    and t.transaction_date >= @since
+order by t.transaction_date asc, t.id asc;
+
+-- name: ListSecurityTransfers :many
+select
+    t.*,
+    s.symbol,
+    a.name as account_name
+from transactions t
+join securities s on s.id = t.security_id
+join accounts a on a.id = t.account_id
+where
+    t.transaction_type in ('transfer_out', 'security_transfer')
+    and t.quantity_micros > 0
+order by t.transaction_date asc, t.id asc;
+
+-- name: UpsertAcquisition :exec
+insert into acquisitions (
+    transaction_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    shares_withheld_micros,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate
+) values (
+    @transaction_id,
+    @acquisition_kind,
+    @fmv_micros,
+    @donor_acquired_date,
+    @plan_type,
+    @shares_withheld_micros,
+    @grant_date,
+    @grant_fmv_micros,
+    @discount_rate
+)
+on conflict (transaction_id) do update set
+    acquisition_kind = excluded.acquisition_kind,
+    fmv_micros = excluded.fmv_micros,
+    donor_acquired_date = excluded.donor_acquired_date,
+    plan_type = excluded.plan_type,
+    shares_withheld_micros = excluded.shares_withheld_micros,
+    grant_date = excluded.grant_date,
+    grant_fmv_micros = excluded.grant_fmv_micros,
+    discount_rate = excluded.discount_rate;
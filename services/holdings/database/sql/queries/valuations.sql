@@ -0,0 +1,45 @@
+-- name: DeleteValuations :exec
+delete from valuations;
+
+-- name: CreateValuation :exec
+insert into valuations (
+    account_id,
+    valuation_date,
+    securities_value_micros,
+    cash_micros,
+    market_value_micros,
+    unpriced_count,
+    net_flow_micros
+) values (
+    @account_id,
+    @valuation_date,
+    @securities_value_micros,
+    @cash_micros,
+    @market_value_micros,
+    @unpriced_count,
+    @net_flow_micros
+);
+
+-- name: ListValuationsByAccount :many
+select *
+from valuations
+where
+    account_id = @account_id
+    and valuation_date >= @start_date
+    and valuation_date <= @end_date
+order by valuation_date;
+
+-- name: ListHouseholdValuations :many
+select
+    valuation_date,
+    cast(sum(securities_value_micros) as integer) as securities_value_micros,
+    cast(sum(cash_micros) as integer) as cash_micros,
+    cast(sum(market_value_micros) as integer) as market_value_micros,
+    cast(sum(unpriced_count) as integer) as unpriced_count,
+    cast(sum(net_flow_micros) as integer) as net_flow_micros
+from valuations
+where
+    valuation_date >= @start_date
+    and valuation_date <= @end_date
+group by valuation_date
+order by valuation_date;
@@ -1,5 +1,6 @@
 -- Holdings Service SQLite Schema
 -- Tables created on startup, no migrations needed
+-- Columns added to existing tables must also be listed in database/migrate.go
 
 create table if not exists accounts (
     id text primary key,
@@ -8,6 +9,8 @@ create table if not exists accounts (
     institution_name text not null,
     external_account_number text,
     account_type text not null,
+    margin boolean not null default 0, -- sells beyond holdings open short lots
+    benchmark text,                    -- benchmark name for performance; unset picks one from holdings
     created_at text not null default (datetime('now')),
     updated_at text not null default (datetime('now')),
     unique (institution_name, external_account_number)
@@ -26,8 +29,56 @@ create table if not exists securities (
     updated_at text not null default (datetime('now'))
 );
 
+create table if not exists options (
+    security_id text primary key references securities (id) on delete cascade,
+    underlying_symbol text not null,
+    expiration_date text not null,
+    option_type text not null,        -- call or put
+    strike_micros integer not null,
+    multiplier integer not null default 100, -- shares per contract
+    created_at text not null default (datetime('now'))
+);
+
+create table if not exists bonds (
+    security_id text primary key references securities (id) on delete cascade,
+    coupon_rate real not null,                  -- annual, e.g. 0.05
+    maturity_date text not null,
+    dated_date text,
+    first_coupon_date text,
+    call_date text,                             -- next call, or the pre-refunded redemption date
+    call_price_micros integer,                  -- per 100 of par
+    par_micros integer not null default 1000000, -- face value per unit of quantity
+    payments_per_year integer not null default 2,
+    created_at text not null default (datetime('now'))
+);
+
 create index if not exists securities_cusip_idx on securities (cusip) where cusip is not null;
 
+-- Daily closing prices as traded, not adjusted for later splits. Market data
+-- syncs fill weekdays without a bar (holidays) with the previous close, marked
+-- filled; readers carry the last close forward over weekends.
+create table if not exists prices (
+    security_id text not null references securities (id) on delete cascade,
+    price_date text not null,
+    close_micros integer not null,
+    source text not null,            -- csv, or the market data provider
+    filled boolean not null default 0, -- carried forward from the previous close
+    created_at text not null default (datetime('now')),
+    primary key (security_id, price_date)
+);
+
+-- Splits and cash dividends reported by a market data provider, by ex-date.
+create table if not exists corporate_actions (
+    security_id text not null references securities (id) on delete cascade,
+    action_date text not null,
+    action_type text not null,       -- split or dividend
+    ratio real,                      -- split: new shares per old share, e.g. 2 or 0.1
+    amount_micros integer,           -- dividend: cash per share
+    source text not null,
+    created_at text not null default (datetime('now')),
+    primary key (security_id, action_date, action_type)
+);
+
 create table if not exists positions (
     id text primary key,
     account_id text not null references accounts (id) on delete cascade,
@@ -54,7 +105,9 @@ create table if not exists transactions (
     price_micros integer,
     amount_micros integer not null,
     fees_micros integer,
+    fees_in_amount boolean not null default 1,
     description text,
+    dividend_type text, -- qualified, ordinary, foreign, tax_exempt, section_199a or return_of_capital, when the broker says
     created_at text not null default (datetime('now')),
     unique (account_id, security_id, transaction_type, transaction_date, quantity_micros, amount_micros, description)
 );
@@ -63,6 +116,22 @@ create index if not exists transactions_account_id_idx on transactions (account_
 create index if not exists transactions_date_idx on transactions (transaction_date);
 create index if not exists transactions_type_idx on transactions (transaction_type);
 
+-- How the shares in a transaction were acquired when they weren't bought, e.g.
+-- an opening balance for a gift or an inheritance. Lots created from the
+-- transaction carry the kind and these fields on every replay.
+create table if not exists acquisitions (
+    transaction_id text primary key references transactions (id) on delete cascade,
+    acquisition_kind text not null,  -- purchase, gift, inheritance, transfer, compensation
+    fmv_micros integer,              -- market value of the shares on the transaction date
+    donor_acquired_date text,        -- gift: when the donor acquired the shares
+    plan_type text,                  -- compensation: rsu or espp
+    shares_withheld_micros integer,  -- rsu: shares withheld for taxes, not in the transaction quantity
+    grant_date text,                 -- espp: offering date
+    grant_fmv_micros integer,        -- espp: market value of the shares on the offering date
+    discount_rate real,              -- espp: purchase price discount, e.g. 0.15
+    created_at text not null default (datetime('now'))
+);
+
 create table if not exists lots (
     id text primary key,
     account_id text not null references accounts (id) on delete cascade,
@@ -72,12 +141,22 @@ create table if not exists lots (
     quantity_micros integer not null,
     remaining_micros integer not null,
     cost_basis_micros integer not null,
+    source_lot_id text references lots (id) on delete set null,
+    acquisition_kind text not null default 'purchase',
+    fmv_micros integer,
+    donor_acquired_date text,
+    plan_type text,
+    grant_date text,
+    grant_fmv_micros integer,
+    discount_rate real,
+    position_side text not null default 'long', -- short: sold to open; cost_basis_micros is the premium received
     created_at text not null default (datetime('now'))
 );
 
 create index if not exists lots_account_id_idx on lots (account_id);
 create index if not exists lots_security_id_idx on lots (security_id);
 create index if not exists lots_acquired_date_idx on lots (acquired_date);
+create index if not exists lots_source_lot_id_idx on lots (source_lot_id) where source_lot_id is not null;
 
 create table if not exists lot_dispositions (
     id text primary key,
@@ -87,8 +166,11 @@ create table if not exists lot_dispositions (
     quantity_micros integer not null,
     cost_basis_micros integer not null,
     proceeds_micros integer not null,
+    fees_micros integer not null default 0,
     realized_gain_micros integer not null,
     holding_period text not null,
+    compensation_income_micros integer not null default 0, -- stock plan income included in cost basis
+    espp_disposition text,                                 -- qualifying or disqualifying
     created_at text not null default (datetime('now'))
 );
 
@@ -96,6 +178,58 @@ create index if not exists lot_dispositions_lot_id_idx on lot_dispositions (lot_
 create index if not exists lot_dispositions_sell_transaction_id_idx on lot_dispositions (sell_transaction_id);
 create index if not exists lot_dispositions_disposed_date_idx on lot_dispositions (disposed_date);
 
+-- Shares moved out of a lot without a sale: transfers to another of our
+-- accounts (destination_lot_id set) or out to a broker we don't track.
+create table if not exists lot_transfers (
+    id text primary key,
+    lot_id text not null references lots (id) on delete cascade,
+    transaction_id text not null references transactions (id) on delete cascade,
+    transfer_date text not null,
+    quantity_micros integer not null,
+    destination_lot_id text references lots (id) on delete set null,
+    created_at text not null default (datetime('now'))
+);
+
+create index if not exists lot_transfers_lot_id_idx on lot_transfers (lot_id);
+create index if not exists lot_transfers_transaction_id_idx on lot_transfers (transaction_id);
+
+-- Changes to a lot's cost basis after it was acquired. Return of capital rows
+-- are written per lot by lot processing from return_of_capital transactions;
+-- basis corrections and overrides are entered against a lot and reapplied on
+-- every replay. Lot IDs are stable across replays, so lot_id has no foreign
+-- key. basis_before/after are the lot's cost_basis_micros around the change.
+create table if not exists lot_adjustments (
+    id text primary key,
+    account_id text not null references accounts (id) on delete cascade,
+    security_id text not null references securities (id) on delete cascade,
+    lot_id text not null,
+    transaction_id text,
+    adjustment_type text not null,  -- return_of_capital, basis_correction, basis_override
+    effective_date text not null,
+    amount_micros integer not null,
+    basis_before_micros integer not null default 0,
+    basis_after_micros integer not null default 0,
+    gain_micros integer not null default 0,
+    reason text not null,
+    created_at text not null default (datetime('now'))
+);
+
+create index if not exists lot_adjustments_account_id_idx on lot_adjustments (account_id, security_id);
+create index if not exists lot_adjustments_lot_id_idx on lot_adjustments (lot_id);
+
+-- Transactions and lot adjustments applied by lot processing, per account and
+-- security, in replay order. The next run replays only from the first entry
+-- that no longer matches. transaction_id holds the adjustment ID for
+-- adjustments and has no foreign key so deleted rows still show up.
+create table if not exists lot_journal (
+    account_id text not null references accounts (id) on delete cascade,
+    security_id text not null references securities (id) on delete cascade,
+    sort_key text not null,
+    transaction_id text not null,
+    digest text not null,
+    primary key (account_id, security_id, sort_key)
+);
+
 create table if not exists cash_transactions (
     id text primary key,
     account_id text not null references accounts (id) on delete cascade,
@@ -105,9 +239,76 @@ create table if not exists cash_transactions (
     amount_micros integer not null,
     security_id text references securities (id) on delete set null,
     description text,
+    dividend_type text, -- from the transaction
     created_at text not null default (datetime('now'))
 );
 
 create index if not exists cash_transactions_account_id_idx on cash_transactions (account_id);
 create index if not exists cash_transactions_date_idx on cash_transactions (transaction_date);
 create index if not exists cash_transactions_type_idx on cash_transactions (cash_type);
+
+-- Cash balances the broker reported on a date, from a statement, a positions
+-- file or entered by hand, to check the cash ledger against.
+create table if not exists cash_checkpoints (
+    account_id text not null references accounts (id) on delete cascade,
+    checkpoint_date text not null,
+    balance_micros integer not null,
+    source text not null default 'manual', -- manual, statement or positions
+    description text,
+    created_at text not null default (datetime('now')),
+    primary key (account_id, checkpoint_date)
+);
+
+-- Activity rows an import read but did not record as transactions, e.g. sweeps
+-- or activity with no transaction type. Cash they moved is missing from the
+-- ledger.
+create table if not exists import_diagnostics (
+    id text primary key,
+    account_id text not null references accounts (id) on delete cascade,
+    source_file text not null,
+    line_number integer not null,
+    activity_date text not null,
+    activity text not null,
+    symbol text,
+    amount_micros integer not null, -- as exported: positive into the account
+    description text,
+    reason text not null,           -- unmapped, sweep, reinvestment, foreign_tax or placeholder
+    created_at text not null default (datetime('now'))
+);
+
+create index if not exists import_diagnostics_account_date_idx on import_diagnostics (account_id, activity_date);
+
+-- Market value per account on each weekday, rebuilt from lots, cash
+-- transactions and stored prices. Household values sum the accounts. Net flows
+-- are money and shares moved into or out of the account, valued at the close,
+-- so performance can take them out of returns.
+create table if not exists valuations (
+    account_id text not null references accounts (id) on delete cascade,
+    valuation_date text not null,
+    securities_value_micros integer not null,
+    cash_micros integer not null,
+    market_value_micros integer not null,      -- securities plus cash
+    unpriced_count integer not null default 0, -- holdings without a price, left out of the value
+    net_flow_micros integer not null default 0, -- deposits and shares moved in, less withdrawals and shares moved out
+    created_at text not null default (datetime('now')),
+    primary key (account_id, valuation_date)
+);
+
+-- Custom benchmarks for performance comparison, alongside the built-in SPY,
+-- AGG, 60/40, MUNI and TOTAL; a stored benchmark replaces a built-in one of
+-- the same name. Components are held at their weights, rebalanced at each
+-- month end or bought and held.
+create table if not exists benchmarks (
+    name text primary key,
+    description text,
+    rebalance text not null default 'monthly', -- monthly or none
+    created_at text not null default (datetime('now'))
+);
+
+create table if not exists benchmark_components (
+    benchmark_name text not null references benchmarks (name) on delete cascade,
+    symbol text not null,
+    weight real not null,                         -- share of the benchmark, e.g. 0.6
+    security_type text not null default 'equity', -- attribution segment, e.g. equity or bond
+    primary key (benchmark_name, symbol)
+);
@@ -26,7 +26,7 @@ insert into accounts (
     ?5,
     ?6
 )
-returning id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+returning id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 `
 
 type CreateAccountParams struct {
@@ -55,6 +55,8 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.InstitutionName,
 		&i.ExternalAccountNumber,
 		&i.AccountType,
+		&i.Margin,
+		&i.Benchmark,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -72,7 +74,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id string) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-select id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+select id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 from accounts
 where id = ?1
 `
@@ -87,6 +89,8 @@ func (q *Queries) GetAccount(ctx context.Context, id string) (Account, error) {
 		&i.InstitutionName,
 		&i.ExternalAccountNumber,
 		&i.AccountType,
+		&i.Margin,
+		&i.Benchmark,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -94,7 +98,7 @@ func (q *Queries) GetAccount(ctx context.Context, id string) (Account, error) {
 }
 
 const getAccountByExternalNumber = `-- name: GetAccountByExternalNumber :one
-select id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+select id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 from accounts
 where
     institution_name = ?1
@@ -116,6 +120,8 @@ func (q *Queries) GetAccountByExternalNumber(ctx context.Context, arg GetAccount
 		&i.InstitutionName,
 		&i.ExternalAccountNumber,
 		&i.AccountType,
+		&i.Margin,
+		&i.Benchmark,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -123,7 +129,7 @@ func (q *Queries) GetAccountByExternalNumber(ctx context.Context, arg GetAccount
 }
 
 const getAccountByName = `-- name: GetAccountByName :one
-select id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+select id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 from accounts
 where name = ?1
 `
@@ -138,6 +144,8 @@ func (q *Queries) GetAccountByName(ctx context.Context, name string) (Account, e
 		&i.InstitutionName,
 		&i.ExternalAccountNumber,
 		&i.AccountType,
+		&i.Margin,
+		&i.Benchmark,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -145,7 +153,7 @@ func (q *Queries) GetAccountByName(ctx context.Context, name string) (Account, e
 }
 
 const listAccounts = `-- name: ListAccounts :many
-select id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+select id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 from accounts
 order by name
 `
@@ -166,6 +174,8 @@ func (q *Queries) ListAccounts(ctx context.Context) ([]Account, error) {
 			&i.InstitutionName,
 			&i.ExternalAccountNumber,
 			&i.AccountType,
+			&i.Margin,
+			&i.Benchmark,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -182,6 +192,42 @@ func (q *Queries) ListAccounts(ctx context.Context) ([]Account, error) {
 	return items, nil
 }
 
+const setAccountBenchmark = `-- name: SetAccountBenchmark :exec
+update accounts
+set
+    benchmark = ?1,
+    updated_at = datetime('now')
+where id = ?2
+`
+
+type SetAccountBenchmarkParams struct {
+	Benchmark sql.NullString `json:"benchmark"`
+	ID        string         `json:"id"`
+}
+
+func (q *Queries) SetAccountBenchmark(ctx context.Context, arg SetAccountBenchmarkParams) error {
+	_, err := q.db.ExecContext(ctx, setAccountBenchmark, arg.Benchmark, arg.ID)
+	return err
+}
+
+const setAccountMargin = `-- name: SetAccountMargin :exec
+update accounts
+set
+    margin = ?1,
+    updated_at = datetime('now')
+where id = ?2
+`
+
+type SetAccountMarginParams struct {
+	Margin bool   `json:"margin"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) SetAccountMargin(ctx context.Context, arg SetAccountMarginParams) error {
+	_, err := q.db.ExecContext(ctx, setAccountMargin, arg.Margin, arg.ID)
+	return err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 update accounts
 set
@@ -191,7 +237,7 @@ set
     account_type = coalesce(nullif(?4, ''), account_type),
     updated_at = datetime('now')
 where id = ?5
-returning id, user_id, name, institution_name, external_account_number, account_type, created_at, updated_at
+returning id, user_id, name, institution_name, external_account_number, account_type, margin, benchmark, created_at, updated_at
 `
 
 type UpdateAccountParams struct {
@@ -218,6 +264,8 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.InstitutionName,
 		&i.ExternalAccountNumber,
 		&i.AccountType,
+		&i.Margin,
+		&i.Benchmark,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
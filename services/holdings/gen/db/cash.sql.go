@@ -19,7 +19,8 @@ insert into cash_transactions (
     cash_type,
     amount_micros,
     security_id,
-    description
+    description,
+    dividend_type
 ) values (
     ?1,
     ?2,
@@ -28,7 +29,8 @@ insert into cash_transactions (
     ?5,
     ?6,
     ?7,
-    ?8
+    ?8,
+    ?9
 )
 on conflict do nothing
 `
@@ -42,6 +44,7 @@ type CreateCashTransactionParams struct {
 	AmountMicros    int64          `json:"amount_micros"`
 	SecurityID      sql.NullString `json:"security_id"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 }
 
 func (q *Queries) CreateCashTransaction(ctx context.Context, arg CreateCashTransactionParams) error {
@@ -54,10 +57,28 @@ func (q *Queries) CreateCashTransaction(ctx context.Context, arg CreateCashTrans
 		arg.AmountMicros,
 		arg.SecurityID,
 		arg.Description,
+		arg.DividendType,
 	)
 	return err
 }
 
+const deleteCashCheckpoint = `-- name: DeleteCashCheckpoint :exec
+delete from cash_checkpoints
+where
+    account_id = ?1
+    and checkpoint_date = ?2
+`
+
+type DeleteCashCheckpointParams struct {
+	AccountID      string `json:"account_id"`
+	CheckpointDate string `json:"checkpoint_date"`
+}
+
+func (q *Queries) DeleteCashCheckpoint(ctx context.Context, arg DeleteCashCheckpointParams) error {
+	_, err := q.db.ExecContext(ctx, deleteCashCheckpoint, arg.AccountID, arg.CheckpointDate)
+	return err
+}
+
 const deleteCashTransactionsByAccount = `-- name: DeleteCashTransactionsByAccount :exec
 delete from cash_transactions
 where account_id = ?1
@@ -124,7 +145,7 @@ func (q *Queries) GetCashBalanceAsOfDate(ctx context.Context, arg GetCashBalance
 }
 
 const getOpeningCashBalance = `-- name: GetOpeningCashBalance :one
-select id, account_id, transaction_id, transaction_date, cash_type, amount_micros, security_id, description, created_at
+select id, account_id, transaction_id, transaction_date, cash_type, amount_micros, security_id, description, dividend_type, created_at
 from cash_transactions
 where
     account_id = ?1
@@ -144,14 +165,52 @@ func (q *Queries) GetOpeningCashBalance(ctx context.Context, accountID string) (
 		&i.AmountMicros,
 		&i.SecurityID,
 		&i.Description,
+		&i.DividendType,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const listCashCheckpoints = `-- name: ListCashCheckpoints :many
+select account_id, checkpoint_date, balance_micros, source, description, created_at
+from cash_checkpoints
+where account_id = ?1
+order by checkpoint_date
+`
+
+func (q *Queries) ListCashCheckpoints(ctx context.Context, accountID string) ([]CashCheckpoint, error) {
+	rows, err := q.db.QueryContext(ctx, listCashCheckpoints, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CashCheckpoint{}
+	for rows.Next() {
+		var i CashCheckpoint
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.CheckpointDate,
+			&i.BalanceMicros,
+			&i.Source,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listCashTransactions = `-- name: ListCashTransactions :many
 select
-    ct.id, ct.account_id, ct.transaction_id, ct.transaction_date, ct.cash_type, ct.amount_micros, ct.security_id, ct.description, ct.created_at,
+    ct.id, ct.account_id, ct.transaction_id, ct.transaction_date, ct.cash_type, ct.amount_micros, ct.security_id, ct.description, ct.dividend_type, ct.created_at,
     s.symbol,
     s.name as security_name
 from cash_transactions ct
@@ -169,6 +228,7 @@ type ListCashTransactionsRow struct {
 	AmountMicros    int64          `json:"amount_micros"`
 	SecurityID      sql.NullString `json:"security_id"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
 	Symbol          sql.NullString `json:"symbol"`
 	SecurityName    sql.NullString `json:"security_name"`
@@ -192,6 +252,7 @@ func (q *Queries) ListCashTransactions(ctx context.Context, accountID string) ([
 			&i.AmountMicros,
 			&i.SecurityID,
 			&i.Description,
+			&i.DividendType,
 			&i.CreatedAt,
 			&i.Symbol,
 			&i.SecurityName,
@@ -211,7 +272,7 @@ func (q *Queries) ListCashTransactions(ctx context.Context, accountID string) ([
 
 const listCashTransactionsByDateRange = `-- name: ListCashTransactionsByDateRange :many
 select
-    ct.id, ct.account_id, ct.transaction_id, ct.transaction_date, ct.cash_type, ct.amount_micros, ct.security_id, ct.description, ct.created_at,
+    ct.id, ct.account_id, ct.transaction_id, ct.transaction_date, ct.cash_type, ct.amount_micros, ct.security_id, ct.description, ct.dividend_type, ct.created_at,
     s.symbol,
     s.name as security_name
 from cash_transactions ct
@@ -238,6 +299,7 @@ type ListCashTransactionsByDateRangeRow struct {
 	AmountMicros    int64          `json:"amount_micros"`
 	SecurityID      sql.NullString `json:"security_id"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
 	Symbol          sql.NullString `json:"symbol"`
 	SecurityName    sql.NullString `json:"security_name"`
@@ -261,6 +323,7 @@ func (q *Queries) ListCashTransactionsByDateRange(ctx context.Context, arg ListC
 			&i.AmountMicros,
 			&i.SecurityID,
 			&i.Description,
+			&i.DividendType,
 			&i.CreatedAt,
 			&i.Symbol,
 			&i.SecurityName,
@@ -277,3 +340,223 @@ func (q *Queries) ListCashTransactionsByDateRange(ctx context.Context, arg ListC
 	}
 	return items, nil
 }
+
+const listDailyCashChanges = `-- name: ListDailyCashChanges :many
+select
+    account_id,
+    transaction_date,
+    cash_type,
+    cast(sum(amount_micros) as integer) as amount_micros
+from cash_transactions
+group by account_id, transaction_date, cash_type
+order by transaction_date
+`
+
+type ListDailyCashChangesRow struct {
+	AccountID       string `json:"account_id"`
+	TransactionDate string `json:"transaction_date"`
+	CashType        string `json:"cash_type"`
+	AmountMicros    int64  `json:"amount_micros"`
+}
+
+func (q *Queries) ListDailyCashChanges(ctx context.Context) ([]ListDailyCashChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDailyCashChanges)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDailyCashChangesRow{}
+	for rows.Next() {
+		var i ListDailyCashChangesRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.TransactionDate,
+			&i.CashType,
+			&i.AmountMicros,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIncomeActivity = `-- name: ListIncomeActivity :many
+select
+    ct.account_id,
+    a.name as account_name,
+    ct.transaction_date,
+    ct.cash_type,
+    ct.amount_micros,
+    ct.security_id,
+    s.symbol,
+    s.name as security_name,
+    b.coupon_rate,
+    ct.description,
+    ct.dividend_type
+from cash_transactions ct
+join accounts a on a.id = ct.account_id
+left join securities s on s.id = ct.security_id
+left join bonds b on b.security_id = ct.security_id
+where
+    ct.cash_type in ('dividend', 'interest', 'cap_gain', 'return_of_capital', 'fee', 'transfer_out', 'withdrawal')
+    and ct.transaction_date >= ?1
+    and ct.transaction_date <= ?2
+order by ct.transaction_date, a.name
+`
+
+type ListIncomeActivityParams struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+type ListIncomeActivityRow struct {
+	AccountID       string          `json:"account_id"`
+	AccountName     string          `json:"account_name"`
+	TransactionDate string          `json:"transaction_date"`
+	CashType        string          `json:"cash_type"`
+	AmountMicros    int64           `json:"amount_micros"`
+	SecurityID      sql.NullString  `json:"security_id"`
+	Symbol          sql.NullString  `json:"symbol"`
+	SecurityName    sql.NullString  `json:"security_name"`
+	CouponRate      sql.NullFloat64 `json:"coupon_rate"`
+	Description     sql.NullString  `json:"description"`
+	DividendType    sql.NullString  `json:"dividend_type"`
+}
+
+func (q *Queries) ListIncomeActivity(ctx context.Context, arg ListIncomeActivityParams) ([]ListIncomeActivityRow, error) {
+	rows, err := q.db.QueryContext(ctx, listIncomeActivity, arg.StartDate, arg.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListIncomeActivityRow{}
+	for rows.Next() {
+		var i ListIncomeActivityRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.AccountName,
+			&i.TransactionDate,
+			&i.CashType,
+			&i.AmountMicros,
+			&i.SecurityID,
+			&i.Symbol,
+			&i.SecurityName,
+			&i.CouponRate,
+			&i.Description,
+			&i.DividendType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSecurityIncome = `-- name: ListSecurityIncome :many
+select
+    cast(security_id as text) as security_id,
+    transaction_date,
+    cash_type,
+    amount_micros
+from cash_transactions
+where
+    account_id = ?1
+    and security_id is not null
+    and cash_type in ('dividend', 'interest', 'cap_gain', 'return_of_capital')
+    and transaction_date > ?2
+    and transaction_date <= ?3
+order by transaction_date
+`
+
+type ListSecurityIncomeParams struct {
+	AccountID string `json:"account_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+type ListSecurityIncomeRow struct {
+	SecurityID      string `json:"security_id"`
+	TransactionDate string `json:"transaction_date"`
+	CashType        string `json:"cash_type"`
+	AmountMicros    int64  `json:"amount_micros"`
+}
+
+func (q *Queries) ListSecurityIncome(ctx context.Context, arg ListSecurityIncomeParams) ([]ListSecurityIncomeRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSecurityIncome, arg.AccountID, arg.StartDate, arg.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSecurityIncomeRow{}
+	for rows.Next() {
+		var i ListSecurityIncomeRow
+		if err := rows.Scan(
+			&i.SecurityID,
+			&i.TransactionDate,
+			&i.CashType,
+			&i.AmountMicros,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertCashCheckpoint = `-- name: UpsertCashCheckpoint :exec
+insert into cash_checkpoints (
+    account_id,
+    checkpoint_date,
+    balance_micros,
+    source,
+    description
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5
+)
+on conflict (account_id, checkpoint_date) do update set
+    balance_micros = excluded.balance_micros,
+    source = excluded.source,
+    description = excluded.description
+`
+
+type UpsertCashCheckpointParams struct {
+	AccountID      string         `json:"account_id"`
+	CheckpointDate string         `json:"checkpoint_date"`
+	BalanceMicros  int64          `json:"balance_micros"`
+	Source         string         `json:"source"`
+	Description    sql.NullString `json:"description"`
+}
+
+func (q *Queries) UpsertCashCheckpoint(ctx context.Context, arg UpsertCashCheckpointParams) error {
+	_, err := q.db.ExecContext(ctx, upsertCashCheckpoint,
+		arg.AccountID,
+		arg.CheckpointDate,
+		arg.BalanceMicros,
+		arg.Source,
+		arg.Description,
+	)
+	return err
+}
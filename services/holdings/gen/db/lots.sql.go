@@ -19,7 +19,16 @@ insert into lots (
     acquired_date,
     quantity_micros,
     remaining_micros,
-    cost_basis_micros
+    cost_basis_micros,
+    source_lot_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate,
+    position_side
 ) values (
     ?1,
     ?2,
@@ -28,20 +37,38 @@ insert into lots (
     ?5,
     ?6,
     ?7,
-    ?8
+    ?8,
+    ?9,
+    ?10,
+    ?11,
+    ?12,
+    ?13,
+    ?14,
+    ?15,
+    ?16,
+    ?17
 )
-returning id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, created_at
+returning id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, source_lot_id, acquisition_kind, fmv_micros, donor_acquired_date, plan_type, grant_date, grant_fmv_micros, discount_rate, position_side, created_at
 `
 
 type CreateLotParams struct {
-	ID              string `json:"id"`
-	AccountID       string `json:"account_id"`
-	SecurityID      string `json:"security_id"`
-	TransactionID   string `json:"transaction_id"`
-	AcquiredDate    string `json:"acquired_date"`
-	QuantityMicros  int64  `json:"quantity_micros"`
-	RemainingMicros int64  `json:"remaining_micros"`
-	CostBasisMicros int64  `json:"cost_basis_micros"`
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        string          `json:"security_id"`
+	TransactionID     string          `json:"transaction_id"`
+	AcquiredDate      string          `json:"acquired_date"`
+	QuantityMicros    int64           `json:"quantity_micros"`
+	RemainingMicros   int64           `json:"remaining_micros"`
+	CostBasisMicros   int64           `json:"cost_basis_micros"`
+	SourceLotID       sql.NullString  `json:"source_lot_id"`
+	AcquisitionKind   string          `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	PositionSide      string          `json:"position_side"`
 }
 
 func (q *Queries) CreateLot(ctx context.Context, arg CreateLotParams) (Lot, error) {
@@ -54,6 +81,15 @@ func (q *Queries) CreateLot(ctx context.Context, arg CreateLotParams) (Lot, erro
 		arg.QuantityMicros,
 		arg.RemainingMicros,
 		arg.CostBasisMicros,
+		arg.SourceLotID,
+		arg.AcquisitionKind,
+		arg.FmvMicros,
+		arg.DonorAcquiredDate,
+		arg.PlanType,
+		arg.GrantDate,
+		arg.GrantFmvMicros,
+		arg.DiscountRate,
+		arg.PositionSide,
 	)
 	var i Lot
 	err := row.Scan(
@@ -65,6 +101,95 @@ func (q *Queries) CreateLot(ctx context.Context, arg CreateLotParams) (Lot, erro
 		&i.QuantityMicros,
 		&i.RemainingMicros,
 		&i.CostBasisMicros,
+		&i.SourceLotID,
+		&i.AcquisitionKind,
+		&i.FmvMicros,
+		&i.DonorAcquiredDate,
+		&i.PlanType,
+		&i.GrantDate,
+		&i.GrantFmvMicros,
+		&i.DiscountRate,
+		&i.PositionSide,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLotAdjustment = `-- name: CreateLotAdjustment :one
+insert into lot_adjustments (
+    id,
+    account_id,
+    security_id,
+    lot_id,
+    transaction_id,
+    adjustment_type,
+    effective_date,
+    amount_micros,
+    basis_before_micros,
+    basis_after_micros,
+    gain_micros,
+    reason
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9,
+    ?10,
+    ?11,
+    ?12
+)
+returning id, account_id, security_id, lot_id, transaction_id, adjustment_type, effective_date, amount_micros, basis_before_micros, basis_after_micros, gain_micros, reason, created_at
+`
+
+type CreateLotAdjustmentParams struct {
+	ID                string         `json:"id"`
+	AccountID         string         `json:"account_id"`
+	SecurityID        string         `json:"security_id"`
+	LotID             string         `json:"lot_id"`
+	TransactionID     sql.NullString `json:"transaction_id"`
+	AdjustmentType    string         `json:"adjustment_type"`
+	EffectiveDate     string         `json:"effective_date"`
+	AmountMicros      int64          `json:"amount_micros"`
+	BasisBeforeMicros int64          `json:"basis_before_micros"`
+	BasisAfterMicros  int64          `json:"basis_after_micros"`
+	GainMicros        int64          `json:"gain_micros"`
+	Reason            string         `json:"reason"`
+}
+
+func (q *Queries) CreateLotAdjustment(ctx context.Context, arg CreateLotAdjustmentParams) (LotAdjustment, error) {
+	row := q.db.QueryRowContext(ctx, createLotAdjustment,
+		arg.ID,
+		arg.AccountID,
+		arg.SecurityID,
+		arg.LotID,
+		arg.TransactionID,
+		arg.AdjustmentType,
+		arg.EffectiveDate,
+		arg.AmountMicros,
+		arg.BasisBeforeMicros,
+		arg.BasisAfterMicros,
+		arg.GainMicros,
+		arg.Reason,
+	)
+	var i LotAdjustment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.SecurityID,
+		&i.LotID,
+		&i.TransactionID,
+		&i.AdjustmentType,
+		&i.EffectiveDate,
+		&i.AmountMicros,
+		&i.BasisBeforeMicros,
+		&i.BasisAfterMicros,
+		&i.GainMicros,
+		&i.Reason,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -79,8 +204,11 @@ insert into lot_dispositions (
     quantity_micros,
     cost_basis_micros,
     proceeds_micros,
+    fees_micros,
     realized_gain_micros,
-    holding_period
+    holding_period,
+    compensation_income_micros,
+    espp_disposition
 ) values (
     ?1,
     ?2,
@@ -90,21 +218,27 @@ insert into lot_dispositions (
     ?6,
     ?7,
     ?8,
-    ?9
+    ?9,
+    ?10,
+    ?11,
+    ?12
 )
-returning id, lot_id, sell_transaction_id, disposed_date, quantity_micros, cost_basis_micros, proceeds_micros, realized_gain_micros, holding_period, created_at
+returning id, lot_id, sell_transaction_id, disposed_date, quantity_micros, cost_basis_micros, proceeds_micros, fees_micros, realized_gain_micros, holding_period, compensation_income_micros, espp_disposition, created_at
 `
 
 type CreateLotDispositionParams struct {
-	ID                 string `json:"id"`
-	LotID              string `json:"lot_id"`
-	SellTransactionID  string `json:"sell_transaction_id"`
-	DisposedDate       string `json:"disposed_date"`
-	QuantityMicros     int64  `json:"quantity_micros"`
-	CostBasisMicros    int64  `json:"cost_basis_micros"`
-	ProceedsMicros     int64  `json:"proceeds_micros"`
-	RealizedGainMicros int64  `json:"realized_gain_micros"`
-	HoldingPeriod      string `json:"holding_period"`
+	ID                       string         `json:"id"`
+	LotID                    string         `json:"lot_id"`
+	SellTransactionID        string         `json:"sell_transaction_id"`
+	DisposedDate             string         `json:"disposed_date"`
+	QuantityMicros           int64          `json:"quantity_micros"`
+	CostBasisMicros          int64          `json:"cost_basis_micros"`
+	ProceedsMicros           int64          `json:"proceeds_micros"`
+	FeesMicros               int64          `json:"fees_micros"`
+	RealizedGainMicros       int64          `json:"realized_gain_micros"`
+	HoldingPeriod            string         `json:"holding_period"`
+	CompensationIncomeMicros int64          `json:"compensation_income_micros"`
+	EsppDisposition          sql.NullString `json:"espp_disposition"`
 }
 
 func (q *Queries) CreateLotDisposition(ctx context.Context, arg CreateLotDispositionParams) (LotDisposition, error) {
@@ -116,8 +250,11 @@ func (q *Queries) CreateLotDisposition(ctx context.Context, arg CreateLotDisposi
 		arg.QuantityMicros,
 		arg.CostBasisMicros,
 		arg.ProceedsMicros,
+		arg.FeesMicros,
 		arg.RealizedGainMicros,
 		arg.HoldingPeriod,
+		arg.CompensationIncomeMicros,
+		arg.EsppDisposition,
 	)
 	var i LotDisposition
 	err := row.Scan(
@@ -128,13 +265,198 @@ func (q *Queries) CreateLotDisposition(ctx context.Context, arg CreateLotDisposi
 		&i.QuantityMicros,
 		&i.CostBasisMicros,
 		&i.ProceedsMicros,
+		&i.FeesMicros,
 		&i.RealizedGainMicros,
 		&i.HoldingPeriod,
+		&i.CompensationIncomeMicros,
+		&i.EsppDisposition,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLotJournalEntry = `-- name: CreateLotJournalEntry :exec
+insert into lot_journal (
+    account_id,
+    security_id,
+    sort_key,
+    transaction_id,
+    digest
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5
+)
+`
+
+type CreateLotJournalEntryParams struct {
+	AccountID     string `json:"account_id"`
+	SecurityID    string `json:"security_id"`
+	SortKey       string `json:"sort_key"`
+	TransactionID string `json:"transaction_id"`
+	Digest        string `json:"digest"`
+}
+
+func (q *Queries) CreateLotJournalEntry(ctx context.Context, arg CreateLotJournalEntryParams) error {
+	_, err := q.db.ExecContext(ctx, createLotJournalEntry,
+		arg.AccountID,
+		arg.SecurityID,
+		arg.SortKey,
+		arg.TransactionID,
+		arg.Digest,
+	)
+	return err
+}
+
+const createLotTransfer = `-- name: CreateLotTransfer :one
+insert into lot_transfers (
+    id,
+    lot_id,
+    transaction_id,
+    transfer_date,
+    quantity_micros,
+    destination_lot_id
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+returning id, lot_id, transaction_id, transfer_date, quantity_micros, destination_lot_id, created_at
+`
+
+type CreateLotTransferParams struct {
+	ID               string         `json:"id"`
+	LotID            string         `json:"lot_id"`
+	TransactionID    string         `json:"transaction_id"`
+	TransferDate     string         `json:"transfer_date"`
+	QuantityMicros   int64          `json:"quantity_micros"`
+	DestinationLotID sql.NullString `json:"destination_lot_id"`
+}
+
+func (q *Queries) CreateLotTransfer(ctx context.Context, arg CreateLotTransferParams) (LotTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createLotTransfer,
+		arg.ID,
+		arg.LotID,
+		arg.TransactionID,
+		arg.TransferDate,
+		arg.QuantityMicros,
+		arg.DestinationLotID,
+	)
+	var i LotTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.LotID,
+		&i.TransactionID,
+		&i.TransferDate,
+		&i.QuantityMicros,
+		&i.DestinationLotID,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const deleteLotAdjustmentsFrom = `-- name: DeleteLotAdjustmentsFrom :exec
+delete from lot_adjustments
+where
+    account_id = ?1
+    and security_id = ?2
+    and transaction_id is not null
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = ?1 and security_id = ?2 and sort_key < ?3
+    )
+`
+
+type DeleteLotAdjustmentsFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) DeleteLotAdjustmentsFrom(ctx context.Context, arg DeleteLotAdjustmentsFromParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLotAdjustmentsFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
+const deleteLotDispositionsFrom = `-- name: DeleteLotDispositionsFrom :exec
+delete from lot_dispositions
+where
+    lot_id in (select id from lots where account_id = ?1 and security_id = ?2)
+    and sell_transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = ?1 and security_id = ?2 and sort_key < ?3
+    )
+`
+
+type DeleteLotDispositionsFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) DeleteLotDispositionsFrom(ctx context.Context, arg DeleteLotDispositionsFromParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLotDispositionsFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
+const deleteLotJournalByAccount = `-- name: DeleteLotJournalByAccount :exec
+delete from lot_journal
+where account_id = ?1
+`
+
+func (q *Queries) DeleteLotJournalByAccount(ctx context.Context, accountID string) error {
+	_, err := q.db.ExecContext(ctx, deleteLotJournalByAccount, accountID)
+	return err
+}
+
+const deleteLotJournalFrom = `-- name: DeleteLotJournalFrom :exec
+delete from lot_journal
+where
+    account_id = ?1
+    and security_id = ?2
+    and sort_key >= ?3
+`
+
+type DeleteLotJournalFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) DeleteLotJournalFrom(ctx context.Context, arg DeleteLotJournalFromParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLotJournalFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
+const deleteLotTransfersFrom = `-- name: DeleteLotTransfersFrom :exec
+delete from lot_transfers
+where
+    lot_id in (select id from lots where account_id = ?1 and security_id = ?2)
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = ?1 and security_id = ?2 and sort_key < ?3
+    )
+`
+
+type DeleteLotTransfersFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) DeleteLotTransfersFrom(ctx context.Context, arg DeleteLotTransfersFromParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLotTransfersFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
 const deleteLotsByAccount = `-- name: DeleteLotsByAccount :exec
 delete from lot_dispositions
 where lot_id in (select id from lots where account_id = ?1)
@@ -155,8 +477,31 @@ func (q *Queries) DeleteLotsForAccount(ctx context.Context, accountID string) er
 	return err
 }
 
+const deleteLotsFrom = `-- name: DeleteLotsFrom :exec
+delete from lots
+where
+    account_id = ?1
+    and security_id = ?2
+    and transaction_id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = ?1 and security_id = ?2 and sort_key < ?3
+    )
+`
+
+type DeleteLotsFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) DeleteLotsFrom(ctx context.Context, arg DeleteLotsFromParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLotsFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
 const getLot = `-- name: GetLot :one
-select id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, created_at
+select id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, source_lot_id, acquisition_kind, fmv_micros, donor_acquired_date, plan_type, grant_date, grant_fmv_micros, discount_rate, position_side, created_at
 from lots
 where id = ?1
 `
@@ -173,6 +518,15 @@ func (q *Queries) GetLot(ctx context.Context, id string) (Lot, error) {
 		&i.QuantityMicros,
 		&i.RemainingMicros,
 		&i.CostBasisMicros,
+		&i.SourceLotID,
+		&i.AcquisitionKind,
+		&i.FmvMicros,
+		&i.DonorAcquiredDate,
+		&i.PlanType,
+		&i.GrantDate,
+		&i.GrantFmvMicros,
+		&i.DiscountRate,
+		&i.PositionSide,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -184,10 +538,11 @@ select
     a.name as account_name,
     s.symbol,
     s.name as security_name,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -240,9 +595,81 @@ func (q *Queries) ListAllHoldings(ctx context.Context) ([]ListAllHoldingsRow, er
 	return items, nil
 }
 
+const listDispositionsByAccount = `-- name: ListDispositionsByAccount :many
+select
+    d.id, d.lot_id, d.sell_transaction_id, d.disposed_date, d.quantity_micros, d.cost_basis_micros, d.proceeds_micros, d.fees_micros, d.realized_gain_micros, d.holding_period, d.compensation_income_micros, d.espp_disposition, d.created_at,
+    l.acquired_date,
+    l.security_id,
+    s.symbol
+from lot_dispositions d
+join lots l on l.id = d.lot_id
+join securities s on s.id = l.security_id
+where l.account_id = ?1
+order by d.disposed_date asc, s.symbol asc
+`
+
+type ListDispositionsByAccountRow struct {
+	ID                       string         `json:"id"`
+	LotID                    string         `json:"lot_id"`
+	SellTransactionID        string         `json:"sell_transaction_id"`
+	DisposedDate             string         `json:"disposed_date"`
+	QuantityMicros           int64          `json:"quantity_micros"`
+	CostBasisMicros          int64          `json:"cost_basis_micros"`
+	ProceedsMicros           int64          `json:"proceeds_micros"`
+	FeesMicros               int64          `json:"fees_micros"`
+	RealizedGainMicros       int64          `json:"realized_gain_micros"`
+	HoldingPeriod            string         `json:"holding_period"`
+	CompensationIncomeMicros int64          `json:"compensation_income_micros"`
+	EsppDisposition          sql.NullString `json:"espp_disposition"`
+	CreatedAt                string         `json:"created_at"`
+	AcquiredDate             string         `json:"acquired_date"`
+	SecurityID               string         `json:"security_id"`
+	Symbol                   string         `json:"symbol"`
+}
+
+func (q *Queries) ListDispositionsByAccount(ctx context.Context, accountID string) ([]ListDispositionsByAccountRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDispositionsByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDispositionsByAccountRow{}
+	for rows.Next() {
+		var i ListDispositionsByAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LotID,
+			&i.SellTransactionID,
+			&i.DisposedDate,
+			&i.QuantityMicros,
+			&i.CostBasisMicros,
+			&i.ProceedsMicros,
+			&i.FeesMicros,
+			&i.RealizedGainMicros,
+			&i.HoldingPeriod,
+			&i.CompensationIncomeMicros,
+			&i.EsppDisposition,
+			&i.CreatedAt,
+			&i.AcquiredDate,
+			&i.SecurityID,
+			&i.Symbol,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listDispositionsBySellTransaction = `-- name: ListDispositionsBySellTransaction :many
 select
-    d.id, d.lot_id, d.sell_transaction_id, d.disposed_date, d.quantity_micros, d.cost_basis_micros, d.proceeds_micros, d.realized_gain_micros, d.holding_period, d.created_at,
+    d.id, d.lot_id, d.sell_transaction_id, d.disposed_date, d.quantity_micros, d.cost_basis_micros, d.proceeds_micros, d.fees_micros, d.realized_gain_micros, d.holding_period, d.compensation_income_micros, d.espp_disposition, d.created_at,
     l.acquired_date,
     l.security_id
 from lot_dispositions d
@@ -251,18 +678,21 @@ where d.sell_transaction_id = ?1
 `
 
 type ListDispositionsBySellTransactionRow struct {
-	ID                 string `json:"id"`
-	LotID              string `json:"lot_id"`
-	SellTransactionID  string `json:"sell_transaction_id"`
-	DisposedDate       string `json:"disposed_date"`
-	QuantityMicros     int64  `json:"quantity_micros"`
-	CostBasisMicros    int64  `json:"cost_basis_micros"`
-	ProceedsMicros     int64  `json:"proceeds_micros"`
-	RealizedGainMicros int64  `json:"realized_gain_micros"`
-	HoldingPeriod      string `json:"holding_period"`
-	CreatedAt          string `json:"created_at"`
-	AcquiredDate       string `json:"acquired_date"`
-	SecurityID         string `json:"security_id"`
+	ID                       string         `json:"id"`
+	LotID                    string         `json:"lot_id"`
+	SellTransactionID        string         `json:"sell_transaction_id"`
+	DisposedDate             string         `json:"disposed_date"`
+	QuantityMicros           int64          `json:"quantity_micros"`
+	CostBasisMicros          int64          `json:"cost_basis_micros"`
+	ProceedsMicros           int64          `json:"proceeds_micros"`
+	FeesMicros               int64          `json:"fees_micros"`
+	RealizedGainMicros       int64          `json:"realized_gain_micros"`
+	HoldingPeriod            string         `json:"holding_period"`
+	CompensationIncomeMicros int64          `json:"compensation_income_micros"`
+	EsppDisposition          sql.NullString `json:"espp_disposition"`
+	CreatedAt                string         `json:"created_at"`
+	AcquiredDate             string         `json:"acquired_date"`
+	SecurityID               string         `json:"security_id"`
 }
 
 func (q *Queries) ListDispositionsBySellTransaction(ctx context.Context, sellTransactionID string) ([]ListDispositionsBySellTransactionRow, error) {
@@ -282,8 +712,11 @@ func (q *Queries) ListDispositionsBySellTransaction(ctx context.Context, sellTra
 			&i.QuantityMicros,
 			&i.CostBasisMicros,
 			&i.ProceedsMicros,
+			&i.FeesMicros,
 			&i.RealizedGainMicros,
 			&i.HoldingPeriod,
+			&i.CompensationIncomeMicros,
+			&i.EsppDisposition,
 			&i.CreatedAt,
 			&i.AcquiredDate,
 			&i.SecurityID,
@@ -303,11 +736,15 @@ func (q *Queries) ListDispositionsBySellTransaction(ctx context.Context, sellTra
 
 const listDispositionsByYear = `-- name: ListDispositionsByYear :many
 select
-    d.id, d.lot_id, d.sell_transaction_id, d.disposed_date, d.quantity_micros, d.cost_basis_micros, d.proceeds_micros, d.realized_gain_micros, d.holding_period, d.created_at,
+    d.id, d.lot_id, d.sell_transaction_id, d.disposed_date, d.quantity_micros, d.cost_basis_micros, d.proceeds_micros, d.fees_micros, d.realized_gain_micros, d.holding_period, d.compensation_income_micros, d.espp_disposition, d.created_at,
     l.acquired_date,
     l.security_id,
+    l.account_id,
+    l.plan_type,
+    l.transaction_id as lot_transaction_id,
     s.symbol,
-    s.name as security_name
+    s.name as security_name,
+    s.security_type
 from lot_dispositions d
 join lots l on l.id = d.lot_id
 join securities s on s.id = l.security_id
@@ -317,20 +754,27 @@ order by d.disposed_date asc
 `
 
 type ListDispositionsByYearRow struct {
-	ID                 string         `json:"id"`
-	LotID              string         `json:"lot_id"`
-	SellTransactionID  string         `json:"sell_transaction_id"`
-	DisposedDate       string         `json:"disposed_date"`
-	QuantityMicros     int64          `json:"quantity_micros"`
-	CostBasisMicros    int64          `json:"cost_basis_micros"`
-	ProceedsMicros     int64          `json:"proceeds_micros"`
-	RealizedGainMicros int64          `json:"realized_gain_micros"`
-	HoldingPeriod      string         `json:"holding_period"`
-	CreatedAt          string         `json:"created_at"`
-	AcquiredDate       string         `json:"acquired_date"`
-	SecurityID         string         `json:"security_id"`
-	Symbol             string         `json:"symbol"`
-	SecurityName       sql.NullString `json:"security_name"`
+	ID                       string         `json:"id"`
+	LotID                    string         `json:"lot_id"`
+	SellTransactionID        string         `json:"sell_transaction_id"`
+	DisposedDate             string         `json:"disposed_date"`
+	QuantityMicros           int64          `json:"quantity_micros"`
+	CostBasisMicros          int64          `json:"cost_basis_micros"`
+	ProceedsMicros           int64          `json:"proceeds_micros"`
+	FeesMicros               int64          `json:"fees_micros"`
+	RealizedGainMicros       int64          `json:"realized_gain_micros"`
+	HoldingPeriod            string         `json:"holding_period"`
+	CompensationIncomeMicros int64          `json:"compensation_income_micros"`
+	EsppDisposition          sql.NullString `json:"espp_disposition"`
+	CreatedAt                string         `json:"created_at"`
+	AcquiredDate             string         `json:"acquired_date"`
+	SecurityID               string         `json:"security_id"`
+	AccountID                string         `json:"account_id"`
+	PlanType                 sql.NullString `json:"plan_type"`
+	LotTransactionID         string         `json:"lot_transaction_id"`
+	Symbol                   string         `json:"symbol"`
+	SecurityName             sql.NullString `json:"security_name"`
+	SecurityType             sql.NullString `json:"security_type"`
 }
 
 func (q *Queries) ListDispositionsByYear(ctx context.Context, year string) ([]ListDispositionsByYearRow, error) {
@@ -350,13 +794,66 @@ func (q *Queries) ListDispositionsByYear(ctx context.Context, year string) ([]Li
 			&i.QuantityMicros,
 			&i.CostBasisMicros,
 			&i.ProceedsMicros,
+			&i.FeesMicros,
 			&i.RealizedGainMicros,
 			&i.HoldingPeriod,
+			&i.CompensationIncomeMicros,
+			&i.EsppDisposition,
 			&i.CreatedAt,
 			&i.AcquiredDate,
 			&i.SecurityID,
+			&i.AccountID,
+			&i.PlanType,
+			&i.LotTransactionID,
 			&i.Symbol,
 			&i.SecurityName,
+			&i.SecurityType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnteredLotAdjustmentsByAccount = `-- name: ListEnteredLotAdjustmentsByAccount :many
+select id, account_id, security_id, lot_id, transaction_id, adjustment_type, effective_date, amount_micros, basis_before_micros, basis_after_micros, gain_micros, reason, created_at
+from lot_adjustments
+where
+    account_id = ?1
+    and transaction_id is null
+order by effective_date asc, id asc
+`
+
+func (q *Queries) ListEnteredLotAdjustmentsByAccount(ctx context.Context, accountID string) ([]LotAdjustment, error) {
+	rows, err := q.db.QueryContext(ctx, listEnteredLotAdjustmentsByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LotAdjustment{}
+	for rows.Next() {
+		var i LotAdjustment
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.LotID,
+			&i.TransactionID,
+			&i.AdjustmentType,
+			&i.EffectiveDate,
+			&i.AmountMicros,
+			&i.BasisBeforeMicros,
+			&i.BasisAfterMicros,
+			&i.GainMicros,
+			&i.Reason,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -375,10 +872,11 @@ const listHoldingsByAccount = `-- name: ListHoldingsByAccount :many
 select
     s.symbol,
     s.name as security_name,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -426,9 +924,182 @@ func (q *Queries) ListHoldingsByAccount(ctx context.Context, accountID string) (
 	return items, nil
 }
 
+const listLotAdjustmentsByAccount = `-- name: ListLotAdjustmentsByAccount :many
+select
+    a.id, a.account_id, a.security_id, a.lot_id, a.transaction_id, a.adjustment_type, a.effective_date, a.amount_micros, a.basis_before_micros, a.basis_after_micros, a.gain_micros, a.reason, a.created_at,
+    s.symbol
+from lot_adjustments a
+join securities s on s.id = a.security_id
+where a.account_id = ?1
+order by a.effective_date asc, a.created_at asc
+`
+
+type ListLotAdjustmentsByAccountRow struct {
+	ID                string         `json:"id"`
+	AccountID         string         `json:"account_id"`
+	SecurityID        string         `json:"security_id"`
+	LotID             string         `json:"lot_id"`
+	TransactionID     sql.NullString `json:"transaction_id"`
+	AdjustmentType    string         `json:"adjustment_type"`
+	EffectiveDate     string         `json:"effective_date"`
+	AmountMicros      int64          `json:"amount_micros"`
+	BasisBeforeMicros int64          `json:"basis_before_micros"`
+	BasisAfterMicros  int64          `json:"basis_after_micros"`
+	GainMicros        int64          `json:"gain_micros"`
+	Reason            string         `json:"reason"`
+	CreatedAt         string         `json:"created_at"`
+	Symbol            string         `json:"symbol"`
+}
+
+func (q *Queries) ListLotAdjustmentsByAccount(ctx context.Context, accountID string) ([]ListLotAdjustmentsByAccountRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLotAdjustmentsByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLotAdjustmentsByAccountRow{}
+	for rows.Next() {
+		var i ListLotAdjustmentsByAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.LotID,
+			&i.TransactionID,
+			&i.AdjustmentType,
+			&i.EffectiveDate,
+			&i.AmountMicros,
+			&i.BasisBeforeMicros,
+			&i.BasisAfterMicros,
+			&i.GainMicros,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.Symbol,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLotJournalByAccount = `-- name: ListLotJournalByAccount :many
+select account_id, security_id, sort_key, transaction_id, digest
+from lot_journal
+where account_id = ?1
+order by security_id asc, sort_key asc
+`
+
+func (q *Queries) ListLotJournalByAccount(ctx context.Context, accountID string) ([]LotJournal, error) {
+	rows, err := q.db.QueryContext(ctx, listLotJournalByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LotJournal{}
+	for rows.Next() {
+		var i LotJournal
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.SecurityID,
+			&i.SortKey,
+			&i.TransactionID,
+			&i.Digest,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLotQuantityChanges = `-- name: ListLotQuantityChanges :many
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    t.transaction_date as change_date,
+    t.transaction_type as change_type,
+    l.quantity_micros
+from lots l
+join transactions t on t.id = l.transaction_id
+union all
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    d.disposed_date as change_date,
+    'disposition' as change_type,
+    -d.quantity_micros as quantity_micros
+from lot_dispositions d
+join lots l on l.id = d.lot_id
+union all
+select
+    l.account_id,
+    l.security_id,
+    l.position_side,
+    x.transfer_date as change_date,
+    'transfer_out' as change_type,
+    -x.quantity_micros as quantity_micros
+from lot_transfers x
+join lots l on l.id = x.lot_id
+order by change_date
+`
+
+type ListLotQuantityChangesRow struct {
+	AccountID      string `json:"account_id"`
+	SecurityID     string `json:"security_id"`
+	PositionSide   string `json:"position_side"`
+	ChangeDate     string `json:"change_date"`
+	ChangeType     string `json:"change_type"`
+	QuantityMicros int64  `json:"quantity_micros"`
+}
+
+func (q *Queries) ListLotQuantityChanges(ctx context.Context) ([]ListLotQuantityChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLotQuantityChanges)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLotQuantityChangesRow{}
+	for rows.Next() {
+		var i ListLotQuantityChangesRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.SecurityID,
+			&i.PositionSide,
+			&i.ChangeDate,
+			&i.ChangeType,
+			&i.QuantityMicros,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listLotsByAccount = `-- name: ListLotsByAccount :many
 select
-    l.id, l.account_id, l.security_id, l.transaction_id, l.acquired_date, l.quantity_micros, l.remaining_micros, l.cost_basis_micros, l.created_at,
+    l.id, l.account_id, l.security_id, l.transaction_id, l.acquired_date, l.quantity_micros, l.remaining_micros, l.cost_basis_micros, l.source_lot_id, l.acquisition_kind, l.fmv_micros, l.donor_acquired_date, l.plan_type, l.grant_date, l.grant_fmv_micros, l.discount_rate, l.position_side, l.created_at,
     s.symbol,
     s.name as security_name
 from lots l
@@ -438,17 +1109,26 @@ order by l.acquired_date asc
 `
 
 type ListLotsByAccountRow struct {
-	ID              string         `json:"id"`
-	AccountID       string         `json:"account_id"`
-	SecurityID      string         `json:"security_id"`
-	TransactionID   string         `json:"transaction_id"`
-	AcquiredDate    string         `json:"acquired_date"`
-	QuantityMicros  int64          `json:"quantity_micros"`
-	RemainingMicros int64          `json:"remaining_micros"`
-	CostBasisMicros int64          `json:"cost_basis_micros"`
-	CreatedAt       string         `json:"created_at"`
-	Symbol          string         `json:"symbol"`
-	SecurityName    sql.NullString `json:"security_name"`
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        string          `json:"security_id"`
+	TransactionID     string          `json:"transaction_id"`
+	AcquiredDate      string          `json:"acquired_date"`
+	QuantityMicros    int64           `json:"quantity_micros"`
+	RemainingMicros   int64           `json:"remaining_micros"`
+	CostBasisMicros   int64           `json:"cost_basis_micros"`
+	SourceLotID       sql.NullString  `json:"source_lot_id"`
+	AcquisitionKind   string          `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	PositionSide      string          `json:"position_side"`
+	CreatedAt         string          `json:"created_at"`
+	Symbol            string          `json:"symbol"`
+	SecurityName      sql.NullString  `json:"security_name"`
 }
 
 func (q *Queries) ListLotsByAccount(ctx context.Context, accountID string) ([]ListLotsByAccountRow, error) {
@@ -469,6 +1149,15 @@ func (q *Queries) ListLotsByAccount(ctx context.Context, accountID string) ([]Li
 			&i.QuantityMicros,
 			&i.RemainingMicros,
 			&i.CostBasisMicros,
+			&i.SourceLotID,
+			&i.AcquisitionKind,
+			&i.FmvMicros,
+			&i.DonorAcquiredDate,
+			&i.PlanType,
+			&i.GrantDate,
+			&i.GrantFmvMicros,
+			&i.DiscountRate,
+			&i.PositionSide,
 			&i.CreatedAt,
 			&i.Symbol,
 			&i.SecurityName,
@@ -487,13 +1176,13 @@ func (q *Queries) ListLotsByAccount(ctx context.Context, accountID string) ([]Li
 }
 
 const listLotsByAccountAndSecurity = `-- name: ListLotsByAccountAndSecurity :many
-select id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, created_at
+select id, account_id, security_id, transaction_id, acquired_date, quantity_micros, remaining_micros, cost_basis_micros, source_lot_id, acquisition_kind, fmv_micros, donor_acquired_date, plan_type, grant_date, grant_fmv_micros, discount_rate, position_side, created_at
 from lots
 where
     account_id = ?1
     and security_id = ?2
     and remaining_micros > 0
-order by acquired_date asc
+order by acquired_date asc, id asc
 `
 
 type ListLotsByAccountAndSecurityParams struct {
@@ -519,6 +1208,15 @@ func (q *Queries) ListLotsByAccountAndSecurity(ctx context.Context, arg ListLots
 			&i.QuantityMicros,
 			&i.RemainingMicros,
 			&i.CostBasisMicros,
+			&i.SourceLotID,
+			&i.AcquisitionKind,
+			&i.FmvMicros,
+			&i.DonorAcquiredDate,
+			&i.PlanType,
+			&i.GrantDate,
+			&i.GrantFmvMicros,
+			&i.DiscountRate,
+			&i.PositionSide,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -534,15 +1232,244 @@ func (q *Queries) ListLotsByAccountAndSecurity(ctx context.Context, arg ListLots
 	return items, nil
 }
 
+const listOpenLots = `-- name: ListOpenLots :many
+select
+    l.id, l.account_id, l.security_id, l.transaction_id, l.acquired_date, l.quantity_micros, l.remaining_micros, l.cost_basis_micros, l.source_lot_id, l.acquisition_kind, l.fmv_micros, l.donor_acquired_date, l.plan_type, l.grant_date, l.grant_fmv_micros, l.discount_rate, l.position_side, l.created_at,
+    s.symbol,
+    s.name as security_name,
+    s.security_type,
+    a.name as account_name
+from lots l
+join securities s on s.id = l.security_id
+join accounts a on a.id = l.account_id
+where l.remaining_micros > 0
+order by s.symbol asc, l.acquired_date asc
+`
+
+type ListOpenLotsRow struct {
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        string          `json:"security_id"`
+	TransactionID     string          `json:"transaction_id"`
+	AcquiredDate      string          `json:"acquired_date"`
+	QuantityMicros    int64           `json:"quantity_micros"`
+	RemainingMicros   int64           `json:"remaining_micros"`
+	CostBasisMicros   int64           `json:"cost_basis_micros"`
+	SourceLotID       sql.NullString  `json:"source_lot_id"`
+	AcquisitionKind   string          `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	PositionSide      string          `json:"position_side"`
+	CreatedAt         string          `json:"created_at"`
+	Symbol            string          `json:"symbol"`
+	SecurityName      sql.NullString  `json:"security_name"`
+	SecurityType      sql.NullString  `json:"security_type"`
+	AccountName       string          `json:"account_name"`
+}
+
+func (q *Queries) ListOpenLots(ctx context.Context) ([]ListOpenLotsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenLots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListOpenLotsRow{}
+	for rows.Next() {
+		var i ListOpenLotsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.TransactionID,
+			&i.AcquiredDate,
+			&i.QuantityMicros,
+			&i.RemainingMicros,
+			&i.CostBasisMicros,
+			&i.SourceLotID,
+			&i.AcquisitionKind,
+			&i.FmvMicros,
+			&i.DonorAcquiredDate,
+			&i.PlanType,
+			&i.GrantDate,
+			&i.GrantFmvMicros,
+			&i.DiscountRate,
+			&i.PositionSide,
+			&i.CreatedAt,
+			&i.Symbol,
+			&i.SecurityName,
+			&i.SecurityType,
+			&i.AccountName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenLotsAsOf = `-- name: ListOpenLotsAsOf :many
+select
+    id,
+    account_id,
+    security_id,
+    transaction_id,
+    acquired_date,
+    quantity_micros,
+    remaining_micros,
+    cost_basis_micros,
+    source_lot_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate,
+    position_side,
+    created_at,
+    symbol,
+    security_name,
+    security_type,
+    account_name
+from (
+    select
+        l.id,
+        l.account_id,
+        l.security_id,
+        l.transaction_id,
+        l.acquired_date,
+        l.quantity_micros,
+        cast(
+            l.quantity_micros
+            - coalesce((select sum(d.quantity_micros) from lot_dispositions d where d.lot_id = l.id and d.disposed_date <= ?1), 0)
+            - coalesce((select sum(x.quantity_micros) from lot_transfers x where x.lot_id = l.id and x.transfer_date <= ?1), 0)
+            as integer
+        ) as remaining_micros,
+        coalesce(
+            (
+                select a.basis_before_micros
+                from lot_adjustments a
+                where a.lot_id = l.id and a.effective_date > ?1
+                order by a.effective_date asc, a.created_at asc
+                limit 1
+            ),
+            l.cost_basis_micros
+        ) as cost_basis_micros,
+        l.source_lot_id,
+        l.acquisition_kind,
+        l.fmv_micros,
+        l.donor_acquired_date,
+        l.plan_type,
+        l.grant_date,
+        l.grant_fmv_micros,
+        l.discount_rate,
+        l.position_side,
+        l.created_at,
+        s.symbol,
+        s.name as security_name,
+        s.security_type,
+        a.name as account_name
+    from lots l
+    join transactions t on t.id = l.transaction_id
+    join securities s on s.id = l.security_id
+    join accounts a on a.id = l.account_id
+    where t.transaction_date <= ?1
+)
+where remaining_micros > 0
+order by symbol asc, acquired_date asc
+`
+
+type ListOpenLotsAsOfRow struct {
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        string          `json:"security_id"`
+	TransactionID     string          `json:"transaction_id"`
+	AcquiredDate      string          `json:"acquired_date"`
+	QuantityMicros    int64           `json:"quantity_micros"`
+	RemainingMicros   int64           `json:"remaining_micros"`
+	CostBasisMicros   int64           `json:"cost_basis_micros"`
+	SourceLotID       sql.NullString  `json:"source_lot_id"`
+	AcquisitionKind   string          `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	PositionSide      string          `json:"position_side"`
+	CreatedAt         string          `json:"created_at"`
+	Symbol            string          `json:"symbol"`
+	SecurityName      sql.NullString  `json:"security_name"`
+	SecurityType      sql.NullString  `json:"security_type"`
+	AccountName       string          `json:"account_name"`
+}
+
+func (q *Queries) ListOpenLotsAsOf(ctx context.Context, asOfDate string) ([]ListOpenLotsAsOfRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenLotsAsOf, asOfDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListOpenLotsAsOfRow{}
+	for rows.Next() {
+		var i ListOpenLotsAsOfRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.TransactionID,
+			&i.AcquiredDate,
+			&i.QuantityMicros,
+			&i.RemainingMicros,
+			&i.CostBasisMicros,
+			&i.SourceLotID,
+			&i.AcquisitionKind,
+			&i.FmvMicros,
+			&i.DonorAcquiredDate,
+			&i.PlanType,
+			&i.GrantDate,
+			&i.GrantFmvMicros,
+			&i.DiscountRate,
+			&i.PositionSide,
+			&i.CreatedAt,
+			&i.Symbol,
+			&i.SecurityName,
+			&i.SecurityType,
+			&i.AccountName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPositions = `-- name: ListPositions :many
 select
     s.symbol,
     s.name as security_name,
     count(distinct a.id) as account_count,
-    sum(l.remaining_micros) as quantity_micros,
+    sum(case when l.position_side = 'short' then -l.remaining_micros else l.remaining_micros end) as quantity_micros,
     sum(
         case when l.remaining_micros > 0
         then cast(cast(l.cost_basis_micros as real) / cast(l.quantity_micros as real) * cast(l.remaining_micros as real) as integer)
+            * case when l.position_side = 'short' then -1 else 1 end
         else 0 end
     ) as cost_basis_micros,
     min(l.acquired_date) as earliest_acquired
@@ -593,6 +1520,82 @@ func (q *Queries) ListPositions(ctx context.Context) ([]ListPositionsRow, error)
 	return items, nil
 }
 
+const recomputeLotRemaining = `-- name: RecomputeLotRemaining :exec
+update lots
+set remaining_micros = quantity_micros
+    - coalesce((select sum(d.quantity_micros) from lot_dispositions d where d.lot_id = lots.id), 0)
+    - coalesce((select sum(t.quantity_micros) from lot_transfers t where t.lot_id = lots.id), 0)
+where
+    account_id = ?1
+    and security_id = ?2
+`
+
+type RecomputeLotRemainingParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+}
+
+func (q *Queries) RecomputeLotRemaining(ctx context.Context, arg RecomputeLotRemainingParams) error {
+	_, err := q.db.ExecContext(ctx, recomputeLotRemaining, arg.AccountID, arg.SecurityID)
+	return err
+}
+
+const resetLotAdjustmentsFrom = `-- name: ResetLotAdjustmentsFrom :exec
+update lot_adjustments
+set
+    basis_before_micros = 0,
+    basis_after_micros = 0
+where
+    account_id = ?1
+    and security_id = ?2
+    and transaction_id is null
+    and id not in (
+        select transaction_id
+        from lot_journal
+        where account_id = ?1 and security_id = ?2 and sort_key < ?3
+    )
+`
+
+type ResetLotAdjustmentsFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) ResetLotAdjustmentsFrom(ctx context.Context, arg ResetLotAdjustmentsFromParams) error {
+	_, err := q.db.ExecContext(ctx, resetLotAdjustmentsFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
+const restoreLotBasisFrom = `-- name: RestoreLotBasisFrom :exec
+update lots
+set cost_basis_micros = cost_basis_micros - coalesce((
+    select sum(a.basis_after_micros - a.basis_before_micros)
+    from lot_adjustments a
+    where
+        a.lot_id = lots.id
+        and coalesce(a.transaction_id, a.id) not in (
+            select transaction_id
+            from lot_journal
+            where account_id = ?1 and security_id = ?2 and sort_key < ?3
+        )
+), 0)
+where
+    account_id = ?1
+    and security_id = ?2
+`
+
+type RestoreLotBasisFromParams struct {
+	AccountID  string `json:"account_id"`
+	SecurityID string `json:"security_id"`
+	SortKey    string `json:"sort_key"`
+}
+
+func (q *Queries) RestoreLotBasisFrom(ctx context.Context, arg RestoreLotBasisFromParams) error {
+	_, err := q.db.ExecContext(ctx, restoreLotBasisFrom, arg.AccountID, arg.SecurityID, arg.SortKey)
+	return err
+}
+
 const sumRealizedGainsByYear = `-- name: SumRealizedGainsByYear :one
 select
     coalesce(sum(case when holding_period = 'short_term' then realized_gain_micros else 0 end), 0) as short_term_gains,
@@ -659,6 +1662,41 @@ func (q *Queries) SumRemainingBySymbol(ctx context.Context, accountID string) ([
 	return items, nil
 }
 
+const updateLotAdjustmentApplied = `-- name: UpdateLotAdjustmentApplied :exec
+update lot_adjustments
+set
+    basis_before_micros = ?1,
+    basis_after_micros = ?2
+where id = ?3
+`
+
+type UpdateLotAdjustmentAppliedParams struct {
+	BasisBeforeMicros int64  `json:"basis_before_micros"`
+	BasisAfterMicros  int64  `json:"basis_after_micros"`
+	ID                string `json:"id"`
+}
+
+func (q *Queries) UpdateLotAdjustmentApplied(ctx context.Context, arg UpdateLotAdjustmentAppliedParams) error {
+	_, err := q.db.ExecContext(ctx, updateLotAdjustmentApplied, arg.BasisBeforeMicros, arg.BasisAfterMicros, arg.ID)
+	return err
+}
+
+const updateLotBasis = `-- name: UpdateLotBasis :exec
+update lots
+set cost_basis_micros = ?1
+where id = ?2
+`
+
+type UpdateLotBasisParams struct {
+	CostBasisMicros int64  `json:"cost_basis_micros"`
+	ID              string `json:"id"`
+}
+
+func (q *Queries) UpdateLotBasis(ctx context.Context, arg UpdateLotBasisParams) error {
+	_, err := q.db.ExecContext(ctx, updateLotBasis, arg.CostBasisMicros, arg.ID)
+	return err
+}
+
 const updateLotRemaining = `-- name: UpdateLotRemaining :exec
 update lots
 set remaining_micros = ?1
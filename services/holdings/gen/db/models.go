@@ -15,10 +15,61 @@ type Account struct {
 	InstitutionName       string         `json:"institution_name"`
 	ExternalAccountNumber sql.NullString `json:"external_account_number"`
 	AccountType           string         `json:"account_type"`
+	Margin                bool           `json:"margin"`
+	Benchmark             sql.NullString `json:"benchmark"`
 	CreatedAt             string         `json:"created_at"`
 	UpdatedAt             string         `json:"updated_at"`
 }
 
+type Acquisition struct {
+	TransactionID        string          `json:"transaction_id"`
+	AcquisitionKind      string          `json:"acquisition_kind"`
+	FmvMicros            sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate    sql.NullString  `json:"donor_acquired_date"`
+	PlanType             sql.NullString  `json:"plan_type"`
+	SharesWithheldMicros sql.NullInt64   `json:"shares_withheld_micros"`
+	GrantDate            sql.NullString  `json:"grant_date"`
+	GrantFmvMicros       sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate         sql.NullFloat64 `json:"discount_rate"`
+	CreatedAt            string          `json:"created_at"`
+}
+
+type Benchmark struct {
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+	Rebalance   string         `json:"rebalance"`
+	CreatedAt   string         `json:"created_at"`
+}
+
+type BenchmarkComponent struct {
+	BenchmarkName string  `json:"benchmark_name"`
+	Symbol        string  `json:"symbol"`
+	Weight        float64 `json:"weight"`
+	SecurityType  string  `json:"security_type"`
+}
+
+type Bond struct {
+	SecurityID      string         `json:"security_id"`
+	CouponRate      float64        `json:"coupon_rate"`
+	MaturityDate    string         `json:"maturity_date"`
+	DatedDate       sql.NullString `json:"dated_date"`
+	FirstCouponDate sql.NullString `json:"first_coupon_date"`
+	CallDate        sql.NullString `json:"call_date"`
+	CallPriceMicros sql.NullInt64  `json:"call_price_micros"`
+	ParMicros       int64          `json:"par_micros"`
+	PaymentsPerYear int64          `json:"payments_per_year"`
+	CreatedAt       string         `json:"created_at"`
+}
+
+type CashCheckpoint struct {
+	AccountID      string         `json:"account_id"`
+	CheckpointDate string         `json:"checkpoint_date"`
+	BalanceMicros  int64          `json:"balance_micros"`
+	Source         string         `json:"source"`
+	Description    sql.NullString `json:"description"`
+	CreatedAt      string         `json:"created_at"`
+}
+
 type CashTransaction struct {
 	ID              string         `json:"id"`
 	AccountID       string         `json:"account_id"`
@@ -28,32 +79,113 @@ type CashTransaction struct {
 	AmountMicros    int64          `json:"amount_micros"`
 	SecurityID      sql.NullString `json:"security_id"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
 }
 
+type CorporateAction struct {
+	SecurityID   string          `json:"security_id"`
+	ActionDate   string          `json:"action_date"`
+	ActionType   string          `json:"action_type"`
+	Ratio        sql.NullFloat64 `json:"ratio"`
+	AmountMicros sql.NullInt64   `json:"amount_micros"`
+	Source       string          `json:"source"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+type ImportDiagnostic struct {
+	ID           string         `json:"id"`
+	AccountID    string         `json:"account_id"`
+	SourceFile   string         `json:"source_file"`
+	LineNumber   int64          `json:"line_number"`
+	ActivityDate string         `json:"activity_date"`
+	Activity     string         `json:"activity"`
+	Symbol       sql.NullString `json:"symbol"`
+	AmountMicros int64          `json:"amount_micros"`
+	Description  sql.NullString `json:"description"`
+	Reason       string         `json:"reason"`
+	CreatedAt    string         `json:"created_at"`
+}
+
 type Lot struct {
-	ID              string `json:"id"`
-	AccountID       string `json:"account_id"`
-	SecurityID      string `json:"security_id"`
-	TransactionID   string `json:"transaction_id"`
-	AcquiredDate    string `json:"acquired_date"`
-	QuantityMicros  int64  `json:"quantity_micros"`
-	RemainingMicros int64  `json:"remaining_micros"`
-	CostBasisMicros int64  `json:"cost_basis_micros"`
-	CreatedAt       string `json:"created_at"`
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        string          `json:"security_id"`
+	TransactionID     string          `json:"transaction_id"`
+	AcquiredDate      string          `json:"acquired_date"`
+	QuantityMicros    int64           `json:"quantity_micros"`
+	RemainingMicros   int64           `json:"remaining_micros"`
+	CostBasisMicros   int64           `json:"cost_basis_micros"`
+	SourceLotID       sql.NullString  `json:"source_lot_id"`
+	AcquisitionKind   string          `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	PositionSide      string          `json:"position_side"`
+	CreatedAt         string          `json:"created_at"`
+}
+
+type LotAdjustment struct {
+	ID                string         `json:"id"`
+	AccountID         string         `json:"account_id"`
+	SecurityID        string         `json:"security_id"`
+	LotID             string         `json:"lot_id"`
+	TransactionID     sql.NullString `json:"transaction_id"`
+	AdjustmentType    string         `json:"adjustment_type"`
+	EffectiveDate     string         `json:"effective_date"`
+	AmountMicros      int64          `json:"amount_micros"`
+	BasisBeforeMicros int64          `json:"basis_before_micros"`
+	BasisAfterMicros  int64          `json:"basis_after_micros"`
+	GainMicros        int64          `json:"gain_micros"`
+	Reason            string         `json:"reason"`
+	CreatedAt         string         `json:"created_at"`
 }
 
 type LotDisposition struct {
-	ID                 string `json:"id"`
-	LotID              string `json:"lot_id"`
-	SellTransactionID  string `json:"sell_transaction_id"`
-	DisposedDate       string `json:"disposed_date"`
-	QuantityMicros     int64  `json:"quantity_micros"`
-	CostBasisMicros    int64  `json:"cost_basis_micros"`
-	ProceedsMicros     int64  `json:"proceeds_micros"`
-	RealizedGainMicros int64  `json:"realized_gain_micros"`
-	HoldingPeriod      string `json:"holding_period"`
-	CreatedAt          string `json:"created_at"`
+	ID                       string         `json:"id"`
+	LotID                    string         `json:"lot_id"`
+	SellTransactionID        string         `json:"sell_transaction_id"`
+	DisposedDate             string         `json:"disposed_date"`
+	QuantityMicros           int64          `json:"quantity_micros"`
+	CostBasisMicros          int64          `json:"cost_basis_micros"`
+	ProceedsMicros           int64          `json:"proceeds_micros"`
+	FeesMicros               int64          `json:"fees_micros"`
+	RealizedGainMicros       int64          `json:"realized_gain_micros"`
+	HoldingPeriod            string         `json:"holding_period"`
+	CompensationIncomeMicros int64          `json:"compensation_income_micros"`
+	EsppDisposition          sql.NullString `json:"espp_disposition"`
+	CreatedAt                string         `json:"created_at"`
+}
+
+type LotJournal struct {
+	AccountID     string `json:"account_id"`
+	SecurityID    string `json:"security_id"`
+	SortKey       string `json:"sort_key"`
+	TransactionID string `json:"transaction_id"`
+	Digest        string `json:"digest"`
+}
+
+type LotTransfer struct {
+	ID               string         `json:"id"`
+	LotID            string         `json:"lot_id"`
+	TransactionID    string         `json:"transaction_id"`
+	TransferDate     string         `json:"transfer_date"`
+	QuantityMicros   int64          `json:"quantity_micros"`
+	DestinationLotID sql.NullString `json:"destination_lot_id"`
+	CreatedAt        string         `json:"created_at"`
+}
+
+type Option struct {
+	SecurityID       string `json:"security_id"`
+	UnderlyingSymbol string `json:"underlying_symbol"`
+	ExpirationDate   string `json:"expiration_date"`
+	OptionType       string `json:"option_type"`
+	StrikeMicros     int64  `json:"strike_micros"`
+	Multiplier       int64  `json:"multiplier"`
+	CreatedAt        string `json:"created_at"`
 }
 
 type Position struct {
@@ -68,6 +200,15 @@ type Position struct {
 	UpdatedAt         string        `json:"updated_at"`
 }
 
+type Price struct {
+	SecurityID  string `json:"security_id"`
+	PriceDate   string `json:"price_date"`
+	CloseMicros int64  `json:"close_micros"`
+	Source      string `json:"source"`
+	Filled      bool   `json:"filled"`
+	CreatedAt   string `json:"created_at"`
+}
+
 type Security struct {
 	ID           string         `json:"id"`
 	Symbol       string         `json:"symbol"`
@@ -88,6 +229,19 @@ type Transaction struct {
 	PriceMicros     sql.NullInt64  `json:"price_micros"`
 	AmountMicros    int64          `json:"amount_micros"`
 	FeesMicros      sql.NullInt64  `json:"fees_micros"`
+	FeesInAmount    bool           `json:"fees_in_amount"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
 }
+
+type Valuation struct {
+	AccountID             string `json:"account_id"`
+	ValuationDate         string `json:"valuation_date"`
+	SecuritiesValueMicros int64  `json:"securities_value_micros"`
+	CashMicros            int64  `json:"cash_micros"`
+	MarketValueMicros     int64  `json:"market_value_micros"`
+	UnpricedCount         int64  `json:"unpriced_count"`
+	NetFlowMicros         int64  `json:"net_flow_micros"`
+	CreatedAt             string `json:"created_at"`
+}
@@ -20,6 +20,26 @@ func (q *Queries) DeletePosition(ctx context.Context, id string) error {
 	return err
 }
 
+const getLatestPositionDate = `-- name: GetLatestPositionDate :one
+select as_of_date
+from positions
+where account_id = ?1 and as_of_date <= ?2
+order by as_of_date desc
+limit 1
+`
+
+type GetLatestPositionDateParams struct {
+	AccountID string `json:"account_id"`
+	AsOfDate  string `json:"as_of_date"`
+}
+
+func (q *Queries) GetLatestPositionDate(ctx context.Context, arg GetLatestPositionDateParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLatestPositionDate, arg.AccountID, arg.AsOfDate)
+	var as_of_date string
+	err := row.Scan(&as_of_date)
+	return as_of_date, err
+}
+
 const getPosition = `-- name: GetPosition :one
 select id, account_id, security_id, quantity_micros, cost_basis_micros, market_value_micros, as_of_date, created_at, updated_at
 from positions
@@ -52,6 +52,173 @@ func (q *Queries) GetSecurityBySymbol(ctx context.Context, symbol string) (Secur
 	return i, err
 }
 
+const listBonds = `-- name: ListBonds :many
+select
+    b.security_id, b.coupon_rate, b.maturity_date, b.dated_date, b.first_coupon_date, b.call_date, b.call_price_micros, b.par_micros, b.payments_per_year, b.created_at,
+    s.symbol
+from bonds b
+join securities s on s.id = b.security_id
+order by s.symbol asc
+`
+
+type ListBondsRow struct {
+	SecurityID      string         `json:"security_id"`
+	CouponRate      float64        `json:"coupon_rate"`
+	MaturityDate    string         `json:"maturity_date"`
+	DatedDate       sql.NullString `json:"dated_date"`
+	FirstCouponDate sql.NullString `json:"first_coupon_date"`
+	CallDate        sql.NullString `json:"call_date"`
+	CallPriceMicros sql.NullInt64  `json:"call_price_micros"`
+	ParMicros       int64          `json:"par_micros"`
+	PaymentsPerYear int64          `json:"payments_per_year"`
+	CreatedAt       string         `json:"created_at"`
+	Symbol          string         `json:"symbol"`
+}
+
+func (q *Queries) ListBonds(ctx context.Context) ([]ListBondsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBonds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBondsRow{}
+	for rows.Next() {
+		var i ListBondsRow
+		if err := rows.Scan(
+			&i.SecurityID,
+			&i.CouponRate,
+			&i.MaturityDate,
+			&i.DatedDate,
+			&i.FirstCouponDate,
+			&i.CallDate,
+			&i.CallPriceMicros,
+			&i.ParMicros,
+			&i.PaymentsPerYear,
+			&i.CreatedAt,
+			&i.Symbol,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHeldSecurities = `-- name: ListHeldSecurities :many
+select
+    s.id, s.symbol, s.name, s.security_type, s.cusip, s.created_at, s.updated_at,
+    cast(min(t.transaction_date) as text) as first_transaction_date
+from securities s
+join transactions t on t.security_id = s.id
+group by s.id
+order by s.symbol
+`
+
+type ListHeldSecuritiesRow struct {
+	ID                   string         `json:"id"`
+	Symbol               string         `json:"symbol"`
+	Name                 sql.NullString `json:"name"`
+	SecurityType         sql.NullString `json:"security_type"`
+	Cusip                sql.NullString `json:"cusip"`
+	CreatedAt            string         `json:"created_at"`
+	UpdatedAt            string         `json:"updated_at"`
+	FirstTransactionDate string         `json:"first_transaction_date"`
+}
+
+func (q *Queries) ListHeldSecurities(ctx context.Context) ([]ListHeldSecuritiesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listHeldSecurities)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListHeldSecuritiesRow{}
+	for rows.Next() {
+		var i ListHeldSecuritiesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Symbol,
+			&i.Name,
+			&i.SecurityType,
+			&i.Cusip,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FirstTransactionDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOptions = `-- name: ListOptions :many
+select
+    o.security_id, o.underlying_symbol, o.expiration_date, o.option_type, o.strike_micros, o.multiplier, o.created_at,
+    s.symbol,
+    u.id as underlying_security_id
+from options o
+join securities s on s.id = o.security_id
+left join securities u on u.symbol = o.underlying_symbol
+order by s.symbol asc
+`
+
+type ListOptionsRow struct {
+	SecurityID           string         `json:"security_id"`
+	UnderlyingSymbol     string         `json:"underlying_symbol"`
+	ExpirationDate       string         `json:"expiration_date"`
+	OptionType           string         `json:"option_type"`
+	StrikeMicros         int64          `json:"strike_micros"`
+	Multiplier           int64          `json:"multiplier"`
+	CreatedAt            string         `json:"created_at"`
+	Symbol               string         `json:"symbol"`
+	UnderlyingSecurityID sql.NullString `json:"underlying_security_id"`
+}
+
+func (q *Queries) ListOptions(ctx context.Context) ([]ListOptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListOptionsRow{}
+	for rows.Next() {
+		var i ListOptionsRow
+		if err := rows.Scan(
+			&i.SecurityID,
+			&i.UnderlyingSymbol,
+			&i.ExpirationDate,
+			&i.OptionType,
+			&i.StrikeMicros,
+			&i.Multiplier,
+			&i.CreatedAt,
+			&i.Symbol,
+			&i.UnderlyingSecurityID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSecurities = `-- name: ListSecurities :many
 select id, symbol, name, security_type, cusip, created_at, updated_at
 from securities
@@ -89,6 +256,111 @@ func (q *Queries) ListSecurities(ctx context.Context) ([]Security, error) {
 	return items, nil
 }
 
+const upsertBond = `-- name: UpsertBond :exec
+insert into bonds (
+    security_id,
+    coupon_rate,
+    maturity_date,
+    dated_date,
+    first_coupon_date,
+    call_date,
+    call_price_micros,
+    par_micros,
+    payments_per_year
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9
+)
+on conflict (security_id) do update set
+    coupon_rate = excluded.coupon_rate,
+    maturity_date = excluded.maturity_date,
+    dated_date = excluded.dated_date,
+    first_coupon_date = excluded.first_coupon_date,
+    call_date = excluded.call_date,
+    call_price_micros = excluded.call_price_micros,
+    par_micros = excluded.par_micros,
+    payments_per_year = excluded.payments_per_year
+`
+
+type UpsertBondParams struct {
+	SecurityID      string         `json:"security_id"`
+	CouponRate      float64        `json:"coupon_rate"`
+	MaturityDate    string         `json:"maturity_date"`
+	DatedDate       sql.NullString `json:"dated_date"`
+	FirstCouponDate sql.NullString `json:"first_coupon_date"`
+	CallDate        sql.NullString `json:"call_date"`
+	CallPriceMicros sql.NullInt64  `json:"call_price_micros"`
+	ParMicros       int64          `json:"par_micros"`
+	PaymentsPerYear int64          `json:"payments_per_year"`
+}
+
+func (q *Queries) UpsertBond(ctx context.Context, arg UpsertBondParams) error {
+	_, err := q.db.ExecContext(ctx, upsertBond,
+		arg.SecurityID,
+		arg.CouponRate,
+		arg.MaturityDate,
+		arg.DatedDate,
+		arg.FirstCouponDate,
+		arg.CallDate,
+		arg.CallPriceMicros,
+		arg.ParMicros,
+		arg.PaymentsPerYear,
+	)
+	return err
+}
+
+const upsertOption = `-- name: UpsertOption :exec
+insert into options (
+    security_id,
+    underlying_symbol,
+    expiration_date,
+    option_type,
+    strike_micros,
+    multiplier
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+on conflict (security_id) do update set
+    underlying_symbol = excluded.underlying_symbol,
+    expiration_date = excluded.expiration_date,
+    option_type = excluded.option_type,
+    strike_micros = excluded.strike_micros,
+    multiplier = excluded.multiplier
+`
+
+type UpsertOptionParams struct {
+	SecurityID       string `json:"security_id"`
+	UnderlyingSymbol string `json:"underlying_symbol"`
+	ExpirationDate   string `json:"expiration_date"`
+	OptionType       string `json:"option_type"`
+	StrikeMicros     int64  `json:"strike_micros"`
+	Multiplier       int64  `json:"multiplier"`
+}
+
+func (q *Queries) UpsertOption(ctx context.Context, arg UpsertOptionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertOption,
+		arg.SecurityID,
+		arg.UnderlyingSymbol,
+		arg.ExpirationDate,
+		arg.OptionType,
+		arg.StrikeMicros,
+		arg.Multiplier,
+	)
+	return err
+}
+
 const upsertSecurity = `-- name: UpsertSecurity :one
 insert into securities (
     id,
@@ -21,7 +21,9 @@ insert into transactions (
     price_micros,
     amount_micros,
     fees_micros,
-    description
+    fees_in_amount,
+    description,
+    dividend_type
 ) values (
     ?1,
     ?2,
@@ -32,9 +34,12 @@ insert into transactions (
     ?7,
     ?8,
     ?9,
-    ?10
+    ?10,
+    ?11,
+    ?12
 )
-on conflict do nothing
+on conflict (account_id, security_id, transaction_type, transaction_date, quantity_micros, amount_micros, description) do update set
+    dividend_type = coalesce(excluded.dividend_type, transactions.dividend_type)
 `
 
 type CreateTransactionParams struct {
@@ -47,7 +52,9 @@ type CreateTransactionParams struct {
 	PriceMicros     sql.NullInt64  `json:"price_micros"`
 	AmountMicros    int64          `json:"amount_micros"`
 	FeesMicros      sql.NullInt64  `json:"fees_micros"`
+	FeesInAmount    bool           `json:"fees_in_amount"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 }
 
 func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) error {
@@ -61,7 +68,9 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		arg.PriceMicros,
 		arg.AmountMicros,
 		arg.FeesMicros,
+		arg.FeesInAmount,
 		arg.Description,
+		arg.DividendType,
 	)
 	return err
 }
@@ -87,7 +96,7 @@ func (q *Queries) DeleteTransactionsByAccount(ctx context.Context, accountID str
 }
 
 const getTransaction = `-- name: GetTransaction :one
-select id, account_id, security_id, transaction_type, transaction_date, quantity_micros, price_micros, amount_micros, fees_micros, description, created_at
+select id, account_id, security_id, transaction_type, transaction_date, quantity_micros, price_micros, amount_micros, fees_micros, fees_in_amount, description, dividend_type, created_at
 from transactions
 where id = ?1
 `
@@ -105,24 +114,66 @@ func (q *Queries) GetTransaction(ctx context.Context, id string) (Transaction, e
 		&i.PriceMicros,
 		&i.AmountMicros,
 		&i.FeesMicros,
+		&i.FeesInAmount,
 		&i.Description,
+		&i.DividendType,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const listTransactionsByAccount = `-- name: ListTransactionsByAccount :many
+const getTransactionIDByKey = `-- name: GetTransactionIDByKey :one
+select id
+from transactions
+where account_id = ?1
+  and security_id is ?2
+  and transaction_type = ?3
+  and transaction_date = ?4
+  and quantity_micros is ?5
+  and amount_micros = ?6
+  and description is ?7
+`
+
+type GetTransactionIDByKeyParams struct {
+	AccountID       string         `json:"account_id"`
+	SecurityID      sql.NullString `json:"security_id"`
+	TransactionType string         `json:"transaction_type"`
+	TransactionDate string         `json:"transaction_date"`
+	QuantityMicros  sql.NullInt64  `json:"quantity_micros"`
+	AmountMicros    int64          `json:"amount_micros"`
+	Description     sql.NullString `json:"description"`
+}
+
+func (q *Queries) GetTransactionIDByKey(ctx context.Context, arg GetTransactionIDByKeyParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getTransactionIDByKey,
+		arg.AccountID,
+		arg.SecurityID,
+		arg.TransactionType,
+		arg.TransactionDate,
+		arg.QuantityMicros,
+		arg.AmountMicros,
+		arg.Description,
+	)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
+
+const listPurchasesSince = `-- name: ListPurchasesSince :many
 select
-    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.description, t.created_at,
+    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.fees_in_amount, t.description, t.dividend_type, t.created_at,
     s.symbol,
-    s.name as security_name
+    a.name as account_name
 from transactions t
-left join securities s on s.id = t.security_id
-where t.account_id = ?1
-order by t.transaction_date desc, t.created_at desc
+join securities s on s.id = t.security_id
+join accounts a on a.id = t.account_id
+where
+    t.transaction_type in ('buy', 'rsu_vest', 'espp_purchase')
+    and t.transaction_date >= ?1
+order by t.transaction_date asc, t.id asc
 `
 
-type ListTransactionsByAccountRow struct {
+type ListPurchasesSinceRow struct {
 	ID              string         `json:"id"`
 	AccountID       string         `json:"account_id"`
 	SecurityID      sql.NullString `json:"security_id"`
@@ -132,10 +183,169 @@ type ListTransactionsByAccountRow struct {
 	PriceMicros     sql.NullInt64  `json:"price_micros"`
 	AmountMicros    int64          `json:"amount_micros"`
 	FeesMicros      sql.NullInt64  `json:"fees_micros"`
+	FeesInAmount    bool           `json:"fees_in_amount"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
-	Symbol          sql.NullString `json:"symbol"`
-	SecurityName    sql.NullString `json:"security_name"`
+	Symbol          string         `json:"symbol"`
+	AccountName     string         `json:"account_name"`
+}
+
+func (q *Queries) ListPurchasesSince(ctx context.Context, since string) ([]ListPurchasesSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPurchasesSince, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPurchasesSinceRow{}
+	for rows.Next() {
+		var i ListPurchasesSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.TransactionType,
+			&i.TransactionDate,
+			&i.QuantityMicros,
+			&i.PriceMicros,
+			&i.AmountMicros,
+			&i.FeesMicros,
+			&i.FeesInAmount,
+			&i.Description,
+			&i.DividendType,
+			&i.CreatedAt,
+			&i.Symbol,
+			&i.AccountName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSecurityTransfers = `-- name: ListSecurityTransfers :many
+select
+    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.fees_in_amount, t.description, t.dividend_type, t.created_at,
+    s.symbol,
+    a.name as account_name
+from transactions t
+join securities s on s.id = t.security_id
+join accounts a on a.id = t.account_id
+where
+    t.transaction_type in ('transfer_out', 'security_transfer')
+    and t.quantity_micros > 0
+order by t.transaction_date asc, t.id asc
+`
+
+type ListSecurityTransfersRow struct {
+	ID              string         `json:"id"`
+	AccountID       string         `json:"account_id"`
+	SecurityID      sql.NullString `json:"security_id"`
+	TransactionType string         `json:"transaction_type"`
+	TransactionDate string         `json:"transaction_date"`
+	QuantityMicros  sql.NullInt64  `json:"quantity_micros"`
+	PriceMicros     sql.NullInt64  `json:"price_micros"`
+	AmountMicros    int64          `json:"amount_micros"`
+	FeesMicros      sql.NullInt64  `json:"fees_micros"`
+	FeesInAmount    bool           `json:"fees_in_amount"`
+	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
+	CreatedAt       string         `json:"created_at"`
+	Symbol          string         `json:"symbol"`
+	AccountName     string         `json:"account_name"`
+}
+
+func (q *Queries) ListSecurityTransfers(ctx context.Context) ([]ListSecurityTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSecurityTransfers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSecurityTransfersRow{}
+	for rows.Next() {
+		var i ListSecurityTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.SecurityID,
+			&i.TransactionType,
+			&i.TransactionDate,
+			&i.QuantityMicros,
+			&i.PriceMicros,
+			&i.AmountMicros,
+			&i.FeesMicros,
+			&i.FeesInAmount,
+			&i.Description,
+			&i.DividendType,
+			&i.CreatedAt,
+			&i.Symbol,
+			&i.AccountName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByAccount = `-- name: ListTransactionsByAccount :many
+select
+    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.fees_in_amount, t.description, t.dividend_type, t.created_at,
+    s.symbol,
+    s.name as security_name,
+    aq.acquisition_kind,
+    aq.fmv_micros,
+    aq.donor_acquired_date,
+    aq.plan_type,
+    aq.grant_date,
+    aq.grant_fmv_micros,
+    aq.discount_rate,
+    a.margin as account_margin
+from transactions t
+join accounts a on a.id = t.account_id
+left join securities s on s.id = t.security_id
+left join acquisitions aq on aq.transaction_id = t.id
+where t.account_id = ?1
+order by t.transaction_date desc, t.created_at desc
+`
+
+type ListTransactionsByAccountRow struct {
+	ID                string          `json:"id"`
+	AccountID         string          `json:"account_id"`
+	SecurityID        sql.NullString  `json:"security_id"`
+	TransactionType   string          `json:"transaction_type"`
+	TransactionDate   string          `json:"transaction_date"`
+	QuantityMicros    sql.NullInt64   `json:"quantity_micros"`
+	PriceMicros       sql.NullInt64   `json:"price_micros"`
+	AmountMicros      int64           `json:"amount_micros"`
+	FeesMicros        sql.NullInt64   `json:"fees_micros"`
+	FeesInAmount      bool            `json:"fees_in_amount"`
+	Description       sql.NullString  `json:"description"`
+	DividendType      sql.NullString  `json:"dividend_type"`
+	CreatedAt         string          `json:"created_at"`
+	Symbol            sql.NullString  `json:"symbol"`
+	SecurityName      sql.NullString  `json:"security_name"`
+	AcquisitionKind   sql.NullString  `json:"acquisition_kind"`
+	FmvMicros         sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate sql.NullString  `json:"donor_acquired_date"`
+	PlanType          sql.NullString  `json:"plan_type"`
+	GrantDate         sql.NullString  `json:"grant_date"`
+	GrantFmvMicros    sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate      sql.NullFloat64 `json:"discount_rate"`
+	AccountMargin     bool            `json:"account_margin"`
 }
 
 func (q *Queries) ListTransactionsByAccount(ctx context.Context, accountID string) ([]ListTransactionsByAccountRow, error) {
@@ -157,10 +367,20 @@ func (q *Queries) ListTransactionsByAccount(ctx context.Context, accountID strin
 			&i.PriceMicros,
 			&i.AmountMicros,
 			&i.FeesMicros,
+			&i.FeesInAmount,
 			&i.Description,
+			&i.DividendType,
 			&i.CreatedAt,
 			&i.Symbol,
 			&i.SecurityName,
+			&i.AcquisitionKind,
+			&i.FmvMicros,
+			&i.DonorAcquiredDate,
+			&i.PlanType,
+			&i.GrantDate,
+			&i.GrantFmvMicros,
+			&i.DiscountRate,
+			&i.AccountMargin,
 		); err != nil {
 			return nil, err
 		}
@@ -177,7 +397,7 @@ func (q *Queries) ListTransactionsByAccount(ctx context.Context, accountID strin
 
 const listTransactionsByAccountAndDateRange = `-- name: ListTransactionsByAccountAndDateRange :many
 select
-    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.description, t.created_at,
+    t.id, t.account_id, t.security_id, t.transaction_type, t.transaction_date, t.quantity_micros, t.price_micros, t.amount_micros, t.fees_micros, t.fees_in_amount, t.description, t.dividend_type, t.created_at,
     s.symbol,
     s.name as security_name
 from transactions t
@@ -205,7 +425,9 @@ type ListTransactionsByAccountAndDateRangeRow struct {
 	PriceMicros     sql.NullInt64  `json:"price_micros"`
 	AmountMicros    int64          `json:"amount_micros"`
 	FeesMicros      sql.NullInt64  `json:"fees_micros"`
+	FeesInAmount    bool           `json:"fees_in_amount"`
 	Description     sql.NullString `json:"description"`
+	DividendType    sql.NullString `json:"dividend_type"`
 	CreatedAt       string         `json:"created_at"`
 	Symbol          sql.NullString `json:"symbol"`
 	SecurityName    sql.NullString `json:"security_name"`
@@ -230,7 +452,9 @@ func (q *Queries) ListTransactionsByAccountAndDateRange(ctx context.Context, arg
 			&i.PriceMicros,
 			&i.AmountMicros,
 			&i.FeesMicros,
+			&i.FeesInAmount,
 			&i.Description,
+			&i.DividendType,
 			&i.CreatedAt,
 			&i.Symbol,
 			&i.SecurityName,
@@ -247,3 +471,63 @@ func (q *Queries) ListTransactionsByAccountAndDateRange(ctx context.Context, arg
 	}
 	return items, nil
 }
+
+const upsertAcquisition = `-- name: UpsertAcquisition :exec
+insert into acquisitions (
+    transaction_id,
+    acquisition_kind,
+    fmv_micros,
+    donor_acquired_date,
+    plan_type,
+    shares_withheld_micros,
+    grant_date,
+    grant_fmv_micros,
+    discount_rate
+) values (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9
+)
+on conflict (transaction_id) do update set
+    acquisition_kind = excluded.acquisition_kind,
+    fmv_micros = excluded.fmv_micros,
+    donor_acquired_date = excluded.donor_acquired_date,
+    plan_type = excluded.plan_type,
+    shares_withheld_micros = excluded.shares_withheld_micros,
+    grant_date = excluded.grant_date,
+    grant_fmv_micros = excluded.grant_fmv_micros,
+    discount_rate = excluded.discount_rate
+`
+
+type UpsertAcquisitionParams struct {
+	TransactionID        string          `json:"transaction_id"`
+	AcquisitionKind      string          `json:"acquisition_kind"`
+	FmvMicros            sql.NullInt64   `json:"fmv_micros"`
+	DonorAcquiredDate    sql.NullString  `json:"donor_acquired_date"`
+	PlanType             sql.NullString  `json:"plan_type"`
+	SharesWithheldMicros sql.NullInt64   `json:"shares_withheld_micros"`
+	GrantDate            sql.NullString  `json:"grant_date"`
+	GrantFmvMicros       sql.NullInt64   `json:"grant_fmv_micros"`
+	DiscountRate         sql.NullFloat64 `json:"discount_rate"`
+}
+
+func (q *Queries) UpsertAcquisition(ctx context.Context, arg UpsertAcquisitionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAcquisition,
+		arg.TransactionID,
+		arg.AcquisitionKind,
+		arg.FmvMicros,
+		arg.DonorAcquiredDate,
+		arg.PlanType,
+		arg.SharesWithheldMicros,
+		arg.GrantDate,
+		arg.GrantFmvMicros,
+		arg.DiscountRate,
+	)
+	return err
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCouponsPerYear is the coupon frequency of US municipal and most
+// corporate bonds.
+const DefaultCouponsPerYear = 2
+
+// BondTerms are a bond's coupon and redemption terms, as brokers print them
+// in the security description.
+type BondTerms struct {
+	CouponRate      float64 // annual, e.g. 0.05
+	Maturity        time.Time
+	DatedDate       time.Time // zero if not given
+	FirstCouponDate time.Time // zero if not given
+	CallDate        time.Time // next call or pre-refunded redemption date; zero if none
+	CallPriceMicros int64     // per 100 of par
+	ParMicros       int64     // face value per unit of quantity
+	CouponsPerYear  int64
+}
+
+var (
+	bondCoupon = regexp.MustCompile(`CPN\s+([\d.]+)%\s+DUE\s+(\d{2}/\d{2}/\d{2})(?:\s*DTD\s+(\d{2}/\d{2}/\d{2}))?(?:\s*FC\s+(\d{2}/\d{2}/\d{2}))?`)
+	bondCall   = regexp.MustCompile(`(?:CALL|PRE)\s+(\d{2}/\d{2}/\d{2})\s+@\s+([\d.]+)`)
+
+	bondDatesSince = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// ParseBondDescription reads bond terms from a description such as
+// "... B/E CPN  5.000% DUE 04/01/41 DTD 03/13/25 FC 10/01/25 CALL 04/01/35 @ 100.000".
+// Quantities of these bonds are face value in dollars. It reports false when
+// the description has no coupon and maturity.
+func ParseBondDescription(description string) (*BondTerms, bool) {
+	m := bondCoupon.FindStringSubmatch(description)
+	if m == nil {
+		return nil, false
+	}
+
+	rate, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return nil, false
+	}
+
+	// Dates have two-digit years. The dated date is read as Go reads them,
+	// which is right for anything issued since 1969; the rest are read as the
+	// first such date on or after it, so a bond due in 2071 isn't due in
+	// 1971. Without a dated date, the bond is assumed issued since 2000.
+	var dated time.Time
+	if m[3] != "" {
+		dated, err = time.Parse("01/02/06", m[3])
+		if err != nil {
+			return nil, false
+		}
+	}
+	since := dated
+	if since.IsZero() {
+		since = bondDatesSince
+	}
+
+	maturity, err := parseBondDate(m[2], since)
+	if err != nil {
+		return nil, false
+	}
+
+	terms := &BondTerms{
+		CouponRate:     rate / 100,
+		Maturity:       maturity,
+		DatedDate:      dated,
+		ParMicros:      microsMultiplier,
+		CouponsPerYear: DefaultCouponsPerYear,
+	}
+	if m[4] != "" {
+		terms.FirstCouponDate, _ = parseBondDate(m[4], since)
+	}
+
+	if c := bondCall.FindStringSubmatch(description); c != nil {
+		callDate, err := parseBondDate(c[1], since)
+		price, perr := decimal.NewFromString(c[2])
+		if err == nil && perr == nil {
+			terms.CallDate = callDate
+			terms.CallPriceMicros = toMicros(price)
+		}
+	}
+
+	return terms, true
+}
+
+// parseBondDate reads an MM/DD/YY date as the first such date on or after
+// since.
+func parseBondDate(s string, since time.Time) (time.Time, error) {
+	t, err := time.Parse("01/02/06", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for t.Before(since) {
+		t = t.AddDate(100, 0, 0)
+	}
+	return t, nil
+}
@@ -0,0 +1,120 @@
+package importer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseBondDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        *importer.BondTerms
+	}{
+		{
+			name:        "callable muni",
+			description: "LOS ANGELES CA DPT WTR & PWR B/E CPN  5.000% DUE 04/01/41 DTD 03/13/25 FC 10/01/25 CALL 04/01/35 @ 100.000",
+			want: &importer.BondTerms{
+				CouponRate:      0.05,
+				Maturity:        date("2041-04-01"),
+				DatedDate:       date("2025-03-13"),
+				FirstCouponDate: date("2025-10-01"),
+				CallDate:        date("2035-04-01"),
+				CallPriceMicros: 100_000_000,
+				ParMicros:       1_000_000,
+				CouponsPerYear:  2,
+			},
+		},
+		{
+			name:        "pre-refunded, no call",
+			description: "CALIFORNIA STWIDE CMNTYS DEV AUTH B/E CPN  1.780% DUE 12/01/21 DTD 05/30/19 FC 12/01/19 PRE  12/01/20 @ 100.000",
+			want: &importer.BondTerms{
+				CouponRate:      0.0178,
+				Maturity:        date("2021-12-01"),
+				DatedDate:       date("2019-05-30"),
+				FirstCouponDate: date("2019-12-01"),
+				CallDate:        date("2020-12-01"),
+				CallPriceMicros: 100_000_000,
+				ParMicros:       1_000_000,
+				CouponsPerYear:  2,
+			},
+		},
+		{
+			name:        "no call",
+			description: "GOLDEN ST TOB SECURTZN CORP CA B/E PTC CPN  5.000% DUE 06/01/29 DTD 04/09/13 FC 06/01/13 120120      50,000",
+			want: &importer.BondTerms{
+				CouponRate:      0.05,
+				Maturity:        date("2029-06-01"),
+				DatedDate:       date("2013-04-09"),
+				FirstCouponDate: date("2013-06-01"),
+				ParMicros:       1_000_000,
+				CouponsPerYear:  2,
+			},
+		},
+		{
+			name:        "due after 2068",
+			description: "NEW YORK N Y CITY TRANSITIONAL B/E CPN  4.000% DUE 05/01/72 DTD 06/15/22 FC 11/01/22 CALL 05/01/32 @ 100.000",
+			want: &importer.BondTerms{
+				CouponRate:      0.04,
+				Maturity:        date("2072-05-01"),
+				DatedDate:       date("2022-06-15"),
+				FirstCouponDate: date("2022-11-01"),
+				CallDate:        date("2032-05-01"),
+				CallPriceMicros: 100_000_000,
+				ParMicros:       1_000_000,
+				CouponsPerYear:  2,
+			},
+		},
+		{
+			name:        "due after 2068, no dated date",
+			description: "PORT AUTH N Y & N J B/E CPN  3.500% DUE 09/01/70",
+			want: &importer.BondTerms{
+				CouponRate:     0.035,
+				Maturity:       date("2070-09-01"),
+				ParMicros:      1_000_000,
+				CouponsPerYear: 2,
+			},
+		},
+		{
+			name:        "issued in the 1990s",
+			description: "PUERTO RICO COMWLTH B/E CPN  6.000% DUE 07/01/27 DTD 07/01/97 FC 01/01/98",
+			want: &importer.BondTerms{
+				CouponRate:      0.06,
+				Maturity:        date("2027-07-01"),
+				DatedDate:       date("1997-07-01"),
+				FirstCouponDate: date("1998-01-01"),
+				ParMicros:       1_000_000,
+				CouponsPerYear:  2,
+			},
+		},
+		{
+			name:        "not a bond",
+			description: "APPLE INC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := importer.ParseBondDescription(tt.description)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ok = %v, want %v", ok, tt.want != nil)
+			}
+			if tt.want == nil {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
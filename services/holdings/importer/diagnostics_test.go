@@ -0,0 +1,119 @@
+package importer_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func TestLPLPositionsCashBalance(t *testing.T) {
+	f, err := os.Open("testdata/lpl/bond-5516/positions.csv")
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	result, err := (&importer.LPLParser{}).Parse(context.Background(), f)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if result.ExternalAccountNumber != "56005516" {
+		t.Errorf("account = %q, want 56005516", result.ExternalAccountNumber)
+	}
+
+	want := importer.CashBalance{
+		AsOfDate:      date("2026-01-05"),
+		BalanceMicros: 7_573_800_000,
+		Description:   "Insured Cash Account",
+	}
+	if len(result.CashBalances) != 1 || result.CashBalances[0] != want {
+		t.Fatalf("cash balances = %+v, want [%+v]", result.CashBalances, want)
+	}
+
+	if len(result.Positions) != 20 {
+		t.Errorf("got %d positions, want 20", len(result.Positions))
+	}
+	for _, pos := range result.Positions {
+		if pos.Symbol == "9999227" {
+			t.Errorf("insured cash account imported as a position: %+v", pos)
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser importer.Parser
+		input  string
+		want   []importer.Diagnostic
+	}{
+		{
+			name:   "lpl",
+			parser: &importer.LPLParser{},
+			input: `Date,Activity,Symbol,Description,Quantity,Unit Price,Value,Held In,Account Nickname,Account Number
+12/31/2024,interest,9999227,"INSURED CASH ACCOUNT 123124       17,645 AS OF 2024-12-31 00:00:00",-,-,	$1.95,cash,Bond Portfolio,56005516
+12/16/2024,buy,9999227,INSURED CASH ACCOUNT,250,$1.00,	-$250,cash,Bond Portfolio,56005516
+12/10/2024,margin adjustment,CASH,MARGIN INTEREST ADJ,-,-,	-$0.50,cash,Bond Portfolio,56005516
+`,
+			want: []importer.Diagnostic{
+				{Line: 3, Date: date("2024-12-16"), Activity: "buy", Symbol: "9999227", AmountMicros: -250_000_000, Description: "INSURED CASH ACCOUNT", Reason: importer.SkipSweep},
+				{Line: 4, Date: date("2024-12-10"), Activity: "margin adjustment", Symbol: "CASH", AmountMicros: -500_000, Description: "MARGIN INTEREST ADJ", Reason: importer.SkipUnmapped},
+			},
+		},
+		{
+			name:   "merrill",
+			parser: &importer.MerrillParser{},
+			input: `Selected account(s):Managed (CMA 5VT-22241)
+
+"Trade Date" ,"Settlement Date" ,"Account" ,"Description" ,"Type" ,"Symbol/ CUSIP" ,"Quantity" ,"Price" ,"Amount" ," "
+"12/31/2024" ,"12/31/2024" ,"CMA 5VT-22241" ,"Dividend PGIM TOTAL RETURN BOND FUND CL Z PAY DATE 12/31/2024" ,"" ,"PDBZX" ,"" ,"" ,"$672.93" ,""
+"12/31/2024" ,"12/31/2024" ,"CMA 5VT-22241" ,"Reinvestment Program PGIM TOTAL RETURN BOND FUND CL Z" ,"" ,"PDBZX" ,"" ,"" ,"-$672.93" ,""
+"12/30/2024" ,"12/30/2024" ,"CMA 5VT-22241" ,"Deposit ML BANK DEPOSIT PROGRAM" ,"" ,"990286916" ,"294" ,"" ,"-$294.00" ,""
+"12/17/2024" ,"12/17/2024" ,"CMA 5VT-22241" ,"Stock Dividend Due Bill PALO ALTO NETWORKS INC COM" ,"" ,"PANW" ,"-37" ,"" ,"$0.00" ,""
+"12/16/2024" ,"12/16/2024" ,"CMA 5VT-22241" ,"Foreign Tax WASTE CONNECTIONS INC" ,"" ,"WCN" ,"" ,"" ,"-$4.20" ,""
+"12/13/2024" ,"12/13/2024" ,"CMA 5VT-22241" ,"Journal Entry" ,"" ,"" ,"" ,"" ,"$10.00" ,""
+`,
+			want: []importer.Diagnostic{
+				{Line: 5, Date: date("2024-12-31"), Symbol: "PDBZX", AmountMicros: -672_930_000, Description: "Reinvestment Program PGIM TOTAL RETURN BOND FUND CL Z", Reason: importer.SkipReinvestment},
+				{Line: 6, Date: date("2024-12-30"), Symbol: "990286916", AmountMicros: -294_000_000, Description: "Deposit ML BANK DEPOSIT PROGRAM", Reason: importer.SkipSweep},
+				{Line: 7, Date: date("2024-12-17"), Symbol: "PANW", Description: "Stock Dividend Due Bill PALO ALTO NETWORKS INC COM", Reason: importer.SkipPlaceholder},
+				{Line: 8, Date: date("2024-12-16"), Symbol: "WCN", AmountMicros: -4_200_000, Description: "Foreign Tax WASTE CONNECTIONS INC", Reason: importer.SkipForeignTax},
+				{Line: 9, Date: date("2024-12-13"), AmountMicros: 10_000_000, Description: "Journal Entry", Reason: importer.SkipUnmapped},
+			},
+		},
+		{
+			name:   "etrade",
+			parser: &importer.ETradeParser{},
+			input: `For Account:,#####2060
+
+
+TransactionDate,TransactionType,SecurityType,Symbol,Quantity,Amount,Price,Commission,Description
+12/29/23,Dividend,EQ,AAPL,0,24.00,0,0,APPLE INC
+12/08/23,Margin Adjustment,EQ,SCPL,0,-38,0,0,SCIPLAY CORP CL A ADJ
+`,
+			want: []importer.Diagnostic{
+				{Line: 6, Date: date("2023-12-08"), Activity: "Margin Adjustment", Symbol: "SCPL", AmountMicros: -38_000_000, Description: "SCIPLAY CORP CL A ADJ", Reason: importer.SkipUnmapped},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.parser.Parse(context.Background(), strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if len(result.Diagnostics) != len(tt.want) {
+				t.Fatalf("got %d diagnostics, want %d: %+v", len(result.Diagnostics), len(tt.want), result.Diagnostics)
+			}
+			for i, want := range tt.want {
+				if result.Diagnostics[i] != want {
+					t.Errorf("diagnostic %d = %+v, want %+v", i, result.Diagnostics[i], want)
+				}
+			}
+		})
+	}
+}
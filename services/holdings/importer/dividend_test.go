@@ -0,0 +1,101 @@
+package importer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func TestDividendTypes(t *testing.T) {
+	const (
+		etradeHeader  = "TransactionDate,TransactionType,SecurityType,Symbol,Quantity,Amount,Price,Commission,Description\n"
+		merrillHeader = `"Trade Date" ,"Settlement Date" ,"Account" ,"Description" ,"Type" ,"Symbol/ CUSIP" ,"Quantity" ,"Price" ,"Amount" ," "` + "\n"
+		lplHeader     = "Date,Activity,Symbol,Description,Quantity,Unit Price,Value,Held In,Account Nickname,Account Number\n"
+	)
+
+	tests := []struct {
+		name         string
+		parser       importer.Parser
+		input        string
+		wantType     importer.TransactionType
+		wantDividend importer.DividendType
+	}{
+		{
+			name:         "etrade qualified dividend",
+			parser:       &importer.ETradeParser{},
+			input:        etradeHeader + "03/14/24,Qualified Dividend,EQ,AAPL,0,24.00,0,0,APPLE INC CASH DIV ON 100 SHS\n",
+			wantType:     importer.TransactionTypeDividend,
+			wantDividend: importer.DividendTypeQualified,
+		},
+		{
+			name:         "etrade non-qualified dividend",
+			parser:       &importer.ETradeParser{},
+			input:        etradeHeader + "03/14/24,Non-Qualified Dividend,EQ,O,0,25.60,0,0,REALTY INCOME CORP CASH DIV ON 100 SHS\n",
+			wantType:     importer.TransactionTypeDividend,
+			wantDividend: importer.DividendTypeOrdinary,
+		},
+		{
+			name:     "etrade dividend",
+			parser:   &importer.ETradeParser{},
+			input:    etradeHeader + "12/29/22,Dividend,EQ,GILD,0,2596.61,0,0,GILEAD SCIENCES INC CASH DIV ON 3557 SHS\n",
+			wantType: importer.TransactionTypeDividend,
+		},
+		{
+			name:     "etrade reinvested dividend",
+			parser:   &importer.ETradeParser{},
+			input:    etradeHeader + "12/29/22,Dividend,EQ,GILD,38.32078,-3259.66,0,0,GILEAD SCIENCES INC REIN @ 85.0624\n",
+			wantType: importer.TransactionTypeBuy,
+		},
+		{
+			name:         "etrade return of capital",
+			parser:       &importer.ETradeParser{},
+			input:        etradeHeader + "09/30/24,Return of Capital,EQ,O,0,5.00,0,0,REALTY INCOME CORP\n",
+			wantType:     importer.TransactionTypeReturnOfCapital,
+			wantDividend: importer.DividendTypeReturnOfCapital,
+		},
+		{
+			name:         "merrill foreign dividend",
+			parser:       &importer.MerrillParser{},
+			input:        merrillHeader + `"11/21/2024" ,"11/21/2024" ,"CMA 5VT-22241" ,"Foreign Dividend WASTE CONNECTIONS INC HOLDING 102.0000 PAY DATE 11/21/2024" ,"" ,"WCN" ,"" ,"" ,"$32.13" ,""` + "\n",
+			wantType:     importer.TransactionTypeDividend,
+			wantDividend: importer.DividendTypeForeign,
+		},
+		{
+			name:     "merrill dividend from a fund named tax-exempt",
+			parser:   &importer.MerrillParser{},
+			input:    merrillHeader + `"12/31/2024" ,"12/31/2024" ,"CMA 5VT-22241" ,"Dividend VANGUARD TAX-EXEMPT BOND ETF PAY DATE 12/31/2024" ,"" ,"VTEB" ,"" ,"" ,"$70.87" ,""` + "\n",
+			wantType: importer.TransactionTypeDividend,
+		},
+		{
+			name:         "lpl return of capital",
+			parser:       &importer.LPLParser{},
+			input:        lplHeader + "09/30/2024,return of capital,O,REALTY INCOME CORP 093024 100,-,-,\t$5,cash,Equities,4015\n",
+			wantType:     importer.TransactionTypeReturnOfCapital,
+			wantDividend: importer.DividendTypeReturnOfCapital,
+		},
+		{
+			name:     "lpl cash dividend",
+			parser:   &importer.LPLParser{},
+			input:    lplHeader + "12/31/2019,cash dividend,CCI,CROWN CASTLE INTL CORP * NEW 123119 200,-,-,\t$240,cash,Equities,4015\n",
+			wantType: importer.TransactionTypeDividend,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.parser.Parse(context.Background(), strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if len(result.Transactions) != 1 {
+				t.Fatalf("got %d transactions, want 1", len(result.Transactions))
+			}
+			txn := result.Transactions[0]
+			if txn.TransactionType != tt.wantType || txn.DividendType != tt.wantDividend {
+				t.Errorf("got %s %q, want %s %q", txn.TransactionType, txn.DividendType, tt.wantType, tt.wantDividend)
+			}
+		})
+	}
+}
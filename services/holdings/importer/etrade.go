@@ -20,6 +20,7 @@ func (p *ETradeParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, e
 	reader.FieldsPerRecord = -1
 
 	var transactions []Transaction
+	var diagnostics []Diagnostic
 	var externalAccountNumber string
 	headerFound := false
 
@@ -60,6 +61,9 @@ func (p *ETradeParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, e
 		}
 		if txn != nil {
 			transactions = append(transactions, *txn)
+		} else if d, ok := etradeDiagnostic(record); ok {
+			d.Line, _ = reader.FieldPos(0)
+			diagnostics = append(diagnostics, d)
 		}
 	}
 
@@ -67,13 +71,33 @@ func (p *ETradeParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, e
 		ExternalAccountNumber: externalAccountNumber,
 		Transactions:          transactions,
 		Positions:             nil,
+		Diagnostics:           diagnostics,
 	}, nil
 }
 
+// etradeDiagnostic describes a dated row parseETradeRow skipped, which is
+// always activity with no transaction type.
+func etradeDiagnostic(record []string) (Diagnostic, bool) {
+	date, err := time.Parse("01/02/06", strings.TrimSpace(record[0]))
+	if err != nil {
+		return Diagnostic{}, false
+	}
+	amount, _ := decimal.NewFromString(strings.TrimSpace(record[5]))
+	return Diagnostic{
+		Date:         date,
+		Activity:     strings.TrimSpace(record[1]),
+		Symbol:       normalizeSymbol(strings.TrimSpace(record[3])),
+		AmountMicros: toMicros(amount),
+		Description:  strings.TrimSpace(record[8]),
+		Reason:       SkipUnmapped,
+	}, true
+}
+
 func parseETradeRow(record []string) (*Transaction, error) {
 	dateStr := strings.TrimSpace(record[0])
 	txnType := strings.TrimSpace(record[1])
-	symbol := normalizeSymbol(strings.TrimSpace(record[3]))
+	rawSymbol := strings.TrimSpace(record[3])
+	symbol := normalizeSymbol(rawSymbol)
 	quantityStr := strings.TrimSpace(record[4])
 	amountStr := strings.TrimSpace(record[5])
 	priceStr := strings.TrimSpace(record[6])
@@ -99,16 +123,29 @@ func parseETradeRow(record []string) (*Transaction, error) {
 		return nil, nil
 	}
 
+	securityName := extractSecurityName(description)
+	option, isOption := ParseOCCSymbol(rawSymbol)
+	if isOption {
+		symbol = option.Symbol()
+		securityName = option.Name()
+		if amount.IsZero() {
+			// Price is per share; each contract covers Multiplier shares.
+			amount = quantity.Abs().Mul(price).Mul(decimal.NewFromInt(option.Multiplier))
+		}
+	}
+
 	return &Transaction{
 		Symbol:          symbol,
-		SecurityName:    extractSecurityName(description),
+		SecurityName:    securityName,
 		TransactionType: transactionType,
 		TransactionDate: date,
 		QuantityMicros:  toMicros(quantity.Abs()),
 		PriceMicros:     toMicros(price),
 		AmountMicros:    toMicros(amount.Abs()),
-		FeesMicros:      toMicros(commission),
+		FeesMicros:      toMicros(commission.Abs()),
 		Description:     description,
+		DividendType:    dividendTypeFor(transactionType, txnType),
+		Option:          option,
 	}, nil
 }
 
@@ -120,13 +157,27 @@ func mapETradeTransactionType(txnType string, quantity, amount decimal.Decimal)
 		return TransactionTypeOpeningBalance
 	case "Sold":
 		return TransactionTypeSell
+	case "Bought To Open":
+		return TransactionTypeBuy
+	case "Sold To Close":
+		return TransactionTypeSell
+	case "Sold To Open", "Sold Short":
+		return TransactionTypeSellShort
+	case "Bought To Close", "Bought To Cover":
+		return TransactionTypeBuyToCover
+	case "Option Expired", "Expired":
+		return TransactionTypeOptionExpiration
+	case "Option Assigned", "Assigned":
+		return TransactionTypeOptionAssignment
+	case "Option Exercised", "Exercised":
+		return TransactionTypeOptionExercise
 	case "Dividend":
 		// DRIP: quantity > 0 means reinvesting dividend into shares (buy)
 		if quantity.IsPositive() {
 			return TransactionTypeBuy
 		}
 		return TransactionTypeDividend
-	case "Qualified Dividend":
+	case "Qualified Dividend", "Non-Qualified Dividend":
 		return TransactionTypeDividend
 	case "Interest Income", "Interest":
 		return TransactionTypeInterest
@@ -149,6 +200,8 @@ func mapETradeTransactionType(txnType string, quantity, amount decimal.Decimal)
 		return TransactionTypeReorgOut
 	case "LT Cap Gain Distribution", "ST Cap Gain Distribution":
 		return TransactionTypeCapGain
+	case "Return of Capital":
+		return TransactionTypeReturnOfCapital
 	case "Misc Trade", "Adjustment":
 		return TransactionTypeOther
 	default:
@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ETradeStockPlanParser reads E*Trade's stock plan benefit history (Stock
+// Plan > My Account > Benefit History, downloaded as CSV). Each row is a
+// record type plus the columns that apply to it:
+//
+//	Release        RSU shares vested on Date; Quantity is the vested total,
+//	               Withheld Qty what was kept back for taxes, FMV per share
+//	Purchase       ESPP shares bought on Date at Price; FMV per share on the
+//	               purchase date, Grant Date and Grant Date FMV for the
+//	               offering, and Discount
+//	Sell to Cover  shares sold on Date at Price to pay the taxes on a release
+type ETradeStockPlanParser struct{}
+
+// stockPlanColumns maps each field to the header names it appears under.
+// Headers are compared after lowercasing and stripping punctuation.
+var stockPlanColumns = map[string][]string{
+	"record":    {"record type", "type"},
+	"symbol":    {"symbol"},
+	"date":      {"date", "vest date", "release date", "purchase date", "event date"},
+	"quantity":  {"quantity", "qty", "vested qty", "released qty", "purchased qty"},
+	"withheld":  {"withheld qty", "shares withheld", "tax withholding qty"},
+	"price":     {"price", "purchase price", "sale price"},
+	"fmv":       {"fmv", "fmv at vest", "purchase date fmv", "vest date fmv"},
+	"grant":     {"grant date", "offering date"},
+	"grant_fmv": {"grant date fmv", "offering date fmv"},
+	"discount":  {"discount", "discount percent"},
+}
+
+func (p *ETradeStockPlanParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var transactions []Transaction
+	var externalAccountNumber string
+	var columns map[string]int
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+
+		if len(record) >= 2 && record[0] == "For Account:" {
+			externalAccountNumber = strings.TrimSpace(record[1])
+			continue
+		}
+
+		if columns == nil {
+			columns = matchStockPlanHeader(record)
+			continue
+		}
+
+		txns, err := parseStockPlanRow(record, columns)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("failed to parse stock plan row on line %d: %w", line, err)
+		}
+		transactions = append(transactions, txns...)
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("no stock plan header row found")
+	}
+
+	return &ImportResult{
+		ExternalAccountNumber: externalAccountNumber,
+		Transactions:          transactions,
+		Positions:             nil,
+	}, nil
+}
+
+// matchStockPlanHeader returns the column index of each known field, or nil
+// if the record isn't the header (it needs a record type, symbol and date).
+func matchStockPlanHeader(record []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range record {
+		cleaned := strings.TrimSpace(headerCleaner.ReplaceAllString(strings.ToLower(name), " "))
+		for field, aliases := range stockPlanColumns {
+			if _, ok := columns[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if cleaned == alias {
+					columns[field] = i
+					break
+				}
+			}
+		}
+	}
+
+	for _, required := range []string{"record", "symbol", "date", "quantity"} {
+		if _, ok := columns[required]; !ok {
+			return nil
+		}
+	}
+	return columns
+}
+
+func parseStockPlanRow(record []string, columns map[string]int) ([]Transaction, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	amount := func(name string) (decimal.Decimal, error) {
+		d, err := parse1099BAmount(field(name))
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return d, nil
+	}
+
+	date, err := parse1099BDate(field("date"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+	if date.IsZero() {
+		return nil, nil
+	}
+	symbol := normalizeSymbol(field("symbol"))
+	quantity, err := amount("quantity")
+	if err != nil {
+		return nil, err
+	}
+	price, err := amount("price")
+	if err != nil {
+		return nil, err
+	}
+	fmv, err := amount("fmv")
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(field("record")) {
+	case "release":
+		withheld, err := amount("withheld")
+		if err != nil {
+			return nil, err
+		}
+		released := quantity.Sub(withheld)
+		value := released.Mul(fmv)
+		return []Transaction{{
+			Symbol:          symbol,
+			TransactionType: TransactionTypeRSUVest,
+			TransactionDate: date,
+			QuantityMicros:  toMicros(released),
+			PriceMicros:     toMicros(fmv),
+			AmountMicros:    toMicros(value),
+			Description:     "RSU release",
+			Compensation: &Compensation{
+				PlanType:             PlanTypeRSU,
+				FMVMicros:            toMicros(value),
+				SharesWithheldMicros: toMicros(withheld),
+			},
+		}}, nil
+
+	case "purchase":
+		grantDate, err := parse1099BDate(field("grant"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse grant date: %w", err)
+		}
+		grantFMV, err := amount("grant_fmv")
+		if err != nil {
+			return nil, err
+		}
+		discount, err := parseStockPlanDiscount(field("discount"), price, fmv, grantFMV)
+		if err != nil {
+			return nil, err
+		}
+		return []Transaction{{
+			Symbol:          symbol,
+			TransactionType: TransactionTypeESPPPurchase,
+			TransactionDate: date,
+			QuantityMicros:  toMicros(quantity),
+			PriceMicros:     toMicros(price),
+			AmountMicros:    toMicros(quantity.Mul(price)),
+			Description:     "ESPP purchase",
+			Compensation: &Compensation{
+				PlanType:       PlanTypeESPP,
+				FMVMicros:      toMicros(quantity.Mul(fmv)),
+				GrantDate:      grantDate,
+				GrantFMVMicros: toMicros(quantity.Mul(grantFMV)),
+				DiscountRate:   discount,
+			},
+		}}, nil
+
+	case "sell to cover":
+		return []Transaction{{
+			Symbol:          symbol,
+			TransactionType: TransactionTypeSell,
+			TransactionDate: date,
+			QuantityMicros:  toMicros(quantity),
+			PriceMicros:     toMicros(price),
+			AmountMicros:    toMicros(quantity.Mul(price)),
+			Description:     "Sell to cover",
+		}}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// parseStockPlanDiscount reads "15%" or "0.15". When the column is missing,
+// it assumes the usual lookback plan: the discount applies to the lower of
+// the offering and purchase date prices.
+func parseStockPlanDiscount(s string, price, fmv, grantFMV decimal.Decimal) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		base := fmv
+		if grantFMV.IsPositive() && grantFMV.LessThan(base) {
+			base = grantFMV
+		}
+		if !base.IsPositive() {
+			return 0, nil
+		}
+		return decimal.NewFromInt(1).Sub(price.Div(base)).Round(4).InexactFloat64(), nil
+	}
+
+	percent := strings.HasSuffix(s, "%")
+	d, err := decimal.NewFromString(strings.TrimSuffix(s, "%"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse discount: %w", err)
+	}
+	if percent || d.GreaterThan(decimal.NewFromInt(1)) {
+		d = d.Div(decimal.NewFromInt(100))
+	}
+	return d.InexactFloat64(), nil
+}
@@ -0,0 +1,183 @@
+package importer_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func TestETradeStockPlanParser(t *testing.T) {
+	f, err := os.Open("testdata/etrade/stockplan-3652/benefit_history.csv")
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	result, err := (&importer.ETradeStockPlanParser{}).Parse(context.Background(), f)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if result.ExternalAccountNumber != "#####3652" {
+		t.Errorf("account = %q, want #####3652", result.ExternalAccountNumber)
+	}
+
+	want := []importer.Transaction{
+		{
+			Symbol:          "GILD",
+			TransactionType: importer.TransactionTypeRSUVest,
+			TransactionDate: date("2024-02-20"),
+			QuantityMicros:  78_000_000,
+			PriceMicros:     73_850_000,
+			AmountMicros:    5_760_300_000,
+			Description:     "RSU release",
+			Compensation: &importer.Compensation{
+				PlanType:             importer.PlanTypeRSU,
+				FMVMicros:            5_760_300_000,
+				SharesWithheldMicros: 42_000_000,
+			},
+		},
+		{
+			Symbol:          "GILD",
+			TransactionType: importer.TransactionTypeESPPPurchase,
+			TransactionDate: date("2024-06-28"),
+			QuantityMicros:  85_000_000,
+			PriceMicros:     54_530_000,
+			AmountMicros:    4_635_050_000,
+			Description:     "ESPP purchase",
+			Compensation: &importer.Compensation{
+				PlanType:       importer.PlanTypeESPP,
+				FMVMicros:      5_452_750_000,
+				GrantDate:      date("2024-01-02"),
+				GrantFMVMicros: 6_901_150_000,
+				DiscountRate:   0.15,
+			},
+		},
+		{
+			Symbol:          "GILD",
+			TransactionType: importer.TransactionTypeRSUVest,
+			TransactionDate: date("2024-08-20"),
+			QuantityMicros:  120_000_000,
+			PriceMicros:     77_410_000,
+			AmountMicros:    9_289_200_000,
+			Description:     "RSU release",
+			Compensation: &importer.Compensation{
+				PlanType:  importer.PlanTypeRSU,
+				FMVMicros: 9_289_200_000,
+			},
+		},
+		{
+			Symbol:          "GILD",
+			TransactionType: importer.TransactionTypeSell,
+			TransactionDate: date("2024-08-20"),
+			QuantityMicros:  44_000_000,
+			PriceMicros:     77_120_000,
+			AmountMicros:    3_393_280_000,
+			Description:     "Sell to cover",
+		},
+		{
+			Symbol:          "GILD",
+			TransactionType: importer.TransactionTypeESPPPurchase,
+			TransactionDate: date("2024-12-31"),
+			QuantityMicros:  62_000_000,
+			PriceMicros:     54_530_000,
+			AmountMicros:    3_380_860_000,
+			Description:     "ESPP purchase",
+			Compensation: &importer.Compensation{
+				PlanType:       importer.PlanTypeESPP,
+				FMVMicros:      5_726_940_000,
+				GrantDate:      date("2024-07-01"),
+				GrantFMVMicros: 3_977_300_000,
+				DiscountRate:   0.15,
+			},
+		},
+	}
+
+	if len(result.Transactions) != len(want) {
+		t.Fatalf("got %d transactions, want %d", len(result.Transactions), len(want))
+	}
+	for i, w := range want {
+		assertStockPlanTransaction(t, i, result.Transactions[i], w)
+	}
+}
+
+func TestETradeStockPlanParserRows(t *testing.T) {
+	const header = "Record Type,Symbol,Date,Quantity,Withheld Qty,Price,FMV,Grant Date,Grant Date FMV,Discount\n"
+
+	tests := []struct {
+		name         string
+		row          string
+		wantDiscount float64
+		wantErr      string
+	}{
+		{
+			name: "discount from the lower of the offering and purchase prices",
+			row:  "Purchase,GILD,06/28/2024,100,,51.00,64.15,01/02/2024,60.00,\n",
+			// $51 is 15% off the $60 offering price.
+			wantDiscount: 0.15,
+		},
+		{
+			name:         "discount as a fraction",
+			row:          "Purchase,GILD,06/28/2024,100,,54.53,64.15,01/02/2024,81.19,0.15\n",
+			wantDiscount: 0.15,
+		},
+		{
+			name:    "unparseable fmv",
+			row:     "Release,GILD,02/20/2024,120,42,,N/A,,,\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "unparseable grant date",
+			row:     "Purchase,GILD,06/28/2024,85,,54.53,64.15,Jan 2 2024,81.19,15%\n",
+			wantErr: "failed to parse grant date",
+		},
+		{
+			name:    "unparseable discount",
+			row:     "Purchase,GILD,06/28/2024,85,,54.53,64.15,01/02/2024,81.19,fifteen\n",
+			wantErr: "failed to parse discount",
+		},
+		{
+			name:    "unparseable date",
+			row:     "Release,GILD,2024-20-02,120,42,,73.85,,,\n",
+			wantErr: "failed to parse date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&importer.ETradeStockPlanParser{}).Parse(context.Background(), strings.NewReader(header+tt.row))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if len(result.Transactions) != 1 || result.Transactions[0].Compensation == nil {
+				t.Fatalf("expected one ESPP purchase, got %+v", result.Transactions)
+			}
+			if got := result.Transactions[0].Compensation.DiscountRate; got != tt.wantDiscount {
+				t.Errorf("discount = %v, want %v", got, tt.wantDiscount)
+			}
+		})
+	}
+}
+
+func assertStockPlanTransaction(t *testing.T, i int, got, want importer.Transaction) {
+	t.Helper()
+	gotComp, wantComp := got.Compensation, want.Compensation
+	got.Compensation, want.Compensation = nil, nil
+	if got != want {
+		t.Errorf("transaction %d = %+v, want %+v", i, got, want)
+	}
+	switch {
+	case (gotComp == nil) != (wantComp == nil):
+		t.Errorf("transaction %d compensation = %+v, want %+v", i, gotComp, wantComp)
+	case gotComp != nil && *gotComp != *wantComp:
+		t.Errorf("transaction %d compensation = %+v, want %+v", i, *gotComp, *wantComp)
+	}
+}
@@ -0,0 +1,444 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Form1099BSale is one sale as the broker reported it on Form 1099-B.
+type Form1099BSale struct {
+	Symbol                   string
+	CUSIP                    string
+	Description              string
+	QuantityMicros           int64
+	DateAcquired             time.Time // zero when reported as "VARIOUS"
+	DateSold                 time.Time
+	ProceedsMicros           int64
+	CostBasisMicros          int64
+	WashSaleDisallowedMicros int64
+	Term                     string // "short_term", "long_term" or "" if not reported
+	BasisReported            bool   // covered: basis was reported to the IRS
+}
+
+type Form1099BResult struct {
+	ExternalAccountNumber string
+	Sales                 []Form1099BSale
+}
+
+// Parse1099B reads a broker's 1099-B download. TXF files (starting with a
+// V04x header) are detected automatically; anything else is read as the
+// broker's CSV export.
+func Parse1099B(ctx context.Context, broker Broker, r io.Reader) (*Form1099BResult, error) {
+	switch broker {
+	case BrokerETrade, BrokerLPL, BrokerMerrill:
+	default:
+		return nil, fmt.Errorf("unsupported 1099-B broker: %s", broker)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 1099-B: %w", err)
+	}
+
+	var result *Form1099BResult
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("V04")) {
+		result, err = parse1099BTXF(data)
+	} else {
+		result, err = parse1099BCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Sales {
+		result.Sales[i].Symbol = normalize1099BSymbol(broker, result.Sales[i].Symbol)
+	}
+	return result, nil
+}
+
+// form1099BColumns maps each field to the header names E*Trade, Merrill and
+// LPL use for it in their 1099-B CSV downloads. Headers are compared after
+// lowercasing and stripping punctuation.
+var form1099BColumns = map[string][]string{
+	"symbol":      {"symbol", "ticker", "ticker symbol"},
+	"cusip":       {"cusip", "cusip number"},
+	"description": {"description", "description of property", "security description", "security", "1a description of property"},
+	"quantity":    {"quantity", "qty", "shares", "quantity sold"},
+	"acquired":    {"date acquired", "acquired", "acquisition date", "1b date acquired"},
+	"sold":        {"date sold", "date sold or disposed", "sale date", "disposition date", "1c date sold or disposed"},
+	"proceeds":    {"proceeds", "gross proceeds", "sales price", "total proceeds", "1d proceeds"},
+	"basis":       {"cost basis", "cost or other basis", "adjusted cost basis", "total cost basis", "1e cost or other basis"},
+	"wash":        {"wash sale loss disallowed", "wash sale", "wash sale amount", "1g wash sale loss disallowed"},
+	"term":        {"term", "short long term", "holding period", "type of gain loss", "gain loss type"},
+	"covered":     {"covered", "covered status", "basis reported to irs", "box 12 basis reported to irs"},
+}
+
+var headerCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+
+func parse1099BCSV(data []byte) (*Form1099BResult, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	result := &Form1099BResult{}
+	var columns map[string]int
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		first := strings.TrimSpace(record[0])
+		if len(record) >= 2 && (first == "For Account:" || strings.EqualFold(first, "Account Number")) {
+			result.ExternalAccountNumber = strings.TrimSpace(record[1])
+			continue
+		}
+
+		if columns == nil {
+			columns = match1099BHeader(record)
+			continue
+		}
+
+		sale, err := parse1099BRow(record, columns)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("failed to parse 1099-B row on line %d: %w", line, err)
+		}
+		if sale != nil {
+			result.Sales = append(result.Sales, *sale)
+		}
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("no 1099-B header row found")
+	}
+	return result, nil
+}
+
+// match1099BHeader returns the column index of each known field, or nil if
+// the record isn't a 1099-B header (it needs at least a sale date and proceeds).
+func match1099BHeader(record []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range record {
+		cleaned := strings.TrimSpace(headerCleaner.ReplaceAllString(strings.ToLower(name), " "))
+		for field, aliases := range form1099BColumns {
+			if _, ok := columns[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if cleaned == alias {
+					columns[field] = i
+					break
+				}
+			}
+		}
+	}
+
+	if _, ok := columns["sold"]; !ok {
+		return nil
+	}
+	if _, ok := columns["proceeds"]; !ok {
+		return nil
+	}
+	return columns
+}
+
+func parse1099BRow(record []string, columns map[string]int) (*Form1099BSale, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	sold, err := parse1099BDate(field("sold"))
+	if err != nil || sold.IsZero() {
+		// Subtotal and total rows have no sale date.
+		return nil, nil
+	}
+
+	acquired, err := parse1099BDate(field("acquired"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date acquired: %w", err)
+	}
+
+	description := field("description")
+	quantity, err := parse1099BAmount(field("quantity"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse quantity: %w", err)
+	}
+	proceeds, err := parse1099BAmount(field("proceeds"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proceeds: %w", err)
+	}
+	basis, err := parse1099BAmount(field("basis"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cost basis: %w", err)
+	}
+	wash, err := parse1099BAmount(field("wash"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse wash sale: %w", err)
+	}
+
+	symbol := field("symbol")
+	cusip := field("cusip")
+	if symbol == "" || cusip == "" {
+		descSymbol, descCUSIP := parse1099BDescription(description)
+		if symbol == "" {
+			symbol = descSymbol
+		}
+		if cusip == "" {
+			cusip = descCUSIP
+		}
+	}
+
+	covered := true
+	if _, ok := columns["covered"]; ok {
+		covered = parse1099BCovered(field("covered"))
+	}
+
+	return &Form1099BSale{
+		Symbol:                   symbol,
+		CUSIP:                    cusip,
+		Description:              description,
+		QuantityMicros:           toMicros(quantity.Abs()),
+		DateAcquired:             acquired,
+		DateSold:                 sold,
+		ProceedsMicros:           toMicros(proceeds),
+		CostBasisMicros:          toMicros(basis),
+		WashSaleDisallowedMicros: toMicros(wash.Abs()),
+		Term:                     parse1099BTerm(field("term")),
+		BasisReported:            covered,
+	}, nil
+}
+
+// txf1099BRefNumbers maps TXF capital gains reference numbers to term and
+// whether basis was reported.
+var txf1099BRefNumbers = map[string]struct {
+	term    string
+	covered bool
+}{
+	"321": {"short_term", true},
+	"711": {"short_term", false},
+	"712": {"short_term", false},
+	"323": {"long_term", true},
+	"713": {"long_term", false},
+	"714": {"long_term", false},
+}
+
+// parse1099BTXF reads TXF v042 format 5 records: N (reference number),
+// P (description), D (acquired), D (sold), $ (basis), $ (proceeds) and an
+// optional $ (wash sale disallowed).
+func parse1099BTXF(data []byte) (*Form1099BResult, error) {
+	result := &Form1099BResult{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	var (
+		inHeader = true
+		ref      string
+		desc     string
+		dates    []string
+		amounts  []string
+	)
+
+	flush := func() error {
+		defer func() { ref, desc, dates, amounts = "", "", nil, nil }()
+
+		kind, ok := txf1099BRefNumbers[ref]
+		if !ok || len(dates) < 2 || len(amounts) < 2 {
+			return nil
+		}
+
+		acquired, err := parse1099BDate(dates[0])
+		if err != nil {
+			return err
+		}
+		sold, err := parse1099BDate(dates[1])
+		if err != nil {
+			return err
+		}
+		basis, err := parse1099BAmount(amounts[0])
+		if err != nil {
+			return err
+		}
+		proceeds, err := parse1099BAmount(amounts[1])
+		if err != nil {
+			return err
+		}
+		var wash decimal.Decimal
+		if len(amounts) > 2 {
+			if wash, err = parse1099BAmount(amounts[2]); err != nil {
+				return err
+			}
+		}
+
+		quantity, symbol := parseTXFDescription(desc)
+		result.Sales = append(result.Sales, Form1099BSale{
+			Symbol:                   symbol,
+			Description:              desc,
+			QuantityMicros:           toMicros(quantity),
+			DateAcquired:             acquired,
+			DateSold:                 sold,
+			ProceedsMicros:           toMicros(proceeds),
+			CostBasisMicros:          toMicros(basis),
+			WashSaleDisallowedMicros: toMicros(wash.Abs()),
+			Term:                     kind.term,
+			BasisReported:            kind.covered,
+		})
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "^" {
+			if inHeader {
+				inHeader = false
+				continue
+			}
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("failed to parse TXF record: %w", err)
+			}
+			continue
+		}
+		if inHeader {
+			continue
+		}
+
+		value := line[1:]
+		switch line[0] {
+		case 'N':
+			ref = value
+		case 'P':
+			desc = value
+		case 'D':
+			dates = append(dates, value)
+		case '$':
+			amounts = append(amounts, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read TXF: %w", err)
+	}
+
+	return result, nil
+}
+
+var txfDescription = regexp.MustCompile(`^([\d,.]+)\s+(?:(?i:sh|shs|shares)\s+)?(\S+)`)
+
+// parseTXFDescription splits descriptions like "10 sh AAPL" or "25 MSFT"
+// into quantity and symbol.
+func parseTXFDescription(desc string) (decimal.Decimal, string) {
+	m := txfDescription.FindStringSubmatch(strings.TrimSpace(desc))
+	if m == nil {
+		return decimal.Zero, ""
+	}
+	qty, err := decimal.NewFromString(strings.ReplaceAll(m[1], ",", ""))
+	if err != nil {
+		return decimal.Zero, ""
+	}
+	return qty, m[2]
+}
+
+var (
+	descriptionSymbol = regexp.MustCompile(`(?i)symbol:?\s*([A-Z0-9.]+)`)
+	descriptionCUSIP  = regexp.MustCompile(`(?i)cusip:?\s*([0-9A-Z]{9})`)
+)
+
+// parse1099BDescription pulls the symbol and CUSIP out of descriptions like
+// "APPLE INC / CUSIP: 037833100 / Symbol: AAPL".
+func parse1099BDescription(desc string) (symbol, cusip string) {
+	if m := descriptionSymbol.FindStringSubmatch(desc); m != nil {
+		symbol = strings.ToUpper(m[1])
+	}
+	if m := descriptionCUSIP.FindStringSubmatch(desc); m != nil {
+		cusip = strings.ToUpper(m[1])
+	}
+	return symbol, cusip
+}
+
+func parse1099BDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "various") || strings.EqualFold(s, "var") {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"01/02/2006", "1/2/2006", "01/02/06", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date: %s", s)
+}
+
+// parse1099BAmount handles "$1,234.56", "(12.34)" and "-" style amounts.
+func parse1099BAmount(s string) (decimal.Decimal, error) {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "$", "")
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" || s == "-" || s == "--" {
+		return decimal.Zero, nil
+	}
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.Trim(s, "()")
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if negative {
+		d = d.Neg()
+	}
+	return d, nil
+}
+
+func parse1099BTerm(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case strings.Contains(s, "short"), s == "st", s == "s":
+		return "short_term"
+	case strings.Contains(s, "long"), s == "lt", s == "l":
+		return "long_term"
+	default:
+		return ""
+	}
+}
+
+func parse1099BCovered(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "noncovered", "non-covered", "not covered", "no", "n", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+func normalize1099BSymbol(broker Broker, symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	switch broker {
+	case BrokerLPL:
+		return normalizeLPLSymbol(symbol)
+	case BrokerMerrill:
+		return normalizeMerrillSymbol(symbol)
+	default:
+		return normalizeSymbol(symbol)
+	}
+}
@@ -0,0 +1,191 @@
+package importer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func TestParse1099B(t *testing.T) {
+	tests := []struct {
+		name    string
+		broker  importer.Broker
+		input   string
+		account string
+		want    []importer.Form1099BSale
+		wantErr string
+	}{
+		{
+			name:   "csv",
+			broker: importer.BrokerETrade,
+			input: `For Account:,#####2060
+
+Description,Symbol,Quantity,Date Acquired,Date Sold,Proceeds,Cost Basis,Wash Sale Loss Disallowed,Term,Covered
+APPLE INC,AAPL,10,06/01/2023,03/01/2024,"$1,500.00","$1,000.00",,Short Term,Covered
+MICROSOFT CORP,MSFT,5,VARIOUS,03/15/2024,$900.00,$950.00,$50.00,Long Term,Noncovered
+Total,,,,,"$2,400.00","$1,950.00",,,
+`,
+			account: "#####2060",
+			want: []importer.Form1099BSale{
+				{
+					Symbol:          "AAPL",
+					Description:     "APPLE INC",
+					QuantityMicros:  10_000_000,
+					DateAcquired:    date("2023-06-01"),
+					DateSold:        date("2024-03-01"),
+					ProceedsMicros:  1_500_000_000,
+					CostBasisMicros: 1_000_000_000,
+					Term:            "short_term",
+					BasisReported:   true,
+				},
+				{
+					Symbol:                   "MSFT",
+					Description:              "MICROSOFT CORP",
+					QuantityMicros:           5_000_000,
+					DateSold:                 date("2024-03-15"),
+					ProceedsMicros:           900_000_000,
+					CostBasisMicros:          950_000_000,
+					WashSaleDisallowedMicros: 50_000_000,
+					Term:                     "long_term",
+				},
+			},
+		},
+		{
+			name:   "csv with symbol and cusip in the description",
+			broker: importer.BrokerMerrill,
+			input: `1a Description of property,Quantity Sold,1b Date acquired,1c Date sold or disposed,1d Proceeds,1e Cost or other basis
+APPLE INC / CUSIP: 037833100 / Symbol: AAPL,2,01/10/2022,02/01/2024,(5.00),10.00
+`,
+			want: []importer.Form1099BSale{{
+				Symbol:          "AAPL",
+				CUSIP:           "037833100",
+				Description:     "APPLE INC / CUSIP: 037833100 / Symbol: AAPL",
+				QuantityMicros:  2_000_000,
+				DateAcquired:    date("2022-01-10"),
+				DateSold:        date("2024-02-01"),
+				ProceedsMicros:  -5_000_000,
+				CostBasisMicros: 10_000_000,
+				BasisReported:   true,
+			}},
+		},
+		{
+			name:   "malformed csv row",
+			broker: importer.BrokerETrade,
+			input: `Description,Symbol,Quantity,Date Acquired,Date Sold,Proceeds,Cost Basis
+APPLE INC,AAPL,10,06/01/2023,03/01/2024,$1500.00,$1000.00
+MICROSOFT CORP,MSFT,5,01/10/2022,03/15/2024,N/A,$950.00
+`,
+			wantErr: "line 3",
+		},
+		{
+			name:    "csv without a header",
+			broker:  importer.BrokerETrade,
+			input:   "APPLE INC,AAPL,10\n",
+			wantErr: "no 1099-B header",
+		},
+		{
+			name:   "txf",
+			broker: importer.BrokerETrade,
+			input: `V042
+AE*TRADE
+D02/01/2025
+^
+TD
+N321
+C1
+L1
+P10 sh AAPL
+D06/01/2023
+D03/01/2024
+$1000.00
+$1500.00
+^
+TD
+N714
+C1
+L1
+P5 MSFT
+DVARIOUS
+D03/15/2024
+$950.00
+$900.00
+$50.00
+^
+`,
+			want: []importer.Form1099BSale{
+				{
+					Symbol:          "AAPL",
+					Description:     "10 sh AAPL",
+					QuantityMicros:  10_000_000,
+					DateAcquired:    date("2023-06-01"),
+					DateSold:        date("2024-03-01"),
+					ProceedsMicros:  1_500_000_000,
+					CostBasisMicros: 1_000_000_000,
+					Term:            "short_term",
+					BasisReported:   true,
+				},
+				{
+					Symbol:                   "MSFT",
+					Description:              "5 MSFT",
+					QuantityMicros:           5_000_000,
+					DateSold:                 date("2024-03-15"),
+					ProceedsMicros:           900_000_000,
+					CostBasisMicros:          950_000_000,
+					WashSaleDisallowedMicros: 50_000_000,
+					Term:                     "long_term",
+				},
+			},
+		},
+		{
+			name:   "malformed txf record",
+			broker: importer.BrokerETrade,
+			input: `V042
+^
+TD
+N321
+P10 sh AAPL
+D06/01/2023
+D03/01/2024
+$1000.00
+$abc
+^
+`,
+			wantErr: "failed to parse TXF record",
+		},
+		{
+			name:    "unsupported broker",
+			broker:  importer.BrokerVanguard,
+			input:   "V042\n^\n",
+			wantErr: "unsupported 1099-B broker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := importer.Parse1099B(context.Background(), tt.broker, strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			if result.ExternalAccountNumber != tt.account {
+				t.Errorf("account = %q, want %q", result.ExternalAccountNumber, tt.account)
+			}
+			if len(result.Sales) != len(tt.want) {
+				t.Fatalf("got %d sales, want %d: %+v", len(result.Sales), len(tt.want), result.Sales)
+			}
+			for i, want := range tt.want {
+				if result.Sales[i] != want {
+					t.Errorf("sale %d = %+v, want %+v", i, result.Sales[i], want)
+				}
+			}
+		})
+	}
+}
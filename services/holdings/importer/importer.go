@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -21,7 +22,16 @@ const (
 	TransactionTypeReorgIn          TransactionType = "reorg_in"          // shares received from reorg
 	TransactionTypeReorgOut         TransactionType = "reorg_out"         // shares removed from reorg (cash merger, etc)
 	TransactionTypeCapGain          TransactionType = "cap_gain"          // capital gain distributions
+	TransactionTypeReturnOfCapital  TransactionType = "return_of_capital" // nondividend distribution, reduces lot basis
 	TransactionTypeOpeningBalance   TransactionType = "opening_balance"   // manual opening lot
+	TransactionTypeRSUVest          TransactionType = "rsu_vest"          // restricted stock released, net of shares withheld
+	TransactionTypeESPPPurchase     TransactionType = "espp_purchase"     // employee stock purchase plan shares bought
+	TransactionTypeSellShort        TransactionType = "sell_short"        // opens a short position (sold short or sold to open)
+	TransactionTypeBuyToCover       TransactionType = "buy_to_cover"      // closes a short position (bought to cover or to close)
+	TransactionTypeOptionExpiration TransactionType = "option_expiration" // contracts expired worthless
+	TransactionTypeOptionAssignment TransactionType = "option_assignment" // short contracts assigned
+	TransactionTypeOptionExercise   TransactionType = "option_exercise"   // long contracts exercised
+	TransactionTypeRedemption       TransactionType = "redemption"        // bond matured or called, repaid at the call or par price
 	TransactionTypeFee              TransactionType = "fee"               // advisory fees, etc
 	TransactionTypeOther            TransactionType = "other"
 )
@@ -36,6 +46,70 @@ type Transaction struct {
 	AmountMicros    int64 // amount * 1,000,000
 	FeesMicros      int64 // fees * 1,000,000
 	Description     string
+	DividendType    DividendType    // set for dividends when the broker says which kind, and returns of capital
+	Compensation    *Compensation   // set for rsu_vest and espp_purchase
+	Option          *OptionContract // set when Symbol is an option
+	Bond            *BondTerms      // set when Symbol is a bond
+}
+
+// DividendType is how a dividend is taxed. Most exports only say "dividend";
+// those are left unset and estimated from the security when reporting.
+type DividendType string
+
+const (
+	DividendTypeQualified       DividendType = "qualified"
+	DividendTypeOrdinary        DividendType = "ordinary"          // nonqualified
+	DividendTypeForeign         DividendType = "foreign"           // paid by a foreign company, may carry foreign tax
+	DividendTypeTaxExempt       DividendType = "tax_exempt"        // exempt-interest dividends from muni funds
+	DividendTypeSection199A     DividendType = "section_199a"      // REIT dividends eligible for the QBI deduction
+	DividendTypeReturnOfCapital DividendType = "return_of_capital" // nondividend distribution
+)
+
+// dividendTypeFor returns the dividend type a broker's activity label names,
+// for dividends and returns of capital, or "" when the label doesn't say.
+func dividendTypeFor(transactionType TransactionType, label string) DividendType {
+	switch transactionType {
+	case TransactionTypeReturnOfCapital:
+		return DividendTypeReturnOfCapital
+	case TransactionTypeDividend:
+	default:
+		return ""
+	}
+
+	label = strings.ToLower(label)
+	switch {
+	case strings.Contains(label, "199a"):
+		return DividendTypeSection199A
+	case strings.Contains(label, "exempt"):
+		return DividendTypeTaxExempt
+	case strings.Contains(label, "foreign"):
+		return DividendTypeForeign
+	case strings.Contains(label, "non-qualified"), strings.Contains(label, "nonqualified"),
+		strings.Contains(label, "non qualified"), strings.Contains(label, "ordinary"):
+		return DividendTypeOrdinary
+	case strings.Contains(label, "qualified"):
+		return DividendTypeQualified
+	default:
+		return ""
+	}
+}
+
+type PlanType string
+
+const (
+	PlanTypeRSU  PlanType = "rsu"
+	PlanTypeESPP PlanType = "espp"
+)
+
+// Compensation is the stock plan detail behind an rsu_vest or espp_purchase
+// transaction. Amounts cover all the shares in the transaction.
+type Compensation struct {
+	PlanType             PlanType
+	FMVMicros            int64     // market value on the vest or purchase date
+	SharesWithheldMicros int64     // rsu: shares withheld for taxes
+	GrantDate            time.Time // espp: offering date
+	GrantFMVMicros       int64     // espp: market value on the offering date
+	DiscountRate         float64   // espp: purchase price discount, e.g. 0.15
 }
 
 type Position struct {
@@ -47,23 +121,79 @@ type Position struct {
 	AsOfDate          time.Time
 }
 
+// CashBalance is the cash a positions file or statement reports on a date,
+// including the sweep or money market fund.
+type CashBalance struct {
+	AsOfDate      time.Time
+	BalanceMicros int64 // balance * 1,000,000
+	Description   string
+}
+
+type SkipReason string
+
+const (
+	SkipUnmapped     SkipReason = "unmapped"     // activity with no transaction type
+	SkipSweep        SkipReason = "sweep"        // cash moved to or from the sweep fund
+	SkipReinvestment SkipReason = "reinvestment" // cash leg of a dividend reinvestment
+	SkipForeignTax   SkipReason = "foreign_tax"  // foreign tax withheld from a dividend
+	SkipPlaceholder  SkipReason = "placeholder"  // entries that cancel out, e.g. due bills
+)
+
+// Diagnostic is an activity row a parser read but did not turn into a
+// transaction. Cash it moved never reaches the cash ledger, so diagnostics are
+// kept to explain drift from statement balances.
+type Diagnostic struct {
+	Line         int // line in the source file
+	Date         time.Time
+	Activity     string
+	Symbol       string
+	AmountMicros int64 // as exported: positive into the account, negative out
+	Description  string
+	Reason       SkipReason
+}
+
 type ImportResult struct {
 	ExternalAccountNumber string
 	Transactions          []Transaction
 	Positions             []Position
+	CashBalances          []CashBalance
+	Diagnostics           []Diagnostic
 }
 
 type Broker string
 
 const (
-	BrokerETrade   Broker = "etrade"
-	BrokerSchwab   Broker = "schwab"
-	BrokerFidelity Broker = "fidelity"
-	BrokerVanguard Broker = "vanguard"
-	BrokerLPL      Broker = "lpl"
-	BrokerMerrill  Broker = "merrill"
+	BrokerETrade          Broker = "etrade"
+	BrokerETradeStockPlan Broker = "etrade_stockplan"
+	BrokerSchwab          Broker = "schwab"
+	BrokerFidelity        Broker = "fidelity"
+	BrokerVanguard        Broker = "vanguard"
+	BrokerLPL             Broker = "lpl"
+	BrokerMerrill         Broker = "merrill"
 )
 
+// AmountIncludesFees reports whether a broker's exported amount is the net
+// cash that moved, with commissions and fees already taken out (sells) or
+// added in (buys). When it isn't, the lot processor adds buy fees to basis
+// and subtracts sell fees from proceeds.
+//
+// Every broker with a working parser exports net amounts:
+//   - E*TRADE: Amount is quantity times price plus the Commission column on
+//     buys, less it and any regulatory fees on sells, as the fixtures show.
+//   - E*TRADE Stock Plan, LPL and Merrill: no fee columns, so FeesMicros is
+//     zero and the amount is all there is.
+//
+// Schwab, Fidelity and Vanguard have no parser yet; they default to gross so
+// their fee columns are applied once one is written.
+func AmountIncludesFees(broker Broker) bool {
+	switch broker {
+	case BrokerETrade, BrokerETradeStockPlan, BrokerLPL, BrokerMerrill:
+		return true
+	default:
+		return false
+	}
+}
+
 type Parser interface {
 	Parse(ctx context.Context, r io.Reader) (*ImportResult, error)
 }
@@ -72,6 +202,8 @@ func GetParser(broker Broker) (Parser, error) {
 	switch broker {
 	case BrokerETrade:
 		return &ETradeParser{}, nil
+	case BrokerETradeStockPlan:
+		return &ETradeStockPlanParser{}, nil
 	case BrokerSchwab:
 		return &SchwabParser{}, nil
 	case BrokerFidelity:
@@ -0,0 +1,61 @@
+package importer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+// TestAmountIncludesFees checks the E*TRADE fixtures against the claim that
+// their amounts are net of commission: buys cost quantity times price plus the
+// commission, and sells bring in no more than quantity times price less it.
+func TestAmountIncludesFees(t *testing.T) {
+	if !importer.AmountIncludesFees(importer.BrokerETrade) {
+		t.Fatal("E*TRADE amounts should include fees")
+	}
+
+	files, err := filepath.Glob("testdata/etrade/*/transactions_*.csv")
+	if err != nil {
+		t.Fatalf("failed to list fixtures: %v", err)
+	}
+
+	const centMicros = 10_000
+	checked := 0
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", file, err)
+		}
+		result, err := (&importer.ETradeParser{}).Parse(context.Background(), f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+
+		for _, txn := range result.Transactions {
+			if txn.FeesMicros == 0 || txn.Option != nil {
+				continue
+			}
+			gross := txn.PriceMicros * (txn.QuantityMicros / 1_000) / 1_000
+			switch txn.TransactionType {
+			case importer.TransactionTypeBuy:
+				if diff := txn.AmountMicros - (gross + txn.FeesMicros); diff < -centMicros || diff > centMicros {
+					t.Errorf("%s %s %s: amount %d, want %d plus %d commission", file, txn.TransactionDate.Format("2006-01-02"), txn.Symbol, txn.AmountMicros, gross, txn.FeesMicros)
+				}
+			case importer.TransactionTypeSell:
+				if txn.AmountMicros > gross-txn.FeesMicros+centMicros {
+					t.Errorf("%s %s %s: amount %d, want at most %d less %d commission", file, txn.TransactionDate.Format("2006-01-02"), txn.Symbol, txn.AmountMicros, gross, txn.FeesMicros)
+				}
+			default:
+				continue
+			}
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no E*TRADE trades with a commission in the fixtures")
+	}
+}
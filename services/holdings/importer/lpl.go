@@ -19,8 +19,12 @@ func (p *LPLParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, erro
 	reader.LazyQuotes = true
 
 	var transactions []Transaction
+	var positions []Position
+	var cashBalances []CashBalance
+	var diagnostics []Diagnostic
 	var externalAccountNumber string
 	headerFound := false
+	positionsFound := false
 
 	for {
 		record, err := reader.Read()
@@ -41,6 +45,33 @@ func (p *LPLParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, erro
 			continue
 		}
 
+		// Positions export header: Account Number,Account Name,Account Nick Name,Symbol/CUSIP,Description,
+		// Quantity,Price ($),Day Change ($),Value ($),Price as Of,Unit Cost,Cost Basis ($),...,Security Type Description
+		if strings.TrimPrefix(record[0], "\ufeff") == "Account Number" {
+			positionsFound = true
+			continue
+		}
+
+		if positionsFound {
+			if len(record) < 12 {
+				continue
+			}
+			if externalAccountNumber == "" {
+				externalAccountNumber = strings.TrimSpace(record[0])
+			}
+			pos, cash, err := parseLPLPosition(record)
+			if err != nil {
+				continue
+			}
+			if pos != nil {
+				positions = append(positions, *pos)
+			}
+			if cash != nil {
+				cashBalances = append(cashBalances, *cash)
+			}
+			continue
+		}
+
 		if !headerFound {
 			continue
 		}
@@ -60,16 +91,80 @@ func (p *LPLParser) Parse(ctx context.Context, r io.Reader) (*ImportResult, erro
 		}
 		if txn != nil {
 			transactions = append(transactions, *txn)
+		} else if d, ok := lplDiagnostic(record); ok {
+			d.Line, _ = reader.FieldPos(0)
+			diagnostics = append(diagnostics, d)
 		}
 	}
 
 	return &ImportResult{
 		ExternalAccountNumber: externalAccountNumber,
 		Transactions:          transactions,
-		Positions:             nil,
+		Positions:             positions,
+		CashBalances:          cashBalances,
+		Diagnostics:           diagnostics,
 	}, nil
 }
 
+// parseLPLPosition reads a row of the positions export. The insured cash
+// account is reported as the cash balance rather than a position.
+func parseLPLPosition(record []string) (*Position, *CashBalance, error) {
+	symbol := strings.TrimSpace(record[3])
+	description := strings.TrimSpace(record[4])
+	quantity, _ := decimal.NewFromString(cleanLPLAmount(record[5]))
+	value, _ := decimal.NewFromString(cleanLPLAmount(record[8]))
+	costBasis, _ := decimal.NewFromString(cleanLPLAmount(record[11]))
+
+	// Price as of, e.g. "1/5/26 03:00 AM ET"
+	asOfStr, _, _ := strings.Cut(strings.TrimSpace(record[9]), " ")
+	date, err := time.Parse("1/2/06", asOfStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse date %s: %w", record[9], err)
+	}
+
+	if normalizeLPLSymbol(symbol) == "" {
+		return nil, &CashBalance{
+			AsOfDate:      date,
+			BalanceMicros: toMicros(value),
+			Description:   description,
+		}, nil
+	}
+
+	return &Position{
+		Symbol:            symbol,
+		SecurityName:      extractLPLSecurityName(description),
+		QuantityMicros:    toMicros(quantity),
+		CostBasisMicros:   toMicros(costBasis),
+		MarketValueMicros: toMicros(value),
+		AsOfDate:          date,
+	}, nil, nil
+}
+
+// lplDiagnostic describes a dated row parseLPLRow skipped: either activity
+// with no transaction type, or a buy or sell of the insured cash account.
+func lplDiagnostic(record []string) (Diagnostic, bool) {
+	date, err := time.Parse("1/2/2006", strings.TrimSpace(record[0]))
+	if err != nil {
+		return Diagnostic{}, false
+	}
+	activity := strings.ToLower(strings.TrimSpace(record[1]))
+	value, _ := decimal.NewFromString(cleanLPLAmount(record[6]))
+
+	reason := SkipUnmapped
+	if mapLPLTransactionType(activity, value) != "" {
+		reason = SkipSweep
+	}
+
+	return Diagnostic{
+		Date:         date,
+		Activity:     activity,
+		Symbol:       strings.TrimSpace(record[2]),
+		AmountMicros: toMicros(value),
+		Description:  strings.TrimSpace(record[3]),
+		Reason:       reason,
+	}, true
+}
+
 func parseLPLRow(record []string) (*Transaction, error) {
 	dateStr := strings.TrimSpace(record[0])
 	activity := strings.ToLower(strings.TrimSpace(record[1]))
@@ -110,6 +205,11 @@ func parseLPLRow(record []string) (*Transaction, error) {
 	// Clean up symbol
 	symbol = normalizeLPLSymbol(symbol)
 
+	var bond *BondTerms
+	if symbol != "" {
+		bond, _ = ParseBondDescription(description)
+	}
+
 	return &Transaction{
 		Symbol:          symbol,
 		SecurityName:    extractLPLSecurityName(description),
@@ -120,6 +220,8 @@ func parseLPLRow(record []string) (*Transaction, error) {
 		AmountMicros:    toMicros(value.Abs()),
 		FeesMicros:      0,
 		Description:     description,
+		DividendType:    dividendTypeFor(transactionType, activity),
+		Bond:            bond,
 	}, nil
 }
 
@@ -137,6 +239,8 @@ func mapLPLTransactionType(activity string, value decimal.Decimal) TransactionTy
 		return TransactionTypeBuy
 	case "cash dividend":
 		return TransactionTypeDividend
+	case "return of capital":
+		return TransactionTypeReturnOfCapital
 	case "interest":
 		return TransactionTypeInterest
 	case "reinvest interest":
@@ -152,6 +256,9 @@ func mapLPLTransactionType(activity string, value decimal.Decimal) TransactionTy
 		return TransactionTypeTransferOut
 	case "fee":
 		return TransactionTypeFee
+	case "redemption":
+		// Matured, called or pre-refunded; value is the principal repaid
+		return TransactionTypeRedemption
 	default:
 		return ""
 	}
@@ -25,6 +25,7 @@ func (p *MerrillParser) Parse(ctx context.Context, r io.Reader) (*ImportResult,
 	reader.TrimLeadingSpace = true
 
 	var transactions []Transaction
+	var diagnostics []Diagnostic
 	var externalAccountNumber string
 	headerFound := false
 
@@ -71,6 +72,9 @@ func (p *MerrillParser) Parse(ctx context.Context, r io.Reader) (*ImportResult,
 		}
 		if txn != nil {
 			transactions = append(transactions, *txn)
+		} else if d, ok := merrillDiagnostic(record); ok {
+			d.Line, _ = reader.FieldPos(0)
+			diagnostics = append(diagnostics, d)
 		}
 	}
 
@@ -78,6 +82,7 @@ func (p *MerrillParser) Parse(ctx context.Context, r io.Reader) (*ImportResult,
 		ExternalAccountNumber: externalAccountNumber,
 		Transactions:          transactions,
 		Positions:             nil,
+		Diagnostics:           diagnostics,
 	}, nil
 }
 
@@ -160,9 +165,20 @@ func parseMerrillRow(record []string) (*Transaction, error) {
 		AmountMicros:    toMicros(absAmount),
 		FeesMicros:      0,
 		Description:     description,
+		DividendType:    dividendTypeFor(transactionType, merrillDividendLabel(description)),
 	}, nil
 }
 
+// merrillDividendLabel returns the activity ahead of the security name in a
+// dividend's description, e.g. "Foreign Dividend" from "Foreign Dividend WASTE
+// CONNECTIONS INC HOLDING ...", so fund names don't read as dividend types.
+func merrillDividendLabel(description string) string {
+	if i := strings.Index(strings.ToLower(description), "dividend"); i >= 0 {
+		return description[:i+len("dividend")]
+	}
+	return description
+}
+
 func mapMerrillTransactionType(description string, quantity, amount decimal.Decimal) TransactionType {
 	desc := strings.ToLower(description)
 
@@ -174,6 +190,10 @@ func mapMerrillTransactionType(description string, quantity, amount decimal.Deci
 	case strings.HasPrefix(desc, "opening balance"):
 		return TransactionTypeOpeningBalance
 
+	// Checked before dividends: Merrill also labels these "Dividend ... Return of Capital"
+	case strings.Contains(desc, "return of capital"):
+		return TransactionTypeReturnOfCapital
+
 	// Stock split handling: "Dividend X HOLDING Y PAY DATE" with qty > 0 and amount = 0
 	case strings.HasPrefix(desc, "dividend ") && !quantity.IsZero() && amount.IsZero():
 		// Stock split shares - treat as buy with $0 cost
@@ -210,25 +230,68 @@ func mapMerrillTransactionType(description string, quantity, amount decimal.Deci
 		}
 		return TransactionTypeBuy
 
-	// Skip these:
-	case strings.HasPrefix(desc, "stock dividend due bill"):
-		// Temporary placeholder entries that cancel out - skip
+	// Securities delivered in or out (ACAT, journal between our accounts).
+	// Cash-only adjustments have no quantity and are skipped below.
+	case strings.HasPrefix(desc, "transfer / adjustment") && !quantity.IsZero():
+		if quantity.IsNegative() {
+			return TransactionTypeTransferOut
+		}
+		return TransactionTypeSecurityTransfer
+
+	// Skipped on purpose: see merrillSkipReason
+	default:
 		return ""
+	}
+}
+
+// merrillDiagnostic describes a dated row parseMerrillRow skipped.
+func merrillDiagnostic(record []string) (Diagnostic, bool) {
+	date, err := time.Parse("01/02/2006", strings.TrimSpace(record[0]))
+	if err != nil {
+		return Diagnostic{}, false
+	}
+	description := strings.TrimSpace(record[3])
+	symbol := strings.TrimSpace(record[5])
+	amount, _ := decimal.NewFromString(cleanMerrillAmount(record[8]))
+
+	reason := merrillSkipReason(description)
+	if symbol == "990286916" || strings.Contains(symbol, "TMCXX") {
+		reason = SkipSweep
+	}
+
+	return Diagnostic{
+		Date:         date,
+		Activity:     strings.TrimSpace(record[4]),
+		Symbol:       symbol,
+		AmountMicros: toMicros(amount),
+		Description:  description,
+		Reason:       reason,
+	}, true
+}
+
+// merrillSkipReason explains why mapMerrillTransactionType has no type for
+// a description.
+func merrillSkipReason(description string) SkipReason {
+	desc := strings.ToLower(description)
+
+	switch {
+	case strings.HasPrefix(desc, "stock dividend due bill"):
+		// Temporary placeholder entries that cancel out
+		return SkipPlaceholder
 	case strings.HasPrefix(desc, "reinvestment program"):
-		// Cash side of DRIP - skip (the "Reinvestment Share(s)" has the actual shares)
-		return ""
+		// Cash side of DRIP (the "Reinvestment Share(s)" has the actual shares)
+		return SkipReinvestment
 	case strings.HasPrefix(desc, "deposit ml bank"), strings.HasPrefix(desc, "withdrawal ml"):
-		// Internal cash sweep - skip
-		return ""
+		// Internal cash sweep
+		return SkipSweep
 	case strings.HasPrefix(desc, "check accumulation"):
-		// Cash sweep accumulation - skip
-		return ""
+		// Cash sweep accumulation
+		return SkipSweep
 	case strings.HasPrefix(desc, "foreign tax"):
-		// Foreign withholding tax - skip for now
-		return ""
-
+		// Foreign withholding tax
+		return SkipForeignTax
 	default:
-		return ""
+		return SkipUnmapped
 	}
 }
 
@@ -266,7 +329,7 @@ func extractMerrillSecurityName(description string) string {
 		"Short Term Capital Gain ", "Long Term Capital Gain ",
 		"Advisory Program Fee ",
 		"Reinvestment Share(s) ", "Reinvestment Program ",
-		"Redemption ", "Exchange ",
+		"Redemption ", "Exchange ", "Transfer / Adjustment ",
 	}
 
 	for _, prefix := range prefixes {
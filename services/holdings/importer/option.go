@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type OptionType string
+
+const (
+	OptionTypeCall OptionType = "call"
+	OptionTypePut  OptionType = "put"
+)
+
+// DefaultOptionMultiplier is the shares per standard equity option contract.
+const DefaultOptionMultiplier = 100
+
+// OptionContract is an option identified by its OCC symbol.
+type OptionContract struct {
+	Underlying   string
+	Expiration   time.Time
+	Type         OptionType
+	StrikeMicros int64
+	Multiplier   int64 // shares per contract
+}
+
+// occSymbol matches an OCC option symbol once spaces and dashes are removed:
+// root, expiration YYMMDD, C or P, and the strike times 1000 in 8 digits.
+var occSymbol = regexp.MustCompile(`^([A-Z0-9.]{1,6})(\d{6})([CP])(\d{8})$`)
+
+// ParseOCCSymbol reads an OCC option symbol such as "AAPL  240119C00150000".
+// Brokers pad the root with spaces or dashes ("AAPL--240119C00150000"), or
+// drop the padding. It reports false for anything else.
+func ParseOCCSymbol(s string) (*OptionContract, bool) {
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(strings.ToUpper(strings.TrimSpace(s)))
+	m := occSymbol.FindStringSubmatch(compact)
+	if m == nil {
+		return nil, false
+	}
+
+	expiration, err := time.Parse("060102", m[2])
+	if err != nil {
+		return nil, false
+	}
+	strike, err := strconv.ParseInt(m[4], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	optionType := OptionTypeCall
+	if m[3] == "P" {
+		optionType = OptionTypePut
+	}
+
+	return &OptionContract{
+		Underlying:   m[1],
+		Expiration:   expiration,
+		Type:         optionType,
+		StrikeMicros: strike * 1_000, // OCC strikes are in thousandths of a dollar
+		Multiplier:   DefaultOptionMultiplier,
+	}, true
+}
+
+// Symbol is the unpadded OCC symbol, e.g. AAPL240119C00150000.
+func (c *OptionContract) Symbol() string {
+	letter := "C"
+	if c.Type == OptionTypePut {
+		letter = "P"
+	}
+	return fmt.Sprintf("%s%s%s%08d", c.Underlying, c.Expiration.Format("060102"), letter, c.StrikeMicros/1_000)
+}
+
+// Name describes the contract the way brokers do, e.g. "AAPL Jan 19 2024 150 Call".
+func (c *OptionContract) Name() string {
+	strike := decimal.NewFromInt(c.StrikeMicros).Div(decimal.NewFromInt(microsMultiplier))
+	kind := "Call"
+	if c.Type == OptionTypePut {
+		kind = "Put"
+	}
+	return fmt.Sprintf("%s %s %s %s", c.Underlying, c.Expiration.Format("Jan 2 2006"), strike.String(), kind)
+}
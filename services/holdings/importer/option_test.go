@@ -0,0 +1,98 @@
+package importer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+func TestParseOCCSymbol(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   *importer.OptionContract
+	}{
+		{"AAPL  240119C00150000", &importer.OptionContract{Underlying: "AAPL", Expiration: date("2024-01-19"), Type: importer.OptionTypeCall, StrikeMicros: 150_000_000, Multiplier: 100}},
+		{"AAPL240119C00150000", &importer.OptionContract{Underlying: "AAPL", Expiration: date("2024-01-19"), Type: importer.OptionTypeCall, StrikeMicros: 150_000_000, Multiplier: 100}},
+		{"AAPL--240119P00150000", &importer.OptionContract{Underlying: "AAPL", Expiration: date("2024-01-19"), Type: importer.OptionTypePut, StrikeMicros: 150_000_000, Multiplier: 100}},
+		{"spy   241220p00450500", &importer.OptionContract{Underlying: "SPY", Expiration: date("2024-12-20"), Type: importer.OptionTypePut, StrikeMicros: 450_500_000, Multiplier: 100}},
+		{"BRK.B 250117C00400000", &importer.OptionContract{Underlying: "BRK.B", Expiration: date("2025-01-17"), Type: importer.OptionTypeCall, StrikeMicros: 400_000_000, Multiplier: 100}},
+		{"AAPL", nil},
+		{"037833100", nil},
+		{"AAPL  240119X00150000", nil},
+		{"AAPL  241319C00150000", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			got, ok := importer.ParseOCCSymbol(tt.symbol)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ok = %v, want %v", ok, tt.want != nil)
+			}
+			if tt.want == nil {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+
+	contract, _ := importer.ParseOCCSymbol("SPY   241220P00450500")
+	if got := contract.Symbol(); got != "SPY241220P00450500" {
+		t.Errorf("symbol = %q, want SPY241220P00450500", got)
+	}
+	if got := contract.Name(); got != "SPY Dec 20 2024 450.5 Put" {
+		t.Errorf("name = %q, want SPY Dec 20 2024 450.5 Put", got)
+	}
+}
+
+func TestETradeOptionActivity(t *testing.T) {
+	input := `TransactionDate,TransactionType,SecurityType,Symbol,Quantity,Amount,Price,Commission,Description
+01/02/24,Bought To Open,OPTN,AAPL  240119C00150000,2,-501.30,2.50,1.30,CALL AAPL 01/19/24 150
+01/03/24,Sold To Open,OPTN,AAPL--240119P00140000,-1,99.35,1.00,0.65,PUT AAPL 01/19/24 140
+01/10/24,Bought To Close,OPTN,AAPL240119P00140000,1,-50.65,0.50,0.65,PUT AAPL 01/19/24 140
+01/11/24,Sold To Close,OPTN,AAPL  240119C00150000,-1,0,3.00,0.65,CALL AAPL 01/19/24 150
+01/19/24,Option Expired,OPTN,AAPL  240119C00150000,-1,0,0,0,CALL AAPL 01/19/24 150
+01/19/24,Option Assigned,OPTN,AAPL  240119P00140000,1,0,0,0,PUT AAPL 01/19/24 140
+01/19/24,Option Exercised,OPTN,AAPL  240119C00150000,-1,0,0,0,CALL AAPL 01/19/24 150
+`
+	result, err := (&importer.ETradeParser{}).Parse(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want := []struct {
+		symbol  string
+		txnType importer.TransactionType
+		qty     int64
+		amount  int64
+	}{
+		{"AAPL240119C00150000", importer.TransactionTypeBuy, 2_000_000, 501_300_000},
+		{"AAPL240119P00140000", importer.TransactionTypeSellShort, 1_000_000, 99_350_000},
+		{"AAPL240119P00140000", importer.TransactionTypeBuyToCover, 1_000_000, 50_650_000},
+		// No amount exported: price per share times 100 shares a contract.
+		{"AAPL240119C00150000", importer.TransactionTypeSell, 1_000_000, 300_000_000},
+		{"AAPL240119C00150000", importer.TransactionTypeOptionExpiration, 1_000_000, 0},
+		{"AAPL240119P00140000", importer.TransactionTypeOptionAssignment, 1_000_000, 0},
+		{"AAPL240119C00150000", importer.TransactionTypeOptionExercise, 1_000_000, 0},
+	}
+	if len(result.Transactions) != len(want) {
+		t.Fatalf("got %d transactions, want %d", len(result.Transactions), len(want))
+	}
+	for i, w := range want {
+		got := result.Transactions[i]
+		if got.Symbol != w.symbol || got.TransactionType != w.txnType || got.QuantityMicros != w.qty || got.AmountMicros != w.amount {
+			t.Errorf("transaction %d = %s %s %d %d, want %s %s %d %d", i,
+				got.Symbol, got.TransactionType, got.QuantityMicros, got.AmountMicros,
+				w.symbol, w.txnType, w.qty, w.amount)
+		}
+		if got.Option == nil {
+			t.Errorf("transaction %d has no option contract", i)
+		}
+	}
+	if name := result.Transactions[0].SecurityName; name != "AAPL Jan 19 2024 150 Call" {
+		t.Errorf("security name = %q, want AAPL Jan 19 2024 150 Call", name)
+	}
+}
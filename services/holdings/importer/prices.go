@@ -0,0 +1,228 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price is a security's closing price on a day.
+type Price struct {
+	Symbol      string
+	Date        time.Time
+	CloseMicros int64
+}
+
+// Split is a stock split on its ex-date. Ratio is new shares per old share:
+// 2 for a 2-for-1 split, 0.1 for a 1-for-10 reverse split.
+type Split struct {
+	Date  time.Time
+	Ratio float64
+}
+
+// Dividend is a cash dividend per share on its ex-date.
+type Dividend struct {
+	Date         time.Time
+	AmountMicros int64
+}
+
+// priceColumns maps each field to the header names price downloads use for
+// it (Yahoo, Stooq, broker exports). Headers are compared after lowercasing
+// and stripping punctuation.
+var priceColumns = map[string][]string{
+	"symbol": {"symbol", "ticker", "cusip"},
+	"date":   {"date", "price date", "as of date"},
+	"close":  {"close", "close price", "closing price", "price", "last", "last price"},
+}
+
+var splitColumns = map[string][]string{
+	"date":  {"date", "ex date", "split date"},
+	"ratio": {"ratio", "split", "split ratio", "stock splits"},
+}
+
+var dividendColumns = map[string][]string{
+	"date":   {"date", "ex date", "ex dividend date"},
+	"amount": {"amount", "dividend", "dividends", "cash amount"},
+}
+
+// ParsePrices reads a CSV of daily closes with a header row naming at least
+// a date and close column. Files without a symbol column (one download per
+// security) take defaultSymbol. Rows that don't parse are skipped.
+func ParsePrices(r io.Reader, defaultSymbol string) ([]Price, error) {
+	columns, rows, err := readHeaderedCSV(r, priceColumns, "date", "close")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := columns["symbol"]; !ok && defaultSymbol == "" {
+		return nil, fmt.Errorf("price CSV has no symbol column; pass the symbol")
+	}
+
+	var prices []Price
+	for _, record := range rows {
+		price, ok := parsePriceRow(record, columns, defaultSymbol)
+		if ok {
+			prices = append(prices, price)
+		}
+	}
+	return prices, nil
+}
+
+// ParseSplits reads a CSV of one security's splits with date and ratio
+// columns. Ratios are written "2:1", "2/1", "2 for 1" or as a number.
+func ParseSplits(r io.Reader) ([]Split, error) {
+	columns, rows, err := readHeaderedCSV(r, splitColumns, "date", "ratio")
+	if err != nil {
+		return nil, err
+	}
+
+	var splits []Split
+	for _, record := range rows {
+		date, err := parse1099BDate(csvField(record, columns, "date"))
+		if err != nil || date.IsZero() {
+			continue
+		}
+		ratio, ok := parseSplitRatio(csvField(record, columns, "ratio"))
+		if !ok {
+			continue
+		}
+		splits = append(splits, Split{Date: date, Ratio: ratio})
+	}
+	return splits, nil
+}
+
+// ParseDividends reads a CSV of one security's cash dividends per share with
+// date and amount columns.
+func ParseDividends(r io.Reader) ([]Dividend, error) {
+	columns, rows, err := readHeaderedCSV(r, dividendColumns, "date", "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	var dividends []Dividend
+	for _, record := range rows {
+		date, err := parse1099BDate(csvField(record, columns, "date"))
+		if err != nil || date.IsZero() {
+			continue
+		}
+		amount, err := parse1099BAmount(csvField(record, columns, "amount"))
+		if err != nil || !amount.IsPositive() {
+			continue
+		}
+		dividends = append(dividends, Dividend{Date: date, AmountMicros: toMicros(amount)})
+	}
+	return dividends, nil
+}
+
+// readHeaderedCSV finds the header row naming the required fields and returns
+// the column of each field it names and the records after it.
+func readHeaderedCSV(r io.Reader, aliases map[string][]string, required ...string) (map[string]int, [][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var columns map[string]int
+	var rows [][]string
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		if columns == nil {
+			columns = matchHeader(record, aliases, required)
+			continue
+		}
+		rows = append(rows, record)
+	}
+
+	if columns == nil {
+		return nil, nil, fmt.Errorf("no %s header row found", strings.Join(required, "/"))
+	}
+	return columns, rows, nil
+}
+
+// matchHeader returns the column index of each known field, or nil if the
+// record doesn't name every required field.
+func matchHeader(record []string, aliases map[string][]string, required []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range record {
+		cleaned := strings.TrimSpace(headerCleaner.ReplaceAllString(strings.ToLower(name), " "))
+		for field, names := range aliases {
+			if _, ok := columns[field]; ok {
+				continue
+			}
+			for _, alias := range names {
+				if cleaned == alias {
+					columns[field] = i
+					break
+				}
+			}
+		}
+	}
+
+	for _, field := range required {
+		if _, ok := columns[field]; !ok {
+			return nil
+		}
+	}
+	return columns
+}
+
+func csvField(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parsePriceRow(record []string, columns map[string]int, defaultSymbol string) (Price, bool) {
+	symbol := strings.ToUpper(csvField(record, columns, "symbol"))
+	if symbol == "" {
+		symbol = defaultSymbol
+	}
+
+	date, err := parse1099BDate(csvField(record, columns, "date"))
+	if err != nil || date.IsZero() {
+		return Price{}, false
+	}
+
+	closePrice, err := parse1099BAmount(csvField(record, columns, "close"))
+	if err != nil || !closePrice.IsPositive() {
+		return Price{}, false
+	}
+
+	return Price{Symbol: symbol, Date: date, CloseMicros: toMicros(closePrice)}, true
+}
+
+// parseSplitRatio reads "3:2", "3/2", "3 for 2" or "1.5" as new shares per
+// old share.
+func parseSplitRatio(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer("-for-", ":", " for ", ":", "/", ":").Replace(s)
+
+	newShares, oldShares, found := strings.Cut(s, ":")
+	if !found {
+		oldShares = "1"
+	}
+	n, err := decimal.NewFromString(strings.TrimSpace(newShares))
+	if err != nil || !n.IsPositive() {
+		return 0, false
+	}
+	d, err := decimal.NewFromString(strings.TrimSpace(oldShares))
+	if err != nil || !d.IsPositive() {
+		return 0, false
+	}
+	return n.Div(d).InexactFloat64(), true
+}
@@ -0,0 +1,132 @@
+package income
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// Form1099DIV approximates the boxes of an account's 1099-DIV for a year, to
+// check against the broker's form. Dividends count in the year they were
+// paid, so a dividend declared in December and paid in January can land a
+// year off the form (IRC 852(b)(7)).
+type Form1099DIV struct {
+	AccountID   string
+	AccountName string
+	Year        int
+
+	OrdinaryDividendsMicros  int64 // 1a: taxable dividends, including 1b and 5
+	QualifiedDividendsMicros int64 // 1b
+	CapitalGainsMicros       int64 // 2a
+	NondividendMicros        int64 // 3: return of capital
+	Section199AMicros        int64 // 5
+	ForeignTaxMicros         int64 // 7: foreign tax withheld, from skipped import rows
+	ExemptInterestMicros     int64 // 12: exempt-interest dividends
+
+	// EstimatedMicros is the dividends the broker didn't give a type for,
+	// classified from the security's name, so 1a and 1b may be off by up to
+	// this much.
+	EstimatedMicros int64
+}
+
+// Form1099DIV estimates a 1099-DIV for accountID, or for each account when
+// it's empty, for year.
+func (r *Reporter) Form1099DIV(ctx context.Context, accountID string, year int) ([]Form1099DIV, error) {
+	start, end := YearRange(year, time.Now())
+	rows, err := r.Activity(ctx, accountID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignTax := make(map[string]int64)
+	accounts := make(map[string]bool)
+	for _, row := range rows {
+		if accounts[row.AccountID] {
+			continue
+		}
+		accounts[row.AccountID] = true
+
+		diagnostics, err := r.queries.ListImportDiagnostics(ctx, db.ListImportDiagnosticsParams{
+			AccountID: row.AccountID,
+			StartDate: start.AddDate(0, 0, -1).Format("2006-01-02"),
+			EndDate:   end.Format("2006-01-02"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list import diagnostics: %w", err)
+		}
+		for _, d := range diagnostics {
+			if d.Reason == "foreign_tax" {
+				foreignTax[row.AccountID] -= d.AmountMicros
+			}
+		}
+	}
+
+	return Estimate1099DIV(rows, foreignTax, year), nil
+}
+
+// Estimate1099DIV totals rows from one year into a form per account.
+// foreignTax is the tax withheld per account, as a positive amount.
+func Estimate1099DIV(rows []db.ListIncomeActivityRow, foreignTax map[string]int64, year int) []Form1099DIV {
+	forms := make(map[string]*Form1099DIV)
+	for _, row := range rows {
+		category, ok := Classify(row)
+		if !ok {
+			continue
+		}
+
+		f, ok := forms[row.AccountID]
+		if !ok {
+			f = &Form1099DIV{
+				AccountID:        row.AccountID,
+				AccountName:      row.AccountName,
+				Year:             year,
+				ForeignTaxMicros: foreignTax[row.AccountID],
+			}
+			forms[row.AccountID] = f
+		}
+
+		switch category {
+		case CategoryQualifiedDividend:
+			f.OrdinaryDividendsMicros += row.AmountMicros
+			f.QualifiedDividendsMicros += row.AmountMicros
+		case CategoryOrdinaryDividend:
+			f.OrdinaryDividendsMicros += row.AmountMicros
+			if row.DividendType.String == "section_199a" {
+				f.Section199AMicros += row.AmountMicros
+			}
+		case CategoryExemptDividend:
+			f.ExemptInterestMicros += row.AmountMicros
+		case CategoryCapitalGain:
+			f.CapitalGainsMicros += row.AmountMicros
+		case CategoryReturnOfCapital:
+			f.NondividendMicros += row.AmountMicros
+		default:
+			continue
+		}
+
+		if row.CashType == "dividend" && !typedDividend(row.DividendType.String) {
+			f.EstimatedMicros += row.AmountMicros
+		}
+	}
+
+	out := make([]Form1099DIV, 0, len(forms))
+	for _, f := range forms {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AccountName < out[j].AccountName })
+	return out
+}
+
+// typedDividend reports whether a dividend type settles how the dividend is
+// taxed. Foreign dividends may still be qualified or not.
+func typedDividend(dividendType string) bool {
+	switch dividendType {
+	case "qualified", "ordinary", "section_199a", "tax_exempt", "return_of_capital":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,59 @@
+package income_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+func typed(row db.ListIncomeActivityRow, dividendType string) db.ListIncomeActivityRow {
+	row.DividendType = sql.NullString{String: dividendType, Valid: true}
+	return row
+}
+
+func TestEstimate1099DIV(t *testing.T) {
+	rows := []db.ListIncomeActivityRow{
+		typed(activity("2025-03-14", "dividend", 100_000_000, "AAPL", "APPLE INC", 0, ""), "qualified"),
+		typed(activity("2025-03-31", "dividend", 40_000_000, "O", "REALTY INCOME CORP", 0, ""), "section_199a"),
+		typed(activity("2025-04-30", "dividend", 30_000_000, "VTEB", "VANGUARD TAX-EXEMPT BOND ETF", 0, ""), "tax_exempt"),
+		typed(activity("2025-06-30", "dividend", 20_000_000, "BND", "VANGUARD TOTAL BOND MARKET", 0, ""), "ordinary"),
+		typed(activity("2025-09-30", "return_of_capital", 5_000_000, "O", "REALTY INCOME CORP", 0, ""), "return_of_capital"),
+		activity("2025-06-15", "dividend", 50_000_000, "MSFT", "MICROSOFT CORP", 0, ""),
+		typed(activity("2025-07-15", "dividend", 10_000_000, "NVS", "NOVARTIS AG ADR", 0, ""), "foreign"),
+		activity("2025-12-15", "cap_gain", 25_000_000, "VTI", "VANGUARD TOTAL STOCK MARKET", 0, ""),
+		activity("2025-07-01", "interest", 625_000_000, "544525ZV3", "LOS ANGELES CA DPT WTR & PWR", 0.05, ""),
+	}
+
+	forms := income.Estimate1099DIV(rows, map[string]int64{"acct": 1_500_000}, 2025)
+	if len(forms) != 1 {
+		t.Fatalf("expected one form, got %+v", forms)
+	}
+	f := forms[0]
+
+	if f.AccountName != "Joint" || f.Year != 2025 {
+		t.Errorf("unexpected form identity: %+v", f)
+	}
+	if f.OrdinaryDividendsMicros != 220_000_000 {
+		t.Errorf("expected $220 in 1a, got %d", f.OrdinaryDividendsMicros)
+	}
+	if f.QualifiedDividendsMicros != 160_000_000 {
+		t.Errorf("expected $160 in 1b, got %d", f.QualifiedDividendsMicros)
+	}
+	if f.CapitalGainsMicros != 25_000_000 || f.NondividendMicros != 5_000_000 {
+		t.Errorf("expected $25 in 2a and $5 in 3, got %d and %d", f.CapitalGainsMicros, f.NondividendMicros)
+	}
+	if f.Section199AMicros != 40_000_000 {
+		t.Errorf("expected $40 in 5, got %d", f.Section199AMicros)
+	}
+	if f.ForeignTaxMicros != 1_500_000 {
+		t.Errorf("expected $1.50 in 7, got %d", f.ForeignTaxMicros)
+	}
+	if f.ExemptInterestMicros != 30_000_000 {
+		t.Errorf("expected fund dividends but not muni coupons in 12, got %d", f.ExemptInterestMicros)
+	}
+	if f.EstimatedMicros != 60_000_000 {
+		t.Errorf("expected the untyped and foreign dividends to be estimated, got %d", f.EstimatedMicros)
+	}
+}
@@ -0,0 +1,405 @@
+// Package income rolls up dividends, interest, fees and distributions from
+// cash transactions by period, security and account.
+package income
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+type Category string
+
+const (
+	CategoryQualifiedDividend Category = "qualified_dividend"
+	CategoryOrdinaryDividend  Category = "ordinary_dividend"
+	CategoryExemptDividend    Category = "exempt_dividend" // exempt-interest dividends from muni funds
+	CategoryCapitalGain       Category = "cap_gain"
+	CategoryBondInterest      Category = "bond_interest"  // taxable coupons
+	CategoryMuniInterest      Category = "muni_interest"  // tax-exempt coupons
+	CategorySweepInterest     Category = "sweep_interest" // cash, bank deposit and money market interest
+	CategoryReturnOfCapital   Category = "return_of_capital"
+	CategoryFee               Category = "fee"
+	CategoryDistribution      Category = "distribution" // cash sent to linked accounts
+)
+
+type Grouping string
+
+const (
+	GroupByMonth   Grouping = "month"
+	GroupByQuarter Grouping = "quarter"
+	GroupByYear    Grouping = "year"
+)
+
+var (
+	// Most exports don't say whether a fund's dividends are qualified, and
+	// none say whether a bond's interest is exempt, so both are estimated
+	// from names when the dividend type isn't known.
+	exemptFundName  = regexp.MustCompile(`\b(MUNI|MUNICIPAL|TAX[- ]?FREE|TAX[- ]?EXEMPT)\b`)
+	interestFund    = regexp.MustCompile(`\b(BOND|BD|FLOATING|FLTG|YIELD|CREDIT|MORTGAGE|TREASURY|TREAS|IBONDS|MONEY|REAL ESTATE|REIT)\b`)
+	corporateIssuer = regexp.MustCompile(`\b(CORP|INC|LLC|PLC|LTD|COMPANY|HLDGS|HOLDINGS|GROUP)\b`)
+	agencyIssuer    = regexp.MustCompile(`\b(FEDERAL|FED|FNMA|FHLMC|FHLB|GNMA|TENNESSEE VALLEY|RESOLUTION FDG|PRIVATE EXPT)\b`)
+	municipalIssuer = regexp.MustCompile(`\b(STATE|ST OF|COMWLTH|COMMONWEALTH|CNTY|COUNTY|CITY|TWP|TOWNSHIP|VLG|VILLAGE|BORO|BOROUGH|PARISH|MUN|MUNI|MUNICIPAL|AUTH|AUTHORITY|DIST|DISTRICT|SCH|SCHOOL|ISD|USD|REV|REVENUE|RFDG|REFUNDING|GO|G O|HSG|HOUSING|FING|WTR|SWR|PWR|UTIL|TPK|TURNPIKE|TRANSIT|TRANSITIONAL|ARPT|AIRPORT|PORT|HOSP|FACS|IMPT|TOB|PRE-?REFUNDED)\b`)
+	stateIssuer     = regexp.MustCompile(`\b(ALABAMA|ALASKA|ARIZONA|ARKANSAS|CALIFORNIA|COLORADO|CONNECTICUT|DELAWARE|FLORIDA|GEORGIA|HAWAII|IDAHO|ILLINOIS|INDIANA|IOWA|KANSAS|KENTUCKY|LOUISIANA|MAINE|MARYLAND|MASSACHUSETTS|MICHIGAN|MINNESOTA|MISSISSIPPI|MISSOURI|MONTANA|NEBRASKA|NEVADA|NEW HAMPSHIRE|NEW JERSEY|NEW MEXICO|NEW YORK|NORTH CAROLINA|NORTH DAKOTA|OHIO|OKLAHOMA|OREGON|PENNSYLVANIA|RHODE ISLAND|SOUTH CAROLINA|SOUTH DAKOTA|TENNESSEE|TEXAS|UTAH|VERMONT|VIRGINIA|WASHINGTON|WEST VIRGINIA|WISCONSIN|WYOMING) ST\b`)
+	sweepAccount    = regexp.MustCompile(`(INSURED CASH|BANK DEPOSIT|SWEEP|MONEY MARKET)`)
+)
+
+// Classify sorts a cash transaction into an income category, by the dividend
+// type the broker reported when there is one. Purchases, proceeds, deposits
+// and transfers between the account and its own sweep fund aren't income and
+// report false.
+func Classify(row db.ListIncomeActivityRow) (Category, bool) {
+	name := strings.ToUpper(row.SecurityName.String)
+	description := strings.ToUpper(row.Description.String)
+
+	switch row.CashType {
+	case "dividend":
+		switch row.DividendType.String {
+		case "qualified":
+			return CategoryQualifiedDividend, true
+		case "ordinary", "section_199a":
+			return CategoryOrdinaryDividend, true
+		case "tax_exempt":
+			return CategoryExemptDividend, true
+		case "return_of_capital":
+			return CategoryReturnOfCapital, true
+		}
+		// Foreign dividends may be qualified or not; estimate like the rest.
+		switch {
+		case exemptFundName.MatchString(name):
+			return CategoryExemptDividend, true
+		case interestFund.MatchString(name):
+			return CategoryOrdinaryDividend, true
+		default:
+			return CategoryQualifiedDividend, true
+		}
+	case "interest":
+		if !row.CouponRate.Valid {
+			return CategorySweepInterest, true
+		}
+		if municipal(row.Symbol.String, name) {
+			return CategoryMuniInterest, true
+		}
+		return CategoryBondInterest, true
+	case "cap_gain":
+		return CategoryCapitalGain, true
+	case "return_of_capital":
+		return CategoryReturnOfCapital, true
+	case "fee":
+		return CategoryFee, true
+	case "transfer_out", "withdrawal":
+		if sweepAccount.MatchString(description) {
+			return "", false
+		}
+		return CategoryDistribution, true
+	default:
+		return "", false
+	}
+}
+
+// municipal reports whether a bond's coupons are presumed tax-exempt: the
+// issuer is named like a state or local government or one of its authorities
+// or districts. Treasuries (CUSIPs starting 912), agencies, companies and
+// anything unrecognized are taxable.
+func municipal(symbol, name string) bool {
+	if strings.HasPrefix(symbol, "912") || agencyIssuer.MatchString(name) || corporateIssuer.MatchString(name) {
+		return false
+	}
+	return municipalIssuer.MatchString(name) || stateIssuer.MatchString(name)
+}
+
+// Totals are amounts by category. Income and capital gains are positive;
+// fees and distributions negative. Return of capital isn't income and is
+// left out of the income totals.
+type Totals struct {
+	QualifiedDividendsMicros int64
+	OrdinaryDividendsMicros  int64
+	ExemptDividendsMicros    int64
+	CapitalGainsMicros       int64
+	BondInterestMicros       int64
+	MuniInterestMicros       int64
+	SweepInterestMicros      int64
+	ReturnOfCapitalMicros    int64
+	FeesMicros               int64
+	DistributionsMicros      int64
+}
+
+func (t *Totals) Add(category Category, amountMicros int64) {
+	switch category {
+	case CategoryQualifiedDividend:
+		t.QualifiedDividendsMicros += amountMicros
+	case CategoryOrdinaryDividend:
+		t.OrdinaryDividendsMicros += amountMicros
+	case CategoryExemptDividend:
+		t.ExemptDividendsMicros += amountMicros
+	case CategoryCapitalGain:
+		t.CapitalGainsMicros += amountMicros
+	case CategoryBondInterest:
+		t.BondInterestMicros += amountMicros
+	case CategoryMuniInterest:
+		t.MuniInterestMicros += amountMicros
+	case CategorySweepInterest:
+		t.SweepInterestMicros += amountMicros
+	case CategoryReturnOfCapital:
+		t.ReturnOfCapitalMicros += amountMicros
+	case CategoryFee:
+		t.FeesMicros += amountMicros
+	case CategoryDistribution:
+		t.DistributionsMicros += amountMicros
+	}
+}
+
+func (t Totals) DividendsMicros() int64 {
+	return t.QualifiedDividendsMicros + t.OrdinaryDividendsMicros + t.ExemptDividendsMicros
+}
+
+func (t Totals) InterestMicros() int64 {
+	return t.BondInterestMicros + t.MuniInterestMicros + t.SweepInterestMicros
+}
+
+// IncomeMicros is dividends, interest and capital gain distributions.
+func (t Totals) IncomeMicros() int64 {
+	return t.DividendsMicros() + t.InterestMicros() + t.CapitalGainsMicros
+}
+
+// TaxExemptMicros is muni coupons and exempt-interest dividends.
+func (t Totals) TaxExemptMicros() int64 {
+	return t.MuniInterestMicros + t.ExemptDividendsMicros
+}
+
+// NetMicros is income after fees.
+func (t Totals) NetMicros() int64 {
+	return t.IncomeMicros() + t.FeesMicros
+}
+
+// Period is a month, quarter or year of activity, with the year-to-date
+// totals through its end.
+type Period struct {
+	Label     string // e.g. 2024-03, 2024-Q1 or 2024
+	StartDate string
+	EndDate   string
+	Totals    Totals
+	YTD       Totals
+}
+
+type AccountTotals struct {
+	AccountID   string
+	AccountName string
+	Totals      Totals
+}
+
+type Summary struct {
+	StartDate string
+	EndDate   string
+	Totals    Totals
+	Periods   []Period
+	Accounts  []AccountTotals
+}
+
+// SecurityIncome is what one security paid in one category. A security with
+// more than one kind of income, e.g. dividends and capital gains, has a line
+// for each.
+type SecurityIncome struct {
+	SecurityID   string
+	Symbol       string
+	Name         string
+	Category     Category
+	Payments     int
+	AmountMicros int64
+	LastPaid     string
+}
+
+type Reporter struct {
+	queries *db.Queries
+}
+
+func NewReporter(queries *db.Queries) *Reporter {
+	return &Reporter{queries: queries}
+}
+
+// Activity lists the cash transactions between start and end, inclusive, for
+// accountID or every account when it's empty.
+func (r *Reporter) Activity(ctx context.Context, accountID string, start, end time.Time) ([]db.ListIncomeActivityRow, error) {
+	rows, err := r.queries.ListIncomeActivity(ctx, db.ListIncomeActivityParams{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list income activity: %w", err)
+	}
+	if accountID == "" {
+		return rows, nil
+	}
+
+	var filtered []db.ListIncomeActivityRow
+	for _, row := range rows {
+		if row.AccountID == accountID {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered, nil
+}
+
+// Summarize totals rows by category, by period and by account. Year-to-date
+// totals restart with each calendar year.
+func Summarize(rows []db.ListIncomeActivityRow, start, end time.Time, grouping Grouping) *Summary {
+	summary := &Summary{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+	}
+
+	periods := make(map[string]*Period)
+	accounts := make(map[string]*AccountTotals)
+	for _, row := range rows {
+		category, ok := Classify(row)
+		if !ok {
+			continue
+		}
+		summary.Totals.Add(category, row.AmountMicros)
+
+		date, err := time.Parse("2006-01-02", row.TransactionDate)
+		if err != nil {
+			continue
+		}
+		label, periodStart, periodEnd := periodOf(date, grouping)
+		p, ok := periods[label]
+		if !ok {
+			p = &Period{
+				Label:     label,
+				StartDate: periodStart.Format("2006-01-02"),
+				EndDate:   periodEnd.Format("2006-01-02"),
+			}
+			periods[label] = p
+		}
+		p.Totals.Add(category, row.AmountMicros)
+
+		a, ok := accounts[row.AccountID]
+		if !ok {
+			a = &AccountTotals{AccountID: row.AccountID, AccountName: row.AccountName}
+			accounts[row.AccountID] = a
+		}
+		a.Totals.Add(category, row.AmountMicros)
+	}
+
+	for _, p := range periods {
+		summary.Periods = append(summary.Periods, *p)
+	}
+	sort.Slice(summary.Periods, func(i, j int) bool { return summary.Periods[i].StartDate < summary.Periods[j].StartDate })
+
+	var ytd Totals
+	year := ""
+	for i := range summary.Periods {
+		p := &summary.Periods[i]
+		if p.StartDate[:4] != year {
+			ytd, year = Totals{}, p.StartDate[:4]
+		}
+		ytd = ytd.plus(p.Totals)
+		p.YTD = ytd
+	}
+
+	for _, a := range accounts {
+		summary.Accounts = append(summary.Accounts, *a)
+	}
+	sort.Slice(summary.Accounts, func(i, j int) bool { return summary.Accounts[i].AccountName < summary.Accounts[j].AccountName })
+
+	return summary
+}
+
+// BySecurity totals income per security and category, largest first. Fees and
+// distributions aren't tied to a security and are left out.
+func BySecurity(rows []db.ListIncomeActivityRow) []SecurityIncome {
+	type key struct {
+		securityID string
+		category   Category
+	}
+	lines := make(map[key]*SecurityIncome)
+	for _, row := range rows {
+		category, ok := Classify(row)
+		if !ok || category == CategoryFee || category == CategoryDistribution {
+			continue
+		}
+
+		k := key{securityID: row.SecurityID.String, category: category}
+		line, ok := lines[k]
+		if !ok {
+			line = &SecurityIncome{
+				SecurityID: row.SecurityID.String,
+				Symbol:     row.Symbol.String,
+				Name:       row.SecurityName.String,
+				Category:   category,
+			}
+			lines[k] = line
+		}
+		line.Payments++
+		line.AmountMicros += row.AmountMicros
+		if row.TransactionDate > line.LastPaid {
+			line.LastPaid = row.TransactionDate
+		}
+	}
+
+	out := make([]SecurityIncome, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, *line)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AmountMicros != out[j].AmountMicros {
+			return out[i].AmountMicros > out[j].AmountMicros
+		}
+		if out[i].Symbol != out[j].Symbol {
+			return out[i].Symbol < out[j].Symbol
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out
+}
+
+// YearRange is the span of a calendar year, through now for the current year.
+// Year 0 is everything through now.
+func YearRange(year int, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if year == 0 {
+		return time.Time{}, today
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, -1)
+	if end.After(today) && !start.After(today) {
+		end = today
+	}
+	return start, end
+}
+
+func periodOf(date time.Time, grouping Grouping) (string, time.Time, time.Time) {
+	year := date.Year()
+	switch grouping {
+	case GroupByQuarter:
+		q := (int(date.Month())-1)/3 + 1
+		start := time.Date(year, time.Month(q*3-2), 1, 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("%d-Q%d", year, q), start, start.AddDate(0, 3, -1)
+	case GroupByYear:
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("%d", year), start, start.AddDate(1, 0, -1)
+	default:
+		start := time.Date(year, date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start, start.AddDate(0, 1, -1)
+	}
+}
+
+func (t Totals) plus(o Totals) Totals {
+	return Totals{
+		QualifiedDividendsMicros: t.QualifiedDividendsMicros + o.QualifiedDividendsMicros,
+		OrdinaryDividendsMicros:  t.OrdinaryDividendsMicros + o.OrdinaryDividendsMicros,
+		ExemptDividendsMicros:    t.ExemptDividendsMicros + o.ExemptDividendsMicros,
+		CapitalGainsMicros:       t.CapitalGainsMicros + o.CapitalGainsMicros,
+		BondInterestMicros:       t.BondInterestMicros + o.BondInterestMicros,
+		MuniInterestMicros:       t.MuniInterestMicros + o.MuniInterestMicros,
+		SweepInterestMicros:      t.SweepInterestMicros + o.SweepInterestMicros,
+		ReturnOfCapitalMicros:    t.ReturnOfCapitalMicros + o.ReturnOfCapitalMicros,
+		FeesMicros:               t.FeesMicros + o.FeesMicros,
+		DistributionsMicros:      t.DistributionsMicros + o.DistributionsMicros,
+	}
+}
@@ -0,0 +1,153 @@
+package income_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+func activity(date, cashType string, amount int64, symbol, name string, coupon float64, description string) db.ListIncomeActivityRow {
+	row := db.ListIncomeActivityRow{
+		AccountID:       "acct",
+		AccountName:     "Joint",
+		TransactionDate: date,
+		CashType:        cashType,
+		AmountMicros:    amount,
+		Description:     sql.NullString{String: description, Valid: description != ""},
+	}
+	if symbol != "" {
+		row.SecurityID = sql.NullString{String: "sec_" + symbol, Valid: true}
+		row.Symbol = sql.NullString{String: symbol, Valid: true}
+		row.SecurityName = sql.NullString{String: name, Valid: true}
+	}
+	if coupon > 0 {
+		row.CouponRate = sql.NullFloat64{Float64: coupon, Valid: true}
+	}
+	return row
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		row  db.ListIncomeActivityRow
+		want income.Category
+		ok   bool
+	}{
+		{"stock dividend", activity("2024-03-15", "dividend", 1, "AAPL", "APPLE INC", 0, ""), income.CategoryQualifiedDividend, true},
+		{"bond fund dividend", activity("2024-03-01", "dividend", 1, "BND", "VANGUARD TOTAL BOND MARKET ETF", 0, ""), income.CategoryOrdinaryDividend, true},
+		{"muni fund dividend", activity("2024-03-01", "dividend", 1, "MUB", "ISHARES NATIONAL MUNI BOND ETF", 0, ""), income.CategoryExemptDividend, true},
+		{"ordinary stock dividend", typed(activity("2024-03-15", "dividend", 1, "AAPL", "APPLE INC", 0, ""), "ordinary"), income.CategoryOrdinaryDividend, true},
+		{"qualified fund dividend", typed(activity("2024-03-01", "dividend", 1, "BND", "VANGUARD TOTAL BOND MARKET ETF", 0, ""), "qualified"), income.CategoryQualifiedDividend, true},
+		{"foreign dividend", typed(activity("2024-03-01", "dividend", 1, "NVS", "NOVARTIS AG ADR", 0, ""), "foreign"), income.CategoryQualifiedDividend, true},
+		{"muni coupon", activity("2024-07-01", "interest", 1, "544525ZV3", "LOS ANGELES CA DPT WTR & PWR", 5, ""), income.CategoryMuniInterest, true},
+		{"treasury coupon", activity("2024-05-15", "interest", 1, "91282CJL6", "UNITED STATES TREAS NTS", 4.375, ""), income.CategoryBondInterest, true},
+		{"state coupon", activity("2024-04-01", "interest", 1, "13063DRK6", "CALIFORNIA ST", 5, ""), income.CategoryMuniInterest, true},
+		{"authority coupon", activity("2024-03-01", "interest", 1, "73358WAG9", "PORT AUTH N Y & N J", 3.5, ""), income.CategoryMuniInterest, true},
+		{"agency coupon", activity("2024-03-01", "interest", 1, "3130AXYZ1", "FEDERAL HOME LN BKS", 4.5, ""), income.CategoryBondInterest, true},
+		{"federally chartered agency coupon", activity("2024-03-01", "interest", 1, "880591EZ1", "TENNESSEE VALLEY AUTH", 4.25, ""), income.CategoryBondInterest, true},
+		{"unrecognized issuer coupon", activity("2024-03-01", "interest", 1, "459058KA0", "INTL BK RECON & DEV", 3.5, ""), income.CategoryBondInterest, true},
+		{"corporate coupon", activity("2024-04-01", "interest", 1, "037833DX5", "APPLE INC", 2.2, ""), income.CategoryBondInterest, true},
+		{"sweep interest", activity("2024-03-29", "interest", 1, "", "", 0, "INSURED CASH ACCOUNT"), income.CategorySweepInterest, true},
+		{"advisory fee", activity("2024-04-01", "fee", -1, "", "", 0, "ADVISORY FEE"), income.CategoryFee, true},
+		{"distribution", activity("2024-06-03", "transfer_out", -1, "", "", 0, "INCOME DISTRIBUTION TRF TO AC#1234"), income.CategoryDistribution, true},
+		{"sweep transfer", activity("2024-06-03", "transfer_out", -1, "", "", 0, "INSURED CASH ACCOUNT"), "", false},
+		{"purchase", activity("2024-06-03", "purchase", -1, "AAPL", "APPLE INC", 0, ""), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := income.Classify(tt.row)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	rows := []db.ListIncomeActivityRow{
+		activity("2023-12-15", "dividend", 50_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2024-01-31", "interest", 2_000_000, "", "", 0, "INSURED CASH ACCOUNT"),
+		activity("2024-02-15", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2024-03-01", "dividend", 30_000_000, "MUB", "ISHARES NATIONAL MUNI BOND ETF", 0, ""),
+		activity("2024-04-01", "fee", -25_000_000, "", "", 0, "ADVISORY FEE"),
+		activity("2024-04-01", "interest", 250_000_000, "544525ZV3", "LOS ANGELES CA DPT WTR & PWR", 5, ""),
+		activity("2024-05-01", "transfer_out", -200_000_000, "", "", 0, "INCOME DISTRIBUTION TRF TO AC#1234"),
+		activity("2024-05-02", "transfer_out", -1_000_000_000, "", "", 0, "INSURED CASH ACCOUNT"),
+	}
+
+	summary := income.Summarize(rows, time.Time{}, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), income.GroupByQuarter)
+
+	totals := summary.Totals
+	if totals.QualifiedDividendsMicros != 110_000_000 || totals.ExemptDividendsMicros != 30_000_000 {
+		t.Errorf("unexpected dividends: %+v", totals)
+	}
+	if totals.IncomeMicros() != 392_000_000 || totals.TaxExemptMicros() != 280_000_000 {
+		t.Errorf("expected income $392 with $280 exempt, got %d and %d", totals.IncomeMicros(), totals.TaxExemptMicros())
+	}
+	if totals.NetMicros() != 367_000_000 || totals.DistributionsMicros != -200_000_000 {
+		t.Errorf("expected net $367 and -$200 distributed, got %d and %d", totals.NetMicros(), totals.DistributionsMicros)
+	}
+
+	want := []struct {
+		label  string
+		net    int64
+		ytdNet int64
+	}{
+		{"2023-Q4", 50_000_000, 50_000_000},
+		{"2024-Q1", 92_000_000, 92_000_000},
+		{"2024-Q2", 225_000_000, 317_000_000},
+	}
+	if len(summary.Periods) != len(want) {
+		t.Fatalf("got %d periods, want %d: %+v", len(summary.Periods), len(want), summary.Periods)
+	}
+	for i, w := range want {
+		p := summary.Periods[i]
+		if p.Label != w.label || p.Totals.NetMicros() != w.net || p.YTD.NetMicros() != w.ytdNet {
+			t.Errorf("period %d: got %s net %d ytd %d, want %+v", i, p.Label, p.Totals.NetMicros(), p.YTD.NetMicros(), w)
+		}
+	}
+	if p := summary.Periods[1]; p.StartDate != "2024-01-01" || p.EndDate != "2024-03-31" {
+		t.Errorf("unexpected Q1 span: %s to %s", p.StartDate, p.EndDate)
+	}
+	if len(summary.Accounts) != 1 || summary.Accounts[0].Totals != totals {
+		t.Errorf("expected one account with every row: %+v", summary.Accounts)
+	}
+}
+
+func TestBySecurity(t *testing.T) {
+	rows := []db.ListIncomeActivityRow{
+		activity("2024-02-15", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2024-05-15", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2024-12-20", "cap_gain", 5_000_000, "VTI", "VANGUARD TOTAL STOCK MARKET ETF", 0, ""),
+		activity("2024-12-20", "dividend", 90_000_000, "VTI", "VANGUARD TOTAL STOCK MARKET ETF", 0, ""),
+		activity("2024-04-01", "fee", -25_000_000, "", "", 0, "ADVISORY FEE"),
+	}
+
+	lines := income.BySecurity(rows)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %+v", len(lines), lines)
+	}
+	if l := lines[0]; l.Symbol != "AAPL" || l.Payments != 2 || l.AmountMicros != 120_000_000 || l.LastPaid != "2024-05-15" {
+		t.Errorf("unexpected AAPL line: %+v", l)
+	}
+	if l := lines[2]; l.Symbol != "VTI" || l.Category != income.CategoryCapitalGain {
+		t.Errorf("expected VTI capital gains last: %+v", l)
+	}
+}
+
+func TestYearRange(t *testing.T) {
+	now := time.Date(2025, 6, 15, 13, 0, 0, 0, time.UTC)
+
+	start, end := income.YearRange(2024, now)
+	if start.Format("2006-01-02") != "2024-01-01" || end.Format("2006-01-02") != "2024-12-31" {
+		t.Errorf("unexpected 2024 range: %s to %s", start, end)
+	}
+	start, end = income.YearRange(2025, now)
+	if start.Format("2006-01-02") != "2025-01-01" || end.Format("2006-01-02") != "2025-06-15" {
+		t.Errorf("unexpected 2025 range: %s to %s", start, end)
+	}
+	if start, _ := income.YearRange(0, now); !start.IsZero() {
+		t.Errorf("expected year 0 to start at the zero time, got %s", start)
+	}
+}
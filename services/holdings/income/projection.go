@@ -0,0 +1,484 @@
+package income
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+type PaymentType string
+
+const (
+	PaymentDividend    PaymentType = "dividend"
+	PaymentInterest    PaymentType = "interest"
+	PaymentMoneyMarket PaymentType = "mmf-distribution" // money market funds and sweep interest
+	PaymentMaturity    PaymentType = "maturity"         // principal repaid, not income
+)
+
+type Frequency string
+
+const (
+	FrequencyMonthly    Frequency = "monthly"
+	FrequencyQuarterly  Frequency = "quarterly"
+	FrequencySemiAnnual Frequency = "semi-annual"
+	FrequencyAnnual     Frequency = "annual"
+	FrequencyVariable   Frequency = "variable"
+)
+
+// ProjectionMonths is how far ahead income is projected, counting the
+// reference date's month.
+const ProjectionMonths = 12
+
+// moneyMarketFund matches money market fund names; their symbols are five
+// letters ending XX.
+var (
+	moneyMarketFund   = regexp.MustCompile(`\b(MONEY MARKET|MONEY MKT|MONEY FUND|MMF|CASH RESERVES?)\b`)
+	moneyMarketSymbol = regexp.MustCompile(`^[A-Z]{3}XX$`)
+)
+
+// Position is an open long position to project income on. A position with no
+// SecurityID is the account's cash, which earns sweep interest.
+type Position struct {
+	AccountID      string
+	SecurityID     string
+	Symbol         string
+	Name           string
+	QuantityMicros int64
+}
+
+// PaidKey identifies a past payment on a position, to look up the quantity
+// held when it was paid.
+type PaidKey struct {
+	AccountID  string
+	SecurityID string
+	Date       string
+}
+
+type ProjectedPayment struct {
+	AccountID      string
+	SecurityID     string
+	Symbol         string
+	Name           string
+	Type           PaymentType
+	PaymentDate    string
+	PerShareMicros int64
+	AmountMicros   int64
+	QuantityMicros int64
+	Frequency      Frequency
+}
+
+type ProjectedMonth struct {
+	Label             string // e.g. Jan 2026
+	StartDate         string
+	EndDate           string
+	DividendMicros    int64
+	InterestMicros    int64
+	MoneyMarketMicros int64
+	MaturityMicros    int64
+}
+
+// IncomeMicros is the month's dividends, interest and money market income.
+func (m ProjectedMonth) IncomeMicros() int64 {
+	return m.DividendMicros + m.InterestMicros + m.MoneyMarketMicros
+}
+
+// Projection is the income expected after ReferenceDate through EndDate, the
+// end of the twelfth month. Payments are income; Maturities are bond
+// principal coming due, kept out of the income totals.
+type Projection struct {
+	ReferenceDate string
+	EndDate       string
+	Payments      []ProjectedPayment
+	Maturities    []ProjectedPayment
+	Months        []ProjectedMonth
+}
+
+// Totals sums the months.
+func (p *Projection) Totals() ProjectedMonth {
+	var t ProjectedMonth
+	for _, m := range p.Months {
+		t.DividendMicros += m.DividendMicros
+		t.InterestMicros += m.InterestMicros
+		t.MoneyMarketMicros += m.MoneyMarketMicros
+		t.MaturityMicros += m.MaturityMicros
+	}
+	return t
+}
+
+type Projector struct {
+	queries *db.Queries
+}
+
+func NewProjector(queries *db.Queries) *Projector {
+	return &Projector{queries: queries}
+}
+
+// Project projects the income of accountID, or of every account when it's
+// empty, over the twelve months starting with from's month, from the lots
+// open at from and the last thirteen months of payments.
+func (p *Projector) Project(ctx context.Context, accountID string, from time.Time) (*Projection, error) {
+	valuer := taxlots.NewValuer(p.queries)
+	lots, err := valuer.OpenLots(ctx, accountID, from)
+	if err != nil {
+		return nil, err
+	}
+	history, err := NewReporter(p.queries).Activity(ctx, accountID, from.AddDate(0, -13, 0), from)
+	if err != nil {
+		return nil, err
+	}
+	bonds, err := p.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+
+	positions := positionsOf(lots)
+
+	// Sweep interest is projected on each account's cash.
+	cashAccounts := make(map[string]bool)
+	for _, row := range history {
+		if row.CashType == "interest" && !row.SecurityID.Valid && !cashAccounts[row.AccountID] {
+			cashAccounts[row.AccountID] = true
+			balance, err := p.queries.GetCashBalanceAsOfDate(ctx, db.GetCashBalanceAsOfDateParams{
+				AccountID: row.AccountID,
+				AsOfDate:  from.Format("2006-01-02"),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cash balance: %w", err)
+			}
+			if v, ok := balance.(int64); ok && v > 0 {
+				positions = append(positions, Position{AccountID: row.AccountID, Symbol: "CASH", Name: "Cash sweep", QuantityMicros: v})
+			}
+		}
+	}
+
+	// Dividends are scaled from the quantity held when last paid.
+	paid := make(map[PaidKey]int64)
+	held := make(map[string]map[string]int64) // date -> account|security -> quantity
+	for key := range lastPaid(history) {
+		quantities, ok := held[key.Date]
+		if !ok {
+			date, err := time.Parse("2006-01-02", key.Date)
+			if err != nil {
+				continue
+			}
+			lots, err := valuer.OpenLots(ctx, accountID, date)
+			if err != nil {
+				return nil, err
+			}
+			quantities = make(map[string]int64)
+			for _, pos := range positionsOf(lots) {
+				quantities[pos.AccountID+"|"+pos.SecurityID] = pos.QuantityMicros
+			}
+			held[key.Date] = quantities
+		}
+		paid[key] = quantities[key.AccountID+"|"+key.SecurityID]
+	}
+
+	return ProjectIncome(positions, bonds, history, paid, from), nil
+}
+
+// ProjectIncome projects each position's payments after from through the end
+// of the twelfth month:
+//   - bonds pay their coupon schedule, and their face value at maturity
+//     (calls aren't assumed);
+//   - money market funds and cash pay each month end the average of the
+//     last three months;
+//   - anything else repeats its last dividend or interest payment per share
+//     at the frequency of its recent payments, on the current quantity.
+//
+// paid is the quantity held at each position's last payment; when it's
+// missing the last payment is taken as paid on the current quantity.
+func ProjectIncome(positions []Position, bonds []db.ListBondsRow, history []db.ListIncomeActivityRow, paid map[PaidKey]int64, from time.Time) *Projection {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := first.AddDate(0, ProjectionMonths, -1)
+
+	bondBySecurity := make(map[string]db.ListBondsRow)
+	for _, b := range bonds {
+		bondBySecurity[b.SecurityID] = b
+	}
+	bySecurity := make(map[string][]db.ListIncomeActivityRow)
+	for _, row := range history {
+		switch row.CashType {
+		case "dividend", "interest":
+			bySecurity[row.AccountID+"|"+row.SecurityID.String] = append(bySecurity[row.AccountID+"|"+row.SecurityID.String], row)
+		}
+	}
+
+	projection := &Projection{
+		ReferenceDate: from.Format("2006-01-02"),
+		EndDate:       end.Format("2006-01-02"),
+	}
+	for _, pos := range positions {
+		if pos.QuantityMicros <= 0 {
+			continue
+		}
+		rows := bySecurity[pos.AccountID+"|"+pos.SecurityID]
+
+		if bond, ok := bondBySecurity[pos.SecurityID]; ok {
+			payments, maturity := projectBond(pos, bond, from, end)
+			projection.Payments = append(projection.Payments, payments...)
+			if maturity != nil {
+				projection.Maturities = append(projection.Maturities, *maturity)
+			}
+			continue
+		}
+		if pos.SecurityID == "" || moneyMarket(pos) {
+			projection.Payments = append(projection.Payments, projectMoneyMarket(pos, rows, from, end)...)
+			continue
+		}
+		projection.Payments = append(projection.Payments, projectDividends(pos, rows, paid, from, end)...)
+	}
+
+	sortPayments(projection.Payments)
+	sortPayments(projection.Maturities)
+
+	for i := 0; i < ProjectionMonths; i++ {
+		start := first.AddDate(0, i, 0)
+		projection.Months = append(projection.Months, ProjectedMonth{
+			Label:     start.Format("Jan 2006"),
+			StartDate: start.Format("2006-01-02"),
+			EndDate:   start.AddDate(0, 1, -1).Format("2006-01-02"),
+		})
+	}
+	monthOf := func(date string) *ProjectedMonth {
+		for i := range projection.Months {
+			if date <= projection.Months[i].EndDate {
+				return &projection.Months[i]
+			}
+		}
+		return &projection.Months[len(projection.Months)-1]
+	}
+	for _, pay := range projection.Payments {
+		m := monthOf(pay.PaymentDate)
+		switch pay.Type {
+		case PaymentDividend:
+			m.DividendMicros += pay.AmountMicros
+		case PaymentInterest:
+			m.InterestMicros += pay.AmountMicros
+		case PaymentMoneyMarket:
+			m.MoneyMarketMicros += pay.AmountMicros
+		}
+	}
+	for _, pay := range projection.Maturities {
+		monthOf(pay.PaymentDate).MaturityMicros += pay.AmountMicros
+	}
+
+	return projection
+}
+
+// projectBond returns the bond's coupons after from through end, and its
+// principal if it matures by then.
+func projectBond(pos Position, bond db.ListBondsRow, from, end time.Time) ([]ProjectedPayment, *ProjectedPayment) {
+	frequency := FrequencySemiAnnual
+	switch bond.PaymentsPerYear {
+	case 1:
+		frequency = FrequencyAnnual
+	case 4:
+		frequency = FrequencyQuarterly
+	case 12:
+		frequency = FrequencyMonthly
+	}
+
+	var payments []ProjectedPayment
+	coupon := taxlots.CouponMicros(pos.QuantityMicros, bond)
+	for _, date := range taxlots.CouponSchedule(bond, from, end) {
+		payments = append(payments, payment(pos, PaymentInterest, date, coupon, frequency))
+	}
+
+	maturity, err := time.Parse("2006-01-02", bond.MaturityDate)
+	if err != nil || !maturity.After(from) || maturity.After(end) {
+		return payments, nil
+	}
+	principal := payment(pos, PaymentMaturity, maturity, taxlots.FaceValue(pos.QuantityMicros, bond), frequency)
+	return payments, &principal
+}
+
+// projectMoneyMarket pays the average of the last three months' payments at
+// each month end, an estimate of the fund's current yield.
+func projectMoneyMarket(pos Position, rows []db.ListIncomeActivityRow, from, end time.Time) []ProjectedPayment {
+	since := from.AddDate(0, -3, 0).Format("2006-01-02")
+	var totalMicros int64
+	for _, row := range rows {
+		if row.TransactionDate > since {
+			totalMicros += row.AmountMicros
+		}
+	}
+	monthly := totalMicros / 3
+	if monthly <= 0 {
+		return nil
+	}
+
+	var payments []ProjectedPayment
+	for d := time.Date(from.Year(), from.Month()+1, 0, 0, 0, 0, 0, time.UTC); !d.After(end); d = time.Date(d.Year(), d.Month()+2, 0, 0, 0, 0, 0, time.UTC) {
+		if d.After(from) {
+			payments = append(payments, payment(pos, PaymentMoneyMarket, d, monthly, FrequencyMonthly))
+		}
+	}
+	return payments
+}
+
+// projectDividends repeats the last payment, per share, every interval its
+// recent payments came at.
+func projectDividends(pos Position, rows []db.ListIncomeActivityRow, paid map[PaidKey]int64, from, end time.Time) []ProjectedPayment {
+	if len(rows) == 0 {
+		return nil
+	}
+	last := rows[len(rows)-1]
+	lastDate, err := time.Parse("2006-01-02", last.TransactionDate)
+	if err != nil {
+		return nil
+	}
+
+	amount := last.AmountMicros
+	if held := paid[PaidKey{AccountID: pos.AccountID, SecurityID: pos.SecurityID, Date: last.TransactionDate}]; held > 0 {
+		amount = taxlots.ProRata(last.AmountMicros, held, pos.QuantityMicros)
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	paymentType := PaymentDividend
+	if last.CashType == "interest" {
+		paymentType = PaymentInterest
+	}
+	frequency, months := paymentFrequency(rows)
+
+	var payments []ProjectedPayment
+	for i := 1; ; i++ {
+		d := lastDate.AddDate(0, months*i, 0)
+		if d.After(end) {
+			break
+		}
+		if d.After(from) {
+			payments = append(payments, payment(pos, paymentType, d, amount, frequency))
+		}
+	}
+	return payments
+}
+
+// paymentFrequency infers how often a security pays from the median gap
+// between its payments, and the months between them. A single payment is
+// taken as annual; gaps that vary by more than double are variable.
+func paymentFrequency(rows []db.ListIncomeActivityRow) (Frequency, int) {
+	var dates []time.Time
+	for _, row := range rows {
+		d, err := time.Parse("2006-01-02", row.TransactionDate)
+		if err != nil {
+			continue
+		}
+		if len(dates) > 0 && d.Equal(dates[len(dates)-1]) {
+			continue
+		}
+		dates = append(dates, d)
+	}
+	if len(dates) < 2 {
+		return FrequencyAnnual, 12
+	}
+
+	gaps := make([]int, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		gaps = append(gaps, int(dates[i].Sub(dates[i-1]).Hours()/24))
+	}
+	sort.Ints(gaps)
+	median := gaps[len(gaps)/2]
+
+	var frequency Frequency
+	var months int
+	switch {
+	case median <= 45:
+		frequency, months = FrequencyMonthly, 1
+	case median <= 120:
+		frequency, months = FrequencyQuarterly, 3
+	case median <= 240:
+		frequency, months = FrequencySemiAnnual, 6
+	default:
+		frequency, months = FrequencyAnnual, 12
+	}
+	if gaps[len(gaps)-1] > 2*gaps[0]+15 {
+		frequency = FrequencyVariable
+	}
+	return frequency, months
+}
+
+// lastPaid returns the date of each position's last dividend or interest
+// payment.
+func lastPaid(history []db.ListIncomeActivityRow) map[PaidKey]bool {
+	last := make(map[string]PaidKey)
+	for _, row := range history {
+		if !row.SecurityID.Valid || (row.CashType != "dividend" && row.CashType != "interest") {
+			continue
+		}
+		k := row.AccountID + "|" + row.SecurityID.String
+		if row.TransactionDate >= last[k].Date {
+			last[k] = PaidKey{AccountID: row.AccountID, SecurityID: row.SecurityID.String, Date: row.TransactionDate}
+		}
+	}
+	keys := make(map[PaidKey]bool, len(last))
+	for _, k := range last {
+		keys[k] = true
+	}
+	return keys
+}
+
+// positionsOf sums long lots by account and security.
+func positionsOf(lots []db.ListOpenLotsRow) []Position {
+	var positions []Position
+	index := make(map[string]int)
+	for _, lot := range lots {
+		if taxlots.PositionSide(lot.PositionSide) == taxlots.PositionShort {
+			continue
+		}
+		k := lot.AccountID + "|" + lot.SecurityID
+		i, ok := index[k]
+		if !ok {
+			i = len(positions)
+			index[k] = i
+			positions = append(positions, Position{
+				AccountID:  lot.AccountID,
+				SecurityID: lot.SecurityID,
+				Symbol:     lot.Symbol,
+				Name:       lot.SecurityName.String,
+			})
+		}
+		positions[i].QuantityMicros += lot.RemainingMicros
+	}
+	return positions
+}
+
+func moneyMarket(pos Position) bool {
+	return moneyMarketSymbol.MatchString(pos.Symbol) || moneyMarketFund.MatchString(strings.ToUpper(pos.Name))
+}
+
+func payment(pos Position, paymentType PaymentType, date time.Time, amountMicros int64, frequency Frequency) ProjectedPayment {
+	return ProjectedPayment{
+		AccountID:      pos.AccountID,
+		SecurityID:     pos.SecurityID,
+		Symbol:         pos.Symbol,
+		Name:           pos.Name,
+		Type:           paymentType,
+		PaymentDate:    date.Format("2006-01-02"),
+		PerShareMicros: taxlots.ProRata(amountMicros, pos.QuantityMicros, 1_000_000),
+		AmountMicros:   amountMicros,
+		QuantityMicros: pos.QuantityMicros,
+		Frequency:      frequency,
+	}
+}
+
+func sortPayments(payments []ProjectedPayment) {
+	sort.Slice(payments, func(i, j int) bool {
+		if payments[i].PaymentDate != payments[j].PaymentDate {
+			return payments[i].PaymentDate < payments[j].PaymentDate
+		}
+		if payments[i].Symbol != payments[j].Symbol {
+			return payments[i].Symbol < payments[j].Symbol
+		}
+		return payments[i].AccountID < payments[j].AccountID
+	})
+}
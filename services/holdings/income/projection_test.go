@@ -0,0 +1,83 @@
+package income_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+func TestProjectIncome(t *testing.T) {
+	history := []db.ListIncomeActivityRow{
+		activity("2025-02-13", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2025-05-15", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2025-08-14", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2025-11-13", "dividend", 60_000_000, "AAPL", "APPLE INC", 0, ""),
+		activity("2025-09-30", "dividend", 80_000_000, "SWVXX", "SCHWAB VALUE ADVANTAGE MONEY FUND", 0, ""),
+		activity("2025-10-31", "dividend", 45_000_000, "SWVXX", "SCHWAB VALUE ADVANTAGE MONEY FUND", 0, ""),
+		activity("2025-11-30", "dividend", 50_000_000, "SWVXX", "SCHWAB VALUE ADVANTAGE MONEY FUND", 0, ""),
+		activity("2025-12-31", "dividend", 55_000_000, "SWVXX", "SCHWAB VALUE ADVANTAGE MONEY FUND", 0, ""),
+		activity("2025-12-31", "interest", 3_000_000, "", "", 0, "INSURED CASH ACCOUNT"),
+		activity("2025-07-01", "interest", 625_000_000, "544525ZV3", "LOS ANGELES CA DPT WTR & PWR", 0.05, ""),
+		activity("2025-06-15", "dividend", 40_000_000, "SOLD", "SOLD LONG AGO INC", 0, ""),
+	}
+	positions := []income.Position{
+		{AccountID: "acct", SecurityID: "sec_AAPL", Symbol: "AAPL", Name: "APPLE INC", QuantityMicros: 300_000_000},
+		{AccountID: "acct", SecurityID: "sec_SWVXX", Symbol: "SWVXX", Name: "SCHWAB VALUE ADVANTAGE MONEY FUND", QuantityMicros: 12_500_000_000},
+		{AccountID: "acct", SecurityID: "sec_544525ZV3", Symbol: "544525ZV3", QuantityMicros: 25_000_000},
+		{AccountID: "acct", Symbol: "CASH", QuantityMicros: 1_000_000_000},
+	}
+	bonds := []db.ListBondsRow{{
+		SecurityID:      "sec_544525ZV3",
+		CouponRate:      0.05,
+		MaturityDate:    "2026-07-01",
+		ParMicros:       1_000_000_000,
+		PaymentsPerYear: 2,
+	}}
+	paid := map[income.PaidKey]int64{
+		{AccountID: "acct", SecurityID: "sec_AAPL", Date: "2025-11-13"}: 240_000_000,
+	}
+
+	projection := income.ProjectIncome(positions, bonds, history, paid, time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC))
+
+	if projection.ReferenceDate != "2026-01-13" || projection.EndDate != "2026-12-31" {
+		t.Errorf("unexpected horizon: %s to %s", projection.ReferenceDate, projection.EndDate)
+	}
+	if len(projection.Months) != 12 || projection.Months[0].Label != "Jan 2026" {
+		t.Fatalf("expected 12 months from Jan 2026, got %+v", projection.Months)
+	}
+
+	var aapl []income.ProjectedPayment
+	for _, p := range projection.Payments {
+		if p.Symbol == "AAPL" {
+			aapl = append(aapl, p)
+		}
+		if p.Symbol == "SOLD" {
+			t.Errorf("projected income on a security no longer held: %+v", p)
+		}
+	}
+	if len(aapl) != 4 || aapl[0].PaymentDate != "2026-02-13" || aapl[3].PaymentDate != "2026-11-13" {
+		t.Fatalf("expected quarterly AAPL from 2026-02-13, got %+v", aapl)
+	}
+	if a := aapl[0]; a.AmountMicros != 75_000_000 || a.PerShareMicros != 250_000 || a.Frequency != income.FrequencyQuarterly {
+		t.Errorf("expected $0.25 a share on 300 shares quarterly, got %+v", a)
+	}
+
+	totals := projection.Totals()
+	if totals.DividendMicros != 300_000_000 {
+		t.Errorf("expected $300 dividends, got %d", totals.DividendMicros)
+	}
+	if totals.MoneyMarketMicros != 612_000_000 {
+		t.Errorf("expected $50 a month of fund and $1 a month of sweep income, got %d", totals.MoneyMarketMicros)
+	}
+	if totals.InterestMicros != 625_000_000 || totals.MaturityMicros != 25_000_000_000 {
+		t.Errorf("expected one $625 coupon and $25,000 maturing, got %d and %d", totals.InterestMicros, totals.MaturityMicros)
+	}
+	if m := projection.Months[6]; m.InterestMicros != 625_000_000 || m.MaturityMicros != 25_000_000_000 || m.IncomeMicros() != 676_000_000 {
+		t.Errorf("unexpected July: %+v", m)
+	}
+	if len(projection.Maturities) != 1 || projection.Maturities[0].PaymentDate != "2026-07-01" {
+		t.Errorf("expected the bond to mature 2026-07-01, got %+v", projection.Maturities)
+	}
+}
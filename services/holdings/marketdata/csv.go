@@ -0,0 +1,165 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+// CSVProvider reads market data from CSV files, either a single file of closes
+// with a symbol column or a directory with one file per security:
+//
+//	AAPL.csv            daily closes (date, close), e.g. a Yahoo download
+//	AAPL.splits.csv     date, ratio ("2:1")
+//	AAPL.dividends.csv  date, amount per share
+//
+// A single file has no splits or dividends.
+type CSVProvider struct {
+	path string
+	dir  bool
+	bars map[string][]Bar // single file, by symbol
+}
+
+// NewCSVProvider opens path, a directory of per-security files or one CSV of
+// closes for any number of symbols.
+func NewCSVProvider(path string) (*CSVProvider, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market data path: %w", err)
+	}
+	p := &CSVProvider{path: path, dir: info.IsDir()}
+	if p.dir {
+		return p, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market data file: %w", err)
+	}
+	defer f.Close()
+
+	prices, err := importer.ParsePrices(f, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	p.bars = make(map[string][]Bar)
+	for _, price := range prices {
+		p.bars[price.Symbol] = append(p.bars[price.Symbol], Bar{Date: price.Date, CloseMicros: price.CloseMicros})
+	}
+	return p, nil
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+func (p *CSVProvider) Bars(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error) {
+	var all []Bar
+	if p.dir {
+		var prices []importer.Price
+		err := p.readFile(symbol, ".csv", func(r io.Reader) (err error) {
+			prices, err = importer.ParsePrices(r, symbol)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, price := range prices {
+			all = append(all, Bar{Date: price.Date, CloseMicros: price.CloseMicros})
+		}
+	} else {
+		var ok bool
+		if all, ok = p.bars[symbol]; !ok {
+			return nil, ErrNotFound
+		}
+	}
+
+	var bars []Bar
+	for _, bar := range all {
+		if inRange(bar.Date, from, to) {
+			bars = append(bars, bar)
+		}
+	}
+	sortBars(bars)
+	return bars, nil
+}
+
+func (p *CSVProvider) Splits(ctx context.Context, symbol string, from, to time.Time) ([]importer.Split, error) {
+	var parsed []importer.Split
+	err := p.readOptionalFile(symbol, ".splits.csv", func(r io.Reader) (err error) {
+		parsed, err = importer.ParseSplits(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var splits []importer.Split
+	for _, s := range parsed {
+		if inRange(s.Date, from, to) {
+			splits = append(splits, s)
+		}
+	}
+	return splits, nil
+}
+
+func (p *CSVProvider) Dividends(ctx context.Context, symbol string, from, to time.Time) ([]importer.Dividend, error) {
+	var parsed []importer.Dividend
+	err := p.readOptionalFile(symbol, ".dividends.csv", func(r io.Reader) (err error) {
+		parsed, err = importer.ParseDividends(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dividends []importer.Dividend
+	for _, d := range parsed {
+		if inRange(d.Date, from, to) {
+			dividends = append(dividends, d)
+		}
+	}
+	return dividends, nil
+}
+
+// readFile parses the directory's file for symbol, returning ErrNotFound when
+// there isn't one.
+func (p *CSVProvider) readFile(symbol, suffix string, parse func(io.Reader) error) error {
+	if strings.ContainsAny(symbol, `/\`) {
+		return ErrNotFound
+	}
+	path := filepath.Join(p.path, symbol+suffix)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := parse(f); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// readOptionalFile is readFile for splits and dividends, which most
+// securities don't have a file for.
+func (p *CSVProvider) readOptionalFile(symbol, suffix string, parse func(io.Reader) error) error {
+	if !p.dir {
+		return nil
+	}
+	err := p.readFile(symbol, suffix, parse)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+// HTTPProvider fetches market data as JSON from a server speaking the stub
+// API served by NewStubHandler:
+//
+//	GET /v1/bars/{symbol}?from=2024-01-01&to=2024-12-31
+//	GET /v1/splits/{symbol}?from=...&to=...
+//	GET /v1/dividends/{symbol}?from=...&to=...
+//
+// Unknown symbols are a 404.
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type barJSON struct {
+	Date        string `json:"date"`
+	CloseMicros int64  `json:"close_micros"`
+}
+
+type splitJSON struct {
+	Date  string  `json:"date"`
+	Ratio float64 `json:"ratio"`
+}
+
+type dividendJSON struct {
+	Date         string `json:"date"`
+	AmountMicros int64  `json:"amount_micros"`
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (p *HTTPProvider) Bars(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error) {
+	var resp []barJSON
+	if err := p.get(ctx, "bars", symbol, from, to, &resp); err != nil {
+		return nil, err
+	}
+
+	bars := make([]Bar, 0, len(resp))
+	for _, b := range resp {
+		date, err := time.Parse("2006-01-02", b.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bar date %q: %w", b.Date, err)
+		}
+		bars = append(bars, Bar{Date: date, CloseMicros: b.CloseMicros})
+	}
+	sortBars(bars)
+	return bars, nil
+}
+
+func (p *HTTPProvider) Splits(ctx context.Context, symbol string, from, to time.Time) ([]importer.Split, error) {
+	var resp []splitJSON
+	if err := p.get(ctx, "splits", symbol, from, to, &resp); err != nil {
+		return nil, err
+	}
+
+	splits := make([]importer.Split, 0, len(resp))
+	for _, s := range resp {
+		date, err := time.Parse("2006-01-02", s.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid split date %q: %w", s.Date, err)
+		}
+		splits = append(splits, importer.Split{Date: date, Ratio: s.Ratio})
+	}
+	return splits, nil
+}
+
+func (p *HTTPProvider) Dividends(ctx context.Context, symbol string, from, to time.Time) ([]importer.Dividend, error) {
+	var resp []dividendJSON
+	if err := p.get(ctx, "dividends", symbol, from, to, &resp); err != nil {
+		return nil, err
+	}
+
+	dividends := make([]importer.Dividend, 0, len(resp))
+	for _, d := range resp {
+		date, err := time.Parse("2006-01-02", d.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dividend date %q: %w", d.Date, err)
+		}
+		dividends = append(dividends, importer.Dividend{Date: date, AmountMicros: d.AmountMicros})
+	}
+	return dividends, nil
+}
+
+func (p *HTTPProvider) get(ctx context.Context, kind, symbol string, from, to time.Time, out any) error {
+	query := url.Values{}
+	query.Set("from", from.Format("2006-01-02"))
+	query.Set("to", to.Format("2006-01-02"))
+	u := fmt.Sprintf("%s/v1/%s/%s?%s", p.baseURL, kind, url.PathEscape(symbol), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s for %s: %w", kind, symbol, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s for %s: %s", kind, symbol, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s for %s: %w", kind, symbol, err)
+	}
+	return nil
+}
+
+// NewStubHandler serves a provider's data over the HTTPProvider API, so a
+// local directory of CSVs can stand in for a market data service.
+func NewStubHandler(provider Provider) http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/v1/{kind}/{symbol}", func(w http.ResponseWriter, r *http.Request) {
+		symbol, err := url.PathUnescape(chi.URLParam(r, "symbol"))
+		if err != nil {
+			http.Error(w, "invalid symbol", http.StatusBadRequest)
+			return
+		}
+		from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(w, "invalid from date", http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, "invalid to date", http.StatusBadRequest)
+			return
+		}
+
+		var resp any
+		switch chi.URLParam(r, "kind") {
+		case "bars":
+			bars, fetchErr := provider.Bars(r.Context(), symbol, from, to)
+			body := make([]barJSON, 0, len(bars))
+			for _, b := range bars {
+				body = append(body, barJSON{Date: b.Date.Format("2006-01-02"), CloseMicros: b.CloseMicros})
+			}
+			resp, err = body, fetchErr
+		case "splits":
+			splits, fetchErr := provider.Splits(r.Context(), symbol, from, to)
+			body := make([]splitJSON, 0, len(splits))
+			for _, s := range splits {
+				body = append(body, splitJSON{Date: s.Date.Format("2006-01-02"), Ratio: s.Ratio})
+			}
+			resp, err = body, fetchErr
+		case "dividends":
+			dividends, fetchErr := provider.Dividends(r.Context(), symbol, from, to)
+			body := make([]dividendJSON, 0, len(dividends))
+			for _, d := range dividends {
+				body = append(body, dividendJSON{Date: d.Date.Format("2006-01-02"), AmountMicros: d.AmountMicros})
+			}
+			resp, err = body, fetchErr
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+	return mux
+}
@@ -0,0 +1,73 @@
+// Package marketdata fetches daily prices, splits and dividends from market
+// data providers and stores them in the prices and corporate_actions tables.
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+)
+
+// ErrNotFound is returned by providers that have no data for a symbol.
+var ErrNotFound = errors.New("symbol not found")
+
+// Bar is a security's daily close as traded, not adjusted for later splits
+// or dividends. Filled bars carry the previous close over a weekday without
+// trading.
+type Bar struct {
+	Date        time.Time
+	CloseMicros int64
+	Filled      bool
+}
+
+// Provider is a source of daily market data. Date ranges are inclusive and
+// results are ordered by date. Providers return ErrNotFound for symbols they
+// don't cover and empty results for ranges without data.
+type Provider interface {
+	Name() string
+	Bars(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error)
+	Splits(ctx context.Context, symbol string, from, to time.Time) ([]importer.Split, error)
+	Dividends(ctx context.Context, symbol string, from, to time.Time) ([]importer.Dividend, error)
+}
+
+// FillGaps returns bars with a filled bar for each weekday between two bars
+// that has none, carrying the earlier close forward over holidays. Weekends
+// are left empty; readers carry the last close forward over them.
+func FillGaps(bars []Bar) []Bar {
+	if len(bars) == 0 {
+		return nil
+	}
+	sorted := append([]Bar(nil), bars...)
+	sortBars(sorted)
+
+	filled := []Bar{sorted[0]}
+	for _, bar := range sorted[1:] {
+		prev := filled[len(filled)-1]
+		if !bar.Date.After(prev.Date) {
+			continue
+		}
+		for day := prev.Date.AddDate(0, 0, 1); day.Before(bar.Date); day = day.AddDate(0, 0, 1) {
+			if isWeekend(day) {
+				continue
+			}
+			filled = append(filled, Bar{Date: day, CloseMicros: prev.CloseMicros, Filled: true})
+		}
+		filled = append(filled, bar)
+	}
+	return filled
+}
+
+func sortBars(bars []Bar) {
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Date.Before(bars[j].Date) })
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func inRange(date, from, to time.Time) bool {
+	return !date.Before(from) && !date.After(to)
+}
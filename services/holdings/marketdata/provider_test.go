@@ -0,0 +1,57 @@
+package marketdata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/marketdata"
+)
+
+func TestFillGaps(t *testing.T) {
+	bars := []marketdata.Bar{
+		// Out of order: FillGaps sorts.
+		{Date: mustDate(t, "2024-07-05"), CloseMicros: 103_000_000},
+		{Date: mustDate(t, "2024-07-02"), CloseMicros: 101_000_000},
+		{Date: mustDate(t, "2024-07-03"), CloseMicros: 102_000_000},
+		// Independence Day on Thursday, then the weekend and Monday missing.
+		{Date: mustDate(t, "2024-07-09"), CloseMicros: 104_000_000},
+	}
+
+	got := marketdata.FillGaps(bars)
+
+	want := []struct {
+		date   string
+		close  int64
+		filled bool
+	}{
+		{"2024-07-02", 101_000_000, false},
+		{"2024-07-03", 102_000_000, false},
+		{"2024-07-04", 102_000_000, true},
+		{"2024-07-05", 103_000_000, false},
+		{"2024-07-08", 103_000_000, true},
+		{"2024-07-09", 104_000_000, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bars, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Date.Format("2006-01-02") != w.date || g.CloseMicros != w.close || g.Filled != w.filled {
+			t.Errorf("bar %d = %s %d filled=%v, want %s %d filled=%v",
+				i, g.Date.Format("2006-01-02"), g.CloseMicros, g.Filled, w.date, w.close, w.filled)
+		}
+	}
+
+	if got := marketdata.FillGaps(nil); got != nil {
+		t.Errorf("FillGaps(nil) = %+v, want nil", got)
+	}
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", s, err)
+	}
+	return d
+}
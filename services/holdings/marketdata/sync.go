@@ -0,0 +1,190 @@
+package marketdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// Syncer backfills stored prices and corporate actions from a provider.
+type Syncer struct {
+	queries  *db.Queries
+	provider Provider
+}
+
+func NewSyncer(queries *db.Queries, provider Provider) *Syncer {
+	return &Syncer{queries: queries, provider: provider}
+}
+
+// SyncResult counts what was stored for one security.
+type SyncResult struct {
+	Symbol    string
+	From      time.Time
+	To        time.Time
+	Bars      int
+	Filled    int // holidays carried forward
+	Splits    int
+	Dividends int
+	NotFound  bool // the provider has no data for the symbol
+}
+
+// Sync backfills every security ever held, or only those in symbols, through
+// to. A security's first sync starts at its first transaction; later syncs
+// start at its last stored close so holidays since then are filled.
+func (s *Syncer) Sync(ctx context.Context, symbols []string, to time.Time) ([]SyncResult, error) {
+	securities, err := s.queries.ListHeldSecurities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list held securities: %w", err)
+	}
+
+	only := make(map[string]bool)
+	for _, symbol := range symbols {
+		only[symbol] = true
+	}
+
+	var results []SyncResult
+	for _, sec := range securities {
+		if len(only) > 0 && !only[sec.Symbol] {
+			continue
+		}
+
+		from, err := time.Parse("2006-01-02", sec.FirstTransactionDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid first transaction date for %s: %w", sec.Symbol, err)
+		}
+		last, err := s.queries.GetLastPrice(ctx, sec.ID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to get last price for %s: %w", sec.Symbol, err)
+		case last.PriceDate > sec.FirstTransactionDate:
+			from, _ = time.Parse("2006-01-02", last.PriceDate)
+		}
+		if from.After(to) {
+			continue
+		}
+
+		result, err := s.syncSecurity(ctx, sec.ID, sec.Symbol, from, to)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// SyncSymbols backfills securities that needn't be held, such as benchmark
+// components, adding any not yet stored. A symbol's first sync starts at
+// from; later syncs start at its last stored close.
+func (s *Syncer) SyncSymbols(ctx context.Context, symbols []string, from, to time.Time) ([]SyncResult, error) {
+	var results []SyncResult
+	for _, symbol := range symbols {
+		sec, err := s.queries.GetSecurityBySymbol(ctx, symbol)
+		if errors.Is(err, sql.ErrNoRows) {
+			sec, err = s.queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+				ID:     database.NewID(database.PrefixSecurity),
+				Symbol: symbol,
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get security %s: %w", symbol, err)
+		}
+
+		start := from
+		last, err := s.queries.GetLastPrice(ctx, sec.ID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to get last price for %s: %w", symbol, err)
+		case last.PriceDate > from.Format("2006-01-02"):
+			start, _ = time.Parse("2006-01-02", last.PriceDate)
+		}
+		if start.After(to) {
+			continue
+		}
+
+		result, err := s.syncSecurity(ctx, sec.ID, symbol, start, to)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (s *Syncer) syncSecurity(ctx context.Context, securityID, symbol string, from, to time.Time) (SyncResult, error) {
+	result := SyncResult{Symbol: symbol, From: from, To: to}
+	source := s.provider.Name()
+
+	bars, err := s.provider.Bars(ctx, symbol, from, to)
+	if errors.Is(err, ErrNotFound) {
+		result.NotFound = true
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch bars for %s: %w", symbol, err)
+	}
+
+	for _, bar := range FillGaps(bars) {
+		err := s.queries.UpsertPrice(ctx, db.UpsertPriceParams{
+			SecurityID:  securityID,
+			PriceDate:   bar.Date.Format("2006-01-02"),
+			CloseMicros: bar.CloseMicros,
+			Source:      source,
+			Filled:      bar.Filled,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to save price for %s: %w", symbol, err)
+		}
+		if bar.Filled {
+			result.Filled++
+		} else {
+			result.Bars++
+		}
+	}
+
+	splits, err := s.provider.Splits(ctx, symbol, from, to)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return result, fmt.Errorf("failed to fetch splits for %s: %w", symbol, err)
+	}
+	for _, split := range splits {
+		err := s.queries.UpsertCorporateAction(ctx, db.UpsertCorporateActionParams{
+			SecurityID: securityID,
+			ActionDate: split.Date.Format("2006-01-02"),
+			ActionType: "split",
+			Ratio:      sql.NullFloat64{Float64: split.Ratio, Valid: true},
+			Source:     source,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to save split for %s: %w", symbol, err)
+		}
+		result.Splits++
+	}
+
+	dividends, err := s.provider.Dividends(ctx, symbol, from, to)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return result, fmt.Errorf("failed to fetch dividends for %s: %w", symbol, err)
+	}
+	for _, dividend := range dividends {
+		err := s.queries.UpsertCorporateAction(ctx, db.UpsertCorporateActionParams{
+			SecurityID:   securityID,
+			ActionDate:   dividend.Date.Format("2006-01-02"),
+			ActionType:   "dividend",
+			AmountMicros: sql.NullInt64{Int64: dividend.AmountMicros, Valid: true},
+			Source:       source,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to save dividend for %s: %w", symbol, err)
+		}
+		result.Dividends++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,211 @@
+package marketdata_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/marketdata"
+)
+
+func setupTestDB(t *testing.T) (*db.Queries, func()) {
+	t.Helper()
+	ctx := context.Background()
+
+	tmpFile, err := os.CreateTemp("", "marketdata-test-*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	conn, err := database.Open(ctx, tmpFile.Name())
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	cleanup := func() {
+		conn.Close()
+		os.Remove(tmpFile.Name())
+	}
+
+	return db.New(conn), cleanup
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	const closes = `Date,Open,High,Low,Close,Adj Close,Volume
+2024-06-28,215.77,216.07,210.30,210.62,209.87,82542700
+2024-07-01,212.09,217.51,211.92,216.75,215.98,60402900
+2024-07-02,216.15,220.38,215.10,220.27,219.49,58046200
+2024-07-03,220.00,221.55,219.03,221.55,220.77,37369800
+2024-07-05,221.65,226.45,221.65,226.34,225.54,60412400
+`
+	dir := t.TempDir()
+	writeFile(t, dir, "AAPL.csv", closes)
+	writeFile(t, dir, "AAPL.splits.csv", "Date,Stock Splits\n2020-08-31,4:1\n")
+	writeFile(t, dir, "AAPL.dividends.csv", "Date,Dividends\n2024-05-10,0.25\n2024-08-12,0.25\n")
+
+	csvProvider, err := marketdata.NewCSVProvider(dir)
+	if err != nil {
+		t.Fatalf("NewCSVProvider: %v", err)
+	}
+	stub := httptest.NewServer(marketdata.NewStubHandler(csvProvider))
+	defer stub.Close()
+
+	providers := map[string]marketdata.Provider{
+		"csv":  csvProvider,
+		"http": marketdata.NewHTTPProvider(stub.URL),
+	}
+
+	for name, provider := range providers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			queries, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+				ID:              database.NewID(database.PrefixAccount),
+				Name:            "Joint",
+				InstitutionName: "etrade",
+				AccountType:     "brokerage",
+			})
+			if err != nil {
+				t.Fatalf("failed to create account: %v", err)
+			}
+			aapl := createHeldSecurity(t, queries, account.ID, "AAPL", "2024-07-01")
+			createHeldSecurity(t, queries, account.ID, "MSFT", "2024-07-01")
+
+			syncer := marketdata.NewSyncer(queries, provider)
+			results, err := syncer.Sync(ctx, nil, mustDate(t, "2024-07-31"))
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("got %d results, want AAPL and MSFT", len(results))
+			}
+			got := results[0]
+			if got.Symbol != "AAPL" || got.Bars != 4 || got.Filled != 1 || got.Splits != 0 || got.Dividends != 0 {
+				t.Errorf("AAPL result = %+v, want 4 bars from the first buy, 1 filled, no actions before it", got)
+			}
+			if !results[1].NotFound {
+				t.Errorf("MSFT result = %+v, want not found", results[1])
+			}
+
+			holiday, err := queries.ListLatestPrices(ctx, "2024-07-04")
+			if err != nil {
+				t.Fatalf("failed to list prices: %v", err)
+			}
+			if len(holiday) != 1 || holiday[0].CloseMicros != 221_550_000 || !holiday[0].Filled || holiday[0].Source != provider.Name() {
+				t.Errorf("July 4 price = %+v, want 221.55 carried forward", holiday)
+			}
+
+			// The next sync picks up from the last close, filling the gap to it.
+			writeFile(t, dir, "AAPL.csv", `Date,Close
+2024-07-05,226.34
+2024-07-09,228.68
+`)
+			defer writeFile(t, dir, "AAPL.csv", closes)
+			results, err = syncer.Sync(ctx, []string{"AAPL"}, mustDate(t, "2024-08-31"))
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			if len(results) != 1 || results[0].From.Format("2006-01-02") != "2024-07-05" || results[0].Filled != 1 || results[0].Dividends != 1 {
+				t.Errorf("second sync = %+v, want from July 5 with July 8 filled and the August dividend", results)
+			}
+
+			actions, err := queries.ListCorporateActions(ctx, aapl)
+			if err != nil {
+				t.Fatalf("failed to list corporate actions: %v", err)
+			}
+			if len(actions) != 1 || actions[0].ActionType != "dividend" || actions[0].AmountMicros.Int64 != 250_000 {
+				t.Errorf("corporate actions = %+v, want the August dividend", actions)
+			}
+
+			// A real close replaces a filled one; a filled one never replaces a
+			// real close.
+			upsert := func(date string, closeMicros int64, filled bool) {
+				err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+					SecurityID:  aapl,
+					PriceDate:   date,
+					CloseMicros: closeMicros,
+					Source:      "csv",
+					Filled:      filled,
+				})
+				if err != nil {
+					t.Fatalf("failed to upsert price: %v", err)
+				}
+			}
+			upsert("2024-07-04", 222_000_000, false)
+			upsert("2024-07-05", 1_000_000, true)
+			for date, want := range map[string]int64{"2024-07-04": 222_000_000, "2024-07-05": 226_340_000} {
+				prices, err := queries.ListLatestPrices(ctx, date)
+				if err != nil {
+					t.Fatalf("failed to list prices: %v", err)
+				}
+				if len(prices) != 1 || prices[0].CloseMicros != want || prices[0].Filled {
+					t.Errorf("%s price = %+v, want real close %d", date, prices, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVProviderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "closes.csv")
+	writeFile(t, filepath.Dir(path), "closes.csv", "symbol,date,close\nSPY,2024-07-01,545.34\nAGG,2024-07-01,97.30\nSPY,2024-07-02,549.01\n")
+
+	provider, err := marketdata.NewCSVProvider(path)
+	if err != nil {
+		t.Fatalf("NewCSVProvider: %v", err)
+	}
+
+	ctx := context.Background()
+	bars, err := provider.Bars(ctx, "SPY", mustDate(t, "2024-07-02"), mustDate(t, "2024-07-31"))
+	if err != nil {
+		t.Fatalf("Bars: %v", err)
+	}
+	if len(bars) != 1 || bars[0].CloseMicros != 549_010_000 {
+		t.Errorf("SPY bars = %+v, want the July 2 close", bars)
+	}
+	if _, err := provider.Bars(ctx, "QQQ", mustDate(t, "2024-07-01"), mustDate(t, "2024-07-31")); err != marketdata.ErrNotFound {
+		t.Errorf("QQQ bars error = %v, want ErrNotFound", err)
+	}
+}
+
+func createHeldSecurity(t *testing.T, queries *db.Queries, accountID, symbol, firstBuy string) string {
+	t.Helper()
+	ctx := context.Background()
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: symbol,
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       accountID,
+		SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		TransactionType: "buy",
+		TransactionDate: firstBuy,
+		QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+		AmountMicros:    2_000_000_000,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	return sec.ID
+}
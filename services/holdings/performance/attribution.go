@@ -0,0 +1,93 @@
+package performance
+
+import (
+	"sort"
+	"time"
+)
+
+// Segment is one security type's part of an account over a period, beside
+// the benchmark's part of the same type.
+type Segment struct {
+	SecurityType     string
+	StartValueMicros int64
+	EndValueMicros   int64
+	NetFlowMicros    int64   // bought or moved in, less sold, moved out or paid out as income
+	Weight           float64 // share of the account's average invested capital
+	Return           float64
+	BenchmarkWeight  float64
+	BenchmarkReturn  float64
+	Allocation       float64
+	Selection        float64
+}
+
+// Attribution splits an account's return over a period against its
+// benchmark's into allocation (weighting security types differently) and
+// selection (doing better or worse within a type). Cash is left out.
+type Attribution struct {
+	Period          Period
+	Start           time.Time
+	End             time.Time
+	Benchmark       string
+	Return          float64 // the segments' weighted return
+	BenchmarkReturn float64
+	Allocation      float64
+	Selection       float64
+	Segments        []Segment
+}
+
+// Attribute computes Brinson-Fachler effects for segments with their
+// weights and returns set, folding interaction into selection:
+//
+//	allocation = (weight - benchmark weight) * (benchmark segment return - benchmark return)
+//	selection  = weight * (return - benchmark segment return)
+//
+// so the two add up to the return less the benchmark's. A segment the
+// benchmark doesn't hold is measured against the whole benchmark.
+func Attribute(segments []Segment) Attribution {
+	var a Attribution
+	var benchmarkWeight float64
+	for _, s := range segments {
+		a.Return += s.Weight * s.Return
+		a.BenchmarkReturn += s.BenchmarkWeight * s.BenchmarkReturn
+		benchmarkWeight += s.BenchmarkWeight
+	}
+	if benchmarkWeight > 0 {
+		a.BenchmarkReturn /= benchmarkWeight
+	}
+
+	for _, s := range segments {
+		if s.BenchmarkWeight == 0 {
+			s.BenchmarkReturn = a.BenchmarkReturn
+		}
+		s.Allocation = (s.Weight - s.BenchmarkWeight) * (s.BenchmarkReturn - a.BenchmarkReturn)
+		s.Selection = s.Weight * (s.Return - s.BenchmarkReturn)
+		a.Allocation += s.Allocation
+		a.Selection += s.Selection
+		a.Segments = append(a.Segments, s)
+	}
+	sort.SliceStable(a.Segments, func(i, j int) bool { return a.Segments[i].SecurityType < a.Segments[j].SecurityType })
+
+	return a
+}
+
+// ModifiedDietz is the return of a holding worth startValue at start and
+// endValue at end, with flows into it (negative out) in between weighted by
+// the share of the period they were invested. capital is the average
+// invested, for weighting; the return is 0 when it isn't positive.
+func ModifiedDietz(start, end time.Time, startValue, endValue int64, flows []CashFlow) (ret, capital float64) {
+	period := end.Sub(start).Hours()
+	capital = float64(startValue)
+	var net int64
+	for _, f := range flows {
+		net += f.AmountMicros
+		weight := 1.0
+		if period > 0 {
+			weight = end.Sub(f.Date).Hours() / period
+		}
+		capital += float64(f.AmountMicros) * weight
+	}
+	if capital <= 0 {
+		return 0, 0
+	}
+	return float64(endValue-startValue-net) / capital, capital
+}
@@ -0,0 +1,250 @@
+package performance
+
+import (
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+const (
+	RebalanceMonthly = "monthly" // back to target weights at each month end
+	RebalanceNone    = "none"    // bought and held
+)
+
+// Default benchmarks for accounts without one set: mostly bonds against
+// munis, anything else against the total market.
+const (
+	DefaultBondBenchmark  = "MUNI"
+	DefaultStockBenchmark = "TOTAL"
+)
+
+// Component is one security in a benchmark.
+type Component struct {
+	Symbol       string
+	Weight       float64
+	SecurityType string // attribution segment, e.g. equity or bond
+}
+
+// Benchmark is a fixed-weight mix of securities valued from stored closes,
+// with dividends reinvested.
+type Benchmark struct {
+	Name        string
+	Description string
+	Rebalance   string
+	Components  []Component
+}
+
+// Builtin benchmarks; a stored benchmark of the same name replaces one.
+var Builtin = []Benchmark{
+	{
+		Name:        "SPY",
+		Description: "S&P 500 (SPY)",
+		Rebalance:   RebalanceNone,
+		Components:  []Component{{Symbol: "SPY", Weight: 1, SecurityType: "equity"}},
+	},
+	{
+		Name:        "AGG",
+		Description: "US aggregate bonds (AGG)",
+		Rebalance:   RebalanceNone,
+		Components:  []Component{{Symbol: "AGG", Weight: 1, SecurityType: "bond"}},
+	},
+	{
+		Name:        "60/40",
+		Description: "60% SPY, 40% AGG, rebalanced monthly",
+		Rebalance:   RebalanceMonthly,
+		Components: []Component{
+			{Symbol: "SPY", Weight: 0.6, SecurityType: "equity"},
+			{Symbol: "AGG", Weight: 0.4, SecurityType: "bond"},
+		},
+	},
+	{
+		Name:        DefaultBondBenchmark,
+		Description: "National municipal bonds (MUB)",
+		Rebalance:   RebalanceNone,
+		Components:  []Component{{Symbol: "MUB", Weight: 1, SecurityType: "bond"}},
+	},
+	{
+		Name:        DefaultStockBenchmark,
+		Description: "US total stock market (VTI)",
+		Rebalance:   RebalanceNone,
+		Components:  []Component{{Symbol: "VTI", Weight: 1, SecurityType: "equity"}},
+	},
+}
+
+// Symbols returns the component symbols of benchmarks, without duplicates.
+func Symbols(benchmarks []Benchmark) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, b := range benchmarks {
+		for _, c := range b.Components {
+			if !seen[c.Symbol] {
+				seen[c.Symbol] = true
+				symbols = append(symbols, c.Symbol)
+			}
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Levels values the benchmark on each of dates, which must be in order: 1 on
+// the first date every component has a close and growing with the
+// components after that, or 0 before it. closes and actions are keyed by
+// symbol and sorted by date. Splits scale a component's units on their date
+// and dividends are reinvested at that day's close.
+func (b Benchmark) Levels(dates []time.Time, closes map[string][]db.Price, actions map[string][]db.CorporateAction) []float64 {
+	var total float64
+	for _, c := range b.Components {
+		total += c.Weight
+	}
+
+	levels := make([]float64, len(dates))
+	if total <= 0 {
+		return levels
+	}
+
+	n := len(b.Components)
+	units := make([]float64, n)
+	prices := make([]float64, n)
+	closeIndex := make([]int, n)
+	actionIndex := make([]int, n)
+	for i := range closeIndex {
+		closeIndex[i] = -1
+	}
+
+	started := false
+	var level float64
+	var prev time.Time
+	for d, day := range dates {
+		date := day.Format("2006-01-02")
+
+		// A new month rebalances at the last month end's closes.
+		if started && b.Rebalance == RebalanceMonthly && day.Month() != prev.Month() {
+			for i, c := range b.Components {
+				units[i] = c.Weight / total * level / prices[i]
+			}
+		}
+
+		priced := true
+		for i, c := range b.Components {
+			series := closes[c.Symbol]
+			for closeIndex[i]+1 < len(series) && series[closeIndex[i]+1].PriceDate <= date {
+				closeIndex[i]++
+			}
+			if closeIndex[i] < 0 {
+				priced = false
+				continue
+			}
+			prices[i] = float64(series[closeIndex[i]].CloseMicros)
+
+			// Skip actions before the start; apply those since the last date.
+			for ; actionIndex[i] < len(actions[c.Symbol]) && actions[c.Symbol][actionIndex[i]].ActionDate <= date; actionIndex[i]++ {
+				a := actions[c.Symbol][actionIndex[i]]
+				if !started {
+					continue
+				}
+				switch a.ActionType {
+				case "split":
+					if a.Ratio.Valid && a.Ratio.Float64 > 0 {
+						units[i] *= a.Ratio.Float64
+					}
+				case "dividend":
+					if a.AmountMicros.Valid && prices[i] > 0 {
+						units[i] += units[i] * float64(a.AmountMicros.Int64) / prices[i]
+					}
+				}
+			}
+		}
+		prev = day
+		if !priced {
+			continue
+		}
+
+		if !started {
+			started = true
+			for i, c := range b.Components {
+				units[i] = c.Weight / total / prices[i]
+			}
+		}
+		level = 0
+		for i := range b.Components {
+			level += units[i] * prices[i]
+		}
+		levels[d] = level
+	}
+
+	return levels
+}
+
+// Comparison is an account's time-weighted return over one period beside
+// its benchmark's return from the same starting day.
+type Comparison struct {
+	Period          Period
+	Start           time.Time
+	End             time.Time
+	TWR             float64
+	BenchmarkReturn float64
+	BenchmarkValid  bool // the benchmark has closes from the start of the period
+}
+
+// Excess is the account's return over the benchmark's.
+func (c Comparison) Excess() float64 {
+	return c.TWR - c.BenchmarkReturn
+}
+
+// Compare measures days and the benchmark levels on the same days over each
+// period, as Compute does.
+func Compare(days []Day, levels []float64) []Comparison {
+	var comparisons []Comparison
+	index := Index(days)
+	for _, pb := range periodBases(days) {
+		c := Comparison{Period: pb.period, End: days[len(days)-1].Date}
+		last := len(days) - 1
+		from := pb.base
+		if from < 0 {
+			c.Start = days[0].Date
+			c.TWR = index[last] - 1
+			from = 0
+		} else {
+			c.Start = days[from].Date
+			c.TWR = index[last]/index[from] - 1
+		}
+		if levels[from] > 0 && levels[last] > 0 {
+			c.BenchmarkValid = true
+			c.BenchmarkReturn = levels[last]/levels[from] - 1
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// SeriesPoint is the cumulative return of an account and of its benchmark
+// from the first day of a series.
+type SeriesPoint struct {
+	Date            time.Time
+	Return          float64
+	BenchmarkReturn float64
+	BenchmarkValid  bool // the benchmark has closes on the first day and this one
+}
+
+// Series returns cumulative returns for each day from start on, measured
+// from the first of them.
+func Series(days []Day, levels []float64, start time.Time) []SeriesPoint {
+	first := sort.Search(len(days), func(i int) bool { return !days[i].Date.Before(start) })
+	if first == len(days) {
+		return nil
+	}
+
+	index := Index(days)
+	points := make([]SeriesPoint, 0, len(days)-first)
+	for i := first; i < len(days); i++ {
+		p := SeriesPoint{Date: days[i].Date, Return: index[i]/index[first] - 1}
+		if levels[first] > 0 && levels[i] > 0 {
+			p.BenchmarkValid = true
+			p.BenchmarkReturn = levels[i]/levels[first] - 1
+		}
+		points = append(points, p)
+	}
+	return points
+}
@@ -0,0 +1,117 @@
+package performance_test
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/performance"
+)
+
+func TestBenchmarkLevels(t *testing.T) {
+	var sixtyForty performance.Benchmark
+	for _, b := range performance.Builtin {
+		if b.Name == "60/40" {
+			sixtyForty = b
+		}
+	}
+
+	closes := map[string][]db.Price{
+		"SPY": {
+			{PriceDate: "2024-01-29", CloseMicros: 90_000_000},
+			{PriceDate: "2024-01-30", CloseMicros: 100_000_000},
+			{PriceDate: "2024-01-31", CloseMicros: 110_000_000},
+			{PriceDate: "2024-02-01", CloseMicros: 60_500_000}, // 121 before the split
+		},
+		"AGG": {
+			{PriceDate: "2024-01-30", CloseMicros: 100_000_000},
+		},
+	}
+	actions := map[string][]db.CorporateAction{
+		"SPY": {{ActionDate: "2024-02-01", ActionType: "split", Ratio: sql.NullFloat64{Float64: 2, Valid: true}}},
+		"AGG": {
+			{ActionDate: "2024-01-02", ActionType: "dividend", AmountMicros: sql.NullInt64{Int64: 5_000_000, Valid: true}}, // before the start
+			{ActionDate: "2024-02-01", ActionType: "dividend", AmountMicros: sql.NullInt64{Int64: 1_000_000, Valid: true}},
+		},
+	}
+	dates := []time.Time{mustDate(t, "2024-01-29"), mustDate(t, "2024-01-30"), mustDate(t, "2024-01-31"), mustDate(t, "2024-02-01")}
+
+	// Starts when AGG has a close; SPY's +10% on Jan 31 is rebalanced back to
+	// 60/40 before February's +10% and AGG's 1% dividend.
+	got := sixtyForty.Levels(dates, closes, actions)
+	want := []float64{0, 1, 1.06, 1.06*0.6*1.1 + 1.06*0.4*1.01}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("level on %s = %.6f, want %.6f", dates[i].Format("2006-01-02"), got[i], want[i])
+		}
+	}
+
+	// Bought and held, SPY keeps its January gain in its weight.
+	sixtyForty.Rebalance = performance.RebalanceNone
+	if got := sixtyForty.Levels(dates, closes, actions); math.Abs(got[3]-(0.6*1.21+0.4*1.01)) > 1e-9 {
+		t.Errorf("buy and hold level = %.6f, want %.6f", got[3], 0.6*1.21+0.4*1.01)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	days := []performance.Day{
+		{Date: mustDate(t, "2024-06-28"), MarketValueMicros: 1_000_000_000, NetFlowMicros: 1_000_000_000},
+		{Date: mustDate(t, "2024-07-31"), MarketValueMicros: 2_200_000_000, NetFlowMicros: 1_000_000_000},
+		{Date: mustDate(t, "2024-08-15"), MarketValueMicros: 2_310_000_000},
+	}
+	levels := []float64{0, 1.0, 1.02}
+
+	comparisons := performance.Compare(days, levels)
+	if len(comparisons) != 3 {
+		t.Fatalf("got %+v, want MTD, QTD and inception", comparisons)
+	}
+	if c := comparisons[0]; c.Period != performance.PeriodMTD || math.Abs(c.TWR-0.05) > 1e-9 || !c.BenchmarkValid || math.Abs(c.Excess()-0.03) > 1e-9 {
+		t.Errorf("MTD = %+v, want 5%% against 2%%", c)
+	}
+	if c := comparisons[2]; c.BenchmarkValid {
+		t.Errorf("inception = %+v, want no benchmark before its first close", c)
+	}
+
+	series := performance.Series(days, levels, mustDate(t, "2024-07-01"))
+	if len(series) != 2 || series[0].Return != 0 || math.Abs(series[1].Return-0.05) > 1e-9 || math.Abs(series[1].BenchmarkReturn-0.02) > 1e-9 {
+		t.Errorf("series = %+v, want 0%% then 5%% against 2%%", series)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	a := performance.Attribute([]performance.Segment{
+		{SecurityType: "equity", Weight: 0.7, Return: 0.10, BenchmarkWeight: 0.6, BenchmarkReturn: 0.08},
+		{SecurityType: "bond", Weight: 0.3, Return: 0.02, BenchmarkWeight: 0.4, BenchmarkReturn: 0.03},
+	})
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"return", a.Return, 0.076},
+		{"benchmark return", a.BenchmarkReturn, 0.06},
+		{"allocation", a.Allocation, 0.1*0.02 + 0.1*0.03}, // overweight the winner, underweight the loser
+		{"selection", a.Selection, 0.7*0.02 - 0.3*0.01},
+		{"total", a.Allocation + a.Selection, a.Return - a.BenchmarkReturn},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %.6f, want %.6f", c.name, c.got, c.want)
+		}
+	}
+	if a.Segments[0].SecurityType != "bond" {
+		t.Errorf("segments = %+v, want sorted by type", a.Segments)
+	}
+}
+
+func TestModifiedDietz(t *testing.T) {
+	// 1000 at the start, 500 added halfway, 1600 at the end: a 100 gain on
+	// 1250 invested on average.
+	ret, capital := performance.ModifiedDietz(mustDate(t, "2024-01-01"), mustDate(t, "2024-01-31"), 1_000_000_000, 1_600_000_000,
+		[]performance.CashFlow{{Date: mustDate(t, "2024-01-16"), AmountMicros: 500_000_000}})
+	if math.Abs(ret-0.08) > 1e-9 || math.Abs(capital-1_250_000_000) > 1 {
+		t.Errorf("ModifiedDietz = %.6f on %.0f, want 8%% on 1250000000", ret, capital)
+	}
+}
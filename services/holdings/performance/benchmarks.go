@@ -0,0 +1,423 @@
+package performance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// Benchmarks returns the built-in benchmarks, with stored ones replacing any
+// of the same name, followed by the other stored ones by name.
+func (c *Calculator) Benchmarks(ctx context.Context) ([]Benchmark, error) {
+	rows, err := c.queries.ListBenchmarks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	components, err := c.queries.ListBenchmarkComponents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark components: %w", err)
+	}
+
+	stored := make(map[string]Benchmark)
+	for _, r := range rows {
+		stored[r.Name] = Benchmark{Name: r.Name, Description: r.Description.String, Rebalance: r.Rebalance}
+	}
+	for _, comp := range components {
+		b, ok := stored[comp.BenchmarkName]
+		if !ok {
+			continue
+		}
+		b.Components = append(b.Components, Component{Symbol: comp.Symbol, Weight: comp.Weight, SecurityType: comp.SecurityType})
+		stored[comp.BenchmarkName] = b
+	}
+
+	var benchmarks []Benchmark
+	for _, b := range Builtin {
+		if s, ok := stored[b.Name]; ok {
+			b = s
+			delete(stored, b.Name)
+		}
+		benchmarks = append(benchmarks, b)
+	}
+	var names []string
+	for name := range stored {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		benchmarks = append(benchmarks, stored[name])
+	}
+
+	return benchmarks, nil
+}
+
+// Benchmark returns the benchmark called name.
+func (c *Calculator) Benchmark(ctx context.Context, name string) (Benchmark, error) {
+	benchmarks, err := c.Benchmarks(ctx)
+	if err != nil {
+		return Benchmark{}, err
+	}
+	for _, b := range benchmarks {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Benchmark{}, fmt.Errorf("benchmark not found: %s", name)
+}
+
+// AccountBenchmark returns the account's benchmark: the one set on it, or
+// else DefaultBondBenchmark when bonds are most of its holdings' value on end
+// and DefaultStockBenchmark otherwise.
+func (c *Calculator) AccountBenchmark(ctx context.Context, account db.Account, end time.Time) (Benchmark, error) {
+	if account.Benchmark.Valid && account.Benchmark.String != "" {
+		return c.Benchmark(ctx, account.Benchmark.String)
+	}
+
+	h, err := c.loadHoldings(ctx, account.ID, end)
+	if err != nil {
+		return Benchmark{}, err
+	}
+	date := end.Format("2006-01-02")
+	var bonds, total int64
+	for securityID := range h.securityTypes {
+		value := h.value(securityID, h.quantity(securityID, date), date)
+		total += value
+		if h.securityType(securityID) == "bond" {
+			bonds += value
+		}
+	}
+	if total > 0 && bonds*2 > total {
+		return c.Benchmark(ctx, DefaultBondBenchmark)
+	}
+	return c.Benchmark(ctx, DefaultStockBenchmark)
+}
+
+// BenchmarkLevels values b on each of dates through the last one; see
+// Benchmark.Levels.
+func (c *Calculator) BenchmarkLevels(ctx context.Context, b Benchmark, dates []time.Time) ([]float64, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+	closes, actions, err := c.loadBenchmarkPrices(ctx, b, dates[len(dates)-1])
+	if err != nil {
+		return nil, err
+	}
+	return b.Levels(dates, closes, actions), nil
+}
+
+func (c *Calculator) loadBenchmarkPrices(ctx context.Context, b Benchmark, end time.Time) (map[string][]db.Price, map[string][]db.CorporateAction, error) {
+	prices, err := c.queries.ListPricesThrough(ctx, end.Format("2006-01-02"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	bySecurity := make(map[string][]db.Price)
+	for _, p := range prices {
+		bySecurity[p.SecurityID] = append(bySecurity[p.SecurityID], p)
+	}
+
+	closes := make(map[string][]db.Price)
+	actions := make(map[string][]db.CorporateAction)
+	for _, comp := range b.Components {
+		sec, err := c.queries.GetSecurityBySymbol(ctx, comp.Symbol)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get security %s: %w", comp.Symbol, err)
+		}
+		closes[comp.Symbol] = bySecurity[sec.ID]
+		actions[comp.Symbol], err = c.queries.ListCorporateActions(ctx, sec.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list corporate actions for %s: %w", comp.Symbol, err)
+		}
+	}
+	return closes, actions, nil
+}
+
+// Compare measures accountID against b over each period through the last
+// valuation on or before end.
+func (c *Calculator) Compare(ctx context.Context, accountID string, b Benchmark, end time.Time) ([]Comparison, error) {
+	days, err := c.Days(ctx, accountID, end)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := c.BenchmarkLevels(ctx, b, dayDates(days))
+	if err != nil {
+		return nil, err
+	}
+	return Compare(days, levels), nil
+}
+
+// Series returns the cumulative returns of accountID and b for each
+// valuation from start through end.
+func (c *Calculator) Series(ctx context.Context, accountID string, b Benchmark, start, end time.Time) ([]SeriesPoint, error) {
+	days, err := c.Days(ctx, accountID, end)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := c.BenchmarkLevels(ctx, b, dayDates(days))
+	if err != nil {
+		return nil, err
+	}
+	return Series(days, levels, start), nil
+}
+
+// Attribution attributes accountID's return over period, through the last
+// valuation on or before end, against b by security type. Segment returns
+// are modified Dietz: shares bought or moved in are flows at the day's
+// close and income paid on a security flows out of its segment, so both
+// count towards its return.
+func (c *Calculator) Attribution(ctx context.Context, accountID string, b Benchmark, period Period, end time.Time) (Attribution, error) {
+	days, err := c.Days(ctx, accountID, end)
+	if err != nil {
+		return Attribution{}, err
+	}
+	var base *periodBase
+	for _, pb := range periodBases(days) {
+		if pb.period == period {
+			base = &pb
+			break
+		}
+	}
+	if base == nil {
+		return Attribution{}, fmt.Errorf("no valuations before the start of %s", period)
+	}
+
+	h, err := c.loadHoldings(ctx, accountID, end)
+	if err != nil {
+		return Attribution{}, err
+	}
+
+	// Since inception starts with nothing on the first change or valuation,
+	// with that day's changes as flows.
+	inception := base.base < 0
+	last := days[len(days)-1].Date
+	start := days[0].Date
+	if !inception {
+		start = days[base.base].Date
+	} else if len(h.changes) > 0 && h.changes[0].ChangeDate < start.Format("2006-01-02") {
+		start = parseDate(h.changes[0].ChangeDate)
+	}
+	from, to := start.Format("2006-01-02"), last.Format("2006-01-02")
+
+	type segmentFlows struct {
+		Segment
+		flows []CashFlow
+	}
+	segments := make(map[string]*segmentFlows)
+	segmentFor := func(securityID string) *segmentFlows {
+		t := h.securityType(securityID)
+		if segments[t] == nil {
+			segments[t] = &segmentFlows{Segment: Segment{SecurityType: t}}
+		}
+		return segments[t]
+	}
+
+	for securityID := range h.securityTypes {
+		var startValue int64
+		if !inception {
+			startValue = h.value(securityID, h.quantity(securityID, from), from)
+		}
+		endValue := h.value(securityID, h.quantity(securityID, to), to)
+		if startValue == 0 && endValue == 0 {
+			continue
+		}
+		s := segmentFor(securityID)
+		s.StartValueMicros += startValue
+		s.EndValueMicros += endValue
+	}
+	for _, ch := range h.changes {
+		if ch.ChangeDate < from || (ch.ChangeDate == from && !inception) || ch.ChangeDate > to {
+			continue
+		}
+		amount := h.value(ch.SecurityID, h.signed(ch), ch.ChangeDate)
+		s := segmentFor(ch.SecurityID)
+		s.NetFlowMicros += amount
+		s.flows = append(s.flows, CashFlow{Date: parseDate(ch.ChangeDate), AmountMicros: amount})
+	}
+	incomeFrom := from
+	if inception {
+		incomeFrom = start.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	income, err := c.queries.ListSecurityIncome(ctx, db.ListSecurityIncomeParams{
+		AccountID: accountID,
+		StartDate: incomeFrom,
+		EndDate:   to,
+	})
+	if err != nil {
+		return Attribution{}, fmt.Errorf("failed to list income: %w", err)
+	}
+	for _, inc := range income {
+		s := segmentFor(inc.SecurityID)
+		s.NetFlowMicros -= inc.AmountMicros
+		s.flows = append(s.flows, CashFlow{Date: parseDate(inc.TransactionDate), AmountMicros: -inc.AmountMicros})
+	}
+
+	var totalCapital float64
+	capital := make(map[string]float64)
+	for t, s := range segments {
+		s.Return, capital[t] = ModifiedDietz(start, last, s.StartValueMicros, s.EndValueMicros, s.flows)
+		totalCapital += capital[t]
+	}
+	if totalCapital > 0 {
+		for t, s := range segments {
+			s.Weight = capital[t] / totalCapital
+		}
+	}
+
+	// The benchmark's segments hold its components at their target weights.
+	closes, actions, err := c.loadBenchmarkPrices(ctx, b, last)
+	if err != nil {
+		return Attribution{}, err
+	}
+	var totalWeight float64
+	for _, comp := range b.Components {
+		totalWeight += comp.Weight
+	}
+	benchmarkReturns := make(map[string]float64)
+	for _, comp := range b.Components {
+		levels := Benchmark{Components: []Component{comp}}.Levels([]time.Time{start, last}, closes, actions)
+		if levels[0] == 0 {
+			return Attribution{}, fmt.Errorf("no %s close on or before %s; run prices sync", comp.Symbol, from)
+		}
+		s := segments[comp.SecurityType]
+		if s == nil {
+			s = &segmentFlows{Segment: Segment{SecurityType: comp.SecurityType}}
+			segments[comp.SecurityType] = s
+		}
+		weight := comp.Weight / totalWeight
+		s.BenchmarkWeight += weight
+		benchmarkReturns[comp.SecurityType] += weight * (levels[1]/levels[0] - 1)
+	}
+
+	var list []Segment
+	for t, s := range segments {
+		if s.BenchmarkWeight > 0 {
+			s.BenchmarkReturn = benchmarkReturns[t] / s.BenchmarkWeight
+		}
+		list = append(list, s.Segment)
+	}
+
+	a := Attribute(list)
+	a.Period = period
+	a.Start = start
+	a.End = last
+	a.Benchmark = b.Name
+	return a, nil
+}
+
+// holdings replays an account's lot changes to value its securities on any
+// date through the end it was loaded for.
+type holdings struct {
+	changes       []db.ListLotQuantityChangesRow
+	closes        map[string][]db.Price
+	securityTypes map[string]string
+	pricing       *taxlots.Pricing
+}
+
+func (c *Calculator) loadHoldings(ctx context.Context, accountID string, end time.Time) (*holdings, error) {
+	all, err := c.queries.ListLotQuantityChanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lot quantity changes: %w", err)
+	}
+	prices, err := c.queries.ListPricesThrough(ctx, end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	securities, err := c.queries.ListSecurities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list securities: %w", err)
+	}
+	bonds, err := c.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	options, err := c.queries.ListOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list options: %w", err)
+	}
+
+	types := make(map[string]string)
+	for _, s := range securities {
+		types[s.ID] = s.SecurityType.String
+	}
+
+	h := &holdings{
+		closes:        make(map[string][]db.Price),
+		securityTypes: make(map[string]string),
+		pricing:       taxlots.NewPricing(bonds, options),
+	}
+	for _, ch := range all {
+		if ch.AccountID != accountID {
+			continue
+		}
+		h.changes = append(h.changes, ch)
+		h.securityTypes[ch.SecurityID] = types[ch.SecurityID]
+	}
+	sort.SliceStable(h.changes, func(i, j int) bool { return h.changes[i].ChangeDate < h.changes[j].ChangeDate })
+	for _, p := range prices {
+		if _, ok := h.securityTypes[p.SecurityID]; ok {
+			h.closes[p.SecurityID] = append(h.closes[p.SecurityID], p)
+		}
+	}
+	return h, nil
+}
+
+// securityType is the attribution segment of a security: its type, or
+// equity when it has none.
+func (h *holdings) securityType(securityID string) string {
+	if t := h.securityTypes[securityID]; t != "" {
+		return t
+	}
+	return "equity"
+}
+
+// signed is a change's quantity, negative for short positions.
+func (h *holdings) signed(ch db.ListLotQuantityChangesRow) int64 {
+	if ch.PositionSide == string(taxlots.PositionShort) {
+		return -ch.QuantityMicros
+	}
+	return ch.QuantityMicros
+}
+
+// quantity is the position in securityID at the end of date.
+func (h *holdings) quantity(securityID, date string) int64 {
+	var quantity int64
+	for _, ch := range h.changes {
+		if ch.ChangeDate > date {
+			break
+		}
+		if ch.SecurityID == securityID {
+			quantity += h.signed(ch)
+		}
+	}
+	return quantity
+}
+
+// value is quantity of securityID at its last close on or before date, or 0
+// without one.
+func (h *holdings) value(securityID string, quantity int64, date string) int64 {
+	if quantity == 0 {
+		return 0
+	}
+	series := h.closes[securityID]
+	i := sort.Search(len(series), func(i int) bool { return series[i].PriceDate > date })
+	if i == 0 {
+		return 0
+	}
+	return h.pricing.MarketValue(securityID, quantity, series[i-1].CloseMicros)
+}
+
+func dayDates(days []Day) []time.Time {
+	dates := make([]time.Time, len(days))
+	for i, d := range days {
+		dates[i] = d.Date
+	}
+	return dates
+}
@@ -0,0 +1,68 @@
+package performance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// Calculator computes returns from stored daily valuations; run
+// "portfolio rebuild" first to bring them up to date.
+type Calculator struct {
+	queries *db.Queries
+}
+
+func NewCalculator(queries *db.Queries) *Calculator {
+	return &Calculator{queries: queries}
+}
+
+// Returns computes every period's returns for accountID, or for all accounts
+// together when it's empty, through the last valuation on or before end.
+func (c *Calculator) Returns(ctx context.Context, accountID string, end time.Time) ([]Return, error) {
+	days, err := c.Days(ctx, accountID, end)
+	if err != nil {
+		return nil, err
+	}
+	return Compute(days), nil
+}
+
+// Days returns the stored daily values and flows of accountID, or of all
+// accounts summed when it's empty, through end.
+func (c *Calculator) Days(ctx context.Context, accountID string, end time.Time) ([]Day, error) {
+	to := end.Format("2006-01-02")
+
+	var days []Day
+	if accountID != "" {
+		rows, err := c.queries.ListValuationsByAccount(ctx, db.ListValuationsByAccountParams{
+			AccountID: accountID,
+			StartDate: "",
+			EndDate:   to,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list valuations: %w", err)
+		}
+		for _, r := range rows {
+			days = append(days, Day{Date: parseDate(r.ValuationDate), MarketValueMicros: r.MarketValueMicros, NetFlowMicros: r.NetFlowMicros, UnpricedCount: int(r.UnpricedCount)})
+		}
+		return days, nil
+	}
+
+	rows, err := c.queries.ListHouseholdValuations(ctx, db.ListHouseholdValuationsParams{
+		StartDate: "",
+		EndDate:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list valuations: %w", err)
+	}
+	for _, r := range rows {
+		days = append(days, Day{Date: parseDate(r.ValuationDate), MarketValueMicros: r.MarketValueMicros, NetFlowMicros: r.NetFlowMicros, UnpricedCount: int(r.UnpricedCount)})
+	}
+	return days, nil
+}
+
+func parseDate(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
@@ -0,0 +1,233 @@
+// Package performance computes time-weighted and money-weighted returns from
+// daily valuations.
+package performance
+
+import (
+	"math"
+	"time"
+)
+
+// Period is a standard reporting window ending on the last valuation.
+type Period string
+
+const (
+	PeriodMTD       Period = "mtd"
+	PeriodQTD       Period = "qtd"
+	PeriodYTD       Period = "ytd"
+	PeriodOneYear   Period = "1y"
+	PeriodThreeYear Period = "3y"
+	PeriodInception Period = "inception"
+)
+
+// Periods lists every period, shortest first.
+var Periods = []Period{PeriodMTD, PeriodQTD, PeriodYTD, PeriodOneYear, PeriodThreeYear, PeriodInception}
+
+// Day is one day's market value and the net external flow into the account
+// that day (negative for withdrawals), both at the close.
+type Day struct {
+	Date              time.Time
+	MarketValueMicros int64
+	NetFlowMicros     int64
+	UnpricedCount     int // holdings without a price, left out of the value
+}
+
+// CashFlow is money in (negative, from the investor's side) or out (positive)
+// on a date, for XIRR.
+type CashFlow struct {
+	Date         time.Time
+	AmountMicros int64
+}
+
+// Return is the performance of one period. Start is the valuation the period
+// is measured from: the last one on or before the day before the period, or
+// zero before the first valuation for since-inception returns.
+type Return struct {
+	Period           Period
+	Start            time.Time
+	End              time.Time
+	StartValueMicros int64
+	EndValueMicros   int64
+	NetFlowMicros    int64
+	GainMicros       int64   // change in value less net flows
+	TWR              float64 // cumulative time-weighted return, 0.05 = 5%
+	AnnualizedTWR    float64 // annualized over periods longer than a year; TWR otherwise
+	XIRR             float64 // annualized money-weighted return
+	XIRRValid        bool    // false when the flows have no solution
+}
+
+// PeriodBase is the date a period's starting value is taken from, for a
+// period ending on end.
+func PeriodBase(p Period, end time.Time) time.Time {
+	switch p {
+	case PeriodMTD:
+		return time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	case PeriodQTD:
+		quarterStart := time.Month((int(end.Month())-1)/3*3 + 1)
+		return time.Date(end.Year(), quarterStart, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	case PeriodYTD:
+		return time.Date(end.Year(), 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	case PeriodOneYear:
+		return end.AddDate(-1, 0, 0)
+	case PeriodThreeYear:
+		return end.AddDate(-3, 0, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// Compute returns each period's performance over days, which must be in date
+// order. Periods reaching back before the first valuation are left out,
+// except since inception.
+func Compute(days []Day) []Return {
+	var returns []Return
+	index := Index(days)
+	for _, pb := range periodBases(days) {
+		returns = append(returns, periodReturn(pb.period, days, index, pb.base))
+	}
+	return returns
+}
+
+type periodBase struct {
+	period Period
+	base   int // index of the starting valuation; -1 is inception at zero
+}
+
+// periodBases finds where each period available over days starts: the last
+// day on or before its base date.
+func periodBases(days []Day) []periodBase {
+	if len(days) == 0 {
+		return nil
+	}
+	end := days[len(days)-1].Date
+
+	var bases []periodBase
+	for _, p := range Periods {
+		base := -1
+		if p != PeriodInception {
+			baseDate := PeriodBase(p, end)
+			for i, d := range days {
+				if d.Date.After(baseDate) {
+					break
+				}
+				base = i
+			}
+			if base < 0 {
+				continue
+			}
+		}
+		bases = append(bases, periodBase{period: p, base: base})
+	}
+	return bases
+}
+
+// Index is the cumulative time-weighted growth of days from nothing before
+// the first, so the return between two days is the ratio of their values
+// less one. Flows are taken to arrive at the start of their day, so each
+// day's return is its close over the previous close plus the day's flow. A
+// day on which a holding gains or loses its price counts as flat, since its
+// value appearing or dropping out isn't a return.
+func Index(days []Day) []float64 {
+	index := make([]float64, len(days))
+	growth := 1.0
+	var prevValue int64
+	var prevUnpriced int
+	for i, d := range days {
+		if invested := prevValue + d.NetFlowMicros; invested > 0 && d.UnpricedCount == prevUnpriced {
+			growth *= float64(d.MarketValueMicros) / float64(invested)
+		}
+		index[i] = growth
+		prevValue = d.MarketValueMicros
+		prevUnpriced = d.UnpricedCount
+	}
+	return index
+}
+
+// periodReturn measures days after base through the last day.
+func periodReturn(p Period, days []Day, index []float64, base int) Return {
+	last := len(days) - 1
+	r := Return{Period: p, End: days[last].Date, EndValueMicros: days[last].MarketValueMicros}
+
+	growth := index[last]
+	var flows []CashFlow
+	if base >= 0 {
+		r.Start = days[base].Date
+		r.StartValueMicros = days[base].MarketValueMicros
+		growth /= index[base]
+		flows = append(flows, CashFlow{Date: r.Start, AmountMicros: -r.StartValueMicros})
+	} else {
+		r.Start = days[0].Date
+	}
+
+	for _, d := range days[base+1:] {
+		r.NetFlowMicros += d.NetFlowMicros
+		if d.NetFlowMicros != 0 {
+			flows = append(flows, CashFlow{Date: d.Date, AmountMicros: -d.NetFlowMicros})
+		}
+	}
+	flows = append(flows, CashFlow{Date: r.End, AmountMicros: r.EndValueMicros})
+
+	r.GainMicros = r.EndValueMicros - r.StartValueMicros - r.NetFlowMicros
+	r.TWR = growth - 1
+	r.AnnualizedTWR = r.TWR
+	if years := yearsBetween(r.Start, r.End); years > 1 {
+		r.AnnualizedTWR = math.Pow(growth, 1/years) - 1
+	}
+	r.XIRR, r.XIRRValid = XIRR(flows)
+
+	return r
+}
+
+// XIRR is the annual rate at which the flows' present value is zero, with
+// flows discounted from the first one's date by actual/365. It needs at least
+// one flow in each direction.
+func XIRR(flows []CashFlow) (float64, bool) {
+	var in, out bool
+	for _, f := range flows {
+		in = in || f.AmountMicros < 0
+		out = out || f.AmountMicros > 0
+	}
+	if !in || !out {
+		return 0, false
+	}
+
+	first := flows[0].Date
+	for _, f := range flows {
+		if f.Date.Before(first) {
+			first = f.Date
+		}
+	}
+	npv := func(rate float64) float64 {
+		var sum float64
+		for _, f := range flows {
+			years := f.Date.Sub(first).Hours() / 24 / 365
+			sum += float64(f.AmountMicros) / math.Pow(1+rate, years)
+		}
+		return sum
+	}
+
+	// Bisect between a near-total loss and a rate high enough to flip the
+	// sign of the present value.
+	lo, hi := -0.9999, 1.0
+	for npv(lo)*npv(hi) > 0 {
+		if hi > 1e6 {
+			return 0, false
+		}
+		hi *= 2
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if npv(lo)*npv(mid) <= 0 {
+			hi = mid
+		} else {
+			lo = mid
+		}
+		if hi-lo < 1e-10 {
+			break
+		}
+	}
+	return (lo + hi) / 2, true
+}
+
+func yearsBetween(start, end time.Time) float64 {
+	return end.Sub(start).Hours() / 24 / 365
+}
@@ -0,0 +1,131 @@
+package performance_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/performance"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestCompute(t *testing.T) {
+	day := func(date string, value, flow int64) performance.Day {
+		return performance.Day{Date: mustDate(t, date), MarketValueMicros: value * 1_000_000, NetFlowMicros: flow * 1_000_000}
+	}
+	days := []performance.Day{
+		day("2023-12-29", 1000, 1000), // opened with a deposit
+		day("2024-06-28", 1100, 0),    // +10% in the first half
+		day("2024-07-01", 2100, 1000), // deposit, flat day
+		day("2024-07-31", 2310, 0),    // +10% in July
+		day("2024-08-15", 2079, 0),    // -10% in August
+	}
+
+	returns := performance.Compute(days)
+	got := make(map[performance.Period]performance.Return)
+	for _, r := range returns {
+		got[r.Period] = r
+	}
+	if _, ok := got[performance.PeriodOneYear]; ok {
+		t.Errorf("1y return = %+v, want none before the first valuation", got[performance.PeriodOneYear])
+	}
+
+	tests := []struct {
+		period performance.Period
+		start  string
+		twr    float64
+		flow   int64
+	}{
+		{performance.PeriodMTD, "2024-07-31", -0.10, 0},
+		{performance.PeriodQTD, "2024-06-28", 1.1*0.9 - 1, 1000},
+		{performance.PeriodYTD, "2023-12-29", 1.1*1.1*0.9 - 1, 1000},
+		{performance.PeriodInception, "2023-12-29", 1.1*1.1*0.9 - 1, 2000},
+	}
+	for _, tt := range tests {
+		r, ok := got[tt.period]
+		if !ok {
+			t.Errorf("no %s return", tt.period)
+			continue
+		}
+		if r.Start.Format("2006-01-02") != tt.start || math.Abs(r.TWR-tt.twr) > 1e-9 || r.NetFlowMicros != tt.flow*1_000_000 {
+			t.Errorf("%s = start %s TWR %.6f flow %d, want %s %.6f %d",
+				tt.period, r.Start.Format("2006-01-02"), r.TWR, r.NetFlowMicros, tt.start, tt.twr, tt.flow*1_000_000)
+		}
+		if r.GainMicros != r.EndValueMicros-r.StartValueMicros-r.NetFlowMicros {
+			t.Errorf("%s gain = %d, want end less start less flows", tt.period, r.GainMicros)
+		}
+	}
+
+	// Since inception, the first day's deposit is money in, not a gain: a
+	// 2000 deposited and a 79 gain.
+	if r := got[performance.PeriodInception]; r.StartValueMicros != 0 || r.GainMicros != 79_000_000 || !r.XIRRValid || r.XIRR <= 0 {
+		t.Errorf("inception = %+v, want a 79 gain from zero and a positive XIRR", r)
+	}
+	// Over the quarter the deposit came in for July's gain and stayed for
+	// August's loss, losing money while the time-weighted return is -1%.
+	if r := got[performance.PeriodQTD]; r.GainMicros != -21_000_000 || !r.XIRRValid || r.XIRR >= 0 {
+		t.Errorf("QTD = %+v, want a 21 loss and a negative XIRR", r)
+	}
+
+	// A holding's first price isn't a gain.
+	unpriced := []performance.Day{
+		day("2024-07-01", 1000, 1000),
+		day("2024-07-02", 1000, 0),
+		day("2024-07-03", 1500, 0),
+		day("2024-07-05", 1650, 0),
+	}
+	unpriced[0].UnpricedCount, unpriced[1].UnpricedCount = 1, 1
+	if r := performance.Compute(unpriced); math.Abs(r[len(r)-1].TWR-0.10) > 1e-9 {
+		t.Errorf("TWR with a holding priced on July 3 = %.6f, want 10%% from July 3", r[len(r)-1].TWR)
+	}
+
+	if got := performance.Compute(nil); got != nil {
+		t.Errorf("Compute(nil) = %+v, want nil", got)
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	// 1000 in, 1100 out a year later.
+	rate, ok := performance.XIRR([]performance.CashFlow{
+		{Date: mustDate(t, "2023-01-01"), AmountMicros: -1_000_000_000},
+		{Date: mustDate(t, "2024-01-01"), AmountMicros: 1_100_000_000},
+	})
+	if !ok || math.Abs(rate-0.0997) > 0.001 {
+		t.Errorf("XIRR = %.4f %v, want about 10%% (365 of 366 days)", rate, ok)
+	}
+
+	// Money only going in has no rate.
+	if _, ok := performance.XIRR([]performance.CashFlow{
+		{Date: mustDate(t, "2023-01-01"), AmountMicros: -1_000_000_000},
+		{Date: mustDate(t, "2024-01-01"), AmountMicros: -1_000_000_000},
+	}); ok {
+		t.Error("XIRR of deposits only = valid, want no solution")
+	}
+}
+
+func TestPeriodBase(t *testing.T) {
+	end := mustDate(t, "2024-08-15")
+	tests := []struct {
+		period performance.Period
+		want   string
+	}{
+		{performance.PeriodMTD, "2024-07-31"},
+		{performance.PeriodQTD, "2024-06-30"},
+		{performance.PeriodYTD, "2023-12-31"},
+		{performance.PeriodOneYear, "2023-08-15"},
+		{performance.PeriodThreeYear, "2021-08-15"},
+	}
+	for _, tt := range tests {
+		if got := performance.PeriodBase(tt.period, end).Format("2006-01-02"); got != tt.want {
+			t.Errorf("PeriodBase(%s) = %s, want %s", tt.period, got, tt.want)
+		}
+	}
+}
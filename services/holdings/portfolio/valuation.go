@@ -0,0 +1,335 @@
+// Package portfolio values accounts and the household over time.
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// Valuation is an account's market value at the close of a day.
+type Valuation struct {
+	AccountID             string
+	Date                  time.Time
+	SecuritiesValueMicros int64
+	CashMicros            int64
+	NetFlowMicros         int64 // external flows in, valued at the close
+	UnpricedCount         int   // holdings without a price, left out of the value
+}
+
+func (v Valuation) MarketValueMicros() int64 {
+	return v.SecuritiesValueMicros + v.CashMicros
+}
+
+// externalCashTypes are cash moved into or out of an account, as opposed to
+// trades, income and fees earned or paid inside it.
+var externalCashTypes = map[string]bool{
+	"opening":      true,
+	"deposit":      true,
+	"withdrawal":   true,
+	"transfer_in":  true,
+	"transfer_out": true,
+}
+
+// externalLotTypes are the lot changes that move shares into or out of an
+// account rather than trading them: opening balances (including gifts and
+// inheritances), transfers from other accounts or brokers, stock plan shares
+// paid for outside the account, and lot transfers out.
+var externalLotTypes = map[string]bool{
+	"opening_balance":   true,
+	"security_transfer": true,
+	"transfer_in":       true,
+	"rsu_vest":          true,
+	"espp_purchase":     true,
+	"transfer_out":      true,
+}
+
+// Point is one day of a valuation history, for an account or the household.
+type Point struct {
+	Date                  time.Time
+	SecuritiesValueMicros int64
+	CashMicros            int64
+	MarketValueMicros     int64
+	NetFlowMicros         int64
+	DayChangeMicros       int64
+	DayChangePercent      float64
+	UnpricedCount         int
+}
+
+// Valuer rebuilds and reads stored daily valuations.
+type Valuer struct {
+	queries *db.Queries
+}
+
+func NewValuer(queries *db.Queries) *Valuer {
+	return &Valuer{queries: queries}
+}
+
+// Rebuild replaces the stored valuations of every account with values on
+// each weekday from its first transaction through to, returning how many
+// were stored.
+func (v *Valuer) Rebuild(ctx context.Context, to time.Time) (int, error) {
+	changes, err := v.queries.ListLotQuantityChanges(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list lot quantity changes: %w", err)
+	}
+	cash, err := v.queries.ListDailyCashChanges(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cash changes: %w", err)
+	}
+	prices, err := v.queries.ListPricesThrough(ctx, to.Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list prices: %w", err)
+	}
+	bonds, err := v.queries.ListBonds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	options, err := v.queries.ListOptions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list options: %w", err)
+	}
+
+	valuations := BuildValuations(changes, cash, prices, taxlots.NewPricing(bonds, options), to)
+
+	if err := v.queries.DeleteValuations(ctx); err != nil {
+		return 0, fmt.Errorf("failed to delete valuations: %w", err)
+	}
+	for _, val := range valuations {
+		err := v.queries.CreateValuation(ctx, db.CreateValuationParams{
+			AccountID:             val.AccountID,
+			ValuationDate:         val.Date.Format("2006-01-02"),
+			SecuritiesValueMicros: val.SecuritiesValueMicros,
+			CashMicros:            val.CashMicros,
+			MarketValueMicros:     val.MarketValueMicros(),
+			UnpricedCount:         int64(val.UnpricedCount),
+			NetFlowMicros:         val.NetFlowMicros,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to save valuation: %w", err)
+		}
+	}
+
+	return len(valuations), nil
+}
+
+// History returns the stored daily values of accountID, or of all accounts
+// summed when it's empty, from start through end. Day changes are against the
+// previous stored day, including for the first point.
+func (v *Valuer) History(ctx context.Context, accountID string, start, end time.Time) ([]Point, error) {
+	// Look back far enough to find the weekday before start.
+	from := start.AddDate(0, 0, -7).Format("2006-01-02")
+	to := end.Format("2006-01-02")
+
+	var points []Point
+	if accountID != "" {
+		rows, err := v.queries.ListValuationsByAccount(ctx, db.ListValuationsByAccountParams{
+			AccountID: accountID,
+			StartDate: from,
+			EndDate:   to,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list valuations: %w", err)
+		}
+		for _, r := range rows {
+			points = append(points, Point{
+				Date:                  parseDate(r.ValuationDate),
+				SecuritiesValueMicros: r.SecuritiesValueMicros,
+				CashMicros:            r.CashMicros,
+				MarketValueMicros:     r.MarketValueMicros,
+				NetFlowMicros:         r.NetFlowMicros,
+				UnpricedCount:         int(r.UnpricedCount),
+			})
+		}
+	} else {
+		rows, err := v.queries.ListHouseholdValuations(ctx, db.ListHouseholdValuationsParams{
+			StartDate: from,
+			EndDate:   to,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list valuations: %w", err)
+		}
+		for _, r := range rows {
+			points = append(points, Point{
+				Date:                  parseDate(r.ValuationDate),
+				SecuritiesValueMicros: r.SecuritiesValueMicros,
+				CashMicros:            r.CashMicros,
+				MarketValueMicros:     r.MarketValueMicros,
+				NetFlowMicros:         r.NetFlowMicros,
+				UnpricedCount:         int(r.UnpricedCount),
+			})
+		}
+	}
+
+	result := make([]Point, 0, len(points))
+	for i, p := range points {
+		if i > 0 {
+			prev := points[i-1].MarketValueMicros
+			p.DayChangeMicros = p.MarketValueMicros - prev
+			if prev != 0 {
+				p.DayChangePercent = float64(p.DayChangeMicros) / float64(prev) * 100
+			}
+		}
+		if !p.Date.Before(start) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// BuildValuations replays lot quantity changes and daily cash changes into
+// each account's holdings and values them at the close of every weekday from
+// the account's first change through to. Changes on a weekend count from the
+// next weekday. Holdings are valued at their last close on or before the day,
+// and so are shares moved in or out for the day's net flow.
+func BuildValuations(changes []db.ListLotQuantityChangesRow, cash []db.ListDailyCashChangesRow, prices []db.Price, pricing *taxlots.Pricing, to time.Time) []Valuation {
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ChangeDate < changes[j].ChangeDate })
+	sort.SliceStable(cash, func(i, j int) bool { return cash[i].TransactionDate < cash[j].TransactionDate })
+
+	var start string
+	if len(changes) > 0 {
+		start = changes[0].ChangeDate
+	}
+	if len(cash) > 0 && (start == "" || cash[0].TransactionDate < start) {
+		start = cash[0].TransactionDate
+	}
+	if start == "" {
+		return nil
+	}
+
+	closes := make(map[string][]db.Price)
+	for _, p := range prices {
+		closes[p.SecurityID] = append(closes[p.SecurityID], p)
+	}
+	for _, series := range closes {
+		sort.SliceStable(series, func(i, j int) bool { return series[i].PriceDate < series[j].PriceDate })
+	}
+	// Index of each security's close for the current day, or -1 before its
+	// first close.
+	closeIndex := make(map[string]int)
+
+	quantities := make(map[string]map[string]int64)
+	balances := make(map[string]int64)
+	flows := make(map[string]int64)
+	var accounts []string
+	open := func(accountID string) {
+		if _, ok := quantities[accountID]; ok {
+			return
+		}
+		quantities[accountID] = make(map[string]int64)
+		accounts = append(accounts, accountID)
+		sort.Strings(accounts)
+	}
+
+	var valuations []Valuation
+	nextChange, nextCash := 0, 0
+	for day := parseDate(start); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		date := day.Format("2006-01-02")
+
+		for ; nextChange < len(changes) && changes[nextChange].ChangeDate <= date; nextChange++ {
+			c := changes[nextChange]
+			open(c.AccountID)
+			quantity := c.QuantityMicros
+			if c.PositionSide == string(taxlots.PositionShort) {
+				quantity = -quantity
+			}
+			quantities[c.AccountID][c.SecurityID] += quantity
+			if externalLotTypes[c.ChangeType] {
+				if closeMicros, ok := closeOn(closes[c.SecurityID], closeIndex, c.SecurityID, date); ok {
+					flows[c.AccountID] += pricing.MarketValue(c.SecurityID, quantity, closeMicros)
+				}
+			}
+		}
+		for ; nextCash < len(cash) && cash[nextCash].TransactionDate <= date; nextCash++ {
+			c := cash[nextCash]
+			open(c.AccountID)
+			balances[c.AccountID] += c.AmountMicros
+			if externalCashTypes[c.CashType] {
+				flows[c.AccountID] += c.AmountMicros
+			}
+		}
+
+		for _, accountID := range accounts {
+			val := Valuation{
+				AccountID:     accountID,
+				Date:          day,
+				CashMicros:    balances[accountID],
+				NetFlowMicros: flows[accountID],
+			}
+			delete(flows, accountID)
+			for securityID, quantity := range quantities[accountID] {
+				if quantity == 0 {
+					continue
+				}
+				closeMicros, ok := closeOn(closes[securityID], closeIndex, securityID, date)
+				if !ok {
+					val.UnpricedCount++
+					continue
+				}
+				val.SecuritiesValueMicros += pricing.MarketValue(securityID, quantity, closeMicros)
+			}
+			valuations = append(valuations, val)
+		}
+	}
+
+	return valuations
+}
+
+// closeOn returns the last close in series on or before date, advancing the
+// security's index. Dates must not go backwards between calls.
+func closeOn(series []db.Price, index map[string]int, securityID, date string) (int64, bool) {
+	i, ok := index[securityID]
+	if !ok {
+		i = -1
+	}
+	for i+1 < len(series) && series[i+1].PriceDate <= date {
+		i++
+	}
+	index[securityID] = i
+	if i < 0 {
+		return 0, false
+	}
+	return series[i].CloseMicros, true
+}
+
+// RangeStart returns the first day of a history range ending on end: 1d, 1w,
+// 1m, 3m, 6m, ytd, 1y, 3y, 5y or all.
+func RangeStart(r string, end time.Time) (time.Time, error) {
+	switch strings.ToLower(r) {
+	case "1d":
+		return end.AddDate(0, 0, -1), nil
+	case "1w":
+		return end.AddDate(0, 0, -7), nil
+	case "1m":
+		return end.AddDate(0, -1, 0), nil
+	case "3m":
+		return end.AddDate(0, -3, 0), nil
+	case "6m":
+		return end.AddDate(0, -6, 0), nil
+	case "ytd":
+		return time.Date(end.Year(), 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "1y":
+		return end.AddDate(-1, 0, 0), nil
+	case "3y":
+		return end.AddDate(-3, 0, 0), nil
+	case "5y":
+		return end.AddDate(-5, 0, 0), nil
+	case "all", "max":
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid range %q", r)
+	}
+}
+
+func parseDate(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
@@ -0,0 +1,98 @@
+package portfolio_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/portfolio"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestBuildValuations(t *testing.T) {
+	changes := []db.ListLotQuantityChangesRow{
+		{AccountID: "acct_joint", SecurityID: "sec_aapl", PositionSide: "long", ChangeDate: "2024-07-01", ChangeType: "buy", QuantityMicros: 10_000_000},
+		{AccountID: "acct_joint", SecurityID: "sec_unpriced", PositionSide: "long", ChangeDate: "2024-07-01", ChangeType: "buy", QuantityMicros: 1_000_000},
+		// Half sold on Friday.
+		{AccountID: "acct_joint", SecurityID: "sec_aapl", PositionSide: "long", ChangeDate: "2024-07-05", ChangeType: "disposition", QuantityMicros: -5_000_000},
+		// A share gifted on Saturday is a flow on Monday at Monday's close.
+		{AccountID: "acct_joint", SecurityID: "sec_aapl", PositionSide: "long", ChangeDate: "2024-07-06", ChangeType: "opening_balance", QuantityMicros: 1_000_000},
+		// Short 2 shares in another account, opened on a Saturday.
+		{AccountID: "acct_margin", SecurityID: "sec_aapl", PositionSide: "short", ChangeDate: "2024-07-06", ChangeType: "sell_short", QuantityMicros: 2_000_000},
+	}
+	cash := []db.ListDailyCashChangesRow{
+		{AccountID: "acct_joint", TransactionDate: "2024-06-28", CashType: "opening", AmountMicros: 3_000_000_000},
+		{AccountID: "acct_joint", TransactionDate: "2024-07-01", CashType: "purchase", AmountMicros: -2_000_000_000},
+		{AccountID: "acct_joint", TransactionDate: "2024-07-05", CashType: "proceeds", AmountMicros: 1_100_000_000},
+		{AccountID: "acct_joint", TransactionDate: "2024-07-05", CashType: "withdrawal", AmountMicros: -100_000_000},
+		{AccountID: "acct_margin", TransactionDate: "2024-07-06", CashType: "proceeds", AmountMicros: 440_000_000},
+	}
+	prices := []db.Price{
+		{SecurityID: "sec_aapl", PriceDate: "2024-07-01", CloseMicros: 200_000_000},
+		{SecurityID: "sec_aapl", PriceDate: "2024-07-02", CloseMicros: 210_000_000},
+		{SecurityID: "sec_aapl", PriceDate: "2024-07-05", CloseMicros: 220_000_000},
+	}
+	pricing := taxlots.NewPricing(nil, nil)
+
+	to, _ := time.Parse("2006-01-02", "2024-07-08")
+	got := portfolio.BuildValuations(changes, cash, prices, pricing, to)
+
+	want := []struct {
+		account    string
+		date       string
+		securities int64
+		cash       int64
+		flow       int64
+		unpriced   int
+	}{
+		{"acct_joint", "2024-06-28", 0, 3_000_000_000, 3_000_000_000, 0},
+		{"acct_joint", "2024-07-01", 2_000_000_000, 1_000_000_000, 0, 1},
+		{"acct_joint", "2024-07-02", 2_100_000_000, 1_000_000_000, 0, 1},
+		{"acct_joint", "2024-07-03", 2_100_000_000, 1_000_000_000, 0, 1}, // carried forward
+		{"acct_joint", "2024-07-04", 2_100_000_000, 1_000_000_000, 0, 1},
+		{"acct_joint", "2024-07-05", 1_100_000_000, 2_000_000_000, -100_000_000, 1},
+		{"acct_joint", "2024-07-08", 1_320_000_000, 2_000_000_000, 220_000_000, 1},
+		{"acct_margin", "2024-07-08", -440_000_000, 440_000_000, 0, 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d valuations, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.AccountID != w.account || g.Date.Format("2006-01-02") != w.date || g.SecuritiesValueMicros != w.securities ||
+			g.CashMicros != w.cash || g.NetFlowMicros != w.flow || g.UnpricedCount != w.unpriced {
+			t.Errorf("valuation %d = %s %s securities %d cash %d flow %d unpriced %d, want %s %s %d %d %d %d",
+				i, g.AccountID, g.Date.Format("2006-01-02"), g.SecuritiesValueMicros, g.CashMicros, g.NetFlowMicros, g.UnpricedCount,
+				w.account, w.date, w.securities, w.cash, w.flow, w.unpriced)
+		}
+	}
+
+	if got := portfolio.BuildValuations(nil, nil, nil, pricing, to); got != nil {
+		t.Errorf("BuildValuations with no history = %+v, want nil", got)
+	}
+}
+
+func TestRangeStart(t *testing.T) {
+	end, _ := time.Parse("2006-01-02", "2024-08-15")
+	tests := []struct {
+		r    string
+		want string
+	}{
+		{"1m", "2024-07-15"},
+		{"YTD", "2024-01-01"},
+		{"1y", "2023-08-15"},
+		{"all", "0001-01-01"},
+	}
+	for _, tt := range tests {
+		got, err := portfolio.RangeStart(tt.r, end)
+		if err != nil {
+			t.Fatalf("RangeStart(%q): %v", tt.r, err)
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("RangeStart(%q) = %s, want %s", tt.r, got.Format("2006-01-02"), tt.want)
+		}
+	}
+	if _, err := portfolio.RangeStart("2y", end); err == nil {
+		t.Error("RangeStart(2y) = nil error, want invalid range")
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+// The projection responses keep the shape of the client's
+// data/cashflow/projections.json: camelCase keys and dollar amounts.
+
+type CashFlowPaymentResponse struct {
+	ID          string  `json:"id"`
+	Symbol      string  `json:"symbol"`
+	Name        string  `json:"name"`
+	IncomeType  string  `json:"incomeType"`
+	PaymentDate string  `json:"paymentDate"`
+	Amount      float64 `json:"amount"` // per share
+	TotalAmount float64 `json:"totalAmount"`
+	Shares      float64 `json:"shares"`
+	Frequency   string  `json:"frequency"`
+	AccountID   string  `json:"accountId"`
+}
+
+type CashFlowPeriodResponse struct {
+	PeriodLabel    string  `json:"periodLabel"`
+	StartDate      string  `json:"startDate"`
+	EndDate        string  `json:"endDate"`
+	DividendIncome float64 `json:"dividendIncome"`
+	InterestIncome float64 `json:"interestIncome"`
+	MMFIncome      float64 `json:"mmfIncome"`
+	TotalIncome    float64 `json:"totalIncome"`
+	Maturities     float64 `json:"maturities"`
+}
+
+type AnnualProjectionResponse struct {
+	Year           int     `json:"year"`
+	TotalProjected float64 `json:"totalProjected"`
+	ByType         struct {
+		Dividend float64 `json:"dividend"`
+		Interest float64 `json:"interest"`
+		MMF      float64 `json:"mmf"`
+	} `json:"byType"`
+}
+
+type CashFlowProjectionResponse struct {
+	TimeHorizon      string                    `json:"timeHorizon"`
+	ReferenceDate    string                    `json:"referenceDate"`
+	AnnualProjection AnnualProjectionResponse  `json:"annualProjection"`
+	Payments         []CashFlowPaymentResponse `json:"payments"`
+	MonthlyBreakdown []CashFlowPeriodResponse  `json:"monthlyBreakdown"`
+	// Bond principal coming due; not income, so not in the totals above.
+	Maturities []CashFlowPaymentResponse `json:"maturities"`
+}
+
+// getCashFlowProjections projects the next twelve months of income for
+// account_id, or every account when it's empty, from as_of (default today).
+func (rt *Router) getCashFlowProjections(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountID := q.Get("account_id")
+
+	asOf := time.Now()
+	if v := q.Get("as_of"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid as_of date")
+			return
+		}
+		asOf = parsed
+	}
+	if !rt.incomeAccount(w, r, accountID) {
+		return
+	}
+
+	projection, err := income.NewProjector(rt.queries).Project(r.Context(), accountID, asOf)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to project income")
+		slog.Error("failed to project income", "error", err)
+		return
+	}
+
+	totals := projection.Totals()
+	resp := CashFlowProjectionResponse{
+		TimeHorizon:      "12-month-forward",
+		ReferenceDate:    projection.ReferenceDate,
+		Payments:         toCashFlowPayments(projection.Payments),
+		MonthlyBreakdown: make([]CashFlowPeriodResponse, 0, len(projection.Months)),
+		Maturities:       toCashFlowPayments(projection.Maturities),
+	}
+	resp.AnnualProjection.Year = asOf.Year()
+	resp.AnnualProjection.TotalProjected = dollars(totals.IncomeMicros())
+	resp.AnnualProjection.ByType.Dividend = dollars(totals.DividendMicros)
+	resp.AnnualProjection.ByType.Interest = dollars(totals.InterestMicros)
+	resp.AnnualProjection.ByType.MMF = dollars(totals.MoneyMarketMicros)
+	for _, m := range projection.Months {
+		resp.MonthlyBreakdown = append(resp.MonthlyBreakdown, CashFlowPeriodResponse{
+			PeriodLabel:    m.Label,
+			StartDate:      m.StartDate,
+			EndDate:        m.EndDate,
+			DividendIncome: dollars(m.DividendMicros),
+			InterestIncome: dollars(m.InterestMicros),
+			MMFIncome:      dollars(m.MoneyMarketMicros),
+			TotalIncome:    dollars(m.IncomeMicros()),
+			Maturities:     dollars(m.MaturityMicros),
+		})
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+var paymentIDPrefix = map[income.PaymentType]string{
+	income.PaymentDividend:    "div",
+	income.PaymentInterest:    "int",
+	income.PaymentMoneyMarket: "mmf",
+	income.PaymentMaturity:    "mat",
+}
+
+func toCashFlowPayments(payments []income.ProjectedPayment) []CashFlowPaymentResponse {
+	resp := make([]CashFlowPaymentResponse, 0, len(payments))
+	for _, p := range payments {
+		resp = append(resp, CashFlowPaymentResponse{
+			ID:          fmt.Sprintf("%s-%s-%s-%s", paymentIDPrefix[p.Type], strings.ToLower(p.Symbol), p.PaymentDate, p.AccountID),
+			Symbol:      p.Symbol,
+			Name:        p.Name,
+			IncomeType:  string(p.Type),
+			PaymentDate: p.PaymentDate,
+			Amount:      float64(p.PerShareMicros) / 1_000_000,
+			TotalAmount: dollars(p.AmountMicros),
+			Shares:      float64(p.QuantityMicros) / 1_000_000,
+			Frequency:   string(p.Frequency),
+			AccountID:   p.AccountID,
+		})
+	}
+	return resp
+}
+
+// dollars rounds micros to cents.
+func dollars(micros int64) float64 {
+	return math.Round(float64(micros)/10_000) / 100
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/income"
+)
+
+type IncomeTotalsResponse struct {
+	QualifiedDividendsMicros int64 `json:"qualified_dividends_micros"`
+	OrdinaryDividendsMicros  int64 `json:"ordinary_dividends_micros"`
+	ExemptDividendsMicros    int64 `json:"exempt_dividends_micros"`
+	CapitalGainsMicros       int64 `json:"capital_gains_micros"`
+	BondInterestMicros       int64 `json:"bond_interest_micros"`
+	MuniInterestMicros       int64 `json:"muni_interest_micros"`
+	SweepInterestMicros      int64 `json:"sweep_interest_micros"`
+	ReturnOfCapitalMicros    int64 `json:"return_of_capital_micros"`
+	FeesMicros               int64 `json:"fees_micros"`
+	DistributionsMicros      int64 `json:"distributions_micros"`
+	DividendsMicros          int64 `json:"dividends_micros"`
+	InterestMicros           int64 `json:"interest_micros"`
+	IncomeMicros             int64 `json:"income_micros"`
+	TaxExemptMicros          int64 `json:"tax_exempt_micros"`
+	NetMicros                int64 `json:"net_micros"`
+}
+
+type IncomePeriodResponse struct {
+	Period    string               `json:"period"`
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Totals    IncomeTotalsResponse `json:"totals"`
+	YTD       IncomeTotalsResponse `json:"ytd"`
+}
+
+type AccountIncomeResponse struct {
+	AccountID   string               `json:"account_id"`
+	AccountName string               `json:"account_name"`
+	Totals      IncomeTotalsResponse `json:"totals"`
+}
+
+type IncomeSummaryResponse struct {
+	AccountID  string                  `json:"account_id,omitempty"`
+	StartDate  string                  `json:"start_date"`
+	EndDate    string                  `json:"end_date"`
+	By         string                  `json:"by"`
+	Totals     IncomeTotalsResponse    `json:"totals"`
+	CashMicros int64                   `json:"cash_micros"`
+	Periods    []IncomePeriodResponse  `json:"periods"`
+	Accounts   []AccountIncomeResponse `json:"accounts,omitempty"`
+}
+
+type SecurityIncomeResponse struct {
+	SecurityID   string `json:"security_id"`
+	Symbol       string `json:"symbol"`
+	Name         string `json:"name"`
+	Category     string `json:"category"`
+	Payments     int    `json:"payments"`
+	AmountMicros int64  `json:"amount_micros"`
+	LastPaid     string `json:"last_paid"`
+}
+
+// getIncomeSummary returns income, fees and distributions for account_id, or
+// every account when it's empty, over year (default the current year, 0 for
+// all years) rolled up by month, quarter or year (default month).
+func (rt *Router) getIncomeSummary(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountID := q.Get("account_id")
+
+	grouping := income.Grouping(q.Get("by"))
+	switch grouping {
+	case "":
+		grouping = income.GroupByMonth
+	case income.GroupByMonth, income.GroupByQuarter, income.GroupByYear:
+	default:
+		respondError(w, http.StatusBadRequest, "invalid by: use month, quarter or year")
+		return
+	}
+
+	now := time.Now()
+	year, ok := incomeYear(w, r, now.Year())
+	if !ok {
+		return
+	}
+	if !rt.incomeAccount(w, r, accountID) {
+		return
+	}
+
+	ctx := r.Context()
+	start, end := income.YearRange(year, now)
+	rows, err := income.NewReporter(rt.queries).Activity(ctx, accountID, start, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to summarize income")
+		slog.Error("failed to summarize income", "error", err)
+		return
+	}
+	summary := income.Summarize(rows, start, end, grouping)
+
+	ids := []string{accountID}
+	if accountID == "" {
+		accounts, err := rt.queries.ListAccounts(ctx)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list accounts")
+			slog.Error("failed to list accounts", "error", err)
+			return
+		}
+		ids = ids[:0]
+		for _, a := range accounts {
+			ids = append(ids, a.ID)
+		}
+	}
+	var cashMicros int64
+	for _, id := range ids {
+		balance, err := rt.queries.GetCashBalance(ctx, id)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to get cash balance")
+			slog.Error("failed to get cash balance", "account_id", id, "error", err)
+			return
+		}
+		if v, ok := balance.(int64); ok {
+			cashMicros += v
+		}
+	}
+
+	resp := IncomeSummaryResponse{
+		AccountID:  accountID,
+		StartDate:  summary.StartDate,
+		EndDate:    summary.EndDate,
+		By:         string(grouping),
+		Totals:     toIncomeTotalsResponse(summary.Totals),
+		CashMicros: cashMicros,
+		Periods:    make([]IncomePeriodResponse, 0, len(summary.Periods)),
+	}
+	for _, p := range summary.Periods {
+		resp.Periods = append(resp.Periods, IncomePeriodResponse{
+			Period:    p.Label,
+			StartDate: p.StartDate,
+			EndDate:   p.EndDate,
+			Totals:    toIncomeTotalsResponse(p.Totals),
+			YTD:       toIncomeTotalsResponse(p.YTD),
+		})
+	}
+	if accountID == "" {
+		for _, a := range summary.Accounts {
+			resp.Accounts = append(resp.Accounts, AccountIncomeResponse{
+				AccountID:   a.AccountID,
+				AccountName: a.AccountName,
+				Totals:      toIncomeTotalsResponse(a.Totals),
+			})
+		}
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// getIncomeBySecurity returns what each security paid account_id, or every
+// account when it's empty, in year (default all years).
+func (rt *Router) getIncomeBySecurity(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+
+	year, ok := incomeYear(w, r, 0)
+	if !ok {
+		return
+	}
+	if !rt.incomeAccount(w, r, accountID) {
+		return
+	}
+
+	start, end := income.YearRange(year, time.Now())
+	rows, err := income.NewReporter(rt.queries).Activity(r.Context(), accountID, start, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list income")
+		slog.Error("failed to list income", "error", err)
+		return
+	}
+
+	lines := income.BySecurity(rows)
+	resp := make([]SecurityIncomeResponse, 0, len(lines))
+	for _, l := range lines {
+		resp = append(resp, SecurityIncomeResponse{
+			SecurityID:   l.SecurityID,
+			Symbol:       l.Symbol,
+			Name:         l.Name,
+			Category:     string(l.Category),
+			Payments:     l.Payments,
+			AmountMicros: l.AmountMicros,
+			LastPaid:     l.LastPaid,
+		})
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// Form1099DIVResponse is an estimate of an account's 1099-DIV boxes.
+type Form1099DIVResponse struct {
+	AccountID   string `json:"account_id"`
+	AccountName string `json:"account_name"`
+	Year        int    `json:"year"`
+
+	OrdinaryDividendsMicros  int64 `json:"box_1a_ordinary_dividends_micros"`
+	QualifiedDividendsMicros int64 `json:"box_1b_qualified_dividends_micros"`
+	CapitalGainsMicros       int64 `json:"box_2a_capital_gains_micros"`
+	NondividendMicros        int64 `json:"box_3_nondividend_micros"`
+	Section199AMicros        int64 `json:"box_5_section_199a_micros"`
+	ForeignTaxMicros         int64 `json:"box_7_foreign_tax_micros"`
+	ExemptInterestMicros     int64 `json:"box_12_exempt_interest_micros"`
+	EstimatedMicros          int64 `json:"estimated_micros"`
+}
+
+// getForm1099DIV estimates 1099-DIV boxes for account_id, or every account
+// when it's empty, for year (default last year).
+func (rt *Router) getForm1099DIV(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+
+	year, ok := incomeYear(w, r, time.Now().Year()-1)
+	if !ok {
+		return
+	}
+	if year == 0 {
+		respondError(w, http.StatusBadRequest, "invalid year")
+		return
+	}
+	if !rt.incomeAccount(w, r, accountID) {
+		return
+	}
+
+	forms, err := income.NewReporter(rt.queries).Form1099DIV(r.Context(), accountID, year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to estimate 1099-DIV")
+		slog.Error("failed to estimate 1099-DIV", "error", err)
+		return
+	}
+
+	resp := make([]Form1099DIVResponse, 0, len(forms))
+	for _, f := range forms {
+		resp = append(resp, Form1099DIVResponse{
+			AccountID:                f.AccountID,
+			AccountName:              f.AccountName,
+			Year:                     f.Year,
+			OrdinaryDividendsMicros:  f.OrdinaryDividendsMicros,
+			QualifiedDividendsMicros: f.QualifiedDividendsMicros,
+			CapitalGainsMicros:       f.CapitalGainsMicros,
+			NondividendMicros:        f.NondividendMicros,
+			Section199AMicros:        f.Section199AMicros,
+			ForeignTaxMicros:         f.ForeignTaxMicros,
+			ExemptInterestMicros:     f.ExemptInterestMicros,
+			EstimatedMicros:          f.EstimatedMicros,
+		})
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// incomeYear parses the year parameter, writing a 400 when it's invalid.
+func incomeYear(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	param := r.URL.Query().Get("year")
+	if param == "" {
+		return fallback, true
+	}
+	year, err := strconv.Atoi(param)
+	if err != nil || year < 0 || (year > 0 && year < 1900) {
+		respondError(w, http.StatusBadRequest, "invalid year")
+		return 0, false
+	}
+	return year, true
+}
+
+// incomeAccount checks accountID exists when one is given, writing the error
+// response when it doesn't.
+func (rt *Router) incomeAccount(w http.ResponseWriter, r *http.Request, accountID string) bool {
+	if accountID == "" {
+		return true
+	}
+	if _, err := rt.queries.GetAccount(r.Context(), accountID); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "account not found")
+			return false
+		}
+		respondError(w, http.StatusInternalServerError, "failed to get account")
+		slog.Error("failed to get account", "error", err)
+		return false
+	}
+	return true
+}
+
+func toIncomeTotalsResponse(t income.Totals) IncomeTotalsResponse {
+	return IncomeTotalsResponse{
+		QualifiedDividendsMicros: t.QualifiedDividendsMicros,
+		OrdinaryDividendsMicros:  t.OrdinaryDividendsMicros,
+		ExemptDividendsMicros:    t.ExemptDividendsMicros,
+		CapitalGainsMicros:       t.CapitalGainsMicros,
+		BondInterestMicros:       t.BondInterestMicros,
+		MuniInterestMicros:       t.MuniInterestMicros,
+		SweepInterestMicros:      t.SweepInterestMicros,
+		ReturnOfCapitalMicros:    t.ReturnOfCapitalMicros,
+		FeesMicros:               t.FeesMicros,
+		DistributionsMicros:      t.DistributionsMicros,
+		DividendsMicros:          t.DividendsMicros(),
+		InterestMicros:           t.InterestMicros(),
+		IncomeMicros:             t.IncomeMicros(),
+		TaxExemptMicros:          t.TaxExemptMicros(),
+		NetMicros:                t.NetMicros(),
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+type OptionPositionResponse struct {
+	AccountID      string  `json:"account_id"`
+	AccountName    string  `json:"account_name"`
+	Symbol         string  `json:"symbol"`
+	Underlying     string  `json:"underlying"`
+	OptionType     string  `json:"option_type"`
+	ExpirationDate string  `json:"expiration_date"`
+	StrikeMicros   int64   `json:"strike_micros"`
+	Multiplier     int64   `json:"multiplier"`
+	Contracts      float64 `json:"contracts"` // negative when short
+	PremiumMicros  int64   `json:"premium_micros"`
+	SharesHeld     float64 `json:"shares_held"`
+}
+
+type OptionPositionsResponse struct {
+	Positions []OptionPositionResponse `json:"positions"`
+}
+
+// listOptions returns open option positions with the underlying shares held
+// next to them, optionally filtered by account_id.
+func (rt *Router) listOptions(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+
+	lots, err := rt.queries.ListOpenLots(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list open lots")
+		slog.Error("failed to list open lots", "error", err)
+		return
+	}
+	options, err := rt.queries.ListOptions(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list options")
+		slog.Error("failed to list options", "error", err)
+		return
+	}
+
+	resp := OptionPositionsResponse{Positions: []OptionPositionResponse{}}
+	for _, pos := range taxlots.OptionPositions(lots, options) {
+		if accountID != "" && pos.AccountID != accountID {
+			continue
+		}
+		resp.Positions = append(resp.Positions, OptionPositionResponse{
+			AccountID:      pos.AccountID,
+			AccountName:    pos.AccountName,
+			Symbol:         pos.Symbol,
+			Underlying:     pos.Underlying,
+			OptionType:     pos.OptionType,
+			ExpirationDate: pos.ExpirationDate,
+			StrikeMicros:   pos.StrikeMicros,
+			Multiplier:     pos.Multiplier,
+			Contracts:      float64(pos.ContractsMicros) / 1_000_000,
+			PremiumMicros:  pos.PremiumMicros,
+			SharesHeld:     float64(pos.UnderlyingMicros) / 1_000_000,
+		})
+	}
+	respond(w, http.StatusOK, resp)
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/performance"
+	"github.com/levisegal/monay/services/holdings/portfolio"
+)
+
+type ReturnResponse struct {
+	Period               string   `json:"period"`
+	StartDate            string   `json:"start_date"`
+	EndDate              string   `json:"end_date"`
+	StartValueMicros     int64    `json:"start_value_micros"`
+	EndValueMicros       int64    `json:"end_value_micros"`
+	NetFlowMicros        int64    `json:"net_flow_micros"`
+	GainMicros           int64    `json:"gain_micros"`
+	TWRPercent           float64  `json:"twr_percent"`
+	AnnualizedTWRPercent float64  `json:"annualized_twr_percent"`
+	XIRRPercent          *float64 `json:"xirr_percent"`
+}
+
+type AccountPerformanceResponse struct {
+	AccountID   string           `json:"account_id"`
+	AccountName string           `json:"account_name"`
+	Returns     []ReturnResponse `json:"returns"`
+}
+
+type PerformanceResponse struct {
+	AccountID string                       `json:"account_id,omitempty"`
+	Returns   []ReturnResponse             `json:"returns"`
+	Accounts  []AccountPerformanceResponse `json:"accounts,omitempty"`
+}
+
+// getPerformance returns period returns for account_id, or for the household
+// with each account's alongside when it's empty, through as_of (default the
+// latest valuation).
+func (rt *Router) getPerformance(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountID := q.Get("account_id")
+
+	end := time.Now()
+	if asOf := q.Get("as_of"); asOf != "" {
+		var err error
+		end, err = time.Parse("2006-01-02", asOf)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid as_of date")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	calc := performance.NewCalculator(rt.queries)
+
+	returns, err := calc.Returns(ctx, accountID, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to compute performance")
+		slog.Error("failed to compute performance", "error", err)
+		return
+	}
+	resp := PerformanceResponse{
+		AccountID: accountID,
+		Returns:   toReturnResponses(returns),
+	}
+
+	if accountID == "" {
+		accounts, err := rt.queries.ListAccounts(ctx)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list accounts")
+			slog.Error("failed to list accounts", "error", err)
+			return
+		}
+		for _, account := range accounts {
+			returns, err := calc.Returns(ctx, account.ID, end)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "failed to compute performance")
+				slog.Error("failed to compute performance", "account_id", account.ID, "error", err)
+				return
+			}
+			if len(returns) == 0 {
+				continue
+			}
+			resp.Accounts = append(resp.Accounts, AccountPerformanceResponse{
+				AccountID:   account.ID,
+				AccountName: account.Name,
+				Returns:     toReturnResponses(returns),
+			})
+		}
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+func toReturnResponses(returns []performance.Return) []ReturnResponse {
+	out := make([]ReturnResponse, 0, len(returns))
+	for _, r := range returns {
+		resp := ReturnResponse{
+			Period:               string(r.Period),
+			StartDate:            r.Start.Format("2006-01-02"),
+			EndDate:              r.End.Format("2006-01-02"),
+			StartValueMicros:     r.StartValueMicros,
+			EndValueMicros:       r.EndValueMicros,
+			NetFlowMicros:        r.NetFlowMicros,
+			GainMicros:           r.GainMicros,
+			TWRPercent:           r.TWR * 100,
+			AnnualizedTWRPercent: r.AnnualizedTWR * 100,
+		}
+		if r.XIRRValid {
+			xirr := r.XIRR * 100
+			resp.XIRRPercent = &xirr
+		}
+		out = append(out, resp)
+	}
+	return out
+}
+
+type BenchmarkComponentResponse struct {
+	Symbol       string  `json:"symbol"`
+	Weight       float64 `json:"weight"`
+	SecurityType string  `json:"security_type"`
+}
+
+type BenchmarkResponse struct {
+	Name        string                       `json:"name"`
+	Description string                       `json:"description,omitempty"`
+	Rebalance   string                       `json:"rebalance"`
+	Components  []BenchmarkComponentResponse `json:"components"`
+}
+
+func (rt *Router) listBenchmarks(w http.ResponseWriter, r *http.Request) {
+	benchmarks, err := performance.NewCalculator(rt.queries).Benchmarks(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list benchmarks")
+		slog.Error("failed to list benchmarks", "error", err)
+		return
+	}
+
+	resp := make([]BenchmarkResponse, 0, len(benchmarks))
+	for _, b := range benchmarks {
+		br := BenchmarkResponse{
+			Name:        b.Name,
+			Description: b.Description,
+			Rebalance:   b.Rebalance,
+			Components:  make([]BenchmarkComponentResponse, 0, len(b.Components)),
+		}
+		for _, c := range b.Components {
+			br.Components = append(br.Components, BenchmarkComponentResponse{
+				Symbol:       c.Symbol,
+				Weight:       c.Weight,
+				SecurityType: c.SecurityType,
+			})
+		}
+		resp = append(resp, br)
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+type ComparisonResponse struct {
+	Period                 string   `json:"period"`
+	StartDate              string   `json:"start_date"`
+	EndDate                string   `json:"end_date"`
+	TWRPercent             float64  `json:"twr_percent"`
+	BenchmarkReturnPercent *float64 `json:"benchmark_return_percent"`
+	ExcessPercent          *float64 `json:"excess_percent"`
+}
+
+type SeriesPointResponse struct {
+	Date                   string   `json:"date"`
+	ReturnPercent          float64  `json:"return_percent"`
+	BenchmarkReturnPercent *float64 `json:"benchmark_return_percent"`
+}
+
+type BenchmarkComparisonResponse struct {
+	AccountID string                `json:"account_id"`
+	Benchmark string                `json:"benchmark"`
+	Range     string                `json:"range"`
+	Periods   []ComparisonResponse  `json:"periods"`
+	Data      []SeriesPointResponse `json:"data"`
+}
+
+// getBenchmarkComparison returns account_id's returns beside its benchmark's
+// (or benchmark's) over each period, and cumulative returns of both on each
+// day over range (default 1y).
+func (rt *Router) getBenchmarkComparison(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	timeRange := q.Get("range")
+	if timeRange == "" {
+		timeRange = "1y"
+	}
+
+	end := time.Now()
+	start, err := portfolio.RangeStart(timeRange, end)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	calc := performance.NewCalculator(rt.queries)
+	account, benchmark, ok := rt.accountBenchmark(w, r, calc, end)
+	if !ok {
+		return
+	}
+
+	comparisons, err := calc.Compare(ctx, account.ID, benchmark, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to compare performance")
+		slog.Error("failed to compare performance", "error", err)
+		return
+	}
+	series, err := calc.Series(ctx, account.ID, benchmark, start, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to compare performance")
+		slog.Error("failed to compare performance", "error", err)
+		return
+	}
+
+	resp := BenchmarkComparisonResponse{
+		AccountID: account.ID,
+		Benchmark: benchmark.Name,
+		Range:     timeRange,
+		Periods:   make([]ComparisonResponse, 0, len(comparisons)),
+		Data:      make([]SeriesPointResponse, 0, len(series)),
+	}
+	for _, c := range comparisons {
+		cr := ComparisonResponse{
+			Period:     string(c.Period),
+			StartDate:  c.Start.Format("2006-01-02"),
+			EndDate:    c.End.Format("2006-01-02"),
+			TWRPercent: c.TWR * 100,
+		}
+		if c.BenchmarkValid {
+			benchmarkReturn, excess := c.BenchmarkReturn*100, c.Excess()*100
+			cr.BenchmarkReturnPercent, cr.ExcessPercent = &benchmarkReturn, &excess
+		}
+		resp.Periods = append(resp.Periods, cr)
+	}
+	for _, p := range series {
+		sp := SeriesPointResponse{
+			Date:          p.Date.Format("2006-01-02"),
+			ReturnPercent: p.Return * 100,
+		}
+		if p.BenchmarkValid {
+			benchmarkReturn := p.BenchmarkReturn * 100
+			sp.BenchmarkReturnPercent = &benchmarkReturn
+		}
+		resp.Data = append(resp.Data, sp)
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+type SegmentResponse struct {
+	SecurityType           string  `json:"security_type"`
+	StartValueMicros       int64   `json:"start_value_micros"`
+	EndValueMicros         int64   `json:"end_value_micros"`
+	NetFlowMicros          int64   `json:"net_flow_micros"`
+	WeightPercent          float64 `json:"weight_percent"`
+	ReturnPercent          float64 `json:"return_percent"`
+	BenchmarkWeightPercent float64 `json:"benchmark_weight_percent"`
+	BenchmarkReturnPercent float64 `json:"benchmark_return_percent"`
+	AllocationPercent      float64 `json:"allocation_percent"`
+	SelectionPercent       float64 `json:"selection_percent"`
+}
+
+type AttributionResponse struct {
+	AccountID              string            `json:"account_id"`
+	Benchmark              string            `json:"benchmark"`
+	Period                 string            `json:"period"`
+	StartDate              string            `json:"start_date"`
+	EndDate                string            `json:"end_date"`
+	ReturnPercent          float64           `json:"return_percent"`
+	BenchmarkReturnPercent float64           `json:"benchmark_return_percent"`
+	AllocationPercent      float64           `json:"allocation_percent"`
+	SelectionPercent       float64           `json:"selection_percent"`
+	Segments               []SegmentResponse `json:"segments"`
+}
+
+// getAttribution returns allocation and selection effects by security type
+// for account_id against its benchmark (or benchmark) over period (default
+// ytd).
+func (rt *Router) getAttribution(w http.ResponseWriter, r *http.Request) {
+	period := performance.Period(strings.ToLower(r.URL.Query().Get("period")))
+	if period == "" {
+		period = performance.PeriodYTD
+	}
+	if !slices.Contains(performance.Periods, period) {
+		respondError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	end := time.Now()
+	calc := performance.NewCalculator(rt.queries)
+	account, benchmark, ok := rt.accountBenchmark(w, r, calc, end)
+	if !ok {
+		return
+	}
+
+	a, err := calc.Attribution(r.Context(), account.ID, benchmark, period, end)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	resp := AttributionResponse{
+		AccountID:              account.ID,
+		Benchmark:              a.Benchmark,
+		Period:                 string(a.Period),
+		StartDate:              a.Start.Format("2006-01-02"),
+		EndDate:                a.End.Format("2006-01-02"),
+		ReturnPercent:          a.Return * 100,
+		BenchmarkReturnPercent: a.BenchmarkReturn * 100,
+		AllocationPercent:      a.Allocation * 100,
+		SelectionPercent:       a.Selection * 100,
+		Segments:               make([]SegmentResponse, 0, len(a.Segments)),
+	}
+	for _, s := range a.Segments {
+		resp.Segments = append(resp.Segments, SegmentResponse{
+			SecurityType:           s.SecurityType,
+			StartValueMicros:       s.StartValueMicros,
+			EndValueMicros:         s.EndValueMicros,
+			NetFlowMicros:          s.NetFlowMicros,
+			WeightPercent:          s.Weight * 100,
+			ReturnPercent:          s.Return * 100,
+			BenchmarkWeightPercent: s.BenchmarkWeight * 100,
+			BenchmarkReturnPercent: s.BenchmarkReturn * 100,
+			AllocationPercent:      s.Allocation * 100,
+			SelectionPercent:       s.Selection * 100,
+		})
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// accountBenchmark looks up the required account_id and the benchmark to
+// compare it with: the benchmark parameter, or else the account's. It
+// responds with an error and returns false when either isn't found.
+func (rt *Router) accountBenchmark(w http.ResponseWriter, r *http.Request, calc *performance.Calculator, end time.Time) (db.Account, performance.Benchmark, bool) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	accountID := q.Get("account_id")
+	if accountID == "" {
+		respondError(w, http.StatusBadRequest, "missing account_id")
+		return db.Account{}, performance.Benchmark{}, false
+	}
+	account, err := rt.queries.GetAccount(ctx, accountID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "account not found")
+		return db.Account{}, performance.Benchmark{}, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get account")
+		slog.Error("failed to get account", "error", err)
+		return db.Account{}, performance.Benchmark{}, false
+	}
+
+	var benchmark performance.Benchmark
+	if name := q.Get("benchmark"); name != "" {
+		benchmark, err = calc.Benchmark(ctx, name)
+		if err != nil {
+			respondError(w, http.StatusNotFound, err.Error())
+			return db.Account{}, performance.Benchmark{}, false
+		}
+	} else {
+		benchmark, err = calc.AccountBenchmark(ctx, account, end)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to get benchmark")
+			slog.Error("failed to get benchmark", "error", err)
+			return db.Account{}, performance.Benchmark{}, false
+		}
+	}
+	return account, benchmark, true
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/portfolio"
+)
+
+type PortfolioHistoryPointResponse struct {
+	Date                  string  `json:"date"`
+	MarketValueMicros     int64   `json:"market_value_micros"`
+	SecuritiesValueMicros int64   `json:"securities_value_micros"`
+	CashMicros            int64   `json:"cash_micros"`
+	NetFlowMicros         int64   `json:"net_flow_micros"`
+	DayChangeMicros       int64   `json:"day_change_micros"`
+	DayChangePercent      float64 `json:"day_change_percent"`
+	UnpricedCount         int     `json:"unpriced_count,omitempty"`
+}
+
+type PortfolioHistoryResponse struct {
+	AccountID string                          `json:"account_id,omitempty"`
+	Range     string                          `json:"range"`
+	Data      []PortfolioHistoryPointResponse `json:"data"`
+}
+
+// getPortfolioHistory returns stored daily market values over range (default
+// 1y) for account_id, or for the household when it's empty.
+func (rt *Router) getPortfolioHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountID := q.Get("account_id")
+	timeRange := q.Get("range")
+	if timeRange == "" {
+		timeRange = "1y"
+	}
+
+	end := time.Now()
+	start, err := portfolio.RangeStart(timeRange, end)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	points, err := portfolio.NewValuer(rt.queries).History(r.Context(), accountID, start, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to load portfolio history")
+		slog.Error("failed to load portfolio history", "error", err)
+		return
+	}
+
+	resp := PortfolioHistoryResponse{
+		AccountID: accountID,
+		Range:     timeRange,
+		Data:      make([]PortfolioHistoryPointResponse, 0, len(points)),
+	}
+	for _, p := range points {
+		resp.Data = append(resp.Data, PortfolioHistoryPointResponse{
+			Date:                  p.Date.Format("2006-01-02"),
+			MarketValueMicros:     p.MarketValueMicros,
+			SecuritiesValueMicros: p.SecuritiesValueMicros,
+			CashMicros:            p.CashMicros,
+			NetFlowMicros:         p.NetFlowMicros,
+			DayChangeMicros:       p.DayChangeMicros,
+			DayChangePercent:      p.DayChangePercent,
+			UnpricedCount:         p.UnpricedCount,
+		})
+	}
+
+	respond(w, http.StatusOK, resp)
+}
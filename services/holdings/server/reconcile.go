@@ -0,0 +1,115 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+type PositionCauseResponse struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+type PositionReconcileLineResponse struct {
+	Status              string                  `json:"status"`
+	Symbol              string                  `json:"symbol"`
+	BrokerQuantity      float64                 `json:"broker_quantity"`
+	BrokerCostBasis     *int64                  `json:"broker_cost_basis_micros,omitempty"`
+	LotQuantity         float64                 `json:"lot_quantity"`
+	LotCostBasis        int64                   `json:"lot_cost_basis_micros"`
+	QuantityDiff        float64                 `json:"quantity_diff"`
+	CostBasisDiff       *int64                  `json:"cost_basis_diff_micros,omitempty"`
+	UnmatchedSells      *float64                `json:"unmatched_sell_quantity,omitempty"`
+	NeedsOpeningBalance bool                    `json:"needs_opening_balance"`
+	Causes              []PositionCauseResponse `json:"causes"`
+}
+
+type PositionReconcileResponse struct {
+	AccountID string                          `json:"account_id"`
+	AsOfDate  string                          `json:"as_of_date"` // the broker snapshot compared
+	Matched   int                             `json:"matched"`
+	Lines     []PositionReconcileLineResponse `json:"lines"`
+}
+
+// reconcileHoldings compares account_id's broker positions snapshot on or
+// before as_of (default today) with the lots open on the snapshot date.
+func (rt *Router) reconcileHoldings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountID := q.Get("account_id")
+	if accountID == "" {
+		respondError(w, http.StatusBadRequest, "missing account_id")
+		return
+	}
+
+	asOf := time.Now()
+	if v := q.Get("as_of"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid as_of date")
+			return
+		}
+		asOf = parsed
+	}
+
+	_, err := rt.queries.GetAccount(r.Context(), accountID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "account not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get account")
+		slog.Error("failed to get account", "error", err)
+		return
+	}
+
+	rec, err := taxlots.NewAnalyzer(rt.queries).ReconcilePositions(r.Context(), accountID, asOf)
+	if errors.Is(err, taxlots.ErrNoPositions) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to reconcile holdings")
+		slog.Error("failed to reconcile holdings", "error", err)
+		return
+	}
+
+	resp := PositionReconcileResponse{
+		AccountID: accountID,
+		AsOfDate:  rec.AsOfDate,
+		Lines:     []PositionReconcileLineResponse{},
+	}
+	for _, l := range rec.Lines {
+		if l.Status == taxlots.PositionMatched {
+			resp.Matched++
+		}
+		line := PositionReconcileLineResponse{
+			Status:         string(l.Status),
+			Symbol:         l.Symbol,
+			BrokerQuantity: float64(l.BrokerQuantityMicros) / 1_000_000,
+			LotQuantity:    float64(l.LotQuantityMicros) / 1_000_000,
+			LotCostBasis:   l.LotCostBasisMicros,
+			QuantityDiff:   float64(l.QuantityDiffMicros) / 1_000_000,
+			Causes:         []PositionCauseResponse{},
+		}
+		if l.BrokerCostBasisValid {
+			line.BrokerCostBasis = &l.BrokerCostBasisMicros
+			line.CostBasisDiff = &l.CostBasisDiffMicros
+		}
+		if l.Gap != nil {
+			unmatched := float64(l.Gap.UnmatchedMicros) / 1_000_000
+			line.UnmatchedSells = &unmatched
+			line.NeedsOpeningBalance = l.Gap.NeedsOpeningBalance
+		}
+		for _, c := range l.Causes {
+			line.Causes = append(line.Causes, PositionCauseResponse{Kind: string(c.Kind), Detail: c.Detail})
+		}
+		resp.Lines = append(resp.Lines, line)
+	}
+
+	respond(w, http.StatusOK, resp)
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
 	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
 	"github.com/levisegal/monay/services/holdings/version"
 )
 
@@ -40,6 +42,18 @@ func NewRouter(queries *db.Queries) http.Handler {
 		api.Get("/accounts", r.listAccounts)
 		api.Get("/accounts/{id}", r.getAccount)
 		api.Get("/holdings", r.listHoldings)
+		api.Get("/holdings/reconcile", r.reconcileHoldings)
+		api.Get("/simulate/sell", r.simulateSell)
+		api.Get("/options", r.listOptions)
+		api.Get("/portfolio/history", r.getPortfolioHistory)
+		api.Get("/performance", r.getPerformance)
+		api.Get("/performance/benchmark", r.getBenchmarkComparison)
+		api.Get("/performance/attribution", r.getAttribution)
+		api.Get("/benchmarks", r.listBenchmarks)
+		api.Get("/income/summary", r.getIncomeSummary)
+		api.Get("/income/by-security", r.getIncomeBySecurity)
+		api.Get("/income/1099-div", r.getForm1099DIV)
+		api.Get("/cashflow/projections", r.getCashFlowProjections)
 	})
 
 	return mux
@@ -129,17 +143,61 @@ type HoldingResponse struct {
 	SecurityName *string `json:"security_name,omitempty"`
 	Quantity     float64 `json:"quantity"`
 	CostBasis    *int64  `json:"cost_basis_micros,omitempty"`
+
+	// Set with with_prices=true when the security has a stored price.
+	PriceMicros         *int64             `json:"price_micros,omitempty"`
+	PriceDate           *string            `json:"price_date,omitempty"`
+	MarketValue         *int64             `json:"market_value_micros,omitempty"`
+	UnrealizedGain      *int64             `json:"unrealized_gain_micros,omitempty"`
+	ShortTermGainMicros *int64             `json:"short_term_gain_micros,omitempty"`
+	LongTermGainMicros  *int64             `json:"long_term_gain_micros,omitempty"`
+	Lots                []LotValueResponse `json:"lots,omitempty"`
+}
+
+type LotValueResponse struct {
+	LotID           string  `json:"lot_id"`
+	AcquiredDate    string  `json:"acquired_date"`
+	Quantity        float64 `json:"quantity"`
+	CostBasisMicros int64   `json:"cost_basis_micros"`
+	MarketValue     *int64  `json:"market_value_micros,omitempty"`
+	UnrealizedGain  *int64  `json:"unrealized_gain_micros,omitempty"`
+	HoldingPeriod   string  `json:"holding_period"`
 }
 
 type HoldingsListResponse struct {
 	Holdings []HoldingResponse `json:"holdings"`
+
+	// Set with as_of.
+	AsOf       *string `json:"as_of,omitempty"`
+	CashMicros *int64  `json:"cash_micros,omitempty"`
 }
 
+// listHoldings lists holdings for account_id, or every account. With
+// with_prices=true each holding and its lots are marked to the latest stored
+// prices, with unrealized gains split short and long-term. With as_of, lots
+// are rebuilt as they stood at the close of that date, marked to the prices
+// then, and the cash balance on that date is included.
 func (rt *Router) listHoldings(w http.ResponseWriter, r *http.Request) {
 	accountID := r.URL.Query().Get("account_id")
+	withPrices := r.URL.Query().Get("with_prices") == "true"
 
 	slog.Info("listHoldings", "account_id", accountID)
 
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		date, err := time.Parse("2006-01-02", asOf)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid as_of date")
+			return
+		}
+		rt.listHoldingsAsOf(w, r, accountID, date, withPrices)
+		return
+	}
+
+	if withPrices {
+		rt.listHoldingValues(w, r, accountID)
+		return
+	}
+
 	var holdings []HoldingResponse
 	if accountID != "" {
 		rows, err := rt.queries.ListHoldingsByAccount(r.Context(), accountID)
@@ -179,6 +237,138 @@ func (rt *Router) listHoldings(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, HoldingsListResponse{Holdings: holdings})
 }
 
+func (rt *Router) listHoldingValues(w http.ResponseWriter, r *http.Request, accountID string) {
+	values, err := taxlots.NewValuer(rt.queries).Value(r.Context(), accountID, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to value holdings")
+		slog.Error("failed to value holdings", "error", err)
+		return
+	}
+
+	respond(w, http.StatusOK, HoldingsListResponse{Holdings: holdingValuesToResponse(values, true)})
+}
+
+func (rt *Router) listHoldingsAsOf(w http.ResponseWriter, r *http.Request, accountID string, asOf time.Time, withPrices bool) {
+	ctx := r.Context()
+	date := asOf.Format("2006-01-02")
+
+	accountIDs := []string{accountID}
+	if accountID == "" {
+		accounts, err := rt.queries.ListAccounts(ctx)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list accounts")
+			slog.Error("failed to list accounts", "error", err)
+			return
+		}
+		accountIDs = accountIDs[:0]
+		for _, a := range accounts {
+			accountIDs = append(accountIDs, a.ID)
+		}
+	} else if _, err := rt.queries.GetAccount(ctx, accountID); err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "account not found")
+		return
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get account")
+		slog.Error("failed to get account", "error", err)
+		return
+	}
+
+	var cash int64
+	for _, id := range accountIDs {
+		balance, err := rt.queries.GetCashBalanceAsOfDate(ctx, db.GetCashBalanceAsOfDateParams{AccountID: id, AsOfDate: date})
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to get cash balance")
+			slog.Error("failed to get cash balance", "error", err)
+			return
+		}
+		if v, ok := balance.(int64); ok {
+			cash += v
+		}
+	}
+
+	valuer := taxlots.NewValuer(rt.queries)
+	var values []taxlots.LotValue
+	if withPrices {
+		v, err := valuer.Value(ctx, accountID, asOf)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to value holdings")
+			slog.Error("failed to value holdings", "error", err)
+			return
+		}
+		values = v
+	} else {
+		lots, err := valuer.OpenLots(ctx, accountID, asOf)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list holdings")
+			slog.Error("failed to list holdings", "error", err)
+			return
+		}
+		values = taxlots.ValueLots(lots, nil, nil, nil, asOf)
+	}
+
+	respond(w, http.StatusOK, HoldingsListResponse{
+		Holdings:   holdingValuesToResponse(values, withPrices),
+		AsOf:       &date,
+		CashMicros: &cash,
+	})
+}
+
+// holdingValuesToResponse nets lot values into holdings, with each holding's
+// lots and prices when withPrices is set.
+func holdingValuesToResponse(values []taxlots.LotValue, withPrices bool) []HoldingResponse {
+	lots := make(map[string][]LotValueResponse)
+	for _, v := range values {
+		key := v.Lot.AccountID + "|" + v.Lot.SecurityID
+		lots[key] = append(lots[key], lotValueToResponse(v))
+	}
+
+	holdings := []HoldingResponse{}
+	for _, h := range taxlots.SummarizeHoldings(values) {
+		resp := HoldingResponse{
+			AccountName: h.AccountName,
+			Symbol:      h.Symbol,
+			Quantity:    float64(h.QuantityMicros) / 1_000_000,
+			CostBasis:   &h.CostBasisMicros,
+		}
+		if withPrices {
+			resp.Lots = lots[h.AccountID+"|"+h.SecurityID]
+		}
+		if h.SecurityName != "" {
+			resp.SecurityName = &h.SecurityName
+		}
+		if h.Priced {
+			gain := h.GainMicros()
+			resp.PriceMicros = &h.PriceMicros
+			resp.PriceDate = &h.PriceDate
+			resp.MarketValue = &h.MarketValueMicros
+			resp.UnrealizedGain = &gain
+			resp.ShortTermGainMicros = &h.ShortTermGainMicros
+			resp.LongTermGainMicros = &h.LongTermGainMicros
+		}
+		holdings = append(holdings, resp)
+	}
+	return holdings
+}
+
+func lotValueToResponse(v taxlots.LotValue) LotValueResponse {
+	qty := float64(v.Lot.RemainingMicros) / 1_000_000
+	if v.Lot.PositionSide == string(taxlots.PositionShort) {
+		qty = -qty
+	}
+	resp := LotValueResponse{
+		LotID:           v.Lot.ID,
+		AcquiredDate:    v.Lot.AcquiredDate,
+		Quantity:        qty,
+		CostBasisMicros: v.CostBasisMicros,
+		HoldingPeriod:   string(v.HoldingPeriod),
+	}
+	if v.Priced {
+		resp.MarketValue = &v.MarketValueMicros
+		resp.UnrealizedGain = &v.GainMicros
+	}
+	return resp
+}
+
 func accountToResponse(a db.Account) AccountResponse {
 	resp := AccountResponse{
 		ID:              a.ID,
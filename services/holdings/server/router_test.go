@@ -4,14 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/portfolio"
 	"github.com/levisegal/monay/services/holdings/server"
+	"github.com/levisegal/monay/services/holdings/taxlots"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, *db.Queries, func()) {
@@ -473,3 +477,1286 @@ func TestContentType(t *testing.T) {
 		t.Errorf("expected Content-Type 'application/json', got %q", contentType)
 	}
 }
+
+func TestSimulateSellEndpoint(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Brokerage Account",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "AAPL",
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       account.ID,
+		SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		TransactionType: "buy",
+		TransactionDate: "2023-01-10",
+		QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+		AmountMicros:    1_500_000_000,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+
+	t.Run("simulates sale", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/simulate/sell?symbol=AAPL&qty=4&price=200&method=hifo&date=2024-06-01&long_term_rate=0.2&state_rate=0", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			Lots []struct {
+				AccountName   string  `json:"account_name"`
+				Quantity      float64 `json:"quantity"`
+				HoldingPeriod string  `json:"holding_period"`
+			} `json:"lots"`
+			Gain struct {
+				LongTermMicros int64 `json:"long_term_micros"`
+			} `json:"gain"`
+			FederalTaxMicros int64 `json:"federal_tax_micros"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if len(resp.Lots) != 1 || resp.Lots[0].Quantity != 4 || resp.Lots[0].HoldingPeriod != "long_term" {
+			t.Fatalf("unexpected lots: %+v", resp.Lots)
+		}
+		if resp.Gain.LongTermMicros != 200_000_000 {
+			t.Errorf("expected long-term gain 200000000, got %d", resp.Gain.LongTermMicros)
+		}
+		if resp.FederalTaxMicros != 40_000_000 {
+			t.Errorf("expected federal tax 40000000, got %d", resp.FederalTaxMicros)
+		}
+	})
+
+	t.Run("rejects missing symbol", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/simulate/sell?qty=4&price=200", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("rejects unknown method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/simulate/sell?symbol=AAPL&qty=4&price=200&method=random", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestListHoldingsWithPrices(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Brokerage Account",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "AAPL",
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+
+	recent := time.Now().AddDate(0, -1, 0).Format("2006-01-02")
+	for _, buy := range []struct {
+		date   string
+		qty    int64
+		amount int64
+	}{
+		{"2023-01-10", 10_000_000, 1_500_000_000},
+		{recent, 5_000_000, 900_000_000},
+	} {
+		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+			ID:              database.NewID(database.PrefixTransaction),
+			AccountID:       account.ID,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+			TransactionType: "buy",
+			TransactionDate: buy.date,
+			QuantityMicros:  sql.NullInt64{Int64: buy.qty, Valid: true},
+			AmountMicros:    buy.amount,
+			FeesInAmount:    true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+	}
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	err = queries.UpsertPrice(ctx, db.UpsertPriceParams{
+		SecurityID:  sec.ID,
+		PriceDate:   recent,
+		CloseMicros: 200_000_000,
+		Source:      "csv",
+	})
+	if err != nil {
+		t.Fatalf("failed to save price: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/holdings?with_prices=true&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Holdings []struct {
+			Symbol              string  `json:"symbol"`
+			Quantity            float64 `json:"quantity"`
+			MarketValue         *int64  `json:"market_value_micros"`
+			UnrealizedGain      *int64  `json:"unrealized_gain_micros"`
+			ShortTermGainMicros *int64  `json:"short_term_gain_micros"`
+			LongTermGainMicros  *int64  `json:"long_term_gain_micros"`
+			Lots                []struct {
+				HoldingPeriod  string `json:"holding_period"`
+				UnrealizedGain *int64 `json:"unrealized_gain_micros"`
+			} `json:"lots"`
+		} `json:"holdings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Holdings) != 1 {
+		t.Fatalf("expected 1 holding, got %d", len(resp.Holdings))
+	}
+	h := resp.Holdings[0]
+	if h.Quantity != 15 || h.MarketValue == nil || *h.MarketValue != 3_000_000_000 {
+		t.Errorf("expected 15 shares worth 3000000000, got %v shares worth %v", h.Quantity, h.MarketValue)
+	}
+	if h.LongTermGainMicros == nil || *h.LongTermGainMicros != 500_000_000 {
+		t.Errorf("expected long-term gain 500000000, got %v", h.LongTermGainMicros)
+	}
+	if h.ShortTermGainMicros == nil || *h.ShortTermGainMicros != 100_000_000 {
+		t.Errorf("expected short-term gain 100000000, got %v", h.ShortTermGainMicros)
+	}
+	if len(h.Lots) != 2 || h.Lots[0].HoldingPeriod != "long_term" || h.Lots[1].HoldingPeriod != "short_term" {
+		t.Errorf("unexpected lots: %+v", h.Lots)
+	}
+}
+
+func TestPortfolioHistory(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "VTI",
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+
+	var accountIDs []string
+	for _, name := range []string{"Joint", "IRA"} {
+		account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+			ID:              database.NewID(database.PrefixAccount),
+			Name:            name,
+			InstitutionName: "vanguard",
+			AccountType:     "brokerage",
+		})
+		if err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		accountIDs = append(accountIDs, account.ID)
+
+		// 10 shares bought Monday with $500 left in cash.
+		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+			ID:              database.NewID(database.PrefixTransaction),
+			AccountID:       account.ID,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+			TransactionType: "buy",
+			TransactionDate: "2024-07-01",
+			QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+			AmountMicros:    2_500_000_000,
+			FeesInAmount:    true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+		err = queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+			ID:              database.NewID(database.PrefixCashTxn),
+			AccountID:       account.ID,
+			TransactionDate: "2024-07-01",
+			CashType:        "opening",
+			AmountMicros:    500_000_000,
+		})
+		if err != nil {
+			t.Fatalf("failed to create cash transaction: %v", err)
+		}
+		if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+			t.Fatalf("failed to process lots: %v", err)
+		}
+	}
+
+	for date, closeMicros := range map[string]int64{"2024-07-01": 250_000_000, "2024-07-02": 275_000_000} {
+		err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+			SecurityID:  sec.ID,
+			PriceDate:   date,
+			CloseMicros: closeMicros,
+			Source:      "csv",
+		})
+		if err != nil {
+			t.Fatalf("failed to save price: %v", err)
+		}
+	}
+
+	to, _ := time.Parse("2006-01-02", "2024-07-03")
+	if _, err := portfolio.NewValuer(queries).Rebuild(ctx, to); err != nil {
+		t.Fatalf("failed to rebuild valuations: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+
+	type historyResponse struct {
+		Data []struct {
+			Date              string  `json:"date"`
+			MarketValueMicros int64   `json:"market_value_micros"`
+			DayChangeMicros   int64   `json:"day_change_micros"`
+			DayChangePercent  float64 `json:"day_change_percent"`
+		} `json:"data"`
+	}
+	get := func(query string) historyResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/portfolio/history?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp historyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	account := get("range=all&account_id=" + accountIDs[0])
+	if len(account.Data) != 3 {
+		t.Fatalf("expected 3 days, got %+v", account.Data)
+	}
+	if d := account.Data[1]; d.Date != "2024-07-02" || d.MarketValueMicros != 3_250_000_000 || d.DayChangeMicros != 250_000_000 || d.DayChangePercent < 8.33 || d.DayChangePercent > 8.34 {
+		t.Errorf("unexpected July 2 value: %+v", d)
+	}
+
+	household := get("range=all")
+	if len(household.Data) != 3 || household.Data[2].MarketValueMicros != 6_500_000_000 || household.Data[2].DayChangeMicros != 0 {
+		t.Errorf("unexpected household history: %+v", household.Data)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/portfolio/history?range=2y", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid range, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPerformance(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "VTI",
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Joint",
+		InstitutionName: "vanguard",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	// 10 shares moved in Monday, $500 deposited Tuesday.
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       account.ID,
+		SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		TransactionType: "opening_balance",
+		TransactionDate: "2024-07-01",
+		QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+		AmountMicros:    2_000_000_000,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	err = queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+		ID:              database.NewID(database.PrefixCashTxn),
+		AccountID:       account.ID,
+		TransactionDate: "2024-07-02",
+		CashType:        "deposit",
+		AmountMicros:    500_000_000,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cash transaction: %v", err)
+	}
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	for date, closeMicros := range map[string]int64{"2024-07-01": 250_000_000, "2024-07-02": 275_000_000} {
+		err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+			SecurityID:  sec.ID,
+			PriceDate:   date,
+			CloseMicros: closeMicros,
+			Source:      "csv",
+		})
+		if err != nil {
+			t.Fatalf("failed to save price: %v", err)
+		}
+	}
+
+	to, _ := time.Parse("2006-01-02", "2024-08-02")
+	if _, err := portfolio.NewValuer(queries).Rebuild(ctx, to); err != nil {
+		t.Fatalf("failed to rebuild valuations: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+
+	type performanceResponse struct {
+		Returns []struct {
+			Period        string   `json:"period"`
+			StartDate     string   `json:"start_date"`
+			NetFlowMicros int64    `json:"net_flow_micros"`
+			GainMicros    int64    `json:"gain_micros"`
+			TWRPercent    float64  `json:"twr_percent"`
+			XIRRPercent   *float64 `json:"xirr_percent"`
+		} `json:"returns"`
+		Accounts []struct {
+			AccountName string `json:"account_name"`
+		} `json:"accounts"`
+	}
+	get := func(query string) performanceResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/performance?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp performanceResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// The $2,500 of shares and the $500 deposit are flows; the $250 rise on
+	// $3,000 invested is the return.
+	resp := get("account_id=" + account.ID + "&as_of=2024-07-02")
+	if len(resp.Returns) != 1 {
+		t.Fatalf("expected only a since-inception return, got %+v", resp.Returns)
+	}
+	if r := resp.Returns[0]; r.Period != "inception" || r.NetFlowMicros != 3_000_000_000 || r.GainMicros != 250_000_000 ||
+		r.TWRPercent < 8.33 || r.TWRPercent > 8.34 {
+		t.Errorf("unexpected inception return: %+v", r)
+	}
+
+	// Through August 2, MTD is measured from July 31 with no change.
+	household := get("")
+	if len(household.Returns) != 2 || household.Returns[0].Period != "mtd" || household.Returns[0].StartDate != "2024-07-31" || household.Returns[0].TWRPercent != 0 {
+		t.Errorf("unexpected household returns: %+v", household.Returns)
+	}
+	if r := household.Returns[len(household.Returns)-1]; r.XIRRPercent == nil || *r.XIRRPercent <= 0 {
+		t.Errorf("unexpected household inception XIRR: %+v", r)
+	}
+	if len(household.Accounts) != 1 || household.Accounts[0].AccountName != "Joint" {
+		t.Errorf("unexpected account returns: %+v", household.Accounts)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/performance?as_of=July", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid as_of, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestBenchmarkComparison(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	closes := map[string]map[string]int64{
+		"VTI": {"2024-07-01": 100_000_000, "2024-07-02": 110_000_000},
+		"SPY": {"2024-07-01": 500_000_000, "2024-07-02": 505_000_000},
+		"AGG": {"2024-07-01": 100_000_000, "2024-07-02": 100_000_000},
+	}
+	securityIDs := make(map[string]string)
+	for symbol, prices := range closes {
+		sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+			ID:     database.NewID(database.PrefixSecurity),
+			Symbol: symbol,
+		})
+		if err != nil {
+			t.Fatalf("failed to create security: %v", err)
+		}
+		securityIDs[symbol] = sec.ID
+		for date, closeMicros := range prices {
+			err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+				SecurityID:  sec.ID,
+				PriceDate:   date,
+				CloseMicros: closeMicros,
+				Source:      "csv",
+			})
+			if err != nil {
+				t.Fatalf("failed to save price: %v", err)
+			}
+		}
+	}
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Joint",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	// 10 shares of VTI moved in Monday, up 10% Tuesday.
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       account.ID,
+		SecurityID:      sql.NullString{String: securityIDs["VTI"], Valid: true},
+		TransactionType: "opening_balance",
+		TransactionDate: "2024-07-01",
+		QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+		AmountMicros:    900_000_000,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	to, _ := time.Parse("2006-01-02", "2024-07-02")
+	if _, err := portfolio.NewValuer(queries).Rebuild(ctx, to); err != nil {
+		t.Fatalf("failed to rebuild valuations: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+	get := func(path string, resp any) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+
+	type comparisonResponse struct {
+		Benchmark string `json:"benchmark"`
+		Periods   []struct {
+			Period                 string   `json:"period"`
+			TWRPercent             float64  `json:"twr_percent"`
+			BenchmarkReturnPercent *float64 `json:"benchmark_return_percent"`
+			ExcessPercent          *float64 `json:"excess_percent"`
+		} `json:"periods"`
+		Data []struct {
+			Date          string  `json:"date"`
+			ReturnPercent float64 `json:"return_percent"`
+		} `json:"data"`
+	}
+
+	// An all-stock account defaults to the total market.
+	var byDefault comparisonResponse
+	get("/api/v1/performance/benchmark?range=all&account_id="+account.ID, &byDefault)
+	if byDefault.Benchmark != "TOTAL" || len(byDefault.Periods) != 1 || *byDefault.Periods[0].ExcessPercent > 1e-9 {
+		t.Errorf("unexpected default comparison: %+v", byDefault)
+	}
+
+	var spy comparisonResponse
+	get("/api/v1/performance/benchmark?range=all&benchmark=SPY&account_id="+account.ID, &spy)
+	if len(spy.Periods) != 1 {
+		t.Fatalf("expected a since-inception comparison, got %+v", spy.Periods)
+	}
+	if p := spy.Periods[0]; math.Abs(p.TWRPercent-10) > 1e-6 || p.BenchmarkReturnPercent == nil || math.Abs(*p.BenchmarkReturnPercent-1) > 1e-6 || math.Abs(*p.ExcessPercent-9) > 1e-6 {
+		t.Errorf("unexpected SPY comparison: %+v", p)
+	}
+	if len(spy.Data) != 2 || spy.Data[1].Date != "2024-07-02" || math.Abs(spy.Data[1].ReturnPercent-10) > 1e-6 {
+		t.Errorf("unexpected series: %+v", spy.Data)
+	}
+
+	// All in stocks against 60/40: overweighting stocks as they beat bonds
+	// is allocation, VTI beating SPY is selection.
+	var attribution struct {
+		ReturnPercent          float64 `json:"return_percent"`
+		BenchmarkReturnPercent float64 `json:"benchmark_return_percent"`
+		AllocationPercent      float64 `json:"allocation_percent"`
+		SelectionPercent       float64 `json:"selection_percent"`
+		Segments               []struct {
+			SecurityType string `json:"security_type"`
+		} `json:"segments"`
+	}
+	get("/api/v1/performance/attribution?period=inception&benchmark=60/40&account_id="+account.ID, &attribution)
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"return", attribution.ReturnPercent, 10},
+		{"benchmark return", attribution.BenchmarkReturnPercent, 0.6},
+		{"allocation", attribution.AllocationPercent, 0.4},
+		{"selection", attribution.SelectionPercent, 9},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-6 {
+			t.Errorf("%s = %.6f%%, want %.6f%%", c.name, c.got, c.want)
+		}
+	}
+	if len(attribution.Segments) != 2 || attribution.Segments[0].SecurityType != "bond" {
+		t.Errorf("unexpected segments: %+v", attribution.Segments)
+	}
+
+	var benchmarks []struct {
+		Name string `json:"name"`
+	}
+	get("/api/v1/benchmarks", &benchmarks)
+	if len(benchmarks) != 5 || benchmarks[2].Name != "60/40" {
+		t.Errorf("unexpected benchmarks: %+v", benchmarks)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/performance/attribution?period=2y&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid period, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestListHoldingsAsOf(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Brokerage Account",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "AAPL",
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+
+	// Bought 10 in December, sold 4 in February, bought 5 more in March.
+	for _, txn := range []struct {
+		txnType  string
+		date     string
+		qty      int64
+		amount   int64
+		cashType string
+		cash     int64
+	}{
+		{"buy", "2023-12-15", 10_000_000, 1_500_000_000, "purchase", -1_500_000_000},
+		{"sell", "2024-02-01", 4_000_000, 800_000_000, "proceeds", 800_000_000},
+		{"buy", "2024-03-01", 5_000_000, 1_000_000_000, "purchase", -1_000_000_000},
+	} {
+		id := database.NewID(database.PrefixTransaction)
+		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+			ID:              id,
+			AccountID:       account.ID,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+			TransactionType: txn.txnType,
+			TransactionDate: txn.date,
+			QuantityMicros:  sql.NullInt64{Int64: txn.qty, Valid: true},
+			AmountMicros:    txn.amount,
+			FeesInAmount:    true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+		err = queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+			ID:              database.NewID(database.PrefixCashTxn),
+			AccountID:       account.ID,
+			TransactionID:   sql.NullString{String: id, Valid: true},
+			TransactionDate: txn.date,
+			CashType:        txn.cashType,
+			AmountMicros:    txn.cash,
+		})
+		if err != nil {
+			t.Fatalf("failed to create cash transaction: %v", err)
+		}
+	}
+	err = queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+		ID:              database.NewID(database.PrefixCashTxn),
+		AccountID:       account.ID,
+		TransactionDate: "2023-12-01",
+		CashType:        "deposit",
+		AmountMicros:    2_000_000_000,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cash transaction: %v", err)
+	}
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	for date, closeMicros := range map[string]int64{"2023-12-29": 190_000_000, "2024-06-28": 210_000_000} {
+		err := queries.UpsertPrice(ctx, db.UpsertPriceParams{
+			SecurityID:  sec.ID,
+			PriceDate:   date,
+			CloseMicros: closeMicros,
+			Source:      "csv",
+		})
+		if err != nil {
+			t.Fatalf("failed to save price: %v", err)
+		}
+	}
+
+	type holdingsResponse struct {
+		Holdings []struct {
+			Symbol      string  `json:"symbol"`
+			Quantity    float64 `json:"quantity"`
+			CostBasis   *int64  `json:"cost_basis_micros"`
+			PriceDate   *string `json:"price_date"`
+			MarketValue *int64  `json:"market_value_micros"`
+			Lots        []struct {
+				Quantity float64 `json:"quantity"`
+			} `json:"lots"`
+		} `json:"holdings"`
+		AsOf       *string `json:"as_of"`
+		CashMicros *int64  `json:"cash_micros"`
+	}
+
+	handler := server.NewRouter(queries)
+	get := func(path string) holdingsResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp holdingsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// At year end all 10 shares were still held, at the December close.
+	yearEnd := get("/api/v1/holdings?with_prices=true&as_of=2023-12-31&account_id=" + account.ID)
+	if yearEnd.AsOf == nil || *yearEnd.AsOf != "2023-12-31" || yearEnd.CashMicros == nil || *yearEnd.CashMicros != 500_000_000 {
+		t.Errorf("expected cash 500000000 as of 2023-12-31, got %v as of %v", yearEnd.CashMicros, yearEnd.AsOf)
+	}
+	if len(yearEnd.Holdings) != 1 {
+		t.Fatalf("expected 1 holding, got %+v", yearEnd.Holdings)
+	}
+	if h := yearEnd.Holdings[0]; h.Quantity != 10 || *h.CostBasis != 1_500_000_000 || h.MarketValue == nil || *h.MarketValue != 1_900_000_000 || *h.PriceDate != "2023-12-29" {
+		t.Errorf("unexpected year-end holding: %+v", h)
+	}
+
+	// After the sale, 6 shares of the first lot; the March lot isn't bought yet.
+	february := get("/api/v1/holdings?as_of=2024-02-15&account_id=" + account.ID)
+	if *february.CashMicros != 1_300_000_000 {
+		t.Errorf("expected cash 1300000000, got %d", *february.CashMicros)
+	}
+	if len(february.Holdings) != 1 {
+		t.Fatalf("expected 1 holding, got %+v", february.Holdings)
+	}
+	if h := february.Holdings[0]; h.Quantity != 6 || *h.CostBasis != 900_000_000 || h.MarketValue != nil || h.Lots != nil {
+		t.Errorf("unexpected February holding: %+v", h)
+	}
+
+	// Nothing held before the first purchase.
+	if before := get("/api/v1/holdings?as_of=2023-12-10"); len(before.Holdings) != 0 || *before.CashMicros != 2_000_000_000 {
+		t.Errorf("expected only cash before the first purchase, got %+v", before)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/holdings?as_of=12/31/2023", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid date, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestReconcileHoldings(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Brokerage Account",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	securityIDs := make(map[string]string)
+	for _, symbol := range []string{"AAPL", "VTI"} {
+		sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+			ID:     database.NewID(database.PrefixSecurity),
+			Symbol: symbol,
+		})
+		if err != nil {
+			t.Fatalf("failed to create security: %v", err)
+		}
+		securityIDs[symbol] = sec.ID
+	}
+
+	err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       account.ID,
+		SecurityID:      sql.NullString{String: securityIDs["AAPL"], Valid: true},
+		TransactionType: "buy",
+		TransactionDate: "2024-01-10",
+		QuantityMicros:  sql.NullInt64{Int64: 10_000_000, Valid: true},
+		AmountMicros:    1_800_000_000,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	// A 4-for-1 split lot processing doesn't know about, and VTI we have no
+	// transactions for.
+	err = queries.UpsertCorporateAction(ctx, db.UpsertCorporateActionParams{
+		SecurityID: securityIDs["AAPL"],
+		ActionDate: "2024-06-10",
+		ActionType: "split",
+		Ratio:      sql.NullFloat64{Float64: 4, Valid: true},
+		Source:     "csv",
+	})
+	if err != nil {
+		t.Fatalf("failed to save split: %v", err)
+	}
+	for _, pos := range []struct {
+		symbol string
+		date   string
+		qty    int64
+		basis  int64
+	}{
+		{"AAPL", "2024-12-31", 40_000_000, 1_800_000_000},
+		{"VTI", "2024-12-31", 5_000_000, 1_000_000_000},
+		{"AAPL", "2025-06-30", 40_000_000, 1_800_000_000},
+	} {
+		_, err := queries.UpsertPosition(ctx, db.UpsertPositionParams{
+			ID:              database.NewID(database.PrefixPosition),
+			AccountID:       account.ID,
+			SecurityID:      securityIDs[pos.symbol],
+			QuantityMicros:  pos.qty,
+			CostBasisMicros: sql.NullInt64{Int64: pos.basis, Valid: true},
+			AsOfDate:        pos.date,
+		})
+		if err != nil {
+			t.Fatalf("failed to create position: %v", err)
+		}
+	}
+
+	handler := server.NewRouter(queries)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/holdings/reconcile?as_of=2025-01-15&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		AsOfDate string `json:"as_of_date"`
+		Lines    []struct {
+			Status       string  `json:"status"`
+			Symbol       string  `json:"symbol"`
+			LotQuantity  float64 `json:"lot_quantity"`
+			QuantityDiff float64 `json:"quantity_diff"`
+			Causes       []struct {
+				Kind string `json:"kind"`
+			} `json:"causes"`
+		} `json:"lines"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.AsOfDate != "2024-12-31" || len(resp.Lines) != 2 {
+		t.Fatalf("expected the 2024-12-31 snapshot with 2 lines, got %+v", resp)
+	}
+	if l := resp.Lines[0]; l.Symbol != "AAPL" || l.Status != "mismatch" || l.QuantityDiff != 30 || len(l.Causes) != 1 || l.Causes[0].Kind != "unapplied_split" {
+		t.Errorf("unexpected AAPL line: %+v", l)
+	}
+	if l := resp.Lines[1]; l.Symbol != "VTI" || l.Status != "missing_lots" || len(l.Causes) != 1 || l.Causes[0].Kind != "missing_opening_balance" {
+		t.Errorf("unexpected VTI line: %+v", l)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/holdings/reconcile?as_of=2024-06-30&account_id=" + account.ID: http.StatusNotFound,
+		"/api/v1/holdings/reconcile?account_id=acct_missing":                  http.StatusNotFound,
+		"/api/v1/holdings/reconcile":                                          http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestIncomeSummary(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Bond Account",
+		InstitutionName: "lpl",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	muni, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "544525ZV3",
+		Name:   sql.NullString{String: "LOS ANGELES CA DPT WTR & PWR", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+	err = queries.UpsertBond(ctx, db.UpsertBondParams{
+		SecurityID:      muni.ID,
+		CouponRate:      5,
+		MaturityDate:    "2030-07-01",
+		ParMicros:       15_000_000_000,
+		PaymentsPerYear: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create bond: %v", err)
+	}
+
+	for _, c := range []struct {
+		date        string
+		cashType    string
+		amount      int64
+		security    string
+		description string
+	}{
+		{"2024-01-01", "opening", 10_000_000_000, "", ""},
+		{"2024-01-02", "interest", 375_000_000, muni.ID, ""},
+		{"2024-01-31", "interest", 12_000_000, "", "INSURED CASH ACCOUNT"},
+		{"2024-04-01", "fee", -100_000_000, "", "ADVISORY FEE"},
+		{"2024-04-15", "transfer_out", -250_000_000, "", "INCOME DISTRIBUTION TRF TO AC#1234"},
+		{"2024-05-01", "transfer_out", -5_000_000_000, "", "INSURED CASH ACCOUNT"},
+		{"2024-07-01", "interest", 375_000_000, muni.ID, ""},
+	} {
+		err := queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+			ID:              database.NewID(database.PrefixCashTxn),
+			AccountID:       account.ID,
+			TransactionDate: c.date,
+			CashType:        c.cashType,
+			AmountMicros:    c.amount,
+			SecurityID:      sql.NullString{String: c.security, Valid: c.security != ""},
+			Description:     sql.NullString{String: c.description, Valid: c.description != ""},
+		})
+		if err != nil {
+			t.Fatalf("failed to create cash transaction: %v", err)
+		}
+	}
+
+	handler := server.NewRouter(queries)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/income/summary?year=2024&by=quarter&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	type totals struct {
+		MuniInterestMicros  int64 `json:"muni_interest_micros"`
+		SweepInterestMicros int64 `json:"sweep_interest_micros"`
+		FeesMicros          int64 `json:"fees_micros"`
+		DistributionsMicros int64 `json:"distributions_micros"`
+		NetMicros           int64 `json:"net_micros"`
+	}
+	var resp struct {
+		EndDate    string `json:"end_date"`
+		Totals     totals `json:"totals"`
+		CashMicros int64  `json:"cash_micros"`
+		Periods    []struct {
+			Period string `json:"period"`
+			Totals totals `json:"totals"`
+			YTD    totals `json:"ytd"`
+		} `json:"periods"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.EndDate != "2024-12-31" {
+		t.Errorf("expected the summary to run through 2024-12-31, got %s", resp.EndDate)
+	}
+	want := totals{
+		MuniInterestMicros:  750_000_000,
+		SweepInterestMicros: 12_000_000,
+		FeesMicros:          -100_000_000,
+		DistributionsMicros: -250_000_000,
+		NetMicros:           662_000_000,
+	}
+	if resp.Totals != want {
+		t.Errorf("got totals %+v, want %+v", resp.Totals, want)
+	}
+	if resp.CashMicros != 5_412_000_000 {
+		t.Errorf("expected cash $5,412, got %d", resp.CashMicros)
+	}
+	if len(resp.Periods) != 3 || resp.Periods[1].Period != "2024-Q2" || resp.Periods[2].YTD.NetMicros != 662_000_000 {
+		t.Errorf("unexpected periods: %+v", resp.Periods)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/income/by-security?account_id="+account.ID, nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var lines []struct {
+		Symbol       string `json:"symbol"`
+		Category     string `json:"category"`
+		Payments     int    `json:"payments"`
+		AmountMicros int64  `json:"amount_micros"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&lines); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(lines) != 2 || lines[0].Symbol != "544525ZV3" || lines[0].Category != "muni_interest" || lines[0].Payments != 2 {
+		t.Errorf("unexpected income by security: %+v", lines)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/income/summary?account_id=acct_missing": http.StatusNotFound,
+		"/api/v1/income/summary?by=week":                 http.StatusBadRequest,
+		"/api/v1/income/by-security?year=abc":            http.StatusBadRequest,
+		"/api/v1/income/summary?year=0":                  http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestForm1099DIV(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Joint",
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	securities := make(map[string]string)
+	for symbol, name := range map[string]string{
+		"AAPL": "APPLE INC",
+		"O":    "REALTY INCOME CORP",
+		"NVS":  "NOVARTIS AG ADR",
+	} {
+		sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+			ID:     database.NewID(database.PrefixSecurity),
+			Symbol: symbol,
+			Name:   sql.NullString{String: name, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to create security: %v", err)
+		}
+		securities[symbol] = sec.ID
+	}
+
+	for _, c := range []struct {
+		date         string
+		cashType     string
+		amount       int64
+		symbol       string
+		dividendType string
+	}{
+		{"2024-03-15", "dividend", 100_000_000, "AAPL", "ordinary"},
+		{"2024-03-29", "dividend", 40_000_000, "O", "section_199a"},
+		{"2024-06-28", "return_of_capital", 5_000_000, "O", "return_of_capital"},
+		{"2024-07-15", "dividend", 20_000_000, "NVS", "foreign"},
+		{"2025-03-14", "dividend", 100_000_000, "AAPL", "qualified"},
+	} {
+		err := queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+			ID:              database.NewID(database.PrefixCashTxn),
+			AccountID:       account.ID,
+			TransactionDate: c.date,
+			CashType:        c.cashType,
+			AmountMicros:    c.amount,
+			SecurityID:      sql.NullString{String: securities[c.symbol], Valid: true},
+			DividendType:    sql.NullString{String: c.dividendType, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to create cash transaction: %v", err)
+		}
+	}
+	err = queries.CreateImportDiagnostic(ctx, db.CreateImportDiagnosticParams{
+		ID:           database.NewID(database.PrefixDiagnostic),
+		AccountID:    account.ID,
+		SourceFile:   "etrade.csv",
+		LineNumber:   12,
+		ActivityDate: "2024-07-15",
+		Activity:     "Foreign Tax Paid",
+		Symbol:       sql.NullString{String: "NVS", Valid: true},
+		AmountMicros: -3_000_000,
+		Reason:       "foreign_tax",
+	})
+	if err != nil {
+		t.Fatalf("failed to create import diagnostic: %v", err)
+	}
+
+	handler := server.NewRouter(queries)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/income/1099-div?year=2024&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var forms []struct {
+		AccountName string `json:"account_name"`
+		Year        int    `json:"year"`
+		Box1a       int64  `json:"box_1a_ordinary_dividends_micros"`
+		Box1b       int64  `json:"box_1b_qualified_dividends_micros"`
+		Box3        int64  `json:"box_3_nondividend_micros"`
+		Box5        int64  `json:"box_5_section_199a_micros"`
+		Box7        int64  `json:"box_7_foreign_tax_micros"`
+		Estimated   int64  `json:"estimated_micros"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&forms); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(forms) != 1 || forms[0].AccountName != "Joint" || forms[0].Year != 2024 {
+		t.Fatalf("unexpected forms: %+v", forms)
+	}
+	f := forms[0]
+	if f.Box1a != 160_000_000 || f.Box1b != 20_000_000 || f.Box5 != 40_000_000 {
+		t.Errorf("expected 1a $160, 1b $20 and 5 $40, got %+v", f)
+	}
+	if f.Box3 != 5_000_000 || f.Box7 != 3_000_000 || f.Estimated != 20_000_000 {
+		t.Errorf("expected 3 $5, 7 $3 and $20 estimated, got %+v", f)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/income/1099-div?account_id=acct_missing": http.StatusNotFound,
+		"/api/v1/income/1099-div?year=0":                  http.StatusBadRequest,
+		"/api/v1/income/1099-div?year=2023":               http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestCashFlowProjections(t *testing.T) {
+	ctx := context.Background()
+	_, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := queries.CreateAccount(ctx, db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            "Brokerage Account",
+		InstitutionName: "schwab",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	sec, err := queries.UpsertSecurity(ctx, db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: "AAPL",
+		Name:   sql.NullString{String: "Apple Inc.", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+
+	for _, buy := range []struct {
+		date     string
+		quantity int64
+	}{
+		{"2025-01-10", 100_000_000},
+		{"2025-12-01", 20_000_000},
+	} {
+		err = queries.CreateTransaction(ctx, db.CreateTransactionParams{
+			ID:              database.NewID(database.PrefixTransaction),
+			AccountID:       account.ID,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+			TransactionType: "buy",
+			TransactionDate: buy.date,
+			QuantityMicros:  sql.NullInt64{Int64: buy.quantity, Valid: true},
+			AmountMicros:    buy.quantity * 200,
+			FeesInAmount:    true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+	}
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	for _, date := range []string{"2025-02-13", "2025-05-15", "2025-08-14", "2025-11-13"} {
+		err := queries.CreateCashTransaction(ctx, db.CreateCashTransactionParams{
+			ID:              database.NewID(database.PrefixCashTxn),
+			AccountID:       account.ID,
+			TransactionDate: date,
+			CashType:        "dividend",
+			AmountMicros:    25_000_000,
+			SecurityID:      sql.NullString{String: sec.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to create cash transaction: %v", err)
+		}
+	}
+
+	handler := server.NewRouter(queries)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cashflow/projections?as_of=2026-01-13&account_id="+account.ID, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		TimeHorizon      string `json:"timeHorizon"`
+		ReferenceDate    string `json:"referenceDate"`
+		AnnualProjection struct {
+			Year           int     `json:"year"`
+			TotalProjected float64 `json:"totalProjected"`
+			ByType         struct {
+				Dividend float64 `json:"dividend"`
+			} `json:"byType"`
+		} `json:"annualProjection"`
+		Payments []struct {
+			Symbol      string  `json:"symbol"`
+			IncomeType  string  `json:"incomeType"`
+			PaymentDate string  `json:"paymentDate"`
+			Amount      float64 `json:"amount"`
+			TotalAmount float64 `json:"totalAmount"`
+			Shares      float64 `json:"shares"`
+			Frequency   string  `json:"frequency"`
+		} `json:"payments"`
+		MonthlyBreakdown []struct {
+			PeriodLabel    string  `json:"periodLabel"`
+			DividendIncome float64 `json:"dividendIncome"`
+		} `json:"monthlyBreakdown"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.TimeHorizon != "12-month-forward" || resp.ReferenceDate != "2026-01-13" || resp.AnnualProjection.Year != 2026 {
+		t.Errorf("unexpected header: %+v", resp)
+	}
+	if resp.AnnualProjection.TotalProjected != 120 || resp.AnnualProjection.ByType.Dividend != 120 {
+		t.Errorf("expected $120 of dividends, got %+v", resp.AnnualProjection)
+	}
+	if len(resp.Payments) != 4 {
+		t.Fatalf("expected 4 payments, got %+v", resp.Payments)
+	}
+	if p := resp.Payments[0]; p.PaymentDate != "2026-02-13" || p.Amount != 0.25 || p.TotalAmount != 30 || p.Shares != 120 || p.Frequency != "quarterly" || p.IncomeType != "dividend" {
+		t.Errorf("unexpected first payment: %+v", p)
+	}
+	if len(resp.MonthlyBreakdown) != 12 || resp.MonthlyBreakdown[1].PeriodLabel != "Feb 2026" || resp.MonthlyBreakdown[1].DividendIncome != 30 {
+		t.Errorf("unexpected monthly breakdown: %+v", resp.MonthlyBreakdown)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/cashflow/projections?account_id=acct_missing": http.StatusNotFound,
+		"/api/v1/cashflow/projections?as_of=01-13-2026":        http.StatusBadRequest,
+		"/api/v1/cashflow/projections":                         http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
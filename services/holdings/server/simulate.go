@@ -0,0 +1,190 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/levisegal/monay/services/holdings/config"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+type SimulatedLotResponse struct {
+	LotID           string  `json:"lot_id"`
+	AccountID       string  `json:"account_id"`
+	AccountName     string  `json:"account_name"`
+	AcquiredDate    string  `json:"acquired_date"`
+	Quantity        float64 `json:"quantity"`
+	CostBasisMicros int64   `json:"cost_basis_micros"`
+	ProceedsMicros  int64   `json:"proceeds_micros"`
+	GainMicros      int64   `json:"gain_micros"`
+	HoldingPeriod   string  `json:"holding_period"`
+	Remaining       float64 `json:"remaining"`
+}
+
+type RealizedGainsResponse struct {
+	ShortTermMicros int64 `json:"short_term_micros"`
+	LongTermMicros  int64 `json:"long_term_micros"`
+	TotalMicros     int64 `json:"total_micros"`
+}
+
+type SimulateSellResponse struct {
+	Symbol           string                 `json:"symbol"`
+	Method           string                 `json:"method"`
+	Date             string                 `json:"date"`
+	Quantity         float64                `json:"quantity"`
+	PriceMicros      int64                  `json:"price_micros"`
+	ProceedsMicros   int64                  `json:"proceeds_micros"`
+	FeesMicros       int64                  `json:"fees_micros"`
+	Lots             []SimulatedLotResponse `json:"lots"`
+	Unmatched        float64                `json:"unmatched_quantity"`
+	Gain             RealizedGainsResponse  `json:"gain"`
+	FederalTaxMicros int64                  `json:"federal_tax_micros"`
+	StateTaxMicros   int64                  `json:"state_tax_micros"`
+	TotalTaxMicros   int64                  `json:"total_tax_micros"`
+	YTDBefore        RealizedGainsResponse  `json:"ytd_before"`
+	YTDAfter         RealizedGainsResponse  `json:"ytd_after"`
+}
+
+// simulateSell runs a what-if sale against open lots. Query parameters:
+// symbol, qty, price (required); account_id, fees, method (fifo|lifo|hifo),
+// date (YYYY-MM-DD), short_term_rate, long_term_rate, state_rate (optional,
+// defaulting to the configured rates).
+func (rt *Router) simulateSell(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	symbol := q.Get("symbol")
+	if symbol == "" {
+		respondError(w, http.StatusBadRequest, "missing symbol")
+		return
+	}
+
+	qty, err := queryMicros(q.Get("qty"))
+	if err != nil || qty <= 0 {
+		respondError(w, http.StatusBadRequest, "invalid qty")
+		return
+	}
+	price, err := queryMicros(q.Get("price"))
+	if err != nil || price <= 0 {
+		respondError(w, http.StatusBadRequest, "invalid price")
+		return
+	}
+	fees := int64(0)
+	if s := q.Get("fees"); s != "" {
+		if fees, err = queryMicros(s); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid fees")
+			return
+		}
+	}
+
+	method := taxlots.MethodFIFO
+	if s := q.Get("method"); s != "" {
+		if method, err = taxlots.ParseMethod(s); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	date := time.Now()
+	if s := q.Get("date"); s != "" {
+		if date, err = time.Parse("2006-01-02", s); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid date")
+			return
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to load config")
+		slog.Error("failed to load config", "error", err)
+		return
+	}
+	rates := taxlots.TaxRates{
+		ShortTerm: queryFloat(q.Get("short_term_rate"), cfg.TaxShortTermRate),
+		LongTerm:  queryFloat(q.Get("long_term_rate"), cfg.TaxLongTermRate),
+		State:     queryFloat(q.Get("state_rate"), cfg.TaxStateRate),
+	}
+
+	result, err := taxlots.NewSimulator(rt.queries).SimulateSell(r.Context(), taxlots.SimulateSellParams{
+		AccountID:      q.Get("account_id"),
+		Symbol:         symbol,
+		QuantityMicros: qty,
+		PriceMicros:    price,
+		FeesMicros:     fees,
+		Method:         method,
+		Date:           date,
+		Rates:          rates,
+	})
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		slog.Error("failed to simulate sell", "error", err)
+		return
+	}
+
+	respond(w, http.StatusOK, simulateSellToResponse(result))
+}
+
+func simulateSellToResponse(result *taxlots.SimulateSellResult) SimulateSellResponse {
+	resp := SimulateSellResponse{
+		Symbol:           result.Symbol,
+		Method:           string(result.Method),
+		Date:             result.Date.Format("2006-01-02"),
+		Quantity:         float64(result.QuantityMicros) / 1_000_000,
+		PriceMicros:      result.PriceMicros,
+		ProceedsMicros:   result.ProceedsMicros,
+		FeesMicros:       result.FeesMicros,
+		Lots:             make([]SimulatedLotResponse, len(result.Reliefs)),
+		Unmatched:        float64(result.UnmatchedMicros) / 1_000_000,
+		Gain:             realizedGainsToResponse(result.Gain),
+		FederalTaxMicros: result.FederalTaxMicros,
+		StateTaxMicros:   result.StateTaxMicros,
+		TotalTaxMicros:   result.TotalTaxMicros,
+		YTDBefore:        realizedGainsToResponse(result.YTDBefore),
+		YTDAfter:         realizedGainsToResponse(result.YTDAfter),
+	}
+	for i, relief := range result.Reliefs {
+		resp.Lots[i] = SimulatedLotResponse{
+			LotID:           relief.Lot.ID,
+			AccountID:       relief.Lot.AccountID,
+			AccountName:     relief.AccountName,
+			AcquiredDate:    relief.Lot.AcquiredDate,
+			Quantity:        float64(relief.QuantityMicros) / 1_000_000,
+			CostBasisMicros: relief.CostBasisMicros,
+			ProceedsMicros:  relief.ProceedsMicros,
+			GainMicros:      relief.GainMicros,
+			HoldingPeriod:   string(relief.HoldingPeriod),
+			Remaining:       float64(relief.RemainingMicros) / 1_000_000,
+		}
+	}
+	return resp
+}
+
+func realizedGainsToResponse(t taxlots.RealizedTotals) RealizedGainsResponse {
+	return RealizedGainsResponse{
+		ShortTermMicros: t.ShortTermMicros,
+		LongTermMicros:  t.LongTermMicros,
+		TotalMicros:     t.TotalMicros,
+	}
+}
+
+func queryMicros(s string) (int64, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	return d.Mul(decimal.NewFromInt(1_000_000)).IntPart(), nil
+}
+
+func queryFloat(s string, fallback float64) float64 {
+	if s == "" {
+		return fallback
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
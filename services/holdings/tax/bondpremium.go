@@ -0,0 +1,149 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// BondPremiumLine is one bond lot's premium amortized during the year. For
+// tax-exempt bonds the amortization isn't deductible; it reduces the
+// tax-exempt interest reported (1099-INT box 13). For taxable bonds it
+// offsets the interest income (box 11).
+type BondPremiumLine struct {
+	AccountID          string
+	Symbol             string
+	DateAcquired       string
+	QuantityMicros     int64 // held at some point during the year
+	CostBasisMicros    int64 // as bought, without accrued interest
+	AmortizationMicros int64
+	LotID              string
+}
+
+type BondPremiumReport struct {
+	Year                    int
+	Lines                   []BondPremiumLine
+	TotalAmortizationMicros int64
+}
+
+// BondPremium builds the year's bond premium amortization. An empty accountID
+// includes every account.
+func (r *Reporter) BondPremium(ctx context.Context, year int, accountID string) (*BondPremiumReport, error) {
+	bonds, err := r.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+
+	accountIDs := []string{accountID}
+	if accountID == "" {
+		accounts, err := r.queries.ListAccounts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		accountIDs = accountIDs[:0]
+		for _, a := range accounts {
+			accountIDs = append(accountIDs, a.ID)
+		}
+	}
+
+	var lots []db.ListLotsByAccountRow
+	var dispositions []db.ListDispositionsByAccountRow
+	for _, id := range accountIDs {
+		accountLots, err := r.queries.ListLotsByAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list lots: %w", err)
+		}
+		lots = append(lots, accountLots...)
+
+		accountDispositions, err := r.queries.ListDispositionsByAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dispositions: %w", err)
+		}
+		dispositions = append(dispositions, accountDispositions...)
+	}
+
+	return BuildBondPremiumReport(year, lots, dispositions, bonds), nil
+}
+
+// BuildBondPremiumReport amortizes each long bond lot over the part of the
+// year it was held: shares disposed of during the year up to their
+// disposition, the rest to year end. Lots transferred out are counted as
+// gone from the start; the receiving lot carries on their amortization.
+func BuildBondPremiumReport(year int, lots []db.ListLotsByAccountRow, dispositions []db.ListDispositionsByAccountRow, bonds []db.ListBondsRow) *BondPremiumReport {
+	report := &BondPremiumReport{Year: year}
+
+	bySecurity := make(map[string]db.ListBondsRow)
+	for _, b := range bonds {
+		bySecurity[b.SecurityID] = b
+	}
+	byLot := make(map[string][]db.ListDispositionsByAccountRow)
+	for _, d := range dispositions {
+		byLot[d.LotID] = append(byLot[d.LotID], d)
+	}
+
+	yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := yearStart.AddDate(1, 0, 0)
+
+	for _, lot := range lots {
+		bond, ok := bySecurity[lot.SecurityID]
+		if !ok || lot.PositionSide == string(taxlots.PositionShort) {
+			continue
+		}
+		acquired := parseDate(lot.AcquiredDate)
+		if !acquired.Before(yearEnd) {
+			continue
+		}
+		start := yearStart
+		if acquired.After(start) {
+			start = acquired
+		}
+
+		// amortized is the premium amortized on quantity between from and to.
+		amortized := func(quantity int64, from, to time.Time) int64 {
+			basis := taxlots.ProRata(lot.CostBasisMicros, lot.QuantityMicros, quantity)
+			return taxlots.AmortizedBasis(basis, quantity, bond, acquired, from) -
+				taxlots.AmortizedBasis(basis, quantity, bond, acquired, to)
+		}
+
+		line := BondPremiumLine{
+			AccountID:    lot.AccountID,
+			Symbol:       lot.Symbol,
+			DateAcquired: lot.AcquiredDate,
+			LotID:        lot.ID,
+		}
+		heldAtYearEnd := lot.RemainingMicros
+		for _, d := range byLot[lot.ID] {
+			disposed := parseDate(d.DisposedDate)
+			switch {
+			case !disposed.Before(yearEnd):
+				heldAtYearEnd += d.QuantityMicros
+			case !disposed.Before(start):
+				line.QuantityMicros += d.QuantityMicros
+				line.AmortizationMicros += amortized(d.QuantityMicros, start, disposed)
+			}
+		}
+		line.QuantityMicros += heldAtYearEnd
+		line.AmortizationMicros += amortized(heldAtYearEnd, start, yearEnd)
+
+		if line.AmortizationMicros == 0 {
+			continue
+		}
+		line.CostBasisMicros = taxlots.ProRata(lot.CostBasisMicros, lot.QuantityMicros, line.QuantityMicros)
+		report.Lines = append(report.Lines, line)
+		report.TotalAmortizationMicros += line.AmortizationMicros
+	}
+
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.DateAcquired < b.DateAcquired
+	})
+
+	return report
+}
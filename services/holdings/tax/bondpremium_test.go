@@ -0,0 +1,64 @@
+package tax_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/tax"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestBuildBondPremiumReport(t *testing.T) {
+	bond := db.ListBondsRow{
+		SecurityID:      "sec_bond",
+		CouponRate:      0.05,
+		MaturityDate:    "2030-01-01",
+		ParMicros:       1_000_000,
+		PaymentsPerYear: 2,
+	}
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	basis := func(quantity int64, on string) int64 {
+		return taxlots.AmortizedBasis(taxlots.ProRata(21_000_000_000, 20_000_000_000, quantity), quantity, bond, date("2023-07-01"), date(on))
+	}
+
+	lots := []db.ListLotsByAccountRow{
+		{
+			ID: "lot_1", AccountID: "acct", SecurityID: "sec_bond", Symbol: "BOND", AcquiredDate: "2023-07-01",
+			QuantityMicros: 20_000_000_000, RemainingMicros: 5_000_000_000, CostBasisMicros: 21_000_000_000, PositionSide: "long",
+		},
+		{
+			ID: "lot_2", AccountID: "acct", SecurityID: "sec_stock", Symbol: "ACME", AcquiredDate: "2023-07-01",
+			QuantityMicros: 100_000_000, RemainingMicros: 100_000_000, CostBasisMicros: 5_000_000_000, PositionSide: "long",
+		},
+	}
+	dispositions := []db.ListDispositionsByAccountRow{
+		{LotID: "lot_1", DisposedDate: "2023-10-01", QuantityMicros: 5_000_000_000},
+		{LotID: "lot_1", DisposedDate: "2024-04-01", QuantityMicros: 5_000_000_000},
+		{LotID: "lot_1", DisposedDate: "2025-02-01", QuantityMicros: 5_000_000_000},
+	}
+
+	report := tax.BuildBondPremiumReport(2024, lots, dispositions, []db.ListBondsRow{bond})
+
+	if len(report.Lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(report.Lines))
+	}
+	line := report.Lines[0]
+
+	// Sold in April: amortized to the sale. Held at year end, including what
+	// was sold the next year: amortized for the whole year.
+	want := basis(5_000_000_000, "2024-01-01") - basis(5_000_000_000, "2024-04-01") +
+		basis(10_000_000_000, "2024-01-01") - basis(10_000_000_000, "2025-01-01")
+	if line.AmortizationMicros != want || want <= 0 {
+		t.Errorf("amortization = %d, want %d", line.AmortizationMicros, want)
+	}
+	if line.QuantityMicros != 15_000_000_000 {
+		t.Errorf("quantity = %d, want 15000000000", line.QuantityMicros)
+	}
+	if report.TotalAmortizationMicros != want {
+		t.Errorf("total = %d, want %d", report.TotalAmortizationMicros, want)
+	}
+}
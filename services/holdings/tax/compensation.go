@@ -0,0 +1,96 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// CompensationLine is a sale of RSU or ESPP shares whose basis includes
+// ordinary income already on the W-2. Brokers report the 1099-B basis without
+// it (zero for RSUs, the purchase price for ESPP), so the 8949 needs code B
+// and the adjustment in column (g) to avoid paying tax on it twice.
+type CompensationLine struct {
+	AccountID         string
+	Symbol            string
+	PlanType          string
+	DateAcquired      string
+	DateSold          string
+	QuantityMicros    int64
+	ProceedsMicros    int64
+	BrokerBasisMicros int64 // what the 1099-B reports: basis less compensation income
+	AdjustmentMicros  int64 // compensation income to add back
+	CostBasisMicros   int64 // adjusted basis
+	GainMicros        int64
+	EsppDisposition   string // qualifying or disqualifying, for ESPP sales
+	DispositionID     string
+}
+
+type CompensationReport struct {
+	Year                  int
+	Lines                 []CompensationLine
+	TotalAdjustmentMicros int64
+}
+
+// Compensation builds the year's compensation income adjustments. An empty
+// accountID includes every account.
+func (r *Reporter) Compensation(ctx context.Context, year int, accountID string) (*CompensationReport, error) {
+	rows, err := r.queries.ListDispositionsByYear(ctx, strconv.Itoa(year))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispositions: %w", err)
+	}
+
+	if accountID != "" {
+		var filtered []db.ListDispositionsByYearRow
+		for _, row := range rows {
+			if row.AccountID == accountID {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	return BuildCompensationReport(year, rows), nil
+}
+
+// BuildCompensationReport keeps the dispositions of employee plan lots that
+// included compensation income.
+func BuildCompensationReport(year int, rows []db.ListDispositionsByYearRow) *CompensationReport {
+	report := &CompensationReport{Year: year}
+
+	for _, row := range rows {
+		if !row.PlanType.Valid || row.CompensationIncomeMicros == 0 {
+			continue
+		}
+
+		report.Lines = append(report.Lines, CompensationLine{
+			AccountID:         row.AccountID,
+			Symbol:            row.Symbol,
+			PlanType:          row.PlanType.String,
+			DateAcquired:      row.AcquiredDate,
+			DateSold:          row.DisposedDate,
+			QuantityMicros:    row.QuantityMicros,
+			ProceedsMicros:    row.ProceedsMicros,
+			BrokerBasisMicros: row.CostBasisMicros - row.CompensationIncomeMicros,
+			AdjustmentMicros:  row.CompensationIncomeMicros,
+			CostBasisMicros:   row.CostBasisMicros,
+			GainMicros:        row.ProceedsMicros - row.CostBasisMicros,
+			EsppDisposition:   row.EsppDisposition.String,
+			DispositionID:     row.ID,
+		})
+		report.TotalAdjustmentMicros += row.CompensationIncomeMicros
+	}
+
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.DateSold != b.DateSold {
+			return a.DateSold < b.DateSold
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	return report
+}
@@ -0,0 +1,44 @@
+package tax_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/tax"
+)
+
+func TestBuildCompensationReport(t *testing.T) {
+	rsu := disposition("d1", "ACME", "stock", "2023-03-01", "2024-06-01", "long_term", 10, 1_200, 1_000)
+	rsu.PlanType = sql.NullString{String: "rsu", Valid: true}
+	rsu.CompensationIncomeMicros = 1_000_000_000
+
+	espp := disposition("d2", "ACME", "stock", "2023-06-30", "2024-03-01", "short_term", 10, 1_200, 1_000)
+	espp.PlanType = sql.NullString{String: "espp", Valid: true}
+	espp.CompensationIncomeMicros = 150_000_000
+	espp.EsppDisposition = sql.NullString{String: "disqualifying", Valid: true}
+
+	report := tax.BuildCompensationReport(2024, []db.ListDispositionsByYearRow{
+		rsu,
+		espp,
+		disposition("d3", "AAPL", "stock", "2023-06-01", "2024-03-01", "short_term", 10, 1_500, 1_000),
+	})
+
+	if len(report.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(report.Lines))
+	}
+
+	first, second := report.Lines[0], report.Lines[1]
+	if first.DispositionID != "d2" || second.DispositionID != "d1" {
+		t.Errorf("lines not sorted by date sold: %s, %s", first.DispositionID, second.DispositionID)
+	}
+	if first.BrokerBasisMicros != 850_000_000 || first.EsppDisposition != "disqualifying" {
+		t.Errorf("espp line = %+v, want broker basis 850000000 and disqualifying", first)
+	}
+	if second.BrokerBasisMicros != 0 || second.AdjustmentMicros != 1_000_000_000 {
+		t.Errorf("rsu line = %+v, want broker basis 0 and adjustment 1000000000", second)
+	}
+	if got, want := report.TotalAdjustmentMicros, int64(1_150_000_000); got != want {
+		t.Errorf("total adjustment = %d, want %d", got, want)
+	}
+}
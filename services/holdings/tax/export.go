@@ -0,0 +1,114 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// WriteCSV writes one row per 8949 line, with the form's column letters in
+// the header so it can be pasted into a worksheet or tax software import.
+func (f *Form8949) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"Box",
+		"(a) Description",
+		"(b) Date Acquired",
+		"(c) Date Sold",
+		"(d) Proceeds",
+		"(e) Cost Basis",
+		"(f) Code",
+		"(g) Adjustment",
+		"(h) Gain or Loss",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, line := range f.Lines {
+		record := []string{
+			string(line.Box),
+			line.Description,
+			formDate(line.DateAcquired),
+			formDate(line.DateSold),
+			dollars(line.ProceedsMicros),
+			dollars(line.CostBasisMicros),
+			line.AdjustmentCode,
+			adjustment(line.AdjustmentMicros),
+			dollars(line.GainMicros),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// txfRefNumbers maps 8949 boxes to TXF reference numbers for capital gains
+// detail records.
+var txfRefNumbers = map[Box]int{
+	BoxA: 321,
+	BoxB: 711,
+	BoxC: 712,
+	BoxD: 323,
+	BoxE: 713,
+	BoxF: 714,
+}
+
+// WriteTXF writes the 8949 lines in Tax Exchange Format v042, which most
+// desktop tax software imports. Each line becomes a format 5 detail record:
+// description, dates, basis, proceeds and, when present, the adjustment.
+func (f *Form8949) WriteTXF(w io.Writer, generated time.Time) error {
+	if _, err := fmt.Fprintf(w, "V042\nAmonay holdings\nD%s\n^\n", generated.Format("01/02/2006")); err != nil {
+		return err
+	}
+
+	for _, line := range f.Lines {
+		if _, err := fmt.Fprintf(w, "TD\nN%d\nC1\nL1\nP%s\nD%s\nD%s\n$%s\n$%s\n",
+			txfRefNumbers[line.Box],
+			line.Description,
+			formDate(line.DateAcquired),
+			formDate(line.DateSold),
+			dollars(line.CostBasisMicros),
+			dollars(line.ProceedsMicros),
+		); err != nil {
+			return err
+		}
+		if line.AdjustmentMicros != 0 {
+			if _, err := fmt.Fprintf(w, "$%s\n", dollars(line.AdjustmentMicros)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprint(w, "^\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dollars(micros int64) string {
+	return decimal.NewFromInt(micros).Div(decimal.NewFromInt(1_000_000)).StringFixed(2)
+}
+
+func adjustment(micros int64) string {
+	if micros == 0 {
+		return ""
+	}
+	return dollars(micros)
+}
+
+// formDate converts YYYY-MM-DD to the MM/DD/YYYY the IRS forms use.
+func formDate(s string) string {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return s
+	}
+	return t.Format("01/02/2006")
+}
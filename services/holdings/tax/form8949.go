@@ -0,0 +1,328 @@
+// Package tax turns lot dispositions into tax forms and checks them against
+// what the brokers report.
+package tax
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// Box is a Form 8949 checkbox. Each box is filed on its own 8949 page and
+// totals to its own Schedule D line.
+type Box string
+
+const (
+	BoxA Box = "A" // short-term, basis reported to the IRS
+	BoxB Box = "B" // short-term, basis not reported
+	BoxC Box = "C" // short-term, no 1099-B
+	BoxD Box = "D" // long-term, basis reported to the IRS
+	BoxE Box = "E" // long-term, basis not reported
+	BoxF Box = "F" // long-term, no 1099-B
+)
+
+// Boxes lists every box in form order.
+var Boxes = []Box{BoxA, BoxB, BoxC, BoxD, BoxE, BoxF}
+
+// ScheduleDLine is the Schedule D line a box's totals are carried to.
+func (b Box) ScheduleDLine() string {
+	switch b {
+	case BoxA:
+		return "1b"
+	case BoxB:
+		return "2"
+	case BoxC:
+		return "3"
+	case BoxD:
+		return "8b"
+	case BoxE:
+		return "9"
+	default:
+		return "10"
+	}
+}
+
+func (b Box) LongTerm() bool {
+	return b == BoxD || b == BoxE || b == BoxF
+}
+
+// Adjustment codes for column (f).
+const (
+	AdjustmentCodeBasis    = "B" // the 1099-B basis is wrong
+	AdjustmentCodeWashSale = "W" // loss disallowed by a wash sale
+)
+
+// Form8949Line is one row of Form 8949, built from one lot disposition.
+type Form8949Line struct {
+	Box              Box
+	AccountID        string
+	Symbol           string
+	Description      string // column (a)
+	DateAcquired     string // column (b), YYYY-MM-DD
+	DateSold         string // column (c), YYYY-MM-DD
+	ProceedsMicros   int64  // column (d)
+	CostBasisMicros  int64  // column (e)
+	AdjustmentCode   string // column (f)
+	AdjustmentMicros int64  // column (g)
+	GainMicros       int64  // column (h) = (d) - (e) + (g)
+	QuantityMicros   int64
+	DispositionID    string
+}
+
+// BoxTotals are the column totals for one 8949 box.
+type BoxTotals struct {
+	Box              Box
+	Lines            int
+	ProceedsMicros   int64
+	CostBasisMicros  int64
+	AdjustmentMicros int64
+	GainMicros       int64
+}
+
+// ScheduleD summarizes the 8949 boxes into Schedule D Parts I and II.
+type ScheduleD struct {
+	Boxes               []BoxTotals
+	ShortTermGainMicros int64 // line 7
+	LongTermGainMicros  int64 // line 15
+	TotalGainMicros     int64 // line 16
+}
+
+type Form8949 struct {
+	Year      int
+	Lines     []Form8949Line
+	ScheduleD ScheduleD
+}
+
+type Reporter struct {
+	queries *db.Queries
+}
+
+func NewReporter(queries *db.Queries) *Reporter {
+	return &Reporter{queries: queries}
+}
+
+// Form8949 builds Form 8949 and Schedule D totals from the year's lot
+// dispositions. An empty accountID includes every account.
+func (r *Reporter) Form8949(ctx context.Context, year int, accountID string) (*Form8949, error) {
+	rows, err := r.queries.ListDispositionsByYear(ctx, strconv.Itoa(year))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispositions: %w", err)
+	}
+
+	if accountID != "" {
+		var filtered []db.ListDispositionsByYearRow
+		for _, row := range rows {
+			if row.AccountID == accountID {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	// Purchases in any account within 30 days of a sale make it a wash sale,
+	// including those in the first weeks of the next year.
+	since := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -washSaleWindowDays)
+	buys, err := r.queries.ListPurchasesSince(ctx, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchases: %w", err)
+	}
+
+	return BuildForm8949(year, rows, buys), nil
+}
+
+// BuildForm8949 assigns each disposition to a box, fills in the adjustment
+// columns and totals the boxes.
+//
+// Covered employee plan lots report the 1099-B basis, which leaves out the
+// compensation income, with code B and that income as a negative adjustment.
+// Losses replaced by buys within 30 days get code W and the disallowed loss
+// added back. The replacement lot's basis isn't raised by it.
+func BuildForm8949(year int, rows []db.ListDispositionsByYearRow, buys []db.ListPurchasesSinceRow) *Form8949 {
+	form := &Form8949{Year: year}
+	washSales := washSaleLosses(rows, buys)
+
+	for _, row := range rows {
+		longTerm := row.HoldingPeriod == string(taxlots.HoldingPeriodLongTerm)
+		// A disposition of no shares is return of capital in excess of
+		// basis, which brokers report on the 1099-DIV, not a 1099-B.
+		onForm1099B := row.QuantityMicros != 0
+		covered := isCovered(row.SecurityType.String, parseDate(row.AcquiredDate))
+		box := boxFor(longTerm, onForm1099B, covered)
+
+		line := Form8949Line{
+			Box:             box,
+			AccountID:       row.AccountID,
+			Symbol:          row.Symbol,
+			Description:     describe(row.QuantityMicros, row.Symbol),
+			DateAcquired:    row.AcquiredDate,
+			DateSold:        row.DisposedDate,
+			ProceedsMicros:  row.ProceedsMicros,
+			CostBasisMicros: row.CostBasisMicros,
+			QuantityMicros:  row.QuantityMicros,
+			DispositionID:   row.ID,
+		}
+		if covered && onForm1099B && row.CompensationIncomeMicros != 0 {
+			line.CostBasisMicros -= row.CompensationIncomeMicros
+			line.AdjustmentCode += AdjustmentCodeBasis
+			line.AdjustmentMicros -= row.CompensationIncomeMicros
+		}
+		if disallowed := washSales[row.ID]; disallowed > 0 {
+			line.AdjustmentCode += AdjustmentCodeWashSale
+			line.AdjustmentMicros += disallowed
+		}
+		line.GainMicros = line.ProceedsMicros - line.CostBasisMicros + line.AdjustmentMicros
+
+		form.Lines = append(form.Lines, line)
+	}
+
+	sort.SliceStable(form.Lines, func(i, j int) bool {
+		a, b := form.Lines[i], form.Lines[j]
+		if a.Box != b.Box {
+			return a.Box < b.Box
+		}
+		if a.DateSold != b.DateSold {
+			return a.DateSold < b.DateSold
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	form.ScheduleD = summarize(form.Lines)
+	return form
+}
+
+func summarize(lines []Form8949Line) ScheduleD {
+	totals := make(map[Box]*BoxTotals)
+	for _, box := range Boxes {
+		totals[box] = &BoxTotals{Box: box}
+	}
+
+	for _, line := range lines {
+		t := totals[line.Box]
+		t.Lines++
+		t.ProceedsMicros += line.ProceedsMicros
+		t.CostBasisMicros += line.CostBasisMicros
+		t.AdjustmentMicros += line.AdjustmentMicros
+		t.GainMicros += line.GainMicros
+	}
+
+	var sched ScheduleD
+	for _, box := range Boxes {
+		t := totals[box]
+		sched.Boxes = append(sched.Boxes, *t)
+		if box.LongTerm() {
+			sched.LongTermGainMicros += t.GainMicros
+		} else {
+			sched.ShortTermGainMicros += t.GainMicros
+		}
+	}
+	sched.TotalGainMicros = sched.ShortTermGainMicros + sched.LongTermGainMicros
+
+	return sched
+}
+
+// washSaleLosses returns the loss disallowed on each disposition, by ID. A
+// loss is disallowed on as many shares as were bought within 30 days before
+// or after the sale, in any account, and each purchased share replaces only
+// one sold share. Shares bought and sold in the same sale don't replace it.
+func washSaleLosses(rows []db.ListDispositionsByYearRow, buys []db.ListPurchasesSinceRow) map[string]int64 {
+	soldWith := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if soldWith[row.SellTransactionID] == nil {
+			soldWith[row.SellTransactionID] = make(map[string]bool)
+		}
+		soldWith[row.SellTransactionID][row.LotTransactionID] = true
+	}
+
+	sorted := make([]db.ListDispositionsByYearRow, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].DisposedDate < sorted[j].DisposedDate })
+
+	unused := make(map[string]int64, len(buys))
+	for _, buy := range buys {
+		unused[buy.ID] = buy.QuantityMicros.Int64
+	}
+
+	losses := make(map[string]int64)
+	for _, row := range sorted {
+		loss := row.CostBasisMicros - row.ProceedsMicros
+		if loss <= 0 || row.QuantityMicros <= 0 {
+			continue
+		}
+
+		sold := parseDate(row.DisposedDate)
+		from := sold.AddDate(0, 0, -washSaleWindowDays).Format("2006-01-02")
+		to := sold.AddDate(0, 0, washSaleWindowDays).Format("2006-01-02")
+
+		var replaced int64
+		for _, buy := range buys {
+			if replaced == row.QuantityMicros {
+				break
+			}
+			if buy.SecurityID.String != row.SecurityID || buy.TransactionDate < from || buy.TransactionDate > to {
+				continue
+			}
+			if soldWith[row.SellTransactionID][buy.ID] {
+				continue
+			}
+			n := min(unused[buy.ID], row.QuantityMicros-replaced)
+			unused[buy.ID] -= n
+			replaced += n
+		}
+		if replaced > 0 {
+			losses[row.ID] = taxlots.ProRata(loss, row.QuantityMicros, replaced)
+		}
+	}
+	return losses
+}
+
+func boxFor(longTerm, onForm1099B, covered bool) Box {
+	switch {
+	case longTerm && !onForm1099B:
+		return BoxF
+	case !onForm1099B:
+		return BoxC
+	case longTerm && covered:
+		return BoxD
+	case longTerm:
+		return BoxE
+	case covered:
+		return BoxA
+	default:
+		return BoxB
+	}
+}
+
+// isCovered reports whether the broker had to report basis to the IRS, per the
+// 1099-B covered security phase-in: stock and ETFs acquired from 2011, mutual
+// funds and DRIP shares from 2012, bonds and options from 2014.
+func isCovered(securityType string, acquired time.Time) bool {
+	coveredFrom := "2011-01-01"
+	switch securityType {
+	case "mutual_fund", "fund":
+		coveredFrom = "2012-01-01"
+	case "bond", "option":
+		coveredFrom = "2014-01-01"
+	}
+	return !acquired.Before(parseDate(coveredFrom))
+}
+
+func describe(quantityMicros int64, symbol string) string {
+	if quantityMicros == 0 {
+		// No shares sold: return of capital in excess of basis.
+		return symbol + " return of capital"
+	}
+	qty := decimal.NewFromInt(quantityMicros).Div(decimal.NewFromInt(1_000_000))
+	return qty.String() + " sh " + symbol
+}
+
+func parseDate(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
@@ -0,0 +1,156 @@
+package tax_test
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/tax"
+)
+
+func TestBuildForm8949(t *testing.T) {
+	rows := []db.ListDispositionsByYearRow{
+		disposition("d1", "AAPL", "stock", "2023-06-01", "2024-03-01", "short_term", 10, 1_500, 1_000),
+		disposition("d2", "AAPL", "stock", "2009-06-01", "2024-03-01", "long_term", 5, 900, 100),
+		disposition("d3", "VTI", "etf", "2011-06-01", "2024-02-01", "long_term", 2, 400, 300),
+		disposition("d4", "MSFT", "", "2022-01-10", "2024-01-15", "long_term", 1, 300, 350),
+		disposition("d5", "VFIAX", "mutual_fund", "2011-06-01", "2024-02-01", "long_term", 2, 400, 300),
+		disposition("d6", "O", "stock", "2015-03-02", "2024-09-30", "long_term", 0, 20, 0),
+		disposition("d7", "O", "stock", "2024-03-01", "2024-09-30", "short_term", 0, 10, 0),
+	}
+
+	form := tax.BuildForm8949(2024, rows, nil)
+
+	wantBoxes := map[string]tax.Box{
+		"d1": tax.BoxA,
+		"d2": tax.BoxE,
+		"d3": tax.BoxD,
+		"d4": tax.BoxD,
+		"d5": tax.BoxE,
+		"d6": tax.BoxF,
+		"d7": tax.BoxC,
+	}
+	for _, line := range form.Lines {
+		if line.Box != wantBoxes[line.DispositionID] {
+			t.Errorf("%s: box = %s, want %s", line.DispositionID, line.Box, wantBoxes[line.DispositionID])
+		}
+	}
+
+	if form.Lines[0].Box != tax.BoxA || form.Lines[len(form.Lines)-1].Box != tax.BoxF {
+		t.Errorf("lines not sorted by box: first %s, last %s", form.Lines[0].Box, form.Lines[len(form.Lines)-1].Box)
+	}
+
+	if got, want := form.ScheduleD.ShortTermGainMicros, int64(510_000_000); got != want {
+		t.Errorf("short-term gain = %d, want %d", got, want)
+	}
+	if got, want := form.ScheduleD.LongTermGainMicros, int64(970_000_000); got != want {
+		t.Errorf("long-term gain = %d, want %d", got, want)
+	}
+	if got, want := form.ScheduleD.TotalGainMicros, int64(1_480_000_000); got != want {
+		t.Errorf("total gain = %d, want %d", got, want)
+	}
+}
+
+func TestForm8949Export(t *testing.T) {
+	form := tax.BuildForm8949(2024, []db.ListDispositionsByYearRow{
+		disposition("d1", "AAPL", "stock", "2023-06-01", "2024-03-01", "short_term", 10, 1_500, 1_000),
+	}, nil)
+
+	var csvOut bytes.Buffer
+	if err := form.WriteCSV(&csvOut); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if want := "A,10 sh AAPL,06/01/2023,03/01/2024,1500.00,1000.00,,,500.00"; !strings.Contains(csvOut.String(), want) {
+		t.Errorf("csv missing %q:\n%s", want, csvOut.String())
+	}
+
+	var txfOut bytes.Buffer
+	if err := form.WriteTXF(&txfOut, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteTXF: %v", err)
+	}
+	want := "V042\nAmonay holdings\nD02/01/2025\n^\n" +
+		"TD\nN321\nC1\nL1\nP10 sh AAPL\nD06/01/2023\nD03/01/2024\n$1000.00\n$1500.00\n^\n"
+	if txfOut.String() != want {
+		t.Errorf("txf =\n%s\nwant\n%s", txfOut.String(), want)
+	}
+}
+
+func TestForm8949Adjustments(t *testing.T) {
+	rsu := disposition("d1", "GILD", "stock", "2023-02-20", "2024-03-01", "long_term", 10, 800, 750)
+	rsu.PlanType = sql.NullString{String: "rsu", Valid: true}
+	rsu.CompensationIncomeMicros = 750_000_000
+
+	loss := disposition("d2", "AAPL", "stock", "2023-01-10", "2024-06-03", "long_term", 10, 1_000, 1_500)
+	loss.SecurityID = "sec-aapl"
+	loss.SellTransactionID = "t-sell"
+	loss.LotTransactionID = "t-buy-2023"
+
+	buys := []db.ListPurchasesSinceRow{
+		{ID: "t-buy-2023", SecurityID: sql.NullString{String: "sec-aapl", Valid: true}, TransactionDate: "2023-01-10", QuantityMicros: sql.NullInt64{Int64: 10_000_000, Valid: true}},
+		{ID: "t-rebuy", SecurityID: sql.NullString{String: "sec-aapl", Valid: true}, TransactionDate: "2024-06-20", QuantityMicros: sql.NullInt64{Int64: 4_000_000, Valid: true}},
+	}
+
+	form := tax.BuildForm8949(2024, []db.ListDispositionsByYearRow{rsu, loss}, buys)
+
+	want := map[string]struct {
+		code       string
+		basis      int64
+		adjustment int64
+		gain       int64
+	}{
+		// The 1099-B basis leaves out the $750 taxed on the W-2.
+		"d1": {"B", 0, -750_000_000, 50_000_000},
+		// 4 of the 10 shares were bought back: $200 of the $500 loss is disallowed.
+		"d2": {"W", 1_500_000_000, 200_000_000, -300_000_000},
+	}
+	for _, line := range form.Lines {
+		w := want[line.DispositionID]
+		if line.AdjustmentCode != w.code || line.CostBasisMicros != w.basis || line.AdjustmentMicros != w.adjustment || line.GainMicros != w.gain {
+			t.Errorf("%s: code %q basis %d adjustment %d gain %d, want %q %d %d %d", line.DispositionID,
+				line.AdjustmentCode, line.CostBasisMicros, line.AdjustmentMicros, line.GainMicros,
+				w.code, w.basis, w.adjustment, w.gain)
+		}
+	}
+
+	for _, totals := range form.ScheduleD.Boxes {
+		if totals.Box != tax.BoxD {
+			continue
+		}
+		if totals.CostBasisMicros != 1_500_000_000 || totals.AdjustmentMicros != -550_000_000 || totals.GainMicros != -250_000_000 {
+			t.Errorf("box D totals = %+v", totals)
+		}
+	}
+	if got, want := form.ScheduleD.TotalGainMicros, int64(-250_000_000); got != want {
+		t.Errorf("total gain = %d, want %d", got, want)
+	}
+
+	var txfOut bytes.Buffer
+	if err := form.WriteTXF(&txfOut, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteTXF: %v", err)
+	}
+	for _, want := range []string{
+		"P10 sh GILD\nD02/20/2023\nD03/01/2024\n$0.00\n$800.00\n$-750.00\n^\n",
+		"P10 sh AAPL\nD01/10/2023\nD06/03/2024\n$1500.00\n$1000.00\n$200.00\n^\n",
+	} {
+		if !strings.Contains(txfOut.String(), want) {
+			t.Errorf("txf missing %q:\n%s", want, txfOut.String())
+		}
+	}
+}
+
+func disposition(id, symbol, securityType, acquired, disposed, period string, qty, proceeds, basis int64) db.ListDispositionsByYearRow {
+	return db.ListDispositionsByYearRow{
+		ID:              id,
+		Symbol:          symbol,
+		SecurityType:    sql.NullString{String: securityType, Valid: securityType != ""},
+		AcquiredDate:    acquired,
+		DisposedDate:    disposed,
+		HoldingPeriod:   period,
+		QuantityMicros:  qty * 1_000_000,
+		ProceedsMicros:  proceeds * 1_000_000,
+		CostBasisMicros: basis * 1_000_000,
+	}
+}
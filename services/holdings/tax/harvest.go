@@ -0,0 +1,300 @@
+package tax
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// washSaleWindowDays is the wash sale window on each side of a sale.
+const washSaleWindowDays = 30
+
+// dripLookbackDays is how far back a dividend reinvestment counts as the
+// security still being enrolled in DRIP. Quarterly payers reinvest at least
+// every ~90 days.
+const dripLookbackDays = 120
+
+// DefaultReplacementPairs are funds tracking different but highly correlated
+// indexes, so selling one and buying the other keeps market exposure without
+// buying a substantially identical security.
+var DefaultReplacementPairs = ReplacementPairs{
+	"VTI":  {"ITOT", "SCHB"},
+	"ITOT": {"VTI", "SCHB"},
+	"SCHB": {"VTI", "ITOT"},
+	"VOO":  {"IVV", "SCHX"},
+	"IVV":  {"VOO", "SCHX"},
+	"SPY":  {"IVV", "SCHX"},
+	"QQQ":  {"VGT", "XLK"},
+	"VXUS": {"IXUS"},
+	"IXUS": {"VXUS"},
+	"VEA":  {"IEFA", "SCHF"},
+	"IEFA": {"VEA", "SCHF"},
+	"VWO":  {"IEMG", "SCHE"},
+	"IEMG": {"VWO", "SCHE"},
+	"BND":  {"AGG", "SCHZ"},
+	"AGG":  {"BND", "SCHZ"},
+	"VNQ":  {"SCHH", "USRT"},
+	"VIG":  {"DGRO"},
+	"DGRO": {"VIG"},
+	"VYM":  {"SCHD"},
+	"SCHD": {"VYM"},
+}
+
+// ReplacementPairs maps a symbol to securities that can stand in for it
+// while its loss is harvested.
+type ReplacementPairs map[string][]string
+
+// ParseReplacementPairs reads a pairs CSV: each line is a symbol followed by
+// its replacements. Lines starting with # are ignored.
+func ParseReplacementPairs(r io.Reader) (ReplacementPairs, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	pairs := make(ReplacementPairs)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pairs CSV: %w", err)
+		}
+		if len(record) < 2 {
+			continue
+		}
+
+		symbol := strings.ToUpper(strings.TrimSpace(record[0]))
+		for _, replacement := range record[1:] {
+			replacement = strings.ToUpper(strings.TrimSpace(replacement))
+			if replacement != "" && replacement != symbol {
+				pairs[symbol] = append(pairs[symbol], replacement)
+			}
+		}
+	}
+	return pairs, nil
+}
+
+type HarvestOptions struct {
+	AsOf          time.Time
+	AccountID     string           // empty for every account
+	MinLossMicros int64            // skip lots losing less than this
+	PricesMicros  map[string]int64 // symbol -> close, priced as taxlots.Pricing expects
+	ShortTermRate float64
+	LongTermRate  float64
+	StateRate     float64
+	Pairs         ReplacementPairs
+}
+
+// WashSaleBuy is a purchase that would turn a harvested loss into a wash sale.
+type WashSaleBuy struct {
+	TransactionID  string
+	AccountName    string
+	Date           string
+	QuantityMicros int64
+	DRIP           bool
+}
+
+// HarvestCandidate is an open lot trading below its basis.
+type HarvestCandidate struct {
+	LotID             string
+	AccountID         string
+	AccountName       string
+	Symbol            string
+	AcquiredDate      string
+	HoldingPeriod     taxlots.HoldingPeriod
+	QuantityMicros    int64
+	CostBasisMicros   int64
+	PriceMicros       int64
+	MarketValueMicros int64
+	LossMicros        int64 // positive: basis minus market value
+	TaxSavedMicros    int64
+
+	// WashSaleBuys are purchases of the same security, in any account,
+	// within 30 days before AsOf. Selling now would disallow the loss on up
+	// to WashSaleQuantityMicros shares.
+	WashSaleBuys           []WashSaleBuy
+	WashSaleQuantityMicros int64
+
+	// DRIPEnrolled is set when the security recently reinvested a dividend:
+	// the next reinvestment within 30 days of the sale would also be a wash sale.
+	DRIPEnrolled bool
+
+	Replacements []string
+}
+
+type HarvestReport struct {
+	AsOf                time.Time
+	ShortTerm           []HarvestCandidate
+	LongTerm            []HarvestCandidate
+	ShortTermLossMicros int64
+	LongTermLossMicros  int64
+	TaxSavedMicros      int64
+	MissingPrices       []string
+}
+
+// Harvest lists the lots open at the close of AsOf with unrealized losses
+// above the threshold.
+func (r *Reporter) Harvest(ctx context.Context, opts HarvestOptions) (*HarvestReport, error) {
+	lots, err := taxlots.NewValuer(r.queries).OpenLots(ctx, opts.AccountID, opts.AsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	since := opts.AsOf.AddDate(0, 0, -dripLookbackDays).Format("2006-01-02")
+	buys, err := r.queries.ListPurchasesSince(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchases: %w", err)
+	}
+
+	bonds, err := r.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	options, err := r.queries.ListOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list options: %w", err)
+	}
+
+	return BuildHarvest(lots, buys, taxlots.NewPricing(bonds, options), opts), nil
+}
+
+// BuildHarvest prices each open lot and keeps those losing at least
+// MinLossMicros. Purchases are checked across every account, since a buy in
+// any of them triggers a wash sale.
+func BuildHarvest(lots []db.ListOpenLotsRow, buys []db.ListPurchasesSinceRow, pricing *taxlots.Pricing, opts HarvestOptions) *HarvestReport {
+	report := &HarvestReport{AsOf: opts.AsOf}
+	missing := make(map[string]bool)
+
+	washFrom := opts.AsOf.AddDate(0, 0, -washSaleWindowDays).Format("2006-01-02")
+	dripFrom := opts.AsOf.AddDate(0, 0, -dripLookbackDays).Format("2006-01-02")
+	asOf := opts.AsOf.Format("2006-01-02")
+
+	buysBySecurity := make(map[string][]db.ListPurchasesSinceRow)
+	for _, buy := range buys {
+		if buy.TransactionDate > asOf {
+			continue
+		}
+		buysBySecurity[buy.SecurityID.String] = append(buysBySecurity[buy.SecurityID.String], buy)
+	}
+
+	for _, lot := range lots {
+		if opts.AccountID != "" && lot.AccountID != opts.AccountID {
+			continue
+		}
+		if lot.PositionSide == string(taxlots.PositionShort) {
+			// Closing a short position is a purchase, not a sale to harvest.
+			continue
+		}
+
+		price, ok := opts.PricesMicros[lot.Symbol]
+		if !ok {
+			missing[lot.Symbol] = true
+			continue
+		}
+
+		basis := taxlots.ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros)
+		longTerm := taxlots.LongTermDateFor(taxlots.AcquisitionKind(lot.AcquisitionKind), lot.AcquiredDate, lot.DonorAcquiredDate.String)
+		if lot.AcquisitionKind == string(taxlots.AcquisitionGift) && lot.FmvMicros.Valid {
+			// A gift worth less than the donor's basis can only lose from
+			// its value when given, held from the gift date.
+			if fmv := taxlots.ProRata(lot.FmvMicros.Int64, lot.QuantityMicros, lot.RemainingMicros); fmv < basis {
+				basis = fmv
+				longTerm = taxlots.LongTermDate(parseDate(lot.AcquiredDate))
+			}
+		}
+
+		if bond, ok := pricing.Bond(lot.SecurityID); ok {
+			// Selling a premium bond realizes a loss only on the premium
+			// not yet amortized.
+			basis = taxlots.AmortizedBasis(basis, lot.RemainingMicros, bond, parseDate(lot.AcquiredDate), opts.AsOf)
+		}
+
+		value := pricing.MarketValue(lot.SecurityID, lot.RemainingMicros, price)
+		loss := basis - value
+		if loss <= 0 || loss < opts.MinLossMicros {
+			continue
+		}
+
+		period := taxlots.HoldingPeriodLongTerm
+		if opts.AsOf.Before(longTerm) {
+			period = taxlots.HoldingPeriodShortTerm
+		}
+		rate := opts.ShortTermRate
+		if period == taxlots.HoldingPeriodLongTerm {
+			rate = opts.LongTermRate
+		}
+
+		candidate := HarvestCandidate{
+			LotID:             lot.ID,
+			AccountID:         lot.AccountID,
+			AccountName:       lot.AccountName,
+			Symbol:            lot.Symbol,
+			AcquiredDate:      lot.AcquiredDate,
+			HoldingPeriod:     period,
+			QuantityMicros:    lot.RemainingMicros,
+			CostBasisMicros:   basis,
+			PriceMicros:       price,
+			MarketValueMicros: value,
+			LossMicros:        loss,
+			TaxSavedMicros:    int64(float64(loss) * (rate + opts.StateRate)),
+			Replacements:      opts.Pairs[lot.Symbol],
+		}
+
+		for _, buy := range buysBySecurity[lot.SecurityID] {
+			drip := isDRIP(buy)
+			if drip && buy.TransactionDate >= dripFrom {
+				candidate.DRIPEnrolled = true
+			}
+			// The purchase that created this lot isn't a replacement for it.
+			if buy.ID == lot.TransactionID || buy.TransactionDate < washFrom {
+				continue
+			}
+			candidate.WashSaleBuys = append(candidate.WashSaleBuys, WashSaleBuy{
+				TransactionID:  buy.ID,
+				AccountName:    buy.AccountName,
+				Date:           buy.TransactionDate,
+				QuantityMicros: buy.QuantityMicros.Int64,
+				DRIP:           drip,
+			})
+			candidate.WashSaleQuantityMicros += buy.QuantityMicros.Int64
+		}
+		candidate.WashSaleQuantityMicros = min(candidate.WashSaleQuantityMicros, candidate.QuantityMicros)
+
+		if period == taxlots.HoldingPeriodLongTerm {
+			report.LongTerm = append(report.LongTerm, candidate)
+			report.LongTermLossMicros += loss
+		} else {
+			report.ShortTerm = append(report.ShortTerm, candidate)
+			report.ShortTermLossMicros += loss
+		}
+		report.TaxSavedMicros += candidate.TaxSavedMicros
+	}
+
+	byLoss := func(c []HarvestCandidate) func(i, j int) bool {
+		return func(i, j int) bool { return c[i].LossMicros > c[j].LossMicros }
+	}
+	sort.SliceStable(report.ShortTerm, byLoss(report.ShortTerm))
+	sort.SliceStable(report.LongTerm, byLoss(report.LongTerm))
+
+	for symbol := range missing {
+		report.MissingPrices = append(report.MissingPrices, symbol)
+	}
+	sort.Strings(report.MissingPrices)
+
+	return report
+}
+
+// isDRIP reports whether a buy was a dividend reinvestment. The importers map
+// reinvested dividends to plain buys, so only the description tells them apart.
+func isDRIP(buy db.ListPurchasesSinceRow) bool {
+	desc := strings.ToUpper(buy.Description.String)
+	return strings.Contains(desc, "REINVEST") || strings.Contains(desc, "DIVIDEND") || strings.Contains(desc, "DRIP")
+}
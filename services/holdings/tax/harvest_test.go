@@ -0,0 +1,189 @@
+package tax_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/tax"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestBuildHarvest(t *testing.T) {
+	lots := []db.ListOpenLotsRow{
+		openLot("lot-vti-old", "acct-a", "sec-vti", "txn-1", "VTI", "2022-01-10", 10, 2_500),
+		openLot("lot-vti-new", "acct-a", "sec-vti", "txn-2", "VTI", "2024-05-10", 10, 2_300),
+		openLot("lot-aapl", "acct-b", "sec-aapl", "txn-3", "AAPL", "2024-01-10", 5, 800),
+		openLot("lot-tiny", "acct-b", "sec-ko", "txn-4", "KO", "2024-01-10", 1, 60),
+		openLot("lot-gain", "acct-b", "sec-msft", "txn-5", "MSFT", "2024-01-10", 1, 300),
+		openLot("lot-noprice", "acct-b", "sec-xyz", "txn-6", "XYZ", "2024-01-10", 1, 100),
+	}
+	buys := []db.ListPurchasesSinceRow{
+		// created lot-vti-new; the lot's own purchase isn't a wash sale
+		purchase("txn-2", "sec-vti", "Joint", "2024-05-10", 10, "Bought"),
+		// DRIP in another account inside the window
+		purchase("txn-7", "sec-vti", "IRA", "2024-05-20", 0.5, "VANGUARD TOTAL STOCK MKT REINVEST PRICE $200"),
+		// DRIP outside the 30 day window but recent enough to count as enrolled
+		purchase("txn-8", "sec-aapl", "Joint", "2024-04-01", 0.2, "DIVIDEND REINVESTMENT"),
+	}
+
+	report := tax.BuildHarvest(lots, buys, taxlots.NewPricing(nil, nil), tax.HarvestOptions{
+		AsOf:          mustDate("2024-06-01"),
+		MinLossMicros: 50_000_000,
+		PricesMicros: map[string]int64{
+			"VTI":  200_000_000,
+			"AAPL": 150_000_000,
+			"KO":   30_000_000,
+			"MSFT": 400_000_000,
+		},
+		ShortTermRate: 0.35,
+		LongTermRate:  0.15,
+		StateRate:     0.05,
+		Pairs:         tax.DefaultReplacementPairs,
+	})
+
+	if len(report.LongTerm) != 1 || report.LongTerm[0].LotID != "lot-vti-old" {
+		t.Fatalf("long-term candidates = %+v, want lot-vti-old", report.LongTerm)
+	}
+	if len(report.ShortTerm) != 2 {
+		t.Fatalf("got %d short-term candidates, want 2", len(report.ShortTerm))
+	}
+
+	old := report.LongTerm[0]
+	if old.LossMicros != 500_000_000 {
+		t.Errorf("lot-vti-old loss = %d, want 500000000", old.LossMicros)
+	}
+	if old.TaxSavedMicros != 100_000_000 {
+		t.Errorf("lot-vti-old tax saved = %d, want 100000000", old.TaxSavedMicros)
+	}
+	if old.HoldingPeriod != taxlots.HoldingPeriodLongTerm {
+		t.Errorf("lot-vti-old period = %s", old.HoldingPeriod)
+	}
+	if len(old.WashSaleBuys) != 2 {
+		t.Errorf("lot-vti-old wash sale buys = %d, want 2", len(old.WashSaleBuys))
+	}
+	if strings.Join(old.Replacements, ",") != "ITOT,SCHB" {
+		t.Errorf("lot-vti-old replacements = %v", old.Replacements)
+	}
+
+	for _, c := range report.ShortTerm {
+		switch c.LotID {
+		case "lot-vti-new":
+			if len(c.WashSaleBuys) != 1 || !c.WashSaleBuys[0].DRIP || c.WashSaleBuys[0].AccountName != "IRA" {
+				t.Errorf("lot-vti-new wash sale buys = %+v, want the IRA DRIP", c.WashSaleBuys)
+			}
+			if c.WashSaleQuantityMicros != 500_000 {
+				t.Errorf("lot-vti-new wash sale quantity = %d, want 500000", c.WashSaleQuantityMicros)
+			}
+		case "lot-aapl":
+			if len(c.WashSaleBuys) != 0 || !c.DRIPEnrolled {
+				t.Errorf("lot-aapl: wash sale buys = %d, DRIP = %v; want none and enrolled", len(c.WashSaleBuys), c.DRIPEnrolled)
+			}
+		default:
+			t.Errorf("unexpected short-term candidate %s", c.LotID)
+		}
+	}
+
+	if strings.Join(report.MissingPrices, ",") != "XYZ" {
+		t.Errorf("missing prices = %v, want XYZ", report.MissingPrices)
+	}
+}
+
+func TestBuildHarvestOption(t *testing.T) {
+	// Two calls bought at $3.00 a share, now $1.00: 100 shares a contract.
+	lots := []db.ListOpenLotsRow{
+		openLot("lot-call", "acct-a", "sec-call", "txn-1", "AAPL240621C00200000", "2024-01-10", 2, 600),
+	}
+	pricing := taxlots.NewPricing(nil, []db.ListOptionsRow{{SecurityID: "sec-call", Multiplier: 100}})
+
+	report := tax.BuildHarvest(lots, nil, pricing, tax.HarvestOptions{
+		AsOf:         mustDate("2024-06-01"),
+		PricesMicros: map[string]int64{"AAPL240621C00200000": 1_000_000},
+	})
+
+	if len(report.ShortTerm) != 1 {
+		t.Fatalf("got %d short-term candidates, want 1", len(report.ShortTerm))
+	}
+	if c := report.ShortTerm[0]; c.MarketValueMicros != 200_000_000 || c.LossMicros != 400_000_000 {
+		t.Errorf("value = %d, loss = %d; want 200000000, 400000000", c.MarketValueMicros, c.LossMicros)
+	}
+}
+
+func TestBuildHarvestBond(t *testing.T) {
+	// $10,000 face bought at 110, now at 100.
+	lots := []db.ListOpenLotsRow{
+		openLot("lot-bond", "acct-a", "sec-bond", "txn-1", "13063EBK1", "2020-01-02", 10_000, 11_000),
+	}
+	bond := db.ListBondsRow{
+		SecurityID:      "sec-bond",
+		CouponRate:      0.05,
+		MaturityDate:    "2030-01-02",
+		ParMicros:       1_000_000,
+		PaymentsPerYear: 2,
+	}
+	asOf := mustDate("2025-01-02")
+
+	report := tax.BuildHarvest(lots, nil, taxlots.NewPricing([]db.ListBondsRow{bond}, nil), tax.HarvestOptions{
+		AsOf:         asOf,
+		PricesMicros: map[string]int64{"13063EBK1": 100_000_000},
+	})
+
+	if len(report.LongTerm) != 1 {
+		t.Fatalf("got %d long-term candidates, want 1", len(report.LongTerm))
+	}
+	c := report.LongTerm[0]
+	amortized := taxlots.AmortizedBasis(11_000_000_000, 10_000_000_000, bond, mustDate("2020-01-02"), asOf)
+	if c.CostBasisMicros != amortized || amortized >= 11_000_000_000 {
+		t.Errorf("basis = %d, want the amortized %d", c.CostBasisMicros, amortized)
+	}
+	if c.MarketValueMicros != 10_000_000_000 {
+		t.Errorf("value = %d, want 10000000000", c.MarketValueMicros)
+	}
+	if c.LossMicros != amortized-10_000_000_000 {
+		t.Errorf("loss = %d, want %d", c.LossMicros, amortized-10_000_000_000)
+	}
+}
+
+func TestParseReplacementPairs(t *testing.T) {
+	pairs, err := tax.ParseReplacementPairs(strings.NewReader("# symbol,replacements\nvti, itot ,SCHB\nBND,AGG\nLONE\n"))
+	if err != nil {
+		t.Fatalf("ParseReplacementPairs: %v", err)
+	}
+	if got := strings.Join(pairs["VTI"], ","); got != "ITOT,SCHB" {
+		t.Errorf("VTI = %s, want ITOT,SCHB", got)
+	}
+	if got := strings.Join(pairs["BND"], ","); got != "AGG" {
+		t.Errorf("BND = %s, want AGG", got)
+	}
+	if _, ok := pairs["LONE"]; ok {
+		t.Error("LONE has no replacements and should be skipped")
+	}
+}
+
+func openLot(id, accountID, securityID, txnID, symbol, acquired string, qty, basis int64) db.ListOpenLotsRow {
+	return db.ListOpenLotsRow{
+		ID:              id,
+		AccountID:       accountID,
+		SecurityID:      securityID,
+		TransactionID:   txnID,
+		AcquiredDate:    acquired,
+		QuantityMicros:  qty * 1_000_000,
+		RemainingMicros: qty * 1_000_000,
+		CostBasisMicros: basis * 1_000_000,
+		Symbol:          symbol,
+		AccountName:     accountID,
+	}
+}
+
+func purchase(id, securityID, accountName, date string, qty float64, description string) db.ListPurchasesSinceRow {
+	return db.ListPurchasesSinceRow{
+		ID:              id,
+		SecurityID:      sql.NullString{String: securityID, Valid: true},
+		TransactionType: "buy",
+		TransactionDate: date,
+		QuantityMicros:  sql.NullInt64{Int64: int64(qty * 1_000_000), Valid: true},
+		Description:     sql.NullString{String: description, Valid: true},
+		AccountName:     accountName,
+	}
+}
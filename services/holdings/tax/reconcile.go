@@ -0,0 +1,235 @@
+package tax
+
+import (
+	"context"
+	"sort"
+
+	"github.com/levisegal/monay/services/holdings/importer"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+// reconcileToleranceMicros absorbs cent rounding from splitting one sale's
+// proceeds and basis across lots. Brokers round per lot, we pro-rate.
+const reconcileToleranceMicros = 1_000_000
+
+type ReconcileStatus string
+
+const (
+	ReconcileMatched    ReconcileStatus = "matched"     // same quantity, amounts and term
+	ReconcileMismatch   ReconcileStatus = "mismatch"    // matched, but amounts or term differ
+	ReconcileMissing    ReconcileStatus = "missing"     // broker reported shares we have no disposition for
+	ReconcileBrokerless ReconcileStatus = "not_on_1099" // our disposition has no 1099-B sale
+)
+
+// ReconcileLine compares one 1099-B sale with the dispositions matched to it.
+// Amount differences are broker minus ours, measured on the matched quantity.
+type ReconcileLine struct {
+	Status       ReconcileStatus
+	Symbol       string
+	DateSold     string
+	DateAcquired string // broker's, or ours for not_on_1099 lines; empty for "VARIOUS"
+
+	Sale         *importer.Form1099BSale
+	Dispositions []Form8949Line
+
+	BrokerQuantityMicros  int64
+	MatchedQuantityMicros int64
+	MissingQuantityMicros int64
+
+	BrokerProceedsMicros int64
+	BrokerBasisMicros    int64
+	OurProceedsMicros    int64
+	OurBasisMicros       int64
+	ProceedsDiffMicros   int64
+	BasisDiffMicros      int64
+	WashSaleMicros       int64
+	BrokerTerm           string
+	OurTerm              string
+	TermMismatch         bool
+}
+
+type Reconciliation struct {
+	Year  int
+	Lines []ReconcileLine
+}
+
+// Reconcile matches a broker's 1099-B sales to the year's dispositions in
+// one account.
+func (r *Reporter) Reconcile(ctx context.Context, year int, accountID string, sales []importer.Form1099BSale) (*Reconciliation, error) {
+	form, err := r.Form8949(ctx, year, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return ReconcileSales(year, sales, form.Lines), nil
+}
+
+// ReconcileSales matches each 1099-B sale to our dispositions by security,
+// sale date and quantity. A sale reported per lot matches the one disposition
+// with the same quantity (and acquired date, when reported); a sale reported
+// in aggregate matches as many same-day dispositions as fit its quantity.
+func ReconcileSales(year int, sales []importer.Form1099BSale, lines []Form8949Line) *Reconciliation {
+	rec := &Reconciliation{Year: year}
+	used := make([]bool, len(lines))
+
+	for i := range sales {
+		sale := &sales[i]
+		if sale.DateSold.Year() != year {
+			continue
+		}
+
+		sold := sale.DateSold.Format("2006-01-02")
+		acquired := ""
+		if !sale.DateAcquired.IsZero() {
+			acquired = sale.DateAcquired.Format("2006-01-02")
+		}
+
+		var candidates []int
+		for j, line := range lines {
+			if used[j] || line.DateSold != sold || !sameSecurity(sale, line.Symbol) {
+				continue
+			}
+			candidates = append(candidates, j)
+		}
+
+		matched := pickDispositions(sale.QuantityMicros, acquired, candidates, lines)
+		line := ReconcileLine{
+			Symbol:               symbolFor(sale, lines, matched),
+			DateSold:             sold,
+			DateAcquired:         acquired,
+			Sale:                 sale,
+			BrokerQuantityMicros: sale.QuantityMicros,
+			WashSaleMicros:       sale.WashSaleDisallowedMicros,
+			BrokerTerm:           sale.Term,
+		}
+		for _, j := range matched {
+			used[j] = true
+			line.Dispositions = append(line.Dispositions, lines[j])
+			line.MatchedQuantityMicros += lines[j].QuantityMicros
+			line.OurProceedsMicros += lines[j].ProceedsMicros
+			line.OurBasisMicros += lines[j].CostBasisMicros
+		}
+		line.MissingQuantityMicros = sale.QuantityMicros - line.MatchedQuantityMicros
+		line.BrokerProceedsMicros = taxlots.ProRata(sale.ProceedsMicros, sale.QuantityMicros, line.MatchedQuantityMicros)
+		line.BrokerBasisMicros = taxlots.ProRata(sale.CostBasisMicros, sale.QuantityMicros, line.MatchedQuantityMicros)
+		line.OurTerm = termOf(line.Dispositions)
+		line.Status = compare(&line)
+
+		rec.Lines = append(rec.Lines, line)
+	}
+
+	for j, line := range lines {
+		// Return of capital beyond basis is reported on the 1099-DIV, not the 1099-B.
+		if used[j] || line.QuantityMicros == 0 {
+			continue
+		}
+		rec.Lines = append(rec.Lines, ReconcileLine{
+			Status:                ReconcileBrokerless,
+			Symbol:                line.Symbol,
+			DateSold:              line.DateSold,
+			DateAcquired:          line.DateAcquired,
+			Dispositions:          []Form8949Line{line},
+			MatchedQuantityMicros: line.QuantityMicros,
+			OurProceedsMicros:     line.ProceedsMicros,
+			OurBasisMicros:        line.CostBasisMicros,
+			OurTerm:               termOf([]Form8949Line{line}),
+		})
+	}
+
+	sort.SliceStable(rec.Lines, func(i, j int) bool {
+		a, b := rec.Lines[i], rec.Lines[j]
+		if a.DateSold != b.DateSold {
+			return a.DateSold < b.DateSold
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	return rec
+}
+
+// Unmatched returns the lines that need attention.
+func (r *Reconciliation) Unmatched() []ReconcileLine {
+	var out []ReconcileLine
+	for _, line := range r.Lines {
+		if line.Status != ReconcileMatched {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func pickDispositions(quantityMicros int64, acquired string, candidates []int, lines []Form8949Line) []int {
+	for _, j := range candidates {
+		if lines[j].QuantityMicros == quantityMicros && (acquired == "" || lines[j].DateAcquired == acquired) {
+			return []int{j}
+		}
+	}
+
+	var picked []int
+	remaining := quantityMicros
+	for _, j := range candidates {
+		if acquired != "" && lines[j].DateAcquired != acquired {
+			continue
+		}
+		if lines[j].QuantityMicros <= remaining {
+			picked = append(picked, j)
+			remaining -= lines[j].QuantityMicros
+		}
+	}
+	return picked
+}
+
+func compare(line *ReconcileLine) ReconcileStatus {
+	if line.MissingQuantityMicros > 0 {
+		return ReconcileMissing
+	}
+
+	line.ProceedsDiffMicros = line.BrokerProceedsMicros - line.OurProceedsMicros
+	line.BasisDiffMicros = line.BrokerBasisMicros - line.OurBasisMicros
+	line.TermMismatch = line.BrokerTerm != "" && line.BrokerTerm != line.OurTerm
+
+	if abs(line.ProceedsDiffMicros) > reconcileToleranceMicros ||
+		abs(line.BasisDiffMicros) > reconcileToleranceMicros ||
+		line.WashSaleMicros != 0 ||
+		line.TermMismatch {
+		return ReconcileMismatch
+	}
+	return ReconcileMatched
+}
+
+// sameSecurity matches on symbol, falling back to CUSIP since bonds are
+// stored with their CUSIP as the symbol.
+func sameSecurity(sale *importer.Form1099BSale, symbol string) bool {
+	return (sale.Symbol != "" && sale.Symbol == symbol) || (sale.CUSIP != "" && sale.CUSIP == symbol)
+}
+
+func symbolFor(sale *importer.Form1099BSale, lines []Form8949Line, matched []int) string {
+	if len(matched) > 0 {
+		return lines[matched[0]].Symbol
+	}
+	if sale.Symbol != "" {
+		return sale.Symbol
+	}
+	return sale.CUSIP
+}
+
+func termOf(lines []Form8949Line) string {
+	term := ""
+	for _, line := range lines {
+		t := string(taxlots.HoldingPeriodShortTerm)
+		if line.Box.LongTerm() {
+			t = string(taxlots.HoldingPeriodLongTerm)
+		}
+		if term != "" && term != t {
+			return "mixed"
+		}
+		term = t
+	}
+	return term
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package tax_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/importer"
+	"github.com/levisegal/monay/services/holdings/tax"
+)
+
+func TestReconcileSales(t *testing.T) {
+	form := tax.BuildForm8949(2024, []db.ListDispositionsByYearRow{
+		disposition("d1", "AAPL", "stock", "2023-06-01", "2024-03-01", "short_term", 10, 1_500, 1_000),
+		disposition("d2", "MSFT", "stock", "2020-01-10", "2024-04-01", "long_term", 3, 900, 300),
+		disposition("d3", "MSFT", "stock", "2021-01-10", "2024-04-01", "long_term", 2, 600, 400),
+		disposition("d4", "79768HCM8", "bond", "2023-09-05", "2024-12-05", "long_term", 15_000, 15_369, 15_890),
+		disposition("d5", "IBM", "stock", "2023-08-14", "2024-10-14", "long_term", 35, 8_250, 4_982),
+	}, nil)
+
+	sales := []importer.Form1099BSale{
+		// per-lot, exact
+		sale("AAPL", "", "2023-06-01", "2024-03-01", 10, 1_500, 1_000, 0, "short_term"),
+		// aggregated "VARIOUS" sale covering two dispositions, basis differs
+		sale("MSFT", "", "", "2024-04-01", 5, 1_500, 800, 0, "long_term"),
+		// bond reported by CUSIP with a wash sale
+		sale("", "79768HCM8", "2023-09-05", "2024-12-05", 15_000, 15_369, 15_890, 25, "long_term"),
+		// sale we have no lots for
+		sale("NVDA", "", "2019-05-01", "2024-06-03", 4, 2_000, 150, 0, "long_term"),
+		// prior year, ignored
+		sale("AAPL", "", "2022-01-01", "2023-03-01", 1, 100, 90, 0, "long_term"),
+	}
+
+	rec := tax.ReconcileSales(2024, sales, form.Lines)
+
+	want := map[string]tax.ReconcileStatus{
+		"AAPL":      tax.ReconcileMatched,
+		"MSFT":      tax.ReconcileMismatch,
+		"79768HCM8": tax.ReconcileMismatch,
+		"NVDA":      tax.ReconcileMissing,
+		"IBM":       tax.ReconcileBrokerless,
+	}
+	if len(rec.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(rec.Lines), len(want))
+	}
+
+	for _, line := range rec.Lines {
+		if line.Status != want[line.Symbol] {
+			t.Errorf("%s: status = %s, want %s", line.Symbol, line.Status, want[line.Symbol])
+		}
+
+		switch line.Symbol {
+		case "MSFT":
+			if len(line.Dispositions) != 2 {
+				t.Errorf("MSFT: matched %d dispositions, want 2", len(line.Dispositions))
+			}
+			if line.BasisDiffMicros != 100_000_000 {
+				t.Errorf("MSFT: basis diff = %d, want 100000000", line.BasisDiffMicros)
+			}
+		case "79768HCM8":
+			if line.WashSaleMicros != 25_000_000 {
+				t.Errorf("bond: wash sale = %d, want 25000000", line.WashSaleMicros)
+			}
+		case "NVDA":
+			if line.MissingQuantityMicros != 4_000_000 {
+				t.Errorf("NVDA: missing = %d, want 4000000", line.MissingQuantityMicros)
+			}
+		}
+	}
+}
+
+func sale(symbol, cusip, acquired, sold string, qty, proceeds, basis, wash int64, term string) importer.Form1099BSale {
+	s := importer.Form1099BSale{
+		Symbol:                   symbol,
+		CUSIP:                    cusip,
+		QuantityMicros:           qty * 1_000_000,
+		DateSold:                 mustDate(sold),
+		ProceedsMicros:           proceeds * 1_000_000,
+		CostBasisMicros:          basis * 1_000_000,
+		WashSaleDisallowedMicros: wash * 1_000_000,
+		Term:                     term,
+		BasisReported:            true,
+	}
+	if acquired != "" {
+		s.DateAcquired = mustDate(acquired)
+	}
+	return s
+}
+
+func mustDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
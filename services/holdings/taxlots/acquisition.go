@@ -0,0 +1,180 @@
+package taxlots
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// AcquisitionKind is how the shares in a lot were acquired. It decides which
+// basis and holding period rules apply when the lot is sold.
+type AcquisitionKind string
+
+const (
+	AcquisitionPurchase     AcquisitionKind = "purchase"
+	AcquisitionGift         AcquisitionKind = "gift"         // donor's basis and holding period; FMV for losses if lower
+	AcquisitionInheritance  AcquisitionKind = "inheritance"  // FMV at date of death, always long-term
+	AcquisitionTransfer     AcquisitionKind = "transfer"     // moved in without the delivering account's lots
+	AcquisitionCompensation AcquisitionKind = "compensation" // FMV when vested or exercised
+)
+
+func ParseAcquisitionKind(s string) (AcquisitionKind, error) {
+	switch k := AcquisitionKind(s); k {
+	case AcquisitionPurchase, AcquisitionGift, AcquisitionInheritance, AcquisitionTransfer, AcquisitionCompensation:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unsupported acquisition kind: %s (use purchase, gift, inheritance, transfer or compensation)", s)
+	}
+}
+
+// PlanType is the employee stock plan a compensation lot came from.
+type PlanType string
+
+const (
+	PlanRSU  PlanType = "rsu"  // basis is the FMV at vest, all of it already W-2 income
+	PlanESPP PlanType = "espp" // basis is the purchase price; income is decided at sale
+)
+
+// EsppDisposition is whether an ESPP sale met the holding requirements: more
+// than two years from the offering (grant) date and more than one year from
+// the purchase.
+type EsppDisposition string
+
+const (
+	EsppQualifying    EsppDisposition = "qualifying"
+	EsppDisqualifying EsppDisposition = "disqualifying"
+)
+
+// acquisition is how the shares in txn were acquired, as recorded in the
+// acquisitions table or else implied by the transaction type, with the fields
+// a new lot copies.
+type acquisition struct {
+	kind              AcquisitionKind
+	fmvMicros         sql.NullInt64
+	donorAcquiredDate sql.NullString
+	planType          sql.NullString
+	grantDate         sql.NullString
+	grantFmvMicros    sql.NullInt64
+	discountRate      sql.NullFloat64
+}
+
+func acquisitionFor(txn db.ListTransactionsByAccountRow) acquisition {
+	if txn.AcquisitionKind.Valid {
+		return acquisition{
+			kind:              AcquisitionKind(txn.AcquisitionKind.String),
+			fmvMicros:         txn.FmvMicros,
+			donorAcquiredDate: txn.DonorAcquiredDate,
+			planType:          txn.PlanType,
+			grantDate:         txn.GrantDate,
+			grantFmvMicros:    txn.GrantFmvMicros,
+			discountRate:      txn.DiscountRate,
+		}
+	}
+	switch txn.TransactionType {
+	case "security_transfer":
+		return acquisition{kind: AcquisitionTransfer}
+	case "rsu_vest":
+		return acquisition{
+			kind:      AcquisitionCompensation,
+			fmvMicros: sql.NullInt64{Int64: txn.AmountMicros, Valid: true},
+			planType:  sql.NullString{String: string(PlanRSU), Valid: true},
+		}
+	case "espp_purchase":
+		// Without the plan details the income can't be worked out at sale.
+		return acquisition{
+			kind:     AcquisitionCompensation,
+			planType: sql.NullString{String: string(PlanESPP), Valid: true},
+		}
+	}
+	return acquisition{kind: AcquisitionPurchase}
+}
+
+// LongTermDateFor is LongTermDate for a lot acquired as kind. Inherited lots
+// are long-term from the day they're acquired. Gifted lots count the donor's
+// holding period too, which applies whenever the donor's basis does.
+func LongTermDateFor(kind AcquisitionKind, acquiredDate, donorAcquiredDate string) time.Time {
+	switch {
+	case kind == AcquisitionInheritance:
+		return parseDate(acquiredDate)
+	case kind == AcquisitionGift && donorAcquiredDate != "":
+		return LongTermDate(parseDate(donorAcquiredDate))
+	default:
+		return LongTermDate(parseDate(acquiredDate))
+	}
+}
+
+// dispositionBasis returns the basis for selling quantityMicros of lot for
+// proceedsMicros, and the first date the sale counts as long-term.
+//
+// A gift keeps the donor's basis, unless the shares were worth less than that
+// when given (IRS Pub. 551). Then a sale below the FMV at the gift is a loss
+// measured from the FMV and held from the gift date, and a sale between the
+// FMV and the donor's basis has no gain or loss at all.
+func dispositionBasis(lot db.Lot, quantityMicros, proceedsMicros int64) (int64, time.Time) {
+	kind := AcquisitionKind(lot.AcquisitionKind)
+	basis := ProRata(lot.CostBasisMicros, lot.QuantityMicros, quantityMicros)
+	longTerm := LongTermDateFor(kind, lot.AcquiredDate, lot.DonorAcquiredDate.String)
+
+	if kind != AcquisitionGift || !lot.FmvMicros.Valid {
+		return basis, longTerm
+	}
+
+	fmv := ProRata(lot.FmvMicros.Int64, lot.QuantityMicros, quantityMicros)
+	switch {
+	case fmv >= basis || proceedsMicros > basis:
+		return basis, longTerm
+	case proceedsMicros < fmv:
+		return fmv, LongTermDate(parseDate(lot.AcquiredDate))
+	default:
+		return proceedsMicros, longTerm
+	}
+}
+
+// compensationIncome returns the ordinary income included in selling
+// quantityMicros of an employee plan lot on disposed, which the broker's
+// 1099-B basis usually leaves out, and for ESPP lots whether the sale
+// qualified (IRS Pub. 525).
+//
+// An RSU lot's basis is the FMV at vest, all of it income. An ESPP lot's basis
+// is the purchase price, and the income isn't known until the sale: the
+// discount from the purchase date FMV for a disqualifying sale, or the lesser
+// of the gain and the discount on the offering date FMV for a qualifying one.
+// The caller adds ESPP income to basis.
+func compensationIncome(lot db.Lot, quantityMicros, proceedsMicros int64, disposed time.Time) (int64, EsppDisposition) {
+	if AcquisitionKind(lot.AcquisitionKind) != AcquisitionCompensation {
+		return 0, ""
+	}
+
+	basis := ProRata(lot.CostBasisMicros, lot.QuantityMicros, quantityMicros)
+	switch PlanType(lot.PlanType.String) {
+	case PlanRSU:
+		return basis, ""
+	case PlanESPP:
+		fmv := ProRata(lot.FmvMicros.Int64, lot.QuantityMicros, quantityMicros)
+		if !lot.FmvMicros.Valid || fmv <= basis {
+			fmv = basis
+		}
+		if !lot.GrantDate.Valid || !qualifyingEspp(lot.AcquiredDate, lot.GrantDate.String, disposed) {
+			return fmv - basis, EsppDisqualifying
+		}
+		grantFMV := ProRata(lot.GrantFmvMicros.Int64, lot.QuantityMicros, quantityMicros)
+		discount := int64(float64(grantFMV) * lot.DiscountRate.Float64)
+		return max(0, min(proceedsMicros-basis, discount)), EsppQualifying
+	default:
+		return 0, ""
+	}
+}
+
+func qualifyingEspp(purchaseDate, grantDate string, disposed time.Time) bool {
+	return !disposed.Before(LongTermDate(parseDate(purchaseDate))) &&
+		disposed.After(parseDate(grantDate).AddDate(2, 0, 0))
+}
+
+func holdingPeriodFrom(longTerm, disposed time.Time) HoldingPeriod {
+	if disposed.Before(longTerm) {
+		return HoldingPeriodShortTerm
+	}
+	return HoldingPeriodLongTerm
+}
@@ -0,0 +1,217 @@
+package taxlots_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestProcessGiftsAndInheritances(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Brokerage")
+
+	// Each gift is 10 shares with a $1000 donor basis, worth $600 when given.
+	gift := db.UpsertAcquisitionParams{
+		AcquisitionKind:   string(taxlots.AcquisitionGift),
+		FmvMicros:         sql.NullInt64{Int64: 600_000_000, Valid: true},
+		DonorAcquiredDate: sql.NullString{String: "2020-01-01", Valid: true},
+	}
+	inheritance := db.UpsertAcquisitionParams{
+		AcquisitionKind: string(taxlots.AcquisitionInheritance),
+		FmvMicros:       sql.NullInt64{Int64: 900_000_000, Valid: true},
+	}
+
+	tests := []struct {
+		symbol      string
+		acquisition db.UpsertAcquisitionParams
+		acquired    string
+		basis       int64
+		proceeds    int64
+		wantBasis   int64
+		wantGain    int64
+		wantPeriod  taxlots.HoldingPeriod
+		wantKind    string
+	}{
+		// Above the donor's basis: donor's basis and holding period.
+		{"GAIN", gift, "2024-03-01", 1_000_000_000, 1_200_000_000, 1_000_000_000, 200_000_000, taxlots.HoldingPeriodLongTerm, "gift"},
+		// Below the FMV at the gift: a loss from the FMV, held from the gift.
+		{"LOSS", gift, "2024-03-01", 1_000_000_000, 500_000_000, 600_000_000, -100_000_000, taxlots.HoldingPeriodShortTerm, "gift"},
+		// In between: no gain or loss.
+		{"NONE", gift, "2024-03-01", 1_000_000_000, 800_000_000, 800_000_000, 0, taxlots.HoldingPeriodLongTerm, "gift"},
+		// Inherited a month before the sale and still long-term.
+		{"INHR", inheritance, "2024-05-01", 900_000_000, 1_000_000_000, 900_000_000, 100_000_000, taxlots.HoldingPeriodLongTerm, "inheritance"},
+	}
+
+	for _, tt := range tests {
+		sec := createSecurity(t, queries, tt.symbol)
+		createAcquiredTxn(t, queries, acct.ID, sec.ID, "opening_balance", tt.acquired, 10_000_000, tt.basis, tt.acquisition)
+		createTxn(t, queries, acct.ID, sec.ID, "sell", "2024-06-01", 10_000_000, tt.proceeds)
+	}
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	lots, err := queries.ListLotsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, lot := range lots {
+		kinds[lot.Symbol] = lot.AcquisitionKind
+	}
+
+	dispositions, err := queries.ListDispositionsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+	bySymbol := make(map[string]db.ListDispositionsByAccountRow)
+	for _, d := range dispositions {
+		bySymbol[d.Symbol] = d
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			if kinds[tt.symbol] != tt.wantKind {
+				t.Errorf("lot kind = %q, want %q", kinds[tt.symbol], tt.wantKind)
+			}
+			d, ok := bySymbol[tt.symbol]
+			if !ok {
+				t.Fatal("no disposition")
+			}
+			if d.CostBasisMicros != tt.wantBasis {
+				t.Errorf("basis = %d, want %d", d.CostBasisMicros, tt.wantBasis)
+			}
+			if d.RealizedGainMicros != tt.wantGain {
+				t.Errorf("gain = %d, want %d", d.RealizedGainMicros, tt.wantGain)
+			}
+			if d.HoldingPeriod != string(tt.wantPeriod) {
+				t.Errorf("holding period = %s, want %s", d.HoldingPeriod, tt.wantPeriod)
+			}
+		})
+	}
+}
+
+func TestProcessEmployeeStockPlans(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Stock Plan")
+
+	rsu := db.UpsertAcquisitionParams{
+		AcquisitionKind: string(taxlots.AcquisitionCompensation),
+		FmvMicros:       sql.NullInt64{Int64: 1_000_000_000, Valid: true},
+		PlanType:        sql.NullString{String: string(taxlots.PlanRSU), Valid: true},
+	}
+	// 10 shares bought at $85 in a plan with a 15% discount: $90 on the
+	// offering date and $100 on the purchase date.
+	espp := db.UpsertAcquisitionParams{
+		AcquisitionKind: string(taxlots.AcquisitionCompensation),
+		FmvMicros:       sql.NullInt64{Int64: 1_000_000_000, Valid: true},
+		PlanType:        sql.NullString{String: string(taxlots.PlanESPP), Valid: true},
+		GrantDate:       sql.NullString{String: "2023-01-01", Valid: true},
+		GrantFmvMicros:  sql.NullInt64{Int64: 900_000_000, Valid: true},
+		DiscountRate:    sql.NullFloat64{Float64: 0.15, Valid: true},
+	}
+
+	tests := []struct {
+		symbol      string
+		txnType     string
+		acquisition db.UpsertAcquisitionParams
+		acquired    string
+		basis       int64
+		sold        string
+		soldQty     int64
+		proceeds    int64
+		wantBasis   int64
+		wantIncome  int64
+		wantEspp    taxlots.EsppDisposition
+	}{
+		// The whole basis is the FMV at vest, already W-2 income.
+		{"RSU", "rsu_vest", rsu, "2023-03-01", 1_000_000_000, "2024-06-01", 10_000_000, 1_200_000_000, 1_000_000_000, 1_000_000_000, ""},
+		// Sold to cover the taxes the day it vested, for no gain.
+		{"STC", "rsu_vest", rsu, "2024-03-01", 1_000_000_000, "2024-03-01", 3_000_000, 300_000_000, 300_000_000, 300_000_000, ""},
+		// Within two years of the offering: the $15 purchase date discount.
+		{"DISQ", "espp_purchase", espp, "2023-06-30", 850_000_000, "2024-03-01", 10_000_000, 1_200_000_000, 1_000_000_000, 150_000_000, taxlots.EsppDisqualifying},
+		// Qualifying: 15% of the $90 offering price, less than the gain.
+		{"QUAL", "espp_purchase", espp, "2023-06-30", 850_000_000, "2025-02-01", 10_000_000, 1_200_000_000, 985_000_000, 135_000_000, taxlots.EsppQualifying},
+		// Qualifying at a loss: no income.
+		{"QLOSS", "espp_purchase", espp, "2023-06-30", 850_000_000, "2025-02-01", 10_000_000, 800_000_000, 850_000_000, 0, taxlots.EsppQualifying},
+	}
+
+	for _, tt := range tests {
+		sec := createSecurity(t, queries, tt.symbol)
+		createAcquiredTxn(t, queries, acct.ID, sec.ID, tt.txnType, tt.acquired, 10_000_000, tt.basis, tt.acquisition)
+		createTxn(t, queries, acct.ID, sec.ID, "sell", tt.sold, tt.soldQty, tt.proceeds)
+	}
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	dispositions, err := queries.ListDispositionsByYear(ctx, "2024")
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+	later, err := queries.ListDispositionsByYear(ctx, "2025")
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+	bySymbol := make(map[string]db.ListDispositionsByYearRow)
+	for _, d := range append(dispositions, later...) {
+		bySymbol[d.Symbol] = d
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			d, ok := bySymbol[tt.symbol]
+			if !ok {
+				t.Fatal("no disposition")
+			}
+			if d.CostBasisMicros != tt.wantBasis {
+				t.Errorf("basis = %d, want %d", d.CostBasisMicros, tt.wantBasis)
+			}
+			if d.CompensationIncomeMicros != tt.wantIncome {
+				t.Errorf("compensation income = %d, want %d", d.CompensationIncomeMicros, tt.wantIncome)
+			}
+			if d.EsppDisposition.String != string(tt.wantEspp) {
+				t.Errorf("espp disposition = %q, want %q", d.EsppDisposition.String, tt.wantEspp)
+			}
+			if d.RealizedGainMicros != tt.proceeds-tt.wantBasis {
+				t.Errorf("gain = %d, want %d", d.RealizedGainMicros, tt.proceeds-tt.wantBasis)
+			}
+		})
+	}
+}
+
+func createAcquiredTxn(t *testing.T, queries *db.Queries, accountID, securityID, txnType, date string, qty, amount int64, acquisition db.UpsertAcquisitionParams) {
+	t.Helper()
+	ctx := context.Background()
+
+	acquisition.TransactionID = database.NewID(database.PrefixTransaction)
+	err := queries.CreateTransaction(ctx, db.CreateTransactionParams{
+		ID:              acquisition.TransactionID,
+		AccountID:       accountID,
+		SecurityID:      sql.NullString{String: securityID, Valid: true},
+		TransactionType: txnType,
+		TransactionDate: date,
+		QuantityMicros:  sql.NullInt64{Int64: qty, Valid: true},
+		AmountMicros:    amount,
+		FeesMicros:      sql.NullInt64{Int64: 0, Valid: true},
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	if err := queries.UpsertAcquisition(ctx, acquisition); err != nil {
+		t.Fatalf("failed to record acquisition: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package taxlots
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// AdjustmentType is the kind of change a lot adjustment makes to basis.
+type AdjustmentType string
+
+const (
+	// AdjustmentReturnOfCapital reduces basis by a nondividend distribution.
+	// Anything beyond the remaining basis is a capital gain.
+	AdjustmentReturnOfCapital AdjustmentType = "return_of_capital"
+	// AdjustmentBasisCorrection adds a signed amount to the lot's remaining
+	// basis, e.g. a correction on a broker statement.
+	AdjustmentBasisCorrection AdjustmentType = "basis_correction"
+	// AdjustmentBasisOverride replaces the lot's remaining basis.
+	AdjustmentBasisOverride AdjustmentType = "basis_override"
+)
+
+type AdjustLotParams struct {
+	LotID         string
+	Type          AdjustmentType // basis_correction or basis_override
+	EffectiveDate string         // defaults to the date the lot was created
+	AmountMicros  int64
+	Reason        string
+}
+
+// AdjustLot records a basis correction or override against a lot. It takes
+// effect the next time lots are processed, and on every replay after that.
+// Return of capital comes from return_of_capital transactions instead.
+func (p *Processor) AdjustLot(ctx context.Context, params AdjustLotParams) (db.LotAdjustment, error) {
+	if params.Type != AdjustmentBasisCorrection && params.Type != AdjustmentBasisOverride {
+		return db.LotAdjustment{}, fmt.Errorf("unsupported adjustment type: %s", params.Type)
+	}
+	if params.Reason == "" {
+		return db.LotAdjustment{}, fmt.Errorf("a reason is required")
+	}
+	if params.Type == AdjustmentBasisOverride && params.AmountMicros < 0 {
+		return db.LotAdjustment{}, fmt.Errorf("basis can't be negative")
+	}
+
+	lot, err := p.queries.GetLot(ctx, params.LotID)
+	if err != nil {
+		return db.LotAdjustment{}, fmt.Errorf("lot not found: %s", params.LotID)
+	}
+
+	date := params.EffectiveDate
+	if date == "" {
+		txn, err := p.queries.GetTransaction(ctx, lot.TransactionID)
+		if err != nil {
+			return db.LotAdjustment{}, fmt.Errorf("failed to get lot transaction: %w", err)
+		}
+		date = txn.TransactionDate
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return db.LotAdjustment{}, fmt.Errorf("invalid effective date %q: %w", date, err)
+	}
+
+	return p.queries.CreateLotAdjustment(ctx, db.CreateLotAdjustmentParams{
+		ID:             database.NewID(database.PrefixLotAdjustment),
+		AccountID:      lot.AccountID,
+		SecurityID:     lot.SecurityID,
+		LotID:          lot.ID,
+		AdjustmentType: string(params.Type),
+		EffectiveDate:  date,
+		AmountMicros:   params.AmountMicros,
+		Reason:         params.Reason,
+	})
+}
+
+// processReturnOfCapital spreads a nondividend distribution over the open
+// lots by shares held. Each lot's remaining basis goes down by its share; once
+// it reaches zero the rest is a gain, recorded as a disposition of no shares
+// so it is reported with the year's other gains. Short lots are skipped: the
+// short seller owes the payment in lieu, which doesn't touch basis.
+func (p *Processor) processReturnOfCapital(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
+	open, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  txn.AccountID,
+		SecurityID: txn.SecurityID.String,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	var lots []db.Lot
+	var shares int64
+	for _, lot := range open {
+		if isShort(lot) {
+			continue
+		}
+		lots = append(lots, lot)
+		shares += lot.RemainingMicros
+	}
+	if shares == 0 {
+		slog.Warn("return of capital with no open lots",
+			"transaction_id", txn.ID,
+			"symbol", txn.Symbol,
+			"amount", txn.AmountMicros,
+		)
+		return nil
+	}
+
+	reason := txn.Description.String
+	if reason == "" {
+		reason = "Return of capital"
+	}
+
+	for _, lot := range lots {
+		amount := ProRata(txn.AmountMicros, shares, lot.RemainingMicros)
+		remainingBasis := ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros)
+		reduction := min(amount, remainingBasis)
+		gain := amount - reduction
+
+		basisAfter := basisForRemaining(lot, remainingBasis-reduction)
+		if err := p.updateLotBasis(ctx, lot, basisAfter); err != nil {
+			return err
+		}
+
+		_, err := p.queries.CreateLotAdjustment(ctx, db.CreateLotAdjustmentParams{
+			ID:                database.DerivedID(database.PrefixLotAdjustment, txn.ID, lot.ID),
+			AccountID:         lot.AccountID,
+			SecurityID:        lot.SecurityID,
+			LotID:             lot.ID,
+			TransactionID:     sql.NullString{String: txn.ID, Valid: true},
+			AdjustmentType:    string(AdjustmentReturnOfCapital),
+			EffectiveDate:     txn.TransactionDate,
+			AmountMicros:      amount,
+			BasisBeforeMicros: lot.CostBasisMicros,
+			BasisAfterMicros:  basisAfter,
+			GainMicros:        gain,
+			Reason:            reason,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record lot adjustment: %w", err)
+		}
+
+		if gain > 0 {
+			longTerm := LongTermDateFor(AcquisitionKind(lot.AcquisitionKind), lot.AcquiredDate, lot.DonorAcquiredDate.String)
+			_, err = p.queries.CreateLotDisposition(ctx, db.CreateLotDispositionParams{
+				ID:                 database.DerivedID(database.PrefixLotDisposition, txn.ID, lot.ID),
+				LotID:              lot.ID,
+				SellTransactionID:  txn.ID,
+				DisposedDate:       txn.TransactionDate,
+				ProceedsMicros:     gain,
+				RealizedGainMicros: gain,
+				HoldingPeriod:      string(holdingPeriodFrom(longTerm, parseDate(txn.TransactionDate))),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create disposition: %w", err)
+			}
+
+			slog.Info("return of capital exceeds basis",
+				"lot_id", lot.ID,
+				"symbol", txn.Symbol,
+				"gain", gain,
+			)
+		}
+	}
+
+	return nil
+}
+
+// processAdjustment applies an entered basis correction or override.
+func (p *Processor) processAdjustment(ctx context.Context, adj db.LotAdjustment) error {
+	lot, err := p.queries.GetLot(ctx, adj.LotID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && lot.RemainingMicros == 0) {
+		slog.Warn("lot adjustment has no open lot to apply to",
+			"adjustment_id", adj.ID,
+			"lot_id", adj.LotID,
+			"effective_date", adj.EffectiveDate,
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lot: %w", err)
+	}
+
+	remainingBasis := ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros)
+	switch AdjustmentType(adj.AdjustmentType) {
+	case AdjustmentBasisCorrection:
+		remainingBasis = max(remainingBasis+adj.AmountMicros, 0)
+	case AdjustmentBasisOverride:
+		remainingBasis = adj.AmountMicros
+	default:
+		return fmt.Errorf("unsupported adjustment type: %s", adj.AdjustmentType)
+	}
+
+	basisAfter := basisForRemaining(lot, remainingBasis)
+	if err := p.updateLotBasis(ctx, lot, basisAfter); err != nil {
+		return err
+	}
+
+	err = p.queries.UpdateLotAdjustmentApplied(ctx, db.UpdateLotAdjustmentAppliedParams{
+		ID:                adj.ID,
+		BasisBeforeMicros: lot.CostBasisMicros,
+		BasisAfterMicros:  basisAfter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record lot adjustment: %w", err)
+	}
+
+	slog.Debug("adjusted lot basis",
+		"lot_id", lot.ID,
+		"type", adj.AdjustmentType,
+		"basis_before", lot.CostBasisMicros,
+		"basis_after", basisAfter,
+	)
+
+	return nil
+}
+
+func (p *Processor) updateLotBasis(ctx context.Context, lot db.Lot, costBasisMicros int64) error {
+	err := p.queries.UpdateLotBasis(ctx, db.UpdateLotBasisParams{
+		ID:              lot.ID,
+		CostBasisMicros: costBasisMicros,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update lot basis: %w", err)
+	}
+	return nil
+}
+
+// basisForRemaining returns the lot cost basis that gives the lot's remaining
+// shares the given basis. Lot basis covers the original quantity and is
+// prorated to whatever is left.
+func basisForRemaining(lot db.Lot, remainingBasisMicros int64) int64 {
+	return ProRata(remainingBasisMicros, lot.RemainingMicros, lot.QuantityMicros)
+}
@@ -0,0 +1,127 @@
+package taxlots_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestLotAdjustments(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Brokerage")
+	sec := createSecurity(t, queries, "EPD")
+
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2022-01-10", 10_000_000, 1_000_000_000)
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2023-06-01", 10_000_000, 200_000_000)
+	createTxn(t, queries, acct.ID, sec.ID, "return_of_capital", "2024-01-15", 0, 600_000_000) // $300 per lot
+	createTxn(t, queries, acct.ID, sec.ID, "sell", "2024-06-01", 20_000_000, 2_000_000_000)
+
+	processor := taxlots.NewProcessor(queries)
+	if _, err := processor.ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	// $300 takes the first lot from $1000 to $700; the second lot only has
+	// $200 of basis, so $100 is a gain.
+	assertGains(t, queries, acct.ID, map[string]int64{
+		"2024-01-15 2023-06-01": 100_000_000,
+		"2024-06-01 2022-01-10": 300_000_000,
+		"2024-06-01 2023-06-01": 1_000_000_000,
+	})
+
+	lots, err := queries.ListLotsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	first, second := lots[0], lots[1]
+
+	_, err = processor.AdjustLot(ctx, taxlots.AdjustLotParams{
+		LotID:        first.ID,
+		Type:         taxlots.AdjustmentBasisCorrection,
+		AmountMicros: 50_000_000,
+		Reason:       "Broker corrected commission",
+	})
+	if err != nil {
+		t.Fatalf("failed to adjust lot: %v", err)
+	}
+	_, err = processor.AdjustLot(ctx, taxlots.AdjustLotParams{
+		LotID:        second.ID,
+		Type:         taxlots.AdjustmentBasisOverride,
+		AmountMicros: 500_000_000,
+		Reason:       "Basis per trade confirmation",
+	})
+	if err != nil {
+		t.Fatalf("failed to adjust lot: %v", err)
+	}
+	if _, err := processor.AdjustLot(ctx, taxlots.AdjustLotParams{LotID: first.ID, Type: taxlots.AdjustmentBasisOverride}); err == nil {
+		t.Error("expected an adjustment without a reason to be rejected")
+	}
+
+	result, err := processor.ProcessTransactions(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	if len(result.Replays) != 1 || result.Replays[0].FromDate != "2022-01-10" {
+		t.Errorf("expected a replay from the first lot's date, got %+v", result.Replays)
+	}
+
+	// Adjustments apply from the lots' dates, before the return of capital:
+	// $1050 - $300 = $750 and $500 - $300 = $200, so nothing exceeds basis.
+	assertGains(t, queries, acct.ID, map[string]int64{
+		"2024-06-01 2022-01-10": 250_000_000,
+		"2024-06-01 2023-06-01": 800_000_000,
+	})
+	incremental := lotSnapshot(t, queries, acct.ID)
+
+	adjustments, err := queries.ListLotAdjustmentsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list adjustments: %v", err)
+	}
+	if len(adjustments) != 4 {
+		t.Fatalf("expected 2 entered and 2 return of capital adjustments, got %d", len(adjustments))
+	}
+	for _, a := range adjustments {
+		if a.AdjustmentType == string(taxlots.AdjustmentBasisOverride) && a.BasisAfterMicros != 500_000_000 {
+			t.Errorf("expected override to record basis after 500000000, got %d", a.BasisAfterMicros)
+		}
+	}
+
+	// Replaying everything applies each adjustment once.
+	if _, err := processor.RebuildTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to rebuild lots: %v", err)
+	}
+	assertSnapshot(t, "incremental vs full rebuild", lotSnapshot(t, queries, acct.ID), incremental)
+	assertGains(t, queries, acct.ID, map[string]int64{
+		"2024-06-01 2022-01-10": 250_000_000,
+		"2024-06-01 2023-06-01": 800_000_000,
+	})
+}
+
+// assertGains checks realized gains keyed by "disposed acquired".
+func assertGains(t *testing.T, queries *db.Queries, accountID string, want map[string]int64) {
+	t.Helper()
+
+	dispositions, err := queries.ListDispositionsByAccount(context.Background(), accountID)
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+
+	got := make(map[string]int64)
+	for _, d := range dispositions {
+		got[d.DisposedDate+" "+d.AcquiredDate] += d.RealizedGainMicros
+	}
+	if len(got) != len(want) {
+		t.Errorf("got dispositions %v, want %v", got, want)
+		return
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("gain for %s = %d, want %d", k, got[k], v)
+		}
+	}
+}
@@ -60,7 +60,7 @@ func (a *Analyzer) Analyze(ctx context.Context, accountID string) (*AnalysisResu
 		return nil, err
 	}
 
-	sorted := sortByDateAsc(txns)
+	sorted := sortForReplay(txns)
 
 	lots := make(map[string][]simulatedLot)
 	unmatched := make(map[string]int64)
@@ -75,7 +75,7 @@ func (a *Analyzer) Analyze(ctx context.Context, accountID string) (*AnalysisResu
 		txnDate := parseDate(txn.TransactionDate)
 
 		switch txn.TransactionType {
-		case "buy", "security_transfer", "opening_balance":
+		case "buy", "security_transfer", "opening_balance", "rsu_vest", "espp_purchase":
 			if txn.QuantityMicros.Valid && txn.QuantityMicros.Int64 > 0 {
 				lots[secID] = append(lots[secID], simulatedLot{
 					quantityMicros:  txn.QuantityMicros.Int64,
@@ -83,7 +83,7 @@ func (a *Analyzer) Analyze(ctx context.Context, accountID string) (*AnalysisResu
 					acquiredDate:    txnDate,
 				})
 			}
-		case "sell":
+		case "sell", "redemption":
 			if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
 				continue
 			}
@@ -0,0 +1,202 @@
+package taxlots
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// AccruedInterest returns the interest a bond buyer paid the seller on top
+// of the price: the amount less face value × price / 100. Broker amounts
+// include it; lot basis and sale proceeds don't, since it is interest (IRS
+// Pub. 550). Anything outside one coupon's worth is taken as bad data and
+// ignored.
+func AccruedInterest(txn db.ListTransactionsByAccountRow, bond db.ListBondsRow) int64 {
+	if !txn.PriceMicros.Valid || txn.PriceMicros.Int64 <= 0 || !txn.QuantityMicros.Valid {
+		return 0
+	}
+
+	face := FaceValue(txn.QuantityMicros.Int64, bond)
+	principal := ProRata(txn.PriceMicros.Int64, 100_000_000, face)
+
+	fees := int64(0)
+	if txn.FeesInAmount {
+		fees = txn.FeesMicros.Int64
+	}
+	var accrued int64
+	switch txn.TransactionType {
+	case "sell":
+		accrued = txn.AmountMicros + fees - principal
+	default:
+		accrued = txn.AmountMicros - fees - principal
+	}
+
+	if accrued < 0 || accrued > couponPayment(face, bond) {
+		return 0
+	}
+	return accrued
+}
+
+// AmortizedBasis returns the basis of quantityMicros of a bond bought for
+// basisMicros on acquired, as of date, with its premium amortized by constant
+// yield (IRC 171; required for tax-exempt bonds). The premium is amortized to
+// maturity, or to the call date if that gives a lower yield, so a bond
+// redeemed at par or at its call price has no gain or loss. Bonds bought at
+// or below par keep their basis.
+func AmortizedBasis(basisMicros, quantityMicros int64, bond db.ListBondsRow, acquired, date time.Time) int64 {
+	if bond.DatedDate.Valid && acquired.Before(parseDate(bond.DatedDate.String)) {
+		// Bought when issued; nothing accrues before the dated date.
+		acquired = parseDate(bond.DatedDate.String)
+	}
+	if !date.After(acquired) {
+		return basisMicros
+	}
+
+	face := FaceValue(quantityMicros, bond)
+	if basisMicros <= face {
+		return basisMicros
+	}
+
+	maturity := parseDate(bond.MaturityDate)
+	target, redemption := maturity, face
+	yield := premiumYield(float64(basisMicros), face, bond, acquired, maturity, float64(face))
+
+	if bond.CallDate.Valid && bond.CallPriceMicros.Valid {
+		callDate := parseDate(bond.CallDate.String)
+		callPrice := ProRata(bond.CallPriceMicros.Int64, 100_000_000, face)
+		if callDate.After(acquired) && callDate.Before(maturity) && basisMicros > callPrice {
+			if y := premiumYield(float64(basisMicros), face, bond, acquired, callDate, float64(callPrice)); y < yield {
+				target, redemption, yield = callDate, callPrice, y
+			}
+		}
+	}
+
+	if !date.Before(target) {
+		if date.Equal(target) || target.Equal(maturity) {
+			return redemption
+		}
+		// Not called: carry on from the call price to maturity.
+		uncalled := bond
+		uncalled.CallDate = sql.NullString{}
+		return AmortizedBasis(redemption, quantityMicros, uncalled, target, date)
+	}
+
+	return int64(math.Round(carryingValue(float64(basisMicros), face, bond, acquired, date, yield)))
+}
+
+// amortizeLots returns lots with each long lot's basis amortized to date.
+// Stored lots keep the basis they were bought at.
+func amortizeLots(lots []db.Lot, bond db.ListBondsRow, date time.Time) []db.Lot {
+	amortized := make([]db.Lot, len(lots))
+	for i, lot := range lots {
+		if !isShort(lot) {
+			lot.CostBasisMicros = AmortizedBasis(lot.CostBasisMicros, lot.QuantityMicros, bond, parseDate(lot.AcquiredDate), date)
+		}
+		amortized[i] = lot
+	}
+	return amortized
+}
+
+// premiumYield solves for the yield per coupon period at which basis
+// amortizes down to redemption on target.
+func premiumYield(basis float64, face int64, bond db.ListBondsRow, acquired, target time.Time, redemption float64) float64 {
+	lo, hi := -0.5, 1.0
+	for range 100 {
+		mid := (lo + hi) / 2
+		if carryingValue(basis, face, bond, acquired, target, mid) < redemption {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// carryingValue walks the accrual periods from acquired to date. Each period
+// earns yield on the carrying value; the coupon paid beyond that is premium
+// returned, which comes off the basis. Part periods accrue ratably.
+func carryingValue(basis float64, face int64, bond db.ListBondsRow, acquired, date time.Time, yield float64) float64 {
+	coupon := float64(couponPayment(face, bond))
+	periodDays := 360 / float64(periodsPerYear(bond))
+
+	value := basis
+	start := acquired
+	for _, end := range couponDates(bond, acquired, date) {
+		frac := float64(days360(start, end)) / periodDays
+		value -= coupon*frac - value*yield*frac
+		start = end
+	}
+	return value
+}
+
+// couponDates returns the coupon dates after from and up to to, followed by
+// to itself if it isn't one.
+func couponDates(bond db.ListBondsRow, from, to time.Time) []time.Time {
+	dates := CouponSchedule(bond, from, to)
+	if len(dates) == 0 || !dates[len(dates)-1].Equal(to) {
+		dates = append(dates, to)
+	}
+	return dates
+}
+
+// CouponSchedule returns the bond's coupon dates after from and up to to,
+// earliest first. Coupons fall on the maturity date's day, every period back
+// from it, and none before the first coupon date.
+func CouponSchedule(bond db.ListBondsRow, from, to time.Time) []time.Time {
+	months := 12 / int(periodsPerYear(bond))
+	maturity := parseDate(bond.MaturityDate)
+
+	var dates []time.Time
+	for i := 0; ; i++ {
+		d := maturity.AddDate(0, -months*i, 0)
+		if !d.After(from) {
+			break
+		}
+		if bond.FirstCouponDate.Valid && d.Before(parseDate(bond.FirstCouponDate.String)) {
+			break
+		}
+		if !d.After(to) {
+			dates = append(dates, d)
+		}
+	}
+	for i, j := 0, len(dates)-1; i < j; i, j = i+1, j-1 {
+		dates[i], dates[j] = dates[j], dates[i]
+	}
+	return dates
+}
+
+// days360 counts days on the 30/360 basis municipal bonds accrue on.
+func days360(from, to time.Time) int {
+	d1, d2 := from.Day(), to.Day()
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 && d1 == 30 {
+		d2 = 30
+	}
+	return (to.Year()-from.Year())*360 + (int(to.Month())-int(from.Month()))*30 + d2 - d1
+}
+
+// FaceValue is the par amount of quantityMicros of the bond, repaid at
+// maturity.
+func FaceValue(quantityMicros int64, bond db.ListBondsRow) int64 {
+	return ProRata(bond.ParMicros, 1_000_000, quantityMicros)
+}
+
+// CouponMicros is one coupon payment on quantityMicros of the bond.
+func CouponMicros(quantityMicros int64, bond db.ListBondsRow) int64 {
+	return couponPayment(FaceValue(quantityMicros, bond), bond)
+}
+
+func couponPayment(face int64, bond db.ListBondsRow) int64 {
+	return int64(float64(face) * bond.CouponRate / float64(periodsPerYear(bond)))
+}
+
+func periodsPerYear(bond db.ListBondsRow) int64 {
+	if bond.PaymentsPerYear <= 0 {
+		return 2
+	}
+	return bond.PaymentsPerYear
+}
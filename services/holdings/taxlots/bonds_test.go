@@ -0,0 +1,148 @@
+package taxlots_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestAmortizedBasis(t *testing.T) {
+	bond := db.ListBondsRow{
+		CouponRate:      0.05,
+		MaturityDate:    "2030-07-01",
+		ParMicros:       1_000_000,
+		PaymentsPerYear: 2,
+	}
+	callable := bond
+	callable.CallDate = sql.NullString{String: "2027-07-01", Valid: true}
+	callable.CallPriceMicros = sql.NullInt64{Int64: 100_000_000, Valid: true}
+
+	const face = 10_000_000_000 // $10,000
+
+	tests := []struct {
+		name  string
+		bond  db.ListBondsRow
+		basis int64
+		date  string
+		want  int64 // exact, or 0 to only check it lies between par and basis
+	}{
+		{name: "on purchase", bond: bond, basis: 10_500_000_000, date: "2025-07-01", want: 10_500_000_000},
+		{name: "at maturity", bond: bond, basis: 10_500_000_000, date: "2030-07-01", want: face},
+		{name: "after maturity", bond: bond, basis: 10_500_000_000, date: "2031-01-01", want: face},
+		{name: "part way", bond: bond, basis: 10_500_000_000, date: "2028-03-15"},
+		{name: "discount not amortized", bond: bond, basis: 9_800_000_000, date: "2028-07-01", want: 9_800_000_000},
+		{name: "par call gives lower yield", bond: callable, basis: 10_500_000_000, date: "2027-07-01", want: face},
+		{name: "past an uncalled par call", bond: callable, basis: 10_500_000_000, date: "2029-01-01", want: face},
+	}
+
+	acquired := mustDate(t, "2025-07-01")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := taxlots.AmortizedBasis(tt.basis, face, tt.bond, acquired, mustDate(t, tt.date))
+			if tt.want == 0 {
+				if got <= face || got >= tt.basis {
+					t.Errorf("AmortizedBasis = %d, want between %d and %d", got, int64(face), tt.basis)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("AmortizedBasis = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Constant yield amortizes less early on, when the carrying value is
+	// higher, than later.
+	first := 10_500_000_000 - taxlots.AmortizedBasis(10_500_000_000, face, bond, acquired, mustDate(t, "2026-07-01"))
+	last := taxlots.AmortizedBasis(10_500_000_000, face, bond, acquired, mustDate(t, "2029-07-01")) - face
+	if first >= last {
+		t.Errorf("first year amortization %d, want less than last year's %d", first, last)
+	}
+}
+
+func TestProcessBonds(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account := createAccount(t, queries, "Munis")
+	sec := createSecurity(t, queries, "13063EBK1")
+	err := queries.UpsertBond(ctx, db.UpsertBondParams{
+		SecurityID:      sec.ID,
+		CouponRate:      0.05,
+		MaturityDate:    "2027-01-01",
+		ParMicros:       1_000_000,
+		PaymentsPerYear: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert bond: %v", err)
+	}
+
+	// $20,000 face at 104 two months into a coupon period: $20,800 plus
+	// $166.67 accrued interest.
+	createBondTxn(t, queries, account.ID, sec.ID, "buy", "2025-03-01", 20_000_000_000, 104_000_000, 20_966_670_000)
+	// Half sold at 103 with two months' accrued interest.
+	createBondTxn(t, queries, account.ID, sec.ID, "sell", "2026-03-01", 10_000_000_000, 103_000_000, 10_383_330_000)
+	// The rest redeemed at par.
+	createBondTxn(t, queries, account.ID, sec.ID, "redemption", "2027-01-01", 10_000_000_000, 0, 10_000_000_000)
+
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, account.ID); err != nil {
+		t.Fatalf("ProcessTransactions: %v", err)
+	}
+
+	lots, err := queries.ListLotsByAccount(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	if len(lots) != 1 || lots[0].CostBasisMicros != 20_800_000_000 {
+		t.Fatalf("got lots %+v, want one with basis 20,800 excluding accrued interest", lots)
+	}
+
+	dispositions, err := queries.ListDispositionsByAccount(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+	if len(dispositions) != 2 {
+		t.Fatalf("got %d dispositions, want 2", len(dispositions))
+	}
+
+	bond, err := queries.ListBonds(ctx)
+	if err != nil {
+		t.Fatalf("failed to list bonds: %v", err)
+	}
+	sold := dispositions[0]
+	wantBasis := taxlots.AmortizedBasis(10_400_000_000, 10_000_000_000, bond[0], mustDate(t, "2025-03-01"), mustDate(t, "2026-03-01"))
+	if sold.ProceedsMicros != 10_300_000_000 {
+		t.Errorf("sale proceeds = %d, want 10,300 excluding accrued interest", sold.ProceedsMicros)
+	}
+	if sold.CostBasisMicros != wantBasis || wantBasis >= 10_400_000_000 {
+		t.Errorf("sale basis = %d, want amortized %d", sold.CostBasisMicros, wantBasis)
+	}
+
+	redeemed := dispositions[1]
+	if redeemed.CostBasisMicros != 10_000_000_000 || redeemed.RealizedGainMicros != 0 {
+		t.Errorf("redemption basis %d gain %d, want par and no gain", redeemed.CostBasisMicros, redeemed.RealizedGainMicros)
+	}
+}
+
+func createBondTxn(t *testing.T, queries *db.Queries, accountID, securityID, txnType, date string, qty, price, amount int64) {
+	t.Helper()
+	err := queries.CreateTransaction(context.Background(), db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       accountID,
+		SecurityID:      sql.NullString{String: securityID, Valid: true},
+		TransactionType: txnType,
+		TransactionDate: date,
+		QuantityMicros:  sql.NullInt64{Int64: qty, Valid: true},
+		PriceMicros:     sql.NullInt64{Int64: price, Valid: price > 0},
+		AmountMicros:    amount,
+		FeesInAmount:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+}
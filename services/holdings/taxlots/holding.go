@@ -0,0 +1,50 @@
+package taxlots
+
+import "time"
+
+type HoldingPeriod string
+
+const (
+	HoldingPeriodShortTerm HoldingPeriod = "short_term"
+	HoldingPeriodLongTerm  HoldingPeriod = "long_term"
+)
+
+// LongTermDate returns the first date a sale of a lot acquired on acquired
+// counts as long-term.
+//
+// The IRS rule is "more than one year", counted from the day after
+// acquisition, so the first long-term day is the day after the one-year
+// anniversary. Anniversaries are calendar based: a lot bought on Feb 29 has
+// its anniversary on Feb 28 of the following (non-leap) year, not Mar 1 as
+// time.AddDate would normalize it.
+//
+//	acquired 2023-03-15 -> anniversary 2024-03-15 -> long-term from 2024-03-16
+//	acquired 2024-02-29 -> anniversary 2025-02-28 -> long-term from 2025-03-01
+func LongTermDate(acquired time.Time) time.Time {
+	year, month, day := acquired.Date()
+	anniversary := time.Date(year+1, month, 1, 0, 0, 0, 0, acquired.Location())
+	anniversary = anniversary.AddDate(0, 0, min(day, daysIn(anniversary))-1)
+	return anniversary.AddDate(0, 0, 1)
+}
+
+// HoldingPeriodFor classifies a disposal on disposed of a lot acquired on acquired.
+func HoldingPeriodFor(acquired, disposed time.Time) HoldingPeriod {
+	if disposed.Before(LongTermDate(acquired)) {
+		return HoldingPeriodShortTerm
+	}
+	return HoldingPeriodLongTerm
+}
+
+// DaysUntilLongTerm returns how many days after asOf a lot turns long-term,
+// or 0 if it already is.
+func DaysUntilLongTerm(acquired, asOf time.Time) int {
+	ltDate := LongTermDate(acquired)
+	if !asOf.Before(ltDate) {
+		return 0
+	}
+	return int(ltDate.Sub(asOf).Hours() / 24)
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
@@ -0,0 +1,83 @@
+package taxlots_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestLongTermDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		acquired string
+		want     string
+	}{
+		{name: "ordinary date", acquired: "2023-03-15", want: "2024-03-16"},
+		{name: "year spans leap day", acquired: "2023-06-01", want: "2024-06-02"},
+		{name: "acquired on leap day", acquired: "2024-02-29", want: "2025-03-01"},
+		{name: "acquired day before leap day", acquired: "2024-02-28", want: "2025-03-01"},
+		{name: "acquired after leap day", acquired: "2024-03-01", want: "2025-03-02"},
+		{name: "anniversary on leap day", acquired: "2023-02-28", want: "2024-02-29"},
+		{name: "year end", acquired: "2024-12-31", want: "2026-01-01"},
+		{name: "month end", acquired: "2023-01-31", want: "2024-02-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := taxlots.LongTermDate(mustDate(t, tt.acquired)).Format("2006-01-02")
+			if got != tt.want {
+				t.Errorf("LongTermDate(%s) = %s, want %s", tt.acquired, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoldingPeriodFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		acquired string
+		disposed string
+		want     taxlots.HoldingPeriod
+	}{
+		{name: "same day", acquired: "2024-01-10", disposed: "2024-01-10", want: taxlots.HoldingPeriodShortTerm},
+		{name: "on anniversary", acquired: "2023-01-10", disposed: "2024-01-10", want: taxlots.HoldingPeriodShortTerm},
+		{name: "day after anniversary", acquired: "2023-01-10", disposed: "2024-01-11", want: taxlots.HoldingPeriodLongTerm},
+		// 366 days across Feb 29, 2024 is still only the anniversary.
+		{name: "366 days across leap day", acquired: "2023-03-01", disposed: "2024-03-01", want: taxlots.HoldingPeriodShortTerm},
+		{name: "day after leap year anniversary", acquired: "2023-03-01", disposed: "2024-03-02", want: taxlots.HoldingPeriodLongTerm},
+		{name: "leap day lot on Feb 28", acquired: "2024-02-29", disposed: "2025-02-28", want: taxlots.HoldingPeriodShortTerm},
+		{name: "leap day lot on Mar 1", acquired: "2024-02-29", disposed: "2025-03-01", want: taxlots.HoldingPeriodLongTerm},
+		// 365 days into a non-leap year is the anniversary, not long-term.
+		{name: "365 days", acquired: "2024-03-01", disposed: "2025-03-01", want: taxlots.HoldingPeriodShortTerm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := taxlots.HoldingPeriodFor(mustDate(t, tt.acquired), mustDate(t, tt.disposed))
+			if got != tt.want {
+				t.Errorf("HoldingPeriodFor(%s, %s) = %s, want %s", tt.acquired, tt.disposed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysUntilLongTerm(t *testing.T) {
+	acquired := mustDate(t, "2024-02-29")
+
+	if got := taxlots.DaysUntilLongTerm(acquired, mustDate(t, "2025-02-27")); got != 2 {
+		t.Errorf("expected 2 days, got %d", got)
+	}
+	if got := taxlots.DaysUntilLongTerm(acquired, mustDate(t, "2025-03-01")); got != 0 {
+		t.Errorf("expected 0 days once long-term, got %d", got)
+	}
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
@@ -0,0 +1,121 @@
+package taxlots
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// Method chooses which lots a sale relieves first.
+type Method string
+
+const (
+	MethodFIFO Method = "fifo" // oldest first; the IRS default and what lots process uses
+	MethodLIFO Method = "lifo" // newest first
+	MethodHIFO Method = "hifo" // highest cost per share first, minimizing gain
+)
+
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(s); m {
+	case MethodFIFO, MethodLIFO, MethodHIFO:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported lot method: %s (use fifo, lifo or hifo)", s)
+	}
+}
+
+// Sale is a sell to match against lots. Proceeds are net of fees.
+type Sale struct {
+	Date           time.Time
+	QuantityMicros int64
+	ProceedsMicros int64
+	FeesMicros     int64
+}
+
+// Relief is the part of one lot a sale relieves.
+type Relief struct {
+	Lot             db.Lot
+	QuantityMicros  int64
+	CostBasisMicros int64
+	ProceedsMicros  int64
+	FeesMicros      int64
+	GainMicros      int64
+	HoldingPeriod   HoldingPeriod
+	RemainingMicros int64 // left in the lot afterwards
+
+	CompensationIncomeMicros int64           // included in CostBasisMicros; see compensationIncome
+	EsppDisposition          EsppDisposition // set for ESPP lots
+}
+
+// MatchSale relieves lots for a sale in the order the method picks, splitting
+// proceeds and fees pro rata by quantity. Basis follows each lot's acquisition
+// rules (see dispositionBasis and compensationIncome). It doesn't touch the
+// database; the returned unmatched quantity is what the lots couldn't cover.
+func MatchSale(lots []db.Lot, method Method, sale Sale) ([]Relief, int64) {
+	var reliefs []Relief
+	remainingToSell := sale.QuantityMicros
+
+	for _, lot := range OrderLots(lots, method) {
+		if remainingToSell <= 0 {
+			break
+		}
+		if lot.RemainingMicros <= 0 || isShort(lot) {
+			continue
+		}
+
+		sellFromLot := min(remainingToSell, lot.RemainingMicros)
+		proceeds := ProRata(sale.ProceedsMicros, sale.QuantityMicros, sellFromLot)
+		costBasis, longTerm := dispositionBasis(lot, sellFromLot, proceeds)
+		income, espp := compensationIncome(lot, sellFromLot, proceeds, sale.Date)
+		if espp != "" {
+			costBasis += income
+		}
+
+		reliefs = append(reliefs, Relief{
+			Lot:             lot,
+			QuantityMicros:  sellFromLot,
+			CostBasisMicros: costBasis,
+			ProceedsMicros:  proceeds,
+			FeesMicros:      ProRata(sale.FeesMicros, sale.QuantityMicros, sellFromLot),
+			GainMicros:      proceeds - costBasis,
+			HoldingPeriod:   holdingPeriodFrom(longTerm, sale.Date),
+			RemainingMicros: lot.RemainingMicros - sellFromLot,
+
+			CompensationIncomeMicros: income,
+			EsppDisposition:          espp,
+		})
+
+		remainingToSell -= sellFromLot
+	}
+
+	return reliefs, remainingToSell
+}
+
+// OrderLots returns lots in the order a method relieves them. Ties fall back
+// to acquisition date so results are stable.
+func OrderLots(lots []db.Lot, method Method) []db.Lot {
+	ordered := make([]db.Lot, len(lots))
+	copy(ordered, lots)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		switch method {
+		case MethodLIFO:
+			return a.AcquiredDate > b.AcquiredDate
+		case MethodHIFO:
+			// Compare cost per share without dividing: a.cost/a.qty > b.cost/b.qty.
+			ca := float64(a.CostBasisMicros) * float64(b.QuantityMicros)
+			cb := float64(b.CostBasisMicros) * float64(a.QuantityMicros)
+			if ca != cb {
+				return ca > cb
+			}
+			return a.AcquiredDate < b.AcquiredDate
+		default:
+			return a.AcquiredDate < b.AcquiredDate
+		}
+	})
+
+	return ordered
+}
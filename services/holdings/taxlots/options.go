@@ -0,0 +1,287 @@
+package taxlots
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// OptionMatch pairs an option exercise or assignment with the trade in the
+// underlying it settled into: the shares bought or sold at the strike, booked
+// by the broker the same day.
+type OptionMatch struct {
+	Event  db.ListTransactionsByAccountRow // option_exercise or option_assignment
+	Leg    db.ListTransactionsByAccountRow // buy or sell of the underlying
+	Option db.ListOptionsRow
+}
+
+// legType is the underlying trade an exercise or assignment settles into: a
+// call holder buys and a call writer sells; puts the other way round.
+func (m OptionMatch) legType() string {
+	call := m.Option.OptionType == "call"
+	if (m.Event.TransactionType == "option_exercise") == call {
+		return "buy"
+	}
+	return "sell"
+}
+
+// MatchOptionEvents pairs each exercise and assignment with a same-day buy or
+// sell of the underlying in the same account for the contracts' shares.
+// Events without one (cash-settled options, or a leg that wasn't imported)
+// are left out and close like an expiration.
+func MatchOptionEvents(txns []db.ListTransactionsByAccountRow, options []db.ListOptionsRow) []OptionMatch {
+	bySecurity := make(map[string]db.ListOptionsRow)
+	for _, o := range options {
+		bySecurity[o.SecurityID] = o
+	}
+
+	used := make(map[string]bool)
+	var matches []OptionMatch
+	for _, event := range txns {
+		if event.TransactionType != "option_exercise" && event.TransactionType != "option_assignment" {
+			continue
+		}
+		option, ok := bySecurity[event.SecurityID.String]
+		if !ok || !option.UnderlyingSecurityID.Valid {
+			continue
+		}
+
+		m := OptionMatch{Event: event, Option: option}
+		shares := event.QuantityMicros.Int64 * option.Multiplier
+		for _, leg := range txns {
+			if used[leg.ID] || leg.AccountID != event.AccountID || leg.SecurityID.String != option.UnderlyingSecurityID.String {
+				continue
+			}
+			if leg.TransactionDate != event.TransactionDate || leg.TransactionType != m.legType() || leg.QuantityMicros.Int64 != shares {
+				continue
+			}
+			used[leg.ID] = true
+			m.Leg = leg
+			matches = append(matches, m)
+			break
+		}
+	}
+
+	return matches
+}
+
+// processExpiration closes contracts that expired worthless: short lots keep
+// their whole premium as a gain, long lots lose what they cost.
+func (p *Processor) processExpiration(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
+	if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
+		return nil
+	}
+
+	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  txn.AccountID,
+		SecurityID: txn.SecurityID.String,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	var long int64
+	for _, lot := range lots {
+		if !isShort(lot) {
+			long += lot.RemainingMicros
+		}
+	}
+	if long > 0 {
+		return p.sell(ctx, txn, 0)
+	}
+	_, err = p.closeShortLots(ctx, txn, 0, 0)
+	return err
+}
+
+// processOptionEvent settles an exercise or assignment. The option lots close
+// without a gain or loss of their own; the premium moves into the underlying
+// trade instead (IRS Pub. 550): added to the basis of shares a call holder
+// buys, taken off the proceeds of shares a put holder sells, and the other way
+// round for the premium a writer received.
+func (p *Processor) processOptionEvent(ctx context.Context, m OptionMatch) error {
+	event, leg := m.Event, m.Leg
+
+	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  event.AccountID,
+		SecurityID: event.SecurityID.String,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	side := PositionLong
+	if event.TransactionType == "option_assignment" {
+		side = PositionShort
+	}
+
+	type relief struct {
+		lot      db.Lot
+		quantity int64
+	}
+	var reliefs []relief
+	var premium int64
+	remainingToClose := event.QuantityMicros.Int64
+	for _, lot := range OrderLots(lots, MethodFIFO) {
+		if remainingToClose <= 0 {
+			break
+		}
+		if isShort(lot) != (side == PositionShort) || lot.RemainingMicros <= 0 {
+			continue
+		}
+		closeFromLot := min(remainingToClose, lot.RemainingMicros)
+		premium += ProRata(lot.CostBasisMicros, lot.QuantityMicros, closeFromLot)
+		reliefs = append(reliefs, relief{lot: lot, quantity: closeFromLot})
+		remainingToClose -= closeFromLot
+	}
+
+	if side == PositionShort {
+		premium = -premium // received, not paid
+	}
+
+	var destination sql.NullString
+	switch leg.TransactionType {
+	case "buy":
+		lot, err := p.buy(ctx, leg, leg.QuantityMicros.Int64, purchaseCost(leg)+premium)
+		if err != nil {
+			return err
+		}
+		destination = sql.NullString{String: lot.ID, Valid: true}
+	case "sell":
+		if err := p.sell(ctx, leg, -premium); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range reliefs {
+		_, err := p.queries.CreateLotTransfer(ctx, db.CreateLotTransferParams{
+			ID:               database.DerivedID(database.PrefixLotTransfer, event.ID, r.lot.ID),
+			LotID:            r.lot.ID,
+			TransactionID:    event.ID,
+			TransferDate:     event.TransactionDate,
+			QuantityMicros:   r.quantity,
+			DestinationLotID: destination,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record option %s: %w", event.TransactionType, err)
+		}
+
+		err = p.queries.UpdateLotRemaining(ctx, db.UpdateLotRemainingParams{
+			ID:              r.lot.ID,
+			RemainingMicros: r.lot.RemainingMicros - r.quantity,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update lot remaining: %w", err)
+		}
+	}
+
+	slog.Debug("settled option",
+		"transaction_id", event.ID,
+		"symbol", event.Symbol,
+		"event", event.TransactionType,
+		"contracts", event.QuantityMicros.Int64,
+		"premium", premium,
+	)
+
+	if remainingToClose > 0 {
+		slog.Warn("option quantity exceeds open lots",
+			"transaction_id", event.ID,
+			"symbol", event.Symbol,
+			"unmatched_quantity", remainingToClose,
+		)
+	}
+
+	return nil
+}
+
+// OptionPosition is the open contracts of one option in one account, with the
+// shares of the underlying held alongside them (covering calls or protected by
+// puts).
+type OptionPosition struct {
+	AccountID        string
+	AccountName      string
+	Symbol           string
+	Underlying       string
+	OptionType       string
+	ExpirationDate   string
+	StrikeMicros     int64
+	Multiplier       int64
+	ContractsMicros  int64 // negative when short
+	PremiumMicros    int64 // paid for long contracts; negative, received, for short ones
+	UnderlyingMicros int64 // long shares of the underlying in the account
+}
+
+// OptionPositions nets open option lots into a position per account and
+// contract, ordered by account, underlying, expiration and symbol.
+func OptionPositions(lots []db.ListOpenLotsRow, options []db.ListOptionsRow) []OptionPosition {
+	bySecurity := make(map[string]db.ListOptionsRow)
+	for _, o := range options {
+		bySecurity[o.SecurityID] = o
+	}
+
+	type key struct{ accountID, symbol string }
+	shares := make(map[key]int64)
+	positions := make(map[key]*OptionPosition)
+	var order []key
+	for _, lot := range lots {
+		option, ok := bySecurity[lot.SecurityID]
+		if !ok {
+			if lot.PositionSide != string(PositionShort) {
+				shares[key{lot.AccountID, lot.Symbol}] += lot.RemainingMicros
+			}
+			continue
+		}
+
+		k := key{lot.AccountID, lot.Symbol}
+		pos, ok := positions[k]
+		if !ok {
+			pos = &OptionPosition{
+				AccountID:      lot.AccountID,
+				AccountName:    lot.AccountName,
+				Symbol:         lot.Symbol,
+				Underlying:     option.UnderlyingSymbol,
+				OptionType:     option.OptionType,
+				ExpirationDate: option.ExpirationDate,
+				StrikeMicros:   option.StrikeMicros,
+				Multiplier:     option.Multiplier,
+			}
+			positions[k] = pos
+			order = append(order, k)
+		}
+
+		premium := ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros)
+		if lot.PositionSide == string(PositionShort) {
+			pos.ContractsMicros -= lot.RemainingMicros
+			pos.PremiumMicros -= premium
+		} else {
+			pos.ContractsMicros += lot.RemainingMicros
+			pos.PremiumMicros += premium
+		}
+	}
+
+	result := make([]OptionPosition, 0, len(order))
+	for _, k := range order {
+		pos := positions[k]
+		pos.UnderlyingMicros = shares[key{pos.AccountID, pos.Underlying}]
+		result = append(result, *pos)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.AccountName != b.AccountName {
+			return a.AccountName < b.AccountName
+		}
+		if a.Underlying != b.Underlying {
+			return a.Underlying < b.Underlying
+		}
+		if a.ExpirationDate != b.ExpirationDate {
+			return a.ExpirationDate < b.ExpirationDate
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	return result
+}
@@ -0,0 +1,137 @@
+package taxlots_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestProcessOptions(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		optionType string
+		txns       []optionTxn
+		// wantGains are realized gains keyed by "disposed acquired".
+		wantGains map[string]int64
+		// wantShares is the basis of the open shares, when the option
+		// settled into a buy.
+		wantShares int64
+	}{
+		{
+			name:       "long call expires",
+			optionType: "call",
+			txns: []optionTxn{
+				{"option", "buy", "2024-01-05", 250_000_000},
+				{"option", "option_expiration", "2024-03-15", 0},
+			},
+			wantGains: map[string]int64{"2024-03-15 2024-01-05": -250_000_000},
+		},
+		{
+			name:       "short put bought to close",
+			optionType: "put",
+			txns: []optionTxn{
+				{"option", "sell_short", "2023-01-05", 400_000_000},
+				{"option", "buy_to_cover", "2024-03-01", 150_000_000},
+			},
+			// Held over a year, but a written option's gain is short-term.
+			wantGains: map[string]int64{"2024-03-01 2023-01-05": 250_000_000},
+		},
+		{
+			name:       "short call assigned",
+			optionType: "call",
+			txns: []optionTxn{
+				{"stock", "buy", "2023-01-05", 12_000_000_000},
+				{"option", "sell_short", "2024-01-05", 300_000_000},
+				{"option", "option_assignment", "2024-03-15", 0},
+				{"stock", "sell", "2024-03-15", 15_000_000_000},
+			},
+			// The premium goes into the proceeds of the called-away shares.
+			wantGains: map[string]int64{"2024-03-15 2023-01-05": 3_300_000_000},
+		},
+		{
+			name:       "long call exercised",
+			optionType: "call",
+			txns: []optionTxn{
+				{"option", "buy", "2024-01-05", 250_000_000},
+				{"option", "option_exercise", "2024-03-15", 0},
+				{"stock", "buy", "2024-03-15", 15_000_000_000},
+			},
+			wantShares: 15_250_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			acct := createAccount(t, queries, "Brokerage")
+			stock := createSecurity(t, queries, "AAPL")
+			option := createSecurity(t, queries, "AAPL240315C00150000")
+			err := queries.UpsertOption(ctx, db.UpsertOptionParams{
+				SecurityID:       option.ID,
+				UnderlyingSymbol: "AAPL",
+				ExpirationDate:   "2024-03-15",
+				OptionType:       tt.optionType,
+				StrikeMicros:     150_000_000,
+				Multiplier:       100,
+			})
+			if err != nil {
+				t.Fatalf("failed to create option: %v", err)
+			}
+
+			for _, txn := range tt.txns {
+				securityID, qty := option.ID, int64(1_000_000) // one contract
+				if txn.security == "stock" {
+					securityID, qty = stock.ID, 100_000_000
+				}
+				createTxn(t, queries, acct.ID, securityID, txn.txnType, txn.date, qty, txn.amount)
+			}
+
+			if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+				t.Fatalf("failed to process lots: %v", err)
+			}
+
+			assertGains(t, queries, acct.ID, tt.wantGains)
+
+			lots, err := queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+				AccountID:  acct.ID,
+				SecurityID: option.ID,
+			})
+			if err != nil {
+				t.Fatalf("failed to list option lots: %v", err)
+			}
+			for _, lot := range lots {
+				if lot.RemainingMicros != 0 {
+					t.Errorf("option lot %s still has %d open", lot.ID, lot.RemainingMicros)
+				}
+			}
+
+			if tt.wantShares != 0 {
+				lots, err := queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+					AccountID:  acct.ID,
+					SecurityID: stock.ID,
+				})
+				if err != nil {
+					t.Fatalf("failed to list stock lots: %v", err)
+				}
+				if len(lots) != 1 || lots[0].CostBasisMicros != tt.wantShares {
+					t.Errorf("stock lots = %+v, want one with basis %d", lots, tt.wantShares)
+				}
+			}
+		})
+	}
+}
+
+// optionTxn is a trade of one contract ("option") or the 100 shares it
+// covers ("stock").
+type optionTxn struct {
+	security string
+	txnType  string
+	date     string
+	amount   int64
+}
@@ -2,9 +2,9 @@ package taxlots
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
-	"time"
 
 	"github.com/levisegal/monay/services/holdings/database"
 	"github.com/levisegal/monay/services/holdings/gen/db"
@@ -12,42 +12,283 @@ import (
 
 type Processor struct {
 	queries *db.Queries
+	bonds   map[string]db.ListBondsRow // by security ID
 }
 
 func NewProcessor(queries *db.Queries) *Processor {
 	return &Processor{queries: queries}
 }
 
-func (p *Processor) ProcessTransactions(ctx context.Context, accountID string) error {
-	if err := p.queries.DeleteLotsByAccount(ctx, accountID); err != nil {
-		return fmt.Errorf("failed to clear lot dispositions: %w", err)
+// ProcessResult summarizes a lot run.
+type ProcessResult struct {
+	// AccountIDs lists every account processed. Accounts linked by matched
+	// transfers are processed together.
+	AccountIDs         []string
+	Transfers          []TransferMatch
+	UnmatchedTransfers []UnmatchedTransfer
+	// Replays lists the accounts and securities whose transactions changed
+	// since the last run. Everything else was left as it was.
+	Replays []Replay
+}
+
+// ProcessTransactions brings lots up to date with the transactions. Each
+// account and security replays only from its earliest changed transaction,
+// using the journal of what the last run applied. Lot and disposition IDs are
+// derived from the transactions that create them, so they survive replays.
+func (p *Processor) ProcessTransactions(ctx context.Context, accountID string) (*ProcessResult, error) {
+	transfers, err := p.queries.ListSecurityTransfers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security transfers: %w", err)
 	}
-	if err := p.queries.DeleteLotsForAccount(ctx, accountID); err != nil {
-		return fmt.Errorf("failed to clear lots: %w", err)
+
+	matches, unmatched := MatchTransfers(transfers)
+	accountIDs := connectedAccounts(accountID, matches)
+
+	var txns []db.ListTransactionsByAccountRow
+	var adjustments []db.LotAdjustment
+	var journal []db.LotJournal
+	for _, id := range accountIDs {
+		accountTxns, err := p.queries.ListTransactionsByAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list transactions: %w", err)
+		}
+		txns = append(txns, accountTxns...)
+
+		accountAdjustments, err := p.queries.ListEnteredLotAdjustmentsByAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list lot adjustments: %w", err)
+		}
+		adjustments = append(adjustments, accountAdjustments...)
+
+		accountJournal, err := p.queries.ListLotJournalByAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list lot journal: %w", err)
+		}
+		journal = append(journal, accountJournal...)
 	}
 
-	slog.Info("cleared existing lots for account", "account_id", accountID)
+	options, err := p.queries.ListOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list options: %w", err)
+	}
 
-	txns, err := p.queries.ListTransactionsByAccount(ctx, accountID)
+	bonds, err := p.queries.ListBonds(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list transactions: %w", err)
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	p.bonds = make(map[string]db.ListBondsRow)
+	for _, b := range bonds {
+		p.bonds[b.SecurityID] = b
 	}
 
-	sorted := sortByDateAsc(txns)
+	plan := planReplay(txns, adjustments, matches, MatchOptionEvents(txns, options), bonds, journal)
+	replays := plan.replays()
 
-	for _, txn := range sorted {
-		if !txn.SecurityID.Valid {
-			continue
+	for _, r := range replays {
+		if err := p.rewind(ctx, r.AccountID, r.SecurityID, plan.from[lotKey{r.AccountID, r.SecurityID}]); err != nil {
+			return nil, fmt.Errorf("failed to rewind lots for %s: %w", r.Symbol, err)
+		}
+		slog.Info("replaying lots",
+			"account_id", r.AccountID,
+			"symbol", r.Symbol,
+			"from", r.FromDate,
+			"transactions", r.Transactions,
+		)
+	}
+
+	for _, e := range plan.entries {
+		if err := p.apply(ctx, e); err != nil {
+			return nil, err
+		}
+
+		key := e.key()
+		err := p.queries.CreateLotJournalEntry(ctx, db.CreateLotJournalEntryParams{
+			AccountID:     key.accountID,
+			SecurityID:    key.securityID,
+			SortKey:       e.sortKey,
+			TransactionID: e.sourceID(),
+			Digest:        e.digest,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record lot journal: %w", err)
+		}
+	}
+
+	result := &ProcessResult{AccountIDs: accountIDs, Replays: replays}
+	inScope := make(map[string]bool)
+	for _, id := range accountIDs {
+		inScope[id] = true
+	}
+	for _, m := range matches {
+		if inScope[m.OutAccountID] {
+			result.Transfers = append(result.Transfers, m)
+		}
+	}
+	for _, u := range unmatched {
+		if inScope[u.AccountID] {
+			result.UnmatchedTransfers = append(result.UnmatchedTransfers, u)
+		}
+	}
+
+	return result, nil
+}
+
+// RebuildTransactions forgets what earlier runs applied for the account and
+// the accounts linked to it by transfers, then replays their full history.
+func (p *Processor) RebuildTransactions(ctx context.Context, accountID string) (*ProcessResult, error) {
+	transfers, err := p.queries.ListSecurityTransfers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security transfers: %w", err)
+	}
+
+	matches, _ := MatchTransfers(transfers)
+	for _, id := range connectedAccounts(accountID, matches) {
+		if err := p.queries.DeleteLotJournalByAccount(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to clear lot journal: %w", err)
+		}
+	}
+
+	return p.ProcessTransactions(ctx, accountID)
+}
+
+// rewind undoes everything transactions and adjustments at or after sortKey
+// did to one account and security's lots: basis changes are reversed, their
+// dispositions, transfers and lots go, and earlier lots get back what those
+// transactions relieved.
+func (p *Processor) rewind(ctx context.Context, accountID, securityID, sortKey string) error {
+	if err := p.queries.RestoreLotBasisFrom(ctx, db.RestoreLotBasisFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to restore lot basis: %w", err)
+	}
+	if err := p.queries.DeleteLotAdjustmentsFrom(ctx, db.DeleteLotAdjustmentsFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to delete lot adjustments: %w", err)
+	}
+	if err := p.queries.ResetLotAdjustmentsFrom(ctx, db.ResetLotAdjustmentsFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to reset lot adjustments: %w", err)
+	}
+	if err := p.queries.DeleteLotDispositionsFrom(ctx, db.DeleteLotDispositionsFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to delete dispositions: %w", err)
+	}
+	if err := p.queries.DeleteLotTransfersFrom(ctx, db.DeleteLotTransfersFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to delete lot transfers: %w", err)
+	}
+	if err := p.queries.DeleteLotsFrom(ctx, db.DeleteLotsFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to delete lots: %w", err)
+	}
+	if err := p.queries.RecomputeLotRemaining(ctx, db.RecomputeLotRemainingParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+	}); err != nil {
+		return fmt.Errorf("failed to recompute lot remaining: %w", err)
+	}
+	if err := p.queries.DeleteLotJournalFrom(ctx, db.DeleteLotJournalFromParams{
+		AccountID:  accountID,
+		SecurityID: securityID,
+		SortKey:    sortKey,
+	}); err != nil {
+		return fmt.Errorf("failed to delete lot journal: %w", err)
+	}
+	return nil
+}
+
+func (p *Processor) apply(ctx context.Context, e replayEntry) error {
+	if e.adjustment != nil {
+		if err := p.processAdjustment(ctx, *e.adjustment); err != nil {
+			return fmt.Errorf("failed to apply lot adjustment %s: %w", e.adjustment.ID, err)
 		}
+		return nil
+	}
 
-		switch txn.TransactionType {
-		case "buy", "security_transfer", "opening_balance", "reorg_in":
+	txn := e.txn
+
+	if e.option != nil {
+		if !e.applies {
+			// The exercise or assignment and its trade in the underlying
+			// settle together; the other side does the work.
+			return nil
+		}
+		if err := p.processOptionEvent(ctx, *e.option); err != nil {
+			return fmt.Errorf("failed to process option %s %s: %w", e.option.Event.TransactionType, e.option.Event.ID, err)
+		}
+		return nil
+	}
+
+	switch txn.TransactionType {
+	case "buy", "opening_balance", "reorg_in", "rsu_vest", "espp_purchase":
+		if err := p.processBuy(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process buy %s: %w", txn.ID, err)
+		}
+	case "sell", "reorg_out":
+		if err := p.processSell(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process sell %s: %w", txn.ID, err)
+		}
+	case "redemption":
+		if err := p.processSell(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process redemption %s: %w", txn.ID, err)
+		}
+	case "sell_short":
+		if err := p.processShort(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process short sale %s: %w", txn.ID, err)
+		}
+	case "buy_to_cover":
+		if err := p.processCover(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process cover %s: %w", txn.ID, err)
+		}
+	case "option_expiration", "option_assignment", "option_exercise":
+		// Exercises and assignments matched to a trade in the underlying are
+		// handled above. Without one, the contracts close like an expiration.
+		if txn.TransactionType != "option_expiration" {
+			slog.Warn("no underlying trade for option event",
+				"transaction_id", txn.ID,
+				"symbol", txn.Symbol,
+				"event", txn.TransactionType,
+			)
+		}
+		if err := p.processExpiration(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process option %s %s: %w", txn.TransactionType, txn.ID, err)
+		}
+	case "return_of_capital":
+		if err := p.processReturnOfCapital(ctx, txn); err != nil {
+			return fmt.Errorf("failed to process return of capital %s: %w", txn.ID, err)
+		}
+	case "security_transfer", "transfer_out":
+		switch {
+		case e.match != nil && !e.applies:
+			// Both sides move together; the other side does the work.
+		case e.match != nil:
+			if err := p.processTransfer(ctx, *e.match, txn); err != nil {
+				return fmt.Errorf("failed to process transfer %s: %w", txn.ID, err)
+			}
+		case txn.TransactionType == "security_transfer":
 			if err := p.processBuy(ctx, txn); err != nil {
-				return fmt.Errorf("failed to process buy %s: %w", txn.ID, err)
+				return fmt.Errorf("failed to process transfer in %s: %w", txn.ID, err)
 			}
-		case "sell", "reorg_out":
-			if err := p.processSell(ctx, txn); err != nil {
-				return fmt.Errorf("failed to process sell %s: %w", txn.ID, err)
+		default:
+			if err := p.processTransferOut(ctx, txn); err != nil {
+				return fmt.Errorf("failed to process transfer out %s: %w", txn.ID, err)
 			}
 		}
 	}
@@ -60,34 +301,74 @@ func (p *Processor) processBuy(ctx context.Context, txn db.ListTransactionsByAcc
 		return nil
 	}
 
-	_, err := p.queries.CreateLot(ctx, db.CreateLotParams{
-		ID:              database.NewID(database.PrefixLot),
-		AccountID:       txn.AccountID,
-		SecurityID:      txn.SecurityID.String,
-		TransactionID:   txn.ID,
-		AcquiredDate:    txn.TransactionDate,
-		QuantityMicros:  txn.QuantityMicros.Int64,
-		RemainingMicros: txn.QuantityMicros.Int64,
-		CostBasisMicros: txn.AmountMicros,
+	quantity, cost := txn.QuantityMicros.Int64, purchaseCost(txn)
+	if bond, ok := p.bonds[txn.SecurityID.String]; ok && txn.TransactionType == "buy" {
+		// Accrued interest paid to the seller comes back in the next coupon;
+		// it isn't part of the bond's basis.
+		cost -= AccruedInterest(txn, bond)
+	}
+	if txn.TransactionType == "buy" {
+		// A purchase while short covers the short position first; only what
+		// is left over opens a long lot.
+		uncovered, err := p.closeShortLots(ctx, txn, cost, txn.FeesMicros.Int64)
+		if err != nil {
+			return err
+		}
+		if uncovered == 0 {
+			return nil
+		}
+		cost = ProRata(cost, quantity, uncovered)
+		quantity = uncovered
+	}
+
+	_, err := p.buy(ctx, txn, quantity, cost)
+	return err
+}
+
+// buy creates a long lot for quantityMicros of txn at costMicros.
+func (p *Processor) buy(ctx context.Context, txn db.ListTransactionsByAccountRow, quantityMicros, costMicros int64) (db.Lot, error) {
+	acq := acquisitionFor(txn)
+	lot, err := p.queries.CreateLot(ctx, db.CreateLotParams{
+		ID:                database.DerivedID(database.PrefixLot, txn.ID),
+		AccountID:         txn.AccountID,
+		SecurityID:        txn.SecurityID.String,
+		TransactionID:     txn.ID,
+		AcquiredDate:      txn.TransactionDate,
+		QuantityMicros:    quantityMicros,
+		RemainingMicros:   quantityMicros,
+		CostBasisMicros:   costMicros,
+		AcquisitionKind:   string(acq.kind),
+		FmvMicros:         acq.fmvMicros,
+		DonorAcquiredDate: acq.donorAcquiredDate,
+		PlanType:          acq.planType,
+		GrantDate:         acq.grantDate,
+		GrantFmvMicros:    acq.grantFmvMicros,
+		DiscountRate:      acq.discountRate,
+		PositionSide:      string(PositionLong),
 	})
 	if err != nil {
-		return err
+		return db.Lot{}, err
 	}
 
 	slog.Debug("created lot",
 		"transaction_id", txn.ID,
 		"symbol", txn.Symbol,
-		"quantity", txn.QuantityMicros.Int64,
+		"quantity", quantityMicros,
+		"acquisition", acq.kind,
 	)
 
-	return nil
+	return lot, nil
 }
 
 func (p *Processor) processSell(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
 	if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
 		return nil
 	}
+	return p.sell(ctx, txn, 0)
+}
 
+// sell relieves lots for txn, with proceedsAdjustment added to its proceeds.
+func (p *Processor) sell(ctx context.Context, txn db.ListTransactionsByAccountRow, proceedsAdjustment int64) error {
 	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
 		AccountID:  txn.AccountID,
 		SecurityID: txn.SecurityID.String,
@@ -96,93 +377,274 @@ func (p *Processor) processSell(ctx context.Context, txn db.ListTransactionsByAc
 		return fmt.Errorf("failed to list lots: %w", err)
 	}
 
-	remainingToSell := txn.QuantityMicros.Int64
-	proceeds := txn.AmountMicros
-	sellDate := parseDate(txn.TransactionDate)
+	proceeds, fees := saleProceeds(txn)
+	if bond, ok := p.bonds[txn.SecurityID.String]; ok {
+		// Accrued interest received is interest income, not proceeds, and
+		// premium amortized while the bond was held has already come off the
+		// basis.
+		proceeds -= AccruedInterest(txn, bond)
+		lots = amortizeLots(lots, bond, parseDate(txn.TransactionDate))
+	}
+	reliefs, remainingToSell := MatchSale(lots, MethodFIFO, Sale{
+		Date:           parseDate(txn.TransactionDate),
+		QuantityMicros: txn.QuantityMicros.Int64,
+		ProceedsMicros: proceeds + proceedsAdjustment,
+		FeesMicros:     fees,
+	})
 
-	for _, lot := range lots {
-		if remainingToSell <= 0 {
-			break
+	for _, relief := range reliefs {
+		_, err := p.queries.CreateLotDisposition(ctx, db.CreateLotDispositionParams{
+			ID:                       database.DerivedID(database.PrefixLotDisposition, txn.ID, relief.Lot.ID),
+			LotID:                    relief.Lot.ID,
+			SellTransactionID:        txn.ID,
+			DisposedDate:             txn.TransactionDate,
+			QuantityMicros:           relief.QuantityMicros,
+			CostBasisMicros:          relief.CostBasisMicros,
+			ProceedsMicros:           relief.ProceedsMicros,
+			FeesMicros:               relief.FeesMicros,
+			RealizedGainMicros:       relief.GainMicros,
+			HoldingPeriod:            string(relief.HoldingPeriod),
+			CompensationIncomeMicros: relief.CompensationIncomeMicros,
+			EsppDisposition:          sql.NullString{String: string(relief.EsppDisposition), Valid: relief.EsppDisposition != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create disposition: %w", err)
 		}
 
-		if lot.RemainingMicros <= 0 {
-			continue
+		err = p.queries.UpdateLotRemaining(ctx, db.UpdateLotRemainingParams{
+			ID:              relief.Lot.ID,
+			RemainingMicros: relief.RemainingMicros,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update lot remaining: %w", err)
 		}
 
-		sellFromLot := min(remainingToSell, lot.RemainingMicros)
+		slog.Debug("matched sell to lot",
+			"lot_id", relief.Lot.ID,
+			"quantity", relief.QuantityMicros,
+			"cost_basis", relief.CostBasisMicros,
+			"proceeds", relief.ProceedsMicros,
+			"fees", relief.FeesMicros,
+			"gain", relief.GainMicros,
+			"holding_period", relief.HoldingPeriod,
+		)
+	}
 
-		costPerMicro := float64(lot.CostBasisMicros) / float64(lot.QuantityMicros)
-		costBasis := int64(costPerMicro * float64(sellFromLot))
+	if remainingToSell > 0 && txn.AccountMargin && txn.TransactionType == "sell" {
+		// Selling more than is held on margin is a short sale of the rest.
+		quantity := txn.QuantityMicros.Int64
+		return p.openShort(ctx, txn, remainingToSell, ProRata(proceeds+proceedsAdjustment, quantity, remainingToSell))
+	}
+	if remainingToSell > 0 {
+		slog.Warn("sell quantity exceeds available lots",
+			"transaction_id", txn.ID,
+			"symbol", txn.Symbol,
+			"unmatched_quantity", remainingToSell,
+		)
+	}
 
-		proceedsPerMicro := float64(proceeds) / float64(txn.QuantityMicros.Int64)
-		lotProceeds := int64(proceedsPerMicro * float64(sellFromLot))
+	return nil
+}
 
-		gain := lotProceeds - costBasis
+// processTransfer moves lots from the delivering account to the receiving
+// account, keeping each lot's acquisition date, basis and kind. The transfer-in
+// transaction becomes the receiving lots' transaction so they are removed if
+// it is deleted.
+func (p *Processor) processTransfer(ctx context.Context, match TransferMatch, txn db.ListTransactionsByAccountRow) error {
+	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  match.OutAccountID,
+		SecurityID: match.SecurityID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list lots: %w", err)
+	}
 
-		holdingPeriod := "short_term"
-		acquiredDate := parseDate(lot.AcquiredDate)
-		if sellDate.Sub(acquiredDate) > 365*24*time.Hour {
-			holdingPeriod = "long_term"
+	remainingToMove := match.QuantityMicros
+
+	for _, lot := range lots {
+		if remainingToMove <= 0 {
+			break
+		}
+		if isShort(lot) {
+			continue
 		}
 
-		_, err := p.queries.CreateLotDisposition(ctx, db.CreateLotDispositionParams{
-			ID:                 database.NewID(database.PrefixLotDisposition),
-			LotID:              lot.ID,
-			SellTransactionID:  txn.ID,
-			DisposedDate:       txn.TransactionDate,
-			QuantityMicros:     sellFromLot,
-			CostBasisMicros:    costBasis,
-			ProceedsMicros:     lotProceeds,
-			RealizedGainMicros: gain,
-			HoldingPeriod:      holdingPeriod,
+		moveFromLot := min(remainingToMove, lot.RemainingMicros)
+		costBasis := ProRata(lot.CostBasisMicros, lot.QuantityMicros, moveFromLot)
+
+		fmv := lot.FmvMicros
+		if fmv.Valid {
+			fmv.Int64 = ProRata(fmv.Int64, lot.QuantityMicros, moveFromLot)
+		}
+		grantFMV := lot.GrantFmvMicros
+		if grantFMV.Valid {
+			grantFMV.Int64 = ProRata(grantFMV.Int64, lot.QuantityMicros, moveFromLot)
+		}
+
+		moved, err := p.queries.CreateLot(ctx, db.CreateLotParams{
+			ID:                database.DerivedID(database.PrefixLot, match.InTransactionID, lot.ID),
+			AccountID:         match.InAccountID,
+			SecurityID:        match.SecurityID,
+			TransactionID:     match.InTransactionID,
+			AcquiredDate:      lot.AcquiredDate,
+			QuantityMicros:    moveFromLot,
+			RemainingMicros:   moveFromLot,
+			CostBasisMicros:   costBasis,
+			SourceLotID:       sql.NullString{String: lot.ID, Valid: true},
+			AcquisitionKind:   lot.AcquisitionKind,
+			FmvMicros:         fmv,
+			DonorAcquiredDate: lot.DonorAcquiredDate,
+			PlanType:          lot.PlanType,
+			GrantDate:         lot.GrantDate,
+			GrantFmvMicros:    grantFMV,
+			DiscountRate:      lot.DiscountRate,
+			PositionSide:      lot.PositionSide,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create disposition: %w", err)
+			return fmt.Errorf("failed to create transferred lot: %w", err)
+		}
+
+		_, err = p.queries.CreateLotTransfer(ctx, db.CreateLotTransferParams{
+			ID:               database.DerivedID(database.PrefixLotTransfer, match.OutTransactionID, lot.ID),
+			LotID:            lot.ID,
+			TransactionID:    match.OutTransactionID,
+			TransferDate:     match.OutDate,
+			QuantityMicros:   moveFromLot,
+			DestinationLotID: sql.NullString{String: moved.ID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record lot transfer: %w", err)
 		}
 
-		newRemaining := lot.RemainingMicros - sellFromLot
 		err = p.queries.UpdateLotRemaining(ctx, db.UpdateLotRemainingParams{
 			ID:              lot.ID,
-			RemainingMicros: newRemaining,
+			RemainingMicros: lot.RemainingMicros - moveFromLot,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to update lot remaining: %w", err)
 		}
 
-		slog.Debug("matched sell to lot",
-			"lot_id", lot.ID,
-			"quantity", sellFromLot,
-			"cost_basis", costBasis,
-			"proceeds", lotProceeds,
-			"gain", gain,
-			"holding_period", holdingPeriod,
+		slog.Debug("transferred lot",
+			"source_lot_id", lot.ID,
+			"symbol", match.Symbol,
+			"quantity", moveFromLot,
+			"acquired_date", lot.AcquiredDate,
 		)
 
-		remainingToSell -= sellFromLot
+		remainingToMove -= moveFromLot
 	}
 
-	if remainingToSell > 0 {
-		slog.Warn("sell quantity exceeds available lots",
+	if remainingToMove > 0 {
+		// The delivering account's history doesn't cover these shares. Fall back
+		// to the receiving side's reported amount, dated on the transfer.
+		inTxn, err := p.queries.GetTransaction(ctx, match.InTransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to get transfer in transaction: %w", err)
+		}
+
+		_, err = p.queries.CreateLot(ctx, db.CreateLotParams{
+			ID:              database.DerivedID(database.PrefixLot, match.InTransactionID),
+			AccountID:       match.InAccountID,
+			SecurityID:      match.SecurityID,
+			TransactionID:   match.InTransactionID,
+			AcquiredDate:    inTxn.TransactionDate,
+			QuantityMicros:  remainingToMove,
+			RemainingMicros: remainingToMove,
+			CostBasisMicros: ProRata(inTxn.AmountMicros, match.QuantityMicros, remainingToMove),
+			AcquisitionKind: string(AcquisitionTransfer),
+			PositionSide:    string(PositionLong),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create transferred lot: %w", err)
+		}
+
+		slog.Warn("transfer quantity exceeds source lots",
 			"transaction_id", txn.ID,
-			"symbol", txn.Symbol,
-			"unmatched_quantity", remainingToSell,
+			"symbol", match.Symbol,
+			"unmatched_quantity", remainingToMove,
 		)
 	}
 
 	return nil
 }
 
-func sortByDateAsc(txns []db.ListTransactionsByAccountRow) []db.ListTransactionsByAccountRow {
-	sorted := make([]db.ListTransactionsByAccountRow, len(txns))
-	copy(sorted, txns)
+// processTransferOut relieves lots for shares delivered out to an account we
+// don't track. Nothing is realized, so no disposition is recorded; the lot
+// transfer keeps the relief so a later replay can undo it.
+func (p *Processor) processTransferOut(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
+	if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
+		return nil
+	}
 
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j].TransactionDate < sorted[i].TransactionDate {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
+	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  txn.AccountID,
+		SecurityID: txn.SecurityID.String,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	remainingToMove := txn.QuantityMicros.Int64
+
+	for _, lot := range lots {
+		if remainingToMove <= 0 {
+			break
+		}
+		if isShort(lot) {
+			continue
 		}
+
+		moveFromLot := min(remainingToMove, lot.RemainingMicros)
+		_, err = p.queries.CreateLotTransfer(ctx, db.CreateLotTransferParams{
+			ID:             database.DerivedID(database.PrefixLotTransfer, txn.ID, lot.ID),
+			LotID:          lot.ID,
+			TransactionID:  txn.ID,
+			TransferDate:   txn.TransactionDate,
+			QuantityMicros: moveFromLot,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record lot transfer: %w", err)
+		}
+
+		err = p.queries.UpdateLotRemaining(ctx, db.UpdateLotRemainingParams{
+			ID:              lot.ID,
+			RemainingMicros: lot.RemainingMicros - moveFromLot,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update lot remaining: %w", err)
+		}
+
+		remainingToMove -= moveFromLot
 	}
 
-	return sorted
+	return nil
+}
+
+// purchaseCost is the lot basis for a buy: the price paid plus commissions
+// and fees. Brokers that report the net cash amount already include them.
+func purchaseCost(txn db.ListTransactionsByAccountRow) int64 {
+	if txn.FeesInAmount {
+		return txn.AmountMicros
+	}
+	return txn.AmountMicros + txn.FeesMicros.Int64
+}
+
+// saleProceeds returns net proceeds (after commissions and fees) and the fees
+// taken out. Gross proceeds are net plus fees.
+func saleProceeds(txn db.ListTransactionsByAccountRow) (netMicros, feesMicros int64) {
+	fees := txn.FeesMicros.Int64
+	if txn.FeesInAmount {
+		return txn.AmountMicros, fees
+	}
+	return txn.AmountMicros - fees, fees
+}
+
+// ProRata returns the share of amountMicros that portionMicros represents out
+// of quantityMicros, e.g. the basis of part of a lot.
+func ProRata(amountMicros, quantityMicros, portionMicros int64) int64 {
+	if quantityMicros == 0 {
+		return 0
+	}
+	perMicro := float64(amountMicros) / float64(quantityMicros)
+	return int64(perMicro * float64(portionMicros))
 }
@@ -0,0 +1,372 @@
+package taxlots_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func setupTestDB(t *testing.T) (*db.Queries, func()) {
+	t.Helper()
+	ctx := context.Background()
+
+	tmpFile, err := os.CreateTemp("", "taxlots-test-*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	conn, err := database.Open(ctx, tmpFile.Name())
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	cleanup := func() {
+		conn.Close()
+		os.Remove(tmpFile.Name())
+	}
+
+	return db.New(conn), cleanup
+}
+
+func createAccount(t *testing.T, queries *db.Queries, name string) db.Account {
+	t.Helper()
+	acct, err := queries.CreateAccount(context.Background(), db.CreateAccountParams{
+		ID:              database.NewID(database.PrefixAccount),
+		Name:            name,
+		InstitutionName: "etrade",
+		AccountType:     "brokerage",
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	return acct
+}
+
+func createSecurity(t *testing.T, queries *db.Queries, symbol string) db.Security {
+	t.Helper()
+	sec, err := queries.UpsertSecurity(context.Background(), db.UpsertSecurityParams{
+		ID:     database.NewID(database.PrefixSecurity),
+		Symbol: symbol,
+	})
+	if err != nil {
+		t.Fatalf("failed to create security: %v", err)
+	}
+	return sec
+}
+
+func createTxn(t *testing.T, queries *db.Queries, accountID, securityID, txnType, date string, qty, amount int64) {
+	t.Helper()
+	createTxnWithFees(t, queries, accountID, securityID, txnType, date, qty, amount, 0, true)
+}
+
+func createTxnWithFees(t *testing.T, queries *db.Queries, accountID, securityID, txnType, date string, qty, amount, fees int64, feesInAmount bool) {
+	t.Helper()
+	err := queries.CreateTransaction(context.Background(), db.CreateTransactionParams{
+		ID:              database.NewID(database.PrefixTransaction),
+		AccountID:       accountID,
+		SecurityID:      sql.NullString{String: securityID, Valid: true},
+		TransactionType: txnType,
+		TransactionDate: date,
+		QuantityMicros:  sql.NullInt64{Int64: qty, Valid: true},
+		AmountMicros:    amount,
+		FeesMicros:      sql.NullInt64{Int64: fees, Valid: true},
+		FeesInAmount:    feesInAmount,
+	})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+}
+
+func TestProcessTransfersBetweenAccounts(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	stockplan := createAccount(t, queries, "Stockplan")
+	joint := createAccount(t, queries, "Joint")
+	gild := createSecurity(t, queries, "GILD")
+
+	createTxn(t, queries, stockplan.ID, gild.ID, "opening_balance", "2018-03-01", 100_000_000, 6_000_000_000)
+	createTxn(t, queries, stockplan.ID, gild.ID, "buy", "2021-06-15", 50_000_000, 3_500_000_000)
+	createTxn(t, queries, stockplan.ID, gild.ID, "transfer_out", "2024-02-01", 120_000_000, 0)
+	createTxn(t, queries, joint.ID, gild.ID, "security_transfer", "2024-02-03", 120_000_000, 9_600_000_000)
+
+	processor := taxlots.NewProcessor(queries)
+	result, err := processor.ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	if len(result.AccountIDs) != 2 {
+		t.Errorf("expected both accounts rebuilt, got %d", len(result.AccountIDs))
+	}
+	if len(result.Transfers) != 1 {
+		t.Fatalf("expected 1 matched transfer, got %d", len(result.Transfers))
+	}
+	if len(result.UnmatchedTransfers) != 0 {
+		t.Errorf("expected no unmatched transfers, got %d", len(result.UnmatchedTransfers))
+	}
+
+	jointLots, err := queries.ListLotsByAccount(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	if len(jointLots) != 2 {
+		t.Fatalf("expected 2 lots in joint, got %d", len(jointLots))
+	}
+	if jointLots[0].AcquiredDate != "2018-03-01" || jointLots[0].QuantityMicros != 100_000_000 {
+		t.Errorf("expected first lot from 2018-03-01 with 100 shares, got %s %d", jointLots[0].AcquiredDate, jointLots[0].QuantityMicros)
+	}
+	if jointLots[0].CostBasisMicros != 6_000_000_000 {
+		t.Errorf("expected first lot basis 6000, got %d", jointLots[0].CostBasisMicros)
+	}
+	if jointLots[1].AcquiredDate != "2021-06-15" || jointLots[1].QuantityMicros != 20_000_000 {
+		t.Errorf("expected second lot from 2021-06-15 with 20 shares, got %s %d", jointLots[1].AcquiredDate, jointLots[1].QuantityMicros)
+	}
+	if jointLots[1].CostBasisMicros != 1_400_000_000 {
+		t.Errorf("expected second lot basis 1400, got %d", jointLots[1].CostBasisMicros)
+	}
+	if !jointLots[0].SourceLotID.Valid {
+		t.Error("expected transferred lot to record its source lot")
+	}
+
+	stockplanLots, err := queries.ListLotsByAccount(ctx, stockplan.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	var remaining int64
+	for _, l := range stockplanLots {
+		remaining += l.RemainingMicros
+	}
+	if remaining != 30_000_000 {
+		t.Errorf("expected 30 shares left in stockplan, got %d", remaining)
+	}
+}
+
+func TestProcessUnmatchedTransfers(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Managed")
+	other := createAccount(t, queries, "Other")
+	aapl := createSecurity(t, queries, "AAPL")
+
+	createTxn(t, queries, acct.ID, aapl.ID, "security_transfer", "2024-01-10", 10_000_000, 1_800_000_000)
+	createTxn(t, queries, other.ID, aapl.ID, "transfer_out", "2024-03-10", 10_000_000, 0)
+
+	processor := taxlots.NewProcessor(queries)
+	result, err := processor.ProcessTransactions(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	if len(result.Transfers) != 0 {
+		t.Errorf("expected no matches outside the date window, got %d", len(result.Transfers))
+	}
+	if len(result.UnmatchedTransfers) != 1 {
+		t.Fatalf("expected 1 unmatched transfer in scope, got %d", len(result.UnmatchedTransfers))
+	}
+
+	lots, err := queries.ListLotsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	if len(lots) != 1 || lots[0].AcquiredDate != "2024-01-10" || lots[0].CostBasisMicros != 1_800_000_000 {
+		t.Errorf("expected fallback lot at transfer date with reported amount, got %+v", lots)
+	}
+}
+
+func TestProcessFees(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		feesInAmount bool
+		buyAmount    int64
+		sellAmount   int64
+		wantBasis    int64
+		wantProceeds int64
+	}{
+		{
+			name:         "gross amounts",
+			feesInAmount: false,
+			buyAmount:    1_000_000_000,
+			sellAmount:   1_500_000_000,
+			wantBasis:    1_005_000_000,
+			wantProceeds: 1_495_000_000,
+		},
+		{
+			name:         "net amounts",
+			feesInAmount: true,
+			buyAmount:    1_005_000_000,
+			sellAmount:   1_495_000_000,
+			wantBasis:    1_005_000_000,
+			wantProceeds: 1_495_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			acct := createAccount(t, queries, "Brokerage")
+			sec := createSecurity(t, queries, "MSFT")
+
+			createTxnWithFees(t, queries, acct.ID, sec.ID, "buy", "2023-01-10", 10_000_000, tt.buyAmount, 5_000_000, tt.feesInAmount)
+			createTxnWithFees(t, queries, acct.ID, sec.ID, "sell", "2024-06-10", 10_000_000, tt.sellAmount, 5_000_000, tt.feesInAmount)
+
+			if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+				t.Fatalf("failed to process lots: %v", err)
+			}
+
+			dispositions, err := queries.ListDispositionsByAccount(ctx, acct.ID)
+			if err != nil {
+				t.Fatalf("failed to list dispositions: %v", err)
+			}
+			if len(dispositions) != 1 {
+				t.Fatalf("expected 1 disposition, got %d", len(dispositions))
+			}
+
+			d := dispositions[0]
+			if d.CostBasisMicros != tt.wantBasis {
+				t.Errorf("expected basis %d, got %d", tt.wantBasis, d.CostBasisMicros)
+			}
+			if d.ProceedsMicros != tt.wantProceeds {
+				t.Errorf("expected net proceeds %d, got %d", tt.wantProceeds, d.ProceedsMicros)
+			}
+			if d.FeesMicros != 5_000_000 {
+				t.Errorf("expected fees 5_000_000, got %d", d.FeesMicros)
+			}
+			if d.RealizedGainMicros != tt.wantProceeds-tt.wantBasis {
+				t.Errorf("expected gain %d, got %d", tt.wantProceeds-tt.wantBasis, d.RealizedGainMicros)
+			}
+		})
+	}
+}
+
+func TestProcessIncremental(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	stockplan := createAccount(t, queries, "Stockplan")
+	joint := createAccount(t, queries, "Joint")
+	gild := createSecurity(t, queries, "GILD")
+	msft := createSecurity(t, queries, "MSFT")
+
+	createTxn(t, queries, stockplan.ID, gild.ID, "buy", "2021-06-15", 50_000_000, 3_500_000_000)
+	createTxn(t, queries, stockplan.ID, gild.ID, "transfer_out", "2024-02-01", 40_000_000, 0)
+	createTxn(t, queries, joint.ID, gild.ID, "security_transfer", "2024-02-03", 40_000_000, 3_200_000_000)
+	// Imported sell first: same-day buys still replay before it.
+	createTxn(t, queries, joint.ID, msft.ID, "sell", "2024-03-01", 5_000_000, 2_000_000_000)
+	createTxn(t, queries, joint.ID, msft.ID, "buy", "2024-03-01", 5_000_000, 1_900_000_000)
+
+	processor := taxlots.NewProcessor(queries)
+	first, err := processor.ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	if len(first.Replays) != 3 {
+		t.Errorf("expected 3 securities replayed on the first run, got %d", len(first.Replays))
+	}
+	before := lotSnapshot(t, queries, stockplan.ID, joint.ID)
+	if before["disp"] != 1 {
+		t.Fatalf("expected the same-day sell to relieve the same-day buy, got %d dispositions", before["disp"])
+	}
+
+	again, err := processor.ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	if len(again.Replays) != 0 {
+		t.Errorf("expected nothing replayed without changes, got %+v", again.Replays)
+	}
+	assertSnapshot(t, "unchanged run", before, lotSnapshot(t, queries, stockplan.ID, joint.ID))
+
+	// A new joint sell only touches joint's GILD lots.
+	createTxn(t, queries, joint.ID, gild.ID, "sell", "2024-05-01", 10_000_000, 900_000_000)
+	result, err := processor.ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	if len(result.Replays) != 1 || result.Replays[0].AccountID != joint.ID || result.Replays[0].FromDate != "2024-05-01" {
+		t.Errorf("expected only joint GILD replayed from 2024-05-01, got %+v", result.Replays)
+	}
+
+	// A backdated stockplan buy changes which lots moved, so both sides of the
+	// transfer replay from the transfer.
+	createTxn(t, queries, stockplan.ID, gild.ID, "buy", "2020-01-10", 10_000_000, 500_000_000)
+	result, err = processor.ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	replayed := make(map[string]string)
+	for _, r := range result.Replays {
+		replayed[r.AccountID] = r.FromDate
+	}
+	if replayed[stockplan.ID] != "2020-01-10" || replayed[joint.ID] != "2024-02-01" {
+		t.Errorf("expected stockplan from 2020-01-10 and joint from the transfer, got %+v", result.Replays)
+	}
+	incremental := lotSnapshot(t, queries, stockplan.ID, joint.ID)
+
+	if _, err := processor.RebuildTransactions(ctx, joint.ID); err != nil {
+		t.Fatalf("failed to rebuild lots: %v", err)
+	}
+	assertSnapshot(t, "incremental vs full rebuild", lotSnapshot(t, queries, stockplan.ID, joint.ID), incremental)
+
+	// Replays recreate lots and dispositions under the same IDs.
+	for id := range before {
+		if _, ok := incremental[id]; !ok {
+			t.Errorf("expected %s to survive replays", id)
+		}
+	}
+}
+
+// lotSnapshot maps each lot and disposition ID to its remaining quantity or
+// realized gain, plus a "disp" count of dispositions.
+func lotSnapshot(t *testing.T, queries *db.Queries, accountIDs ...string) map[string]int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	snapshot := make(map[string]int64)
+	for _, id := range accountIDs {
+		lots, err := queries.ListLotsByAccount(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to list lots: %v", err)
+		}
+		for _, l := range lots {
+			snapshot[l.ID] = l.RemainingMicros
+		}
+
+		dispositions, err := queries.ListDispositionsByAccount(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to list dispositions: %v", err)
+		}
+		for _, d := range dispositions {
+			snapshot[d.ID] = d.RealizedGainMicros
+			snapshot["disp"]++
+		}
+	}
+	return snapshot
+}
+
+func assertSnapshot(t *testing.T, name string, want, got map[string]int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d lots and dispositions, want %d", name, len(got), len(want))
+	}
+	for id, v := range want {
+		if g, ok := got[id]; !ok || g != v {
+			t.Errorf("%s: %s = %d (present %t), want %d", name, id, g, ok, v)
+		}
+	}
+}
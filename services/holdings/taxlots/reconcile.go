@@ -0,0 +1,388 @@
+package taxlots
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+const (
+	// positionQuantityToleranceMicros absorbs brokers rounding fractional
+	// shares to three places.
+	positionQuantityToleranceMicros = 1_000
+	// positionBasisToleranceMicros absorbs cent rounding per lot.
+	positionBasisToleranceMicros = 1_000_000
+	// dripSlack allows for reinvestment prices above the ones we estimate
+	// missing shares at.
+	dripSlack = 1.25
+)
+
+// ErrNoPositions is returned when an account has no broker positions
+// snapshot on or before the reconciliation date.
+var ErrNoPositions = errors.New("no broker positions")
+
+type PositionStatus string
+
+const (
+	PositionMatched     PositionStatus = "matched"      // same quantity and basis
+	PositionMismatch    PositionStatus = "mismatch"     // both hold it, but quantity or basis differ
+	PositionMissingLots PositionStatus = "missing_lots" // the broker reports shares we have no lots for
+	PositionNotReported PositionStatus = "not_reported" // we have lots the broker doesn't report
+)
+
+type CauseKind string
+
+const (
+	CauseOpeningBalance CauseKind = "missing_opening_balance"
+	CauseSplit          CauseKind = "unapplied_split"
+	CauseDRIP           CauseKind = "drip_not_imported"
+	CauseMissingBuy     CauseKind = "missing_buy"
+	CauseMissingSale    CauseKind = "missing_sale"
+	CauseBasis          CauseKind = "basis_difference"
+)
+
+// Cause is a likely explanation for a position mismatch.
+type Cause struct {
+	Kind   CauseKind
+	Detail string
+}
+
+// PositionLine compares the broker's position in one security with the lots
+// we have open on the snapshot date. Differences are broker minus ours.
+type PositionLine struct {
+	Status     PositionStatus
+	SecurityID string
+	Symbol     string
+
+	BrokerQuantityMicros  int64
+	BrokerCostBasisMicros int64
+	BrokerCostBasisValid  bool // brokers don't always report basis
+	LotQuantityMicros     int64
+	LotCostBasisMicros    int64
+	QuantityDiffMicros    int64
+	CostBasisDiffMicros   int64
+
+	Gap    *SymbolGap // from Analyze, when sells went unmatched
+	Causes []Cause
+}
+
+type PositionReconciliation struct {
+	AccountID string
+	AsOfDate  string // the broker snapshot compared
+	Lines     []PositionLine
+}
+
+// Unmatched returns the lines that need attention.
+func (r *PositionReconciliation) Unmatched() []PositionLine {
+	var lines []PositionLine
+	for _, l := range r.Lines {
+		if l.Status != PositionMatched {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// ReconcilePositions compares the account's latest broker positions snapshot
+// on or before asOf with the lots open at the close of the snapshot date, and
+// suggests causes for the differences from the gap analysis, stored splits and
+// dividends paid.
+func (a *Analyzer) ReconcilePositions(ctx context.Context, accountID string, asOf time.Time) (*PositionReconciliation, error) {
+	date, err := a.queries.GetLatestPositionDate(ctx, db.GetLatestPositionDateParams{
+		AccountID: accountID,
+		AsOfDate:  asOf.Format("2006-01-02"),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w on or before %s", ErrNoPositions, asOf.Format("2006-01-02"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions date: %w", err)
+	}
+
+	positions, err := a.queries.ListPositionsByAccountAndDate(ctx, db.ListPositionsByAccountAndDateParams{
+		AccountID: accountID,
+		AsOfDate:  date,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %w", err)
+	}
+	lots, err := NewValuer(a.queries).OpenLots(ctx, accountID, parseDate(date))
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := a.Analyze(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("analysis failed: %w", err)
+	}
+	income, err := a.queries.ListSecurityIncome(ctx, db.ListSecurityIncomeParams{
+		AccountID: accountID,
+		EndDate:   date,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list income: %w", err)
+	}
+
+	splits := make(map[string][]db.CorporateAction)
+	for _, p := range positions {
+		actions, err := a.queries.ListCorporateActions(ctx, p.SecurityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list corporate actions for %s: %w", p.Symbol, err)
+		}
+		for _, action := range actions {
+			if action.ActionType == "split" && action.Ratio.Valid && action.Ratio.Float64 > 0 {
+				splits[p.SecurityID] = append(splits[p.SecurityID], action)
+			}
+		}
+	}
+
+	rec := ComparePositions(date, positions, lots, analysis.Gaps, splits, income)
+	rec.AccountID = accountID
+	return rec, nil
+}
+
+// ComparePositions matches broker positions to open lots by security.
+func ComparePositions(date string, positions []db.ListPositionsByAccountAndDateRow, lots []db.ListOpenLotsRow, gaps []SymbolGap, splits map[string][]db.CorporateAction, income []db.ListSecurityIncomeRow) *PositionReconciliation {
+	rec := &PositionReconciliation{AsOfDate: date}
+
+	type holding struct {
+		symbol         string
+		quantity, cost int64
+		earliest       string
+	}
+	held := make(map[string]*holding)
+	for _, l := range lots {
+		h, ok := held[l.SecurityID]
+		if !ok {
+			h = &holding{symbol: l.Symbol, earliest: l.AcquiredDate}
+			held[l.SecurityID] = h
+		}
+		quantity, cost := l.RemainingMicros, ProRata(l.CostBasisMicros, l.QuantityMicros, l.RemainingMicros)
+		if l.PositionSide == string(PositionShort) {
+			quantity, cost = -quantity, -cost
+		}
+		h.quantity += quantity
+		h.cost += cost
+		if l.AcquiredDate < h.earliest {
+			h.earliest = l.AcquiredDate
+		}
+	}
+
+	gapBySecurity := make(map[string]SymbolGap)
+	for _, g := range gaps {
+		gapBySecurity[g.SecurityID] = g
+	}
+	dividends := make(map[string]int64)
+	for _, r := range income {
+		if r.CashType == "dividend" || r.CashType == "cap_gain" {
+			dividends[r.SecurityID] += r.AmountMicros
+		}
+	}
+
+	reported := make(map[string]bool)
+	for _, p := range positions {
+		reported[p.SecurityID] = true
+		line := PositionLine{
+			SecurityID:            p.SecurityID,
+			Symbol:                p.Symbol,
+			BrokerQuantityMicros:  p.QuantityMicros,
+			BrokerCostBasisMicros: p.CostBasisMicros.Int64,
+			BrokerCostBasisValid:  p.CostBasisMicros.Valid,
+		}
+		var earliest string
+		if h, ok := held[p.SecurityID]; ok {
+			line.LotQuantityMicros = h.quantity
+			line.LotCostBasisMicros = h.cost
+			earliest = h.earliest
+		}
+		if g, ok := gapBySecurity[p.SecurityID]; ok {
+			line.Gap = &g
+		}
+
+		line.QuantityDiffMicros = line.BrokerQuantityMicros - line.LotQuantityMicros
+		if line.BrokerCostBasisValid {
+			line.CostBasisDiffMicros = line.BrokerCostBasisMicros - line.LotCostBasisMicros
+		}
+		quantityOff := abs(line.QuantityDiffMicros) > positionQuantityToleranceMicros
+		basisOff := abs(line.CostBasisDiffMicros) > positionBasisToleranceMicros
+
+		switch {
+		case line.LotQuantityMicros == 0 && line.BrokerQuantityMicros != 0:
+			line.Status = PositionMissingLots
+		case quantityOff || basisOff:
+			line.Status = PositionMismatch
+		default:
+			line.Status = PositionMatched
+		}
+		if line.Status != PositionMatched {
+			var price float64
+			if p.MarketValueMicros.Valid && p.QuantityMicros != 0 {
+				price = float64(p.MarketValueMicros.Int64) / float64(p.QuantityMicros)
+			}
+			line.Causes = positionCauses(line, earliest, date, splits[p.SecurityID], dividends[p.SecurityID], price)
+		}
+		rec.Lines = append(rec.Lines, line)
+	}
+
+	for securityID, h := range held {
+		if reported[securityID] {
+			continue
+		}
+		line := PositionLine{
+			Status:              PositionNotReported,
+			SecurityID:          securityID,
+			Symbol:              h.symbol,
+			LotQuantityMicros:   h.quantity,
+			LotCostBasisMicros:  h.cost,
+			QuantityDiffMicros:  -h.quantity,
+			CostBasisDiffMicros: -h.cost,
+		}
+		line.Causes = positionCauses(line, h.earliest, date, nil, 0, 0)
+		rec.Lines = append(rec.Lines, line)
+	}
+
+	sort.SliceStable(rec.Lines, func(i, j int) bool { return rec.Lines[i].Symbol < rec.Lines[j].Symbol })
+	return rec
+}
+
+// positionCauses suggests why a position doesn't match: a split after our
+// earliest open lot that lot processing doesn't apply, shares sold before the
+// transaction history starts, reinvested dividends the import left out, or a
+// buy, sale or transfer not imported. price is the broker's per share, or zero.
+func positionCauses(line PositionLine, earliest, date string, splits []db.CorporateAction, dividendsMicros int64, price float64) []Cause {
+	diff := line.QuantityDiffMicros
+	if abs(diff) <= positionQuantityToleranceMicros {
+		return []Cause{{
+			Kind:   CauseBasis,
+			Detail: fmt.Sprintf("same quantity, basis differs by %s: check wash sale adjustments, return of capital and opening balance basis", formatMicros(line.CostBasisDiffMicros)),
+		}}
+	}
+
+	var causes []Cause
+
+	if line.LotQuantityMicros != 0 && line.BrokerQuantityMicros != 0 {
+		ratio := 1.0
+		var applied []db.CorporateAction
+		for _, s := range splits {
+			if s.ActionDate > earliest && s.ActionDate <= date {
+				ratio *= s.Ratio.Float64
+				applied = append(applied, s)
+			}
+		}
+		explained := math.Abs(float64(line.LotQuantityMicros)*ratio-float64(line.BrokerQuantityMicros)) <= positionQuantityToleranceMicros
+		switch {
+		case len(applied) > 0 && explained:
+			for _, s := range applied {
+				causes = append(causes, Cause{
+					Kind:   CauseSplit,
+					Detail: fmt.Sprintf("%s split on %s isn't applied to our lots", splitRatio(s.Ratio.Float64), s.ActionDate),
+				})
+			}
+			return causes
+		case len(applied) > 0:
+			causes = append(causes, Cause{
+				Kind:   CauseSplit,
+				Detail: fmt.Sprintf("%d split(s) since %s aren't applied to our lots", len(applied), earliest),
+			})
+		default:
+			// Without a stored split, a whole-number ratio at the same
+			// basis is still most likely one.
+			r := float64(line.BrokerQuantityMicros) / float64(line.LotQuantityMicros)
+			basisSame := !line.BrokerCostBasisValid || abs(line.CostBasisDiffMicros) <= positionBasisToleranceMicros
+			if n, ok := wholeRatio(r); ok && basisSame {
+				return []Cause{{
+					Kind:   CauseSplit,
+					Detail: fmt.Sprintf("broker quantity is %s ours at the same basis: likely a split not applied", splitRatio(n)),
+				}}
+			}
+		}
+	}
+
+	if diff > 0 {
+		if line.Gap != nil && line.Gap.UnmatchedMicros > 0 {
+			causes = append(causes, Cause{
+				Kind: CauseOpeningBalance,
+				Detail: fmt.Sprintf("%s shares were sold with no matching buy (last %s): add an opening balance for shares bought before the history starts",
+					formatShares(line.Gap.UnmatchedMicros), line.Gap.LastUnmatchedSellDate.Format("2006-01-02")),
+			})
+		} else if line.LotQuantityMicros == 0 {
+			causes = append(causes, Cause{
+				Kind:   CauseOpeningBalance,
+				Detail: "no lots: add an opening balance, or import the buys or transfer in",
+			})
+		}
+
+		if dividendsMicros > 0 && line.LotQuantityMicros != 0 {
+			missingValue := price * float64(diff) / 1_000_000
+			if price == 0 || missingValue <= float64(dividendsMicros)*dripSlack {
+				causes = append(causes, Cause{
+					Kind: CauseDRIP,
+					Detail: fmt.Sprintf("%s of dividends paid; the %s extra shares may be reinvestments not imported",
+						formatMicros(dividendsMicros), formatShares(diff)),
+				})
+			}
+		}
+	}
+
+	if diff > 0 && len(causes) == 0 {
+		causes = append(causes, Cause{
+			Kind:   CauseMissingBuy,
+			Detail: fmt.Sprintf("the broker holds %s more shares: a buy or transfer in may not be imported", formatShares(diff)),
+		})
+	}
+
+	if diff < 0 {
+		causes = append(causes, Cause{
+			Kind:   CauseMissingSale,
+			Detail: fmt.Sprintf("we hold %s more shares: a sale, transfer out or reorganization may not be imported", formatShares(-diff)),
+		})
+	}
+
+	return causes
+}
+
+// wholeRatio reports whether r is n-for-1 or 1-for-n for a whole n from 2
+// to 50, returning n or 1/n.
+func wholeRatio(r float64) (float64, bool) {
+	if r <= 0 {
+		return 0, false
+	}
+	for _, candidate := range []float64{r, 1 / r} {
+		n := math.Round(candidate)
+		if n >= 2 && n <= 50 && math.Abs(candidate-n) <= 0.0005*n {
+			if candidate == r {
+				return n, true
+			}
+			return 1 / n, true
+		}
+	}
+	return 0, false
+}
+
+// splitRatio formats a split ratio as "2-for-1" or "1-for-10".
+func splitRatio(r float64) string {
+	if r >= 1 {
+		return fmt.Sprintf("%g-for-1", math.Round(r*1000)/1000)
+	}
+	return fmt.Sprintf("1-for-%g", math.Round(1/r*1000)/1000)
+}
+
+func formatShares(micros int64) string {
+	return fmt.Sprintf("%g", float64(micros)/1_000_000)
+}
+
+func formatMicros(micros int64) string {
+	return fmt.Sprintf("$%.2f", float64(micros)/1_000_000)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package taxlots_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestComparePositions(t *testing.T) {
+	openLot := func(securityID, symbol, acquired string, qty, basis int64) db.ListOpenLotsRow {
+		return db.ListOpenLotsRow{
+			ID: "lot_" + symbol, AccountID: "acct", SecurityID: securityID, Symbol: symbol,
+			AcquiredDate: acquired, QuantityMicros: qty, RemainingMicros: qty, CostBasisMicros: basis, PositionSide: "long",
+		}
+	}
+	position := func(securityID, symbol string, qty, basis, value int64) db.ListPositionsByAccountAndDateRow {
+		return db.ListPositionsByAccountAndDateRow{
+			SecurityID: securityID, Symbol: symbol, QuantityMicros: qty,
+			CostBasisMicros:   sql.NullInt64{Int64: basis, Valid: true},
+			MarketValueMicros: sql.NullInt64{Int64: value, Valid: value != 0},
+		}
+	}
+
+	lots := []db.ListOpenLotsRow{
+		openLot("sec_aapl", "AAPL", "2020-01-15", 10_000_000, 3_000_000_000),
+		openLot("sec_ko", "KO", "2021-03-01", 100_000_000, 5_000_000_000),
+		openLot("sec_msft", "MSFT", "2022-05-01", 10_000_000, 2_800_000_000),
+		openLot("sec_nvda", "NVDA", "2022-09-01", 4_000_000, 500_000_000),
+		openLot("sec_tsla", "TSLA", "2023-01-03", 5_000_000, 600_000_000),
+		openLot("sec_zs", "ZS", "2023-11-13", 200_000_000, 40_000_000_000),
+	}
+	positions := []db.ListPositionsByAccountAndDateRow{
+		position("sec_aapl", "AAPL", 40_000_000, 3_000_000_000, 0),
+		position("sec_ko", "KO", 100_500_000, 5_030_000_000, 6_030_000_000), // $60 a share
+		position("sec_msft", "MSFT", 10_000_000, 2_800_000_000, 0),
+		position("sec_nvda", "NVDA", 40_000_000, 500_000_000, 0),
+		position("sec_vti", "VTI", 25_000_000, 5_000_000_000, 0),
+		position("sec_zs", "ZS", 300_000_000, 50_000_000_000, 0),
+	}
+	gaps := []taxlots.SymbolGap{{
+		Symbol: "ZS", SecurityID: "sec_zs", UnmatchedMicros: 100_000_000, RemainingMicros: 200_000_000,
+		NeedsOpeningBalance: true, LastUnmatchedSellDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	splits := map[string][]db.CorporateAction{
+		"sec_aapl": {
+			{ActionDate: "2014-06-09", ActionType: "split", Ratio: sql.NullFloat64{Float64: 7, Valid: true}}, // before our lot
+			{ActionDate: "2020-08-31", ActionType: "split", Ratio: sql.NullFloat64{Float64: 4, Valid: true}},
+		},
+	}
+	income := []db.ListSecurityIncomeRow{
+		{SecurityID: "sec_ko", TransactionDate: "2024-04-01", CashType: "dividend", AmountMicros: 46_000_000},
+		{SecurityID: "sec_msft", TransactionDate: "2024-06-13", CashType: "dividend", AmountMicros: 7_500_000},
+	}
+
+	rec := taxlots.ComparePositions("2024-12-31", positions, lots, gaps, splits, income)
+
+	want := map[string]struct {
+		status taxlots.PositionStatus
+		causes []taxlots.CauseKind
+	}{
+		"AAPL": {taxlots.PositionMismatch, []taxlots.CauseKind{taxlots.CauseSplit}},
+		"KO":   {taxlots.PositionMismatch, []taxlots.CauseKind{taxlots.CauseDRIP}},
+		"MSFT": {taxlots.PositionMatched, nil},
+		"NVDA": {taxlots.PositionMismatch, []taxlots.CauseKind{taxlots.CauseSplit}},
+		"TSLA": {taxlots.PositionNotReported, []taxlots.CauseKind{taxlots.CauseMissingSale}},
+		"VTI":  {taxlots.PositionMissingLots, []taxlots.CauseKind{taxlots.CauseOpeningBalance}},
+		"ZS":   {taxlots.PositionMismatch, []taxlots.CauseKind{taxlots.CauseOpeningBalance}},
+	}
+	if len(rec.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(rec.Lines), len(want), rec.Lines)
+	}
+	for _, line := range rec.Lines {
+		w, ok := want[line.Symbol]
+		if !ok {
+			t.Errorf("unexpected line for %s", line.Symbol)
+			continue
+		}
+		var kinds []taxlots.CauseKind
+		for _, c := range line.Causes {
+			kinds = append(kinds, c.Kind)
+		}
+		if line.Status != w.status || len(kinds) != len(w.causes) || (len(kinds) > 0 && kinds[0] != w.causes[0]) {
+			t.Errorf("%s: got %s %+v, want %s %v", line.Symbol, line.Status, line.Causes, w.status, w.causes)
+		}
+	}
+
+	if len(rec.Unmatched()) != 6 {
+		t.Errorf("expected 6 unmatched lines, got %d", len(rec.Unmatched()))
+	}
+	if zs := rec.Lines[6]; zs.Gap == nil || zs.QuantityDiffMicros != 100_000_000 || zs.CostBasisDiffMicros != 10_000_000_000 {
+		t.Errorf("unexpected ZS line: %+v", zs)
+	}
+}
@@ -0,0 +1,401 @@
+package taxlots
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// replayVersion is folded into every journal digest. Bump it when the lot
+// rules change so the next run replays everything.
+const replayVersion = 7
+
+// Replay is one account and security whose lots were replayed.
+type Replay struct {
+	AccountID    string
+	SecurityID   string
+	Symbol       string
+	FromDate     string // earliest changed transaction date
+	Transactions int    // transactions reapplied; 0 when history was only removed
+}
+
+type lotKey struct {
+	accountID  string
+	securityID string
+}
+
+// replayEntry is a transaction or entered lot adjustment that affects lots,
+// with its place in replay order and a digest of everything the lot rules
+// read from it.
+type replayEntry struct {
+	txn        db.ListTransactionsByAccountRow
+	adjustment *db.LotAdjustment // set instead of txn for entered adjustments
+	sortKey    string
+	digest     string
+	match      *TransferMatch
+	option     *OptionMatch
+	applies    bool // false for the side of a matched pair the other side handles
+}
+
+func (e replayEntry) key() lotKey {
+	if e.adjustment != nil {
+		return lotKey{accountID: e.adjustment.AccountID, securityID: e.adjustment.SecurityID}
+	}
+	return lotKey{accountID: e.txn.AccountID, securityID: e.txn.SecurityID.String}
+}
+
+// linkID identifies the matched transfer or option event the entry is one
+// side of, or is empty.
+func (e replayEntry) linkID() string {
+	switch {
+	case e.match != nil:
+		return e.match.OutTransactionID
+	case e.option != nil:
+		return e.option.Event.ID
+	default:
+		return ""
+	}
+}
+
+// replayLink is a matched pair of transactions that move lots between two
+// accounts or securities, so both have to replay together.
+type replayLink struct {
+	id   string
+	a, b lotKey
+}
+
+func replayLinks(matches []TransferMatch, options []OptionMatch) []replayLink {
+	var links []replayLink
+	for _, m := range matches {
+		links = append(links, replayLink{
+			id: m.OutTransactionID,
+			a:  lotKey{accountID: m.OutAccountID, securityID: m.SecurityID},
+			b:  lotKey{accountID: m.InAccountID, securityID: m.SecurityID},
+		})
+	}
+	for _, m := range options {
+		links = append(links, replayLink{
+			id: m.Event.ID,
+			a:  lotKey{accountID: m.Event.AccountID, securityID: m.Event.SecurityID.String},
+			b:  lotKey{accountID: m.Leg.AccountID, securityID: m.Leg.SecurityID.String},
+		})
+	}
+	return links
+}
+
+// sourceID is the journal's transaction_id for the entry.
+func (e replayEntry) sourceID() string {
+	if e.adjustment != nil {
+		return e.adjustment.ID
+	}
+	return e.txn.ID
+}
+
+// replayPlan is what a run has to redo: per account and security, the sort
+// key to replay from, and the entries at or after it in replay order.
+type replayPlan struct {
+	from    map[lotKey]string
+	entries []replayEntry
+	current map[lotKey][]replayEntry
+}
+
+// planReplay compares the transactions that affect lots with the journal of
+// what was applied last time. Each account and security replays from its
+// first difference; both accounts of a matched transfer, and both the option
+// and the underlying of a matched exercise or assignment, replay from it if
+// either has to. Changed bond terms replay the bond's whole history.
+func planReplay(txns []db.ListTransactionsByAccountRow, adjustments []db.LotAdjustment, matches []TransferMatch, options []OptionMatch, bonds []db.ListBondsRow, journal []db.LotJournal) *replayPlan {
+	current := replayEntries(txns, adjustments, matches, options, bonds)
+
+	applied := make(map[lotKey][]db.LotJournal)
+	for _, j := range journal {
+		key := lotKey{accountID: j.AccountID, securityID: j.SecurityID}
+		applied[key] = append(applied[key], j)
+	}
+
+	from := make(map[lotKey]string)
+	for key, entries := range current {
+		if k, ok := firstDifference(applied[key], entries); ok {
+			from[key] = k
+		}
+	}
+	for key, entries := range applied {
+		if _, ok := current[key]; !ok {
+			from[key] = entries[0].SortKey
+		}
+	}
+
+	linkKeys := make(map[string]string)
+	for _, entries := range current {
+		for _, e := range entries {
+			if id := e.linkID(); id != "" {
+				linkKeys[id] = e.sortKey
+			}
+		}
+	}
+	links := replayLinks(matches, options)
+	for changed := true; changed; {
+		changed = false
+		for _, l := range links {
+			linkKey, ok := linkKeys[l.id]
+			if !ok {
+				continue
+			}
+			if replaysFrom(from, l.a, linkKey) || replaysFrom(from, l.b, linkKey) {
+				changed = lowerFrom(from, l.a, linkKey) || changed
+				changed = lowerFrom(from, l.b, linkKey) || changed
+			}
+		}
+	}
+
+	plan := &replayPlan{from: from, current: current}
+	for key, k := range from {
+		for _, e := range current[key] {
+			if e.sortKey >= k {
+				plan.entries = append(plan.entries, e)
+			}
+		}
+	}
+	sort.SliceStable(plan.entries, func(i, j int) bool {
+		a, b := plan.entries[i], plan.entries[j]
+		if a.sortKey != b.sortKey {
+			return a.sortKey < b.sortKey
+		}
+		return a.sourceID() < b.sourceID()
+	})
+
+	return plan
+}
+
+// replays returns a summary of each account and security the plan replays,
+// ordered by account and security.
+func (p *replayPlan) replays() []Replay {
+	var replays []Replay
+	for key, k := range p.from {
+		r := Replay{
+			AccountID:  key.accountID,
+			SecurityID: key.securityID,
+			FromDate:   strings.SplitN(k, "|", 2)[0],
+		}
+		for _, e := range p.current[key] {
+			if e.txn.Symbol.Valid {
+				r.Symbol = e.txn.Symbol.String
+			}
+			if e.sortKey >= k {
+				r.Transactions++
+			}
+		}
+		replays = append(replays, r)
+	}
+	sort.Slice(replays, func(i, j int) bool {
+		if replays[i].AccountID != replays[j].AccountID {
+			return replays[i].AccountID < replays[j].AccountID
+		}
+		return replays[i].SecurityID < replays[j].SecurityID
+	})
+	return replays
+}
+
+// replayEntries groups the transactions and adjustments that affect lots by
+// account and security, in replay order. Both sides of a matched transfer or
+// option event take the sort key of whichever side comes first, which is where
+// the lots move.
+func replayEntries(txns []db.ListTransactionsByAccountRow, adjustments []db.LotAdjustment, matches []TransferMatch, options []OptionMatch, bonds []db.ListBondsRow) map[lotKey][]replayEntry {
+	bondTerms := make(map[string]string)
+	for _, b := range bonds {
+		bondTerms[b.SecurityID] = fmt.Sprintf("%g|%s|%s|%s|%d|%d|%d",
+			b.CouponRate,
+			b.MaturityDate,
+			b.FirstCouponDate.String,
+			b.CallDate.String,
+			b.CallPriceMicros.Int64,
+			b.ParMicros,
+			b.PaymentsPerYear,
+		)
+	}
+
+	matchByTxn := make(map[string]*TransferMatch)
+	for i := range matches {
+		matchByTxn[matches[i].OutTransactionID] = &matches[i]
+		matchByTxn[matches[i].InTransactionID] = &matches[i]
+	}
+	optionByTxn := make(map[string]*OptionMatch)
+	for i := range options {
+		optionByTxn[options[i].Event.ID] = &options[i]
+		optionByTxn[options[i].Leg.ID] = &options[i]
+	}
+
+	ownKeys := make(map[string]string)
+	for _, txn := range txns {
+		ownKeys[txn.ID] = sortKey(txn)
+	}
+
+	entries := make(map[lotKey][]replayEntry)
+	for _, txn := range txns {
+		if !txn.SecurityID.Valid || !affectsLots(txn.TransactionType) {
+			continue
+		}
+
+		e := replayEntry{txn: txn, sortKey: ownKeys[txn.ID], applies: true}
+		counterpart := ""
+		if m, ok := matchByTxn[txn.ID]; ok {
+			e.match = m
+			counterpart = m.OutTransactionID
+			if txn.ID == m.OutTransactionID {
+				counterpart = m.InTransactionID
+			}
+		}
+		if m, ok := optionByTxn[txn.ID]; ok {
+			e.option = m
+			counterpart = m.Event.ID
+			if txn.ID == m.Event.ID {
+				counterpart = m.Leg.ID
+			}
+		}
+		if other, ok := ownKeys[counterpart]; ok && other < e.sortKey {
+			e.sortKey = other
+			e.applies = false
+		}
+		e.digest = transactionDigest(txn, counterpart, bondTerms[txn.SecurityID.String])
+
+		entries[e.key()] = append(entries[e.key()], e)
+	}
+
+	for i := range adjustments {
+		adj := &adjustments[i]
+		e := replayEntry{
+			adjustment: adj,
+			sortKey:    fmt.Sprintf("%s|%d|%s", adj.EffectiveDate, rankAdjustment, adj.ID),
+			digest:     adjustmentDigest(*adj),
+			applies:    true,
+		}
+		entries[e.key()] = append(entries[e.key()], e)
+	}
+
+	for _, list := range entries {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].sortKey < list[j].sortKey
+		})
+	}
+
+	return entries
+}
+
+// sortForReplay returns txns in replay order (see sortKey).
+func sortForReplay(txns []db.ListTransactionsByAccountRow) []db.ListTransactionsByAccountRow {
+	sorted := make([]db.ListTransactionsByAccountRow, len(txns))
+	copy(sorted, txns)
+
+	keys := make(map[string]string, len(sorted))
+	for _, txn := range sorted {
+		keys[txn.ID] = sortKey(txn)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return keys[sorted[i].ID] < keys[sorted[j].ID]
+	})
+
+	return sorted
+}
+
+// Same-day replay order: shares arrive, then basis changes, then shares leave.
+const (
+	rankArrival = iota
+	rankAdjustment
+	rankDeparture
+)
+
+// sortKey orders transactions for replay: by date, then by rank, then by ID,
+// so same-day buys and sells replay the same way regardless of import order.
+func sortKey(txn db.ListTransactionsByAccountRow) string {
+	rank := rankArrival
+	switch txn.TransactionType {
+	case "return_of_capital":
+		rank = rankAdjustment
+	case "sell", "reorg_out", "redemption", "transfer_out", "buy_to_cover", "option_expiration", "option_assignment", "option_exercise":
+		rank = rankDeparture
+	}
+	return fmt.Sprintf("%s|%d|%s", txn.TransactionDate, rank, txn.ID)
+}
+
+func affectsLots(txnType string) bool {
+	switch txnType {
+	case "buy", "opening_balance", "reorg_in", "rsu_vest", "espp_purchase", "sell", "reorg_out", "security_transfer", "transfer_out", "return_of_capital",
+		"sell_short", "buy_to_cover", "option_expiration", "option_assignment", "option_exercise", "redemption":
+		return true
+	default:
+		return false
+	}
+}
+
+// transactionDigest covers every field the lot rules read, so an edited
+// amount or a transfer matched to a different counterpart counts as a change.
+// bondTerms is set for bonds, whose basis and proceeds depend on them.
+func transactionDigest(txn db.ListTransactionsByAccountRow, counterpart, bondTerms string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%s|%d|%d|%d|%d|%t|%s|%s|%d|%s|%s|%s|%d|%g|%t|%s",
+		replayVersion,
+		txn.TransactionType,
+		txn.TransactionDate,
+		txn.QuantityMicros.Int64,
+		txn.PriceMicros.Int64,
+		txn.AmountMicros,
+		txn.FeesMicros.Int64,
+		txn.FeesInAmount,
+		counterpart,
+		txn.AcquisitionKind.String,
+		txn.FmvMicros.Int64,
+		txn.DonorAcquiredDate.String,
+		txn.PlanType.String,
+		txn.GrantDate.String,
+		txn.GrantFmvMicros.Int64,
+		txn.DiscountRate.Float64,
+		txn.AccountMargin,
+		bondTerms,
+	)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func adjustmentDigest(adj db.LotAdjustment) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%s|%s|%d",
+		replayVersion,
+		adj.AdjustmentType,
+		adj.LotID,
+		adj.EffectiveDate,
+		adj.AmountMicros,
+	)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// firstDifference returns the sort key to replay from, or false when the
+// journal matches the current transactions exactly.
+func firstDifference(applied []db.LotJournal, current []replayEntry) (string, bool) {
+	for i := 0; i < max(len(applied), len(current)); i++ {
+		switch {
+		case i >= len(applied):
+			return current[i].sortKey, true
+		case i >= len(current):
+			return applied[i].SortKey, true
+		case applied[i].SortKey != current[i].sortKey,
+			applied[i].TransactionID != current[i].sourceID(),
+			applied[i].Digest != current[i].digest:
+			return min(applied[i].SortKey, current[i].sortKey), true
+		}
+	}
+	return "", false
+}
+
+func replaysFrom(from map[lotKey]string, key lotKey, sortKey string) bool {
+	k, ok := from[key]
+	return ok && k <= sortKey
+}
+
+func lowerFrom(from map[lotKey]string, key lotKey, sortKey string) bool {
+	if replaysFrom(from, key, sortKey) {
+		return false
+	}
+	from[key] = sortKey
+	return true
+}
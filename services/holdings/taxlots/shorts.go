@@ -0,0 +1,172 @@
+package taxlots
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/database"
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// PositionSide is whether a lot is shares or contracts held (long) or sold
+// to open and owed back (short). A short lot's cost basis is the premium or
+// proceeds received when it was opened.
+type PositionSide string
+
+const (
+	PositionLong  PositionSide = "long"
+	PositionShort PositionSide = "short"
+)
+
+func isShort(lot db.Lot) bool {
+	return PositionSide(lot.PositionSide) == PositionShort
+}
+
+// processShort opens a short lot for a short sale or a sale to open. Its
+// basis is the net proceeds or premium received.
+func (p *Processor) processShort(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
+	if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
+		return nil
+	}
+	proceeds, _ := saleProceeds(txn)
+	return p.openShort(ctx, txn, txn.QuantityMicros.Int64, proceeds)
+}
+
+// openShort creates a short lot for quantityMicros of txn that brought in
+// proceedsMicros.
+func (p *Processor) openShort(ctx context.Context, txn db.ListTransactionsByAccountRow, quantityMicros, proceedsMicros int64) error {
+	_, err := p.queries.CreateLot(ctx, db.CreateLotParams{
+		ID:              database.DerivedID(database.PrefixLot, txn.ID),
+		AccountID:       txn.AccountID,
+		SecurityID:      txn.SecurityID.String,
+		TransactionID:   txn.ID,
+		AcquiredDate:    txn.TransactionDate,
+		QuantityMicros:  quantityMicros,
+		RemainingMicros: quantityMicros,
+		CostBasisMicros: proceedsMicros,
+		AcquisitionKind: string(AcquisitionPurchase),
+		PositionSide:    string(PositionShort),
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("opened short lot",
+		"transaction_id", txn.ID,
+		"symbol", txn.Symbol,
+		"quantity", quantityMicros,
+		"proceeds", proceedsMicros,
+	)
+
+	return nil
+}
+
+// processCover closes short lots oldest first for a purchase to close.
+func (p *Processor) processCover(ctx context.Context, txn db.ListTransactionsByAccountRow) error {
+	if !txn.QuantityMicros.Valid || txn.QuantityMicros.Int64 == 0 {
+		return nil
+	}
+
+	uncovered, err := p.closeShortLots(ctx, txn, purchaseCost(txn), txn.FeesMicros.Int64)
+	if err != nil {
+		return err
+	}
+	if uncovered > 0 {
+		slog.Warn("cover quantity exceeds open short lots",
+			"transaction_id", txn.ID,
+			"symbol", txn.Symbol,
+			"unmatched_quantity", uncovered,
+		)
+	}
+	return nil
+}
+
+// closeShortLots closes short lots, oldest first, for up to txn's quantity at
+// costMicros and returns the quantity left over. The proceeds received when
+// the short opened are the proceeds and the cost to close is the basis.
+//
+// A short sale's gain is short-term however long it was open (IRC 1233(b);
+// for written options, IRS Pub. 550). A loss is long-term if shares held
+// long-term when the short opened are still held (IRC 1233(d)).
+func (p *Processor) closeShortLots(ctx context.Context, txn db.ListTransactionsByAccountRow, costMicros, feesMicros int64) (int64, error) {
+	lots, err := p.queries.ListLotsByAccountAndSecurity(ctx, db.ListLotsByAccountAndSecurityParams{
+		AccountID:  txn.AccountID,
+		SecurityID: txn.SecurityID.String,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	quantity := txn.QuantityMicros.Int64
+	remainingToClose := quantity
+	for _, lot := range OrderLots(lots, MethodFIFO) {
+		if remainingToClose <= 0 {
+			break
+		}
+		if !isShort(lot) || lot.RemainingMicros <= 0 {
+			continue
+		}
+
+		closeFromLot := min(remainingToClose, lot.RemainingMicros)
+		proceeds := ProRata(lot.CostBasisMicros, lot.QuantityMicros, closeFromLot)
+		basis := ProRata(costMicros, quantity, closeFromLot)
+
+		period := HoldingPeriodShortTerm
+		if proceeds < basis && heldLongTermAt(lots, parseDate(lot.AcquiredDate)) {
+			period = HoldingPeriodLongTerm
+		}
+
+		_, err := p.queries.CreateLotDisposition(ctx, db.CreateLotDispositionParams{
+			ID:                 database.DerivedID(database.PrefixLotDisposition, txn.ID, lot.ID),
+			LotID:              lot.ID,
+			SellTransactionID:  txn.ID,
+			DisposedDate:       txn.TransactionDate,
+			QuantityMicros:     closeFromLot,
+			CostBasisMicros:    basis,
+			ProceedsMicros:     proceeds,
+			FeesMicros:         ProRata(feesMicros, quantity, closeFromLot),
+			RealizedGainMicros: proceeds - basis,
+			HoldingPeriod:      string(period),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create disposition: %w", err)
+		}
+
+		err = p.queries.UpdateLotRemaining(ctx, db.UpdateLotRemainingParams{
+			ID:              lot.ID,
+			RemainingMicros: lot.RemainingMicros - closeFromLot,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to update lot remaining: %w", err)
+		}
+
+		slog.Debug("closed short lot",
+			"lot_id", lot.ID,
+			"quantity", closeFromLot,
+			"proceeds", proceeds,
+			"cost", basis,
+			"holding_period", period,
+		)
+
+		remainingToClose -= closeFromLot
+	}
+
+	return remainingToClose, nil
+}
+
+// heldLongTermAt reports whether an open long lot had already been held
+// long-term on date.
+func heldLongTermAt(lots []db.Lot, date time.Time) bool {
+	for _, lot := range lots {
+		if isShort(lot) || lot.RemainingMicros <= 0 {
+			continue
+		}
+		longTerm := LongTermDateFor(AcquisitionKind(lot.AcquisitionKind), lot.AcquiredDate, lot.DonorAcquiredDate.String)
+		if !date.Before(longTerm) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package taxlots_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestProcessShortSales(t *testing.T) {
+	ctx := context.Background()
+
+	type txn struct {
+		txnType string
+		date    string
+		qty     int64
+		amount  int64
+	}
+
+	tests := []struct {
+		name   string
+		margin bool
+		txns   []txn
+		// wantGains are realized gains keyed by "disposed acquired".
+		wantGains    map[string]int64
+		wantPeriod   taxlots.HoldingPeriod // of the last disposition
+		wantQuantity int64                 // net holding, negative when short
+	}{
+		{
+			name: "short sale covered",
+			txns: []txn{
+				{"sell_short", "2023-01-10", 100_000_000, 5_000_000_000},
+				{"buy_to_cover", "2024-03-01", 100_000_000, 4_000_000_000},
+			},
+			// Open more than a year, still short-term.
+			wantGains:  map[string]int64{"2024-03-01 2023-01-10": 1_000_000_000},
+			wantPeriod: taxlots.HoldingPeriodShortTerm,
+		},
+		{
+			name: "short sale still open",
+			txns: []txn{
+				{"sell_short", "2024-01-10", 100_000_000, 5_000_000_000},
+			},
+			wantGains:    map[string]int64{},
+			wantQuantity: -100_000_000,
+		},
+		{
+			name:   "margin sell beyond holdings goes short",
+			margin: true,
+			txns: []txn{
+				{"buy", "2023-01-10", 50_000_000, 2_500_000_000},
+				{"sell", "2024-02-01", 100_000_000, 6_000_000_000},
+				{"buy", "2024-03-01", 80_000_000, 4_000_000_000},
+			},
+			// The buy covers the 50 short shares first; 30 stay long.
+			wantGains: map[string]int64{
+				"2024-02-01 2023-01-10": 500_000_000,
+				"2024-03-01 2024-02-01": 500_000_000,
+			},
+			wantPeriod:   taxlots.HoldingPeriodShortTerm,
+			wantQuantity: 30_000_000,
+		},
+		{
+			name: "cash account sell beyond holdings",
+			txns: []txn{
+				{"buy", "2023-01-10", 50_000_000, 2_500_000_000},
+				{"sell", "2024-02-01", 100_000_000, 6_000_000_000},
+				{"buy", "2024-03-01", 80_000_000, 4_000_000_000},
+			},
+			wantGains: map[string]int64{
+				"2024-02-01 2023-01-10": 500_000_000,
+			},
+			wantPeriod:   taxlots.HoldingPeriodLongTerm,
+			wantQuantity: 80_000_000,
+		},
+		{
+			name: "loss against long-term shares",
+			txns: []txn{
+				{"buy", "2022-01-10", 100_000_000, 5_000_000_000},
+				{"sell_short", "2024-01-10", 100_000_000, 6_000_000_000},
+				{"buy_to_cover", "2024-03-01", 100_000_000, 7_000_000_000},
+			},
+			// IRC 1233(d): the loss is long-term.
+			wantGains:    map[string]int64{"2024-03-01 2024-01-10": -1_000_000_000},
+			wantPeriod:   taxlots.HoldingPeriodLongTerm,
+			wantQuantity: 100_000_000,
+		},
+		{
+			name: "transfer out leaves short lots",
+			txns: []txn{
+				{"sell_short", "2023-01-10", 100_000_000, 5_000_000_000},
+				{"opening_balance", "2023-06-01", 100_000_000, 4_000_000_000},
+				{"transfer_out", "2024-02-01", 100_000_000, 0},
+			},
+			wantGains:    map[string]int64{},
+			wantQuantity: -100_000_000,
+		},
+		{
+			name: "return of capital leaves short lots",
+			txns: []txn{
+				{"sell_short", "2023-01-10", 100_000_000, 5_000_000_000},
+				{"opening_balance", "2023-06-01", 100_000_000, 100_000_000},
+				{"return_of_capital", "2024-02-01", 0, 300_000_000},
+				{"buy_to_cover", "2024-03-01", 100_000_000, 4_000_000_000},
+			},
+			// All $300 goes to the long lot, $200 past its basis; the short
+			// lot's proceeds are untouched when it's covered.
+			wantGains: map[string]int64{
+				"2024-02-01 2023-06-01": 200_000_000,
+				"2024-03-01 2023-01-10": 1_000_000_000,
+			},
+			wantPeriod:   taxlots.HoldingPeriodShortTerm,
+			wantQuantity: 100_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			acct := createAccount(t, queries, "Brokerage")
+			sec := createSecurity(t, queries, "TSLA")
+			if tt.margin {
+				err := queries.SetAccountMargin(ctx, db.SetAccountMarginParams{ID: acct.ID, Margin: true})
+				if err != nil {
+					t.Fatalf("failed to enable margin: %v", err)
+				}
+			}
+
+			for _, txn := range tt.txns {
+				createTxn(t, queries, acct.ID, sec.ID, txn.txnType, txn.date, txn.qty, txn.amount)
+			}
+
+			if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+				t.Fatalf("failed to process lots: %v", err)
+			}
+
+			assertGains(t, queries, acct.ID, tt.wantGains)
+
+			if tt.wantPeriod != "" {
+				dispositions, err := queries.ListDispositionsByAccount(ctx, acct.ID)
+				if err != nil {
+					t.Fatalf("failed to list dispositions: %v", err)
+				}
+				last := dispositions[0]
+				for _, d := range dispositions {
+					if d.DisposedDate > last.DisposedDate {
+						last = d
+					}
+				}
+				if last.HoldingPeriod != string(tt.wantPeriod) {
+					t.Errorf("holding period = %s, want %s", last.HoldingPeriod, tt.wantPeriod)
+				}
+			}
+
+			holdings, err := queries.ListHoldingsByAccount(ctx, acct.ID)
+			if err != nil {
+				t.Fatalf("failed to list holdings: %v", err)
+			}
+			var quantity int64
+			for _, h := range holdings {
+				quantity += int64(h.QuantityMicros.Float64)
+			}
+			if quantity != tt.wantQuantity {
+				t.Errorf("holding quantity = %d, want %d", quantity, tt.wantQuantity)
+			}
+		})
+	}
+}
+
+func TestProcessTransferLeavesShortLots(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	brokerage := createAccount(t, queries, "Brokerage")
+	joint := createAccount(t, queries, "Joint")
+	tsla := createSecurity(t, queries, "TSLA")
+
+	createTxn(t, queries, brokerage.ID, tsla.ID, "sell_short", "2023-01-10", 100_000_000, 5_000_000_000)
+	createTxn(t, queries, brokerage.ID, tsla.ID, "opening_balance", "2023-06-01", 100_000_000, 4_000_000_000)
+	createTxn(t, queries, brokerage.ID, tsla.ID, "transfer_out", "2024-02-01", 100_000_000, 0)
+	createTxn(t, queries, joint.ID, tsla.ID, "security_transfer", "2024-02-03", 100_000_000, 4_000_000_000)
+
+	result, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+	if len(result.Transfers) != 1 {
+		t.Fatalf("expected 1 matched transfer, got %d", len(result.Transfers))
+	}
+
+	jointLots, err := queries.ListLotsByAccount(ctx, joint.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	if len(jointLots) != 1 || jointLots[0].AcquiredDate != "2023-06-01" || jointLots[0].PositionSide != string(taxlots.PositionLong) {
+		t.Fatalf("expected the long lot from 2023-06-01 to move, got %+v", jointLots)
+	}
+
+	brokerageLots, err := queries.ListLotsByAccount(ctx, brokerage.ID)
+	if err != nil {
+		t.Fatalf("failed to list lots: %v", err)
+	}
+	for _, l := range brokerageLots {
+		if l.PositionSide == string(taxlots.PositionShort) && l.RemainingMicros != 100_000_000 {
+			t.Errorf("expected the short lot to stay open, got %d remaining", l.RemainingMicros)
+		}
+	}
+}
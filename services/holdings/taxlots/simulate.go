@@ -0,0 +1,191 @@
+package taxlots
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// TaxRates are marginal rates for estimating the tax on realized gains.
+type TaxRates struct {
+	ShortTerm float64
+	LongTerm  float64
+	State     float64
+}
+
+type SimulateSellParams struct {
+	AccountID      string // empty pools open lots from every account
+	Symbol         string
+	QuantityMicros int64
+	PriceMicros    int64
+	FeesMicros     int64
+	Method         Method
+	Date           time.Time
+	Rates          TaxRates
+}
+
+// SimulatedRelief is a lot a simulated sale would relieve.
+type SimulatedRelief struct {
+	Relief
+	AccountName string
+}
+
+// RealizedTotals are realized gains split by holding period.
+type RealizedTotals struct {
+	ShortTermMicros int64
+	LongTermMicros  int64
+	TotalMicros     int64
+}
+
+type SimulateSellResult struct {
+	Symbol          string
+	Method          Method
+	Date            time.Time
+	QuantityMicros  int64
+	PriceMicros     int64
+	ProceedsMicros  int64 // net of fees, for the shares the lots cover
+	FeesMicros      int64 // for the shares the lots cover
+	Reliefs         []SimulatedRelief
+	UnmatchedMicros int64 // shares the open lots can't cover, left out of proceeds
+
+	Gain RealizedTotals
+
+	// Tax is estimated at marginal rates; a negative figure is tax saved.
+	FederalTaxMicros int64
+	StateTaxMicros   int64
+	TotalTaxMicros   int64
+
+	// YTD realized gains across all accounts for the sale's year, before and
+	// after the simulated sale.
+	YTDBefore RealizedTotals
+	YTDAfter  RealizedTotals
+}
+
+// Simulator runs sales against the current lots without writing anything.
+type Simulator struct {
+	queries *db.Queries
+}
+
+func NewSimulator(queries *db.Queries) *Simulator {
+	return &Simulator{queries: queries}
+}
+
+func (s *Simulator) SimulateSell(ctx context.Context, params SimulateSellParams) (*SimulateSellResult, error) {
+	if params.QuantityMicros <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+
+	openLots, err := s.queries.ListOpenLots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open lots: %w", err)
+	}
+
+	var lots []db.Lot
+	accountNames := make(map[string]string)
+	for _, l := range openLots {
+		if l.Symbol != params.Symbol || (params.AccountID != "" && l.AccountID != params.AccountID) {
+			continue
+		}
+		lots = append(lots, db.Lot{
+			ID:                l.ID,
+			AccountID:         l.AccountID,
+			SecurityID:        l.SecurityID,
+			TransactionID:     l.TransactionID,
+			AcquiredDate:      l.AcquiredDate,
+			QuantityMicros:    l.QuantityMicros,
+			RemainingMicros:   l.RemainingMicros,
+			CostBasisMicros:   l.CostBasisMicros,
+			SourceLotID:       l.SourceLotID,
+			AcquisitionKind:   l.AcquisitionKind,
+			FmvMicros:         l.FmvMicros,
+			DonorAcquiredDate: l.DonorAcquiredDate,
+			PlanType:          l.PlanType,
+			GrantDate:         l.GrantDate,
+			GrantFmvMicros:    l.GrantFmvMicros,
+			DiscountRate:      l.DiscountRate,
+			PositionSide:      l.PositionSide,
+			CreatedAt:         l.CreatedAt,
+		})
+		accountNames[l.AccountID] = l.AccountName
+	}
+	if len(lots) == 0 {
+		return nil, fmt.Errorf("no open lots for %s", params.Symbol)
+	}
+
+	bonds, err := s.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	options, err := s.queries.ListOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list options: %w", err)
+	}
+	pricing := NewPricing(bonds, options)
+
+	gross := pricing.MarketValue(lots[0].SecurityID, params.QuantityMicros, params.PriceMicros)
+	if bond, ok := pricing.Bond(lots[0].SecurityID); ok {
+		// Bonds are sold at their amortized basis.
+		lots = amortizeLots(lots, bond, params.Date)
+	}
+	result := &SimulateSellResult{
+		Symbol:         params.Symbol,
+		Method:         params.Method,
+		Date:           params.Date,
+		QuantityMicros: params.QuantityMicros,
+		PriceMicros:    params.PriceMicros,
+		ProceedsMicros: gross - params.FeesMicros,
+		FeesMicros:     params.FeesMicros,
+	}
+
+	reliefs, unmatched := MatchSale(lots, params.Method, Sale{
+		Date:           params.Date,
+		QuantityMicros: params.QuantityMicros,
+		ProceedsMicros: result.ProceedsMicros,
+		FeesMicros:     params.FeesMicros,
+	})
+	result.UnmatchedMicros = unmatched
+	if unmatched > 0 {
+		// Only the covered shares can be sold.
+		matched := params.QuantityMicros - unmatched
+		result.ProceedsMicros = ProRata(result.ProceedsMicros, params.QuantityMicros, matched)
+		result.FeesMicros = ProRata(params.FeesMicros, params.QuantityMicros, matched)
+	}
+
+	for _, relief := range reliefs {
+		result.Reliefs = append(result.Reliefs, SimulatedRelief{
+			Relief:      relief,
+			AccountName: accountNames[relief.Lot.AccountID],
+		})
+		if relief.HoldingPeriod == HoldingPeriodLongTerm {
+			result.Gain.LongTermMicros += relief.GainMicros
+		} else {
+			result.Gain.ShortTermMicros += relief.GainMicros
+		}
+	}
+	result.Gain.TotalMicros = result.Gain.ShortTermMicros + result.Gain.LongTermMicros
+
+	result.FederalTaxMicros = int64(float64(result.Gain.ShortTermMicros)*params.Rates.ShortTerm +
+		float64(result.Gain.LongTermMicros)*params.Rates.LongTerm)
+	result.StateTaxMicros = int64(float64(result.Gain.TotalMicros) * params.Rates.State)
+	result.TotalTaxMicros = result.FederalTaxMicros + result.StateTaxMicros
+
+	ytd, err := s.queries.SumRealizedGainsByYear(ctx, strconv.Itoa(params.Date.Year()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum realized gains: %w", err)
+	}
+	result.YTDBefore = RealizedTotals{
+		ShortTermMicros: toInt64(ytd.ShortTermGains),
+		LongTermMicros:  toInt64(ytd.LongTermGains),
+		TotalMicros:     toInt64(ytd.TotalGains),
+	}
+	result.YTDAfter = RealizedTotals{
+		ShortTermMicros: result.YTDBefore.ShortTermMicros + result.Gain.ShortTermMicros,
+		LongTermMicros:  result.YTDBefore.LongTermMicros + result.Gain.LongTermMicros,
+		TotalMicros:     result.YTDBefore.TotalMicros + result.Gain.TotalMicros,
+	}
+
+	return result, nil
+}
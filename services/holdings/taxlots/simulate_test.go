@@ -0,0 +1,197 @@
+package taxlots_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestSimulateSell(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Brokerage")
+	sec := createSecurity(t, queries, "AAPL")
+
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2022-01-10", 10_000_000, 1_500_000_000) // $150/sh, long-term
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2023-06-01", 10_000_000, 1_800_000_000) // $180/sh, long-term
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2024-03-01", 10_000_000, 1_700_000_000) // $170/sh, short-term
+	createTxn(t, queries, acct.ID, sec.ID, "sell", "2024-04-01", 5_000_000, 1_000_000_000) // relieves half the first lot
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	before, err := queries.ListDispositionsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+
+	rates := taxlots.TaxRates{ShortTerm: 0.30, LongTerm: 0.15, State: 0.05}
+	sellDate := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		method        taxlots.Method
+		wantAcquired  []string
+		wantShortTerm int64
+		wantLongTerm  int64
+	}{
+		{
+			method:        taxlots.MethodFIFO,
+			wantAcquired:  []string{"2022-01-10", "2023-06-01"},
+			wantShortTerm: 0,
+			wantLongTerm:  450_000_000,
+		},
+		{
+			method:        taxlots.MethodHIFO,
+			wantAcquired:  []string{"2023-06-01", "2024-03-01"},
+			wantShortTerm: 150_000_000,
+			wantLongTerm:  200_000_000,
+		},
+		{
+			method:        taxlots.MethodLIFO,
+			wantAcquired:  []string{"2024-03-01", "2023-06-01"},
+			wantShortTerm: 300_000_000,
+			wantLongTerm:  100_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			result, err := taxlots.NewSimulator(queries).SimulateSell(ctx, taxlots.SimulateSellParams{
+				AccountID:      acct.ID,
+				Symbol:         "AAPL",
+				QuantityMicros: 15_000_000,
+				PriceMicros:    200_000_000,
+				Method:         tt.method,
+				Date:           sellDate,
+				Rates:          rates,
+			})
+			if err != nil {
+				t.Fatalf("failed to simulate: %v", err)
+			}
+
+			if len(result.Reliefs) != len(tt.wantAcquired) {
+				t.Fatalf("relieved %d lots, want %d", len(result.Reliefs), len(tt.wantAcquired))
+			}
+			for i, want := range tt.wantAcquired {
+				if got := result.Reliefs[i].Lot.AcquiredDate; got != want {
+					t.Errorf("relief %d acquired %s, want %s", i, got, want)
+				}
+			}
+			if result.Gain.ShortTermMicros != tt.wantShortTerm {
+				t.Errorf("short-term gain = %d, want %d", result.Gain.ShortTermMicros, tt.wantShortTerm)
+			}
+			if result.Gain.LongTermMicros != tt.wantLongTerm {
+				t.Errorf("long-term gain = %d, want %d", result.Gain.LongTermMicros, tt.wantLongTerm)
+			}
+
+			wantFederal := int64(float64(tt.wantShortTerm)*0.30 + float64(tt.wantLongTerm)*0.15)
+			if result.FederalTaxMicros != wantFederal {
+				t.Errorf("federal tax = %d, want %d", result.FederalTaxMicros, wantFederal)
+			}
+
+			// The real April sale realized 5 @ (200-150) = $250 long-term.
+			if result.YTDBefore.TotalMicros != 250_000_000 {
+				t.Errorf("YTD before = %d, want 250000000", result.YTDBefore.TotalMicros)
+			}
+			if result.YTDAfter.TotalMicros != 250_000_000+result.Gain.TotalMicros {
+				t.Errorf("YTD after = %d, want before + simulated gain", result.YTDAfter.TotalMicros)
+			}
+		})
+	}
+
+	after, err := queries.ListDispositionsByAccount(ctx, acct.ID)
+	if err != nil {
+		t.Fatalf("failed to list dispositions: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("simulation wrote dispositions: %d before, %d after", len(before), len(after))
+	}
+}
+
+func TestSimulateSellOption(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Brokerage")
+	option := createSecurity(t, queries, "AAPL240621C00200000")
+	err := queries.UpsertOption(ctx, db.UpsertOptionParams{
+		SecurityID:       option.ID,
+		UnderlyingSymbol: "AAPL",
+		ExpirationDate:   "2024-06-21",
+		OptionType:       "call",
+		StrikeMicros:     200_000_000,
+		Multiplier:       100,
+	})
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+
+	// Two contracts at $3.00 a share.
+	createTxn(t, queries, acct.ID, option.ID, "buy", "2024-01-10", 2_000_000, 600_000_000)
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	result, err := taxlots.NewSimulator(queries).SimulateSell(ctx, taxlots.SimulateSellParams{
+		AccountID:      acct.ID,
+		Symbol:         "AAPL240621C00200000",
+		QuantityMicros: 1_000_000,
+		PriceMicros:    5_000_000,
+		Method:         taxlots.MethodFIFO,
+		Date:           time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+
+	// One contract at $5.00 a share is $500 against $300 of basis.
+	if result.ProceedsMicros != 500_000_000 {
+		t.Errorf("proceeds = %d, want 500000000", result.ProceedsMicros)
+	}
+	if result.Gain.ShortTermMicros != 200_000_000 {
+		t.Errorf("short-term gain = %d, want 200000000", result.Gain.ShortTermMicros)
+	}
+}
+
+func TestSimulateSellUnmatched(t *testing.T) {
+	ctx := context.Background()
+	queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	acct := createAccount(t, queries, "Brokerage")
+	sec := createSecurity(t, queries, "AAPL")
+	createTxn(t, queries, acct.ID, sec.ID, "buy", "2024-01-10", 10_000_000, 1_500_000_000)
+	if _, err := taxlots.NewProcessor(queries).ProcessTransactions(ctx, acct.ID); err != nil {
+		t.Fatalf("failed to process lots: %v", err)
+	}
+
+	result, err := taxlots.NewSimulator(queries).SimulateSell(ctx, taxlots.SimulateSellParams{
+		AccountID:      acct.ID,
+		Symbol:         "AAPL",
+		QuantityMicros: 15_000_000,
+		PriceMicros:    200_000_000,
+		FeesMicros:     15_000_000,
+		Method:         taxlots.MethodFIFO,
+		Date:           time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+
+	// 10 of the 15 shares are covered: $2,000 less $10 of the fees.
+	if result.UnmatchedMicros != 5_000_000 {
+		t.Errorf("unmatched = %d, want 5000000", result.UnmatchedMicros)
+	}
+	if result.ProceedsMicros != 1_990_000_000 || result.FeesMicros != 10_000_000 {
+		t.Errorf("proceeds = %d, fees = %d; want 1990000000, 10000000", result.ProceedsMicros, result.FeesMicros)
+	}
+	if result.Gain.TotalMicros != 490_000_000 {
+		t.Errorf("gain = %d, want 490000000", result.Gain.TotalMicros)
+	}
+}
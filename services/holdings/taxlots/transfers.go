@@ -0,0 +1,158 @@
+package taxlots
+
+import (
+	"sort"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// transferMatchWindowDays is how far apart the two sides of an
+// account-to-account transfer may be dated. Brokers often book the
+// receiving side a few days after the delivering side.
+const transferMatchWindowDays = 7
+
+// TransferMatch pairs shares leaving one of our accounts (transfer_out)
+// with the same shares arriving in another (security_transfer).
+type TransferMatch struct {
+	OutTransactionID string
+	InTransactionID  string
+	OutAccountID     string
+	InAccountID      string
+	SecurityID       string
+	Symbol           string
+	QuantityMicros   int64
+	OutDate          string
+	InDate           string
+}
+
+// UnmatchedTransfer is a transfer side with no counterpart in another account.
+// Shares leaving to an outside broker or arriving via ACAT from one look like this.
+type UnmatchedTransfer struct {
+	TransactionID   string
+	TransactionType string
+	AccountID       string
+	AccountName     string
+	Symbol          string
+	TransactionDate string
+	QuantityMicros  int64
+}
+
+// MatchTransfers pairs each transfer_out with a security_transfer of the same
+// security and quantity in a different account, dated within the match window.
+// When several candidates qualify the closest date wins.
+func MatchTransfers(transfers []db.ListSecurityTransfersRow) ([]TransferMatch, []UnmatchedTransfer) {
+	var outs, ins []db.ListSecurityTransfersRow
+	for _, t := range transfers {
+		switch t.TransactionType {
+		case "transfer_out":
+			outs = append(outs, t)
+		case "security_transfer":
+			ins = append(ins, t)
+		}
+	}
+
+	usedIn := make(map[string]bool)
+	var matches []TransferMatch
+	var unmatched []UnmatchedTransfer
+
+	for _, out := range outs {
+		outDate := parseDate(out.TransactionDate)
+		bestIdx := -1
+		bestDays := transferMatchWindowDays + 1
+
+		for i, in := range ins {
+			if usedIn[in.ID] || in.AccountID == out.AccountID {
+				continue
+			}
+			if in.SecurityID.String != out.SecurityID.String || in.QuantityMicros.Int64 != out.QuantityMicros.Int64 {
+				continue
+			}
+			days := absDays(parseDate(in.TransactionDate).Sub(outDate).Hours() / 24)
+			if days < bestDays {
+				bestIdx = i
+				bestDays = days
+			}
+		}
+
+		if bestIdx < 0 {
+			unmatched = append(unmatched, toUnmatchedTransfer(out))
+			continue
+		}
+
+		in := ins[bestIdx]
+		usedIn[in.ID] = true
+		matches = append(matches, TransferMatch{
+			OutTransactionID: out.ID,
+			InTransactionID:  in.ID,
+			OutAccountID:     out.AccountID,
+			InAccountID:      in.AccountID,
+			SecurityID:       out.SecurityID.String,
+			Symbol:           out.Symbol,
+			QuantityMicros:   out.QuantityMicros.Int64,
+			OutDate:          out.TransactionDate,
+			InDate:           in.TransactionDate,
+		})
+	}
+
+	for _, in := range ins {
+		if !usedIn[in.ID] {
+			unmatched = append(unmatched, toUnmatchedTransfer(in))
+		}
+	}
+
+	sort.SliceStable(unmatched, func(i, j int) bool {
+		return unmatched[i].TransactionDate < unmatched[j].TransactionDate
+	})
+
+	return matches, unmatched
+}
+
+// connectedAccounts returns accountID plus every account reachable from it
+// through matched transfers. Their lots have to be rebuilt together because
+// lots move between them.
+func connectedAccounts(accountID string, matches []TransferMatch) []string {
+	links := make(map[string][]string)
+	for _, m := range matches {
+		links[m.OutAccountID] = append(links[m.OutAccountID], m.InAccountID)
+		links[m.InAccountID] = append(links[m.InAccountID], m.OutAccountID)
+	}
+
+	seen := map[string]bool{accountID: true}
+	queue := []string{accountID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range links[current] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	accounts := make([]string, 0, len(seen))
+	for id := range seen {
+		accounts = append(accounts, id)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+func toUnmatchedTransfer(t db.ListSecurityTransfersRow) UnmatchedTransfer {
+	return UnmatchedTransfer{
+		TransactionID:   t.ID,
+		TransactionType: t.TransactionType,
+		AccountID:       t.AccountID,
+		AccountName:     t.AccountName,
+		Symbol:          t.Symbol,
+		TransactionDate: t.TransactionDate,
+		QuantityMicros:  t.QuantityMicros.Int64,
+	}
+}
+
+func absDays(d float64) int {
+	if d < 0 {
+		d = -d
+	}
+	return int(d + 0.5)
+}
@@ -0,0 +1,245 @@
+package taxlots
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+)
+
+// LotValue is an open lot marked to its security's latest close. Short lots
+// have a negative market value and basis: the cost to buy back against the
+// proceeds received.
+type LotValue struct {
+	Lot               db.ListOpenLotsRow
+	Priced            bool
+	PriceMicros       int64
+	PriceDate         string
+	CostBasisMicros   int64 // of the remaining quantity; amortized for bonds
+	MarketValueMicros int64
+	GainMicros        int64
+	HoldingPeriod     HoldingPeriod // if sold on the valuation date
+}
+
+// HoldingValue nets the lot values of one security in one account.
+type HoldingValue struct {
+	AccountID           string
+	AccountName         string
+	SecurityID          string
+	Symbol              string
+	SecurityName        string
+	EarliestAcquired    string
+	QuantityMicros      int64 // negative when short
+	CostBasisMicros     int64
+	MarketValueMicros   int64 // of the priced lots
+	ShortTermGainMicros int64
+	LongTermGainMicros  int64
+	PriceMicros         int64
+	PriceDate           string
+	Priced              bool // false when no lot has a price
+}
+
+// GainMicros is the holding's total unrealized gain.
+func (h HoldingValue) GainMicros() int64 {
+	return h.ShortTermGainMicros + h.LongTermGainMicros
+}
+
+// Valuer marks open lots to stored prices.
+type Valuer struct {
+	queries *db.Queries
+}
+
+func NewValuer(queries *db.Queries) *Valuer {
+	return &Valuer{queries: queries}
+}
+
+// OpenLots returns the lots of accountID, or of every account when it is
+// empty, that were open at the close of asOf: lots created by transactions
+// on or before it, less what was sold or transferred out by then, at the
+// basis they had before any later adjustment.
+func (v *Valuer) OpenLots(ctx context.Context, accountID string, asOf time.Time) ([]db.ListOpenLotsRow, error) {
+	rows, err := v.queries.ListOpenLotsAsOf(ctx, asOf.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open lots: %w", err)
+	}
+	var lots []db.ListOpenLotsRow
+	for _, r := range rows {
+		if accountID == "" || r.AccountID == accountID {
+			lots = append(lots, db.ListOpenLotsRow(r))
+		}
+	}
+	return lots, nil
+}
+
+// Value marks the lots of accountID, or of every account when it is empty,
+// open at the close of asOf to the latest prices on or before it.
+func (v *Valuer) Value(ctx context.Context, accountID string, asOf time.Time) ([]LotValue, error) {
+	lots, err := v.OpenLots(ctx, accountID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := v.queries.ListLatestPrices(ctx, asOf.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	bonds, err := v.queries.ListBonds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bonds: %w", err)
+	}
+	options, err := v.queries.ListOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list options: %w", err)
+	}
+
+	return ValueLots(lots, prices, bonds, options, asOf), nil
+}
+
+// ValueLots marks each open lot to the latest price on or before asOf. Lots
+// of securities without a price are returned unpriced with no gain.
+func ValueLots(lots []db.ListOpenLotsRow, prices []db.ListLatestPricesRow, bonds []db.ListBondsRow, options []db.ListOptionsRow, asOf time.Time) []LotValue {
+	priceBySecurity := make(map[string]db.ListLatestPricesRow)
+	for _, p := range prices {
+		priceBySecurity[p.SecurityID] = p
+	}
+	pricing := NewPricing(bonds, options)
+
+	values := make([]LotValue, 0, len(lots))
+	for _, lot := range lots {
+		short := lot.PositionSide == string(PositionShort)
+		acquired := parseDate(lot.AcquiredDate)
+
+		v := LotValue{
+			Lot:             lot,
+			CostBasisMicros: ProRata(lot.CostBasisMicros, lot.QuantityMicros, lot.RemainingMicros),
+			HoldingPeriod:   HoldingPeriodFor(acquired, asOf),
+		}
+		if bond, ok := pricing.Bond(lot.SecurityID); ok && !short {
+			v.CostBasisMicros = AmortizedBasis(v.CostBasisMicros, lot.RemainingMicros, bond, acquired, asOf)
+		}
+		if short {
+			// Gains on short positions are short-term however long they're open.
+			v.HoldingPeriod = HoldingPeriodShortTerm
+			v.CostBasisMicros = -v.CostBasisMicros
+		}
+
+		price, ok := priceBySecurity[lot.SecurityID]
+		if !ok {
+			values = append(values, v)
+			continue
+		}
+
+		v.Priced = true
+		v.PriceMicros = price.CloseMicros
+		v.PriceDate = price.PriceDate
+		v.MarketValueMicros = pricing.MarketValue(lot.SecurityID, lot.RemainingMicros, price.CloseMicros)
+		if short {
+			v.MarketValueMicros = -v.MarketValueMicros
+		}
+		v.GainMicros = v.MarketValueMicros - v.CostBasisMicros
+
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// Pricing turns closes into market values. Closes are per share, except
+// bonds (per 100 of face value) and options (per share of the underlying,
+// times the contract multiplier).
+type Pricing struct {
+	bonds       map[string]db.ListBondsRow
+	multipliers map[string]int64
+}
+
+func NewPricing(bonds []db.ListBondsRow, options []db.ListOptionsRow) *Pricing {
+	p := &Pricing{
+		bonds:       make(map[string]db.ListBondsRow),
+		multipliers: make(map[string]int64),
+	}
+	for _, b := range bonds {
+		p.bonds[b.SecurityID] = b
+	}
+	for _, o := range options {
+		p.multipliers[o.SecurityID] = o.Multiplier
+	}
+	return p
+}
+
+// Bond returns the terms of securityID if it's a bond.
+func (p *Pricing) Bond(securityID string) (db.ListBondsRow, bool) {
+	bond, ok := p.bonds[securityID]
+	return bond, ok
+}
+
+// MarketValue is the value of quantityMicros of securityID at closeMicros.
+// Negative quantities give negative values.
+func (p *Pricing) MarketValue(securityID string, quantityMicros, closeMicros int64) int64 {
+	if bond, ok := p.bonds[securityID]; ok {
+		return ProRata(closeMicros, 100_000_000, FaceValue(quantityMicros, bond))
+	}
+	if multiplier := p.multipliers[securityID]; multiplier > 0 {
+		return ProRata(closeMicros*multiplier, 1_000_000, quantityMicros)
+	}
+	return ProRata(closeMicros, 1_000_000, quantityMicros)
+}
+
+// SummarizeHoldings nets lot values into a holding per account and security,
+// ordered by account name and symbol.
+func SummarizeHoldings(values []LotValue) []HoldingValue {
+	type key struct{ accountID, securityID string }
+	holdings := make(map[key]*HoldingValue)
+	var order []key
+	for _, v := range values {
+		k := key{v.Lot.AccountID, v.Lot.SecurityID}
+		h, ok := holdings[k]
+		if !ok {
+			h = &HoldingValue{
+				AccountID:    v.Lot.AccountID,
+				AccountName:  v.Lot.AccountName,
+				SecurityID:   v.Lot.SecurityID,
+				Symbol:       v.Lot.Symbol,
+				SecurityName: v.Lot.SecurityName.String,
+			}
+			holdings[k] = h
+			order = append(order, k)
+		}
+
+		if v.Lot.PositionSide == string(PositionShort) {
+			h.QuantityMicros -= v.Lot.RemainingMicros
+		} else {
+			h.QuantityMicros += v.Lot.RemainingMicros
+		}
+		h.CostBasisMicros += v.CostBasisMicros
+		if h.EarliestAcquired == "" || v.Lot.AcquiredDate < h.EarliestAcquired {
+			h.EarliestAcquired = v.Lot.AcquiredDate
+		}
+		if !v.Priced {
+			continue
+		}
+		h.Priced = true
+		h.PriceMicros = v.PriceMicros
+		h.PriceDate = v.PriceDate
+		h.MarketValueMicros += v.MarketValueMicros
+		if v.HoldingPeriod == HoldingPeriodLongTerm {
+			h.LongTermGainMicros += v.GainMicros
+		} else {
+			h.ShortTermGainMicros += v.GainMicros
+		}
+	}
+
+	result := make([]HoldingValue, 0, len(order))
+	for _, k := range order {
+		result = append(result, *holdings[k])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].AccountName != result[j].AccountName {
+			return result[i].AccountName < result[j].AccountName
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+
+	return result
+}
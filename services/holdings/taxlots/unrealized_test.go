@@ -0,0 +1,76 @@
+package taxlots_test
+
+import (
+	"testing"
+
+	"github.com/levisegal/monay/services/holdings/gen/db"
+	"github.com/levisegal/monay/services/holdings/taxlots"
+)
+
+func TestValueLots(t *testing.T) {
+	openLot := func(id, securityID, symbol, acquired, side string, qty, basis int64) db.ListOpenLotsRow {
+		return db.ListOpenLotsRow{
+			ID: id, AccountID: "acct", AccountName: "Brokerage", SecurityID: securityID, Symbol: symbol,
+			AcquiredDate: acquired, QuantityMicros: qty, RemainingMicros: qty, CostBasisMicros: basis, PositionSide: side,
+		}
+	}
+
+	lots := []db.ListOpenLotsRow{
+		openLot("lot_1", "sec_acme", "ACME", "2023-01-10", "long", 10_000_000, 1_000_000_000),
+		openLot("lot_2", "sec_acme", "ACME", "2024-03-01", "long", 10_000_000, 1_300_000_000),
+		openLot("lot_3", "sec_short", "SHRT", "2023-01-10", "short", 10_000_000, 500_000_000),
+		openLot("lot_4", "sec_call", "ACME  240621C00100000", "2024-03-01", "long", 2_000_000, 400_000_000),
+		openLot("lot_5", "sec_bond", "13063EBK1", "2024-01-02", "long", 10_000_000_000, 10_000_000_000),
+		openLot("lot_6", "sec_none", "NONE", "2024-01-02", "long", 1_000_000, 50_000_000),
+	}
+	lots[1].RemainingMicros = 5_000_000 // half sold
+
+	prices := []db.ListLatestPricesRow{
+		{SecurityID: "sec_acme", Symbol: "ACME", PriceDate: "2024-05-31", CloseMicros: 120_000_000},
+		{SecurityID: "sec_short", Symbol: "SHRT", PriceDate: "2024-05-31", CloseMicros: 40_000_000},
+		{SecurityID: "sec_call", PriceDate: "2024-05-31", CloseMicros: 3_000_000},
+		{SecurityID: "sec_bond", PriceDate: "2024-05-31", CloseMicros: 98_500_000},
+	}
+	bonds := []db.ListBondsRow{{SecurityID: "sec_bond", CouponRate: 0.04, MaturityDate: "2030-01-01", ParMicros: 1_000_000, PaymentsPerYear: 2}}
+	options := []db.ListOptionsRow{{SecurityID: "sec_call", Multiplier: 100}}
+
+	values := taxlots.ValueLots(lots, prices, bonds, options, mustDate(t, "2024-06-01"))
+
+	tests := []struct {
+		lot         int
+		marketValue int64
+		gain        int64
+		period      taxlots.HoldingPeriod
+	}{
+		{lot: 0, marketValue: 1_200_000_000, gain: 200_000_000, period: taxlots.HoldingPeriodLongTerm},
+		{lot: 1, marketValue: 600_000_000, gain: -50_000_000, period: taxlots.HoldingPeriodShortTerm},
+		// Short: owes 400 to buy back what sold for 500, short-term regardless of age.
+		{lot: 2, marketValue: -400_000_000, gain: 100_000_000, period: taxlots.HoldingPeriodShortTerm},
+		// Two contracts of 100 shares at $3.
+		{lot: 3, marketValue: 600_000_000, gain: 200_000_000, period: taxlots.HoldingPeriodShortTerm},
+		// $10,000 face at 98.50.
+		{lot: 4, marketValue: 9_850_000_000, gain: -150_000_000, period: taxlots.HoldingPeriodShortTerm},
+	}
+	for _, tt := range tests {
+		v := values[tt.lot]
+		if !v.Priced || v.MarketValueMicros != tt.marketValue || v.GainMicros != tt.gain || v.HoldingPeriod != tt.period {
+			t.Errorf("%s: got value %d gain %d %s, want %d %d %s",
+				v.Lot.ID, v.MarketValueMicros, v.GainMicros, v.HoldingPeriod, tt.marketValue, tt.gain, tt.period)
+		}
+	}
+	if values[5].Priced || values[5].GainMicros != 0 {
+		t.Errorf("unpriced lot got %+v", values[5])
+	}
+
+	holdings := taxlots.SummarizeHoldings(values)
+	if len(holdings) != 5 {
+		t.Fatalf("expected 5 holdings, got %d", len(holdings))
+	}
+	acme := holdings[1]
+	if acme.Symbol != "ACME" || acme.QuantityMicros != 15_000_000 || acme.LongTermGainMicros != 200_000_000 || acme.ShortTermGainMicros != -50_000_000 {
+		t.Errorf("ACME holding = %+v", acme)
+	}
+	if shrt := holdings[4]; shrt.Symbol != "SHRT" || shrt.QuantityMicros != -10_000_000 || shrt.CostBasisMicros != -500_000_000 {
+		t.Errorf("SHRT holding = %+v", shrt)
+	}
+}